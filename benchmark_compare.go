@@ -60,6 +60,12 @@ func main() {
 
 	// 5. VM Benchmark (FOR/NEXT)
 	runVM("Bytecode VM (FOR/NEXT)", forProg)
+
+	// 6. Register VM Benchmark (GOTO)
+	runRVM("Register VM (GOTO)", gotoProg)
+
+	// 7. Register VM Benchmark (FOR/NEXT)
+	runRVM("Register VM (FOR/NEXT)", forProg)
 }
 
 func mustParse(code string) *ast.Program {
@@ -132,6 +138,34 @@ func runVM(name string, prog *ast.Program) {
 	fmt.Println()
 }
 
+func runRVM(name string, prog *ast.Program) {
+	fmt.Printf("--- %s ---\n", name)
+	// Compile with the register backend
+	comp := compiler.New(compiler.WithRegisters())
+	chunk, err := comp.Compile(prog)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Bytecode Size: %d bytes\n", len(chunk.Code))
+	dumpChunk(chunk)
+
+	// execution
+	rvm := vm.NewRegister(chunk)
+	start := time.Now()
+	if err := rvm.Run(); err != nil {
+		panic(err)
+	}
+	elapsed := time.Since(start)
+
+	fmt.Printf("Time:   %v\n", elapsed)
+	if elapsed > 0 {
+		ops := float64(Iterations) / elapsed.Seconds()
+		fmt.Printf("Speed:  %.2f ops/sec\n", ops)
+	}
+	fmt.Println()
+}
+
 func dumpChunk(c *bytecode.Chunk) {
 	// Optional: print disassembly if small enough
 	// fmt.Println(c.Disassemble("Benchmark Chunk"))
@@ -9,7 +9,11 @@ mkdir -p bin
 # Stripped build for better size
 LDFLAGS="-s -w"
 
-echo "1. Building Zork BASIC (zb)..."
+echo "1. Checking the 32-bit build (GOARCH=386)..."
+GOARCH=386 go vet ./... || exit 1
+
+echo ""
+echo "2. Building Zork BASIC (zb)..."
 go build -ldflags="$LDFLAGS" -trimpath -o bin/zb ./cmd
 ls -lh bin/zb
 
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/parser"
+)
+
+// runAsm 执行 zb asm 子命令：把 .zasm 汇编为 .zbc，或用 -d 把 .zbc/.bas 反汇编为 .zasm
+// 返回进程退出码：0 表示成功，1 表示汇编或校验失败，2 表示用法错误
+func runAsm(args []string) int {
+	fs := flag.NewFlagSet("asm", flag.ContinueOnError)
+	output := fs.String("o", "", "Output file (default: input name with .zbc)")
+	disassemble := fs.Bool("d", false, "Print a .zbc or .bas file as .zasm instead")
+	mode := fs.String("mode", "vm", "Backend for .bas files with -d: vm or rvm")
+	optLevel := optLevelVar(fs, "Optimization `level` for .bas files with -d")
+	noVerify := fs.Bool("noverify", false, "Write the bytecode even if the verifier rejects it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: zb asm [-o out.zbc] [-noverify] <file.zasm>")
+		fmt.Fprintln(fs.Output(), "       zb asm -d [-mode vm|rvm] [-O n] <file.zbc|file.bas>")
+		fs.PrintDefaults()
+	}
+	// 允许选项写在文件名之后，如 zb asm in.zasm -o out.zbc
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return 2
+	}
+	filename := files[0]
+
+	if *disassemble {
+		chunk, err := loadChunk(filename, *mode, *optLevel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Print(chunk.Assembly())
+		return 0
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	chunk, err := bytecode.Assemble(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%v\n", filename, strings.TrimPrefix(err.Error(), "line "))
+		return 1
+	}
+	if _, err := bytecode.Verify(chunk); err != nil && !*noVerify {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+		return 1
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(filename, ".zasm") + ".zbc"
+	}
+	var buf bytes.Buffer
+	if err := chunk.Write(&buf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing bytecode: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// loadChunk 读取 .zbc 文件，或按 mode 和优化级别编译 .bas 文件
+func loadChunk(filename, mode string, level int) (*bytecode.Chunk, error) {
+	fileType, err := detectFileType(filename)
+	if err != nil {
+		return nil, err
+	}
+	if fileType == "bytecode" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return bytecode.ReadChunk(f)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := parser.ParseProgram(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %v", err)
+	}
+	opts := []compiler.Option{compiler.WithOptimization(level)}
+	if mode == "rvm" {
+		opts = append(opts, compiler.WithRegisters())
+	}
+	return compiler.New(opts...).Compile(prog)
+}
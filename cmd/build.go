@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/codegen"
+	"zork-basic/internal/parser"
+)
+
+// runBuild 执行 zb build 子命令：把 BASIC 程序转译为独立的 Go 源程序或 ES 模块
+// 返回进程退出码：0 表示成功，1 表示解析或转译失败，2 表示用法错误
+func runBuild(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := fs.String("o", "", "Output file (default: input name with .go or .mjs, - for stdout)")
+	target := fs.String("target", "", "Target language: go or js (default: from the -o extension, else go)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: zb build [-target go|js] [-o prog.go] <file.bas>")
+		fs.PrintDefaults()
+	}
+	// 允许选项写在文件名之后，如 zb build prog.bas -o prog.go
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return 2
+	}
+	filename := files[0]
+
+	// 未指定 -target 时按输出文件扩展名选择目标语言
+	lang := *target
+	if lang == "" {
+		lang = "go"
+		if ext := filepath.Ext(*output); ext == ".js" || ext == ".mjs" {
+			lang = "js"
+		}
+	}
+	var emit func(*ast.Program, ...codegen.Option) ([]byte, error)
+	ext := ""
+	switch lang {
+	case "go":
+		emit, ext = codegen.Go, ".go"
+	case "js":
+		emit, ext = codegen.JS, ".mjs"
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown target %q (want go or js)\n", lang)
+		return 2
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	prog, err := parser.ParseProgram(filename, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Parse error: %v\n", err)
+		return 1
+	}
+	src, err := emit(prog, codegen.WithSource(filename, data))
+	if err != nil {
+		// Errors name their own line ("line 30" or "file:line")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(filename, ".bas") + ext
+	}
+	if out == "-" {
+		os.Stdout.Write(src)
+		return 0
+	}
+	if err := os.WriteFile(out, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"zork-basic/internal/bytecode"
+)
+
+// runLink 执行 zb link 子命令：把一个程序和它使用的模块链接为一个 .zbc 文件。
+// 输入可以是 .zbc 目标文件，也可以是 .bas 源文件（按 -mode 和 -O 编译）
+// 返回进程退出码：0 表示成功，1 表示编译或链接失败，2 表示用法错误
+func runLink(args []string) int {
+	fs := flag.NewFlagSet("link", flag.ContinueOnError)
+	output := fs.String("o", "", "Output file (required)")
+	mode := fs.String("mode", "vm", "Backend for .bas inputs: vm or rvm")
+	optLevel := optLevelVar(fs, "Optimization `level` for .bas inputs")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: zb link [-mode vm|rvm] [-O n] -o app.zbc <program> <module>...")
+		fmt.Fprintln(fs.Output(), "Inputs are .zbc object files or .bas sources; exactly one is a program, the rest are MODULEs.")
+		fs.PrintDefaults()
+	}
+	// 允许选项写在文件名之后，如 zb link a.zbc b.zbc -o app.zbc
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) == 0 || *output == "" {
+		fs.Usage()
+		return 2
+	}
+
+	objects := make([]*bytecode.Chunk, len(files))
+	for i, filename := range files {
+		chunk, err := loadChunk(filename, *mode, *optLevel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			return 1
+		}
+		objects[i] = chunk
+	}
+	linked, err := bytecode.Link(objects...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "link: %v\n", err)
+		return 1
+	}
+
+	var buf bytes.Buffer
+	if err := linked.Write(&buf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing bytecode: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -3,14 +3,20 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
-	"zork-basic/internal/ast"
 	"zork-basic/internal/bytecode"
+	"zork-basic/internal/codegen"
 	"zork-basic/internal/compiler"
+	"zork-basic/internal/coverage"
+	"zork-basic/internal/interpreter"
 	"zork-basic/internal/parser"
 	"zork-basic/internal/repl"
 	"zork-basic/internal/vm"
@@ -22,6 +28,20 @@ const (
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "vet":
+			os.Exit(runVet(os.Args[2:]))
+		case "asm":
+			os.Exit(runAsm(os.Args[2:]))
+		case "build":
+			os.Exit(runBuild(os.Args[2:]))
+		case "link":
+			os.Exit(runLink(os.Args[2:]))
+		}
+	}
+
 	// 定义命令行参数
 	interactive := flag.Bool("i", false, "Interactive mode")
 	interactiveLong := flag.Bool("interactive", false, "Interactive mode")
@@ -29,9 +49,14 @@ func main() {
 	versionLong := flag.Bool("version", false, "Show version")
 	help := flag.Bool("h", false, "Show help")
 	helpLong := flag.Bool("help", false, "Show help")
-	modePtr := flag.String("mode", "vm", "Execution mode: ast or vm (for .bas files)")
-	outputFile := flag.String("o", "", "Compile to bytecode file (.zbc)")
-	disassemble := flag.Bool("d", false, "Disassemble bytecode")
+	modePtr := flag.String("mode", "vm", "Execution mode: ast, vm or rvm (for .bas files)")
+	outputFile := flag.String("o", "", "Compile to bytecode file (.zbc) or WebAssembly module (.wasm)")
+	disassemble := flag.Bool("d", false, "Disassemble .bas or .zbc to .zasm")
+	embedSource := flag.Bool("embedsrc", false, "Embed the program source in the bytecode file (with -o)")
+	wasmHost := flag.String("wasmhost", "", "Also write a JavaScript host for the .wasm module (with -o prog.wasm)")
+	cover := flag.Bool("cover", false, "Record line and branch coverage (.bas files)")
+	coverOut := flag.String("coverout", "", "Output prefix for coverage reports (default: source file name)")
+	optLevel := optLevelVar(flag.CommandLine, "Optimization `level`: 0 none, 1 constant folding, 2 folding and dead-code elimination")
 
 	flag.Parse()
 
@@ -48,6 +73,8 @@ func main() {
 		return
 	}
 
+	optimize := compiler.WithOptimization(*optLevel)
+
 	// 确定运行模式
 	isInteractive := *interactive || *interactiveLong
 	mode := *modePtr
@@ -66,23 +93,99 @@ func main() {
 		if *disassemble {
 			fmt.Println("Warning: -d flag is ignored in interactive mode")
 		}
+		if *optLevel != compiler.OptNone {
+			fmt.Println("Warning: -O flag is ignored in interactive mode")
+		}
 		repl.Run(Version, mode)
 	} else {
 		// 文件模式
 		filename := args[0]
 
+		// -O 只影响编译为字节码的程序；覆盖率统计的是未优化的语句
+		if *optLevel != compiler.OptNone && *outputFile == "" && !*disassemble {
+			if *cover {
+				fmt.Println("Warning: -O flag is ignored with -cover")
+			} else if mode == "ast" {
+				fmt.Println("Warning: -O flag is ignored in -mode ast")
+			}
+		}
+
 		if *outputFile != "" {
-			compileFileToBytecode(filename, *outputFile)
+			opts := []compiler.Option{optimize}
+			if mode == "rvm" {
+				opts = append(opts, compiler.WithRegisters())
+			}
+			compileFileToBytecode(filename, *outputFile, *embedSource, *wasmHost, opts...)
 			return
 		}
 
 		if *disassemble {
-			disassembleFile(filename)
+			disassembleFile(filename, mode, *optLevel)
 			return
 		}
 
-		runFileUnified(filename, mode)
+		if *cover {
+			runFileWithCoverage(filename, mode, *coverOut)
+			return
+		}
+
+		runFileUnified(filename, mode, optimize)
+	}
+}
+
+// optLevelFlag 是 -O 选项的值，只接受 compiler.OptNone 到 compiler.OptDeadCode 的级别
+type optLevelFlag struct {
+	level *int
+}
+
+// optAliasFlag 是 -O0/-O1/-O2 简写选项，出现时把 level 设为 n
+type optAliasFlag struct {
+	level *int
+	n     int
+}
+
+// optLevelVar 在 fs 中定义 -O 选项和它的简写 -O0/-O1/-O2，并返回级别。-O 后面必须
+// 有级别（-O=2、-O 2 或 -O2），"zb -O prog.bas" 会把文件名当作级别而报错
+func optLevelVar(fs *flag.FlagSet, usage string) *int {
+	level := new(int)
+	fs.Var(optLevelFlag{level}, "O", usage)
+	for n := compiler.OptNone; n <= compiler.OptDeadCode; n++ {
+		fs.Var(optAliasFlag{level, n}, fmt.Sprintf("O%d", n), fmt.Sprintf("Same as -O=%d", n))
+	}
+	return level
+}
+
+func (o optAliasFlag) String() string   { return "false" }
+func (o optAliasFlag) IsBoolFlag() bool { return true }
+
+func (o optAliasFlag) Set(s string) error {
+	set, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if set && o.level != nil {
+		*o.level = o.n
 	}
+	return nil
+}
+
+func (o optLevelFlag) String() string {
+	if o.level == nil {
+		return strconv.Itoa(compiler.OptNone)
+	}
+	return strconv.Itoa(*o.level)
+}
+
+func (o optLevelFlag) Set(s string) error {
+	level, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("-O needs a level, e.g. -O2 or -O=2")
+	}
+	if level < compiler.OptNone || level > compiler.OptDeadCode {
+		return fmt.Errorf("optimization level %d does not exist; use 0, 1 or 2", level)
+	}
+	*o.level = level
+	return nil
 }
 
 // detectFileType 探测文件类型：返回 "bytecode", "source", 或 "unknown"
@@ -105,8 +208,9 @@ func detectFileType(filename string) (string, error) {
 	return "source", nil
 }
 
-// disassembleFile 反汇编执行文件
-func disassembleFile(filename string) {
+// disassembleFile 把源文件或 .zbc 文件反汇编为 .zasm，输出与 zb asm -d 相同，可以用 zb asm 汇编回去
+// 对源文件使用 level 级别的优化，并在反汇编前以注释输出优化统计；mode 为 rvm 时输出寄存器指令
+func disassembleFile(filename string, mode string, level int) {
 	fileType, err := detectFileType(filename)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -133,25 +237,31 @@ func disassembleFile(filename string) {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		parsedAST, err := parser.Parse(filename, data)
+		prog, err := parser.ParseProgram(filename, data)
 		if err != nil {
 			fmt.Printf("Parse error: %v\n", err)
 			os.Exit(1)
 		}
-		prog := parsedAST.(*ast.Program)
-		comp := compiler.New()
+		opts := []compiler.Option{compiler.WithOptimization(level)}
+		if mode == "rvm" {
+			opts = append(opts, compiler.WithRegisters())
+		}
+		comp := compiler.New(opts...)
 		chunk, err = comp.Compile(prog)
 		if err != nil {
 			fmt.Printf("Compilation error: %v\n", err)
 			os.Exit(1)
 		}
+		if level > compiler.OptNone {
+			fmt.Printf("; -O%d: %s\n", level, comp.OptStats())
+		}
 	}
 
-	fmt.Print(chunk.Disassemble(filename))
+	fmt.Print(chunk.Assembly())
 }
 
 // runFileUnified 统一运行文件（自动识别类型）
-func runFileUnified(filename string, mode string) {
+func runFileUnified(filename string, mode string, opts ...compiler.Option) {
 	fileType, err := detectFileType(filename)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -170,8 +280,30 @@ func runFileUnified(filename string, mode string) {
 			fmt.Printf("Error reading bytecode: %v\n", err)
 			os.Exit(1)
 		}
-		v := vm.New(chunk)
-		if err := v.Run(); err != nil {
+		// 执行前校验字节码，避免构造的 .zbc 文件让虚拟机 panic 或越界访问
+		depth, err := bytecode.Verify(chunk)
+		if err == nil && depth > vm.StackSize {
+			err = fmt.Errorf("invalid bytecode: needs a stack of %d values, the VM has %d", depth, vm.StackSize)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		// 文件头记录了字节码面向哪种虚拟机；CHAIN 载入的源文件按同样的方式编译
+		var machine interface{ Run() error }
+		if chunk.Registers {
+			machine = vm.NewRegister(chunk, vm.WithChain(compiler.ChainLoader(append(opts, compiler.WithRegisters())...)))
+		} else {
+			machine = vm.New(chunk, vm.WithChain(compiler.ChainLoader(opts...)))
+		}
+		err = machine.Run()
+		var brk *interpreter.Break
+		if errors.As(err, &brk) {
+			// STOP 暂停了程序，非交互模式下不能继续
+			fmt.Printf("\n%v\n", brk)
+			return
+		}
+		if err != nil {
 			fmt.Printf("Runtime error: %v\n", err)
 			os.Exit(1)
 		}
@@ -183,12 +315,101 @@ func runFileUnified(filename string, mode string) {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		repl.ExecuteProgram(string(data), filename, mode)
+		repl.ExecuteProgram(string(data), filename, mode, opts...)
 	}
 }
 
+// runFileWithCoverage 运行源文件并记录覆盖率
+// 生成三个报告：<prefix>.cov（覆盖率数据）、<prefix>.cov.txt（带注释的源码清单）、<prefix>.cov.html（HTML 报告）
+func runFileWithCoverage(filename string, mode string, prefix string) {
+	fileType, err := detectFileType(filename)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if fileType == "bytecode" {
+		fmt.Println("Error: -cover requires a BASIC source file")
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	prog, err := parser.ParseProgram(filename, data)
+	if err != nil {
+		fmt.Printf("Parse error: %v\n", err)
+		os.Exit(1)
+	}
+	profile := coverage.New(prog)
+
+	if mode == "vm" || mode == "rvm" {
+		opts := []compiler.Option{compiler.WithCoverage(profile)}
+		if mode == "rvm" {
+			opts = append(opts, compiler.WithRegisters())
+		}
+		comp := compiler.New(opts...)
+		chunk, err := comp.Compile(prog)
+		if err != nil {
+			fmt.Printf("Compilation error: %v\n", err)
+			os.Exit(1)
+		}
+		var machine interface{ Run() error }
+		chain := vm.WithChain(compiler.ChainLoader(opts...))
+		if mode == "rvm" {
+			machine = vm.NewRegister(chunk, vm.WithCoverage(profile), chain)
+		} else {
+			machine = vm.New(chunk, vm.WithCoverage(profile), chain)
+		}
+		err = machine.Run()
+	} else {
+		interp := interpreter.NewInterpreter(interpreter.WithCoverage(profile))
+		err = interp.Run(prog)
+	}
+	var brk *interpreter.Break
+	if errors.As(err, &brk) {
+		fmt.Printf("\n%v\n", brk)
+	} else {
+		if err != nil {
+			fmt.Printf("Runtime error: %v\n", err)
+		}
+		fmt.Println("\nProgram complete.")
+	}
+
+	if prefix == "" {
+		prefix = strings.TrimSuffix(filename, ".bas")
+	}
+	reports := []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{prefix + ".cov", func(w io.Writer) error { return profile.WriteProfile(w, filename) }},
+		{prefix + ".cov.txt", func(w io.Writer) error { return profile.WriteListing(w, filename, string(data)) }},
+		{prefix + ".cov.html", func(w io.Writer) error { return profile.WriteHTML(w, filename, string(data)) }},
+	}
+	for _, report := range reports {
+		f, err := os.Create(report.path)
+		if err != nil {
+			fmt.Printf("Error creating file: %v\n", err)
+			os.Exit(1)
+		}
+		err = report.write(f)
+		f.Close()
+		if err != nil {
+			fmt.Printf("Error writing coverage report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, profile.SummaryString())
+	fmt.Fprintf(os.Stderr, "coverage reports: %s.cov, %s.cov.txt, %s.cov.html\n", prefix, prefix, prefix)
+}
+
 // compileFileToBytecode 编译文件为字节码并保存
-func compileFileToBytecode(inputFile, outputFile string) {
+// embedSource 为 true 时把源码写入字节码文件的调试段，供 -d 显示
+// 输出文件以 .wasm 结尾时把字节码降级为 WebAssembly 模块；hostFile 非空时同时写出 JavaScript 宿主
+func compileFileToBytecode(inputFile, outputFile string, embedSource bool, hostFile string, opts ...compiler.Option) {
 	fmt.Printf("Compiling %s to %s...\n", inputFile, outputFile)
 	data, err := os.ReadFile(inputFile)
 	if err != nil {
@@ -196,24 +417,29 @@ func compileFileToBytecode(inputFile, outputFile string) {
 		os.Exit(1)
 	}
 
-	parsedAST, err := parser.Parse(inputFile, data)
+	prog, err := parser.ParseProgram(inputFile, data)
 	if err != nil {
 		fmt.Printf("Parse error: %v\n", err)
 		os.Exit(1)
 	}
 
-	prog, ok := parsedAST.(*ast.Program)
-	if !ok {
-		fmt.Println("Error: not a valid BASIC program")
-		os.Exit(1)
-	}
-
-	comp := compiler.New()
+	comp := compiler.New(opts...)
 	chunk, err := comp.Compile(prog)
 	if err != nil {
 		fmt.Printf("Compilation error: %v\n", err)
 		os.Exit(1)
 	}
+	if filepath.Ext(outputFile) == ".wasm" {
+		writeWasm(chunk, outputFile, hostFile)
+		fmt.Println("Compilation successful.")
+		return
+	}
+	if hostFile != "" {
+		fmt.Println("Warning: -wasmhost flag is ignored unless -o names a .wasm file")
+	}
+	if embedSource {
+		chunk.Source = string(data)
+	}
 
 	f, err := os.Create(outputFile)
 	if err != nil {
@@ -229,6 +455,30 @@ func compileFileToBytecode(inputFile, outputFile string) {
 	fmt.Println("Compilation successful.")
 }
 
+// writeWasm 把字节码降级为 WebAssembly 模块写入 outputFile，hostFile 非空时写出宿主脚本
+func writeWasm(chunk *bytecode.Chunk, outputFile, hostFile string) {
+	m, err := codegen.Wasm(chunk)
+	if err != nil {
+		fmt.Printf("Compilation error: %v\n", err)
+		os.Exit(1)
+	}
+	module, err := m.Encode()
+	if err != nil {
+		fmt.Printf("Compilation error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(outputFile, module, 0644); err != nil {
+		fmt.Printf("Error writing module: %v\n", err)
+		os.Exit(1)
+	}
+	if hostFile != "" {
+		if err := os.WriteFile(hostFile, codegen.WasmHost(), 0644); err != nil {
+			fmt.Printf("Error writing host: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
 // printHelp 打印帮助信息
 func printHelp() {
 	fmt.Println("Zork BASIC - A high-performance BASIC interpreter and compiler")
@@ -237,19 +487,40 @@ func printHelp() {
 	fmt.Println("  zb [options] <file.bas>     Run a BASIC source file")
 	fmt.Println("  zb [options] <file.zbc>     Run a compiled bytecode file")
 	fmt.Println("  zb -i                       Start interactive mode (REPL)")
+	fmt.Println("  zb vet [-json] <file.bas>   Report likely bugs without running")
+	fmt.Println("  zb asm <file.zasm>          Assemble a .zasm file to .zbc (-d prints .zasm)")
+	fmt.Println("  zb build [-o prog.go] <file.bas>  Transpile to a standalone Go program")
+	fmt.Println("  zb build -o prog.mjs <file.bas>   Transpile to a JavaScript module (-target js)")
+	fmt.Println("  zb link -o app.zbc <program> <module>...  Link a program with MODULE libraries")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -i, --interactive    Run in interactive mode")
 	fmt.Println("  -v, --version        Show version information")
 	fmt.Println("  -h, --help           Show this help message")
-	fmt.Println("  -mode <ast|vm>       Execution mode for source files (default: vm)")
+	fmt.Println("  -mode <ast|vm|rvm>   Execution mode for source files (default: vm)")
+	fmt.Println("                       rvm runs the register-based VM; with -o it writes register bytecode")
 	fmt.Println("  -o <file.zbc>        Compile source to a bytecode file")
-	fmt.Println("  -d                   Disassemble bytecode (supports .bas and .zbc)")
+	fmt.Println("  -o <file.wasm>       Compile source to a WebAssembly module (imports I/O from the host)")
+	fmt.Println("  -wasmhost <file.mjs> With -o file.wasm, also write a JavaScript host for it")
+	fmt.Println("  -embedsrc            Embed the source in the bytecode file; -d shows it")
+	fmt.Println("  -d                   Disassemble .bas or .zbc to .zasm (same as zb asm -d)")
+	fmt.Println("  -O0, -O1, -O2        Optimization level for the vm compiler (default: -O0)")
+	fmt.Println("                       1 folds constants and fuses instructions, 2 also removes dead code")
+	fmt.Println("                       -O takes a level (-O2, -O=2); ignored by -mode ast, -cover and .zbc files")
+	fmt.Println("  -cover               Record line/branch coverage and write reports")
+	fmt.Println("  -coverout <prefix>   Output prefix for coverage reports")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  zb hello.bas                Run program using VM")
 	fmt.Println("  zb -mode ast hello.bas      Run program using AST interpreter")
+	fmt.Println("  zb -mode rvm -d hello.bas   View register-machine code")
 	fmt.Println("  zb hello.zbc                Run compiled bytecode")
 	fmt.Println("  zb -o hello.zbc hello.bas   Compile to bytecode")
+	fmt.Println("  zb -o prog.wasm -wasmhost host.mjs prog.bas && node host.mjs prog.wasm")
 	fmt.Println("  zb -d hello.bas             View bytecode for source file")
+	fmt.Println("  zb -cover test.bas          Run with coverage (test.cov, test.cov.txt, test.cov.html)")
+	fmt.Println("  zb vet -json prog.bas       Static analysis with JSON output")
+	fmt.Println("  zb build -o prog.go prog.bas && go build prog.go")
+	fmt.Println("  zb build -o prog.mjs prog.bas   Module for a web page: run({print, input})")
+	fmt.Println("  zb -o mathutil.zbc mathutil.bas && zb link main.zbc mathutil.zbc -o app.zbc")
 }
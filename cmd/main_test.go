@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/compiler"
+)
+
+// writeSource 在临时目录中写一个 .bas 文件并返回它的路径
+func writeSource(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prog.bas")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// captureStdout 运行 f 并返回它写到标准输出的内容
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestAsmOptLevel(t *testing.T) {
+	src := writeSource(t, "X = 2 + 3\nPRINT X\n")
+	for _, args := range [][]string{
+		{"-O2", "-d", src},
+		{"-d", src, "-O2"},
+		{"-O=2", "-d", src},
+	} {
+		var code int
+		out := captureStdout(t, func() { code = runAsm(args) })
+		if code != 0 {
+			t.Fatalf("zb asm %s: exit code %d", strings.Join(args, " "), code)
+		}
+		if !strings.Contains(out, ".const k0 5\n") {
+			t.Errorf("zb asm %s: 2 + 3 was not folded:\n%s", strings.Join(args, " "), out)
+		}
+	}
+	out := captureStdout(t, func() { runAsm([]string{"-O2", "-O0", "-d", src}) })
+	if strings.Contains(out, ".const k0 5\n") {
+		t.Errorf("zb asm -O2 -O0 -d: the last level should win:\n%s", out)
+	}
+}
+
+func TestLinkOptLevel(t *testing.T) {
+	src := writeSource(t, "X = 2 + 3\nPRINT X\n")
+	out := filepath.Join(t.TempDir(), "app.zbc")
+	if code := runLink([]string{"-O2", "-o", out, src}); code != 0 {
+		t.Fatalf("zb link -O2: exit code %d", code)
+	}
+	chunk, err := loadChunk(out, "vm", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chunk.Assembly(); !strings.Contains(got, ".const k0 5\n") {
+		t.Errorf("zb link -O2: 2 + 3 was not folded:\n%s", got)
+	}
+	if code := runLink([]string{"-O3", "-o", out, src}); code != 2 {
+		t.Errorf("zb link -O3: exit code %d, want 2", code)
+	}
+}
+
+// chunkBytes 返回 chunk 序列化后的字节
+func chunkBytes(t *testing.T, chunk *bytecode.Chunk) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := chunk.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestDisassembleRoundTrip 检查 zb -d 的输出可以用 zb asm 汇编回逐字节相同的字节码
+func TestDisassembleRoundTrip(t *testing.T) {
+	src := writeSource(t, "10 FOR I = 1 TO 3\n20 IF I = 2 THEN GOSUB 100\n"+
+		"30 PRINT I * 2 + 1; \"x\"\n40 NEXT I\n50 END\n100 PRINT \"two\": RETURN\n")
+	zbc := filepath.Join(t.TempDir(), "prog.zbc")
+	captureStdout(t, func() {
+		compileFileToBytecode(src, zbc, true, "", compiler.WithOptimization(compiler.OptNone))
+	})
+	for _, tc := range []struct {
+		file, mode string
+		level      int
+	}{
+		{src, "vm", 0},
+		{src, "vm", 2},
+		{src, "rvm", 0},
+		{src, "rvm", 2},
+		{zbc, "vm", 0},
+	} {
+		out := captureStdout(t, func() { disassembleFile(tc.file, tc.mode, tc.level) })
+		got, err := bytecode.Assemble(out)
+		if err != nil {
+			t.Fatalf("zb -d -mode %s -O%d %s: %v\n%s", tc.mode, tc.level, filepath.Base(tc.file), err, out)
+		}
+		want, err := loadChunk(tc.file, tc.mode, tc.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(chunkBytes(t, got), chunkBytes(t, want)) {
+			t.Errorf("zb -d -mode %s -O%d %s: reassembled bytecode differs\n%s", tc.mode, tc.level, filepath.Base(tc.file), out)
+		}
+	}
+}
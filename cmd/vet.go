@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vet"
+)
+
+// runVet 执行 zb vet 子命令，对源文件进行静态分析
+// 返回进程退出码：0 表示没有问题，1 表示发现问题，2 表示用法或解析错误
+func runVet(args []string) int {
+	fs := flag.NewFlagSet("vet", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "Output diagnostics as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: zb vet [-json] <file.bas> ...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	exitCode := 0
+	for _, filename := range fs.Args() {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		prog, err := parser.ParseProgram(filename, data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Parse error: %v\n", err)
+			return 2
+		}
+
+		diags := vet.Check(prog)
+		if *jsonOut {
+			err = vet.WriteJSON(os.Stdout, filename, diags)
+		} else {
+			err = vet.WriteText(os.Stdout, filename, diags)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		if len(diags) > 0 {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
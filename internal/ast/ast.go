@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -14,14 +15,52 @@ type Node interface {
 // Program 表示一个完整的 BASIC 程序
 // BASIC 程序由多行组成，每行都有行号
 type Program struct {
-	Lines []*Line // 带行号的语句行，按行号排序
+	Lines      []*Line    // 带行号的语句行，按行号排序
+	Unnumbered bool       // 源文件没有行号，LineNumber 是 parser.ParseProgram 分配的内部行号
+	Module     string     // MODULE 声明的模块名，普通程序为空
+	Routines   []*Routine // 程序中定义或声明的 SUB/FUNCTION，由 parser.ParseProgram 填写
+	Temps      []string   // 主程序中保存函数返回值等的临时变量（"CALL.1"……），由 parser.ParseProgram 填写
+	Decls      []Node     // 展开前的 TYPE 定义和主程序中 DIM ... AS 的声明，由 parser.ParseProgram 保留，供 parser.ExpandDirect 展开直接模式语句
+}
+
+// Routine 描述一个 SUB 或 FUNCTION。parser.ParseProgram 把它展开为 GOSUB 子程序：
+// 参数和局部变量是名为 "<名称>.<变量>" 的全局变量，返回时清零，递归调用前后由
+// SaveLocalsStmt 和 RestoreLocalsStmt 保存和恢复；FUNCTION 的返回值存放在与函数
+// 同名的变量中
+type Routine struct {
+	Name     string   // 链接时使用的名称（大写）；模块中未导出的子程序以 "<模块名>." 开头
+	Params   []string // 参数名（大写），以 $ 结尾的是字符串参数
+	Function bool     // FUNCTION（有返回值）
+	Export   bool     // 模块导出，可以被其他模块调用
+	Extern   bool     // 只有 DECLARE，定义在其他模块中，由 zb link 解析
+	Line     int      // 入口的行号（Extern 时为 -1）
+
+	ParamVars []string // 调用者给参数赋值的全局变量，链接时按名称对应
+	Locals    []string // 参数、返回值、局部变量和临时变量展开后的名称，数组名后加 "()"；Extern 时只有参数和返回值
 }
 
 // Line 表示 BASIC 程序中的一行
-// 每行都有一个行号和零个或多个语句
+// 每行都有一个行号和零个或多个语句。无行号源文件中的行由 parser.ParseProgram
+// 按顺序分配内部行号
 type Line struct {
-	LineNumber int    // 行号（10, 20, 30 等）
-	Statements []Node // 该行包含的语句列表
+	LineNumber int      // 行号（10, 20, 30 等）
+	Label      string   // 行首的标签（"Loop:" 中的 Loop），没有时为空
+	Statements []Node   // 该行包含的语句列表
+	Pos        Position // 该行在源文件中的位置
+}
+
+// Position 表示源文件中的位置，用于在错误信息中指出 $INCLUDE 进来的行
+type Position struct {
+	File string // 文件名
+	Line int    // 文件中的行号（从 1 开始），0 表示未知
+}
+
+// String 返回 "文件:行" 形式的位置
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
 }
 
 // Assignment 表示变量赋值语句
@@ -67,15 +106,18 @@ type NextStmt struct {
 }
 
 // GotoStmt 表示 GOTO 无条件跳转语句
-// 语法: GOTO <行号>
+// 语法: GOTO <行号> 或 GOTO <标签>
 type GotoStmt struct {
-	LineNumber int // 要跳转到的目标行号
+	LineNumber int    // 要跳转到的目标行号
+	Label      string // 目标标签，由 parser.ParseProgram 换成 LineNumber
 }
 
 // GosubStmt 表示 GOSUB 子程序调用语句
-// 语法: GOSUB <行号>
+// 语法: GOSUB <行号> 或 GOSUB <标签>
 type GosubStmt struct {
-	LineNumber int // 子程序开始的行号
+	LineNumber int    // 子程序开始的行号
+	Label      string // 子程序的标签，由 parser.ParseProgram 换成 LineNumber
+	Extern     string // 调用其他模块中的子程序时为它的名称，此时没有 LineNumber
 }
 
 // ReturnStmt 表示 RETURN 语句
@@ -87,6 +129,91 @@ type ReturnStmt struct{}
 // 语法: END
 type EndStmt struct{}
 
+// StopStmt 表示 STOP 语句
+// 暂停程序并显示 "Break in line N"，交互模式下可以用 CONT 从下一条语句继续
+// 语法: STOP
+type StopStmt struct{}
+
+// ChainStmt 表示 CHAIN 语句
+// 载入并运行另一个程序：不带 ALL 时只保留 COMMON 声明的变量，带 ALL 时保留所有变量
+// 语法: CHAIN <文件名>[, [<行号>]][, ALL]
+type ChainStmt struct {
+	File Node // 程序文件名（字符串表达式）
+	Line Node // 开始执行的行号（可选，nil 表示从第一行开始）
+	All  bool // 保留所有变量
+}
+
+// CommonStmt 表示 COMMON 语句
+// 声明 CHAIN 到其他程序时保留的变量和数组，数组写作 A()
+// 语法: COMMON <变量1>[, <变量2>, ...]
+type CommonStmt struct {
+	Vars []Node // Identifier，或没有索引的 ArrayAccess（数组）
+}
+
+// ModuleStmt 表示 MODULE 语句，声明源文件是一个库模块
+// 模块只包含 SUB/FUNCTION 和 DECLARE，单独编译为 .zbc 后由 zb link 链接到程序中
+// 语法: MODULE <模块名>
+type ModuleStmt struct {
+	Name string // 模块名
+}
+
+// SubStmt 表示 SUB 或 FUNCTION 的第一行，到 END SUB / END FUNCTION 为止。参数后的
+// AS <类型> 声明记录参数，调用时传入同类型记录的副本
+// 语法: [EXPORT] SUB <名称>[(<参数1> [AS <类型>], ...)] 或 [EXPORT] FUNCTION <名称>[(...)]
+type SubStmt struct {
+	Name     string        // 名称，字符串函数以 $ 结尾
+	Params   []string      // 参数名
+	Types    []*RecordType // 各参数的记录类型，不是记录的参数为 nil；没有记录参数时整个为 nil。解析时只有 Name，parser.ParseProgram 换成 TYPE 的定义
+	Function bool          // FUNCTION（有返回值）
+	Export   bool          // 模块导出的子程序
+}
+
+// EndSubStmt 表示 END SUB 或 END FUNCTION
+type EndSubStmt struct {
+	Function bool
+}
+
+// ExitSubStmt 表示 EXIT SUB 或 EXIT FUNCTION，立即返回调用者
+type ExitSubStmt struct {
+	Function bool
+}
+
+// DeclareStmt 声明定义在其他模块中的 SUB 或 FUNCTION
+// 语法: DECLARE SUB <名称>[(<参数1> [AS <类型>], ...)] 或 DECLARE FUNCTION <名称>[(...)]
+type DeclareStmt struct {
+	Name     string
+	Params   []string
+	Types    []*RecordType // 同 SubStmt.Types
+	Function bool
+}
+
+// CallStmt 表示 CALL 语句，调用 SUB（或调用 FUNCTION 并丢弃返回值）
+// 语法: CALL <名称>[(<参数1>, ...)]，或不写 CALL：<名称> [<参数1>, ...]
+type CallStmt struct {
+	Name string
+	Args []Node
+	Bare bool // 没有写 CALL
+}
+
+// SharedStmt 表示 SHARED 语句：SUB/FUNCTION 中列出的变量和数组是全局的，不是局部变量
+// 语法: SHARED <变量1>[, <数组名>(), ...]
+type SharedStmt struct {
+	Vars []Node // Identifier，或没有索引的 ArrayAccess（数组）
+}
+
+// SaveLocalsStmt 把变量和数组的当前值压入保存栈，RestoreLocalsStmt 把它们弹出，
+// 恢复为保存时的值。源程序中没有这两条语句：parser.ParseProgram 在可能递归的调用
+// 前后生成它们，保护调用者的局部变量
+type SaveLocalsStmt struct {
+	Vars   []string // 变量名（大写）
+	Arrays []string // 数组名（大写）
+}
+
+// RestoreLocalsStmt 按与 Saved 相反的顺序弹出它保存的变量和数组
+type RestoreLocalsStmt struct {
+	Saved *SaveLocalsStmt
+}
+
 // IfBlockStmt 表示多行 IF 语句的开头
 // 语法: IF <条件> THEN
 type IfBlockStmt struct {
@@ -107,18 +234,126 @@ type RemStmt struct {
 	Text string // 注释文本（包括 REM 关键字）
 }
 
-// DimStmt 表示 DIM 数组声明语句
-// 语法: DIM <数组名>(<大小1>[, <大小2>, ...])
+// DimStmt 表示 DIM 数组声明语句，带 AS 时声明记录、记录数组或字典（AS MAP）
+// 语法: DIM <数组名>(<大小1>[, <大小2>, ...]) [AS <类型>]
+// 语法: DIM <变量名> AS <类型>
 type DimStmt struct {
-	Name  string // 数组名
-	Sizes []Node // 数组各维度的大小（表达式列表）
+	Name   string      // 数组名
+	Sizes  []Node      // 数组各维度的大小（表达式列表），记录变量和字典为空
+	Type   string      // AS 之后的 TYPE 名称或 MAP，普通数组为空
+	Record *RecordType // 声明记录时的类型，由 parser.ParseProgram 填写
+}
+
+// ForEachStmt 表示遍历字典的 FOR EACH 语句，按键的顺序把循环开始时的每个键赋给
+// 循环变量，以 NEXT 结束；字典为空时跳到对应的 NEXT 之后
+// 语法: FOR EACH <字符串变量> IN <字典>
+type ForEachStmt struct {
+	Var string // 循环变量名，以 $ 结尾
+	Map string // 字典名
+}
+
+// DeleteKeyStmt 表示从字典中删除一个键，键不存在时什么也不做
+// 语法: DELETEKEY <字典>, <键>
+type DeleteKeyStmt struct {
+	Map string // 字典名
+	Key Node   // 键（字符串表达式）
+}
+
+// RecordType 是 TYPE 定义的记录类型。记录的值按字段顺序存放在连续的槽位中：
+// STRING 和数值字段各占一个槽位，类型为 TYPE 的字段占用该类型的全部槽位。记录变量
+// 和记录数组与数组放在同一个表中，每个元素占 Size() 个槽位
+type RecordType struct {
+	Name   string // 源程序中的类型名
+	Fields []*RecordField
+}
+
+// RecordField 是记录类型的一个字段
+type RecordField struct {
+	Name   string      // 字段名（大写）
+	String bool        // STRING 字段
+	Record *RecordType // 类型为 TYPE 的字段，否则为 nil
+}
+
+// Size 返回字段占用的槽位数
+func (f *RecordField) Size() int {
+	if f.Record != nil {
+		return f.Record.Size()
+	}
+	return 1
+}
+
+// Size 返回记录占用的槽位数
+func (t *RecordType) Size() int {
+	n := 0
+	for _, f := range t.Fields {
+		n += f.Size()
+	}
+	return n
+}
+
+// Field 返回名为 name 的字段和它的第一个槽位，没有这个字段时返回 nil
+func (t *RecordType) Field(name string) (*RecordField, int) {
+	slot := 0
+	for _, f := range t.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, slot
+		}
+		slot += f.Size()
+	}
+	return nil, 0
+}
+
+// Shape 返回每个槽位的类型，'$' 是字符串，'#' 是数值，与 INPUT 的字段类型一致
+func (t *RecordType) Shape() string {
+	var b strings.Builder
+	for _, f := range t.Fields {
+		switch {
+		case f.Record != nil:
+			b.WriteString(f.Record.Shape())
+		case f.String:
+			b.WriteByte('$')
+		default:
+			b.WriteByte('#')
+		}
+	}
+	return b.String()
 }
 
-// InputStmt 表示 INPUT 输入语句
-// 语法: INPUT ["提示字符串",] <变量名1>[, <变量名2>, ...]
+// TypeStmt 表示 TYPE 记录类型定义的开头，之后是字段声明，以 END TYPE 结束
+// 语法: TYPE <类型名>
+type TypeStmt struct {
+	Name string
+}
+
+// FieldStmt 表示 TYPE 中的字段声明，类型是 STRING、数值类型（DOUBLE、SINGLE、
+// INTEGER、LONG）或另一个 TYPE
+// 语法: <字段名> AS <类型>
+type FieldStmt struct {
+	Name string
+	Type string
+}
+
+// EndTypeStmt 表示 TYPE 定义的结束
+// 语法: END TYPE
+type EndTypeStmt struct{}
+
+// InputStmt 表示 INPUT 或 LINE INPUT 输入语句
+// 语法: INPUT ["提示字符串"{;|,}] <目标1>[, <目标2>, ...]
+// 语法: LINE INPUT ["提示字符串"{;|,}] <字符串目标>
 type InputStmt struct {
-	Prompt string   // 可选的提示字符串
-	Vars   []string // 要接收输入的变量名列表（支持多个变量）
+	Prompt   string // 可选的提示字符串
+	Question bool   // 提示字符串后是分号：INPUT 在提示后再显示 "? "
+	Line     bool   // LINE INPUT：把整行读入一个字符串变量
+	Vars     []Node // 接收输入的目标（Identifier 或 ArrayAccess）
+}
+
+// PromptText 返回读取输入前显示的提示。没有提示字符串或提示后是分号时，
+// INPUT 显示 "? "；LINE INPUT 只显示提示字符串
+func (i *InputStmt) PromptText() string {
+	if !i.Line && (i.Prompt == "" || i.Question) {
+		return i.Prompt + "? "
+	}
+	return i.Prompt
 }
 
 // BinaryOp 表示二元算术运算表达式
@@ -172,6 +407,82 @@ type ArrayAccess struct {
 	Indices []Node // 索引表达式列表
 }
 
+// FieldAccess 表示记录的字段。字段的类型是 TYPE 时它本身也是一个记录
+// 语法: <记录>.<字段>，记录是变量、数组元素或另一个 FieldAccess
+type FieldAccess struct {
+	Record Node        // Identifier、ArrayAccess 或 FieldAccess，parser.ParseProgram 把前两者换成 RecordRef
+	Field  string      // 字段名
+	Type   *RecordType // Record 的类型，由 parser.ParseProgram 填写
+}
+
+// Info 返回访问的字段和它在 Record 中的第一个槽位
+func (f *FieldAccess) Info() (*RecordField, int) {
+	return f.Type.Field(f.Field)
+}
+
+// Slot 返回字段所在的记录变量或记录数组元素，以及字段在其中的第一个槽位
+func (f *FieldAccess) Slot() (*RecordRef, int) {
+	_, slot := f.Info()
+	switch r := f.Record.(type) {
+	case *FieldAccess:
+		ref, base := r.Slot()
+		return ref, base + slot
+	case *RecordRef:
+		return r, slot
+	}
+	return nil, 0
+}
+
+// RecordRef 表示一个完整的记录：记录变量或记录数组的元素。parser.ParseProgram 把
+// 记录名对应的 Identifier 和 ArrayAccess 换成 RecordRef
+type RecordRef struct {
+	Name    string      // 记录变量或记录数组名
+	Indices []Node      // 记录数组元素的索引，记录变量为 nil
+	Type    *RecordType // 记录的类型
+}
+
+// RecordOf 返回完整记录 n（RecordRef 或类型为 TYPE 的 FieldAccess）所在的记录变量或
+// 记录数组元素、它的第一个槽位和类型。n 不是完整记录时返回的 RecordRef 为 nil
+func RecordOf(n Node) (*RecordRef, int, *RecordType) {
+	switch r := n.(type) {
+	case *RecordRef:
+		return r, 0, r.Type
+	case *FieldAccess:
+		if f, _ := r.Info(); f != nil && f.Record != nil {
+			ref, slot := r.Slot()
+			return ref, slot, f.Record
+		}
+	}
+	return nil, 0, nil
+}
+
+// RecordAssignment 表示同类型记录之间的整体赋值，逐个槽位复制 Value 的值。
+// parser.ParseProgram 把目标是完整记录的 Assignment 换成 RecordAssignment
+// 语法: [LET] <记录> = <记录>
+type RecordAssignment struct {
+	Target Node // RecordRef 或类型为 TYPE 的 FieldAccess
+	Value  Node // 同上，类型与 Target 相同
+}
+
+// MapAccess 表示字典中一个键对应的值，可以读取、赋值或作为 INPUT 的目标。
+// 读取不存在的键得到 0（以 $ 结尾的字典得到空串）。parser.ParseProgram 把
+// 字典名后的 ArrayAccess 换成 MapAccess
+// 语法: <字典>(<键>)
+type MapAccess struct {
+	Name string // 字典名
+	Key  Node   // 键（字符串表达式）
+}
+
+// MapFunc 表示以字典为第一个参数的函数，由 parser.ParseProgram 从函数调用换来：
+//   - HASKEY(<字典>, <键>)：字典中有该键时为 1，否则为 0
+//   - KEYS$(<字典>, <序号>)：按顺序的第几个键，从 0 开始
+//   - COUNT(<字典>)：键的个数
+type MapFunc struct {
+	Func string // "HASKEY"、"KEYS$" 或 "COUNT"
+	Map  string // 字典名
+	Arg  Node   // 第二个参数，COUNT 为 nil
+}
+
 // Number 表示数字字面量
 // 支持整数和浮点数
 type Number struct {
@@ -189,25 +500,23 @@ type StringLiteral struct {
 func (p *Program) String() string {
 	result := "Program:\n"
 	for _, line := range p.Lines {
-		result += fmt.Sprintf("%4d: ", line.LineNumber)
-		for i, stmt := range line.Statements {
-			if i > 0 {
-				result += ": "
-			}
-			result += stmt.String()
-		}
-		result += "\n"
+		result += line.String() + "\n"
 	}
 	return result
 }
 
 // String 返回行的字符串表示
-// 格式: "<行号>: <语句1>: <语句2>: ..."
+// 格式: "<行号>: [<标签>: ]<语句1>: <语句2>: ..."
 func (l *Line) String() string {
 	result := fmt.Sprintf("%4d: ", l.LineNumber)
+	if l.Label != "" {
+		result += l.Label + ":"
+	}
 	for i, stmt := range l.Statements {
 		if i > 0 {
 			result += ": "
+		} else if l.Label != "" {
+			result += " "
 		}
 		result += stmt.String()
 	}
@@ -278,14 +587,23 @@ func (n *NextStmt) String() string {
 }
 
 // String 返回 GOTO 语句的字符串表示
-// 格式: "GOTO <行号>"
+// 格式: "GOTO <行号>" 或 "GOTO <标签>"
 func (g *GotoStmt) String() string {
+	if g.Label != "" {
+		return "GOTO " + g.Label
+	}
 	return fmt.Sprintf("GOTO %d", g.LineNumber)
 }
 
 // String 返回 GOSUB 语句的字符串表示
-// 格式: "GOSUB <行号>"
+// 格式: "GOSUB <行号>" 或 "GOSUB <标签>"，调用其他模块时为 "GOSUB <名称>"
 func (g *GosubStmt) String() string {
+	if g.Extern != "" {
+		return "GOSUB " + g.Extern
+	}
+	if g.Label != "" {
+		return "GOSUB " + g.Label
+	}
 	return fmt.Sprintf("GOSUB %d", g.LineNumber)
 }
 
@@ -301,6 +619,137 @@ func (e *EndStmt) String() string {
 	return "END"
 }
 
+// String 返回 STOP 语句的字符串表示
+// 格式: "STOP"
+func (s *StopStmt) String() string {
+	return "STOP"
+}
+
+// String 返回 CHAIN 语句的字符串表示
+// 格式: "CHAIN <文件名>[, [<行号>]][, ALL]"
+func (c *ChainStmt) String() string {
+	result := "CHAIN " + c.File.String()
+	if c.Line != nil {
+		result += ", " + c.Line.String()
+	} else if c.All {
+		result += ","
+	}
+	if c.All {
+		result += ", ALL"
+	}
+	return result
+}
+
+// String 返回 COMMON 语句的字符串表示
+// 格式: "COMMON <变量1>[, <变量2>, ...]"
+func (c *CommonStmt) String() string {
+	vars := make([]string, len(c.Vars))
+	for i, v := range c.Vars {
+		vars[i] = v.String()
+	}
+	return "COMMON " + strings.Join(vars, ", ")
+}
+
+// String 返回 MODULE 语句的字符串表示
+// 格式: "MODULE <模块名>"
+func (m *ModuleStmt) String() string {
+	return "MODULE " + m.Name
+}
+
+// routineKind 返回 "SUB" 或 "FUNCTION"
+func routineKind(function bool) string {
+	if function {
+		return "FUNCTION"
+	}
+	return "SUB"
+}
+
+// routineHeader 返回 "<名称>(<参数1>, ...)"，没有参数时省略括号。types 不为空时
+// 记录参数后加 " AS <类型>"
+func routineHeader(name string, params []string, types []*RecordType) string {
+	if len(params) == 0 {
+		return name
+	}
+	list := slices.Clone(params)
+	for i, t := range types {
+		if t != nil {
+			list[i] += " AS " + t.Name
+		}
+	}
+	return name + "(" + strings.Join(list, ", ") + ")"
+}
+
+// String 返回 SUB/FUNCTION 第一行的字符串表示
+// 格式: "[EXPORT ]SUB <名称>[(<参数1>, ...)]"
+func (s *SubStmt) String() string {
+	result := routineKind(s.Function) + " " + routineHeader(s.Name, s.Params, s.Types)
+	if s.Export {
+		result = "EXPORT " + result
+	}
+	return result
+}
+
+// String 返回 "END SUB" 或 "END FUNCTION"
+func (e *EndSubStmt) String() string {
+	return "END " + routineKind(e.Function)
+}
+
+// String 返回 "EXIT SUB" 或 "EXIT FUNCTION"
+func (e *ExitSubStmt) String() string {
+	return "EXIT " + routineKind(e.Function)
+}
+
+// String 返回 DECLARE 语句的字符串表示
+// 格式: "DECLARE SUB <名称>[(<参数1>, ...)]"
+func (d *DeclareStmt) String() string {
+	return "DECLARE " + routineKind(d.Function) + " " + routineHeader(d.Name, d.Params, d.Types)
+}
+
+// String 返回 CALL 语句的字符串表示
+// 格式: "CALL <名称>[(<参数1>, ...)]" 或 "<名称> [<参数1>, ...]"
+func (c *CallStmt) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	if c.Bare {
+		if len(args) == 0 {
+			return c.Name
+		}
+		return c.Name + " " + strings.Join(args, ", ")
+	}
+	return "CALL " + routineHeader(c.Name, args, nil)
+}
+
+// String 返回 SHARED 语句的字符串表示
+// 格式: "SHARED <变量1>[, <变量2>, ...]"
+func (s *SharedStmt) String() string {
+	vars := make([]string, len(s.Vars))
+	for i, v := range s.Vars {
+		vars[i] = v.String()
+	}
+	return "SHARED " + strings.Join(vars, ", ")
+}
+
+// String 返回 "SAVE LOCALS <变量1>, ..., <数组1>(), ..."
+func (s *SaveLocalsStmt) String() string {
+	return "SAVE LOCALS " + localNames(s)
+}
+
+// String 返回 "RESTORE LOCALS <变量1>, ..., <数组1>(), ..."
+func (r *RestoreLocalsStmt) String() string {
+	return "RESTORE LOCALS " + localNames(r.Saved)
+}
+
+// localNames 列出 s 保存的变量和数组
+func localNames(s *SaveLocalsStmt) string {
+	names := slices.Clone(s.Vars)
+	for _, name := range s.Arrays {
+		names = append(names, name+"()")
+	}
+	return strings.Join(names, ", ")
+}
+
 func (i *IfBlockStmt) String() string {
 	return fmt.Sprintf("IF %s THEN", i.Condition.String())
 }
@@ -320,27 +769,70 @@ func (r *RemStmt) String() string {
 }
 
 // String 返回 DIM 数组声明语句的字符串表示
-// 格式: "DIM <数组名>(<大小1>[, <大小2>, ...])"
+// 格式: "DIM <数组名>(<大小1>[, <大小2>, ...])[ AS <类型>]" 或 "DIM <变量名> AS <类型>"
 func (d *DimStmt) String() string {
-	sizes := make([]string, len(d.Sizes))
-	for i, size := range d.Sizes {
-		sizes[i] = size.String()
+	result := "DIM " + d.Name
+	if len(d.Sizes) > 0 {
+		sizes := make([]string, len(d.Sizes))
+		for i, size := range d.Sizes {
+			sizes[i] = size.String()
+		}
+		result += "(" + strings.Join(sizes, ", ") + ")"
 	}
-	return fmt.Sprintf("DIM %s(%s)", d.Name, strings.Join(sizes, ", "))
+	if d.Type != "" {
+		result += " AS " + d.Type
+	}
+	return result
+}
+
+// String 返回 FOR EACH 语句的字符串表示
+// 格式: "FOR EACH <变量> IN <字典>"
+func (f *ForEachStmt) String() string {
+	return fmt.Sprintf("FOR EACH %s IN %s", f.Var, f.Map)
+}
+
+// String 返回 DELETEKEY 语句的字符串表示
+// 格式: "DELETEKEY <字典>, <键>"
+func (d *DeleteKeyStmt) String() string {
+	return fmt.Sprintf("DELETEKEY %s, %s", d.Map, d.Key.String())
+}
+
+// String 返回 TYPE 语句的字符串表示
+// 格式: "TYPE <类型名>"
+func (t *TypeStmt) String() string {
+	return "TYPE " + t.Name
+}
+
+// String 返回字段声明的字符串表示
+// 格式: "<字段名> AS <类型>"
+func (f *FieldStmt) String() string {
+	return f.Name + " AS " + f.Type
+}
+
+// String 返回 "END TYPE"
+func (e *EndTypeStmt) String() string {
+	return "END TYPE"
 }
 
 // String 返回 INPUT 输入语句的字符串表示
-// 格式: "INPUT [<提示字符串>,] <变量名1>[, <变量名2>, ...]"
+// 格式: "[LINE ]INPUT [<提示字符串>{;|,}] <目标1>[, <目标2>, ...]"
 func (i *InputStmt) String() string {
 	result := "INPUT"
+	if i.Line {
+		result = "LINE INPUT"
+	}
 	if i.Prompt != "" {
-		result += fmt.Sprintf(" \"%s\",", i.Prompt)
+		sep := ","
+		if i.Question {
+			sep = ";"
+		}
+		result += fmt.Sprintf(" \"%s\"%s", i.Prompt, sep)
 	}
 	for idx, v := range i.Vars {
 		if idx > 0 {
-			result += ", "
+			result += ","
 		}
-		result += v
+		result += " " + v.String()
 	}
 	return result
 }
@@ -399,6 +891,42 @@ func (a *ArrayAccess) String() string {
 	return a.Name + "(" + strings.Join(indices, ", ") + ")"
 }
 
+// String 返回字段访问的字符串表示
+// 格式: "<记录>.<字段>"
+func (f *FieldAccess) String() string {
+	return f.Record.String() + "." + f.Field
+}
+
+// String 返回记录的字符串表示
+// 格式: "<记录变量>" 或 "<记录数组>(<索引1>[, <索引2>, ...])"
+func (r *RecordRef) String() string {
+	if len(r.Indices) == 0 {
+		return r.Name
+	}
+	return (&ArrayAccess{Name: r.Name, Indices: r.Indices}).String()
+}
+
+// String 返回记录整体赋值的字符串表示
+// 格式: "LET <记录> = <记录>"
+func (a *RecordAssignment) String() string {
+	return fmt.Sprintf("LET %s = %s", a.Target.String(), a.Value.String())
+}
+
+// String 返回字典访问的字符串表示
+// 格式: "<字典>(<键>)"
+func (m *MapAccess) String() string {
+	return m.Name + "(" + m.Key.String() + ")"
+}
+
+// String 返回字典函数的字符串表示
+// 格式: "<函数名>(<字典>[, <参数>])"
+func (m *MapFunc) String() string {
+	if m.Arg == nil {
+		return m.Func + "(" + m.Map + ")"
+	}
+	return m.Func + "(" + m.Map + ", " + m.Arg.String() + ")"
+}
+
 // String 返回数字的字符串表示
 // 使用最短的有效格式（整数不带小数点，浮点数根据需要显示）
 func (n *Number) String() string {
@@ -0,0 +1,642 @@
+package bytecode
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zork-basic/internal/interpreter"
+)
+
+// .zasm is the textual form of a chunk. Chunk.Assembly writes it and
+// Assemble reads it back into an identical chunk:
+//
+//	; comment
+//	.registers                 code targets the register machine
+//	.module MATHUTIL           the chunk is a module with this name
+//	.globals 3                 GlobalCount
+//	.arrays 1                  ArrayCount
+//	.global 0 I                variable (or register) name
+//	.array 0 A                 array name
+//	.const k0 "text"           constant pool entry, numbers as Go floats
+//	.position 3 "main.bas:5"   source position of an internal line number
+//	.source "10 PRINT X\n"     a piece of the embedded program source
+//	.export FUNCTION HYP L0005 A B   exported routine, its entry and parameters
+//	.import SUB BUMP N         routine defined in another module
+//	.line 10                   source line of the instructions that follow
+//	; 10 PRINT X               that line of the embedded source, if any
+//	L0012:                     label
+//	        Jump    L0012      instruction
+//
+// Mnemonics are opcode names without the Op prefix. Operands are written
+// by kind: variables by name (or gN / rN when unnamed), constants as kN,
+// arrays by name (or aN), jump targets as labels (or, for a Gosub to an
+// imported routine, its name), builtins and comparisons by name, and counts
+// as plain numbers. Register operands use the same
+// letters as registerLayouts; a register-or-constant operand is rN, a
+// variable name or kN.
+
+// stackLayouts gives the operand kinds of stack instructions, in the
+// letters of registerLayouts plus:
+//
+//	g variable   K constant
+var stackLayouts = map[OpCode]string{
+	OpConstant:       "K",
+	OpJump:           "j",
+	OpJumpIfFalse:    "j",
+	OpGosub:          "j",
+	OpForInit:        "g",
+	OpNext:           "gj",
+	OpGetGlobal:      "g",
+	OpSetGlobal:      "g",
+	OpGetArray:       "an",
+	OpSetArray:       "an",
+	OpInput:          "g",
+	OpCallBuiltin:    "fn",
+	OpDim:            "an",
+	OpCover:          "n",
+	OpGetGlobal2:     "gg",
+	OpAddGlobalConst: "gK",
+	OpIncGlobal:      "g",
+	OpCmpJump:        "cj",
+	OpReadInput:      "KK",
+	OpLineInput:      "K",
+	OpChain:          "K",
+	OpDimMap:         "a",
+	OpForEach:        "agj",
+	OpNextEach:       "gj",
+	OpMapGet:         "a",
+	OpMapSet:         "a",
+	OpMapHas:         "a",
+	OpMapDelete:      "a",
+	OpMapKey:         "a",
+	OpMapCount:       "a",
+	OpSaveGlobal:     "g",
+	OpRestoreGlobal:  "g",
+	OpSaveArray:      "a",
+	OpRestoreArray:   "a",
+	OpDimRecord:      "anK",
+	OpGetField:       "ans",
+	OpSetField:       "ans",
+	OpCopyRecord:     "ansanss",
+}
+
+// operandLayout returns the operand kinds of op
+func operandLayout(op OpCode) string {
+	if layout, ok := registerLayouts[op]; ok {
+		return layout
+	}
+	return stackLayouts[op]
+}
+
+// Assembly returns the chunk as .zasm source that Assemble turns back into
+// the same chunk
+func (c *Chunk) Assembly() string {
+	var out strings.Builder
+	out.WriteString("; zork-basic assembly\n")
+	if c.Registers {
+		out.WriteString(".registers\n")
+	}
+	if c.Module != "" {
+		fmt.Fprintf(&out, ".module %s\n", c.Module)
+	}
+	fmt.Fprintf(&out, ".globals %d\n", c.GlobalCount)
+	fmt.Fprintf(&out, ".arrays %d\n", c.ArrayCount)
+	for i, name := range c.GlobalNames {
+		if name != "" {
+			fmt.Fprintf(&out, ".global %d %s\n", i, name)
+		}
+	}
+	for i, name := range c.ArrayNames {
+		if name != "" {
+			fmt.Fprintf(&out, ".array %d %s\n", i, name)
+		}
+	}
+	for i, val := range c.Constants {
+		fmt.Fprintf(&out, ".const k%d %s\n", i, formatConstant(val))
+	}
+	for _, line := range slices.Sorted(maps.Keys(c.Positions)) {
+		fmt.Fprintf(&out, ".position %d %s\n", line, strconv.Quote(c.Positions[line]))
+	}
+	if c.Source != "" {
+		for _, text := range strings.SplitAfter(c.Source, "\n") {
+			if text != "" {
+				fmt.Fprintf(&out, ".source %s\n", strconv.Quote(text))
+			}
+		}
+	}
+
+	insts, err := c.decode()
+	if err != nil {
+		// Not decodable; fall back to the listing so nothing is hidden
+		fmt.Fprintf(&out, "; %v\n", err)
+		for _, text := range strings.Split(c.Disassemble("chunk"), "\n") {
+			fmt.Fprintf(&out, "; %s\n", text)
+		}
+		return out.String()
+	}
+
+	// Gosubs to imported routines name the routine instead of a label
+	calls := make(map[int]string)
+	for _, sym := range c.Imports {
+		fmt.Fprintf(&out, ".import %s\n", symbolFields(sym))
+		for _, call := range sym.Calls {
+			calls[call] = sym.Name
+		}
+	}
+	labels := make(map[int]bool)
+	for _, sym := range c.Exports {
+		labels[sym.Offset] = true
+		fmt.Fprintf(&out, ".export %s\n", symbolFields(sym, label(sym.Offset)))
+	}
+	for _, inst := range insts {
+		if i := jumpOperand(inst.op); i >= 0 && calls[inst.offset] == "" {
+			labels[inst.operands[i]] = true
+		}
+	}
+	starts := make(map[int]bool, len(insts))
+	for _, inst := range insts {
+		starts[inst.offset] = true
+	}
+
+	source := c.sourceLines()
+	line := -1
+	for _, inst := range insts {
+		if inst.line != line {
+			line = inst.line
+			fmt.Fprintf(&out, "\n.line %d\n", line)
+			if text, ok := source[line]; ok {
+				fmt.Fprintf(&out, "; %s\n", text)
+			}
+		}
+		if labels[inst.offset] {
+			fmt.Fprintf(&out, "%s:\n", label(inst.offset))
+		}
+		def, _ := Lookup(inst.op)
+		mnemonic := strings.TrimPrefix(def.Name, "Op")
+		if len(inst.operands) == 0 {
+			fmt.Fprintf(&out, "\t%s\n", mnemonic)
+			continue
+		}
+		fields := make([]string, len(inst.operands))
+		layout := operandLayout(inst.op)
+		for i, val := range inst.operands {
+			fields[i] = c.formatOperand(layout[i], val, starts)
+		}
+		if name := calls[inst.offset]; name != "" {
+			fields[0] = name
+		}
+		fmt.Fprintf(&out, "\t%-16s%s\n", mnemonic, strings.Join(fields, " "))
+	}
+	if labels[len(c.Code)] {
+		fmt.Fprintf(&out, "%s:\n", label(len(c.Code)))
+	}
+	return out.String()
+}
+
+// symbolFields writes an export or import as directive arguments: kind,
+// name, the extra fields (the entry label of an export), then parameters
+func symbolFields(sym Symbol, extra ...string) string {
+	kind := "SUB"
+	if sym.Function {
+		kind = "FUNCTION"
+	}
+	fields := append([]string{kind, sym.Name}, extra...)
+	return strings.Join(append(fields, sym.Params...), " ")
+}
+
+func label(offset int) string {
+	return fmt.Sprintf("L%04d", offset)
+}
+
+func formatConstant(val interpreter.Value) string {
+	if val.IsString() {
+		return strconv.Quote(val.String())
+	}
+	return strconv.FormatFloat(val.AsNumber(), 'g', -1, 64)
+}
+
+// formatOperand writes one operand of the given kind
+func (c *Chunk) formatOperand(kind byte, val int, starts map[int]bool) string {
+	switch kind {
+	case 'g', 'r':
+		if name := symbolName(c.GlobalNames, val); name != "" {
+			return name
+		}
+		return fmt.Sprintf("%c%s", kind, operandText(val))
+	case 'k':
+		if k, constant := RegOperand(val); constant {
+			return fmt.Sprintf("k%d", k)
+		}
+		return c.formatOperand('r', val, starts)
+	case 'K':
+		return "k" + operandText(val)
+	case 'j':
+		if starts[val] || val == len(c.Code) {
+			return label(val)
+		}
+	case 'c':
+		if isComparison(OpCode(val)) {
+			def, _ := Lookup(OpCode(val))
+			return strings.TrimPrefix(def.Name, "Op")
+		}
+	case 'a':
+		if name := symbolName(c.ArrayNames, val); name != "" {
+			return name
+		}
+		return "a" + operandText(val)
+	case 'f':
+		if val >= 0 && val < len(BuiltinNames) {
+			return BuiltinNames[val]
+		}
+	}
+	return operandText(val)
+}
+
+// operandText prints an operand as an unsigned number: on 32-bit targets a
+// 4-byte operand with the top bit set is a negative int
+func operandText(val int) string {
+	return strconv.FormatUint(uint64(uint32(val)), 10)
+}
+
+// parseOperand parses an unsigned operand of up to 4 bytes, the inverse of
+// operandText
+func parseOperand(s string) (int, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	return int(uint32(n)), err
+}
+
+// mnemonics maps upper-cased mnemonics to opcodes
+var mnemonics = func() map[string]OpCode {
+	m := make(map[string]OpCode, len(definitions))
+	for op, def := range definitions {
+		m[strings.ToUpper(strings.TrimPrefix(def.Name, "Op"))] = op
+	}
+	return m
+}()
+
+// numbered matches generated operand names such as r3, k0 or a1
+var numbered = regexp.MustCompile(`^([gkra])([0-9]+)$`)
+
+// labelFixup is a jump operand waiting for its label to be defined
+type labelFixup struct {
+	offset int // Offset of the operand in Code
+	label  string
+	line   int // .zasm line, for errors
+}
+
+// assembler holds the state of one Assemble call
+type assembler struct {
+	chunk   *Chunk
+	globals map[string]int
+	arrays  map[string]int
+	labels  map[string]int
+	fixups  []labelFixup
+	exports []labelFixup   // Entry labels of c.Exports, by index
+	imports map[string]int // Imported routine -> index in c.Imports
+	consts  map[int]interpreter.Value
+	line    int // Current BASIC line
+	srcLine int // Current .zasm line
+}
+
+// Assemble parses .zasm source into a chunk. Errors name the .zasm line.
+func Assemble(src string) (*Chunk, error) {
+	a := &assembler{
+		chunk:   NewChunk(),
+		globals: make(map[string]int),
+		arrays:  make(map[string]int),
+		labels:  make(map[string]int),
+		imports: make(map[string]int),
+		consts:  make(map[int]interpreter.Value),
+	}
+	for i, text := range strings.Split(src, "\n") {
+		a.srcLine = i + 1
+		fields, err := splitFields(text)
+		if err == nil {
+			err = a.assembleLine(fields)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+	}
+
+	for _, f := range a.fixups {
+		target, ok := a.labels[f.label]
+		if !ok {
+			return nil, fmt.Errorf("line %d: undefined label %s", f.line, f.label)
+		}
+		copy(a.chunk.Code[f.offset:], AppendOperand(nil, jumpWidth, target))
+	}
+	for i, f := range a.exports {
+		target, ok := a.labels[f.label]
+		if !ok {
+			return nil, fmt.Errorf("line %d: undefined label %s", f.line, f.label)
+		}
+		a.chunk.Exports[i].Offset = target
+	}
+
+	c := a.chunk
+	c.Constants = make([]interpreter.Value, len(a.consts))
+	for i := range c.Constants {
+		val, ok := a.consts[i]
+		if !ok {
+			return nil, fmt.Errorf("constant k%d is not defined", i)
+		}
+		c.Constants[i] = val
+	}
+	if len(c.GlobalNames) > c.GlobalCount {
+		return nil, fmt.Errorf("%d variables named but .globals is %d", len(c.GlobalNames), c.GlobalCount)
+	}
+	if len(c.ArrayNames) > c.ArrayCount {
+		return nil, fmt.Errorf("%d arrays named but .arrays is %d", len(c.ArrayNames), c.ArrayCount)
+	}
+	return c, nil
+}
+
+// jumpWidth is the width of every jump operand
+const jumpWidth = 4
+
+// splitFields splits a line into whitespace-separated fields, keeping
+// quoted strings whole and dropping ; comments
+func splitFields(text string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(text); {
+		switch ch := text[i]; {
+		case ch == ';':
+			return fields, nil
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			i++
+		case ch == '"':
+			quoted, err := strconv.QuotedPrefix(text[i:])
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			fields = append(fields, quoted)
+			i += len(quoted)
+		default:
+			j := i
+			for j < len(text) && !strings.ContainsRune(" \t\r;\"", rune(text[j])) {
+				j++
+			}
+			fields = append(fields, text[i:j])
+			i = j
+		}
+	}
+	return fields, nil
+}
+
+func (a *assembler) assembleLine(fields []string) error {
+	if len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+		name := strings.TrimSuffix(fields[0], ":")
+		if _, dup := a.labels[name]; dup {
+			return fmt.Errorf("label %s defined twice", name)
+		}
+		a.labels[name] = len(a.chunk.Code)
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(fields[0], ".") {
+		return a.directive(fields[0], fields[1:])
+	}
+	return a.instruction(fields[0], fields[1:])
+}
+
+func (a *assembler) directive(name string, args []string) error {
+	c := a.chunk
+	if name == ".export" || name == ".import" {
+		return a.symbol(name, args)
+	}
+	want := map[string]int{".registers": 0, ".module": 1, ".globals": 1, ".arrays": 1, ".global": 2, ".array": 2, ".const": 2, ".source": 1, ".line": 1, ".position": 2}
+	n, ok := want[name]
+	if !ok {
+		return fmt.Errorf("unknown directive %s", name)
+	}
+	if len(args) != n {
+		return fmt.Errorf("%s takes %d arguments, got %d", name, n, len(args))
+	}
+
+	switch name {
+	case ".registers":
+		c.Registers = true
+	case ".module":
+		c.Module = args[0]
+	case ".globals", ".arrays", ".line":
+		val, err := strconv.Atoi(args[0])
+		if err != nil || val < 0 {
+			return fmt.Errorf("%s: bad number %q", name, args[0])
+		}
+		switch name {
+		case ".globals":
+			c.GlobalCount = val
+		case ".arrays":
+			c.ArrayCount = val
+		default:
+			a.line = val
+		}
+	case ".global", ".array":
+		idx, err := strconv.Atoi(args[0])
+		if err != nil || idx < 0 {
+			return fmt.Errorf("%s: bad index %q", name, args[0])
+		}
+		sym := args[1]
+		if numbered.MatchString(sym) || isNumber(sym) || strings.HasPrefix(sym, "\"") {
+			return fmt.Errorf("%s: %q cannot be used as a name", name, sym)
+		}
+		names, symbols := &c.GlobalNames, a.globals
+		if name == ".array" {
+			names, symbols = &c.ArrayNames, a.arrays
+		}
+		if _, dup := symbols[sym]; dup {
+			return fmt.Errorf("%s: %s named twice", name, sym)
+		}
+		for len(*names) <= idx {
+			*names = append(*names, "")
+		}
+		if (*names)[idx] != "" {
+			return fmt.Errorf("%s: index %d named twice", name, idx)
+		}
+		(*names)[idx] = sym
+		symbols[sym] = idx
+	case ".const":
+		m := numbered.FindStringSubmatch(args[0])
+		if m == nil || m[1] != "k" {
+			return fmt.Errorf(".const: bad constant %q", args[0])
+		}
+		idx, _ := strconv.Atoi(m[2])
+		if _, dup := a.consts[idx]; dup {
+			return fmt.Errorf(".const: k%d defined twice", idx)
+		}
+		if strings.HasPrefix(args[1], "\"") {
+			s, err := strconv.Unquote(args[1])
+			if err != nil {
+				return fmt.Errorf(".const: bad string %s", args[1])
+			}
+			a.consts[idx] = interpreter.StringValue(s)
+		} else {
+			num, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return fmt.Errorf(".const: bad number %q", args[1])
+			}
+			a.consts[idx] = interpreter.NumberValue(num)
+		}
+	case ".position":
+		line, err := strconv.Atoi(args[0])
+		if err != nil || line < 0 {
+			return fmt.Errorf(".position: bad line %q", args[0])
+		}
+		pos, err := strconv.Unquote(args[1])
+		if err != nil {
+			return fmt.Errorf(".position: bad string %s", args[1])
+		}
+		if c.Positions == nil {
+			c.Positions = make(map[int]string)
+		}
+		c.Positions[line] = pos
+	case ".source":
+		s, err := strconv.Unquote(args[0])
+		if err != nil {
+			return fmt.Errorf(".source: bad string %s", args[0])
+		}
+		c.Source += s
+	}
+	return nil
+}
+
+// symbol parses .export KIND NAME LABEL PARAM... and .import KIND NAME PARAM...
+func (a *assembler) symbol(name string, args []string) error {
+	n := 2
+	if name == ".export" {
+		n = 3
+	}
+	if len(args) < n {
+		return fmt.Errorf("%s takes at least %d arguments, got %d", name, n, len(args))
+	}
+	var sym Symbol
+	switch args[0] {
+	case "SUB":
+	case "FUNCTION":
+		sym.Function = true
+	default:
+		return fmt.Errorf("%s: %q is not SUB or FUNCTION", name, args[0])
+	}
+	sym.Name = args[1]
+	sym.Params = args[n:]
+	if name == ".export" {
+		for _, exp := range a.chunk.Exports {
+			if exp.Name == sym.Name {
+				return fmt.Errorf(".export: %s exported twice", sym.Name)
+			}
+		}
+		a.exports = append(a.exports, labelFixup{label: args[2], line: a.srcLine})
+		a.chunk.Exports = append(a.chunk.Exports, sym)
+		return nil
+	}
+	if _, dup := a.imports[sym.Name]; dup {
+		return fmt.Errorf(".import: %s imported twice", sym.Name)
+	}
+	a.imports[sym.Name] = len(a.chunk.Imports)
+	a.chunk.Imports = append(a.chunk.Imports, sym)
+	return nil
+}
+
+func (a *assembler) instruction(mnemonic string, args []string) error {
+	op, ok := mnemonics[strings.ToUpper(mnemonic)]
+	if !ok {
+		return fmt.Errorf("unknown instruction %s", mnemonic)
+	}
+	def := definitions[op]
+	if len(args) != len(def.OperandWidths) {
+		return fmt.Errorf("%s takes %d operands, got %d", mnemonic, len(def.OperandWidths), len(args))
+	}
+
+	code := []byte{byte(op)}
+	layout := operandLayout(op)
+	for i, width := range def.OperandWidths {
+		if idx, ok := a.imports[args[i]]; ok && op == OpGosub {
+			// Link fills in the entry point
+			calls := &a.chunk.Imports[idx].Calls
+			*calls = append(*calls, len(a.chunk.Code))
+			code = AppendOperand(code, width, 0)
+			continue
+		}
+		if layout[i] == 'j' && !isNumber(args[i]) {
+			a.fixups = append(a.fixups, labelFixup{offset: len(a.chunk.Code) + len(code), label: args[i], line: a.srcLine})
+			code = AppendOperand(code, width, 0)
+			continue
+		}
+		val, err := a.operand(layout[i], args[i])
+		if err != nil {
+			return fmt.Errorf("%s operand %d: %v", mnemonic, i+1, err)
+		}
+		if int64(val) > OperandLimit(width) {
+			return fmt.Errorf("%s operand %d: %d does not fit in %d bytes", mnemonic, i+1, val, width)
+		}
+		code = AppendOperand(code, width, val)
+	}
+	for _, b := range code {
+		a.chunk.Emit(b, a.line)
+	}
+	return nil
+}
+
+func isNumber(s string) bool {
+	_, err := parseOperand(s)
+	return err == nil
+}
+
+// operand parses one operand of the given kind
+func (a *assembler) operand(kind byte, arg string) (int, error) {
+	if m := numbered.FindStringSubmatch(arg); m != nil {
+		n, err := parseOperand(m[2])
+		if err != nil {
+			return 0, fmt.Errorf("bad operand %q", arg)
+		}
+		switch {
+		case m[1] == "k" && kind == 'k':
+			return ConstOperand(n), nil
+		case m[1] == "k" && kind == 'K',
+			(m[1] == "r" || m[1] == "g") && (kind == 'r' || kind == 'k' || kind == 'g'),
+			m[1] == "a" && kind == 'a':
+			return n, nil
+		}
+		return 0, fmt.Errorf("%q is not a %s", arg, kindNames[kind])
+	}
+
+	switch kind {
+	case 'g', 'r', 'k':
+		if idx, ok := a.globals[arg]; ok {
+			return idx, nil
+		}
+		return 0, fmt.Errorf("unknown variable %s", arg)
+	case 'a':
+		if idx, ok := a.arrays[arg]; ok {
+			return idx, nil
+		}
+		return 0, fmt.Errorf("unknown array %s", arg)
+	case 'c':
+		if op, ok := mnemonics[strings.ToUpper(arg)]; ok && isComparison(op) {
+			return int(op), nil
+		}
+	case 'f':
+		if id := GetBuiltinID(strings.ToUpper(arg)); id >= 0 {
+			return id, nil
+		}
+	}
+	n, err := parseOperand(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a %s", arg, kindNames[kind])
+	}
+	return n, nil
+}
+
+// kindNames describes operand kinds in error messages
+var kindNames = map[byte]string{
+	'g': "variable", 'r': "register", 'k': "register or constant", 'K': "constant",
+	'j': "label", 'c': "comparison", 'a': "array", 'f': "builtin", 'n': "count",
+	's': "slot",
+}
@@ -0,0 +1,164 @@
+package bytecode_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden .zasm files")
+
+// sameChunk reports how got differs from want, or ""
+func sameChunk(got, want *bytecode.Chunk) string {
+	switch {
+	case !bytes.Equal(got.Code, want.Code):
+		return fmt.Sprintf("code = % x, want % x", got.Code, want.Code)
+	case fmt.Sprint(got.Lines) != fmt.Sprint(want.Lines):
+		return fmt.Sprintf("lines = %v, want %v", got.Lines, want.Lines)
+	case fmt.Sprintf("%q", got.Constants) != fmt.Sprintf("%q", want.Constants):
+		return fmt.Sprintf("constants = %q, want %q", got.Constants, want.Constants)
+	case got.GlobalCount != want.GlobalCount || got.ArrayCount != want.ArrayCount || got.Registers != want.Registers:
+		return fmt.Sprintf("header = %d/%d/%v, want %d/%d/%v", got.GlobalCount, got.ArrayCount, got.Registers,
+			want.GlobalCount, want.ArrayCount, want.Registers)
+	case strings.Join(got.GlobalNames, ",") != strings.Join(want.GlobalNames, ","),
+		strings.Join(got.ArrayNames, ",") != strings.Join(want.ArrayNames, ","):
+		return fmt.Sprintf("names = %v %v, want %v %v", got.GlobalNames, got.ArrayNames, want.GlobalNames, want.ArrayNames)
+	case got.Source != want.Source:
+		return fmt.Sprintf("source = %q, want %q", got.Source, want.Source)
+	case fmt.Sprint(got.Positions) != fmt.Sprint(want.Positions):
+		return fmt.Sprintf("positions = %v, want %v", got.Positions, want.Positions)
+	}
+	return ""
+}
+
+// TestAssemblyEveryOpcode round-trips each opcode through Assembly and
+// Assemble with several operand values
+func TestAssemblyEveryOpcode(t *testing.T) {
+	for code := 0; code < 256; code++ {
+		op := bytecode.OpCode(code)
+		def, err := bytecode.Lookup(op)
+		if err != nil {
+			continue
+		}
+		t.Run(strings.TrimPrefix(def.Name, "Op"), func(t *testing.T) {
+			c := bytecode.NewChunk()
+			c.Registers = bytecode.IsRegisterOp(op)
+			c.Constants = []interpreter.Value{interpreter.NumberValue(-0.5), interpreter.StringValue("a \"b\"\n")}
+			c.GlobalCount, c.ArrayCount = 3, 2
+			c.GlobalNames = []string{"X", "S$"}
+			c.ArrayNames = []string{"A"}
+			for i, val := range []int{0, 1, 2, bytecode.ConstOperand(1)} {
+				code := []byte{byte(op)}
+				for _, width := range def.OperandWidths {
+					code = bytecode.AppendOperand(code, width, int(int64(uint32(val))&bytecode.OperandLimit(width)))
+				}
+				for _, b := range code {
+					c.Emit(b, 10*(i+1))
+				}
+			}
+
+			text := c.Assembly()
+			got, err := bytecode.Assemble(text)
+			if err != nil {
+				t.Fatalf("Assemble: %v\n%s", err, text)
+			}
+			if diff := sameChunk(got, c); diff != "" {
+				t.Errorf("%s\n%s", diff, text)
+			}
+		})
+	}
+}
+
+// TestAssemblyGolden compiles small programs with both backends, compares
+// the .zasm form with testdata and assembles the golden file back
+func TestAssemblyGolden(t *testing.T) {
+	const src = "10 DIM A(3)\n" +
+		"20 FOR I = 1 TO 3\n" +
+		"30 A(I) = I * I: S = S + 1\n" +
+		"40 NEXT I\n" +
+		"50 IF A(2) > S THEN PRINT \"yes\" ELSE PRINT \"no\"\n" +
+		"55 GOSUB 100\n" +
+		"60 PRINT S; SQR(A(3)), \"end\"\n" +
+		"70 END\n" +
+		"100 PRINT \"sub\": RETURN\n"
+	parsed, err := parser.Parse("golden.bas", []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		file string
+		opts []compiler.Option
+	}{
+		{"stack.zasm", []compiler.Option{compiler.WithOptimization(compiler.OptFold)}},
+		{"register.zasm", []compiler.Option{compiler.WithRegisters()}},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			chunk, err := compiler.New(tt.opts...).Compile(parsed.(*ast.Program))
+			if err != nil {
+				t.Fatalf("compile error: %v", err)
+			}
+			chunk.Source = src
+			text := chunk.Assembly()
+
+			path := filepath.Join("testdata", tt.file)
+			if *update {
+				if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if text != string(golden) {
+				t.Errorf("assembly differs from %s (run with -update):\n%s", path, text)
+			}
+
+			got, err := bytecode.Assemble(string(golden))
+			if err != nil {
+				t.Fatalf("Assemble: %v", err)
+			}
+			if diff := sameChunk(got, chunk); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"\tJump nowhere\n", "line 1: undefined label nowhere"},
+		{"\tFrobnicate\n", "line 1: unknown instruction Frobnicate"},
+		{"\tConstant\n", "Constant takes 1 operands, got 0"},
+		{".globals 1\n\tGetGlobal X\n", "line 2: GetGlobal operand 1: unknown variable X"},
+		{"\tGetGlobal k0\n", `"k0" is not a variable`},
+		{"\tCallBuiltin NOPE 1\n", `"NOPE" is not a builtin`},
+		{"\tCmpJump Add L\nL:\n", `"Add" is not a comparison`},
+		{"\tGetArray a0 300\n", "300 does not fit in 1 bytes"},
+		{".const k1 2\n", "constant k0 is not defined"},
+		{".const k0 \"open\n", "unterminated string"},
+		{".global 0 r1\n", `"r1" cannot be used as a name`},
+		{"L:\nL:\n", "label L defined twice"},
+		{".bogus\n", "unknown directive .bogus"},
+	}
+	for _, tt := range tests {
+		_, err := bytecode.Assemble(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Assemble(%q) err = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -1,10 +1,9 @@
 package bytecode
 
 import (
-	"encoding/binary"
 	"fmt"
-	"io"
 	"strings"
+
 	"zork-basic/internal/interpreter"
 )
 
@@ -12,9 +11,66 @@ import (
 type Chunk struct {
 	Code        []byte
 	Constants   []interpreter.Value
-	Lines       []int // Map bytecode offset to source line number
-	GlobalCount int   // Number of global variables used
-	ArrayCount  int   // Number of arrays used
+	Lines       []int    // Map bytecode offset to source line number
+	GlobalCount int      // Number of global variables used (registers, for register code)
+	ArrayCount  int      // Number of arrays used
+	GlobalNames []string // Variable names by global index (may be shorter than GlobalCount)
+	ArrayNames  []string // Array names by array index
+	Registers   bool     // Code targets the register machine (vm.RegisterVM)
+	Source      string   // Program source, when embedded in the .zbc file
+
+	// Positions maps the internal line numbers of a program written
+	// without line numbers to "file:line" source positions, so breaks and
+	// errors can point at the source. It is nil for numbered programs.
+	Positions map[int]string
+
+	// Object files: a MODULE compiles to a chunk with a module name and
+	// exports, and any chunk that calls a routine declared with DECLARE has
+	// imports. Link combines them into a program without either.
+	Module  string   // Module name, empty for a program
+	Exports []Symbol // Routines this module makes available to others
+	Imports []Symbol // Routines called here but defined in another module
+}
+
+// Symbol is an entry of the export or import table of an object file: a
+// SUB or FUNCTION with the parameters it takes. Parameters are passed in
+// variables named after the routine, so the linker only checks that their
+// number and types agree.
+type Symbol struct {
+	Name     string   // Routine name as the linker sees it, e.g. "HYP" or "PAD$"
+	Params   []string // Parameter names; a "$" suffix marks a string parameter
+	Function bool     // FUNCTION rather than SUB
+	Offset   int      // Export: offset of the routine's first instruction
+	Calls    []int    // Import: offsets of the OpGosub instructions to patch
+}
+
+// Signature formats the symbol as it would be declared, e.g.
+// "FUNCTION HYP(A, B)"
+func (s Symbol) Signature() string {
+	kind := "SUB"
+	if s.Function {
+		kind = "FUNCTION"
+	}
+	if len(s.Params) == 0 {
+		return kind + " " + s.Name
+	}
+	return fmt.Sprintf("%s %s(%s)", kind, s.Name, strings.Join(s.Params, ", "))
+}
+
+// Runnable reports why the chunk cannot run on its own: it is a module,
+// or it still has imports, which only Link can resolve
+func (c *Chunk) Runnable() error {
+	if c.Module != "" {
+		return fmt.Errorf("%s is a module; link it into a program with zb link", c.Module)
+	}
+	if len(c.Imports) == 0 {
+		return nil
+	}
+	names := make([]string, len(c.Imports))
+	for i, sym := range c.Imports {
+		names[i] = sym.Name
+	}
+	return fmt.Errorf("unresolved imports %s; link the program with its modules using zb link", strings.Join(names, ", "))
 }
 
 // NewChunk creates a new Chunk
@@ -28,8 +84,8 @@ func NewChunk() *Chunk {
 	}
 }
 
-// WriteByte writes a single byte to the chunk
-func (c *Chunk) WriteByte(b byte, line int) {
+// Emit appends a single byte to the chunk, recording its source line
+func (c *Chunk) Emit(b byte, line int) {
 	c.Code = append(c.Code, b)
 	c.Lines = append(c.Lines, line)
 }
@@ -40,160 +96,79 @@ func (c *Chunk) AddConstant(value interpreter.Value) int {
 	return len(c.Constants) - 1
 }
 
-// Write serializes the chunk to a writer
-func (c *Chunk) Write(w io.Writer) error {
-	// Magic header: ZBC (Zork Basic Compiled) + version 1
-	if _, err := w.Write([]byte("ZBC\x01")); err != nil {
-		return err
-	}
-
-	// Meta counts
-	if err := binary.Write(w, binary.BigEndian, uint16(c.GlobalCount)); err != nil {
-		return err
-	}
-	if err := binary.Write(w, binary.BigEndian, uint16(c.ArrayCount)); err != nil {
-		return err
+// Disassemble returns a string representation of the chunk for debugging.
+// Variables and arrays are annotated with their names, and the source line
+// is shown above its code when the chunk embeds the program source.
+func (c *Chunk) Disassemble(name string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "== %s ==\n", name)
+	if c.Module != "" {
+		fmt.Fprintf(&out, "; module %s\n", c.Module)
 	}
-
-	// Constants
-	if err := binary.Write(w, binary.BigEndian, uint16(len(c.Constants))); err != nil {
-		return err
+	for _, sym := range c.Exports {
+		fmt.Fprintf(&out, "; export %s at %04d\n", sym.Signature(), sym.Offset)
 	}
-	for _, val := range c.Constants {
-		if val.IsNumber() {
-			if _, err := w.Write([]byte{1}); err != nil {
-				return err
-			}
-			if err := binary.Write(w, binary.BigEndian, val.AsNumber()); err != nil {
-				return err
-			}
-		} else {
-			if _, err := w.Write([]byte{2}); err != nil {
-				return err
-			}
-			str := val.String()
-			if err := binary.Write(w, binary.BigEndian, uint16(len(str))); err != nil {
-				return err
-			}
-			if _, err := w.Write([]byte(str)); err != nil {
-				return err
-			}
+	for _, sym := range c.Imports {
+		calls := make([]string, len(sym.Calls))
+		for i, call := range sym.Calls {
+			calls[i] = fmt.Sprintf("%04d", call)
 		}
+		fmt.Fprintf(&out, "; import %s called at %s\n", sym.Signature(), strings.Join(calls, ", "))
 	}
 
-	// Code
-	if err := binary.Write(w, binary.BigEndian, uint32(len(c.Code))); err != nil {
-		return err
-	}
-	if _, err := w.Write(c.Code); err != nil {
-		return err
-	}
-
-	// Lines
-	if err := binary.Write(w, binary.BigEndian, uint32(len(c.Lines))); err != nil {
-		return err
-	}
-	for _, line := range c.Lines {
-		if err := binary.Write(w, binary.BigEndian, uint32(line)); err != nil {
-			return err
+	source := c.sourceLines()
+	offset := 0
+	for offset < len(c.Code) {
+		if source != nil && offset < len(c.Lines) && (offset == 0 || c.Lines[offset] != c.Lines[offset-1]) {
+			if text, ok := source[c.Lines[offset]]; ok {
+				fmt.Fprintf(&out, "          ; %s\n", text)
+			}
 		}
+		offset = c.disassembleInstruction(&out, offset)
 	}
 
-	return nil
+	return out.String()
 }
 
-// ReadChunk deserializes a chunk from a reader
-func ReadChunk(r io.Reader) (*Chunk, error) {
-	// Magic header
-	header := make([]byte, 4)
-	if _, err := io.ReadFull(r, header); err != nil {
-		return nil, err
-	}
-	if string(header[:3]) != "ZBC" {
-		return nil, fmt.Errorf("invalid bytecode header")
-	}
-
-	c := NewChunk()
-
-	// Meta counts
-	var globalCount, arrayCount uint16
-	if err := binary.Read(r, binary.BigEndian, &globalCount); err != nil {
-		return nil, err
-	}
-	c.GlobalCount = int(globalCount)
-	if err := binary.Read(r, binary.BigEndian, &arrayCount); err != nil {
-		return nil, err
-	}
-	c.ArrayCount = int(arrayCount)
-
-	// Constants
-	var constCount uint16
-	if err := binary.Read(r, binary.BigEndian, &constCount); err != nil {
-		return nil, err
-	}
-	c.Constants = make([]interpreter.Value, constCount)
-	for i := 0; i < int(constCount); i++ {
-		var typ byte
-		if err := binary.Read(r, binary.BigEndian, &typ); err != nil {
-			return nil, err
-		}
-		if typ == 1 {
-			var num float64
-			if err := binary.Read(r, binary.BigEndian, &num); err != nil {
-				return nil, err
-			}
-			c.Constants[i] = interpreter.NumberValue(num)
-		} else if typ == 2 {
-			var length uint16
-			if err := binary.Read(r, binary.BigEndian, &length); err != nil {
-				return nil, err
-			}
-			strBuf := make([]byte, length)
-			if _, err := io.ReadFull(r, strBuf); err != nil {
-				return nil, err
-			}
-			c.Constants[i] = interpreter.StringValue(string(strBuf))
-		}
-	}
+// globalOperands lists, per opcode, the operands that are global indices
+var globalOperands = map[OpCode][]int{
+	OpGetGlobal:      {0},
+	OpSetGlobal:      {0},
+	OpForInit:        {0},
+	OpNext:           {0},
+	OpForEach:        {1},
+	OpNextEach:       {0},
+	OpInput:          {0},
+	OpGetGlobal2:     {0, 1},
+	OpAddGlobalConst: {0},
+	OpIncGlobal:      {0},
+}
 
-	// Code
-	var codeLen uint32
-	if err := binary.Read(r, binary.BigEndian, &codeLen); err != nil {
-		return nil, err
-	}
-	c.Code = make([]byte, codeLen)
-	if _, err := io.ReadFull(r, c.Code); err != nil {
-		return nil, err
+// symbolName returns the name recorded for index, or ""
+// Position returns the source position of line for messages: "file:line"
+// for a program without line numbers, "line N" otherwise
+func (c *Chunk) Position(line int) string {
+	if pos, ok := c.Positions[line]; ok {
+		return pos
 	}
+	return fmt.Sprintf("line %d", line)
+}
 
-	// Lines
-	var linesLen uint32
-	if err := binary.Read(r, binary.BigEndian, &linesLen); err != nil {
-		return nil, err
+func symbolName(names []string, index int) string {
+	if index >= 0 && index < len(names) {
+		return names[index]
 	}
-	c.Lines = make([]int, linesLen)
-	for i := 0; i < int(linesLen); i++ {
-		var line uint32
-		if err := binary.Read(r, binary.BigEndian, &line); err != nil {
-			return nil, err
-		}
-		c.Lines[i] = int(line)
-	}
-
-	return c, nil
+	return ""
 }
 
-// Disassemble returns a string representation of the chunk for debugging
-func (c *Chunk) Disassemble(name string) string {
-	var out strings.Builder
-	fmt.Fprintf(&out, "== %s ==\n", name)
-
-	offset := 0
-	for offset < len(c.Code) {
-		offset = c.disassembleInstruction(&out, offset)
+// isGlobalOperand reports whether operand i of op is a global index
+func isGlobalOperand(op OpCode, i int) bool {
+	for _, idx := range globalOperands[op] {
+		if idx == i {
+			return true
+		}
 	}
-
-	return out.String()
+	return false
 }
 
 func (c *Chunk) disassembleInstruction(out *strings.Builder, offset int) int {
@@ -218,33 +193,51 @@ func (c *Chunk) disassembleInstruction(out *strings.Builder, offset int) int {
 
 	offset++
 
+	if layout, ok := registerLayouts[op]; ok {
+		offset = c.disassembleRegister(out, layout, def, offset)
+		fmt.Fprint(out, "\n")
+		return offset
+	}
+
 	for i, width := range def.OperandWidths {
-		switch width {
-		case 2:
-			val := binary.BigEndian.Uint16(c.Code[offset:])
+		val := ReadOperand(c.Code, offset, width)
+		offset += width
+
+		switch {
+		case op == OpCmpJump && i == 0:
+			if cmp, err := Lookup(OpCode(val)); err == nil {
+				fmt.Fprintf(out, "%s ", strings.TrimPrefix(cmp.Name, "Op"))
+				continue
+			}
 			fmt.Fprintf(out, "%d ", val)
 
-			// Special handling for instructions that reference pools
-			if op == OpConstant && i == 0 {
-				if int(val) < len(c.Constants) {
-					constVal := c.Constants[val]
-					if constVal.IsString() {
-						fmt.Fprintf(out, "(\"%s\") ", constVal.String())
-					} else {
-						fmt.Fprintf(out, "(%s) ", constVal.String())
-					}
-				}
-			} else if op == OpCallBuiltin && i == 0 {
-				if int(val) < len(BuiltinNames) {
-					fmt.Fprintf(out, "(%s) ", BuiltinNames[val])
+		// Special handling for instructions that reference pools
+		case (op == OpConstant && i == 0) || (op == OpAddGlobalConst && i == 1) || op == OpReadInput || op == OpLineInput || op == OpChain ||
+			(op == OpDimRecord && i == 2):
+			fmt.Fprintf(out, "%d ", val)
+			if val >= 0 && val < len(c.Constants) {
+				constVal := c.Constants[val]
+				if constVal.IsString() {
+					fmt.Fprintf(out, "(\"%s\") ", constVal.String())
+				} else {
+					fmt.Fprintf(out, "(%s) ", constVal.String())
 				}
 			}
 
-			offset += 2
-		case 1:
-			val := c.Code[offset]
+		case op == OpCallBuiltin && i == 0:
+			fmt.Fprintf(out, "%d ", val)
+			if val >= 0 && val < len(BuiltinNames) {
+				fmt.Fprintf(out, "(%s) ", BuiltinNames[val])
+			}
+
+		case isGlobalOperand(op, i) && symbolName(c.GlobalNames, val) != "":
+			fmt.Fprintf(out, "%d (%s) ", val, c.GlobalNames[val])
+
+		case i < len(stackLayouts[op]) && stackLayouts[op][i] == 'a' && symbolName(c.ArrayNames, val) != "":
+			fmt.Fprintf(out, "%d (%s) ", val, c.ArrayNames[val])
+
+		default:
 			fmt.Fprintf(out, "%d ", val)
-			offset++
 		}
 	}
 
@@ -0,0 +1,495 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zork-basic/internal/interpreter"
+)
+
+// .zbc file layout (all integers big-endian):
+//
+//	magic    "ZBC" + 1 byte format version
+//	flags    uint16 (FlagRegisters, FlagSource)
+//	count    uint16 number of sections
+//	section  4-byte id, uint32 payload length, payload   (count times)
+//	checksum uint32 CRC-32 (IEEE) of every preceding byte
+//
+// Sections:
+//
+//	CNST  uint32 count, then per constant: 1 + float64, or 2 + uint32 length + bytes
+//	CODE  the instruction bytes
+//	LINE  uint32 count, then (uint32 offset, uint32 line) where the line changes
+//	SYMS  uint32 global count, uint32 array count, then the global and the
+//	      array names, each list as uint32 count + (uint32 length + bytes)
+//	SRC   program source (only with FlagSource)
+//	POSN  uint32 count, then per line: uint32 line + "file:line" string
+//	      (programs without line numbers only)
+//	MODL  module name (object files of a MODULE)
+//	EXPT  uint32 count, then per export: name, uint8 1 for FUNCTION, the
+//	      parameter names as a list, uint32 entry offset
+//	IMPT  uint32 count, then per import: name, uint8 1 for FUNCTION, the
+//	      parameter names as a list, uint32 count + uint32 call offsets
+//
+// Strings are uint32 length + bytes. MODL, EXPT and IMPT are only written
+// for object files; a linked program has none of them.
+//
+// Readers skip sections they do not know, so new optional sections can be
+// added without a version bump.
+const FormatVersion = 3
+
+// Header flags
+const (
+	FlagRegisters = 1 << 0 // Code targets the register machine
+	FlagSource    = 1 << 1 // A SRC section holds the program source
+)
+
+// Section identifiers
+const (
+	sectionConstants = "CNST"
+	sectionCode      = "CODE"
+	sectionLines     = "LINE"
+	sectionSymbols   = "SYMS"
+	sectionSource    = "SRC "
+	sectionPositions = "POSN"
+	sectionModule    = "MODL"
+	sectionExports   = "EXPT"
+	sectionImports   = "IMPT"
+)
+
+// Constant tags in the CNST section
+const (
+	constNumber = 1
+	constString = 2
+)
+
+// Write serializes the chunk to a writer. The source section is written
+// when c.Source is set.
+func (c *Chunk) Write(w io.Writer) error {
+	var flags uint16
+	if c.Registers {
+		flags |= FlagRegisters
+	}
+
+	type section struct {
+		id      string
+		payload []byte
+	}
+	sections := []section{
+		{sectionConstants, c.encodeConstants()},
+		{sectionCode, c.Code},
+		{sectionLines, c.encodeLines()},
+		{sectionSymbols, c.encodeSymbols()},
+	}
+	if c.Source != "" {
+		flags |= FlagSource
+		sections = append(sections, section{sectionSource, []byte(c.Source)})
+	}
+	if len(c.Positions) > 0 {
+		sections = append(sections, section{sectionPositions, c.encodePositions()})
+	}
+	if c.Module != "" {
+		sections = append(sections, section{sectionModule, []byte(c.Module)})
+	}
+	if len(c.Exports) > 0 {
+		sections = append(sections, section{sectionExports, encodeSymbols(c.Exports, false)})
+	}
+	if len(c.Imports) > 0 {
+		sections = append(sections, section{sectionImports, encodeSymbols(c.Imports, true)})
+	}
+
+	buf := []byte{'Z', 'B', 'C', FormatVersion}
+	buf = binary.BigEndian.AppendUint16(buf, flags)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(sections)))
+	for _, s := range sections {
+		buf = append(buf, s.id...)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(s.payload)))
+		buf = append(buf, s.payload...)
+	}
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func (c *Chunk) encodeConstants() []byte {
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(c.Constants)))
+	for _, val := range c.Constants {
+		if val.IsString() {
+			buf = append(buf, constString)
+			buf = appendString(buf, val.String())
+		} else {
+			buf = append(buf, constNumber)
+			buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(val.AsNumber()))
+		}
+	}
+	return buf
+}
+
+// encodeLines run-length encodes Lines as (offset, line) pairs
+func (c *Chunk) encodeLines() []byte {
+	var pairs []byte
+	count := 0
+	for offset, line := range c.Lines {
+		if offset > 0 && c.Lines[offset-1] == line {
+			continue
+		}
+		pairs = binary.BigEndian.AppendUint32(pairs, uint32(offset))
+		pairs = binary.BigEndian.AppendUint32(pairs, uint32(line))
+		count++
+	}
+	return append(binary.BigEndian.AppendUint32(nil, uint32(count)), pairs...)
+}
+
+// encodePositions encodes Positions in line order
+func (c *Chunk) encodePositions() []byte {
+	lines := slices.Sorted(maps.Keys(c.Positions))
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(lines)))
+	for _, line := range lines {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(line))
+		buf = appendString(buf, c.Positions[line])
+	}
+	return buf
+}
+
+func (c *Chunk) encodeSymbols() []byte {
+	buf := binary.BigEndian.AppendUint32(nil, uint32(c.GlobalCount))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(c.ArrayCount))
+	for _, names := range [][]string{c.GlobalNames, c.ArrayNames} {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(names)))
+		for _, name := range names {
+			buf = appendString(buf, name)
+		}
+	}
+	return buf
+}
+
+// encodeSymbols encodes an export table, or an import table when calls is
+// set
+func encodeSymbols(symbols []Symbol, calls bool) []byte {
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(symbols)))
+	for _, sym := range symbols {
+		buf = appendString(buf, sym.Name)
+		var function byte
+		if sym.Function {
+			function = 1
+		}
+		buf = append(buf, function)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(sym.Params)))
+		for _, param := range sym.Params {
+			buf = appendString(buf, param)
+		}
+		if !calls {
+			buf = binary.BigEndian.AppendUint32(buf, uint32(sym.Offset))
+			continue
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(sym.Calls)))
+		for _, offset := range sym.Calls {
+			buf = binary.BigEndian.AppendUint32(buf, uint32(offset))
+		}
+	}
+	return buf
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+// ReadChunk deserializes a chunk from a reader. Files with another format
+// version, a bad checksum or malformed sections are rejected.
+func ReadChunk(r io.Reader) (*Chunk, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || string(data[:3]) != "ZBC" {
+		return nil, fmt.Errorf("invalid bytecode header")
+	}
+	if data[3] != FormatVersion {
+		return nil, fmt.Errorf("unsupported bytecode version %d (expected %d); recompile the source file", data[3], FormatVersion)
+	}
+	if len(data) < 12 {
+		return nil, fmt.Errorf("corrupt bytecode file: truncated header")
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, fmt.Errorf("corrupt bytecode file: checksum mismatch")
+	}
+
+	d := &decoder{buf: body, pos: 4, section: "header"}
+	flags := d.u16()
+	count := int(d.u16())
+	payloads := make(map[string][]byte)
+	for i := 0; i < count && d.err == nil; i++ {
+		id := string(d.bytes(4))
+		length := int(d.u32())
+		payload := d.bytes(length)
+		if d.err == nil {
+			payloads[id] = payload
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.pos != len(body) {
+		return nil, fmt.Errorf("corrupt bytecode file: %d unexpected bytes after the last section", len(body)-d.pos)
+	}
+
+	for _, id := range []string{sectionConstants, sectionCode, sectionLines, sectionSymbols} {
+		if _, ok := payloads[id]; !ok {
+			return nil, fmt.Errorf("corrupt bytecode file: missing %s section", strings.TrimSpace(id))
+		}
+	}
+
+	c := NewChunk()
+	c.Registers = flags&FlagRegisters != 0
+	c.Code = payloads[sectionCode]
+	if err := c.decodeConstants(payloads[sectionConstants]); err != nil {
+		return nil, err
+	}
+	if err := c.decodeLines(payloads[sectionLines]); err != nil {
+		return nil, err
+	}
+	if err := c.decodeSymbols(payloads[sectionSymbols]); err != nil {
+		return nil, err
+	}
+	if flags&FlagSource != 0 {
+		src, ok := payloads[sectionSource]
+		if !ok {
+			return nil, fmt.Errorf("corrupt bytecode file: missing SRC section")
+		}
+		c.Source = string(src)
+	}
+	if payload, ok := payloads[sectionPositions]; ok {
+		if err := c.decodePositions(payload); err != nil {
+			return nil, err
+		}
+	}
+	c.Module = string(payloads[sectionModule])
+	if payload, ok := payloads[sectionExports]; ok {
+		if c.Exports, err = c.decodeSymbolTable(sectionExports, payload); err != nil {
+			return nil, err
+		}
+	}
+	if payload, ok := payloads[sectionImports]; ok {
+		if c.Imports, err = c.decodeSymbolTable(sectionImports, payload); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Chunk) decodeConstants(payload []byte) error {
+	d := &decoder{buf: payload, section: sectionConstants}
+	count := d.count(5) // Smallest entry: tag + empty string
+	c.Constants = make([]interpreter.Value, 0, count)
+	for i := 0; i < count && d.err == nil; i++ {
+		switch tag := d.u8(); tag {
+		case constNumber:
+			c.Constants = append(c.Constants, interpreter.NumberValue(math.Float64frombits(d.u64())))
+		case constString:
+			c.Constants = append(c.Constants, interpreter.StringValue(d.str()))
+		default:
+			if d.err == nil {
+				d.fail(fmt.Sprintf("constant %d has unknown type %d", i, tag))
+			}
+		}
+	}
+	return d.finish()
+}
+
+func (c *Chunk) decodeLines(payload []byte) error {
+	d := &decoder{buf: payload, section: sectionLines}
+	count := d.count(8)
+	c.Lines = make([]int, len(c.Code))
+	next := 0
+	line := 0
+	for i := 0; i < count && d.err == nil; i++ {
+		offset, l := int(d.u32()), int(d.u32())
+		if offset < next || offset > len(c.Code) || (i == 0 && offset != 0) {
+			d.fail(fmt.Sprintf("entry %d has out-of-order offset %d", i, offset))
+			break
+		}
+		for ; next < offset; next++ {
+			c.Lines[next] = line
+		}
+		line = l
+	}
+	if count == 0 && len(c.Code) > 0 {
+		d.fail("no entries for non-empty code")
+	}
+	for ; next < len(c.Code); next++ {
+		c.Lines[next] = line
+	}
+	return d.finish()
+}
+
+func (c *Chunk) decodePositions(payload []byte) error {
+	d := &decoder{buf: payload, section: sectionPositions}
+	count := d.count(8) // Smallest entry: line + empty string
+	c.Positions = make(map[int]string, count)
+	for i := 0; i < count && d.err == nil; i++ {
+		line := int(d.u32())
+		c.Positions[line] = d.str()
+	}
+	return d.finish()
+}
+
+func (c *Chunk) decodeSymbols(payload []byte) error {
+	d := &decoder{buf: payload, section: sectionSymbols}
+	c.GlobalCount = int(d.u32())
+	c.ArrayCount = int(d.u32())
+	c.GlobalNames = d.names()
+	c.ArrayNames = d.names()
+	if d.err == nil && (len(c.GlobalNames) > c.GlobalCount || len(c.ArrayNames) > c.ArrayCount) {
+		d.fail("more names than symbols")
+	}
+	return d.finish()
+}
+
+// decodeSymbolTable decodes an EXPT or IMPT section. Offsets must point
+// into the code; Link checks that they are instruction boundaries.
+func (c *Chunk) decodeSymbolTable(section string, payload []byte) ([]Symbol, error) {
+	d := &decoder{buf: payload, section: section}
+	count := d.count(13) // Smallest entry: empty name, flag, no parameters, offset
+	symbols := make([]Symbol, 0, count)
+	for i := 0; i < count && d.err == nil; i++ {
+		sym := Symbol{Name: d.str(), Function: d.u8() == 1, Params: d.names()}
+		offsets := []int{}
+		if section == sectionExports {
+			sym.Offset = int(d.u32())
+			offsets = append(offsets, sym.Offset)
+		} else {
+			n := d.count(4)
+			for j := 0; j < n && d.err == nil; j++ {
+				sym.Calls = append(sym.Calls, int(d.u32()))
+			}
+			offsets = sym.Calls
+		}
+		for _, offset := range offsets {
+			if d.err == nil && offset >= len(c.Code) {
+				d.fail(fmt.Sprintf("%s has offset %d outside the code", sym.Name, offset))
+			}
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols, d.finish()
+}
+
+// decoder reads big-endian values from a section payload. The first error
+// sticks; later reads return zero values.
+type decoder struct {
+	buf     []byte
+	pos     int
+	section string
+	err     error
+}
+
+func (d *decoder) fail(msg string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("corrupt bytecode file: %s section: %s", strings.TrimSpace(d.section), msg)
+	}
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf)-d.pos {
+		d.fail("truncated at byte " + strconv.Itoa(d.pos))
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) u8() byte {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) u16() uint16 {
+	if b := d.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) u32() uint32 {
+	if b := d.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) u64() uint64 {
+	if b := d.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) str() string {
+	return string(d.bytes(int(d.u32())))
+}
+
+// count reads an element count, rejecting counts that cannot fit in the
+// rest of the payload when each element takes at least minSize bytes
+func (d *decoder) count(minSize int) int {
+	n := int(d.u32())
+	if n > (len(d.buf)-d.pos)/minSize {
+		d.fail(fmt.Sprintf("count %d exceeds section size", n))
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) names() []string {
+	n := d.count(4)
+	if n == 0 {
+		return nil
+	}
+	names := make([]string, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		names = append(names, d.str())
+	}
+	return names
+}
+
+// finish reports the first error, or trailing bytes left in the payload
+func (d *decoder) finish() error {
+	if d.err == nil && d.pos != len(d.buf) {
+		d.fail(fmt.Sprintf("%d unexpected trailing bytes", len(d.buf)-d.pos))
+	}
+	return d.err
+}
+
+// sourceLines indexes embedded source text by BASIC line number
+func (c *Chunk) sourceLines() map[int]string {
+	if c.Source == "" {
+		return nil
+	}
+	lines := make(map[int]string)
+	for _, text := range strings.Split(c.Source, "\n") {
+		text = strings.TrimRight(text, "\r")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if num, err := strconv.Atoi(fields[0]); err == nil {
+			lines[num] = strings.TrimSpace(text)
+		}
+	}
+	return lines
+}
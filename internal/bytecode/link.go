@@ -0,0 +1,313 @@
+package bytecode
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"zork-basic/internal/interpreter"
+)
+
+// Link combines a program and the modules it uses into one program chunk.
+//
+// Exactly one object must be a program (no module name). Its code comes
+// first, so execution starts there and ends at its OpEnd before reaching
+// the modules, which follow in the order given. Link relocates jump
+// targets by the offset each object's code lands at, merges the constant
+// pools, gives variables and arrays with the same name the same slot (so
+// SHARED variables and routine parameters meet across objects), and points
+// every imported call at the matching export. Register temporaries are
+// only live within a statement, so all objects share one block of them
+// after the named registers. Objects compiled from source without line
+// numbers get their internal line numbers moved past every other object's,
+// so each keeps pointing at its own source positions.
+func Link(objects ...*Chunk) (*Chunk, error) {
+	program, err := linkOrder(objects)
+	if err != nil {
+		return nil, err
+	}
+	l := &linker{
+		out:       NewChunk(),
+		constants: make(map[constKey]int),
+		globals:   make(map[string]int),
+		arrays:    make(map[string]int),
+		exports:   make(map[string]export),
+	}
+	l.out.Registers = program[0].Registers
+
+	// Named variables first, so register temporaries can follow them
+	for _, obj := range program {
+		for _, name := range obj.GlobalNames {
+			l.global(name)
+		}
+	}
+	temps := 0
+	for _, obj := range program {
+		temps = max(temps, obj.GlobalCount-len(obj.GlobalNames))
+	}
+	named := len(l.out.GlobalNames)
+	l.out.GlobalCount = named + temps
+
+	bases := make([]int, len(program))
+	lineBases := lineBases(program)
+	for i, obj := range program {
+		bases[i] = len(l.out.Code)
+		if err := l.relocate(obj, named, lineBases[i]); err != nil {
+			return nil, fmt.Errorf("%s: %v", objectName(obj), err)
+		}
+		for _, sym := range obj.Exports {
+			if prev, ok := l.exports[sym.Name]; ok {
+				return nil, fmt.Errorf("%s is exported by both %s and %s", sym.Name, objectName(prev.from), objectName(obj))
+			}
+			l.exports[sym.Name] = export{Symbol: sym, from: obj, offset: bases[i] + sym.Offset}
+		}
+	}
+
+	for i, obj := range program {
+		for _, sym := range obj.Imports {
+			exp, ok := l.exports[sym.Name]
+			if !ok {
+				return nil, fmt.Errorf("%s: undefined %s", objectName(obj), sym.Signature())
+			}
+			if !sameParams(sym, exp.Symbol) {
+				return nil, fmt.Errorf("%s: %s does not match %s exported by %s", objectName(obj), sym.Signature(), exp.Signature(), objectName(exp.from))
+			}
+			for _, call := range sym.Calls {
+				at := bases[i] + call
+				if at >= len(l.out.Code) || OpCode(l.out.Code[at]) != OpGosub {
+					return nil, fmt.Errorf("%s: call to %s at offset %d is not a GOSUB", objectName(obj), sym.Name, call)
+				}
+				copy(l.out.Code[at+1:], AppendOperand(nil, 4, exp.offset))
+			}
+		}
+	}
+
+	if _, err := Verify(l.out); err != nil {
+		return nil, fmt.Errorf("linked program: %v", err)
+	}
+	return l.out, nil
+}
+
+// export is an entry of the combined export table
+type export struct {
+	Symbol
+	from   *Chunk // Module that exports the routine
+	offset int    // Entry point in the linked code
+}
+
+// constKey identifies a constant for deduplication across pools
+type constKey struct {
+	str bool
+	s   string
+	n   float64
+}
+
+// linker holds the state of a Link call
+type linker struct {
+	out       *Chunk
+	constants map[constKey]int
+	globals   map[string]int
+	arrays    map[string]int
+	exports   map[string]export
+}
+
+// linkOrder checks the objects can be linked together and returns them
+// with the program first
+func linkOrder(objects []*Chunk) ([]*Chunk, error) {
+	var program *Chunk
+	var modules []*Chunk
+	seen := make(map[string]bool)
+	for _, obj := range objects {
+		if obj.Module == "" {
+			if program != nil {
+				return nil, fmt.Errorf("more than one program: only modules can be linked into a program")
+			}
+			program = obj
+			continue
+		}
+		key := strings.ToUpper(obj.Module)
+		if seen[key] {
+			return nil, fmt.Errorf("module %s is linked twice", obj.Module)
+		}
+		seen[key] = true
+		modules = append(modules, obj)
+	}
+	if program == nil {
+		return nil, fmt.Errorf("no program to link: every object is a module")
+	}
+	ordered := append([]*Chunk{program}, modules...)
+	for _, obj := range ordered {
+		if obj.Registers != program.Registers {
+			return nil, fmt.Errorf("%s is compiled for the other virtual machine", objectName(obj))
+		}
+	}
+	return ordered, nil
+}
+
+// lineBases returns how far to move each object's line numbers: objects
+// with source positions (written without line numbers, so every one starts
+// at line 1) are stacked after the highest line of the numbered objects
+func lineBases(objects []*Chunk) []int {
+	top := 0
+	for _, obj := range objects {
+		if len(obj.Positions) == 0 {
+			top = max(top, slices.Max(append([]int{0}, obj.Lines...)))
+		}
+	}
+	bases := make([]int, len(objects))
+	for i, obj := range objects {
+		if len(obj.Positions) == 0 {
+			continue
+		}
+		bases[i] = top
+		top += slices.Max(append([]int{0}, obj.Lines...))
+	}
+	return bases
+}
+
+// objectName names an object in error messages
+func objectName(obj *Chunk) string {
+	if obj.Module == "" {
+		return "program"
+	}
+	return "module " + obj.Module
+}
+
+// sameParams reports whether an import agrees with the export it resolves
+// to: both SUB or both FUNCTION, with parameters of the same types
+func sameParams(imp, exp Symbol) bool {
+	if imp.Function != exp.Function || len(imp.Params) != len(exp.Params) {
+		return false
+	}
+	for i, param := range imp.Params {
+		if strings.HasSuffix(param, "$") != strings.HasSuffix(exp.Params[i], "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// global returns the linked slot of a named variable. Unnamed variables
+// (from hand-written assembly) always get a slot of their own.
+func (l *linker) global(name string) int {
+	if idx, ok := l.globals[name]; ok && name != "" {
+		return idx
+	}
+	idx := len(l.out.GlobalNames)
+	l.out.GlobalNames = append(l.out.GlobalNames, name)
+	l.globals[name] = idx
+	return idx
+}
+
+// array returns the linked slot of a named array
+func (l *linker) array(name string) int {
+	if idx, ok := l.arrays[name]; ok && name != "" {
+		return idx
+	}
+	idx := len(l.out.ArrayNames)
+	l.out.ArrayNames = append(l.out.ArrayNames, name)
+	l.out.ArrayCount++
+	l.arrays[name] = idx
+	return idx
+}
+
+// constant returns the linked index of a constant
+func (l *linker) constant(val interpreter.Value) int {
+	key := constKey{str: val.IsString()}
+	if key.str {
+		key.s = val.String()
+	} else {
+		key.n = val.AsNumber()
+	}
+	if idx, ok := l.constants[key]; ok {
+		return idx
+	}
+	idx := l.out.AddConstant(val)
+	l.constants[key] = idx
+	return idx
+}
+
+// relocate appends the code of obj, rewriting every operand that refers to
+// a code offset, a constant, a variable or an array. named is the number
+// of named variables in the linked program; obj's register temporaries
+// move to just after them, and its line numbers move up by lineBase.
+func (l *linker) relocate(obj *Chunk, named, lineBase int) error {
+	insts, err := obj.decode()
+	if err != nil {
+		return err
+	}
+	base := len(l.out.Code)
+	globals := make([]int, obj.GlobalCount)
+	for i := range globals {
+		if i < len(obj.GlobalNames) {
+			globals[i] = l.global(obj.GlobalNames[i])
+		} else {
+			globals[i] = named + i - len(obj.GlobalNames)
+		}
+	}
+	arrays := make([]int, obj.ArrayCount)
+	for i := range arrays {
+		arrays[i] = l.array(symbolName(obj.ArrayNames, i))
+	}
+	constants := make([]int, len(obj.Constants))
+	for i, val := range obj.Constants {
+		constants[i] = l.constant(val)
+	}
+	for line, pos := range obj.Positions {
+		if l.out.Positions == nil {
+			l.out.Positions = make(map[int]string)
+		}
+		l.out.Positions[line+lineBase] = pos
+	}
+
+	// lookup maps an operand through one of the tables above
+	lookup := func(table []int, val int, what string) (int, error) {
+		if val < 0 || val >= len(table) {
+			return 0, fmt.Errorf("%s index %d out of range", what, val)
+		}
+		return table[val], nil
+	}
+	for _, inst := range insts {
+		if inst.op == OpCover {
+			return fmt.Errorf("code compiled with coverage cannot be linked")
+		}
+		def, _ := Lookup(inst.op)
+		layout := operandLayout(inst.op)
+		for i, val := range inst.operands {
+			kind := byte(0)
+			if i < len(layout) {
+				kind = layout[i]
+			}
+			var err error
+			k, isConst := RegOperand(val)
+			switch {
+			case kind == 'j':
+				val += base
+			case kind == 'K':
+				val, err = lookup(constants, val, "constant")
+			case kind == 'k' && isConst:
+				val, err = lookup(constants, k, "constant")
+				val = ConstOperand(val)
+			case kind == 'g' || kind == 'r' || kind == 'k':
+				val, err = lookup(globals, val, "variable")
+			case kind == 'a':
+				val, err = lookup(arrays, val, "array")
+			}
+			if err != nil {
+				return fmt.Errorf("offset %d: %v", inst.offset, err)
+			}
+			if int64(val) > OperandLimit(def.OperandWidths[i]) {
+				return fmt.Errorf("offset %d: operand %d does not fit in %d bytes after linking", inst.offset, val, def.OperandWidths[i])
+			}
+			inst.operands[i] = val
+		}
+		l.out.Code = append(l.out.Code, byte(inst.op))
+		for i, width := range def.OperandWidths {
+			l.out.Code = AppendOperand(l.out.Code, width, inst.operands[i])
+		}
+		for len(l.out.Lines) < len(l.out.Code) {
+			l.out.Lines = append(l.out.Lines, inst.line+lineBase)
+		}
+	}
+	return nil
+}
@@ -1,13 +1,16 @@
 package bytecode
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+)
 
 // OpCode represents a bytecode instruction
 type OpCode byte
 
 const (
 	// OpConstant pushes a constant from the constant pool onto the stack
-	// Operand: 2 bytes (index in constant pool)
+	// Operand: 4 bytes (index in constant pool)
 	OpConstant OpCode = iota
 
 	// OpPop pops the top element from the stack
@@ -38,32 +41,129 @@ const (
 	OpLte // <=
 
 	// Control flow
-	OpJump        // Unconditional jump. Operand: 2 bytes (offset)
-	OpJumpIfFalse // Jump if stack top is false. Operand: 2 bytes (offset)
-	OpGosub       // Call subroutine. Operand: 2 bytes (line index)
+	OpJump        // Unconditional jump. Operand: 4 bytes (offset)
+	OpJumpIfFalse // Jump if stack top is false. Operand: 4 bytes (offset)
+	OpGosub       // Call subroutine. Operand: 4 bytes (offset)
 	OpReturn      // Return from subroutine
 	OpEnd         // Terminate program
-	OpForInit     // Initialize FOR loop. Operand: 2 bytes (loop variable index in globals). Pops step, end from stack.
-	OpNext        // FOR loop next iteration. Operands: 2 bytes (variable index), 2 bytes (loop top offset)
+	OpForInit     // Initialize FOR loop. Operand: 4 bytes (loop variable index in globals). Pops step, end from stack.
+	OpNext        // FOR loop next iteration. Operands: 4 bytes (variable index), 4 bytes (loop top offset)
 
 	// Variable access
-	OpGetGlobal // Get global variable. Operand: 2 bytes (index in global names)
-	OpSetGlobal // Set global variable. Operand: 2 bytes (index in global names)
+	OpGetGlobal // Get global variable. Operand: 4 bytes (index in global names)
+	OpSetGlobal // Set global variable. Operand: 4 bytes (index in global names)
 
 	// Array access
-	OpGetArray // Get array element. Operands: 2 bytes (array name index), 1 byte (dimensions count)
-	OpSetArray // Set array element. Operands: 2 bytes (array name index), 1 byte (dimensions count)
+	OpGetArray // Get array element. Operands: 4 bytes (array name index), 1 byte (dimensions count)
+	OpSetArray // Set array element. Operands: 4 bytes (array name index), 1 byte (dimensions count)
 
 	// I/O
 	OpPrint   // Print stack top (no newline)
 	OpPrintNl // Print newline
-	OpInput   // Read input. Operand: 2 bytes (variable name index)
+	OpInput   // Read a line into a variable (legacy: compilers now emit OpReadInput). Operand: 4 bytes (variable name index)
 
 	// Builtin calls
 	OpCallBuiltin // Call builtin function. Operands: 2 bytes (name index), 1 byte (arg count)
 
 	// Array declaration
-	OpDim // Declare array. Operands: 2 bytes (array name index), 1 byte (dimensions count)
+	OpDim // Declare array. Operands: 4 bytes (array name index), 1 byte (dimensions count)
+
+	// Instrumentation
+	OpCover // Increment a coverage counter. Operand: 4 bytes (counter index)
+
+	// Superinstructions (produced by the peephole optimizer, see Peephole)
+	OpGetGlobal2     // Push two globals. Operands: 4 bytes (first index), 4 bytes (second index)
+	OpAddGlobalConst // globals[a] = globals[a] + constant. Operands: 4 bytes (global index), 4 bytes (constant index)
+	OpIncGlobal      // globals[a] = globals[a] + 1. Operand: 4 bytes (global index)
+	OpCmpJump        // Compare the top two values, jump if false. Operands: 1 byte (comparison opcode), 4 bytes (offset)
+
+	// Register machine instructions (compiled WithRegisters, run by vm.RegisterVM).
+	// d is a register; a and b are register-or-constant operands (see
+	// RegConstant). Each is 4 bytes. OpJump, OpGosub, OpReturn, OpEnd,
+	// OpNext, OpInput, OpPrintNl and OpCover are shared with the stack machine.
+	OpRMove        // d = a
+	OpRAdd         // d = a + b
+	OpRSub         // d = a - b
+	OpRMul         // d = a * b
+	OpRDiv         // d = a / b
+	OpRPow         // d = a ^ b
+	OpRMod         // d = a MOD b
+	OpRNeg         // d = -a
+	OpRNot         // d = NOT a
+	OpRAnd         // d = a AND b
+	OpROr          // d = a OR b
+	OpREq          // d = a = b
+	OpRNeq         // d = a <> b
+	OpRGt          // d = a > b
+	OpRGte         // d = a >= b
+	OpRLt          // d = a < b
+	OpRLte         // d = a <= b
+	OpRJumpIfFalse // Jump if a is false. Operands: a, 4 bytes (offset)
+	OpRCmpJump     // Compare a and b, jump if false. Operands: 1 byte (comparison opcode), a, b, 4 bytes (offset)
+	OpRForInit     // Initialize FOR loop. Operands: d (loop variable), a (end), b (step)
+	OpRGetArray    // d = array element. Operands: d, 4 bytes (array index), first index register, 1 byte (dimensions count)
+	OpRSetArray    // Set array element to a. Operands: 4 bytes (array index), first index register, 1 byte (dimensions count), a
+	OpRDim         // Declare array. Operands: 4 bytes (array index), first size register, 1 byte (dimensions count)
+	OpRPrint       // Print a (no newline)
+	OpRCall        // d = builtin(args). Operands: d, 2 bytes (builtin index), first argument register, 1 byte (arg count)
+
+	// Later additions go at the end so existing .zbc files keep their opcodes
+	OpStop // Pause the program (STOP): Run returns a break and continues after it. Shared by both machines.
+
+	// INPUT reads a line into the machine's field buffer, re-prompting until
+	// it matches the targets; each target then takes the next field, so array
+	// indices are evaluated after the line is read
+	OpReadInput   // Prompt and read comma-separated fields. Operands: 4 bytes (prompt constant), 4 bytes (type constant: '#' or '$' per field). Shared.
+	OpLineInput   // Prompt and read a whole line as one string field (LINE INPUT). Operand: 4 bytes (prompt constant). Shared.
+	OpInputField  // Push the next input field
+	OpRInputField // d = next input field. Operand: d
+
+	// CHAIN replaces the running program with another one loaded by the
+	// VM's chain loader. The constant names the variables that survive,
+	// separated by spaces, with "()" after array names, or is "*" when all
+	// of them do (CHAIN ... ALL).
+	OpChain  // Pop the start line (-1 for the first line) and the file name. Operand: 4 bytes (names constant)
+	OpRChain // Operands: a (file name), b (start line), 4 bytes (names constant)
+
+	// Maps (DIM ... AS MAP) live in the array table; keys are strings and
+	// are visited in sorted order. Each operand naming a map is 4 bytes.
+	OpDimMap     // Create an empty map. Operand: map. Shared.
+	OpForEach    // Start FOR EACH over a snapshot of the keys: jump to the offset when there are none, else set the variable to the first. Operands: map, 4 bytes (variable), 4 bytes (offset). Shared.
+	OpNextEach   // FOR EACH next iteration: set the variable to the next key and jump back, or end the loop. Operands: 4 bytes (variable), 4 bytes (loop top offset). Shared.
+	OpMapGet     // Pop a key, push its value (0 or "" when missing). Operand: map
+	OpMapSet     // Pop a value and a key, store the value. Operand: map
+	OpMapHas     // Pop a key, push 1 if the map has it, else 0. Operand: map
+	OpMapDelete  // Pop a key and remove it. Operand: map
+	OpMapKey     // Pop an index, push the key at that position. Operand: map
+	OpMapCount   // Push the number of keys. Operand: map
+	OpRMapGet    // d = map(a). Operands: d, map, a
+	OpRMapSet    // map(a) = b. Operands: map, a, b
+	OpRMapHas    // d = HASKEY(map, a). Operands: d, map, a
+	OpRMapDelete // Remove key a. Operands: map, a
+	OpRMapKey    // d = KEYS$(map, a). Operands: d, map, a
+	OpRMapCount  // d = COUNT(map). Operands: d, map
+
+	// Recursive SUB and FUNCTION calls push the caller's variables and
+	// arrays onto the machine's save stack and pop them back afterwards
+	// (ast.SaveLocalsStmt). Shared.
+	OpSaveGlobal    // Push a variable. Operand: 4 bytes (variable)
+	OpRestoreGlobal // Pop into a variable. Operand: 4 bytes (variable)
+	OpSaveArray     // Push an array or map. Operand: 4 bytes (array)
+	OpRestoreArray  // Pop into an array or map. Operand: 4 bytes (array)
+
+	// TYPE records live in the array table too: a record variable or record
+	// array has the declared sizes plus a last dimension of one slot per
+	// field, and the compiler resolves field names to slot offsets. Each
+	// record operand is the array (4 bytes), the count of index values
+	// (1 byte) and a slot (2 bytes).
+	OpDimRecord   // Pop the sizes and create the record. Operands: array, 1 byte (sizes count), 4 bytes (shape constant: '#' or '$' per slot)
+	OpGetField    // Pop the indices, push the field. Operands: record
+	OpSetField    // Pop a value, then the indices, and store the field. Operands: record
+	OpCopyRecord  // Pop the source indices, then the target indices, and copy the slots. Operands: target record, source record, 2 bytes (slot count)
+	OpRDimRecord  // Operands: array, first size register, 1 byte (sizes count), 4 bytes (shape constant)
+	OpRGetField   // d = field. Operands: d, array, first index register, 1 byte (indices count), slot
+	OpRSetField   // Set the field to a. Operands: array, first index register, 1 byte (indices count), slot, a
+	OpRCopyRecord // Operands: the target and source records as array, first index register, count, slot; 2 bytes (slot count)
 )
 
 // OpDefinition defines the properties of an opcode
@@ -73,7 +173,7 @@ type OpDefinition struct {
 }
 
 var definitions = map[OpCode]*OpDefinition{
-	OpConstant:    {"OpConstant", []int{2}},
+	OpConstant:    {"OpConstant", []int{4}},
 	OpPop:         {"OpPop", []int{}},
 	OpAdd:         {"OpAdd", []int{}},
 	OpSub:         {"OpSub", []int{}},
@@ -91,22 +191,125 @@ var definitions = map[OpCode]*OpDefinition{
 	OpGte:         {"OpGte", []int{}},
 	OpLt:          {"OpLt", []int{}},
 	OpLte:         {"OpLte", []int{}},
-	OpJump:        {"OpJump", []int{2}},
-	OpJumpIfFalse: {"OpJumpIfFalse", []int{2}},
-	OpGosub:       {"OpGosub", []int{2}}, // Index in line map (not byte offset)
+	OpJump:        {"OpJump", []int{4}},
+	OpJumpIfFalse: {"OpJumpIfFalse", []int{4}},
+	OpGosub:       {"OpGosub", []int{4}}, // Index in line map (not byte offset)
 	OpReturn:      {"OpReturn", []int{}},
 	OpEnd:         {"OpEnd", []int{}},
-	OpForInit:     {"OpForInit", []int{2}},
-	OpNext:        {"OpNext", []int{2, 2}},
-	OpGetGlobal:   {"OpGetGlobal", []int{2}},
-	OpSetGlobal:   {"OpSetGlobal", []int{2}},
-	OpGetArray:    {"OpGetArray", []int{2, 1}},
-	OpSetArray:    {"OpSetArray", []int{2, 1}},
+	OpForInit:     {"OpForInit", []int{4}},
+	OpNext:        {"OpNext", []int{4, 4}},
+	OpGetGlobal:   {"OpGetGlobal", []int{4}},
+	OpSetGlobal:   {"OpSetGlobal", []int{4}},
+	OpGetArray:    {"OpGetArray", []int{4, 1}},
+	OpSetArray:    {"OpSetArray", []int{4, 1}},
 	OpPrint:       {"OpPrint", []int{}},
 	OpPrintNl:     {"OpPrintNl", []int{}},
-	OpInput:       {"OpInput", []int{2}},
+	OpInput:       {"OpInput", []int{4}},
 	OpCallBuiltin: {"OpCallBuiltin", []int{2, 1}},
-	OpDim:         {"OpDim", []int{2, 1}},
+	OpDim:         {"OpDim", []int{4, 1}},
+	OpCover:       {"OpCover", []int{4}},
+
+	OpGetGlobal2:     {"OpGetGlobal2", []int{4, 4}},
+	OpAddGlobalConst: {"OpAddGlobalConst", []int{4, 4}},
+	OpIncGlobal:      {"OpIncGlobal", []int{4}},
+	OpCmpJump:        {"OpCmpJump", []int{1, 4}},
+
+	OpRMove:        {"OpRMove", []int{4, 4}},
+	OpRAdd:         {"OpRAdd", []int{4, 4, 4}},
+	OpRSub:         {"OpRSub", []int{4, 4, 4}},
+	OpRMul:         {"OpRMul", []int{4, 4, 4}},
+	OpRDiv:         {"OpRDiv", []int{4, 4, 4}},
+	OpRPow:         {"OpRPow", []int{4, 4, 4}},
+	OpRMod:         {"OpRMod", []int{4, 4, 4}},
+	OpRNeg:         {"OpRNeg", []int{4, 4}},
+	OpRNot:         {"OpRNot", []int{4, 4}},
+	OpRAnd:         {"OpRAnd", []int{4, 4, 4}},
+	OpROr:          {"OpROr", []int{4, 4, 4}},
+	OpREq:          {"OpREq", []int{4, 4, 4}},
+	OpRNeq:         {"OpRNeq", []int{4, 4, 4}},
+	OpRGt:          {"OpRGt", []int{4, 4, 4}},
+	OpRGte:         {"OpRGte", []int{4, 4, 4}},
+	OpRLt:          {"OpRLt", []int{4, 4, 4}},
+	OpRLte:         {"OpRLte", []int{4, 4, 4}},
+	OpRJumpIfFalse: {"OpRJumpIfFalse", []int{4, 4}},
+	OpRCmpJump:     {"OpRCmpJump", []int{1, 4, 4, 4}},
+	OpRForInit:     {"OpRForInit", []int{4, 4, 4}},
+	OpRGetArray:    {"OpRGetArray", []int{4, 4, 4, 1}},
+	OpRSetArray:    {"OpRSetArray", []int{4, 4, 1, 4}},
+	OpRDim:         {"OpRDim", []int{4, 4, 1}},
+	OpRPrint:       {"OpRPrint", []int{4}},
+	OpRCall:        {"OpRCall", []int{4, 2, 4, 1}},
+
+	OpStop: {"OpStop", []int{}},
+
+	OpReadInput:   {"OpReadInput", []int{4, 4}},
+	OpLineInput:   {"OpLineInput", []int{4}},
+	OpInputField:  {"OpInputField", []int{}},
+	OpRInputField: {"OpRInputField", []int{4}},
+
+	OpChain:  {"OpChain", []int{4}},
+	OpRChain: {"OpRChain", []int{4, 4, 4}},
+
+	OpDimMap:     {"OpDimMap", []int{4}},
+	OpForEach:    {"OpForEach", []int{4, 4, 4}},
+	OpNextEach:   {"OpNextEach", []int{4, 4}},
+	OpMapGet:     {"OpMapGet", []int{4}},
+	OpMapSet:     {"OpMapSet", []int{4}},
+	OpMapHas:     {"OpMapHas", []int{4}},
+	OpMapDelete:  {"OpMapDelete", []int{4}},
+	OpMapKey:     {"OpMapKey", []int{4}},
+	OpMapCount:   {"OpMapCount", []int{4}},
+	OpRMapGet:    {"OpRMapGet", []int{4, 4, 4}},
+	OpRMapSet:    {"OpRMapSet", []int{4, 4, 4}},
+	OpRMapHas:    {"OpRMapHas", []int{4, 4, 4}},
+	OpRMapDelete: {"OpRMapDelete", []int{4, 4}},
+	OpRMapKey:    {"OpRMapKey", []int{4, 4, 4}},
+	OpRMapCount:  {"OpRMapCount", []int{4, 4}},
+
+	OpSaveGlobal:    {"OpSaveGlobal", []int{4}},
+	OpRestoreGlobal: {"OpRestoreGlobal", []int{4}},
+	OpSaveArray:     {"OpSaveArray", []int{4}},
+	OpRestoreArray:  {"OpRestoreArray", []int{4}},
+
+	OpDimRecord:   {"OpDimRecord", []int{4, 1, 4}},
+	OpGetField:    {"OpGetField", []int{4, 1, 2}},
+	OpSetField:    {"OpSetField", []int{4, 1, 2}},
+	OpCopyRecord:  {"OpCopyRecord", []int{4, 1, 2, 4, 1, 2, 2}},
+	OpRDimRecord:  {"OpRDimRecord", []int{4, 4, 1, 4}},
+	OpRGetField:   {"OpRGetField", []int{4, 4, 4, 1, 2}},
+	OpRSetField:   {"OpRSetField", []int{4, 4, 1, 2, 4}},
+	OpRCopyRecord: {"OpRCopyRecord", []int{4, 4, 1, 2, 4, 4, 1, 2, 2}},
+}
+
+// ReadOperand decodes a big-endian operand of the given width (1, 2 or 4
+// bytes) at code[offset:]. A 4-byte operand with the top bit set is
+// negative on 32-bit targets; register-or-constant operands are decoded
+// with RegOperand, which masks them as a uint32.
+func ReadOperand(code []byte, offset, width int) int {
+	switch width {
+	case 4:
+		return int(binary.BigEndian.Uint32(code[offset:]))
+	case 2:
+		return int(binary.BigEndian.Uint16(code[offset:]))
+	}
+	return int(code[offset])
+}
+
+// AppendOperand appends a big-endian operand of the given width
+func AppendOperand(code []byte, width, val int) []byte {
+	switch width {
+	case 4:
+		return binary.BigEndian.AppendUint32(code, uint32(val))
+	case 2:
+		return binary.BigEndian.AppendUint16(code, uint16(val))
+	}
+	return append(code, byte(val))
+}
+
+// OperandLimit is the largest value an operand of the given width can hold.
+// It is an int64 because a 4-byte limit does not fit in a 32-bit int.
+func OperandLimit(width int) int64 {
+	return 1<<(8*width) - 1
 }
 
 // Lookup returns the definition for an opcode
@@ -0,0 +1,180 @@
+package bytecode
+
+import "fmt"
+
+// instruction is a decoded instruction used by the peephole optimizer
+type instruction struct {
+	op       OpCode
+	operands []int
+	line     int
+	offset   int // Offset in the original code
+}
+
+// jumpOperand returns the index of the operand holding a code offset, or -1
+func jumpOperand(op OpCode) int {
+	switch op {
+	case OpJump, OpJumpIfFalse, OpGosub:
+		return 0
+	case OpNext, OpNextEach:
+		return 1
+	case OpForEach:
+		return 2
+	case OpCmpJump, OpRJumpIfFalse:
+		return 1
+	case OpRCmpJump:
+		return 3
+	}
+	return -1
+}
+
+// isComparison reports whether op is one of the comparison opcodes
+func isComparison(op OpCode) bool {
+	switch op {
+	case OpEq, OpNeq, OpGt, OpGte, OpLt, OpLte:
+		return true
+	}
+	return false
+}
+
+// decode splits the chunk's code into instructions
+func (c *Chunk) decode() ([]instruction, error) {
+	var insts []instruction
+	for offset := 0; offset < len(c.Code); {
+		op := OpCode(c.Code[offset])
+		def, err := Lookup(op)
+		if err != nil {
+			return nil, fmt.Errorf("offset %d: %v", offset, err)
+		}
+		inst := instruction{op: op, line: c.Lines[offset], offset: offset}
+		pos := offset + 1
+		for _, width := range def.OperandWidths {
+			if pos+width > len(c.Code) {
+				return nil, fmt.Errorf("offset %d: truncated %s", offset, def.Name)
+			}
+			inst.operands = append(inst.operands, ReadOperand(c.Code, pos, width))
+			pos += width
+		}
+		insts = append(insts, inst)
+		offset = pos
+	}
+	return insts, nil
+}
+
+// Peephole rewrites common instruction sequences into superinstructions:
+//
+//	GetGlobal a; Constant 1; Add; SetGlobal a  ->  IncGlobal a
+//	GetGlobal a; Constant k; Add; SetGlobal a  ->  AddGlobalConst a k
+//	<comparison>; JumpIfFalse t                ->  CmpJump <comparison> t
+//	GetGlobal a; GetGlobal b                   ->  GetGlobal2 a b
+//
+// A sequence is only fused when all of its instructions belong to the same
+// source line and no jump lands inside it. All jump targets are remapped to
+// the new offsets and Lines is rebuilt. Returns the number of fused sequences.
+func (c *Chunk) Peephole() (int, error) {
+	insts, err := c.decode()
+	if err != nil {
+		return 0, err
+	}
+
+	targets := make(map[int]bool)
+	for _, inst := range insts {
+		if idx := jumpOperand(inst.op); idx >= 0 {
+			targets[inst.operands[idx]] = true
+		}
+	}
+
+	// fusable reports whether insts[i:i+n] can be replaced as a unit
+	fusable := func(i, n int) bool {
+		if i+n > len(insts) {
+			return false
+		}
+		for j := i + 1; j < i+n; j++ {
+			if targets[insts[j].offset] || insts[j].line != insts[i].line {
+				return false
+			}
+		}
+		return true
+	}
+
+	fused := 0
+	out := make([]instruction, 0, len(insts))
+	for i := 0; i < len(insts); {
+		cur := insts[i]
+		switch {
+		case cur.op == OpGetGlobal && fusable(i, 4) &&
+			insts[i+1].op == OpConstant && insts[i+2].op == OpAdd &&
+			insts[i+3].op == OpSetGlobal && insts[i+3].operands[0] == cur.operands[0]:
+			k := insts[i+1].operands[0]
+			if val := c.Constants[k]; val.IsNumber() && val.AsNumber() == 1 {
+				out = append(out, instruction{op: OpIncGlobal, operands: []int{cur.operands[0]}, line: cur.line, offset: cur.offset})
+			} else {
+				out = append(out, instruction{op: OpAddGlobalConst, operands: []int{cur.operands[0], k}, line: cur.line, offset: cur.offset})
+			}
+			i += 4
+
+		case isComparison(cur.op) && fusable(i, 2) && insts[i+1].op == OpJumpIfFalse:
+			out = append(out, instruction{op: OpCmpJump, operands: []int{int(cur.op), insts[i+1].operands[0]}, line: cur.line, offset: cur.offset})
+			i += 2
+
+		case cur.op == OpGetGlobal && fusable(i, 2) && insts[i+1].op == OpGetGlobal:
+			out = append(out, instruction{op: OpGetGlobal2, operands: []int{cur.operands[0], insts[i+1].operands[0]}, line: cur.line, offset: cur.offset})
+			i += 2
+
+		default:
+			out = append(out, cur)
+			i++
+			continue
+		}
+		fused++
+	}
+	if fused == 0 {
+		return 0, nil
+	}
+
+	// Compute new offsets, then re-encode with remapped jump targets
+	newOffset := make(map[int]int, len(out)+1)
+	size := 0
+	for _, inst := range out {
+		newOffset[inst.offset] = size
+		def, _ := Lookup(inst.op)
+		size++
+		for _, width := range def.OperandWidths {
+			size += width
+		}
+	}
+	newOffset[len(c.Code)] = size
+
+	code := make([]byte, 0, size)
+	lines := make([]int, 0, size)
+	for _, inst := range out {
+		if idx := jumpOperand(inst.op); idx >= 0 {
+			target, ok := newOffset[inst.operands[idx]]
+			if !ok {
+				return 0, fmt.Errorf("offset %d: jump target %d is not an instruction boundary", inst.offset, inst.operands[idx])
+			}
+			inst.operands[idx] = target
+		}
+		def, _ := Lookup(inst.op)
+		code = append(code, byte(inst.op))
+		for i, width := range def.OperandWidths {
+			code = AppendOperand(code, width, inst.operands[i])
+		}
+		for len(lines) < len(code) {
+			lines = append(lines, inst.line)
+		}
+	}
+
+	// Keep the export and import tables pointing at the same instructions
+	for i := range c.Exports {
+		c.Exports[i].Offset = newOffset[c.Exports[i].Offset]
+	}
+	for _, sym := range c.Imports {
+		for j, call := range sym.Calls {
+			sym.Calls[j] = newOffset[call]
+		}
+	}
+
+	c.Code = code
+	c.Lines = lines
+	return fused, nil
+}
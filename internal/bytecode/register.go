@@ -0,0 +1,159 @@
+package bytecode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Register operands are 4 bytes. With RegConstant set the low 31 bits index
+// the constant pool; otherwise the operand is a register. Registers
+// 0..n-1 are the program's variables (the same indices OpGetGlobal uses),
+// the compiler's temporaries follow them, and GlobalCount counts both.
+const RegConstant uint32 = 1 << 31
+
+// RegOperand splits a register-or-constant operand into its register or
+// constant index. The operand is masked as a uint32: on 32-bit targets an
+// int holding a constant operand is negative.
+func RegOperand(operand int) (idx int, constant bool) {
+	u := uint32(operand)
+	return int(u &^ RegConstant), u&RegConstant != 0
+}
+
+// ConstOperand returns the register-or-constant operand naming constant k
+func ConstOperand(k int) int {
+	return int(uint32(k) | RegConstant)
+}
+
+// registerLayouts describes the operands of each register instruction, one
+// letter per operand:
+//
+//	r register   k register or constant   j code offset   c comparison opcode
+//	a array      f builtin                n count         K constant
+//	s record slot or slot count
+var registerLayouts = map[OpCode]string{
+	OpRMove:        "rk",
+	OpRAdd:         "rkk",
+	OpRSub:         "rkk",
+	OpRMul:         "rkk",
+	OpRDiv:         "rkk",
+	OpRPow:         "rkk",
+	OpRMod:         "rkk",
+	OpRNeg:         "rk",
+	OpRNot:         "rk",
+	OpRAnd:         "rkk",
+	OpROr:          "rkk",
+	OpREq:          "rkk",
+	OpRNeq:         "rkk",
+	OpRGt:          "rkk",
+	OpRGte:         "rkk",
+	OpRLt:          "rkk",
+	OpRLte:         "rkk",
+	OpRJumpIfFalse: "kj",
+	OpRCmpJump:     "ckkj",
+	OpRForInit:     "rkk",
+	OpRGetArray:    "rarn",
+	OpRSetArray:    "arnk",
+	OpRDim:         "arn",
+	OpRPrint:       "k",
+	OpRCall:        "rfrn",
+	OpRInputField:  "r",
+	OpRChain:       "kkK",
+	OpRMapGet:      "rak",
+	OpRMapSet:      "akk",
+	OpRMapHas:      "rak",
+	OpRMapDelete:   "ak",
+	OpRMapKey:      "rak",
+	OpRMapCount:    "ra",
+	OpRDimRecord:   "arnK",
+	OpRGetField:    "rarns",
+	OpRSetField:    "arnsk",
+	OpRCopyRecord:  "arnsarnss",
+}
+
+// IsRegisterOp reports whether op belongs to the register instruction set
+func IsRegisterOp(op OpCode) bool {
+	_, ok := registerLayouts[op]
+	return ok
+}
+
+// RegisterArith maps a stack arithmetic, logical or comparison opcode to its
+// three-address register form
+var RegisterArith = map[OpCode]OpCode{
+	OpAdd: OpRAdd,
+	OpSub: OpRSub,
+	OpMul: OpRMul,
+	OpDiv: OpRDiv,
+	OpPow: OpRPow,
+	OpMod: OpRMod,
+	OpAnd: OpRAnd,
+	OpOr:  OpROr,
+	OpEq:  OpREq,
+	OpNeq: OpRNeq,
+	OpGt:  OpRGt,
+	OpGte: OpRGte,
+	OpLt:  OpRLt,
+	OpLte: OpRLte,
+}
+
+// disassembleRegister prints the operands of a register instruction,
+// showing registers as rN and constants as kN(value)
+func (c *Chunk) disassembleRegister(out *strings.Builder, layout string, def *OpDefinition, offset int) int {
+	for i, width := range def.OperandWidths {
+		val := ReadOperand(c.Code, offset, width)
+		offset += width
+
+		switch layout[i] {
+		case 'r':
+			c.writeRegister(out, val)
+		case 'k':
+			k, constant := RegOperand(val)
+			if !constant {
+				c.writeRegister(out, val)
+				continue
+			}
+			if k < len(c.Constants) && c.Constants[k].IsString() {
+				fmt.Fprintf(out, "k%d(\"%s\") ", k, c.Constants[k].String())
+			} else if k < len(c.Constants) {
+				fmt.Fprintf(out, "k%d(%s) ", k, c.Constants[k].String())
+			} else {
+				fmt.Fprintf(out, "k%d ", k)
+			}
+		case 'K':
+			if val >= 0 && val < len(c.Constants) {
+				fmt.Fprintf(out, "k%d(\"%s\") ", val, c.Constants[val].String())
+			} else {
+				fmt.Fprintf(out, "k%d ", val)
+			}
+		case 'c':
+			if cmp, err := Lookup(OpCode(val)); err == nil {
+				fmt.Fprintf(out, "%s ", strings.TrimPrefix(cmp.Name, "Op"))
+			} else {
+				fmt.Fprintf(out, "%d ", val)
+			}
+		case 'a':
+			if name := symbolName(c.ArrayNames, val); name != "" {
+				fmt.Fprintf(out, "%d (%s) ", val, name)
+			} else {
+				fmt.Fprintf(out, "%d ", val)
+			}
+		case 'f':
+			if val >= 0 && val < len(BuiltinNames) {
+				fmt.Fprintf(out, "%d (%s) ", val, BuiltinNames[val])
+			} else {
+				fmt.Fprintf(out, "%d ", val)
+			}
+		default:
+			fmt.Fprintf(out, "%d ", val)
+		}
+	}
+	return offset
+}
+
+// writeRegister prints a register as rN, or rN(NAME) for a variable
+func (c *Chunk) writeRegister(out *strings.Builder, reg int) {
+	if name := symbolName(c.GlobalNames, reg); name != "" {
+		fmt.Fprintf(out, "r%d(%s) ", reg, name)
+	} else {
+		fmt.Fprintf(out, "r%d ", reg)
+	}
+}
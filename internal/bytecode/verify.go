@@ -0,0 +1,340 @@
+package bytecode
+
+import "fmt"
+
+// sharedOps are the stack-machine instructions the register backend also
+// emits (see the register section of the opcode list)
+var sharedOps = map[OpCode]bool{
+	OpJump:      true,
+	OpGosub:     true,
+	OpReturn:    true,
+	OpEnd:       true,
+	OpNext:      true,
+	OpInput:     true,
+	OpPrintNl:   true,
+	OpCover:     true,
+	OpStop:      true,
+	OpReadInput: true,
+	OpLineInput: true,
+	OpDimMap:    true,
+	OpForEach:   true,
+	OpNextEach:  true,
+
+	OpSaveGlobal:    true,
+	OpRestoreGlobal: true,
+	OpSaveArray:     true,
+	OpRestoreArray:  true,
+}
+
+// stackEffects gives the values popped and pushed by stack instructions
+// with a fixed effect. Instructions whose effect depends on an operand
+// (array and record access, DIM, builtin calls) are handled in stackEffect.
+var stackEffects = map[OpCode][2]int{
+	OpConstant: {0, 1}, OpPop: {1, 0},
+	OpAdd: {2, 1}, OpSub: {2, 1}, OpMul: {2, 1}, OpDiv: {2, 1}, OpPow: {2, 1}, OpMod: {2, 1},
+	OpNeg: {1, 1}, OpNot: {1, 1}, OpAnd: {2, 1}, OpOr: {2, 1},
+	OpEq: {2, 1}, OpNeq: {2, 1}, OpGt: {2, 1}, OpGte: {2, 1}, OpLt: {2, 1}, OpLte: {2, 1},
+	OpJump: {0, 0}, OpJumpIfFalse: {1, 0}, OpGosub: {0, 0}, OpReturn: {0, 0}, OpEnd: {0, 0},
+	OpForInit: {2, 0}, OpNext: {0, 0},
+	OpGetGlobal: {0, 1}, OpSetGlobal: {1, 0},
+	OpPrint: {1, 0}, OpPrintNl: {0, 0}, OpInput: {0, 0}, OpCover: {0, 0},
+	OpGetGlobal2: {0, 2}, OpAddGlobalConst: {0, 0}, OpIncGlobal: {0, 0}, OpCmpJump: {2, 0},
+	OpStop: {0, 0}, OpReadInput: {0, 0}, OpLineInput: {0, 0}, OpInputField: {0, 1},
+	OpChain:  {2, 0},
+	OpDimMap: {0, 0}, OpForEach: {0, 0}, OpNextEach: {0, 0}, OpMapGet: {1, 1}, OpMapSet: {2, 0},
+	OpMapHas: {1, 1}, OpMapDelete: {1, 0}, OpMapKey: {1, 1}, OpMapCount: {0, 1},
+	OpSaveGlobal: {0, 0}, OpRestoreGlobal: {0, 0}, OpSaveArray: {0, 0}, OpRestoreArray: {0, 0},
+}
+
+// Verify checks that a chunk is safe to execute: every instruction decodes
+// and belongs to the chunk's machine, operands index existing constants,
+// variables, arrays and builtins, and jumps land on instruction boundaries.
+// For stack code it also checks by abstract interpretation that no path
+// underflows the stack or reaches an instruction with two different stack
+// depths, and returns the maximum depth. GOSUB and RETURN must run with an
+// empty stack, as the compiler emits them.
+func Verify(c *Chunk) (int, error) {
+	if len(c.Lines) != len(c.Code) {
+		return 0, fmt.Errorf("invalid bytecode: line table covers %d bytes, code has %d", len(c.Lines), len(c.Code))
+	}
+
+	insts, err := c.decode()
+	if err != nil {
+		return 0, fmt.Errorf("invalid bytecode: %v", err)
+	}
+	starts := make(map[int]int, len(insts)) // Offset -> index in insts
+	for i, inst := range insts {
+		starts[inst.offset] = i
+	}
+
+	for _, inst := range insts {
+		if !sharedOps[inst.op] && c.Registers != IsRegisterOp(inst.op) {
+			machine := "stack"
+			if c.Registers {
+				machine = "register"
+			}
+			def, _ := Lookup(inst.op)
+			return 0, verifyError(inst.offset, "%s is not a %s machine instruction", def.Name, machine)
+		}
+		if err := c.verifyOperands(inst, starts); err != nil {
+			return 0, err
+		}
+	}
+	if c.Registers || len(insts) == 0 {
+		return 0, nil
+	}
+	return c.verifyStack(insts, starts)
+}
+
+func verifyError(offset int, format string, args ...any) error {
+	return fmt.Errorf("invalid bytecode at %04d: %s", offset, fmt.Sprintf(format, args...))
+}
+
+// verifyOperands range-checks the operands of one instruction. Indices are
+// checked against 0 too, since a 4-byte operand with the top bit set reads
+// as a negative int on 32-bit targets.
+func (c *Chunk) verifyOperands(inst instruction, starts map[int]int) error {
+	def, _ := Lookup(inst.op)
+	name := def.Name
+	ops := inst.operands
+
+	constant := func(idx int) error {
+		if idx < 0 || idx >= len(c.Constants) {
+			return verifyError(inst.offset, "%s: constant %d out of range (%d constants)", name, idx, len(c.Constants))
+		}
+		return nil
+	}
+	global := func(idx int) error {
+		if idx < 0 || idx >= c.GlobalCount {
+			return verifyError(inst.offset, "%s: variable %d out of range (%d variables)", name, idx, c.GlobalCount)
+		}
+		return nil
+	}
+	array := func(idx int) error {
+		if idx < 0 || idx >= c.ArrayCount {
+			return verifyError(inst.offset, "%s: array %d out of range (%d arrays)", name, idx, c.ArrayCount)
+		}
+		return nil
+	}
+	builtin := func(idx int) error {
+		if idx < 0 || idx >= len(BuiltinNames) {
+			return verifyError(inst.offset, "%s: builtin %d out of range (%d builtins)", name, idx, len(BuiltinNames))
+		}
+		return nil
+	}
+	jump := func(target int) error {
+		if _, ok := starts[target]; !ok && target != len(c.Code) {
+			return verifyError(inst.offset, "%s: jump target %d is not an instruction boundary", name, target)
+		}
+		return nil
+	}
+	comparison := func(cmp int) error {
+		if !isComparison(OpCode(cmp)) {
+			return verifyError(inst.offset, "%s: %d is not a comparison", name, cmp)
+		}
+		return nil
+	}
+	// registers checks count consecutive registers starting at first
+	registers := func(first, count int) error {
+		if count > 0 && (first < 0 || first+count > c.GlobalCount) {
+			return verifyError(inst.offset, "%s: registers %d..%d out of range (%d registers)", name, first, first+count-1, c.GlobalCount)
+		}
+		return nil
+	}
+
+	if layout, ok := registerLayouts[inst.op]; ok {
+		for i, val := range ops {
+			var err error
+			switch layout[i] {
+			case 'r':
+				// A first register followed by a count is checked with the count
+				if i+1 < len(layout) && layout[i+1] == 'n' {
+					continue
+				}
+				err = global(val)
+			case 'k':
+				if k, isConst := RegOperand(val); isConst {
+					err = constant(k)
+				} else {
+					err = global(val)
+				}
+			case 'K':
+				err = constant(val)
+			case 'j':
+				err = jump(val)
+			case 'c':
+				err = comparison(val)
+			case 'a':
+				err = array(val)
+			case 'f':
+				err = builtin(val)
+			case 'n':
+				// The count follows the first register it applies to
+				err = registers(ops[i-1], val)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch inst.op {
+	case OpConstant, OpLineInput, OpChain:
+		return constant(ops[0])
+	case OpReadInput:
+		if err := constant(ops[0]); err != nil {
+			return err
+		}
+		return constant(ops[1])
+	case OpGetGlobal, OpSetGlobal, OpForInit, OpInput, OpIncGlobal, OpSaveGlobal, OpRestoreGlobal:
+		return global(ops[0])
+	case OpGetGlobal2:
+		if err := global(ops[0]); err != nil {
+			return err
+		}
+		return global(ops[1])
+	case OpAddGlobalConst:
+		if err := global(ops[0]); err != nil {
+			return err
+		}
+		return constant(ops[1])
+	case OpNext, OpNextEach:
+		if err := global(ops[0]); err != nil {
+			return err
+		}
+		return jump(ops[1])
+	case OpJump, OpJumpIfFalse, OpGosub:
+		return jump(ops[0])
+	case OpCmpJump:
+		if err := comparison(ops[0]); err != nil {
+			return err
+		}
+		return jump(ops[1])
+	case OpDimRecord:
+		if err := array(ops[0]); err != nil {
+			return err
+		}
+		return constant(ops[2])
+	case OpCopyRecord:
+		if err := array(ops[0]); err != nil {
+			return err
+		}
+		return array(ops[3])
+	case OpGetArray, OpSetArray, OpDim, OpDimMap, OpGetField, OpSetField, OpMapGet, OpMapSet, OpMapHas, OpMapDelete, OpMapKey, OpMapCount,
+		OpSaveArray, OpRestoreArray:
+		return array(ops[0])
+	case OpForEach:
+		if err := array(ops[0]); err != nil {
+			return err
+		}
+		if err := global(ops[1]); err != nil {
+			return err
+		}
+		return jump(ops[2])
+	case OpCallBuiltin:
+		return builtin(ops[0])
+	}
+	return nil
+}
+
+// stackEffect returns the values an instruction pops and pushes
+func stackEffect(inst instruction) (int, int) {
+	switch inst.op {
+	case OpGetArray:
+		return inst.operands[1], 1
+	case OpSetArray:
+		return inst.operands[1] + 1, 0
+	case OpDim, OpDimRecord:
+		return inst.operands[1], 0
+	case OpGetField:
+		return inst.operands[1], 1
+	case OpSetField:
+		return inst.operands[1] + 1, 0
+	case OpCopyRecord:
+		return inst.operands[1] + inst.operands[4], 0
+	case OpCallBuiltin:
+		return inst.operands[1], 1
+	}
+	effect := stackEffects[inst.op]
+	return effect[0], effect[1]
+}
+
+// verifyStack follows every path from the entry point, tracking the stack
+// depth before each instruction
+func (c *Chunk) verifyStack(insts []instruction, starts map[int]int) (int, error) {
+	depths := make([]int, len(insts))
+	for i := range depths {
+		depths[i] = -1
+	}
+	maxDepth := 0
+	work := []int{0}
+	depths[0] = 0
+
+	// reach records depth as the depth on entry to the instruction at target
+	reach := func(from instruction, target, depth int) error {
+		if target == len(c.Code) {
+			return nil
+		}
+		i := starts[target]
+		switch depths[i] {
+		case -1:
+			depths[i] = depth
+			work = append(work, i)
+		case depth:
+		default:
+			return verifyError(from.offset, "stack depth %d at %04d does not match depth %d from another path", depth, target, depths[i])
+		}
+		return nil
+	}
+
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		inst := insts[i]
+		depth := depths[i]
+		def, _ := Lookup(inst.op)
+		next := len(c.Code)
+		if i+1 < len(insts) {
+			next = insts[i+1].offset
+		}
+
+		pops, pushes := stackEffect(inst)
+		if pops > depth {
+			return 0, verifyError(inst.offset, "%s needs %d values but the stack holds %d", def.Name, pops, depth)
+		}
+		depth += pushes - pops
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		var err error
+		switch inst.op {
+		case OpEnd, OpChain:
+		case OpReturn:
+			if depth != 0 {
+				err = verifyError(inst.offset, "%s with %d values on the stack", def.Name, depth)
+			}
+		case OpJump:
+			err = reach(inst, inst.operands[0], depth)
+		case OpGosub:
+			if depth != 0 {
+				err = verifyError(inst.offset, "%s with %d values on the stack", def.Name, depth)
+				break
+			}
+			if err = reach(inst, inst.operands[0], depth); err == nil {
+				err = reach(inst, next, depth)
+			}
+		case OpJumpIfFalse, OpCmpJump, OpNext, OpForEach, OpNextEach:
+			if err = reach(inst, inst.operands[jumpOperand(inst.op)], depth); err == nil {
+				err = reach(inst, next, depth)
+			}
+		default:
+			err = reach(inst, next, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return maxDepth, nil
+}
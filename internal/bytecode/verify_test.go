@@ -0,0 +1,113 @@
+package bytecode_test
+
+import (
+	"strings"
+	"testing"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/interpreter"
+)
+
+// asm builds a chunk from opcodes and operands, encoding each operand with
+// the width its opcode declares
+func asm(t *testing.T, registers bool, prog ...[]int) *bytecode.Chunk {
+	t.Helper()
+	c := bytecode.NewChunk()
+	c.Registers = registers
+	c.Constants = []interpreter.Value{interpreter.NumberValue(1), interpreter.StringValue("x")}
+	c.GlobalCount = 2
+	c.ArrayCount = 1
+	for _, inst := range prog {
+		op := bytecode.OpCode(inst[0])
+		def, err := bytecode.Lookup(op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := []byte{byte(op)}
+		for i, width := range def.OperandWidths {
+			code = bytecode.AppendOperand(code, width, inst[1+i])
+		}
+		for _, b := range code {
+			c.Emit(b, 10)
+		}
+	}
+	return c
+}
+
+func op(code bytecode.OpCode, operands ...int) []int {
+	return append([]int{int(code)}, operands...)
+}
+
+func TestVerifyAccepts(t *testing.T) {
+	c := asm(t, false,
+		op(bytecode.OpConstant, 0),      // 0
+		op(bytecode.OpConstant, 0),      // 5
+		op(bytecode.OpGt),               // 10
+		op(bytecode.OpJumpIfFalse, 27),  // 11
+		op(bytecode.OpGetGlobal2, 0, 1), // 16
+		op(bytecode.OpAdd),              // 25
+		op(bytecode.OpPrint),            // 26
+		op(bytecode.OpEnd),              // 27
+	)
+	depth, err := bytecode.Verify(c)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if depth != 2 {
+		t.Errorf("max stack depth = %d, want 2", depth)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		registers bool
+		prog      [][]int
+		want      string
+	}{
+		{"constant index", false, [][]int{op(bytecode.OpConstant, 2), op(bytecode.OpPrint)}, "constant 2 out of range"},
+		{"global index", false, [][]int{op(bytecode.OpGetGlobal, 5), op(bytecode.OpPrint)}, "variable 5 out of range"},
+		{"array index", false, [][]int{op(bytecode.OpConstant, 0), op(bytecode.OpDim, 1, 1)}, "array 1 out of range"},
+		{"builtin index", false, [][]int{op(bytecode.OpCallBuiltin, 999, 0), op(bytecode.OpPop)}, "builtin 999 out of range"},
+		{"jump into an instruction", false, [][]int{op(bytecode.OpJump, 2), op(bytecode.OpConstant, 0)}, "jump target 2 is not an instruction boundary"},
+		{"jump past the end", false, [][]int{op(bytecode.OpJump, 100)}, "jump target 100"},
+		{"underflow", false, [][]int{op(bytecode.OpConstant, 0), op(bytecode.OpAdd)}, "OpAdd needs 2 values but the stack holds 1"},
+		{"pop on empty stack", false, [][]int{op(bytecode.OpPop)}, "needs 1 values but the stack holds 0"},
+		{"depth mismatch", false, [][]int{
+			op(bytecode.OpConstant, 0),     // 0
+			op(bytecode.OpJumpIfFalse, 15), // 5
+			op(bytecode.OpConstant, 0),     // 10
+			op(bytecode.OpPrintNl),         // 15: reached with depth 0 and 1
+		}, "does not match"},
+		{"gosub with values on the stack", false, [][]int{op(bytecode.OpConstant, 0), op(bytecode.OpGosub, 0)}, "OpGosub with 1 values"},
+		{"comparison operand", false, [][]int{op(bytecode.OpConstant, 0), op(bytecode.OpConstant, 0), op(bytecode.OpCmpJump, int(bytecode.OpAdd), 0)}, "is not a comparison"},
+		{"register op in stack code", false, [][]int{op(bytecode.OpRPrint, 0)}, "OpRPrint is not a stack machine instruction"},
+		{"stack op in register code", true, [][]int{op(bytecode.OpPrint)}, "OpPrint is not a register machine instruction"},
+		{"register index", true, [][]int{op(bytecode.OpRMove, 2, 0)}, "variable 2 out of range"},
+		{"register constant", true, [][]int{op(bytecode.OpRPrint, bytecode.ConstOperand(7))}, "constant 7 out of range"},
+		{"register with the constant bit", true, [][]int{op(bytecode.OpRMove, bytecode.ConstOperand(0), 0)}, "out of range"},
+		{"register range", true, [][]int{op(bytecode.OpRCall, 0, 0, 1, 2)}, "registers 1..2 out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bytecode.Verify(asm(t, tt.registers, tt.prog...))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsTruncatedCode(t *testing.T) {
+	c := asm(t, false, op(bytecode.OpConstant, 0))
+	c.Code, c.Lines = c.Code[:3], c.Lines[:3]
+	if _, err := bytecode.Verify(c); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("err = %v, want truncated", err)
+	}
+
+	c = asm(t, false, op(bytecode.OpConstant, 0))
+	c.Code[0] = 250
+	if _, err := bytecode.Verify(c); err == nil || !strings.Contains(err.Error(), "undefined") {
+		t.Errorf("err = %v, want unknown opcode", err)
+	}
+}
@@ -0,0 +1,519 @@
+// Package codegen translates BASIC programs into source code for other
+// languages (zb build) and lowers bytecode to WebAssembly (zb -o
+// prog.wasm, see Wasm). The analysis in this file is shared by the
+// emitters: it assigns static types to variables, decides which FOR/NEXT
+// pairs can become native loops and records the labels the generated code
+// needs.
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/vet"
+)
+
+// valueType is the static type of a variable or expression
+type valueType int
+
+const (
+	typeNone   valueType = iota // Not known yet (during inference)
+	typeNumber                  // Always a number
+	typeString                  // Always a string
+	typeValue                   // Decided at run time: empty, number or string
+)
+
+// join combines the types of two assignments to the same variable
+func join(a, b valueType) valueType {
+	switch {
+	case a == typeNone:
+		return b
+	case b == typeNone, a == b:
+		return a
+	}
+	return typeValue
+}
+
+// loop is a statically matched FOR/NEXT pair
+type loop struct {
+	id     int
+	stmt   *ast.ForStmt
+	varIdx int // Index of the loop variable, as the VM numbers it
+	forAt  int // Position of the FOR among the top-level statements, or -1
+	nextAt int // Position of the NEXT, or -1 if it is nested in an IF
+	next   *ast.NextStmt
+
+	// native loops become a structured loop in the target language. A loop
+	// is native when FOR and NEXT are top-level statements, nothing jumps
+	// into or out of the body and every loop inside the body is native too.
+	// Other loops keep the VM's run-time FOR stack.
+	native bool
+}
+
+// analysis is what the emitters need to know about a program
+type analysis struct {
+	prog       *ast.Program
+	globals    map[string]int // Variable name -> VM index
+	arrays     map[string]int // Array name -> VM index
+	names      []string       // Variables in VM index order
+	arrayNames []string       // Arrays in VM index order
+
+	types   map[string]valueType    // Static type of each variable
+	targets map[int]bool            // Line numbers used by GOTO and GOSUB
+	loops   map[*ast.ForStmt]*loop  // FOR statements and their loops
+	nexts   map[*ast.NextStmt]*loop // NEXT statements and their loops
+	gosubs  map[*ast.GosubStmt]int  // Return point index of each GOSUB
+	returns bool                    // The program contains RETURN
+	read    map[string]bool         // Variables that are read somewhere
+	used    map[string]bool         // Arrays that are accessed somewhere
+	frames  bool                    // Some FOR needs the run-time FOR stack
+	saves   bool                    // The program saves locals around recursive calls
+	flat    map[*ast.IfStmt]bool    // IFs whose branches must be emitted inline
+	order   []ast.Node              // Top-level statements in program order
+	lineAt  map[int]int             // Line number -> position of its first statement
+}
+
+// analyze checks prog with the bytecode compiler, so that it accepts and
+// rejects exactly the programs the VM does and numbers variables and arrays
+// the same way, then runs the analyses the emitters need
+func analyze(prog *ast.Program) (*analysis, error) {
+	chunk, err := compiler.New().Compile(prog)
+	if err != nil {
+		return nil, err
+	}
+	if err := chunk.Runnable(); err != nil {
+		// There is no linker for generated code: a MODULE and its callers
+		// are only combined as bytecode
+		return nil, err
+	}
+	if line := findStmt(prog, isChain); line >= 0 {
+		// A generated program cannot load and compile another BASIC file
+		return nil, fmt.Errorf("%s: CHAIN is not supported in generated code", chunk.Position(line))
+	}
+	if line := findStmt(prog, isMapDim); line >= 0 {
+		// The runtimes have no dictionary type to back a MAP
+		return nil, fmt.Errorf("%s: MAP is not supported in generated code", chunk.Position(line))
+	}
+	a := &analysis{
+		prog:       prog,
+		globals:    make(map[string]int),
+		arrays:     make(map[string]int),
+		names:      chunk.GlobalNames,
+		arrayNames: chunk.ArrayNames,
+		types:      make(map[string]valueType),
+		targets:    make(map[int]bool),
+		loops:      make(map[*ast.ForStmt]*loop),
+		nexts:      make(map[*ast.NextStmt]*loop),
+		gosubs:     make(map[*ast.GosubStmt]int),
+		read:       make(map[string]bool),
+		used:       make(map[string]bool),
+		flat:       make(map[*ast.IfStmt]bool),
+		lineAt:     make(map[int]int),
+	}
+	for i, name := range chunk.GlobalNames {
+		a.globals[name] = i
+	}
+	for i, name := range chunk.ArrayNames {
+		a.arrays[name] = i
+	}
+
+	a.collect()
+	a.findNativeLoops()
+	a.inferTypes()
+	for _, line := range prog.Lines {
+		for _, stmt := range line.Statements {
+			a.markFlat(stmt)
+		}
+	}
+	return a, nil
+}
+
+// isChain reports whether stmt is a CHAIN
+func isChain(stmt ast.Node) bool {
+	_, ok := stmt.(*ast.ChainStmt)
+	return ok
+}
+
+// isMapDim reports whether stmt declares a MAP
+func isMapDim(stmt ast.Node) bool {
+	d, ok := stmt.(*ast.DimStmt)
+	return ok && strings.EqualFold(d.Type, "MAP")
+}
+
+// findStmt returns the number of the first line with a statement
+// matching match, or -1
+func findStmt(prog *ast.Program, match func(ast.Node) bool) int {
+	var has func(stmts []ast.Node) bool
+	has = func(stmts []ast.Node) bool {
+		for _, stmt := range stmts {
+			if match(stmt) {
+				return true
+			}
+			switch s := stmt.(type) {
+			case *ast.IfStmt:
+				if has(s.ThenStmts) || has(s.ElseStmts) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, line := range prog.Lines {
+		if has(line.Statements) {
+			return line.LineNumber
+		}
+	}
+	return -1
+}
+
+// collect records jump targets, GOSUB return points, FOR/NEXT pairs and
+// which variables and arrays are read, in the order the compiler visits
+// the statements
+func (a *analysis) collect() {
+	var stack []*loop
+	var visit func(stmt ast.Node, top bool)
+	visit = func(stmt ast.Node, top bool) {
+		pos := -1
+		if top {
+			pos = len(a.order)
+			a.order = append(a.order, stmt)
+		}
+		a.reads(stmt)
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			for _, sub := range s.ThenStmts {
+				visit(sub, false)
+			}
+			for _, sub := range s.ElseStmts {
+				visit(sub, false)
+			}
+		case *ast.GotoStmt:
+			a.targets[s.LineNumber] = true
+		case *ast.GosubStmt:
+			a.targets[s.LineNumber] = true
+			a.gosubs[s] = len(a.gosubs)
+		case *ast.ReturnStmt:
+			a.returns = true
+		case *ast.SaveLocalsStmt:
+			a.saves = true
+		case *ast.ForStmt:
+			l := &loop{id: len(a.loops) + 1, stmt: s, varIdx: a.globals[strings.ToUpper(s.Var)], forAt: pos, nextAt: -1}
+			a.loops[s] = l
+			stack = append(stack, l)
+		case *ast.NextStmt:
+			// The compiler has already rejected unmatched NEXTs
+			l := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			l.next = s
+			l.nextAt = pos
+			a.nexts[s] = l
+		}
+	}
+	for _, line := range a.prog.Lines {
+		a.lineAt[line.LineNumber] = len(a.order)
+		for _, stmt := range line.Statements {
+			visit(stmt, true)
+		}
+	}
+}
+
+// reads marks the variables and arrays a statement reads
+func (a *analysis) reads(stmt ast.Node) {
+	var expr func(e ast.Node)
+	expr = func(e ast.Node) {
+		switch n := e.(type) {
+		case *ast.Identifier:
+			a.read[strings.ToUpper(n.Name)] = true
+		case *ast.ArrayAccess:
+			a.used[strings.ToUpper(n.Name)] = true
+			for _, idx := range n.Indices {
+				expr(idx)
+			}
+		case *ast.FunctionCall:
+			for _, arg := range n.Args {
+				expr(arg)
+			}
+		case *ast.BinaryOp:
+			expr(n.Left)
+			expr(n.Right)
+		case *ast.ComparisonOp:
+			expr(n.Left)
+			expr(n.Right)
+		case *ast.LogicalOp:
+			expr(n.Left)
+			expr(n.Right)
+		case *ast.UnaryOp:
+			expr(n.Right)
+		case *ast.RecordRef:
+			a.used[strings.ToUpper(n.Name)] = true
+			for _, idx := range n.Indices {
+				expr(idx)
+			}
+		case *ast.FieldAccess:
+			ref, _ := n.Slot()
+			expr(ref)
+		}
+	}
+	switch s := stmt.(type) {
+	case *ast.Assignment:
+		switch s.Target.(type) {
+		case *ast.ArrayAccess, *ast.FieldAccess:
+			expr(s.Target)
+		}
+		expr(s.Value)
+	case *ast.RecordAssignment:
+		expr(s.Target)
+		expr(s.Value)
+	case *ast.InputStmt:
+		for _, v := range s.Vars {
+			switch v.(type) {
+			case *ast.ArrayAccess, *ast.FieldAccess:
+				expr(v)
+			}
+		}
+	case *ast.PrintStmt:
+		for _, v := range s.Values {
+			expr(v)
+		}
+	case *ast.IfStmt:
+		expr(s.Condition)
+	case *ast.ForStmt:
+		expr(s.Start)
+		expr(s.End)
+		expr(s.Step)
+	case *ast.NextStmt:
+		// NEXT reads the loop variable to increment it; the variable name
+		// is optional, so it is marked through the matching FOR instead
+	case *ast.DimStmt:
+		for _, size := range s.Sizes {
+			expr(size)
+		}
+	case *ast.SaveLocalsStmt:
+		for _, name := range s.Vars {
+			a.read[name] = true
+		}
+		for _, name := range s.Arrays {
+			a.used[name] = true
+		}
+	}
+}
+
+// findNativeLoops decides which loops can be emitted as structured loops.
+// Inner loops end first, so visiting loops in NEXT order sees them before
+// the loops that contain them.
+func (a *analysis) findNativeLoops() {
+	byNext := make(map[int]*loop)
+	for _, l := range a.loops {
+		if l.next != nil {
+			a.read[a.names[l.varIdx]] = true
+		}
+		if l.forAt >= 0 && l.nextAt >= 0 {
+			byNext[l.nextAt] = l
+		}
+	}
+	for pos := range a.order {
+		if l, ok := byNext[pos]; ok {
+			l.native = a.nativeBody(l)
+		}
+	}
+	for _, l := range a.loops {
+		if !l.native {
+			a.frames = true
+		}
+	}
+}
+
+// nativeBody reports whether the body of l can run without the FOR stack
+func (a *analysis) nativeBody(l *loop) bool {
+	for _, line := range a.prog.Lines {
+		if pos := a.lineAt[line.LineNumber]; a.targets[line.LineNumber] && pos > l.forAt && pos <= l.nextAt {
+			return false
+		}
+	}
+	ok := true
+	var visit func(stmt ast.Node)
+	visit = func(stmt ast.Node) {
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			for _, sub := range s.ThenStmts {
+				visit(sub)
+			}
+			for _, sub := range s.ElseStmts {
+				visit(sub)
+			}
+		case *ast.GotoStmt, *ast.GosubStmt, *ast.ReturnStmt:
+			ok = false
+		case *ast.ForStmt:
+			if !a.loops[s].native {
+				ok = false
+			}
+		case *ast.NextStmt:
+			if !a.nexts[s].native {
+				ok = false
+			}
+		}
+	}
+	for _, stmt := range a.order[l.forAt+1 : l.nextAt] {
+		visit(stmt)
+	}
+	return ok
+}
+
+// inferTypes gives each variable the join of the types of everything
+// assigned to it. Variables that INPUT assigns, and variables that may be
+// read before their first assignment (and so be empty), are dynamic.
+func (a *analysis) inferTypes() {
+	for name := range vet.MaybeUnassigned(a.prog) {
+		a.types[name] = typeValue
+	}
+	for changed := true; changed; {
+		changed = false
+		assign := func(name string, t valueType) {
+			if old := a.types[name]; join(old, t) != old {
+				a.types[name] = join(old, t)
+				changed = true
+			}
+		}
+		var visit func(stmt ast.Node)
+		visit = func(stmt ast.Node) {
+			switch s := stmt.(type) {
+			case *ast.Assignment:
+				if target, ok := s.Target.(*ast.Identifier); ok {
+					assign(strings.ToUpper(target.Name), a.typeOf(s.Value))
+				}
+			case *ast.ForStmt:
+				name := strings.ToUpper(s.Var)
+				assign(name, a.typeOf(s.Start))
+				assign(name, typeNumber) // NEXT stores a number
+			case *ast.InputStmt:
+				// INPUT converts each field to the type of its variable
+				for _, v := range s.Vars {
+					if target, ok := v.(*ast.Identifier); ok {
+						name := strings.ToUpper(target.Name)
+						if interpreter.InputType(name) == interpreter.InputString {
+							assign(name, typeString)
+						} else {
+							assign(name, typeNumber)
+						}
+					}
+				}
+			case *ast.IfStmt:
+				for _, sub := range s.ThenStmts {
+					visit(sub)
+				}
+				for _, sub := range s.ElseStmts {
+					visit(sub)
+				}
+			}
+		}
+		for _, stmt := range a.order {
+			visit(stmt)
+		}
+	}
+	for _, name := range a.names {
+		if a.types[name] == typeNone {
+			a.types[name] = typeValue
+		}
+	}
+}
+
+// typeOf returns the static type of an expression
+func (a *analysis) typeOf(e ast.Node) valueType {
+	switch n := e.(type) {
+	case *ast.Number:
+		return typeNumber
+	case *ast.StringLiteral:
+		return typeString
+	case *ast.Identifier:
+		return a.types[strings.ToUpper(n.Name)]
+	case *ast.ArrayAccess:
+		if strings.HasSuffix(n.Name, "$") {
+			return typeString
+		}
+		return typeNumber
+	case *ast.FunctionCall:
+		if strings.HasSuffix(n.Name, "$") {
+			return typeString
+		}
+		return typeNumber
+	case *ast.FieldAccess:
+		if f, _ := n.Info(); f.String {
+			return typeString
+		}
+		return typeNumber
+	case *ast.BinaryOp:
+		if n.Op != "+" {
+			return typeNumber
+		}
+		// Same rules as the VM's OpAdd
+		left, right := a.typeOf(n.Left), a.typeOf(n.Right)
+		switch {
+		case left == typeString || right == typeString:
+			return typeString
+		case left == typeValue || right == typeValue:
+			return typeValue
+		case left == typeNone || right == typeNone:
+			return typeNone
+		}
+		return typeNumber
+	case *ast.UnaryOp:
+		if n.Op == "+" {
+			return a.typeOf(n.Right)
+		}
+		return typeNumber
+	}
+	// Comparisons and logical operators are numbers
+	return typeNumber
+}
+
+// markFlat records IFs whose branches contain a statement that needs a
+// label (a GOSUB return point or a FOR loop top). Labels must be at the
+// top level of the generated function, so such IFs are emitted as jumps
+// around inline branches rather than as nested blocks.
+func (a *analysis) markFlat(stmt ast.Node) bool {
+	switch s := stmt.(type) {
+	case *ast.IfStmt:
+		flat := false
+		for _, sub := range s.ThenStmts {
+			flat = a.markFlat(sub) || flat
+		}
+		for _, sub := range s.ElseStmts {
+			flat = a.markFlat(sub) || flat
+		}
+		a.flat[s] = flat
+		return flat
+	case *ast.GosubStmt:
+		return true
+	case *ast.ForStmt:
+		return !a.loops[s].native
+	}
+	return false
+}
+
+// builtinArity returns the argument counts a builtin accepts
+func builtinArity(name string) (min, max int) {
+	switch name {
+	case "RND", "PI", "EULER":
+		return 0, 0
+	case "LEFT$", "RIGHT$":
+		return 2, 2
+	case "MID$", "INSTR":
+		return 2, 3
+	}
+	return 1, 1
+}
+
+// arityError is the message the VM reports for a wrong argument count
+func arityError(name string) string {
+	min, max := builtinArity(name)
+	switch {
+	case min != max:
+		return fmt.Sprintf("%s requires %d or %d arguments", name, min, max)
+	case min == 1:
+		return fmt.Sprintf("%s requires 1 argument", name)
+	}
+	return fmt.Sprintf("%s requires %d arguments", name, min)
+}
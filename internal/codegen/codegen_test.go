@@ -0,0 +1,191 @@
+package codegen_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/codegen"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vm"
+)
+
+// input is fed to programs that use INPUT
+const input = "5\nabc\nx\n7\n1, 2,3\n\"Smith, John\", 40\nHe said \"hi\", then left\n3\n2, 9\n-40\n" +
+	"90,85, 77.5,60,100\n25\n90,85, 77.5,60,100\n"
+
+func parse(t *testing.T, name, src string) *ast.Program {
+	t.Helper()
+	prog, err := parser.ParseProgram(name, []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	return prog
+}
+
+// runVM returns what the stack VM prints and the runtime error, if any
+func runVM(t *testing.T, prog *ast.Program) (string, string) {
+	t.Helper()
+	chunk, err := compiler.New().Compile(prog)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	var out bytes.Buffer
+	err = vm.New(chunk, vm.WithOutput(&out), vm.WithInput(strings.NewReader(input))).Run()
+	// Generated programs print a STOP and end, as the REPL shows it
+	var brk *interpreter.Break
+	if errors.As(err, &brk) {
+		return out.String() + "\n" + brk.Error() + "\n", ""
+	}
+	if err != nil {
+		return out.String(), "Runtime error: " + err.Error()
+	}
+	return out.String(), ""
+}
+
+// runGo transpiles prog, builds it with the go tool and runs it
+func runGo(t *testing.T, prog *ast.Program) (string, string) {
+	t.Helper()
+	src, err := codegen.Go(prog)
+	if err != nil {
+		t.Fatalf("codegen: %v", err)
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "prog")
+	build := exec.Command("go", "build", "-o", bin, file)
+	if msg, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s\n%s", err, msg, src)
+	}
+
+	var stdout, stderr bytes.Buffer
+	run := exec.Command(bin)
+	run.Stdin = strings.NewReader(input)
+	run.Stdout, run.Stderr = &stdout, &stderr
+	err = run.Run()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatal(err)
+	}
+	return stdout.String(), strings.TrimSpace(stderr.String())
+}
+
+func needGo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go tool not found")
+	}
+}
+
+// TestGoSamples checks that every sample prints the same when transpiled
+// as when run by the VM. Samples that print random numbers are only built.
+func TestGoSamples(t *testing.T) {
+	needGo(t)
+	files, err := filepath.Glob("../../samples/*.bas")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no samples: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			t.Parallel()
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prog := parse(t, file, string(data))
+			got, gotErr := runGo(t, prog)
+			if strings.Contains(strings.ToUpper(string(data)), "RND") {
+				return
+			}
+			want, wantErr := runVM(t, prog)
+			if got != want || gotErr != wantErr {
+				t.Errorf("output differs from the VM\n got: %q %s\nwant: %q %s", got, gotErr, want, wantErr)
+			}
+		})
+	}
+}
+
+// semantics covers value, loop and error behaviour the samples do not
+var semantics = []struct{ name, src string }{
+	{"empty and dynamic values", "10 PRINT \"[\"; X; \"]\"; X + 1; Y$ + \"a\"\n20 IF Z = \"\" THEN PRINT \"empty\"\n" +
+		"30 X = 5\n40 IF X > 3 THEN X = \"s\"\n50 PRINT X + 1; X > \"r\"\n"},
+	{"arithmetic", "10 PRINT 3 * 0.1; 0.1 + 0.2; 2 ^ 10; 7 MOD 3; -7 MOD 3; INT(-2.5); ABS(-3); -0; PI; EULER\n" +
+		"20 A = 3: PRINT A * 0.1; -A; -(-A); NOT A; NOT 0; A = 3 AND A <> 2; 1 < 2 OR A\n"},
+	{"strings", "10 S$ = \"hello\"\n20 PRINT LEFT$(S$, 2); RIGHT$(S$, 10); MID$(S$, 2, 3); MID$(S$, 3); INSTR(S$, \"l\"); INSTR(4, S$, \"l\")\n" +
+		"30 PRINT UCASE$(\"a\"); LCASE$(\"B\"); \"[\"; SPACE$(3); \"]\"; CHR$(65); ASC(\"A\"); LEN(S$); S$ + 1; S$ < \"help\"\n"},
+	{"utf-8 strings", "10 S$ = \"中文ab\"\n20 PRINT LEN(S$); LEFT$(S$, 3); RIGHT$(S$, 1); MID$(S$, 4); INSTR(S$, \"b\"); ASC(S$)\n"},
+	{"number formatting", "10 X = 1000\n20 PRINT X * 1000; X * 123456.789; X / 10000000; X / 100000000; X * 1E+18; 1 / X / 3; -X * X\n"},
+	{"print separators", "10 PRINT 1, 2; 3,\n20 PRINT \"x\";\n30 PRINT\n40 PRINT \"a\", \"b\"\n"},
+	{"loops", "10 FOR I = 10 TO 1 STEP -3: PRINT I;: NEXT I\n20 S = 2: FOR I = 1 TO 5 STEP S: PRINT I;: NEXT I\n" +
+		"30 FOR I = 1 TO 0: PRINT \"once\": NEXT I\n40 FOR I = 1 TO 3 STEP 0: PRINT \"zero\": NEXT I\n" +
+		"50 FOR I = \"2\" TO 4: PRINT I;: NEXT I\n60 FOR I = 1 TO 3: FOR J = I TO 3: PRINT I * J;: NEXT J: NEXT I\n70 PRINT\n"},
+	{"gosub and for inside if", "10 FOR K = 1 TO 3\n20 IF K = 2 THEN GOSUB 100 ELSE FOR J = 1 TO 2: PRINT K; J: NEXT J\n" +
+		"30 NEXT K\n40 END\n100 PRINT \"sub\"\n110 RETURN\n"},
+	{"recursion", "10 PRINT FACT(6); FIB(12)\n20 WALK 3\n30 PRINT\n40 END\n50 FUNCTION FACT(N)\n60 IF N <= 1 THEN FACT = 1 ELSE FACT = N * FACT(N - 1)\n70 END FUNCTION\n" +
+		"80 FUNCTION FIB(N)\n90 IF N < 2 THEN FIB = N ELSE FIB = FIB(N - 1) + FIB(N - 2)\n100 END FUNCTION\n" +
+		"110 SUB WALK(N)\n120 DIM T$(2): T$(1) = CHR$(65 + N): K = K + N\n130 IF N > 0 THEN WALK N - 1\n140 PRINT T$(1); K; \" \";\n150 END SUB\n"},
+	{"goto out of loop", "10 FOR I = 1 TO 3\n20 IF I = 2 THEN GOTO 40\n30 NEXT I\n40 PRINT \"out\"; I\n"},
+	{"end inside loop", "10 FOR I = 1 TO 3\n20 IF I = 2 THEN END\n30 PRINT I\n40 NEXT I\n"},
+	{"stop inside loop", "10 FOR I = 1 TO 3\n20 PRINT I;\n30 IF I = 2 THEN STOP\n40 NEXT I\n"},
+	{"arrays", "10 DIM A(3): DIM B(2, 3)\n20 FOR I = 0 TO 2: A(I) = I * I: B(I, I) = \"7\": NEXT I\n30 PRINT A(2); B(1, 1); B(0, 1)\n"},
+	{"string arrays", "10 DIM N$(3): DIM M$(2, 2)\n20 N$(1) = \"ab\": N$(2) = 5: M$(1, 1) = N$(1) + \"c\"\n" +
+		"30 PRINT \"[\"; N$(0); \"]\"; N$(1) + N$(2); LEN(N$(2)); M$(1, 1); LEN(M$(0, 1))\n"},
+	{"records", "10 TYPE PT: X AS DOUBLE: Y AS DOUBLE: END TYPE\n20 TYPE ITEM: NAME AS STRING: AT AS PT: END TYPE\n" +
+		"30 DIM IT AS ITEM: DIM INV(2) AS ITEM\n40 IT.NAME = \"bolt\": IT.AT.X = 3\n50 INV(1) = IT: INV(0).AT = IT.AT: IT.AT.X = 7\n" +
+		"60 SHOW INV(1)\n70 PRINT \"[\"; INV(0).NAME; \"]\"; INV(0).AT.X; IT.AT.X\n80 END\n90 SUB SHOW(P AS ITEM)\n100 PRINT P.NAME; P.AT.X + P.AT.Y\n110 END SUB\n"},
+	{"record not declared", "10 TYPE PT: X AS DOUBLE: END TYPE\n20 PRINT \"a\"\n30 IF 0 THEN DIM P AS PT\n40 PRINT P.X\n"},
+	{"input", "10 INPUT \"n\"; N\n20 INPUT S$, T\n30 LINE INPUT \"line: \"; L$\n40 DIM C(3): INPUT I, C(I)\n50 INPUT A$\n" +
+		"60 PRINT N; S$; T; L$; I; C(2); A$\n70 INPUT \"x\", X\n80 PRINT X\n90 INPUT Y\n"},
+	{"next variable mismatch", "10 FOR I = 1 TO 3\n20 FOR J = 1 TO 2\n30 IF J = 2 THEN GOTO 50\n40 NEXT J\n50 NEXT I\n"},
+	{"next without for", "10 GOTO 30\n20 FOR I = 1 TO 2\n30 NEXT I\n"},
+	{"return without gosub", "10 PRINT \"a\"\n20 RETURN\n"},
+	{"division by zero", "10 PRINT \"a\"; 1 / 0\n"},
+	{"and evaluates both sides", "10 X = 0\n20 IF X AND 1 / X THEN PRINT \"no\"\n"},
+	{"negating a string", "10 A$ = \"x\"\n20 PRINT -A$\n"},
+	{"array not declared", "10 PRINT A(1)\n"},
+	{"array out of bounds", "10 DIM A(2, 3)\n20 A(1, 2) = 5\n30 PRINT A(1, 2); A(3, 0)\n"},
+	{"negative dimension", "10 DIM A(-1)\n"},
+	{"wrong argument count", "10 PRINT ABS(1, 2)\n"},
+	{"builtin errors", "10 PRINT SQR(4); CHR$(300)\n"},
+}
+
+func TestGoSemantics(t *testing.T) {
+	needGo(t)
+	for _, tt := range semantics {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			prog := parse(t, tt.name, tt.src)
+			want, wantErr := runVM(t, prog)
+			got, gotErr := runGo(t, prog)
+			if got != want || gotErr != wantErr {
+				t.Errorf("output differs from the VM\n got: %q %s\nwant: %q %s", got, gotErr, want, wantErr)
+			}
+		})
+	}
+}
+
+func TestGoRejects(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"10 GOTO 99\n", "jump to undefined line number 99"},
+		{"10 PRINT FOO()\n", "unknown builtin function: FOO"},
+		{"10 NEXT I\n", "NEXT without FOR"},
+	}
+	for _, tt := range tests {
+		_, err := codegen.Go(parse(t, "bad.bas", tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Go(%q) err = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
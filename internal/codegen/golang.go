@@ -0,0 +1,866 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"strconv"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/interpreter"
+)
+
+// Option configures code generation
+type Option func(*options)
+
+type options struct {
+	name   string // Source file name for the header comment
+	source []byte // BASIC source, copied into comments line by line
+}
+
+// WithSource names the BASIC file the program came from and copies each
+// of its lines into a comment above the code generated for it
+func WithSource(name string, src []byte) Option {
+	return func(o *options) {
+		o.name = name
+		o.source = src
+	}
+}
+
+// Go translates prog into a self-contained Go program (package main,
+// standard library only) that behaves like the program run by the VM.
+//
+// The program becomes one function with a label for every line that
+// GOTO or GOSUB targets. GOSUB pushes a return point and RETURN
+// dispatches on it with a switch. FOR/NEXT pairs that the analysis proves
+// structured become Go for loops; the others keep the VM's run-time FOR
+// stack. Variables that always hold a number or always hold a string are
+// float64 or string, and the rest use the runtime's dynamic value type.
+func Go(prog *ast.Program, opts ...Option) ([]byte, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	a, err := analyze(prog)
+	if err != nil {
+		return nil, err
+	}
+	g := &goGen{a: a, lines: sourceLines(prog, o)}
+
+	var buf bytes.Buffer
+	if o.name != "" {
+		fmt.Fprintf(&buf, "// Code generated by zb build from %s. DO NOT EDIT.\n\n", o.name)
+	} else {
+		buf.WriteString("// Code generated by zb build. DO NOT EDIT.\n\n")
+	}
+	buf.WriteString("package main\n\n")
+	buf.WriteString("import (\n\t\"bufio\"\n\t\"fmt\"\n\t\"math\"\n\t\"math/rand\"\n\t\"os\"\n\t\"regexp\"\n\t\"strconv\"\n\t\"strings\"\n)\n\n")
+	buf.WriteString("// run executes the BASIC program\n")
+	buf.WriteString("func run() (err error) {\n")
+	buf.WriteString("defer catch(&err)\n")
+	g.declarations(&buf)
+	g.program()
+	buf.Write(g.body.Bytes())
+	buf.WriteString("return nil\n")
+	g.returnDispatch(&buf)
+	buf.WriteString("}\n")
+	buf.WriteString(goRuntime)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %v", err)
+	}
+	return src, nil
+}
+
+// sourceLines maps BASIC line numbers to their source text. The lines of
+// an unnumbered program are found by their position in the file; lines
+// included from other files get no comment.
+func sourceLines(prog *ast.Program, o options) map[int]string {
+	lines := make(map[int]string)
+	texts := strings.Split(string(o.source), "\n")
+	if prog.Unnumbered {
+		for _, line := range prog.Lines {
+			if line.Pos.File == o.name && line.Pos.Line >= 1 && line.Pos.Line <= len(texts) {
+				lines[line.LineNumber] = strings.TrimSpace(texts[line.Pos.Line-1])
+			}
+		}
+		return lines
+	}
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		digits := len(text) - len(strings.TrimLeft(text, "0123456789"))
+		if n, err := strconv.Atoi(text[:digits]); err == nil {
+			lines[n] = text
+		}
+	}
+	return lines
+}
+
+// goGen holds the state of one Go translation
+type goGen struct {
+	a      *analysis
+	lines  map[int]string
+	body   bytes.Buffer
+	labels int // Counter for IF labels
+	line   int // BASIC line being generated
+}
+
+func (g *goGen) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+	g.body.WriteByte('\n')
+}
+
+// varName returns the Go identifier of a BASIC variable. BASIC names are
+// upper case, so mapping '$' to "Str" and the '.' of SUB and FUNCTION
+// locals to "Dot" cannot collide with another variable or with the
+// lower-case runtime names.
+func varName(name string) string {
+	return goNames.Replace(strings.ToUpper(name))
+}
+
+var goNames = strings.NewReplacer("$", "Str", ".", "Dot")
+
+func arrayName(name string) string {
+	return "arr" + varName(name)
+}
+
+// goType returns the Go type of a variable of type t
+func goType(t valueType) string {
+	return map[valueType]string{typeNumber: "float64", typeString: "string", typeValue: "value"}[t]
+}
+
+// declarations declares every variable, array and stack the program uses.
+// All declarations come before the first label, so no goto jumps over one.
+func (g *goGen) declarations(buf *bytes.Buffer) {
+	a := g.a
+	var unused []string
+	if len(a.names) > 0 || len(a.arrayNames) > 0 {
+		buf.WriteString("var (\n")
+		for _, name := range a.names {
+			fmt.Fprintf(buf, "%s %s\n", varName(name), goType(a.types[name]))
+			if !a.read[name] {
+				unused = append(unused, varName(name))
+			}
+		}
+		for i, name := range a.arrayNames {
+			if strings.HasSuffix(name, "$") {
+				fmt.Fprintf(buf, "%s = &array{id: %d, str: true}\n", arrayName(name), i)
+			} else {
+				fmt.Fprintf(buf, "%s = &array{id: %d}\n", arrayName(name), i)
+			}
+			if !a.used[name] {
+				unused = append(unused, arrayName(name))
+			}
+		}
+		buf.WriteString(")\n")
+	}
+	if a.frames {
+		buf.WriteString("var frames forStack\n")
+	}
+	if len(a.gosubs) > 0 && a.returns {
+		buf.WriteString("var returns []int\n")
+	}
+	if a.saves {
+		buf.WriteString("var saved []any\n")
+	}
+	if len(unused) > 0 {
+		buf.WriteString("// Assigned but never read\n")
+		fmt.Fprintf(buf, "%s = %s\n", strings.TrimSuffix(strings.Repeat("_, ", len(unused)), ", "), strings.Join(unused, ", "))
+	}
+}
+
+// returnDispatch emits the code RETURN jumps to: it pops the return point
+// pushed by GOSUB and jumps back to the statement after that GOSUB
+func (g *goGen) returnDispatch(buf *bytes.Buffer) {
+	if len(g.a.gosubs) == 0 || !g.a.returns {
+		return
+	}
+	buf.WriteString("\ndoReturn:\nswitch popReturn(&returns) {\n")
+	for i := 0; i < len(g.a.gosubs); i++ {
+		fmt.Fprintf(buf, "case %d:\ngoto ret%d\n", i, i)
+	}
+	buf.WriteString("}\npanic(\"unreachable\")\n")
+}
+
+func (g *goGen) program() {
+	for _, line := range g.a.prog.Lines {
+		g.line = line.LineNumber
+		if g.a.targets[line.LineNumber] {
+			g.printf("line%d:", line.LineNumber)
+		}
+		if text := g.lines[line.LineNumber]; text != strconv.Itoa(line.LineNumber) && text != "" {
+			g.printf("// %s", text)
+		}
+		for _, stmt := range line.Statements {
+			g.stmt(stmt)
+		}
+	}
+}
+
+func (g *goGen) stmt(stmt ast.Node) {
+	a := g.a
+	switch s := stmt.(type) {
+	case *ast.Assignment:
+		g.assign(s)
+
+	case *ast.RecordAssignment:
+		// The target indices are evaluated first, as in the VM
+		dst, src := g.record(s.Target), g.record(s.Value)
+		_, _, t := ast.RecordOf(s.Target)
+		g.printf("copyRecord(%s, %s, %d)", dst, src, t.Size())
+
+	case *ast.PrintStmt:
+		g.print(s)
+
+	case *ast.IfStmt:
+		cond := g.truth(g.expr(s.Condition))
+		if !a.flat[s] {
+			g.printf("if %s {", cond.code)
+			g.stmts(s.ThenStmts)
+			if len(s.ElseStmts) > 0 {
+				g.printf("} else {")
+				g.stmts(s.ElseStmts)
+			}
+			g.printf("}")
+			break
+		}
+		// Branches with labels stay at the top level of the function
+		g.labels++
+		id := g.labels
+		if len(s.ElseStmts) == 0 {
+			g.printf("if !%s {\ngoto endif%d\n}", paren(cond, precUnary), id)
+			g.stmts(s.ThenStmts)
+		} else {
+			g.printf("if !%s {\ngoto else%d\n}", paren(cond, precUnary), id)
+			g.stmts(s.ThenStmts)
+			g.printf("goto endif%d", id)
+			g.printf("else%d:", id)
+			g.stmts(s.ElseStmts)
+		}
+		g.printf("endif%d:", id)
+
+	case *ast.ForStmt:
+		g.forStmt(s)
+
+	case *ast.NextStmt:
+		g.nextStmt(s)
+
+	case *ast.GotoStmt:
+		g.printf("goto line%d", s.LineNumber)
+
+	case *ast.GosubStmt:
+		if !a.returns {
+			// Nothing ever returns, so no return point is needed
+			g.printf("goto line%d", s.LineNumber)
+			break
+		}
+		id := a.gosubs[s]
+		g.printf("returns = append(returns, %d)", id)
+		g.printf("goto line%d", s.LineNumber)
+		g.printf("ret%d:", id)
+
+	case *ast.ReturnStmt:
+		if len(a.gosubs) == 0 {
+			g.printf("fail(\"return without gosub\")")
+		} else {
+			g.printf("goto doReturn")
+		}
+
+	case *ast.EndStmt:
+		g.printf("return nil")
+
+	case *ast.StopStmt:
+		// A standalone program has no CONT, so STOP reports the break
+		// like the VM and then ends like END.
+		g.printf("out.WriteString(%s)\nreturn nil", strconv.Quote(fmt.Sprintf("\nBreak in line %d\n", g.line)))
+
+	case *ast.InputStmt:
+		if s.Line {
+			g.printf("lineInput(%s)", strconv.Quote(s.PromptText()))
+		} else {
+			g.printf("input(%s, %s)", strconv.Quote(s.PromptText()), strconv.Quote(interpreter.InputTypes(s.Vars)))
+		}
+		for _, v := range s.Vars {
+			g.store(v, goExpr{code: "field()", typ: typeValue, prec: precPrimary})
+		}
+
+	case *ast.DimStmt:
+		var sizes []string
+		for _, size := range s.Sizes {
+			sizes = append(sizes, g.num(g.expr(size)).code)
+		}
+		if s.Record != nil {
+			g.printf("%s.record(%s)", arrayName(s.Name), strings.Join(append([]string{strconv.Quote(s.Record.Shape())}, sizes...), ", "))
+			break
+		}
+		g.printf("%s.dim(%s)", arrayName(s.Name), strings.Join(sizes, ", "))
+
+	case *ast.SaveLocalsStmt:
+		var values []string
+		for _, name := range s.Vars {
+			values = append(values, varName(name))
+		}
+		for _, name := range s.Arrays {
+			values = append(values, "*"+arrayName(name))
+		}
+		g.printf("saved = append(saved, %s)", strings.Join(values, ", "))
+
+	case *ast.RestoreLocalsStmt:
+		for k := len(s.Saved.Arrays) - 1; k >= 0; k-- {
+			g.printf("*%s = popSaved(&saved).(array)", arrayName(s.Saved.Arrays[k]))
+		}
+		for k := len(s.Saved.Vars) - 1; k >= 0; k-- {
+			name := s.Saved.Vars[k]
+			g.printf("%s = popSaved(&saved).(%s)", varName(name), goType(a.types[name]))
+		}
+
+	case *ast.RemStmt:
+	}
+}
+
+func (g *goGen) stmts(stmts []ast.Node) {
+	for _, stmt := range stmts {
+		g.stmt(stmt)
+	}
+}
+
+func (g *goGen) assign(s *ast.Assignment) {
+	value := g.expr(s.Value)
+	if target, ok := s.Target.(*ast.Identifier); ok && g.a.types[strings.ToUpper(target.Name)] == typeNumber {
+		// X = X + Y becomes X += Y, and X = X + 1 becomes X++
+		name := varName(target.Name)
+		if bin, ok := s.Value.(*ast.BinaryOp); ok && (bin.Op == "+" || bin.Op == "-" || bin.Op == "*") {
+			if left, ok := bin.Left.(*ast.Identifier); ok && varName(left.Name) == name {
+				right := g.num(g.expr(bin.Right))
+				switch {
+				case bin.Op != "*" && right.constant && right.value == 1:
+					g.printf("%s%s%s", name, bin.Op, bin.Op)
+				default:
+					g.printf("%s %s= %s", name, bin.Op, right.code)
+				}
+				return
+			}
+		}
+	}
+	g.store(s.Target, value)
+}
+
+// store assigns value to a variable or array element
+func (g *goGen) store(target ast.Node, value goExpr) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		name := varName(target.Name)
+		switch g.a.types[strings.ToUpper(target.Name)] {
+		case typeNumber:
+			g.printf("%s = %s", name, g.num(value).code)
+		case typeString:
+			g.printf("%s = %s", name, g.str(value).code)
+		default:
+			g.printf("%s = %s", name, g.val(value).code)
+		}
+	case *ast.ArrayAccess:
+		// Indices are evaluated before the value, as in the VM
+		arr := arrayName(target.Name)
+		idx := g.indices(target.Indices)
+		index := "index"
+		if len(target.Indices) == 1 {
+			index = "index1"
+		}
+		if strings.HasSuffix(target.Name, "$") {
+			g.printf("%s.strs[%s.%s(%s)] = %s", arr, arr, index, idx, g.str(value).code)
+		} else {
+			g.printf("%s.data[%s.%s(%s)] = %s", arr, arr, index, idx, g.num(value).code)
+		}
+	case *ast.FieldAccess:
+		arr, index := g.field(target)
+		if f, _ := target.Info(); f.String {
+			g.printf("%s.strs[%s] = %s", arr, index, g.str(value).code)
+		} else {
+			g.printf("%s.data[%s] = %s", arr, index, g.num(value).code)
+		}
+	}
+}
+
+// field returns the array holding a record field and the index of its
+// slot: the record's indices followed by the slot
+func (g *goGen) field(f *ast.FieldAccess) (arr, index string) {
+	ref, slot := f.Slot()
+	return g.slot(ref, slot)
+}
+
+func (g *goGen) slot(ref *ast.RecordRef, slot int) (arr, index string) {
+	arr = arrayName(ref.Name)
+	if len(ref.Indices) == 0 {
+		return arr, fmt.Sprintf("%s.index1(%d)", arr, slot)
+	}
+	return arr, fmt.Sprintf("%s.index(%s, %d)", arr, g.indices(ref.Indices), slot)
+}
+
+// record returns the array and first slot of a whole record as arguments
+// of copyRecord
+func (g *goGen) record(n ast.Node) string {
+	ref, slot, _ := ast.RecordOf(n)
+	arr, index := g.slot(ref, slot)
+	return arr + ", " + index
+}
+
+func (g *goGen) indices(nodes []ast.Node) string {
+	var idx []string
+	for _, n := range nodes {
+		idx = append(idx, g.num(g.expr(n)).code)
+	}
+	return strings.Join(idx, ", ")
+}
+
+func (g *goGen) print(s *ast.PrintStmt) {
+	// Adjacent literal text is written in one call; everything else is
+	// written as soon as it is evaluated, so output before a runtime error
+	// matches the VM's
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			g.printf("out.WriteString(%s)", strconv.Quote(text.String()))
+			text.Reset()
+		}
+	}
+	for i, v := range s.Values {
+		e := g.expr(v)
+		switch lit, ok := v.(*ast.StringLiteral); {
+		case ok:
+			text.WriteString(lit.Value)
+		case e.constant:
+			text.WriteString(strconv.FormatFloat(e.value, 'g', -1, 64))
+		default:
+			flush()
+			g.printf("out.WriteString(%s)", g.str(e).code)
+		}
+		sep := ""
+		if i < len(s.Separators) {
+			sep = s.Separators[i]
+		} else if i == len(s.Values)-1 {
+			sep = s.Trailer
+		}
+		if sep == "," {
+			text.WriteString(" ")
+		}
+	}
+	if s.Trailer == "" {
+		text.WriteString("\n")
+	}
+	flush()
+}
+
+// forStmt emits a FOR. A native loop opens a Go for statement that the
+// matching NEXT closes; the body must run once before the bounds are
+// checked, so the check is at the bottom.
+func (g *goGen) forStmt(s *ast.ForStmt) {
+	l := g.a.loops[s]
+	name := strings.ToUpper(s.Var)
+	start := g.expr(s.Start)
+	switch g.a.types[name] {
+	case typeNumber:
+		g.printf("%s = %s", varName(name), g.num(start).code)
+	default:
+		g.printf("%s = %s", varName(name), g.val(start).code)
+	}
+	end, step := g.num(g.expr(s.End)), g.num(g.expr(s.Step))
+
+	if !l.native {
+		g.printf("frames.push(%d, %s, %s)", l.varIdx, end.code, step.code)
+		if l.next != nil {
+			g.printf("for%d:", l.id)
+		}
+		return
+	}
+
+	var init []string
+	var vars []string
+	if !end.constant {
+		vars = append(vars, fmt.Sprintf("end%d", l.id))
+		init = append(init, end.code)
+	}
+	if !step.constant {
+		vars = append(vars, fmt.Sprintf("step%d", l.id))
+		init = append(init, step.code)
+	}
+	if len(vars) == 0 {
+		g.printf("for {")
+	} else {
+		g.printf("for %s := %s; ; {", strings.Join(vars, ", "), strings.Join(init, ", "))
+	}
+}
+
+// nextStmt increments the loop variable and loops again while it is
+// within the bounds
+func (g *goGen) nextStmt(s *ast.NextStmt) {
+	l := g.a.nexts[s]
+	name := g.a.names[l.varIdx]
+	v := varName(name)
+	numeric := g.a.types[name] == typeNumber
+
+	if !l.native {
+		if numeric {
+			g.printf("%s += frames.step(%d)", v, l.varIdx)
+			g.printf("if frames.again(%s) {\ngoto for%d\n}", v, l.id)
+		} else {
+			g.printf("%s = numberValue(%s.num() + frames.step(%d))", v, v, l.varIdx)
+			g.printf("if frames.again(%s.num()) {\ngoto for%d\n}", v, l.id)
+		}
+		return
+	}
+
+	end, step := g.num(g.expr(l.stmt.End)), g.num(g.expr(l.stmt.Step))
+	endCode, stepCode := fmt.Sprintf("end%d", l.id), fmt.Sprintf("step%d", l.id)
+	if end.constant {
+		endCode = end.code
+	}
+	if step.constant {
+		stepCode = step.code
+	}
+
+	x := v
+	if numeric {
+		switch {
+		case step.constant && step.value == 1:
+			g.printf("%s++", v)
+		case step.constant && step.value == -1:
+			g.printf("%s--", v)
+		default:
+			g.printf("%s += %s", v, stepCode)
+		}
+	} else {
+		g.printf("%s = numberValue(%s.num() + %s)", v, v, stepCode)
+		x = v + ".num()"
+	}
+
+	var again string
+	switch {
+	case !step.constant:
+		again = fmt.Sprintf("%s > 0 && %s <= %s || %s < 0 && %s >= %s", stepCode, x, endCode, stepCode, x, endCode)
+	case step.value > 0:
+		again = fmt.Sprintf("%s <= %s", x, endCode)
+	case step.value < 0:
+		again = fmt.Sprintf("%s >= %s", x, endCode)
+	default:
+		again = "false"
+	}
+	g.printf("if !(%s) {\nbreak\n}", again)
+	g.printf("}")
+}
+
+// Operator precedence of generated Go expressions, for parenthesization
+const (
+	precOr = iota + 1
+	precAnd
+	precCompare
+	precAdd
+	precMul
+	precUnary
+	precPrimary
+)
+
+// goExpr is a translated expression
+type goExpr struct {
+	code     string
+	typ      valueType // typeNumber, typeString, typeValue or typeBool
+	prec     int       // Precedence of the outermost operator in code
+	pure     bool      // Evaluating it cannot fail and has no effects
+	constant bool      // A numeric constant with value
+	value    float64
+}
+
+// typeBool is a Go bool: the result of a comparison or logical operator,
+// which BASIC treats as the number 1 or 0
+const typeBool valueType = -1
+
+func paren(e goExpr, prec int) string {
+	if e.prec < prec {
+		return "(" + e.code + ")"
+	}
+	return e.code
+}
+
+// number returns a numeric constant expression. Constants are folded here
+// with float64 arithmetic, because Go evaluates constant expressions
+// exactly and could print a different result than the VM.
+func number(v float64) goExpr {
+	e := goExpr{typ: typeNumber, prec: precPrimary, pure: true, constant: true, value: v}
+	switch {
+	case math.IsInf(v, 1):
+		e.code = "math.Inf(1)"
+	case math.IsInf(v, -1):
+		e.code = "math.Inf(-1)"
+	case math.IsNaN(v):
+		e.code = "math.NaN()"
+	case v == 0 && math.Signbit(v):
+		e.code = "math.Copysign(0, -1)"
+	default:
+		e.code = strconv.FormatFloat(v, 'g', -1, 64)
+		if v < 0 {
+			e.prec = precUnary
+		}
+	}
+	return e
+}
+
+// num converts e to a float64 expression
+func (g *goGen) num(e goExpr) goExpr {
+	switch e.typ {
+	case typeNumber:
+		return e
+	case typeBool:
+		return goExpr{code: "b2f(" + e.code + ")", typ: typeNumber, prec: precPrimary, pure: e.pure}
+	case typeString:
+		return goExpr{code: "toNumber(" + e.code + ")", typ: typeNumber, prec: precPrimary, pure: e.pure}
+	}
+	return goExpr{code: paren(e, precPrimary) + ".num()", typ: typeNumber, prec: precPrimary, pure: e.pure}
+}
+
+// str converts e to a string expression
+func (g *goGen) str(e goExpr) goExpr {
+	switch e.typ {
+	case typeString:
+		return e
+	case typeValue:
+		return goExpr{code: paren(e, precPrimary) + ".String()", typ: typeString, prec: precPrimary, pure: e.pure}
+	}
+	if n := g.num(e); n.constant {
+		return goExpr{code: strconv.Quote(strconv.FormatFloat(n.value, 'g', -1, 64)), typ: typeString, prec: precPrimary, pure: true}
+	}
+	return goExpr{code: "fmtNum(" + g.num(e).code + ")", typ: typeString, prec: precPrimary, pure: e.pure}
+}
+
+// val converts e to a dynamic value
+func (g *goGen) val(e goExpr) goExpr {
+	switch e.typ {
+	case typeValue:
+		return e
+	case typeString:
+		return goExpr{code: "stringValue(" + e.code + ")", typ: typeValue, prec: precPrimary, pure: e.pure}
+	}
+	return goExpr{code: "numberValue(" + g.num(e).code + ")", typ: typeValue, prec: precPrimary, pure: e.pure}
+}
+
+// truth converts e to a bool expression: non-zero numbers and non-empty
+// strings are true
+func (g *goGen) truth(e goExpr) goExpr {
+	b := goExpr{typ: typeBool, prec: precCompare, pure: e.pure}
+	switch e.typ {
+	case typeBool:
+		return e
+	case typeNumber:
+		b.code = paren(e, precAdd) + " != 0"
+	case typeString:
+		b.code = paren(e, precAdd) + ` != ""`
+	default:
+		b.code = paren(e, precPrimary) + ".isTrue()"
+		b.prec = precPrimary
+	}
+	return b
+}
+
+var goCompareOps = map[string]string{"=": "==", "<>": "!=", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+func (g *goGen) expr(e ast.Node) goExpr {
+	switch n := e.(type) {
+	case *ast.Number:
+		return number(n.Value)
+
+	case *ast.StringLiteral:
+		return goExpr{code: strconv.Quote(n.Value), typ: typeString, prec: precPrimary, pure: true}
+
+	case *ast.Identifier:
+		name := strings.ToUpper(n.Name)
+		return goExpr{code: varName(name), typ: g.a.types[name], prec: precPrimary, pure: true}
+
+	case *ast.ArrayAccess:
+		arr := arrayName(n.Name)
+		index := "index"
+		if len(n.Indices) == 1 {
+			index = "index1"
+		}
+		if strings.HasSuffix(n.Name, "$") {
+			code := fmt.Sprintf("%s.strs[%s.%s(%s)]", arr, arr, index, g.indices(n.Indices))
+			return goExpr{code: code, typ: typeString, prec: precPrimary}
+		}
+		code := fmt.Sprintf("%s.data[%s.%s(%s)]", arr, arr, index, g.indices(n.Indices))
+		return goExpr{code: code, typ: typeNumber, prec: precPrimary}
+
+	case *ast.FieldAccess:
+		arr, index := g.field(n)
+		if f, _ := n.Info(); f.String {
+			return goExpr{code: fmt.Sprintf("%s.strs[%s]", arr, index), typ: typeString, prec: precPrimary}
+		}
+		return goExpr{code: fmt.Sprintf("%s.data[%s]", arr, index), typ: typeNumber, prec: precPrimary}
+
+	case *ast.FunctionCall:
+		return g.call(n)
+
+	case *ast.BinaryOp:
+		left, right := g.expr(n.Left), g.expr(n.Right)
+		pure := left.pure && right.pure
+		if n.Op == "+" {
+			switch {
+			case left.typ == typeString || right.typ == typeString:
+				l, r := g.str(left), g.str(right)
+				return goExpr{code: paren(l, precAdd) + " + " + paren(r, precMul), typ: typeString, prec: precAdd, pure: pure}
+			case left.typ == typeValue || right.typ == typeValue:
+				return goExpr{code: "add(" + g.val(left).code + ", " + g.val(right).code + ")", typ: typeValue, prec: precPrimary, pure: pure}
+			}
+		}
+		l, r := g.num(left), g.num(right)
+		if l.constant && r.constant {
+			switch n.Op {
+			case "+":
+				return number(l.value + r.value)
+			case "-":
+				return number(l.value - r.value)
+			case "*":
+				return number(l.value * r.value)
+			case "/":
+				if r.value != 0 {
+					return number(l.value / r.value)
+				}
+			}
+		}
+		switch n.Op {
+		case "+", "-":
+			return goExpr{code: paren(l, precAdd) + " " + n.Op + " " + paren(r, precMul), typ: typeNumber, prec: precAdd, pure: pure}
+		case "*":
+			return goExpr{code: paren(l, precMul) + " * " + paren(r, precUnary), typ: typeNumber, prec: precMul, pure: pure}
+		case "/":
+			return goExpr{code: "div(" + l.code + ", " + r.code + ")", typ: typeNumber, prec: precPrimary}
+		case "^":
+			return goExpr{code: "math.Pow(" + l.code + ", " + r.code + ")", typ: typeNumber, prec: precPrimary, pure: pure}
+		}
+		return goExpr{code: "math.Mod(" + l.code + ", " + r.code + ")", typ: typeNumber, prec: precPrimary, pure: pure}
+
+	case *ast.ComparisonOp:
+		left, right := g.expr(n.Left), g.expr(n.Right)
+		pure := left.pure && right.pure
+		op := goCompareOps[n.Op]
+		switch {
+		case left.typ == typeString || right.typ == typeString:
+			left, right = g.str(left), g.str(right)
+		case left.typ == typeValue || right.typ == typeValue:
+			code := fmt.Sprintf("compare(%s, %q, %s)", g.val(left).code, n.Op, g.val(right).code)
+			return goExpr{code: code, typ: typeBool, prec: precPrimary, pure: pure}
+		default:
+			left, right = g.num(left), g.num(right)
+		}
+		code := paren(left, precAdd) + " " + op + " " + paren(right, precAdd)
+		return goExpr{code: code, typ: typeBool, prec: precCompare, pure: pure}
+
+	case *ast.LogicalOp:
+		left, right := g.truth(g.expr(n.Left)), g.truth(g.expr(n.Right))
+		pure := left.pure && right.pure
+		if !right.pure {
+			// Both operands are always evaluated, so a failing right
+			// operand must not be skipped
+			fn := map[string]string{"AND": "and", "OR": "or"}[n.Op]
+			return goExpr{code: fn + "(" + left.code + ", " + right.code + ")", typ: typeBool, prec: precPrimary}
+		}
+		if n.Op == "AND" {
+			return goExpr{code: paren(left, precAnd) + " && " + paren(right, precCompare), typ: typeBool, prec: precAnd, pure: pure}
+		}
+		return goExpr{code: paren(left, precOr) + " || " + paren(right, precAnd), typ: typeBool, prec: precOr, pure: pure}
+
+	case *ast.UnaryOp:
+		operand := g.expr(n.Right)
+		switch n.Op {
+		case "-":
+			switch {
+			case operand.constant:
+				return number(-operand.value)
+			case operand.typ == typeNumber || operand.typ == typeBool:
+				code := paren(g.num(operand), precUnary)
+				if strings.HasPrefix(code, "-") {
+					code = "(" + code + ")" // Not the -- operator
+				}
+				return goExpr{code: "-" + code, typ: typeNumber, prec: precUnary, pure: operand.pure}
+			}
+			return goExpr{code: "neg(" + g.val(operand).code + ")", typ: typeNumber, prec: precPrimary}
+		case "NOT":
+			b := g.truth(operand)
+			return goExpr{code: "!" + paren(b, precUnary), typ: typeBool, prec: precUnary, pure: b.pure}
+		}
+		return operand
+	}
+	panic(fmt.Sprintf("codegen: unexpected expression %T", e))
+}
+
+// call translates a builtin function call
+func (g *goGen) call(n *ast.FunctionCall) goExpr {
+	name := strings.ToUpper(n.Name)
+	args := make([]goExpr, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = g.expr(arg)
+	}
+	if min, max := builtinArity(name); len(args) < min || len(args) > max {
+		code := strconv.Quote(arityError(name))
+		for _, arg := range args {
+			code += ", " + g.val(arg).code
+		}
+		return goExpr{code: "badCall(" + code + ")", typ: typeValue, prec: precPrimary}
+	}
+
+	numArg := func(i int) string { return g.num(args[i]).code }
+	strArg := func(i int) string { return g.str(args[i]).code }
+	pure := true
+	for _, arg := range args {
+		pure = pure && arg.pure
+	}
+	number := func(code string, pure bool) goExpr {
+		return goExpr{code: code, typ: typeNumber, prec: precPrimary, pure: pure}
+	}
+	text := func(code string, pure bool) goExpr {
+		return goExpr{code: code, typ: typeString, prec: precPrimary, pure: pure}
+	}
+
+	switch name {
+	case "ABS", "SIN", "COS", "TAN", "EXP":
+		fn := name[:1] + strings.ToLower(name[1:])
+		return number("math."+fn+"("+numArg(0)+")", pure)
+	case "INT":
+		return number("math.Trunc("+numArg(0)+")", pure)
+	case "SQR":
+		return number("sqr("+numArg(0)+")", false)
+	case "LOG":
+		return number("logn("+numArg(0)+")", false)
+	case "RND":
+		return number("rnd()", false)
+	case "LEN":
+		return number("float64(len("+strArg(0)+"))", pure)
+	case "LEFT$":
+		return text("left("+strArg(0)+", "+numArg(1)+")", pure)
+	case "RIGHT$":
+		return text("right("+strArg(0)+", "+numArg(1)+")", pure)
+	case "MID$":
+		if len(args) == 3 {
+			return text("mid("+strArg(0)+", "+numArg(1)+", "+numArg(2)+")", false)
+		}
+		return text("mid("+strArg(0)+", "+numArg(1)+")", pure)
+	case "INSTR":
+		if len(args) == 3 {
+			return number("instr("+numArg(0)+", "+strArg(1)+", "+strArg(2)+")", pure)
+		}
+		return number("instr(1, "+strArg(0)+", "+strArg(1)+")", pure)
+	case "UCASE$":
+		return text("strings.ToUpper("+strArg(0)+")", pure)
+	case "LCASE$":
+		return text("strings.ToLower("+strArg(0)+")", pure)
+	case "SPACE$":
+		return text("space("+numArg(0)+")", pure)
+	case "CHR$":
+		return text("chr("+numArg(0)+")", false)
+	case "ASC":
+		return number("asc("+strArg(0)+")", false)
+	case "PI":
+		return goExpr{code: "math.Pi", typ: typeNumber, prec: precPrimary, pure: true, constant: true, value: math.Pi}
+	}
+	// EULER
+	return goExpr{code: "math.E", typ: typeNumber, prec: precPrimary, pure: true, constant: true, value: math.E}
+}
@@ -0,0 +1,466 @@
+package codegen
+
+// goRuntime is appended to every generated Go program. It mirrors the
+// VM's value semantics (internal/vm/value.go), its arrays and FOR stack
+// and the builtins in internal/vm/builtins.go, so that a transpiled
+// program prints exactly what the VM prints and fails with the same
+// runtime errors.
+const goRuntime = `
+// ---- BASIC runtime ----
+
+var (
+	out = bufio.NewWriter(os.Stdout)
+	in  = bufio.NewReader(os.Stdin)
+)
+
+func main() {
+	err := run()
+	out.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runtimeError is a BASIC runtime error. fail panics with one and run
+// recovers it, so expressions that can fail stay expressions.
+type runtimeError string
+
+func (e runtimeError) Error() string { return string(e) }
+
+func fail(format string, args ...any) {
+	panic(runtimeError(fmt.Sprintf(format, args...)))
+}
+
+// catch turns a runtimeError panic into run's error result
+func catch(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(runtimeError)
+		if !ok {
+			panic(r)
+		}
+		*err = e
+	}
+}
+
+// value is a variable whose type is only known at run time
+type value struct {
+	kind byte // kindEmpty, kindNumber or kindString
+	n    float64
+	s    string
+}
+
+const (
+	kindEmpty byte = iota
+	kindNumber
+	kindString
+)
+
+func numberValue(n float64) value { return value{kind: kindNumber, n: n} }
+func stringValue(s string) value  { return value{kind: kindString, s: s} }
+
+// num converts v to a number, parsing strings and treating empty as 0
+func (v value) num() float64 {
+	switch v.kind {
+	case kindNumber:
+		return v.n
+	case kindString:
+		return toNumber(v.s)
+	}
+	return 0
+}
+
+func (v value) String() string {
+	switch v.kind {
+	case kindNumber:
+		return fmtNum(v.n)
+	case kindString:
+		return v.s
+	}
+	return ""
+}
+
+func (v value) isTrue() bool {
+	switch v.kind {
+	case kindNumber:
+		return v.n != 0
+	case kindString:
+		return v.s != ""
+	}
+	return false
+}
+
+func toNumber(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func fmtNum(n float64) string { return strconv.FormatFloat(n, 'g', -1, 64) }
+
+func b2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// and and or evaluate both operands, as the VM does
+func and(a, b bool) bool { return a && b }
+func or(a, b bool) bool  { return a || b }
+
+// add adds two numbers and concatenates anything involving a string
+func add(a, b value) value {
+	switch {
+	case a.kind == kindNumber && b.kind == kindNumber:
+		return numberValue(a.n + b.n)
+	case a.kind == kindString || b.kind == kindString:
+		return stringValue(a.String() + b.String())
+	}
+	return numberValue(a.num() + b.num())
+}
+
+// compare compares as strings if either side is a string and as numbers
+// otherwise
+func compare(a value, op string, b value) bool {
+	if a.kind == kindString || b.kind == kindString {
+		x, y := a.String(), b.String()
+		switch op {
+		case "=":
+			return x == y
+		case "<>":
+			return x != y
+		case "<":
+			return x < y
+		case "<=":
+			return x <= y
+		case ">":
+			return x > y
+		}
+		return x >= y
+	}
+	x, y := a.num(), b.num()
+	switch op {
+	case "=":
+		return x == y
+	case "<>":
+		return x != y
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	}
+	return x >= y
+}
+
+func neg(v value) float64 {
+	if v.kind != kindNumber {
+		fail("operand must be a number")
+	}
+	return -v.n
+}
+
+func div(a, b float64) float64 {
+	if b == 0 {
+		fail("division by zero")
+	}
+	return a / b
+}
+
+// fields holds the INPUT fields not yet stored
+var fields []value
+
+// inputNumber is the syntax of a numeric INPUT field
+var inputNumber = regexp.MustCompile(` + "`" + `^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$` + "`" + `)
+
+// input prompts until a line of comma-separated fields matches types, one
+// '#' (number) or '$' (string) per variable
+func input(prompt, types string) {
+	for {
+		if values, ok := parseInput(readLine(prompt), types); ok {
+			fields = values
+			return
+		}
+		out.WriteString("?Redo from start\n")
+	}
+}
+
+// lineInput reads a whole line as one string field (LINE INPUT)
+func lineInput(prompt string) {
+	fields = []value{stringValue(readLine(prompt))}
+}
+
+// field returns the next INPUT field
+func field() value {
+	v := fields[0]
+	fields = fields[1:]
+	return v
+}
+
+func readLine(prompt string) string {
+	out.WriteString(prompt)
+	out.Flush()
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		fail("Input past end")
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+// parseInput splits line into comma-separated fields like GW-BASIC. Quoted
+// fields keep commas and spaces; other fields are trimmed, and an empty
+// numeric field is 0.
+func parseInput(line, types string) ([]value, bool) {
+	values := make([]value, 0, len(types))
+	rest := line
+	for i := 0; i < len(types); i++ {
+		field := strings.TrimLeft(rest, " \t")
+		quoted := strings.HasPrefix(field, ` + "`" + `"` + "`" + `)
+		if quoted {
+			field = field[1:]
+			end := strings.IndexByte(field, '"')
+			if end < 0 {
+				end = len(field)
+				rest = ""
+			} else {
+				rest = strings.TrimLeft(field[end+1:], " \t")
+				if rest != "" && rest[0] != ',' {
+					return nil, false
+				}
+			}
+			field = field[:end]
+		} else if end := strings.IndexByte(field, ','); end >= 0 {
+			field, rest = strings.TrimRight(field[:end], " \t"), field[end:]
+		} else {
+			field, rest = strings.TrimRight(field, " \t"), ""
+		}
+
+		switch {
+		case types[i] == '$':
+			values = append(values, stringValue(field))
+		case quoted:
+			return nil, false
+		case field == "":
+			values = append(values, numberValue(0))
+		default:
+			n, err := strconv.ParseFloat(field, 64)
+			if !inputNumber.MatchString(field) || err != nil {
+				return nil, false
+			}
+			values = append(values, numberValue(n))
+		}
+		if i < len(types)-1 {
+			if !strings.HasPrefix(rest, ",") {
+				return nil, false
+			}
+			rest = rest[1:]
+		}
+	}
+	return values, rest == ""
+}
+
+// array is a DIM array; dims is nil until the DIM runs. A string array
+// keeps its elements in strs instead of data. A TYPE record variable or
+// record array has one more dimension for the slots of a record and uses
+// both: numeric fields in data and string fields in strs.
+type array struct {
+	id   int
+	str  bool
+	dims []int
+	data []float64
+	strs []string
+}
+
+func (a *array) dim(sizes ...float64) {
+	dims := make([]int, len(sizes))
+	for i := len(sizes) - 1; i >= 0; i-- {
+		d := int(sizes[i])
+		if d < 0 {
+			fail("negative array dimension: %d", d)
+		}
+		dims[i] = d
+	}
+	total := 1
+	for _, d := range dims {
+		total *= d
+	}
+	a.dims = dims
+	if a.str {
+		a.strs = make([]string, total)
+	} else {
+		a.data = make([]float64, total)
+	}
+}
+
+// record makes a a record variable or record array; shape holds '#' or
+// '$' for each slot of a record
+func (a *array) record(shape string, sizes ...float64) {
+	a.dim(append(sizes, float64(len(shape)))...)
+	a.strs = make([]string, len(a.data))
+}
+
+// copyRecord copies the n slots of a record of src starting at from to dst
+// starting at to (LET A = B for records)
+func copyRecord(dst *array, to int, src *array, from, n int) {
+	copy(dst.data[to:to+n], src.data[from:from+n])
+	copy(dst.strs[to:to+n], src.strs[from:from+n])
+}
+
+func (a *array) index(idx ...float64) int {
+	if a.dims == nil {
+		fail("array not declared (index %d)", a.id)
+	}
+	if len(idx) != len(a.dims) {
+		fail("array index out of bounds")
+	}
+	flat, mul := 0, 1
+	for i := len(idx) - 1; i >= 0; i-- {
+		n := int(idx[i])
+		if n < 0 || n >= a.dims[i] {
+			fail("array index out of bounds")
+		}
+		flat += n * mul
+		mul *= a.dims[i]
+	}
+	return flat
+}
+
+func (a *array) index1(i float64) int {
+	if a.dims == nil {
+		fail("array not declared (index %d)", a.id)
+	}
+	n := int(i)
+	if len(a.dims) != 1 || n < 0 || n >= a.dims[0] {
+		fail("array index out of bounds")
+	}
+	return n
+}
+
+// forFrame is an active FOR loop that is not a native Go loop
+type forFrame struct {
+	v         int // Loop variable
+	end, step float64
+}
+
+type forStack []forFrame
+
+func (s *forStack) push(v int, end, step float64) {
+	*s = append(*s, forFrame{v, end, step})
+}
+
+// step checks that the innermost loop belongs to variable v and returns
+// its step
+func (s forStack) step(v int) float64 {
+	if len(s) == 0 {
+		fail("NEXT without FOR")
+	}
+	if s[len(s)-1].v != v {
+		fail("NEXT variable mismatch")
+	}
+	return s[len(s)-1].step
+}
+
+// again reports whether the innermost loop runs again with the loop
+// variable at x, and pops the loop when it does not
+func (s *forStack) again(x float64) bool {
+	f := (*s)[len(*s)-1]
+	if f.step > 0 && x <= f.end || f.step < 0 && x >= f.end {
+		return true
+	}
+	*s = (*s)[:len(*s)-1]
+	return false
+}
+
+func popReturn(s *[]int) int {
+	if len(*s) == 0 {
+		fail("return without gosub")
+	}
+	r := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return r
+}
+
+// popSaved pops a variable or array saved around a recursive call
+func popSaved(s *[]any) any {
+	v := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return v
+}
+
+func sqr(x float64) float64 {
+	if x < 0 {
+		fail("SQR of negative number")
+	}
+	return math.Sqrt(x)
+}
+
+func logn(x float64) float64 {
+	if x <= 0 {
+		fail("LOG of non-positive number")
+	}
+	return math.Log(x)
+}
+
+func left(s string, n float64) string {
+	k := min(max(int(n), 0), len(s))
+	return s[:k]
+}
+
+func right(s string, n float64) string {
+	k := min(max(int(n), 0), len(s))
+	return s[len(s)-k:]
+}
+
+func mid(s string, start float64, n ...float64) string {
+	first := max(int(start), 1)
+	count := len(s) - first + 1
+	if len(n) > 0 {
+		count = int(n[0])
+	}
+	from, to := first-1, min(first-1+count, len(s))
+	if from >= len(s) || from < 0 {
+		return ""
+	}
+	return s[from:to]
+}
+
+func instr(start float64, s, sub string) float64 {
+	first := max(int(start), 1)
+	if first > len(s) {
+		return 0
+	}
+	pos := strings.Index(s[first-1:], sub)
+	if pos == -1 {
+		return 0
+	}
+	return float64(first + pos)
+}
+
+func space(n float64) string { return strings.Repeat(" ", max(int(n), 0)) }
+
+func chr(n float64) string {
+	code := int(n)
+	if code < 0 || code > 255 {
+		fail("CHR$ argument must be between 0 and 255")
+	}
+	return string(rune(code))
+}
+
+func asc(s string) float64 {
+	if s == "" {
+		fail("ASC argument is an empty string")
+	}
+	return float64(s[0])
+}
+
+func rnd() float64 { return rand.Float64() }
+
+// badCall evaluates the arguments of a builtin called with the wrong
+// number of them, then fails
+func badCall(msg string, args ...value) value {
+	fail("%s", msg)
+	return value{}
+}
+`
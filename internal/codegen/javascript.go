@@ -0,0 +1,862 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/interpreter"
+)
+
+// JS translates prog into an ES module for browsers and other JavaScript
+// engines. The module exports BasicError and
+//
+//	async function run(io)
+//
+// which passes the program's output to io.print(text) as it is printed
+// and awaits io.input() for every INPUT variable, so a page can feed it
+// from a form. The promise rejects with a BasicError on a runtime error.
+//
+// JavaScript has no goto, so a program that jumps becomes a line-dispatch
+// state machine: a switch on pc inside a loop, with a case for every line
+// that GOTO or GOSUB targets. A jump sets pc and continues the loop, and
+// execution falls through from one case into the next. GOSUB pushes the
+// case to resume at and RETURN pops it. Loops, IFs and variable types
+// follow the same analysis as the Go translation. The math functions are
+// the JavaScript engine's and may differ from Go's in the last bit.
+func JS(prog *ast.Program, opts ...Option) ([]byte, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	a, err := analyze(prog)
+	if err != nil {
+		return nil, err
+	}
+	g := &jsGen{a: a, lines: sourceLines(prog, o), loopTop: make(map[*loop]int)}
+
+	var buf bytes.Buffer
+	if o.name != "" {
+		fmt.Fprintf(&buf, "// Code generated by zb build from %s. DO NOT EDIT.\n\n", o.name)
+	} else {
+		buf.WriteString("// Code generated by zb build. DO NOT EDIT.\n\n")
+	}
+	buf.WriteString("// run executes the BASIC program, printing through io.print(text) and\n")
+	buf.WriteString("// awaiting io.input() for each INPUT variable\n")
+	buf.WriteString("export async function run(io = {}) {\n")
+	g.depth = 1
+	g.declarations()
+	g.printf("const term = new Terminal(io);")
+	g.printf("try {")
+	if g.dispatch() {
+		g.printf("let pc = %d;", prog.Lines[0].LineNumber)
+		g.printf("dispatch: for (;;) {")
+		g.printf("switch (pc) {")
+		g.depth++ // Cases are indented inside the switch
+		g.program(true)
+		g.depth--
+		g.printf("}")
+		g.printf("return;")
+		g.printf("}")
+	} else {
+		g.program(false)
+	}
+	g.printf("} finally {")
+	g.printf("term.flush();")
+	g.printf("}")
+	buf.Write(g.body.Bytes())
+	buf.WriteString("}\n")
+	buf.WriteString(jsRuntime)
+	return buf.Bytes(), nil
+}
+
+// jsGen holds the state of one JavaScript translation
+type jsGen struct {
+	a       *analysis
+	lines   map[int]string
+	body    bytes.Buffer
+	depth   int           // Indentation level
+	points  int           // Dispatch points allocated so far
+	loopTop map[*loop]int // Dispatch point at the top of each run-time loop
+	line    int           // BASIC line being generated
+}
+
+// printf writes one or more lines of code, indenting the lines between
+// an opening and a closing brace. Case labels sit one level out.
+func (g *jsGen) printf(format string, args ...any) {
+	for _, line := range strings.Split(fmt.Sprintf(format, args...), "\n") {
+		if strings.HasPrefix(line, "//") {
+			g.body.WriteString(strings.Repeat("  ", g.depth) + line + "\n")
+			continue
+		}
+		if strings.HasPrefix(line, "}") {
+			g.depth--
+		}
+		indent := g.depth
+		if strings.HasPrefix(line, "case ") {
+			indent--
+		}
+		g.body.WriteString(strings.Repeat("  ", indent))
+		g.body.WriteString(line)
+		g.body.WriteByte('\n')
+		if strings.HasSuffix(line, "{") {
+			g.depth++
+		}
+	}
+}
+
+// dispatch reports whether the program jumps, so that it needs the
+// line-dispatch loop; straight-line programs run as plain code
+func (g *jsGen) dispatch() bool {
+	if len(g.a.targets) > 0 || g.a.frames {
+		return true
+	}
+	for _, flat := range g.a.flat {
+		if flat {
+			return true
+		}
+	}
+	return false
+}
+
+// point allocates a case for a place that is not the start of a line.
+// Lines use their line numbers and other places negative numbers.
+func (g *jsGen) point() int {
+	g.points++
+	return -g.points
+}
+
+func (g *jsGen) jump(pc int) {
+	g.printf("pc = %d;\ncontinue dispatch;", pc)
+}
+
+// declarations declares every variable, array and stack the program uses
+func (g *jsGen) declarations() {
+	a := g.a
+	for _, name := range a.names {
+		switch a.types[name] {
+		case typeNumber:
+			g.printf("let %s = 0;", jsName(name))
+		case typeString:
+			g.printf("let %s = \"\";", jsName(name))
+		default:
+			g.printf("let %s;", jsName(name))
+		}
+	}
+	for i, name := range a.arrayNames {
+		g.printf("const %s = new BasicArray(%d, %t);", jsArrayName(name), i, strings.HasSuffix(name, "$"))
+	}
+	if a.frames {
+		g.printf("const frames = new ForStack();")
+	}
+	if len(a.gosubs) > 0 && a.returns {
+		g.printf("const returns = [];")
+	}
+	if a.saves {
+		g.printf("const saved = [];")
+	}
+}
+
+// jsName returns the JavaScript identifier of a BASIC variable. BASIC
+// names are valid JavaScript identifiers as they are, since '$' is allowed
+// and upper-case names cannot collide with the runtime; only the '.' of
+// SUB and FUNCTION locals becomes "Dot".
+func jsName(name string) string {
+	return strings.ReplaceAll(strings.ToUpper(name), ".", "Dot")
+}
+
+// jsArrayName returns the JavaScript identifier of a BASIC array
+func jsArrayName(name string) string {
+	return "arr" + jsName(name)
+}
+
+func (g *jsGen) program(dispatch bool) {
+	for i, line := range g.a.prog.Lines {
+		g.line = line.LineNumber
+		if g.a.targets[line.LineNumber] || dispatch && i == 0 {
+			g.printf("case %d:", line.LineNumber)
+		}
+		if text := g.lines[line.LineNumber]; text != strconv.Itoa(line.LineNumber) && text != "" {
+			g.printf("// %s", text)
+		}
+		for _, stmt := range line.Statements {
+			g.stmt(stmt)
+		}
+	}
+}
+
+func (g *jsGen) stmt(stmt ast.Node) {
+	a := g.a
+	switch s := stmt.(type) {
+	case *ast.Assignment:
+		g.assign(s)
+
+	case *ast.RecordAssignment:
+		// The target indices are evaluated first, as in the VM
+		dst, src := g.record(s.Target), g.record(s.Value)
+		_, _, t := ast.RecordOf(s.Target)
+		g.printf("copyRecord(%s, %s, %d);", dst, src, t.Size())
+
+	case *ast.PrintStmt:
+		g.print(s)
+
+	case *ast.IfStmt:
+		cond := g.truth(g.expr(s.Condition))
+		if !a.flat[s] {
+			g.printf("if (%s) {", cond.code)
+			g.stmts(s.ThenStmts)
+			if len(s.ElseStmts) > 0 {
+				g.printf("} else {")
+				g.stmts(s.ElseStmts)
+			}
+			g.printf("}")
+			break
+		}
+		// Branches with cases stay at the top level of the switch
+		endif := g.point()
+		if len(s.ElseStmts) == 0 {
+			g.printf("if (!%s) {", jsParen(cond, precUnary))
+			g.jump(endif)
+			g.printf("}")
+			g.stmts(s.ThenStmts)
+		} else {
+			els := g.point()
+			g.printf("if (!%s) {", jsParen(cond, precUnary))
+			g.jump(els)
+			g.printf("}")
+			g.stmts(s.ThenStmts)
+			g.jump(endif)
+			g.printf("case %d:", els)
+			g.stmts(s.ElseStmts)
+		}
+		g.printf("case %d:", endif)
+
+	case *ast.ForStmt:
+		g.forStmt(s)
+
+	case *ast.NextStmt:
+		g.nextStmt(s)
+
+	case *ast.GotoStmt:
+		g.jump(s.LineNumber)
+
+	case *ast.GosubStmt:
+		if !a.returns {
+			// Nothing ever returns, so no return point is needed
+			g.jump(s.LineNumber)
+			break
+		}
+		ret := g.point()
+		g.printf("returns.push(%d);", ret)
+		g.jump(s.LineNumber)
+		g.printf("case %d:", ret)
+
+	case *ast.ReturnStmt:
+		if len(a.gosubs) == 0 {
+			g.printf("fail(\"return without gosub\");")
+		} else {
+			g.printf("pc = popReturn(returns);\ncontinue dispatch;")
+		}
+
+	case *ast.EndStmt:
+		g.printf("return;")
+
+	case *ast.StopStmt:
+		g.printf("term.write(%s);\nreturn;", jsQuote(fmt.Sprintf("\nBreak in line %d\n", g.line)))
+
+	case *ast.InputStmt:
+		if s.Line {
+			g.printf("await term.lineInput(%s);", jsQuote(s.PromptText()))
+		} else {
+			g.printf("await term.input(%s, %s);", jsQuote(s.PromptText()), jsQuote(interpreter.InputTypes(s.Vars)))
+		}
+		for _, v := range s.Vars {
+			g.store(v, jsExpr{code: "term.field()", typ: typeValue, prec: precPrimary})
+		}
+
+	case *ast.DimStmt:
+		var sizes []string
+		for _, size := range s.Sizes {
+			sizes = append(sizes, g.num(g.expr(size)).code)
+		}
+		if s.Record != nil {
+			g.printf("%s.record(%s);", jsArrayName(s.Name), strings.Join(append([]string{jsQuote(s.Record.Shape())}, sizes...), ", "))
+			break
+		}
+		g.printf("%s.dim(%s);", jsArrayName(s.Name), strings.Join(sizes, ", "))
+
+	case *ast.SaveLocalsStmt:
+		var values []string
+		for _, name := range s.Vars {
+			values = append(values, jsName(name))
+		}
+		for _, name := range s.Arrays {
+			values = append(values, jsArrayName(name)+".dims", jsArrayName(name)+".data")
+		}
+		g.printf("saved.push(%s);", strings.Join(values, ", "))
+
+	case *ast.RestoreLocalsStmt:
+		for k := len(s.Saved.Arrays) - 1; k >= 0; k-- {
+			arr := jsArrayName(s.Saved.Arrays[k])
+			g.printf("%s.data = saved.pop();\n%s.dims = saved.pop();", arr, arr)
+		}
+		for k := len(s.Saved.Vars) - 1; k >= 0; k-- {
+			g.printf("%s = saved.pop();", jsName(s.Saved.Vars[k]))
+		}
+
+	case *ast.RemStmt:
+	}
+}
+
+func (g *jsGen) stmts(stmts []ast.Node) {
+	for _, stmt := range stmts {
+		g.stmt(stmt)
+	}
+}
+
+func (g *jsGen) assign(s *ast.Assignment) {
+	value := g.expr(s.Value)
+	if target, ok := s.Target.(*ast.Identifier); ok && g.a.types[strings.ToUpper(target.Name)] == typeNumber {
+		// X = X + Y becomes X += Y, and X = X + 1 becomes X++
+		name := strings.ToUpper(target.Name)
+		if bin, ok := s.Value.(*ast.BinaryOp); ok && (bin.Op == "+" || bin.Op == "-" || bin.Op == "*") {
+			if left, ok := bin.Left.(*ast.Identifier); ok && strings.ToUpper(left.Name) == name {
+				right := g.num(g.expr(bin.Right))
+				name = jsName(name)
+				switch {
+				case bin.Op != "*" && right.constant && right.value == 1:
+					g.printf("%s%s%s;", name, bin.Op, bin.Op)
+				default:
+					g.printf("%s %s= %s;", name, bin.Op, right.code)
+				}
+				return
+			}
+		}
+	}
+	g.store(s.Target, value)
+}
+
+// store assigns value to a variable or array element
+func (g *jsGen) store(target ast.Node, value jsExpr) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		name := strings.ToUpper(target.Name)
+		switch g.a.types[name] {
+		case typeNumber:
+			g.printf("%s = %s;", jsName(name), g.num(value).code)
+		case typeString:
+			g.printf("%s = %s;", jsName(name), g.str(value).code)
+		default:
+			g.printf("%s = %s;", jsName(name), g.val(value).code)
+		}
+	case *ast.ArrayAccess:
+		// Indices are evaluated before the value, as in the VM
+		arr := jsArrayName(target.Name)
+		index := "index"
+		if len(target.Indices) == 1 {
+			index = "index1"
+		}
+		if strings.HasSuffix(target.Name, "$") {
+			g.printf("%s.data[%s.%s(%s)] = %s;", arr, arr, index, g.indices(target.Indices), g.str(value).code)
+		} else {
+			g.printf("%s.data[%s.%s(%s)] = %s;", arr, arr, index, g.indices(target.Indices), g.num(value).code)
+		}
+	case *ast.FieldAccess:
+		if f, _ := target.Info(); f.String {
+			g.printf("%s = %s;", g.field(target), g.str(value).code)
+		} else {
+			g.printf("%s = %s;", g.field(target), g.num(value).code)
+		}
+	}
+}
+
+// field returns the element of a record field: the slot of the record's
+// array at the record's indices followed by the slot
+func (g *jsGen) field(f *ast.FieldAccess) string {
+	ref, slot := f.Slot()
+	arr, index := g.slot(ref, slot)
+	return fmt.Sprintf("%s.data[%s]", arr, index)
+}
+
+func (g *jsGen) slot(ref *ast.RecordRef, slot int) (arr, index string) {
+	arr = jsArrayName(ref.Name)
+	if len(ref.Indices) == 0 {
+		return arr, fmt.Sprintf("%s.index1(%d)", arr, slot)
+	}
+	return arr, fmt.Sprintf("%s.index(%s, %d)", arr, g.indices(ref.Indices), slot)
+}
+
+// record returns the array and first slot of a whole record as arguments
+// of copyRecord
+func (g *jsGen) record(n ast.Node) string {
+	ref, slot, _ := ast.RecordOf(n)
+	arr, index := g.slot(ref, slot)
+	return arr + ", " + index
+}
+
+func (g *jsGen) indices(nodes []ast.Node) string {
+	var idx []string
+	for _, n := range nodes {
+		idx = append(idx, g.num(g.expr(n)).code)
+	}
+	return strings.Join(idx, ", ")
+}
+
+func (g *jsGen) print(s *ast.PrintStmt) {
+	// Adjacent literal text is written in one call; everything else is
+	// written as soon as it is evaluated, so output before a runtime error
+	// matches the VM's
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			g.printf("term.write(%s);", jsQuote(text.String()))
+			text.Reset()
+		}
+	}
+	for i, v := range s.Values {
+		e := g.expr(v)
+		switch lit, ok := v.(*ast.StringLiteral); {
+		case ok:
+			text.WriteString(lit.Value)
+		case e.constant:
+			text.WriteString(strconv.FormatFloat(e.value, 'g', -1, 64))
+		default:
+			flush()
+			g.printf("term.write(%s);", g.str(e).code)
+		}
+		sep := ""
+		if i < len(s.Separators) {
+			sep = s.Separators[i]
+		} else if i == len(s.Values)-1 {
+			sep = s.Trailer
+		}
+		if sep == "," {
+			text.WriteString(" ")
+		}
+	}
+	if s.Trailer == "" {
+		text.WriteString("\n")
+	}
+	flush()
+}
+
+// forStmt emits a FOR. A native loop opens a JavaScript for statement
+// that the matching NEXT closes; a run-time loop gets a case that NEXT
+// jumps back to.
+func (g *jsGen) forStmt(s *ast.ForStmt) {
+	l := g.a.loops[s]
+	name := strings.ToUpper(s.Var)
+	start := g.expr(s.Start)
+	switch g.a.types[name] {
+	case typeNumber:
+		g.printf("%s = %s;", jsName(name), g.num(start).code)
+	default:
+		g.printf("%s = %s;", jsName(name), g.val(start).code)
+	}
+	end, step := g.num(g.expr(s.End)), g.num(g.expr(s.Step))
+
+	if !l.native {
+		g.printf("frames.push(%d, %s, %s);", l.varIdx, end.code, step.code)
+		if l.next != nil {
+			g.loopTop[l] = g.point()
+			g.printf("case %d:", g.loopTop[l])
+		}
+		return
+	}
+
+	var init []string
+	if !end.constant {
+		init = append(init, fmt.Sprintf("end%d = %s", l.id, end.code))
+	}
+	if !step.constant {
+		init = append(init, fmt.Sprintf("step%d = %s", l.id, step.code))
+	}
+	if len(init) == 0 {
+		g.printf("for (;;) {")
+	} else {
+		g.printf("for (let %s; ; ) {", strings.Join(init, ", "))
+	}
+}
+
+// nextStmt increments the loop variable and loops again while it is
+// within the bounds
+func (g *jsGen) nextStmt(s *ast.NextStmt) {
+	l := g.a.nexts[s]
+	name := g.a.names[l.varIdx]
+	numeric := g.a.types[name] == typeNumber
+	name = jsName(name)
+
+	if !l.native {
+		if numeric {
+			g.printf("%s += frames.step(%d);", name, l.varIdx)
+		} else {
+			g.printf("%s = num(%s) + frames.step(%d);", name, name, l.varIdx)
+		}
+		g.printf("if (frames.again(%s)) {", name)
+		g.jump(g.loopTop[l])
+		g.printf("}")
+		return
+	}
+
+	end, step := g.num(g.expr(l.stmt.End)), g.num(g.expr(l.stmt.Step))
+	endCode, stepCode := fmt.Sprintf("end%d", l.id), fmt.Sprintf("step%d", l.id)
+	if end.constant {
+		endCode = end.code
+	}
+	if step.constant {
+		stepCode = step.code
+	}
+
+	switch {
+	case !numeric:
+		g.printf("%s = num(%s) + %s;", name, name, stepCode)
+	case step.constant && step.value == 1:
+		g.printf("%s++;", name)
+	case step.constant && step.value == -1:
+		g.printf("%s--;", name)
+	default:
+		g.printf("%s += %s;", name, stepCode)
+	}
+
+	var again string
+	switch {
+	case !step.constant:
+		again = fmt.Sprintf("(%s > 0 && %s <= %s) || (%s < 0 && %s >= %s)", stepCode, name, endCode, stepCode, name, endCode)
+	case step.value > 0:
+		again = fmt.Sprintf("%s <= %s", name, endCode)
+	case step.value < 0:
+		again = fmt.Sprintf("%s >= %s", name, endCode)
+	default:
+		again = "false"
+	}
+	g.printf("if (!(%s)) {\nbreak;\n}", again)
+	g.printf("}")
+}
+
+// jsExpr is a translated expression. Precedences use the prec constants
+// of the Go translation; JavaScript's are ordered the same way for the
+// operators used here.
+type jsExpr struct {
+	code     string
+	typ      valueType // typeNumber, typeString, typeValue or typeBool
+	prec     int       // Precedence of the outermost operator in code
+	pure     bool      // Evaluating it cannot fail and has no effects
+	constant bool      // A numeric constant with value
+	value    float64
+}
+
+func jsParen(e jsExpr, prec int) string {
+	if e.prec < prec {
+		return "(" + e.code + ")"
+	}
+	return e.code
+}
+
+// jsNumber returns a numeric constant expression, folded with float64
+// arithmetic like the Go translation's constants
+func jsNumber(v float64) jsExpr {
+	e := jsExpr{typ: typeNumber, prec: precPrimary, pure: true, constant: true, value: v}
+	switch {
+	case math.IsInf(v, 1):
+		e.code = "Infinity"
+	case math.IsInf(v, -1):
+		e.code = "-Infinity"
+	case math.IsNaN(v):
+		e.code = "NaN"
+	case v == 0 && math.Signbit(v):
+		e.code = "-0"
+	default:
+		e.code = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	if strings.HasPrefix(e.code, "-") {
+		e.prec = precUnary
+	}
+	return e
+}
+
+// jsQuote returns a JavaScript string literal for s
+func jsQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r == 0x2028 || r == 0x2029 || r == utf8.RuneError:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// num converts e to a number expression
+func (g *jsGen) num(e jsExpr) jsExpr {
+	switch e.typ {
+	case typeNumber:
+		return e
+	case typeBool:
+		return jsExpr{code: "b2f(" + e.code + ")", typ: typeNumber, prec: precPrimary, pure: e.pure}
+	case typeString:
+		return jsExpr{code: "toNumber(" + e.code + ")", typ: typeNumber, prec: precPrimary, pure: e.pure}
+	}
+	return jsExpr{code: "num(" + e.code + ")", typ: typeNumber, prec: precPrimary, pure: e.pure}
+}
+
+// str converts e to a string expression
+func (g *jsGen) str(e jsExpr) jsExpr {
+	switch e.typ {
+	case typeString:
+		return e
+	case typeValue:
+		return jsExpr{code: "str(" + e.code + ")", typ: typeString, prec: precPrimary, pure: e.pure}
+	}
+	if n := g.num(e); n.constant {
+		return jsExpr{code: jsQuote(strconv.FormatFloat(n.value, 'g', -1, 64)), typ: typeString, prec: precPrimary, pure: true}
+	}
+	return jsExpr{code: "fmtNum(" + g.num(e).code + ")", typ: typeString, prec: precPrimary, pure: e.pure}
+}
+
+// val converts e to a dynamic value. Numbers and strings already are
+// values; only booleans need converting.
+func (g *jsGen) val(e jsExpr) jsExpr {
+	switch e.typ {
+	case typeNumber, typeString, typeValue:
+		e.typ = typeValue
+		return e
+	}
+	return g.val(g.num(e))
+}
+
+// truth converts e to a boolean expression: non-zero numbers and
+// non-empty strings are true
+func (g *jsGen) truth(e jsExpr) jsExpr {
+	b := jsExpr{typ: typeBool, prec: precCompare, pure: e.pure}
+	switch e.typ {
+	case typeBool:
+		return e
+	case typeNumber:
+		b.code = jsParen(e, precAdd) + " !== 0"
+	case typeString:
+		b.code = jsParen(e, precAdd) + ` !== ""`
+	default:
+		b.code = "truth(" + e.code + ")"
+		b.prec = precPrimary
+	}
+	return b
+}
+
+var jsCompareOps = map[string]string{"=": "===", "<>": "!==", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+func (g *jsGen) expr(e ast.Node) jsExpr {
+	switch n := e.(type) {
+	case *ast.Number:
+		return jsNumber(n.Value)
+
+	case *ast.StringLiteral:
+		return jsExpr{code: jsQuote(n.Value), typ: typeString, prec: precPrimary, pure: true}
+
+	case *ast.Identifier:
+		name := strings.ToUpper(n.Name)
+		return jsExpr{code: jsName(name), typ: g.a.types[name], prec: precPrimary, pure: true}
+
+	case *ast.ArrayAccess:
+		arr := jsArrayName(n.Name)
+		index := "index"
+		if len(n.Indices) == 1 {
+			index = "index1"
+		}
+		code := fmt.Sprintf("%s.data[%s.%s(%s)]", arr, arr, index, g.indices(n.Indices))
+		if strings.HasSuffix(n.Name, "$") {
+			return jsExpr{code: code, typ: typeString, prec: precPrimary}
+		}
+		return jsExpr{code: code, typ: typeNumber, prec: precPrimary}
+
+	case *ast.FieldAccess:
+		if f, _ := n.Info(); f.String {
+			return jsExpr{code: g.field(n), typ: typeString, prec: precPrimary}
+		}
+		return jsExpr{code: g.field(n), typ: typeNumber, prec: precPrimary}
+
+	case *ast.FunctionCall:
+		return g.call(n)
+
+	case *ast.BinaryOp:
+		left, right := g.expr(n.Left), g.expr(n.Right)
+		pure := left.pure && right.pure
+		if n.Op == "+" {
+			switch {
+			case left.typ == typeString || right.typ == typeString:
+				l, r := g.str(left), g.str(right)
+				return jsExpr{code: jsParen(l, precAdd) + " + " + jsParen(r, precMul), typ: typeString, prec: precAdd, pure: pure}
+			case left.typ == typeValue || right.typ == typeValue:
+				return jsExpr{code: "add(" + g.val(left).code + ", " + g.val(right).code + ")", typ: typeValue, prec: precPrimary, pure: pure}
+			}
+		}
+		l, r := g.num(left), g.num(right)
+		if l.constant && r.constant {
+			switch n.Op {
+			case "+":
+				return jsNumber(l.value + r.value)
+			case "-":
+				return jsNumber(l.value - r.value)
+			case "*":
+				return jsNumber(l.value * r.value)
+			case "/":
+				if r.value != 0 {
+					return jsNumber(l.value / r.value)
+				}
+			}
+		}
+		switch n.Op {
+		case "+", "-":
+			return jsExpr{code: jsParen(l, precAdd) + " " + n.Op + " " + jsParen(r, precMul), typ: typeNumber, prec: precAdd, pure: pure}
+		case "*":
+			return jsExpr{code: jsParen(l, precMul) + " * " + jsParen(r, precUnary), typ: typeNumber, prec: precMul, pure: pure}
+		case "/":
+			return jsExpr{code: "div(" + l.code + ", " + r.code + ")", typ: typeNumber, prec: precPrimary}
+		case "^":
+			// Not **, which cannot follow a unary minus
+			return jsExpr{code: "Math.pow(" + l.code + ", " + r.code + ")", typ: typeNumber, prec: precPrimary, pure: pure}
+		}
+		return jsExpr{code: jsParen(l, precMul) + " % " + jsParen(r, precUnary), typ: typeNumber, prec: precMul, pure: pure}
+
+	case *ast.ComparisonOp:
+		left, right := g.expr(n.Left), g.expr(n.Right)
+		pure := left.pure && right.pure
+		switch {
+		case left.typ == typeString || right.typ == typeString:
+			left, right = g.str(left), g.str(right)
+		case left.typ == typeValue || right.typ == typeValue:
+			code := fmt.Sprintf("compare(%s, %q, %s)", g.val(left).code, n.Op, g.val(right).code)
+			return jsExpr{code: code, typ: typeBool, prec: precPrimary, pure: pure}
+		default:
+			left, right = g.num(left), g.num(right)
+		}
+		code := jsParen(left, precAdd) + " " + jsCompareOps[n.Op] + " " + jsParen(right, precAdd)
+		return jsExpr{code: code, typ: typeBool, prec: precCompare, pure: pure}
+
+	case *ast.LogicalOp:
+		left, right := g.truth(g.expr(n.Left)), g.truth(g.expr(n.Right))
+		pure := left.pure && right.pure
+		if !right.pure {
+			// Both operands are always evaluated, so a failing right
+			// operand must not be skipped
+			fn := map[string]string{"AND": "and", "OR": "or"}[n.Op]
+			return jsExpr{code: fn + "(" + left.code + ", " + right.code + ")", typ: typeBool, prec: precPrimary}
+		}
+		if n.Op == "AND" {
+			return jsExpr{code: jsParen(left, precAnd) + " && " + jsParen(right, precCompare), typ: typeBool, prec: precAnd, pure: pure}
+		}
+		return jsExpr{code: jsParen(left, precOr) + " || " + jsParen(right, precAnd), typ: typeBool, prec: precOr, pure: pure}
+
+	case *ast.UnaryOp:
+		operand := g.expr(n.Right)
+		switch n.Op {
+		case "-":
+			switch {
+			case operand.constant:
+				return jsNumber(-operand.value)
+			case operand.typ == typeNumber || operand.typ == typeBool:
+				code := jsParen(g.num(operand), precUnary)
+				if strings.HasPrefix(code, "-") {
+					code = "(" + code + ")" // Not the -- operator
+				}
+				return jsExpr{code: "-" + code, typ: typeNumber, prec: precUnary, pure: operand.pure}
+			}
+			return jsExpr{code: "neg(" + g.val(operand).code + ")", typ: typeNumber, prec: precPrimary}
+		case "NOT":
+			b := g.truth(operand)
+			return jsExpr{code: "!" + jsParen(b, precUnary), typ: typeBool, prec: precUnary, pure: b.pure}
+		}
+		return operand
+	}
+	panic(fmt.Sprintf("codegen: unexpected expression %T", e))
+}
+
+// call translates a builtin function call
+func (g *jsGen) call(n *ast.FunctionCall) jsExpr {
+	name := strings.ToUpper(n.Name)
+	args := make([]jsExpr, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = g.expr(arg)
+	}
+	if min, max := builtinArity(name); len(args) < min || len(args) > max {
+		code := jsQuote(arityError(name))
+		for _, arg := range args {
+			code += ", " + g.val(arg).code
+		}
+		return jsExpr{code: "badCall(" + code + ")", typ: typeValue, prec: precPrimary}
+	}
+
+	numArg := func(i int) string { return g.num(args[i]).code }
+	strArg := func(i int) string { return g.str(args[i]).code }
+	pure := true
+	for _, arg := range args {
+		pure = pure && arg.pure
+	}
+	number := func(code string, pure bool) jsExpr {
+		return jsExpr{code: code, typ: typeNumber, prec: precPrimary, pure: pure}
+	}
+	text := func(code string, pure bool) jsExpr {
+		return jsExpr{code: code, typ: typeString, prec: precPrimary, pure: pure}
+	}
+
+	switch name {
+	case "ABS", "SIN", "COS", "TAN", "EXP":
+		return number("Math."+strings.ToLower(name)+"("+numArg(0)+")", pure)
+	case "INT":
+		return number("Math.trunc("+numArg(0)+")", pure)
+	case "SQR":
+		return number("sqr("+numArg(0)+")", false)
+	case "LOG":
+		return number("logn("+numArg(0)+")", false)
+	case "RND":
+		return number("Math.random()", false)
+	case "LEN":
+		return number("len("+strArg(0)+")", pure)
+	case "LEFT$":
+		return text("left("+strArg(0)+", "+numArg(1)+")", pure)
+	case "RIGHT$":
+		return text("right("+strArg(0)+", "+numArg(1)+")", pure)
+	case "MID$":
+		if len(args) == 3 {
+			return text("mid("+strArg(0)+", "+numArg(1)+", "+numArg(2)+")", pure)
+		}
+		return text("mid("+strArg(0)+", "+numArg(1)+")", pure)
+	case "INSTR":
+		if len(args) == 3 {
+			return number("instr("+numArg(0)+", "+strArg(1)+", "+strArg(2)+")", pure)
+		}
+		return number("instr(1, "+strArg(0)+", "+strArg(1)+")", pure)
+	case "UCASE$":
+		return text(jsParen(g.str(args[0]), precPrimary)+".toUpperCase()", pure)
+	case "LCASE$":
+		return text(jsParen(g.str(args[0]), precPrimary)+".toLowerCase()", pure)
+	case "SPACE$":
+		return text("space("+numArg(0)+")", pure)
+	case "CHR$":
+		return text("chr("+numArg(0)+")", false)
+	case "ASC":
+		return number("asc("+strArg(0)+")", false)
+	case "PI":
+		return jsExpr{code: "Math.PI", typ: typeNumber, prec: precPrimary, pure: true, constant: true, value: math.Pi}
+	}
+	// EULER
+	return jsExpr{code: "Math.E", typ: typeNumber, prec: precPrimary, pure: true, constant: true, value: math.E}
+}
@@ -0,0 +1,143 @@
+package codegen_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/codegen"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden .mjs files")
+
+// jsRunner runs prog.mjs the way a page would: output is collected through
+// io.print and INPUT lines come from an async io.input
+const jsRunner = `import { readFileSync } from "node:fs";
+import { run, BasicError } from "./prog.mjs";
+
+const text = readFileSync(0, "utf8");
+const lines = text === "" ? [] : text.replace(/\n$/, "").split("\n");
+let out = "";
+try {
+  await run({ print: (text) => { out += text; }, input: async () => lines.shift() });
+} catch (e) {
+  if (!(e instanceof BasicError)) throw e;
+  process.stderr.write("Runtime error: " + e.message + "\n");
+  process.exitCode = 1;
+}
+process.stdout.write(out);
+`
+
+// runJS transpiles prog to JavaScript and runs it with node
+func runJS(t *testing.T, prog *ast.Program) (string, string) {
+	t.Helper()
+	src, err := codegen.JS(prog)
+	if err != nil {
+		t.Fatalf("codegen: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "prog.mjs"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.mjs"), []byte(jsRunner), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	run := exec.Command("node", filepath.Join(dir, "run.mjs"))
+	run.Stdin = strings.NewReader(input)
+	run.Stdout, run.Stderr = &stdout, &stderr
+	err = run.Run()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatal(err)
+	}
+	return stdout.String(), strings.TrimSpace(stderr.String())
+}
+
+// inexact matches programs whose output may differ in the last digits
+var inexact = regexp.MustCompile(`(?i)\b(RND|SIN|COS|TAN|EXP|LOG)\b`)
+
+func needNode(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not found")
+	}
+}
+
+// TestJSGolden compares the module generated for testdata/dispatch.bas,
+// which uses every kind of dispatch point, with a checked-in snapshot, so
+// changes to the output show up without a JavaScript engine
+func TestJSGolden(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "dispatch.bas"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := codegen.JS(parse(t, "dispatch.bas", string(data)), codegen.WithSource("dispatch.bas", data))
+	if err != nil {
+		t.Fatalf("codegen: %v", err)
+	}
+	path := filepath.Join("testdata", "dispatch.mjs")
+	if *update {
+		if err := os.WriteFile(path, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(src, golden) {
+		t.Errorf("module differs from %s (run with -update):\n%s", path, src)
+	}
+}
+
+// TestJSSamples checks that every sample prints the same in node as in
+// the VM. Samples that print random numbers or results of the engine's
+// math functions are only run.
+func TestJSSamples(t *testing.T) {
+	needNode(t)
+	files, err := filepath.Glob("../../samples/*.bas")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no samples: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			t.Parallel()
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prog := parse(t, file, string(data))
+			got, gotErr := runJS(t, prog)
+			if inexact.MatchString(string(data)) {
+				return
+			}
+			want, wantErr := runVM(t, prog)
+			if got != want || gotErr != wantErr {
+				t.Errorf("output differs from the VM\n got: %q %s\nwant: %q %s", got, gotErr, want, wantErr)
+			}
+		})
+	}
+}
+
+func TestJSSemantics(t *testing.T) {
+	needNode(t)
+	for _, tt := range semantics {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			prog := parse(t, tt.name, tt.src)
+			want, wantErr := runVM(t, prog)
+			got, gotErr := runJS(t, prog)
+			if got != want || gotErr != wantErr {
+				t.Errorf("output differs from the VM\n got: %q %s\nwant: %q %s", got, gotErr, want, wantErr)
+			}
+		})
+	}
+}
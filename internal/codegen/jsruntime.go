@@ -0,0 +1,491 @@
+package codegen
+
+// jsRuntime is appended to every generated ES module. Like goRuntime it
+// mirrors the VM's value semantics, arrays, FOR stack and builtins. BASIC
+// values map onto JavaScript ones: numbers, strings and undefined for the
+// empty value. Strings are measured and cut in UTF-8 bytes, as in Go.
+const jsRuntime = jsErrors + jsTerminal + jsNumbers + jsValues
+
+// jsErrors and jsNumbers are shared with WasmHost
+const jsErrors = `
+// ---- BASIC runtime ----
+
+// BasicError is a BASIC runtime error; the promise returned by run
+// rejects with one.
+export class BasicError extends Error {
+  constructor(message) {
+    super(message);
+    this.name = "BasicError";
+  }
+}
+
+function fail(message) {
+  throw new BasicError(message);
+}
+
+`
+
+const jsTerminal = `// Terminal passes output to io.print and takes INPUT lines from io.input.
+// Without io.print, complete lines go to console.log. When io.input is
+// missing or returns undefined, INPUT fails with "Input past end".
+class Terminal {
+  constructor(io) {
+    this.print = io.print;
+    this.inputLine = io.input;
+    this.line = "";
+  }
+
+  write(text) {
+    if (this.print) {
+      this.print(text);
+      return;
+    }
+    const lines = (this.line + text).split("\n");
+    this.line = lines.pop();
+    for (const line of lines) {
+      console.log(line);
+    }
+  }
+
+  flush() {
+    if (!this.print && this.line !== "") {
+      console.log(this.line);
+      this.line = "";
+    }
+  }
+
+  // input prompts until a line matches types (see parseInput) and keeps
+  // the fields for field
+  async input(prompt, types) {
+    for (;;) {
+      const values = parseInput(await this.readLine(prompt), types);
+      if (values !== undefined) {
+        this.fields = values;
+        return;
+      }
+      this.write("?Redo from start\n");
+    }
+  }
+
+  // lineInput reads a whole line as one string field (LINE INPUT)
+  async lineInput(prompt) {
+    this.fields = [await this.readLine(prompt)];
+  }
+
+  // field returns the next INPUT field
+  field() {
+    return this.fields.shift();
+  }
+
+  async readLine(prompt) {
+    this.write(prompt);
+    this.flush();
+    const line = this.inputLine ? await this.inputLine() : undefined;
+    if (line === undefined || line === null) {
+      fail("Input past end");
+    }
+    return String(line).replace(/\r?\n$/, "");
+  }
+}
+
+`
+
+const jsNumbers = `const decimal = /^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$/;
+const infinity = /^([+-]?)inf(inity)?$/i;
+
+// parseNumber parses s like Go's strconv.ParseFloat and returns undefined
+// when that fails
+function parseNumber(s) {
+  if (decimal.test(s)) {
+    const n = Number(s);
+    return Number.isFinite(n) ? n : undefined;
+  }
+  const inf = infinity.exec(s);
+  if (inf) {
+    return inf[1] === "-" ? -Infinity : Infinity;
+  }
+  return /^nan$/i.test(s) ? NaN : undefined;
+}
+
+// toNumber converts a string to a number; numbers too large to represent
+// become infinite and anything else that does not parse becomes 0
+function toNumber(s) {
+  return decimal.test(s) ? Number(s) : parseNumber(s) ?? 0;
+}
+
+// parseInput splits an INPUT line into comma-separated fields like
+// GW-BASIC and converts them by types, one "#" (number) or "$" (string)
+// per variable. Quoted fields keep commas and spaces; other fields are
+// trimmed, and an empty numeric field is 0. It returns undefined when the
+// line does not match.
+function parseInput(line, types) {
+  const values = [];
+  let rest = line;
+  for (let i = 0; i < types.length; i++) {
+    let field = rest.replace(/^[ \t]+/, "");
+    const quoted = field.startsWith('"');
+    if (quoted) {
+      field = field.slice(1);
+      let end = field.indexOf('"');
+      if (end < 0) {
+        end = field.length;
+        rest = "";
+      } else {
+        rest = field.slice(end + 1).replace(/^[ \t]+/, "");
+        if (rest !== "" && rest[0] !== ",") {
+          return undefined;
+        }
+      }
+      field = field.slice(0, end);
+    } else {
+      const end = field.indexOf(",");
+      rest = end < 0 ? "" : field.slice(end);
+      field = (end < 0 ? field : field.slice(0, end)).replace(/[ \t]+$/, "");
+    }
+
+    if (types[i] === "$") {
+      values.push(field);
+    } else if (quoted) {
+      return undefined;
+    } else if (field === "") {
+      values.push(0);
+    } else if (decimal.test(field) && Number.isFinite(Number(field))) {
+      values.push(Number(field));
+    } else {
+      return undefined;
+    }
+    if (i < types.length - 1) {
+      if (!rest.startsWith(",")) {
+        return undefined;
+      }
+      rest = rest.slice(1);
+    }
+  }
+  return rest === "" ? values : undefined;
+}
+
+// fmtNum formats n like Go's strconv.FormatFloat(n, 'g', -1, 64)
+function fmtNum(n) {
+  if (Number.isNaN(n)) {
+    return "NaN";
+  }
+  if (!Number.isFinite(n)) {
+    return n > 0 ? "+Inf" : "-Inf";
+  }
+  if (n === 0) {
+    return Object.is(n, -0) ? "-0" : "0";
+  }
+  const sign = n < 0 ? "-" : "";
+  const [mantissa, e] = Math.abs(n).toExponential().split("e");
+  const digits = mantissa.replace(".", "");
+  const exp = Number(e);
+  if (exp < -4 || exp >= 6) {
+    const frac = digits.length > 1 ? "." + digits.slice(1) : "";
+    const abs = Math.abs(exp);
+    return sign + digits[0] + frac + "e" + (exp < 0 ? "-" : "+") + (abs < 10 ? "0" : "") + abs;
+  }
+  if (exp < 0) {
+    return sign + "0." + "0".repeat(-exp - 1) + digits;
+  }
+  if (digits.length <= exp + 1) {
+    return sign + digits + "0".repeat(exp + 1 - digits.length);
+  }
+  return sign + digits.slice(0, exp + 1) + "." + digits.slice(exp + 1);
+}
+
+`
+
+const jsValues = `// toInt truncates n like Go's int(n) on amd64: NaN and values out of range
+// become the smallest int64
+function toInt(n) {
+  return n >= -(2 ** 63) && n < 2 ** 63 ? Math.trunc(n) : -(2 ** 63);
+}
+
+function b2f(b) {
+  return b ? 1 : 0;
+}
+
+// num converts a value to a number, parsing strings and treating empty as 0
+function num(v) {
+  if (typeof v === "number") {
+    return v;
+  }
+  return typeof v === "string" ? toNumber(v) : 0;
+}
+
+function str(v) {
+  return typeof v === "number" ? fmtNum(v) : v ?? "";
+}
+
+function truth(v) {
+  return v !== undefined && v !== 0 && v !== "";
+}
+
+// and and or evaluate both operands, as the VM does
+function and(a, b) {
+  return a && b;
+}
+
+function or(a, b) {
+  return a || b;
+}
+
+// add adds two numbers and concatenates anything involving a string
+function add(a, b) {
+  if (typeof a === "number" && typeof b === "number") {
+    return a + b;
+  }
+  if (typeof a === "string" || typeof b === "string") {
+    return str(a) + str(b);
+  }
+  return num(a) + num(b);
+}
+
+// compare compares as strings if either side is a string and as numbers
+// otherwise
+function compare(a, op, b) {
+  let x, y;
+  if (typeof a === "string" || typeof b === "string") {
+    x = str(a);
+    y = str(b);
+  } else {
+    x = num(a);
+    y = num(b);
+  }
+  switch (op) {
+    case "=":
+      return x === y;
+    case "<>":
+      return x !== y;
+    case "<":
+      return x < y;
+    case "<=":
+      return x <= y;
+    case ">":
+      return x > y;
+  }
+  return x >= y;
+}
+
+function neg(v) {
+  if (typeof v !== "number") {
+    fail("operand must be a number");
+  }
+  return -v;
+}
+
+function div(a, b) {
+  if (b === 0) {
+    fail("division by zero");
+  }
+  return a / b;
+}
+
+// BasicArray is a DIM array; dims is null until the DIM runs. A string
+// array holds strings instead of numbers. A TYPE record variable or record
+// array has one more dimension for the slots of a record, which hold
+// numbers or strings as its shape says.
+class BasicArray {
+  constructor(id, str) {
+    this.id = id;
+    this.str = str;
+    this.dims = null;
+    this.data = null;
+  }
+
+  dim(...sizes) {
+    const dims = new Array(sizes.length);
+    for (let i = sizes.length - 1; i >= 0; i--) {
+      const d = toInt(sizes[i]);
+      if (d < 0) {
+        fail("negative array dimension: " + d);
+      }
+      dims[i] = d;
+    }
+    this.dims = dims;
+    const total = dims.reduce((total, d) => total * d, 1);
+    this.data = this.str ? new Array(total).fill("") : new Float64Array(total);
+  }
+
+  // record makes this a record variable or record array; shape holds "#"
+  // or "$" for each slot of a record
+  record(shape, ...sizes) {
+    this.dim(...sizes, shape.length);
+    this.data = Array.from(this.data, (_, i) => (shape[i % shape.length] === "$" ? "" : 0));
+  }
+
+  index(...idx) {
+    if (this.dims === null) {
+      fail("array not declared (index " + this.id + ")");
+    }
+    if (idx.length !== this.dims.length) {
+      fail("array index out of bounds");
+    }
+    let flat = 0;
+    let mul = 1;
+    for (let i = idx.length - 1; i >= 0; i--) {
+      const n = toInt(idx[i]);
+      if (n < 0 || n >= this.dims[i]) {
+        fail("array index out of bounds");
+      }
+      flat += n * mul;
+      mul *= this.dims[i];
+    }
+    return flat;
+  }
+
+  index1(i) {
+    if (this.dims === null) {
+      fail("array not declared (index " + this.id + ")");
+    }
+    const n = toInt(i);
+    if (this.dims.length !== 1 || n < 0 || n >= this.dims[0]) {
+      fail("array index out of bounds");
+    }
+    return n;
+  }
+}
+
+// copyRecord copies the n slots of a record of src starting at from to dst
+// starting at to (LET A = B for records)
+function copyRecord(dst, to, src, from, n) {
+  for (let i = 0; i < n; i++) {
+    dst.data[to + i] = src.data[from + i];
+  }
+}
+
+// ForStack holds the active FOR loops that are not native loops
+class ForStack {
+  constructor() {
+    this.frames = [];
+  }
+
+  push(v, end, step) {
+    this.frames.push({ v, end, step });
+  }
+
+  // step checks that the innermost loop belongs to variable v and returns
+  // its step
+  step(v) {
+    const top = this.frames[this.frames.length - 1];
+    if (top === undefined) {
+      fail("NEXT without FOR");
+    }
+    if (top.v !== v) {
+      fail("NEXT variable mismatch");
+    }
+    return top.step;
+  }
+
+  // again reports whether the innermost loop runs again with the loop
+  // variable at x, and pops the loop when it does not
+  again(x) {
+    const top = this.frames[this.frames.length - 1];
+    if ((top.step > 0 && x <= top.end) || (top.step < 0 && x >= top.end)) {
+      return true;
+    }
+    this.frames.pop();
+    return false;
+  }
+}
+
+function popReturn(returns) {
+  if (returns.length === 0) {
+    fail("return without gosub");
+  }
+  return returns.pop();
+}
+
+function sqr(x) {
+  if (x < 0) {
+    fail("SQR of negative number");
+  }
+  return Math.sqrt(x);
+}
+
+function logn(x) {
+  if (x <= 0) {
+    fail("LOG of non-positive number");
+  }
+  return Math.log(x);
+}
+
+const ascii = /^[\x00-\x7f]*$/;
+const utf8 = new TextEncoder();
+const utf8Decoder = new TextDecoder();
+
+function len(s) {
+  return ascii.test(s) ? s.length : utf8.encode(s).length;
+}
+
+// slice returns bytes from to to of the UTF-8 encoding of s
+function slice(s, from, to) {
+  return ascii.test(s) ? s.slice(from, to) : utf8Decoder.decode(utf8.encode(s).subarray(from, to));
+}
+
+function left(s, n) {
+  return slice(s, 0, Math.min(Math.max(toInt(n), 0), len(s)));
+}
+
+function right(s, n) {
+  const length = len(s);
+  return slice(s, length - Math.min(Math.max(toInt(n), 0), length), length);
+}
+
+function mid(s, start, n) {
+  const length = len(s);
+  const first = Math.max(toInt(start), 1);
+  const count = n === undefined ? length - first + 1 : toInt(n);
+  const from = first - 1;
+  if (from >= length || from < 0) {
+    return "";
+  }
+  return slice(s, from, Math.min(from + count, length));
+}
+
+function instr(start, s, sub) {
+  const first = Math.max(toInt(start), 1);
+  if (first > len(s)) {
+    return 0;
+  }
+  if (ascii.test(s) && ascii.test(sub)) {
+    return s.indexOf(sub, first - 1) + 1;
+  }
+  const b = utf8.encode(s);
+  const t = utf8.encode(sub);
+  search: for (let i = first - 1; i + t.length <= b.length; i++) {
+    for (let j = 0; j < t.length; j++) {
+      if (b[i + j] !== t[j]) {
+        continue search;
+      }
+    }
+    return i + 1;
+  }
+  return 0;
+}
+
+function space(n) {
+  return " ".repeat(Math.max(toInt(n), 0));
+}
+
+function chr(n) {
+  const code = toInt(n);
+  if (code < 0 || code > 255) {
+    fail("CHR$ argument must be between 0 and 255");
+  }
+  return String.fromCharCode(code);
+}
+
+function asc(s) {
+  if (s === "") {
+    fail("ASC argument is an empty string");
+  }
+  return s.charCodeAt(0) < 0x80 ? s.charCodeAt(0) : utf8.encode(s)[0];
+}
+
+// badCall evaluates the arguments of a builtin called with the wrong
+// number of them, then fails
+function badCall(message, ...args) {
+  fail(message);
+}
+`
@@ -0,0 +1,14 @@
+10 REM Line dispatch: GOTO, GOSUB/RETURN, run-time FOR and IF
+20 INPUT "How many? ", N
+30 IF N < 1 THEN GOTO 20
+40 FOR I = 1 TO N
+50 IF I MOD 2 = 0 THEN GOSUB 200 ELSE GOSUB 300
+60 NEXT I
+70 DIM T(4)
+80 FOR J = 0 TO 3: T(J) = J * J: NEXT J
+90 PRINT "squares"; T(1); T(2); T(3)
+100 END
+200 PRINT I; "is even"
+210 RETURN
+300 PRINT I; "is odd"
+310 RETURN
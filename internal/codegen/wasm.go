@@ -0,0 +1,776 @@
+package codegen
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/wasm"
+)
+
+// Wasm lowers stack-machine bytecode to a WebAssembly module that behaves
+// like the program run by the VM. The module imports its I/O, number
+// formatting and math functions from the host (module "basic", see
+// wasmImports) and exports memory, alloc and run; WasmHost is a host for
+// JavaScript engines.
+//
+// A BASIC value is a pair of an f64 payload and an i32 tag, kept on the
+// WebAssembly operand stack where the VM keeps it on its own, and
+// variables are pairs of locals of run. The code is split into basic
+// blocks at every jump target and GOSUB return point; run is a loop
+// around one nested block per basic block, entered through a br_table on
+// the block number. Forward jumps branch straight out to their target,
+// backward jumps and RETURN set the block number and restart the loop.
+// The VM stack must be empty across jumps, as the compiler emits it.
+//
+// The module is validated before it is returned.
+func Wasm(chunk *bytecode.Chunk) (*wasm.Module, error) {
+	if chunk.Registers {
+		return nil, errors.New("WebAssembly output needs stack-machine bytecode, not register bytecode")
+	}
+	if err := chunk.Runnable(); err != nil {
+		return nil, err
+	}
+	if _, err := bytecode.Verify(chunk); err != nil {
+		return nil, err
+	}
+	insts, err := decodeChunk(chunk)
+	if err != nil {
+		return nil, err
+	}
+	l := &wasmLowering{chunk: chunk, funcs: make(map[string]uint32), strings: make(map[string]uint32)}
+	if err := l.recordShapes(insts); err != nil {
+		return nil, err
+	}
+	for i, f := range wasmImports {
+		l.funcs[f.name] = uint32(i)
+	}
+	for i, f := range wasmRuntime {
+		l.funcs[f.name] = uint32(len(wasmImports) + i)
+	}
+
+	m := &wasm.Module{}
+	for _, f := range wasmImports {
+		typ := m.AddType(wasm.FuncType{Params: f.params, Results: f.results})
+		m.Imports = append(m.Imports, wasm.Import{Module: "basic", Name: f.name, Type: typ})
+	}
+	for _, f := range wasmRuntime {
+		a := &wasmAsm{l: l}
+		f.body(a)
+		typ := m.AddType(wasm.FuncType{Params: f.params, Results: f.results})
+		m.Funcs = append(m.Funcs, wasm.Func{Type: typ, Locals: f.locals, Body: a.code})
+	}
+	run, err := l.run(insts)
+	if err != nil {
+		return nil, err
+	}
+	run.Type = m.AddType(wasm.FuncType{})
+	m.Funcs = append(m.Funcs, run)
+
+	heap := (wasmDataBase + len(l.data) + 7) &^ 7
+	m.Memories = []wasm.Memory{{Min: uint32(heap+wasm.PageSize-1) / wasm.PageSize, Max: wasmMaxPages, HasMax: true}}
+	if len(l.data) > 0 {
+		m.Data = []wasm.Data{{Offset: wasmDataBase, Bytes: l.data}}
+	}
+	m.Globals = []wasm.Global{
+		wasmHeap: {Type: wasm.I32, Mutable: true, Init: wasm.Instr{Op: wasm.OpI32Const, Int: int64(heap)}},
+		wasmFsp:  {Type: wasm.I32, Mutable: true, Init: wasm.Instr{Op: wasm.OpI32Const}},
+		wasmRsp:  {Type: wasm.I32, Mutable: true, Init: wasm.Instr{Op: wasm.OpI32Const}},
+		wasmSsp:  {Type: wasm.I32, Mutable: true, Init: wasm.Instr{Op: wasm.OpI32Const}},
+	}
+	for range chunk.ArrayCount {
+		m.Globals = append(m.Globals, wasm.Global{Type: wasm.I32, Mutable: true, Init: wasm.Instr{Op: wasm.OpI32Const}})
+	}
+	m.Exports = []wasm.Export{
+		{Name: "memory", Kind: wasm.ExternMemory},
+		{Name: "alloc", Kind: wasm.ExternFunc, Index: l.funcs["alloc"]},
+		{Name: "run", Kind: wasm.ExternFunc, Index: uint32(len(wasmImports) + len(m.Funcs) - 1)},
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("internal error: invalid module: %v", err)
+	}
+	return m, nil
+}
+
+// wasmInst is one decoded bytecode instruction
+type wasmInst struct {
+	offset   int
+	op       bytecode.OpCode
+	operands []int
+}
+
+func decodeChunk(c *bytecode.Chunk) ([]wasmInst, error) {
+	var insts []wasmInst
+	for offset := 0; offset < len(c.Code); {
+		op := bytecode.OpCode(c.Code[offset])
+		def, err := bytecode.Lookup(op)
+		if err != nil {
+			return nil, err
+		}
+		inst := wasmInst{offset: offset, op: op}
+		offset++
+		for _, width := range def.OperandWidths {
+			inst.operands = append(inst.operands, bytecode.ReadOperand(c.Code, offset, width))
+			offset += width
+		}
+		insts = append(insts, inst)
+	}
+	return insts, nil
+}
+
+// wasmLowering holds the state of one Wasm call
+type wasmLowering struct {
+	chunk   *bytecode.Chunk
+	funcs   map[string]uint32 // Function indices by name
+	strings map[string]uint32 // Addresses of string constants
+	data    []byte            // The data segment, from wasmDataBase
+	shapes  map[int]string    // Slot types of the record arrays, from their OpDimRecord
+}
+
+// recordShapes finds the slot types of every record array. A record is
+// an array with a last dimension of one slot per field; a field reads a
+// number or a string address depending on its slot, so each record array
+// must be created with a single shape and never as a plain array. One
+// that is never created fails at run time like any undeclared array.
+func (l *wasmLowering) recordShapes(insts []wasmInst) error {
+	l.shapes = make(map[int]string)
+	plain := make(map[int]bool)
+	for _, inst := range insts {
+		switch inst.op {
+		case bytecode.OpDimRecord:
+			shape := l.chunk.Constants[inst.operands[2]].String()
+			if old, ok := l.shapes[inst.operands[0]]; (ok && old != shape) || shape == "" {
+				return l.recordError(inst.operands[0])
+			}
+			l.shapes[inst.operands[0]] = shape
+		case bytecode.OpDim, bytecode.OpDimMap:
+			plain[inst.operands[0]] = true
+		}
+	}
+	for _, inst := range insts {
+		var slots [][2]int // Array and last slot of each record operand
+		switch inst.op {
+		case bytecode.OpGetField, bytecode.OpSetField:
+			slots = [][2]int{{inst.operands[0], inst.operands[2]}}
+		case bytecode.OpCopyRecord:
+			n := inst.operands[6]
+			slots = [][2]int{{inst.operands[0], inst.operands[2] + n - 1}, {inst.operands[3], inst.operands[5] + n - 1}}
+		}
+		for _, s := range slots {
+			shape, ok := l.shapes[s[0]]
+			if !ok && !plain[s[0]] {
+				continue // Never declared, so the access fails at run time
+			}
+			if !ok || plain[s[0]] || s[1] >= len(shape) {
+				return l.recordError(s[0])
+			}
+		}
+	}
+	for arr := range l.shapes {
+		if plain[arr] {
+			return l.recordError(arr)
+		}
+	}
+	return nil
+}
+
+func (l *wasmLowering) recordError(arr int) error {
+	return fmt.Errorf("cannot compile to WebAssembly: array %d is not used as one TYPE of record", arr)
+}
+
+// constant returns the address of string constant s
+func (l *wasmLowering) constant(s string) uint32 {
+	if addr, ok := l.strings[s]; ok {
+		return addr
+	}
+	addr := uint32(wasmDataBase + len(l.data))
+	l.data = binary.LittleEndian.AppendUint32(l.data, uint32(len(s)))
+	l.data = append(l.data, s...)
+	for len(l.data)%4 != 0 {
+		l.data = append(l.data, 0)
+	}
+	l.strings[s] = addr
+	return addr
+}
+
+// Locals of run; variable i is the pair wasmVars+2i, wasmVars+2i+1
+const (
+	wasmPC    = iota // Block to enter when the dispatch loop restarts
+	wasmTemp         // Scratch f64
+	wasmTemp2        // Scratch f64
+	wasmAddr         // Scratch i32
+	wasmVars
+)
+
+// run lowers the program to the body of the exported run function
+func (l *wasmLowering) run(insts []wasmInst) (wasm.Func, error) {
+	// Basic blocks start at the entry, at every jump target, after every
+	// GOSUB and at the end of the code, where jumps may also land
+	starts := map[int]bool{0: true, len(l.chunk.Code): true}
+	for i, inst := range insts {
+		switch inst.op {
+		case bytecode.OpJump, bytecode.OpJumpIfFalse:
+			starts[inst.operands[0]] = true
+		case bytecode.OpCmpJump, bytecode.OpNext:
+			starts[inst.operands[1]] = true
+		case bytecode.OpGosub:
+			starts[inst.operands[0]] = true
+			next := len(l.chunk.Code)
+			if i+1 < len(insts) {
+				next = insts[i+1].offset
+			}
+			starts[next] = true
+		}
+	}
+	offsets := make([]int, 0, len(starts))
+	for offset := range starts {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	blocks := make(map[int]int, len(offsets))
+	for i, offset := range offsets {
+		blocks[offset] = i
+	}
+
+	g := &wasmRunGen{wasmAsm: wasmAsm{l: l}, blocks: blocks, n: len(offsets)}
+	g.loop()
+	for range offsets {
+		g.block()
+	}
+	g.get(wasmPC)
+	labels := make([]uint32, len(offsets))
+	for i := range labels {
+		labels[i] = uint32(i)
+	}
+	g.emit(wasm.Instr{Op: wasm.OpBrTable, Labels: labels})
+
+	next := 0
+	for _, inst := range insts {
+		if inst.offset == offsets[next] {
+			if err := g.enter(next, inst.offset); err != nil {
+				return wasm.Func{}, err
+			}
+			next++
+		}
+		if err := g.inst(inst); err != nil {
+			return wasm.Func{}, err
+		}
+	}
+	// The block at the end of the code is empty; leaving it leaves the loop
+	if err := g.enter(next, len(l.chunk.Code)); err != nil {
+		return wasm.Func{}, err
+	}
+	g.end()
+
+	locals := []wasm.ValType{wasm.I32, wasm.F64, wasm.F64, wasm.I32}
+	for range l.chunk.GlobalCount {
+		locals = append(locals, wasm.F64, wasm.I32)
+	}
+	return wasm.Func{Locals: locals, Body: g.code}, nil
+}
+
+// wasmRunGen emits the body of run
+type wasmRunGen struct {
+	wasmAsm
+	blocks    map[int]int // Block numbers by bytecode offset
+	n         int         // Number of blocks
+	cur       int         // Block being emitted
+	depth     int         // VM stack depth
+	reachable bool        // False after an unconditional jump
+}
+
+// enter closes the block whose code follows and starts it
+func (g *wasmRunGen) enter(block, offset int) error {
+	if g.reachable && g.depth != 0 {
+		return stackError(g.depth, offset)
+	}
+	g.end()
+	g.cur, g.depth, g.reachable = block, 0, true
+	return nil
+}
+
+func stackError(depth, offset int) error {
+	return fmt.Errorf("cannot compile to WebAssembly: %d values on the stack across a jump at %04d", depth, offset)
+}
+
+// jump branches to the block at offset from inside nest ifs
+func (g *wasmRunGen) jump(offset int, nest uint32) {
+	target := g.blocks[offset]
+	if target > g.cur {
+		g.br(uint32(target-g.cur-1) + nest)
+		return
+	}
+	g.i32(int32(target))
+	g.set(wasmPC)
+	g.br(uint32(g.n-1-g.cur) + nest)
+}
+
+// jumpIf branches to the block at offset if the i32 on the stack is not 0
+func (g *wasmRunGen) jumpIf(offset int) {
+	g.ifThen(wasm.BlockEmpty)
+	g.jump(offset, 1)
+	g.end()
+}
+
+func (g *wasmRunGen) jumpUnless(offset int) {
+	g.op(wasm.OpI32Eqz)
+	g.jumpIf(offset)
+}
+
+func wasmVar(idx int) (uint32, uint32) {
+	return uint32(wasmVars + 2*idx), uint32(wasmVars + 2*idx + 1)
+}
+
+func (g *wasmRunGen) getVar(idx int) {
+	p, t := wasmVar(idx)
+	g.get(p)
+	g.get(t)
+}
+
+func (g *wasmRunGen) setVar(idx int) {
+	p, t := wasmVar(idx)
+	g.set(t)
+	g.set(p)
+}
+
+// asNumber, asString and asBool make a value of the f64, string address
+// or i32 condition on the stack
+func (g *wasmRunGen) asNumber() {
+	g.i32(tagNumber)
+}
+
+func (g *wasmRunGen) asString() {
+	g.op(wasm.OpF64ConvertI32U)
+	g.i32(tagString)
+}
+
+func (g *wasmRunGen) asBool() {
+	g.op(wasm.OpF64ConvertI32U)
+	g.i32(tagNumber)
+}
+
+func (g *wasmRunGen) constant(idx int) {
+	c := g.l.chunk.Constants[idx]
+	switch {
+	case c.IsNumber():
+		g.f64(c.AsNumber())
+		g.asNumber()
+	case c.IsString():
+		g.f64(float64(g.l.constant(c.String())))
+		g.i32(tagString)
+	default:
+		g.f64(0)
+		g.i32(tagEmpty)
+	}
+}
+
+// compare turns the result of order into the comparison op
+func (g *wasmRunGen) compare(op bytecode.OpCode) {
+	g.call("order")
+	switch op {
+	case bytecode.OpEq:
+		g.op(wasm.OpI32Eqz)
+	case bytecode.OpNeq:
+		g.i32(0)
+		g.op(wasm.OpI32Ne)
+	case bytecode.OpLt:
+		g.i32(-1)
+		g.op(wasm.OpI32Eq)
+	case bytecode.OpGt:
+		g.i32(1)
+		g.op(wasm.OpI32Eq)
+	case bytecode.OpLte:
+		// -1 or 0; unordered is 2
+		g.i32(1)
+		g.op(wasm.OpI32Add)
+		g.i32(2)
+		g.op(wasm.OpI32LtU)
+	case bytecode.OpGte:
+		g.i32(2)
+		g.op(wasm.OpI32LtU)
+	}
+}
+
+// scratch pops n values into the scratch area as numbers
+func (g *wasmRunGen) scratch(n int) {
+	for i := n - 1; i >= 0; i-- {
+		g.call("num")
+		g.set(wasmTemp)
+		g.i32(0)
+		g.get(wasmTemp)
+		g.store(wasm.OpF64Store, uint32(wasmScratch+8*i))
+	}
+}
+
+// element pushes the address of the element of array arr at the n
+// indices on the stack
+func (g *wasmRunGen) element(arr, n int) {
+	g.scratch(n)
+	g.gget(uint32(wasmArrays + arr))
+	g.i32(int32(n))
+	g.str(fmt.Sprintf("array not declared (index %d)", arr))
+	g.call("elem")
+}
+
+// field pushes the address of a slot of the record at the n indices on
+// the stack; the slot is the record array's last index
+func (g *wasmRunGen) field(arr, n, slot int) {
+	g.f64(float64(slot))
+	g.asNumber()
+	g.element(arr, n+1)
+}
+
+// stringSlot reports whether a slot of record array arr holds a string
+func (g *wasmRunGen) stringSlot(arr, slot int) bool {
+	shape := g.l.shapes[arr]
+	return slot < len(shape) && shape[slot] == '$'
+}
+
+// stringArray reports whether array arr holds strings. Its elements are
+// string addresses, and those of a new array point at the empty string at
+// address 0.
+func (g *wasmRunGen) stringArray(arr int) bool {
+	names := g.l.chunk.ArrayNames
+	return arr < len(names) && strings.HasSuffix(names[arr], "$")
+}
+
+// inst lowers one instruction
+func (g *wasmRunGen) inst(inst wasmInst) error {
+	ops := inst.operands
+	pops, pushes := 0, 0
+	switch inst.op {
+	case bytecode.OpConstant:
+		g.constant(ops[0])
+		pushes = 1
+	case bytecode.OpPop:
+		g.op(wasm.OpDrop, wasm.OpDrop)
+		pops = 1
+	case bytecode.OpAdd:
+		g.call("add")
+		pops, pushes = 2, 1
+	case bytecode.OpSub, bytecode.OpMul, bytecode.OpDiv, bytecode.OpPow, bytecode.OpMod:
+		g.call("nums")
+		switch inst.op {
+		case bytecode.OpSub:
+			g.op(wasm.OpF64Sub)
+		case bytecode.OpMul:
+			g.op(wasm.OpF64Mul)
+		case bytecode.OpDiv:
+			g.call("div")
+		case bytecode.OpPow:
+			g.call("pow")
+		case bytecode.OpMod:
+			g.call("fmod")
+		}
+		g.asNumber()
+		pops, pushes = 2, 1
+	case bytecode.OpNeg:
+		g.call("neg")
+		g.asNumber()
+		pops, pushes = 1, 1
+	case bytecode.OpNot:
+		g.call("truth")
+		g.op(wasm.OpI32Eqz)
+		g.asBool()
+		pops, pushes = 1, 1
+	case bytecode.OpAnd, bytecode.OpOr:
+		g.call("truths")
+		if inst.op == bytecode.OpAnd {
+			g.op(wasm.OpI32And)
+		} else {
+			g.op(wasm.OpI32Or)
+		}
+		g.asBool()
+		pops, pushes = 2, 1
+	case bytecode.OpEq, bytecode.OpNeq, bytecode.OpGt, bytecode.OpGte, bytecode.OpLt, bytecode.OpLte:
+		g.compare(inst.op)
+		g.asBool()
+		pops, pushes = 2, 1
+
+	case bytecode.OpJump:
+		if err := g.checkJump(inst, 0); err != nil {
+			return err
+		}
+		g.jump(ops[0], 0)
+		g.reachable = false
+	case bytecode.OpJumpIfFalse:
+		if err := g.checkJump(inst, 1); err != nil {
+			return err
+		}
+		g.call("truth")
+		g.jumpUnless(ops[0])
+		pops = 1
+	case bytecode.OpCmpJump:
+		if err := g.checkJump(inst, 2); err != nil {
+			return err
+		}
+		g.compare(bytecode.OpCode(ops[0]))
+		g.jumpUnless(ops[1])
+		pops = 2
+	case bytecode.OpGosub:
+		if err := g.checkJump(inst, 0); err != nil {
+			return err
+		}
+		g.i32(int32(g.cur + 1))
+		g.call("retpush")
+		g.jump(ops[0], 0)
+		g.reachable = false
+	case bytecode.OpReturn:
+		g.call("retpop")
+		g.set(wasmPC)
+		g.br(uint32(g.n - 1 - g.cur))
+		g.reachable = false
+	case bytecode.OpEnd:
+		g.op(wasm.OpReturn)
+		g.reachable = false
+	case bytecode.OpStop:
+		// The module cannot be continued, so STOP reports the break and ends
+		g.str(fmt.Sprintf("\nBreak in line %d\n", g.l.chunk.Lines[inst.offset]))
+		g.call("print")
+		g.op(wasm.OpReturn)
+		g.reachable = false
+	case bytecode.OpForInit:
+		g.call("nums")
+		g.i32(int32(ops[0]))
+		g.call("forpush")
+		pops = 2
+	case bytecode.OpNext:
+		if err := g.checkJump(inst, 0); err != nil {
+			return err
+		}
+		p, t := wasmVar(ops[0])
+		g.i32(int32(ops[0]))
+		g.call("forstep")
+		g.getVar(ops[0])
+		g.call("num")
+		g.op(wasm.OpF64Add)
+		g.tee(p)
+		g.i32(tagNumber)
+		g.set(t)
+		g.call("foragain")
+		g.jumpIf(ops[1])
+
+	case bytecode.OpGetGlobal:
+		g.getVar(ops[0])
+		pushes = 1
+	case bytecode.OpSetGlobal:
+		g.setVar(ops[0])
+		pops = 1
+	case bytecode.OpGetGlobal2:
+		g.getVar(ops[0])
+		g.getVar(ops[1])
+		pushes = 2
+	case bytecode.OpAddGlobalConst:
+		g.getVar(ops[0])
+		g.constant(ops[1])
+		g.call("add")
+		g.setVar(ops[0])
+	case bytecode.OpIncGlobal:
+		g.getVar(ops[0])
+		g.f64(1)
+		g.asNumber()
+		g.call("add")
+		g.setVar(ops[0])
+
+	case bytecode.OpSaveGlobal:
+		g.getVar(ops[0])
+		g.call("savepush")
+	case bytecode.OpRestoreGlobal:
+		g.call("savepop")
+		g.setVar(ops[0])
+	case bytecode.OpSaveArray:
+		g.f64(0)
+		g.gget(uint32(wasmArrays + ops[0]))
+		g.call("savepush")
+	case bytecode.OpRestoreArray:
+		g.call("savepop")
+		g.gset(uint32(wasmArrays + ops[0]))
+		g.op(wasm.OpDrop)
+
+	case bytecode.OpGetArray:
+		g.element(ops[0], ops[1])
+		g.load(wasm.OpF64Load, 0)
+		if g.stringArray(ops[0]) {
+			g.i32(tagString)
+		} else {
+			g.asNumber()
+		}
+		pops, pushes = ops[1], 1
+	case bytecode.OpSetArray:
+		if g.stringArray(ops[0]) {
+			g.call("str")
+			g.op(wasm.OpF64ConvertI32U)
+		} else {
+			g.call("num")
+		}
+		g.set(wasmTemp2)
+		g.element(ops[0], ops[1])
+		g.get(wasmTemp2)
+		g.store(wasm.OpF64Store, 0)
+		pops = ops[1] + 1
+	case bytecode.OpDim:
+		g.scratch(ops[1])
+		g.i32(int32(ops[1]))
+		g.call("dim")
+		g.gset(uint32(wasmArrays + ops[0]))
+		pops = ops[1]
+
+	case bytecode.OpDimRecord:
+		// The slots are one more dimension
+		g.scratch(ops[1])
+		g.i32(0)
+		g.f64(float64(len(g.l.shapes[ops[0]])))
+		g.store(wasm.OpF64Store, uint32(wasmScratch+8*ops[1]))
+		g.i32(int32(ops[1] + 1))
+		g.call("dim")
+		g.gset(uint32(wasmArrays + ops[0]))
+		pops = ops[1]
+	case bytecode.OpGetField:
+		g.field(ops[0], ops[1], ops[2])
+		g.load(wasm.OpF64Load, 0)
+		if g.stringSlot(ops[0], ops[2]) {
+			g.i32(tagString)
+		} else {
+			g.asNumber()
+		}
+		pops, pushes = ops[1], 1
+	case bytecode.OpSetField:
+		if g.stringSlot(ops[0], ops[2]) {
+			g.call("str")
+			g.op(wasm.OpF64ConvertI32U)
+		} else {
+			g.call("num")
+		}
+		g.set(wasmTemp2)
+		g.field(ops[0], ops[1], ops[2])
+		g.get(wasmTemp2)
+		g.store(wasm.OpF64Store, 0)
+		pops = ops[1] + 1
+	case bytecode.OpCopyRecord:
+		// The source indices are on top
+		g.field(ops[3], ops[4], ops[5])
+		g.set(wasmAddr)
+		g.field(ops[0], ops[1], ops[2])
+		g.get(wasmAddr)
+		g.i32(int32(8 * ops[6]))
+		g.op(wasm.OpMemoryCopy)
+		pops = ops[1] + ops[4]
+
+	case bytecode.OpPrint:
+		g.call("str")
+		g.call("print")
+		pops = 1
+	case bytecode.OpPrintNl:
+		g.str("\n")
+		g.call("print")
+	case bytecode.OpReadInput:
+		g.str(g.l.chunk.Constants[ops[0]].String())
+		g.str(g.l.chunk.Constants[ops[1]].String())
+		g.call("input")
+	case bytecode.OpLineInput:
+		g.str(g.l.chunk.Constants[ops[0]].String())
+		g.call("lineinput")
+	case bytecode.OpInputField:
+		g.call("field")
+		pushes = 1
+	case bytecode.OpCallBuiltin:
+		g.builtin(bytecode.BuiltinNames[ops[0]], ops[1])
+		pops, pushes = ops[1], 1
+	case bytecode.OpCover:
+	default:
+		def, _ := bytecode.Lookup(inst.op)
+		return fmt.Errorf("cannot compile %s to WebAssembly", def.Name)
+	}
+	g.depth += pushes - pops
+	return nil
+}
+
+// checkJump checks that the VM stack is empty once a jump at inst has
+// popped its operands
+func (g *wasmRunGen) checkJump(inst wasmInst, pops int) error {
+	if g.reachable && g.depth != pops {
+		return stackError(g.depth-pops, inst.offset)
+	}
+	return nil
+}
+
+// builtin calls builtin name with argc arguments on the stack
+func (g *wasmRunGen) builtin(name string, argc int) {
+	if min, max := builtinArity(name); argc < min || argc > max {
+		for range argc {
+			g.op(wasm.OpDrop, wasm.OpDrop)
+		}
+		g.fail(arityError(name))
+		g.op(wasm.OpUnreachable)
+		g.reachable = false
+		return
+	}
+	math1 := map[string]wasm.Opcode{"ABS": wasm.OpF64Abs, "INT": wasm.OpF64Trunc}
+	imports := map[string]string{"SIN": "sin", "COS": "cos", "TAN": "tan", "EXP": "exp", "SQR": "sqr", "LOG": "logn"}
+	switch name {
+	case "ABS", "INT":
+		g.call("num")
+		g.op(math1[name])
+		g.asNumber()
+	case "SIN", "COS", "TAN", "EXP", "SQR", "LOG":
+		g.call("num")
+		g.call(imports[name])
+		g.asNumber()
+	case "RND":
+		g.call("random")
+		g.asNumber()
+	case "PI":
+		g.f64(math.Pi)
+		g.asNumber()
+	case "EULER":
+		g.f64(math.E)
+		g.asNumber()
+	case "LEN":
+		g.call("str")
+		g.load(wasm.OpI32Load, 0)
+		g.op(wasm.OpF64ConvertI32U)
+		g.asNumber()
+	case "LEFT$":
+		g.call("left")
+		g.asString()
+	case "RIGHT$":
+		g.call("right")
+		g.asString()
+	case "MID$":
+		if argc == 2 {
+			g.f64(0)
+			g.i32(tagEmpty)
+		}
+		g.i32(int32(argc - 2))
+		g.call("mid")
+		g.asString()
+	case "INSTR":
+		if argc == 2 {
+			g.call("instr2")
+		} else {
+			g.call("instr")
+		}
+		g.asNumber()
+	case "UCASE$", "LCASE$":
+		g.call("str")
+		if name == "UCASE$" {
+			g.call("upper")
+		} else {
+			g.call("lower")
+		}
+		g.asString()
+	case "SPACE$":
+		g.call("space")
+		g.asString()
+	case "CHR$":
+		g.call("chr")
+		g.asString()
+	case "ASC":
+		g.call("asc")
+		g.asNumber()
+	}
+}
@@ -0,0 +1,147 @@
+package codegen_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/codegen"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/wasm"
+)
+
+// wasmBytes compiles prog at the given optimization level and encodes the
+// module, checking that it validates and survives a decode round trip
+func wasmBytes(t *testing.T, prog *ast.Program, level int) []byte {
+	t.Helper()
+	chunk, err := compiler.New(compiler.WithOptimization(level)).Compile(prog)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	m, err := codegen.Wasm(chunk)
+	if err != nil {
+		t.Fatalf("codegen: %v", err)
+	}
+	data, err := m.Encode()
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	back, err := wasm.Decode(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if err := back.Validate(); err != nil {
+		t.Fatalf("decoded module does not validate: %v", err)
+	}
+	if !reflect.DeepEqual(back, m) {
+		t.Fatalf("decoded module differs from the encoded one")
+	}
+	return data
+}
+
+// runWasm compiles prog to a module and runs it with node and WasmHost
+func runWasm(t *testing.T, prog *ast.Program) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	module := filepath.Join(dir, "prog.wasm")
+	if err := os.WriteFile(module, wasmBytes(t, prog, 0), 0644); err != nil {
+		t.Fatal(err)
+	}
+	host := filepath.Join(dir, "host.mjs")
+	if err := os.WriteFile(host, codegen.WasmHost(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	run := exec.Command("node", host, module)
+	run.Stdin = strings.NewReader(input)
+	run.Stdout, run.Stderr = &stdout, &stderr
+	err := run.Run()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatal(err)
+	}
+	return stdout.String(), strings.TrimSpace(stderr.String())
+}
+
+// TestWasmValidates lowers every sample and semantics program at each
+// optimization level, without running them
+func TestWasmValidates(t *testing.T) {
+	files, err := filepath.Glob("../../samples/*.bas")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no samples: %v", err)
+	}
+	var progs []*ast.Program
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		progs = append(progs, parse(t, file, string(data)))
+	}
+	for _, tt := range semantics {
+		progs = append(progs, parse(t, tt.name, tt.src))
+	}
+	for _, prog := range progs {
+		for level := 0; level <= 2; level++ {
+			wasmBytes(t, prog, level)
+		}
+	}
+}
+
+func TestWasmSamples(t *testing.T) {
+	needNode(t)
+	files, err := filepath.Glob("../../samples/*.bas")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no samples: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			t.Parallel()
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prog := parse(t, file, string(data))
+			got, gotErr := runWasm(t, prog)
+			if inexact.MatchString(string(data)) {
+				return
+			}
+			want, wantErr := runVM(t, prog)
+			if got != want || gotErr != wantErr {
+				t.Errorf("output differs from the VM\n got: %q %s\nwant: %q %s", got, gotErr, want, wantErr)
+			}
+		})
+	}
+}
+
+func TestWasmSemantics(t *testing.T) {
+	needNode(t)
+	for _, tt := range semantics {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			prog := parse(t, tt.name, tt.src)
+			want, wantErr := runVM(t, prog)
+			got, gotErr := runWasm(t, prog)
+			if got != want || gotErr != wantErr {
+				t.Errorf("output differs from the VM\n got: %q %s\nwant: %q %s", got, gotErr, want, wantErr)
+			}
+		})
+	}
+}
+
+func TestWasmRejectsRegisters(t *testing.T) {
+	chunk, err := compiler.New(compiler.WithRegisters()).Compile(parse(t, "r.bas", "10 PRINT 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := codegen.Wasm(chunk); err == nil || !strings.Contains(err.Error(), "register bytecode") {
+		t.Errorf("Wasm(register chunk) err = %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package codegen
+
+// WasmHost returns an ES module that runs the modules Wasm builds in a
+// JavaScript engine. It exports run(source, io), the counterpart of the run
+// function of zb build's modules, and when started by node as
+// node host.mjs prog.wasm it runs prog.wasm on standard input and output.
+func WasmHost() []byte {
+	return []byte(wasmHost)
+}
+
+const wasmHost = `// Code generated by zb. DO NOT EDIT.
+//
+// Host for WebAssembly modules compiled with zb -o prog.wasm:
+//
+//   import { run } from "./host.mjs";
+//   await run(fetch("prog.wasm"), { print: (text) => ..., input: () => prompt() });
+//
+// or from a shell: node host.mjs prog.wasm
+` + jsErrors + jsTerminal + jsNumbers + `
+// ---- WebAssembly host ----
+
+const utf8 = new TextEncoder();
+const utf8Decoder = new TextDecoder();
+
+// run runs a module compiled by zb -o prog.wasm. source is a BufferSource,
+// a WebAssembly.Module or a fetch Response, or a promise of one. Output
+// goes to io.print as for modules from zb build, but io.input must return
+// the INPUT line directly: WebAssembly cannot wait for a promise.
+export async function run(source, io = {}) {
+  let exports;
+  let message;
+  const term = new Terminal(io);
+
+  // Strings are a u32 byte length followed by the UTF-8 bytes
+  const text = (ptr) => {
+    const length = new DataView(exports.memory.buffer).getUint32(ptr, true);
+    return utf8Decoder.decode(new Uint8Array(exports.memory.buffer, ptr + 4, length));
+  };
+  const string = (s) => {
+    const data = utf8.encode(s);
+    const ptr = exports.alloc(data.length + 4);
+    new DataView(exports.memory.buffer).setUint32(ptr, data.length, true);
+    new Uint8Array(exports.memory.buffer).set(data, ptr + 4);
+    return ptr;
+  };
+
+  // INPUT lines come from io.input; fields holds those not yet stored
+  let fields = [];
+  const readLine = (prompt) => {
+    term.write(prompt);
+    term.flush();
+    const line = io.input ? io.input() : undefined;
+    if (line === undefined || line === null) {
+      fail("Input past end");
+    }
+    return String(line).replace(/\r?\n$/, "");
+  };
+
+  // Values are a payload and a tag: 1 for numbers, 2 for strings
+  const basic = {
+    print: (ptr) => term.write(text(ptr)),
+    input: (prompt, types) => {
+      for (;;) {
+        const values = parseInput(readLine(text(prompt)), text(types));
+        if (values !== undefined) {
+          fields = values;
+          return;
+        }
+        term.write("?Redo from start\n");
+      }
+    },
+    lineinput: (prompt) => {
+      fields = [readLine(text(prompt))];
+    },
+    field: () => {
+      const v = fields.shift();
+      return typeof v === "string" ? [string(v), 2] : [v, 1];
+    },
+    error: (ptr) => {
+      message = text(ptr);
+    },
+    format: (n) => string(fmtNum(n)),
+    parse: (ptr) => toNumber(text(ptr)),
+    upper: (ptr) => string(text(ptr).toUpperCase()),
+    lower: (ptr) => string(text(ptr).toLowerCase()),
+    sin: Math.sin,
+    cos: Math.cos,
+    tan: Math.tan,
+    exp: Math.exp,
+    log: Math.log,
+    pow: Math.pow,
+    fmod: (x, y) => x % y,
+    random: Math.random,
+  };
+
+  source = await source;
+  let instance;
+  if (source instanceof WebAssembly.Module) {
+    instance = await WebAssembly.instantiate(source, { basic });
+  } else if (typeof Response !== "undefined" && source instanceof Response) {
+    ({ instance } = await WebAssembly.instantiateStreaming(source, { basic }));
+  } else {
+    ({ instance } = await WebAssembly.instantiate(source, { basic }));
+  }
+  exports = instance.exports;
+  try {
+    exports.run();
+  } catch (e) {
+    // Runtime errors report their message and then trap
+    if (message !== undefined) {
+      throw new BasicError(message);
+    }
+    throw e;
+  } finally {
+    term.flush();
+  }
+}
+
+if (globalThis.process?.argv?.[1]) {
+  const { pathToFileURL } = await import("node:url");
+  if (import.meta.url === pathToFileURL(process.argv[1]).href) {
+    await main(process.argv.slice(2));
+  }
+}
+
+// main runs the module named by args[0] on standard input and output
+async function main(args) {
+  const fs = await import("node:fs");
+  if (args.length !== 1) {
+    console.error("usage: node host.mjs prog.wasm");
+    process.exitCode = 2;
+    return;
+  }
+  try {
+    await run(fs.readFileSync(args[0]), {
+      print: (text) => process.stdout.write(text),
+      input: stdinLines(fs),
+    });
+  } catch (e) {
+    if (!(e instanceof BasicError)) {
+      throw e;
+    }
+    console.error("Runtime error: " + e.message);
+    process.exitCode = 1;
+  }
+}
+
+// stdinLines returns a function that reads the next line of standard input
+// synchronously, or undefined at the end
+function stdinLines(fs) {
+  const chunk = new Uint8Array(4096);
+  const decoder = new TextDecoder();
+  let buffered = "";
+  let eof = false;
+  return () => {
+    for (;;) {
+      const nl = buffered.indexOf("\n");
+      if (nl >= 0) {
+        const line = buffered.slice(0, nl);
+        buffered = buffered.slice(nl + 1);
+        return line;
+      }
+      if (eof) {
+        const line = buffered;
+        buffered = "";
+        return line === "" ? undefined : line;
+      }
+      let n;
+      try {
+        n = fs.readSync(0, chunk);
+      } catch (e) {
+        if (e.code === "EAGAIN") {
+          continue;
+        }
+        if (e.code !== "EOF") {
+          throw e;
+        }
+        n = 0;
+      }
+      if (n === 0) {
+        eof = true;
+      } else {
+        buffered += decoder.decode(chunk.subarray(0, n), { stream: true });
+      }
+    }
+  };
+}
+`
@@ -0,0 +1,1281 @@
+package codegen
+
+import (
+	"math"
+
+	"zork-basic/internal/wasm"
+)
+
+// Memory layout of the modules Wasm builds. Strings are a little-endian
+// u32 byte length followed by the UTF-8 bytes; string constants sit in
+// the data segment and everything built at run time is bump-allocated
+// from the heap above it and never freed. The first 8 bytes stay zero, so
+// address 0 is an empty string.
+const (
+	wasmScratch  = 8      // Indices or sizes of one array access or DIM, 8 bytes each
+	wasmDigits   = 2048   // itoa writes its digits backwards from wasmDigits+24
+	wasmForBase  = 4096   // FOR stack: 24-byte frames of variable, end and step
+	wasmForMax   = 4096   // FOR stack capacity
+	wasmRetBase  = 102400 // GOSUB stack: u32 block numbers
+	wasmRetMax   = 16384  // GOSUB stack capacity
+	wasmSaveBase = 167936 // Locals saved around recursive calls: 16-byte frames of payload and tag
+	wasmSaveMax  = 16384  // Save stack capacity
+	wasmDataBase = 430080 // String constants
+	wasmMaxPages = 32768  // 2 GiB, so heap addresses never overflow
+	wasmMaxAlloc = 0x7fff0000
+	wasmMaxElems = 1 << 28 // Largest array, in elements
+)
+
+// Globals of every module; the array globals follow. An array global is 0
+// until its DIM runs and then points at [u32 ndims, pad, i64 dims...,
+// f64 data...].
+const (
+	wasmHeap = iota // Next free byte
+	wasmFsp         // FOR frames in use
+	wasmRsp         // GOSUB return points in use
+	wasmSsp         // Saved values in use
+	wasmArrays
+)
+
+var (
+	wasmValue = []wasm.ValType{wasm.F64, wasm.I32} // A BASIC value: payload and tag
+	wasmPair  = []wasm.ValType{wasm.F64, wasm.I32, wasm.F64, wasm.I32}
+)
+
+// Value tags. The payload of a number is the number and that of a string
+// the address of the string.
+const (
+	tagEmpty = iota
+	tagNumber
+	tagString
+)
+
+// wasmFunc is an imported or runtime function
+type wasmFunc struct {
+	name    string
+	params  []wasm.ValType
+	results []wasm.ValType
+	locals  []wasm.ValType   // Runtime functions only
+	body    func(a *wasmAsm) // Runtime functions only
+}
+
+func valTypes(ts ...wasm.ValType) []wasm.ValType {
+	return ts
+}
+
+func joinTypes(lists ...[]wasm.ValType) []wasm.ValType {
+	var out []wasm.ValType
+	for _, l := range lists {
+		out = append(out, l...)
+	}
+	return out
+}
+
+// wasmImports are the host functions, imported from module "basic". The
+// host allocates the strings it returns with the exported alloc.
+var wasmImports = []wasmFunc{
+	{name: "print", params: valTypes(wasm.I32)},                               // Write a string
+	{name: "input", params: valTypes(wasm.I32, wasm.I32)},                     // Prompt until a line matches the field types (OpReadInput)
+	{name: "lineinput", params: valTypes(wasm.I32)},                           // Prompt and read a whole line (OpLineInput)
+	{name: "field", results: wasmValue},                                       // The next INPUT field
+	{name: "error", params: valTypes(wasm.I32)},                               // Record the runtime error the module traps with
+	{name: "format", params: valTypes(wasm.F64), results: valTypes(wasm.I32)}, // Format a number like the VM
+	{name: "parse", params: valTypes(wasm.I32), results: valTypes(wasm.F64)},  // Convert a string to a number like the VM
+	{name: "upper", params: valTypes(wasm.I32), results: valTypes(wasm.I32)},  // UCASE$
+	{name: "lower", params: valTypes(wasm.I32), results: valTypes(wasm.I32)},  // LCASE$
+	{name: "sin", params: valTypes(wasm.F64), results: valTypes(wasm.F64)},
+	{name: "cos", params: valTypes(wasm.F64), results: valTypes(wasm.F64)},
+	{name: "tan", params: valTypes(wasm.F64), results: valTypes(wasm.F64)},
+	{name: "exp", params: valTypes(wasm.F64), results: valTypes(wasm.F64)},
+	{name: "log", params: valTypes(wasm.F64), results: valTypes(wasm.F64)},
+	{name: "pow", params: valTypes(wasm.F64, wasm.F64), results: valTypes(wasm.F64)},
+	{name: "fmod", params: valTypes(wasm.F64, wasm.F64), results: valTypes(wasm.F64)},
+	{name: "random", results: valTypes(wasm.F64)},
+}
+
+// wasmRuntime is linked into every module. Like goRuntime and jsRuntime it
+// mirrors the VM: value conversions and operators, arrays, the FOR and
+// GOSUB stacks and the builtins. Runtime errors call the error import and
+// trap.
+var wasmRuntime = []wasmFunc{
+	// alloc(n) returns n zeroed bytes, growing memory as needed
+	{name: "alloc", params: valTypes(wasm.I32), results: valTypes(wasm.I32), locals: valTypes(wasm.I32, wasm.I32, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.i32(wasmMaxAlloc)
+			a.op(wasm.OpI32GtU)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("out of memory")
+			a.end()
+			a.gget(wasmHeap)
+			a.tee(1)
+			a.get(0)
+			a.op(wasm.OpI32Add)
+			a.i32(7)
+			a.op(wasm.OpI32Add)
+			a.i32(-8)
+			a.op(wasm.OpI32And)
+			a.tee(2)
+			// Pages missing: ceil(end / 64 KiB) - memory.size
+			a.i32(wasm.PageSize - 1)
+			a.op(wasm.OpI32Add)
+			a.i32(16)
+			a.op(wasm.OpI32ShrU, wasm.OpMemorySize, wasm.OpI32Sub)
+			a.tee(3)
+			a.i32(0)
+			a.op(wasm.OpI32GtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(3)
+			a.op(wasm.OpMemoryGrow)
+			a.i32(-1)
+			a.op(wasm.OpI32Eq)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("out of memory")
+			a.end()
+			a.end()
+			a.get(2)
+			a.gset(wasmHeap)
+			a.get(1)
+		}},
+	// fail(msg) reports a runtime error and traps
+	{name: "fail", params: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.call("error")
+			a.op(wasm.OpUnreachable)
+		}},
+	// strnew(n) allocates a string of n bytes
+	{name: "strnew", params: valTypes(wasm.I32), results: valTypes(wasm.I32), locals: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.call("alloc")
+			a.tee(1)
+			a.get(0)
+			a.store(wasm.OpI32Store, 0)
+			a.get(1)
+		}},
+	// substr(s, from, to) copies bytes from to to of s
+	{name: "substr", params: valTypes(wasm.I32, wasm.I32, wasm.I32), results: valTypes(wasm.I32), locals: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(2)
+			a.get(1)
+			a.op(wasm.OpI32Sub)
+			a.call("strnew")
+			a.tee(3)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.get(0)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.get(1)
+			a.op(wasm.OpI32Add)
+			a.get(2)
+			a.get(1)
+			a.op(wasm.OpI32Sub, wasm.OpMemoryCopy)
+			a.get(3)
+		}},
+	// concat(a, b) returns a new string a+b
+	{name: "concat", params: valTypes(wasm.I32, wasm.I32), results: valTypes(wasm.I32), locals: valTypes(wasm.I32, wasm.I32, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.load(wasm.OpI32Load, 0)
+			a.set(2)
+			a.get(1)
+			a.load(wasm.OpI32Load, 0)
+			a.set(3)
+			a.get(2)
+			a.get(3)
+			a.op(wasm.OpI32Add)
+			a.call("strnew")
+			a.tee(4)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.get(0)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.get(2)
+			a.op(wasm.OpMemoryCopy)
+			a.get(4)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.get(2)
+			a.op(wasm.OpI32Add)
+			a.get(1)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.get(3)
+			a.op(wasm.OpMemoryCopy)
+			a.get(4)
+		}},
+	// strcmp(a, b) compares bytes like Go's string comparison: -1, 0 or 1
+	{name: "strcmp", params: valTypes(wasm.I32, wasm.I32), results: valTypes(wasm.I32),
+		locals: valTypes(wasm.I32, wasm.I32, wasm.I32, wasm.I32, wasm.I32, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.load(wasm.OpI32Load, 0)
+			a.set(2)
+			a.get(1)
+			a.load(wasm.OpI32Load, 0)
+			a.set(3)
+			a.get(2)
+			a.get(3)
+			a.get(2)
+			a.get(3)
+			a.op(wasm.OpI32LtU, wasm.OpSelect)
+			a.set(4) // Common length
+			a.block()
+			a.loop()
+			a.get(5)
+			a.get(4)
+			a.op(wasm.OpI32GeU)
+			a.brIf(1)
+			a.get(0)
+			a.get(5)
+			a.op(wasm.OpI32Add)
+			a.load(wasm.OpI32Load8U, 4)
+			a.set(6)
+			a.get(1)
+			a.get(5)
+			a.op(wasm.OpI32Add)
+			a.load(wasm.OpI32Load8U, 4)
+			a.set(7)
+			a.get(6)
+			a.get(7)
+			a.op(wasm.OpI32Ne)
+			a.ifThen(wasm.BlockEmpty)
+			a.i32(1)
+			a.i32(-1)
+			a.get(6)
+			a.get(7)
+			a.op(wasm.OpI32GtU, wasm.OpSelect, wasm.OpReturn)
+			a.end()
+			a.get(5)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.set(5)
+			a.br(0)
+			a.end()
+			a.end()
+			// Equal up to the shorter length: the longer string is greater
+			a.get(2)
+			a.get(3)
+			a.op(wasm.OpI32GtU)
+			a.get(2)
+			a.get(3)
+			a.op(wasm.OpI32LtU, wasm.OpI32Sub)
+		}},
+	// itoa(n) formats an integer like %d
+	{name: "itoa", params: valTypes(wasm.I64), results: valTypes(wasm.I32),
+		locals: valTypes(wasm.I32, wasm.I32, wasm.I64, wasm.I32, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.i64(0)
+			a.op(wasm.OpI64LtS)
+			a.set(1)
+			a.i32(wasmDigits + 24)
+			a.set(2)
+			a.loop()
+			a.get(0)
+			a.i64(10)
+			a.op(wasm.OpI64RemS)
+			a.set(3)
+			a.get(1)
+			a.ifThen(wasm.BlockEmpty)
+			a.i64(0)
+			a.get(3)
+			a.op(wasm.OpI64Sub)
+			a.set(3)
+			a.end()
+			a.get(2)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.tee(2)
+			a.get(3)
+			a.op(wasm.OpI32WrapI64)
+			a.i32('0')
+			a.op(wasm.OpI32Add)
+			a.store(wasm.OpI32Store8, 0)
+			a.get(0)
+			a.i64(10)
+			a.op(wasm.OpI64DivS)
+			a.tee(0)
+			a.i64(0)
+			a.op(wasm.OpI64Ne)
+			a.brIf(0)
+			a.end()
+			a.get(1)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(2)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.tee(2)
+			a.i32('-')
+			a.store(wasm.OpI32Store8, 0)
+			a.end()
+			a.i32(wasmDigits + 24)
+			a.get(2)
+			a.op(wasm.OpI32Sub)
+			a.tee(4)
+			a.call("strnew")
+			a.tee(5)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.get(2)
+			a.get(4)
+			a.op(wasm.OpMemoryCopy)
+			a.get(5)
+		}},
+	// num(v) converts a value to a number like Value.AsNumber
+	{name: "num", params: wasmValue, results: valTypes(wasm.F64),
+		body: func(a *wasmAsm) {
+			a.isTag(1, tagNumber)
+			a.ifThen(wasm.BlockOf(wasm.F64))
+			a.get(0)
+			a.els()
+			a.isTag(1, tagString)
+			a.ifThen(wasm.BlockOf(wasm.F64))
+			a.get(0)
+			a.op(wasm.OpI32TruncSatF64U)
+			a.call("parse")
+			a.els()
+			a.f64(0)
+			a.end()
+			a.end()
+		}},
+	// str(v) converts a value to a string like Value.String
+	{name: "str", params: wasmValue, results: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.isTag(1, tagString)
+			a.ifThen(wasm.BlockOf(wasm.I32))
+			a.get(0)
+			a.op(wasm.OpI32TruncSatF64U)
+			a.els()
+			a.isTag(1, tagNumber)
+			a.ifThen(wasm.BlockOf(wasm.I32))
+			a.get(0)
+			a.call("format")
+			a.els()
+			a.str("")
+			a.end()
+			a.end()
+		}},
+	// truth(v) reports whether v is a non-zero number or non-empty string
+	{name: "truth", params: wasmValue, results: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.isTag(1, tagNumber)
+			a.ifThen(wasm.BlockOf(wasm.I32))
+			a.get(0)
+			a.f64(0)
+			a.op(wasm.OpF64Ne)
+			a.els()
+			a.isTag(1, tagString)
+			a.ifThen(wasm.BlockOf(wasm.I32))
+			a.get(0)
+			a.op(wasm.OpI32TruncSatF64U)
+			a.load(wasm.OpI32Load, 0)
+			a.i32(0)
+			a.op(wasm.OpI32Ne)
+			a.els()
+			a.i32(0)
+			a.end()
+			a.end()
+		}},
+	// nums and truths convert two values at once
+	{name: "nums", params: wasmPair, results: valTypes(wasm.F64, wasm.F64),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.get(1)
+			a.call("num")
+			a.get(2)
+			a.get(3)
+			a.call("num")
+		}},
+	{name: "truths", params: wasmPair, results: valTypes(wasm.I32, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.get(1)
+			a.call("truth")
+			a.get(2)
+			a.get(3)
+			a.call("truth")
+		}},
+	// toint(x) truncates like Go's int(x) on amd64: NaN and values out of
+	// range become the smallest int64
+	{name: "toint", params: valTypes(wasm.F64), results: valTypes(wasm.I64),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.f64(-(1 << 63))
+			a.op(wasm.OpF64Ge)
+			a.get(0)
+			a.f64(1 << 63)
+			a.op(wasm.OpF64Lt, wasm.OpI32And)
+			a.ifThen(wasm.BlockOf(wasm.I64))
+			a.get(0)
+			a.op(wasm.OpI64TruncF64S)
+			a.els()
+			a.i64(math.MinInt64)
+			a.end()
+		}},
+	// fcmp(x, y) orders two numbers: -1, 0, 1, or 2 if either is NaN
+	{name: "fcmp", params: valTypes(wasm.F64, wasm.F64), results: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			for _, c := range []struct {
+				op     wasm.Opcode
+				result int32
+			}{{wasm.OpF64Lt, -1}, {wasm.OpF64Gt, 1}, {wasm.OpF64Eq, 0}} {
+				a.get(0)
+				a.get(1)
+				a.op(c.op)
+				a.ifThen(wasm.BlockEmpty)
+				a.i32(c.result)
+				a.op(wasm.OpReturn)
+				a.end()
+			}
+			a.i32(2)
+		}},
+	// order(a, b) orders two values like the comparison operators: as
+	// strings if either is a string, as numbers otherwise
+	{name: "order", params: wasmPair, results: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.isTag(1, tagNumber)
+			a.isTag(3, tagNumber)
+			a.op(wasm.OpI32And)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(0)
+			a.get(2)
+			a.call("fcmp")
+			a.op(wasm.OpReturn)
+			a.end()
+			a.isTag(1, tagString)
+			a.isTag(3, tagString)
+			a.op(wasm.OpI32Or)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(0)
+			a.get(1)
+			a.call("str")
+			a.get(2)
+			a.get(3)
+			a.call("str")
+			a.call("strcmp")
+			a.op(wasm.OpReturn)
+			a.end()
+			a.getAll(4)
+			a.call("nums")
+			a.call("fcmp")
+		}},
+	// add(a, b) adds numbers and concatenates anything involving a string
+	{name: "add", params: wasmPair, results: wasmValue,
+		body: func(a *wasmAsm) {
+			a.isTag(1, tagNumber)
+			a.isTag(3, tagNumber)
+			a.op(wasm.OpI32And)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(0)
+			a.get(2)
+			a.op(wasm.OpF64Add)
+			a.i32(tagNumber)
+			a.op(wasm.OpReturn)
+			a.end()
+			a.isTag(1, tagString)
+			a.isTag(3, tagString)
+			a.op(wasm.OpI32Or)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(0)
+			a.get(1)
+			a.call("str")
+			a.get(2)
+			a.get(3)
+			a.call("str")
+			a.call("concat")
+			a.op(wasm.OpF64ConvertI32U)
+			a.i32(tagString)
+			a.op(wasm.OpReturn)
+			a.end()
+			a.getAll(4)
+			a.call("nums")
+			a.op(wasm.OpF64Add)
+			a.i32(tagNumber)
+		}},
+	{name: "neg", params: wasmValue, results: valTypes(wasm.F64),
+		body: func(a *wasmAsm) {
+			a.get(1)
+			a.i32(tagNumber)
+			a.op(wasm.OpI32Ne)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("operand must be a number")
+			a.end()
+			a.get(0)
+			a.op(wasm.OpF64Neg)
+		}},
+	{name: "div", params: valTypes(wasm.F64, wasm.F64), results: valTypes(wasm.F64),
+		body: func(a *wasmAsm) {
+			a.get(1)
+			a.f64(0)
+			a.op(wasm.OpF64Eq)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("division by zero")
+			a.end()
+			a.get(0)
+			a.get(1)
+			a.op(wasm.OpF64Div)
+		}},
+	// dim(n) builds an array from the n sizes in the scratch area
+	{name: "dim", params: valTypes(wasm.I32), results: valTypes(wasm.I32),
+		locals: valTypes(wasm.I32, wasm.I64, wasm.I64, wasm.I32),
+		body: func(a *wasmAsm) {
+			// Convert and check the sizes from the last to the first, as
+			// the VM pops them
+			a.get(0)
+			a.set(1)
+			a.block()
+			a.loop()
+			a.get(1)
+			a.op(wasm.OpI32Eqz)
+			a.brIf(1)
+			a.get(1)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.tee(1)
+			a.i32(3)
+			a.op(wasm.OpI32Shl)
+			a.load(wasm.OpF64Load, wasmScratch)
+			a.call("toint")
+			a.tee(2)
+			a.i64(0)
+			a.op(wasm.OpI64LtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.str("negative array dimension: ")
+			a.get(2)
+			a.call("itoa")
+			a.call("concat")
+			a.call("fail")
+			a.end()
+			a.get(1)
+			a.i32(3)
+			a.op(wasm.OpI32Shl)
+			a.get(2)
+			a.store(wasm.OpI64Store, wasmScratch)
+			a.br(0)
+			a.end()
+			a.end()
+			// Multiply them out, failing before the count gets too large
+			a.i64(1)
+			a.set(3)
+			a.block()
+			a.loop()
+			a.get(1)
+			a.get(0)
+			a.op(wasm.OpI32GeU)
+			a.brIf(1)
+			a.get(1)
+			a.i32(3)
+			a.op(wasm.OpI32Shl)
+			a.load(wasm.OpI64Load, wasmScratch)
+			a.tee(2)
+			a.i64(0)
+			a.op(wasm.OpI64Ne)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(3)
+			a.i64(wasmMaxElems)
+			a.get(2)
+			a.op(wasm.OpI64DivU, wasm.OpI64GtU)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("out of memory")
+			a.end()
+			a.end()
+			a.get(3)
+			a.get(2)
+			a.op(wasm.OpI64Mul)
+			a.set(3)
+			a.get(1)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.set(1)
+			a.br(0)
+			a.end()
+			a.end()
+			// Header and data
+			a.get(0)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.get(3)
+			a.op(wasm.OpI32WrapI64, wasm.OpI32Add)
+			a.i32(3)
+			a.op(wasm.OpI32Shl)
+			a.call("alloc")
+			a.tee(4)
+			a.get(0)
+			a.store(wasm.OpI32Store, 0)
+			a.get(4)
+			a.i32(8)
+			a.op(wasm.OpI32Add)
+			a.i32(wasmScratch)
+			a.get(0)
+			a.i32(3)
+			a.op(wasm.OpI32Shl, wasm.OpMemoryCopy)
+			a.get(4)
+		}},
+	// elem(array, n, msg) returns the address of the element at the n
+	// indices in the scratch area, failing with msg if the array has not
+	// been declared
+	{name: "elem", params: valTypes(wasm.I32, wasm.I32, wasm.I32), results: valTypes(wasm.I32),
+		locals: valTypes(wasm.I32, wasm.I64, wasm.I64, wasm.I64, wasm.I64),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.op(wasm.OpI32Eqz)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(2)
+			a.call("fail")
+			a.end()
+			a.get(0)
+			a.load(wasm.OpI32Load, 0)
+			a.get(1)
+			a.op(wasm.OpI32Ne)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("array index out of bounds")
+			a.end()
+			a.i64(1)
+			a.set(7)
+			a.get(1)
+			a.set(3)
+			a.block()
+			a.loop()
+			a.get(3)
+			a.op(wasm.OpI32Eqz)
+			a.brIf(1)
+			a.get(3)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.tee(3)
+			a.i32(3)
+			a.op(wasm.OpI32Shl)
+			a.load(wasm.OpF64Load, wasmScratch)
+			a.call("toint")
+			a.set(4)
+			a.get(0)
+			a.get(3)
+			a.i32(3)
+			a.op(wasm.OpI32Shl, wasm.OpI32Add)
+			a.load(wasm.OpI64Load, 8)
+			a.set(5)
+			a.get(4)
+			a.i64(0)
+			a.op(wasm.OpI64LtS)
+			a.get(4)
+			a.get(5)
+			a.op(wasm.OpI64GeS, wasm.OpI32Or)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("array index out of bounds")
+			a.end()
+			a.get(6)
+			a.get(4)
+			a.get(7)
+			a.op(wasm.OpI64Mul, wasm.OpI64Add)
+			a.set(6)
+			a.get(7)
+			a.get(5)
+			a.op(wasm.OpI64Mul)
+			a.set(7)
+			a.br(0)
+			a.end()
+			a.end()
+			a.get(0)
+			a.get(1)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.get(6)
+			a.op(wasm.OpI32WrapI64, wasm.OpI32Add)
+			a.i32(3)
+			a.op(wasm.OpI32Shl, wasm.OpI32Add)
+		}},
+	// forpush(end, step, var) starts a FOR loop
+	{name: "forpush", params: valTypes(wasm.F64, wasm.F64, wasm.I32), locals: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.gget(wasmFsp)
+			a.i32(wasmForMax)
+			a.op(wasm.OpI32GeU)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("stack overflow")
+			a.end()
+			a.gget(wasmFsp)
+			a.i32(24)
+			a.op(wasm.OpI32Mul)
+			a.tee(3)
+			a.get(2)
+			a.store(wasm.OpI32Store, wasmForBase)
+			a.get(3)
+			a.get(0)
+			a.store(wasm.OpF64Store, wasmForBase+8)
+			a.get(3)
+			a.get(1)
+			a.store(wasm.OpF64Store, wasmForBase+16)
+			a.gget(wasmFsp)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.gset(wasmFsp)
+		}},
+	// forstep(var) checks that NEXT var closes the innermost loop and
+	// returns its step
+	{name: "forstep", params: valTypes(wasm.I32), results: valTypes(wasm.F64), locals: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.gget(wasmFsp)
+			a.op(wasm.OpI32Eqz)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("NEXT without FOR")
+			a.end()
+			a.forTop()
+			a.tee(1)
+			a.load(wasm.OpI32Load, wasmForBase)
+			a.get(0)
+			a.op(wasm.OpI32Ne)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("NEXT variable mismatch")
+			a.end()
+			a.get(1)
+			a.load(wasm.OpF64Load, wasmForBase+16)
+		}},
+	// foragain(x) reports whether the innermost loop runs again with its
+	// variable at x, and pops the loop when it does not
+	{name: "foragain", params: valTypes(wasm.F64), results: valTypes(wasm.I32), locals: valTypes(wasm.I32, wasm.F64),
+		body: func(a *wasmAsm) {
+			a.forTop()
+			a.tee(1)
+			a.load(wasm.OpF64Load, wasmForBase+16)
+			a.set(2)
+			for _, c := range [][2]wasm.Opcode{{wasm.OpF64Gt, wasm.OpF64Le}, {wasm.OpF64Lt, wasm.OpF64Ge}} {
+				a.get(2)
+				a.f64(0)
+				a.op(c[0])
+				a.get(0)
+				a.get(1)
+				a.load(wasm.OpF64Load, wasmForBase+8)
+				a.op(c[1], wasm.OpI32And)
+			}
+			a.op(wasm.OpI32Or)
+			a.ifThen(wasm.BlockEmpty)
+			a.i32(1)
+			a.op(wasm.OpReturn)
+			a.end()
+			a.gget(wasmFsp)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.gset(wasmFsp)
+			a.i32(0)
+		}},
+	// retpush and retpop keep the block numbers GOSUB returns to
+	{name: "retpush", params: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.gget(wasmRsp)
+			a.i32(wasmRetMax)
+			a.op(wasm.OpI32GeU)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("stack overflow")
+			a.end()
+			a.gget(wasmRsp)
+			a.i32(2)
+			a.op(wasm.OpI32Shl)
+			a.get(0)
+			a.store(wasm.OpI32Store, wasmRetBase)
+			a.gget(wasmRsp)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.gset(wasmRsp)
+		}},
+	{name: "retpop", results: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.gget(wasmRsp)
+			a.op(wasm.OpI32Eqz)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("return without gosub")
+			a.end()
+			a.gget(wasmRsp)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.gset(wasmRsp)
+			a.gget(wasmRsp)
+			a.i32(2)
+			a.op(wasm.OpI32Shl)
+			a.load(wasm.OpI32Load, wasmRetBase)
+		}},
+	// savepush and savepop keep the locals saved around recursive calls;
+	// an array is saved as its address in the tag
+	{name: "savepush", params: wasmValue, locals: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.gget(wasmSsp)
+			a.i32(wasmSaveMax)
+			a.op(wasm.OpI32GeU)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("stack overflow")
+			a.end()
+			a.gget(wasmSsp)
+			a.i32(4)
+			a.op(wasm.OpI32Shl)
+			a.tee(2)
+			a.get(0)
+			a.store(wasm.OpF64Store, wasmSaveBase)
+			a.get(2)
+			a.get(1)
+			a.store(wasm.OpI32Store, wasmSaveBase+8)
+			a.gget(wasmSsp)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.gset(wasmSsp)
+		}},
+	{name: "savepop", results: wasmValue, locals: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.gget(wasmSsp)
+			a.op(wasm.OpI32Eqz)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("restore without save")
+			a.end()
+			a.gget(wasmSsp)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.gset(wasmSsp)
+			a.gget(wasmSsp)
+			a.i32(4)
+			a.op(wasm.OpI32Shl)
+			a.tee(0)
+			a.load(wasm.OpF64Load, wasmSaveBase)
+			a.get(0)
+			a.load(wasm.OpI32Load, wasmSaveBase+8)
+		}},
+	{name: "sqr", params: valTypes(wasm.F64), results: valTypes(wasm.F64),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.f64(0)
+			a.op(wasm.OpF64Lt)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("SQR of negative number")
+			a.end()
+			a.get(0)
+			a.op(wasm.OpF64Sqrt)
+		}},
+	{name: "logn", params: valTypes(wasm.F64), results: valTypes(wasm.F64),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.f64(0)
+			a.op(wasm.OpF64Le)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("LOG of non-positive number")
+			a.end()
+			a.get(0)
+			a.call("log")
+		}},
+	// left and right: s, n -> string
+	{name: "left", params: wasmPair, results: valTypes(wasm.I32), locals: valTypes(wasm.I32, wasm.I64),
+		body: func(a *wasmAsm) {
+			a.clampLen()
+			a.get(4)
+			a.i32(0)
+			a.get(5)
+			a.op(wasm.OpI32WrapI64)
+			a.call("substr")
+		}},
+	{name: "right", params: wasmPair, results: valTypes(wasm.I32), locals: valTypes(wasm.I32, wasm.I64),
+		body: func(a *wasmAsm) {
+			a.clampLen()
+			a.get(4)
+			a.get(4)
+			a.load(wasm.OpI32Load, 0)
+			a.get(5)
+			a.op(wasm.OpI32WrapI64, wasm.OpI32Sub)
+			a.get(4)
+			a.load(wasm.OpI32Load, 0)
+			a.call("substr")
+		}},
+	// mid(s, start, n, hasN) -> string
+	{name: "mid", params: joinTypes(wasmPair, wasmValue, valTypes(wasm.I32)), results: valTypes(wasm.I32),
+		locals: valTypes(wasm.I32, wasm.I64, wasm.I64, wasm.I64, wasm.I64),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.get(1)
+			a.call("str")
+			a.tee(7)
+			a.load(wasm.OpI32Load, 0)
+			a.op(wasm.OpI64ExtendI32U)
+			a.set(8) // Length
+			a.get(2)
+			a.get(3)
+			a.call("num")
+			a.call("toint")
+			a.set(9) // Start
+			a.get(9)
+			a.i64(1)
+			a.op(wasm.OpI64LtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.i64(1)
+			a.set(9)
+			a.end()
+			a.get(6)
+			a.ifThen(wasm.BlockOf(wasm.I64))
+			a.get(4)
+			a.get(5)
+			a.call("num")
+			a.call("toint")
+			a.els()
+			a.get(8)
+			a.get(9)
+			a.op(wasm.OpI64Sub)
+			a.i64(1)
+			a.op(wasm.OpI64Add)
+			a.end()
+			a.set(10) // Count
+			a.get(9)
+			a.i64(1)
+			a.op(wasm.OpI64Sub)
+			a.tee(9) // Start index
+			a.get(10)
+			a.op(wasm.OpI64Add)
+			a.set(11) // End index
+			a.get(11)
+			a.get(8)
+			a.op(wasm.OpI64GtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(8)
+			a.set(11)
+			a.end()
+			a.get(9)
+			a.get(8)
+			a.op(wasm.OpI64GeS)
+			a.get(9)
+			a.i64(0)
+			a.op(wasm.OpI64LtS, wasm.OpI32Or)
+			a.get(11)
+			a.get(9)
+			a.op(wasm.OpI64LtS, wasm.OpI32Or)
+			a.ifThen(wasm.BlockEmpty)
+			a.str("")
+			a.op(wasm.OpReturn)
+			a.end()
+			a.get(7)
+			a.get(9)
+			a.op(wasm.OpI32WrapI64)
+			a.get(11)
+			a.op(wasm.OpI32WrapI64)
+			a.call("substr")
+		}},
+	// instr(start, s, sub) -> position
+	{name: "instr", params: joinTypes(wasmPair, wasmValue), results: valTypes(wasm.F64),
+		locals: valTypes(wasm.I32, wasm.I32, wasm.I64, wasm.I32, wasm.I32, wasm.I32, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(2)
+			a.get(3)
+			a.call("str")
+			a.set(6)
+			a.get(4)
+			a.get(5)
+			a.call("str")
+			a.set(7)
+			a.get(0)
+			a.get(1)
+			a.call("num")
+			a.call("toint")
+			a.set(8)
+			a.get(8)
+			a.i64(1)
+			a.op(wasm.OpI64LtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.i64(1)
+			a.set(8)
+			a.end()
+			a.get(6)
+			a.load(wasm.OpI32Load, 0)
+			a.set(9)
+			a.get(7)
+			a.load(wasm.OpI32Load, 0)
+			a.set(10)
+			a.get(8)
+			a.get(9)
+			a.op(wasm.OpI64ExtendI32U, wasm.OpI64GtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.f64(0)
+			a.op(wasm.OpReturn)
+			a.end()
+			a.get(8)
+			a.op(wasm.OpI32WrapI64)
+			a.i32(1)
+			a.op(wasm.OpI32Sub)
+			a.set(11)
+			a.block()
+			a.loop()
+			a.get(11)
+			a.get(10)
+			a.op(wasm.OpI32Add)
+			a.get(9)
+			a.op(wasm.OpI32GtU)
+			a.brIf(1)
+			a.i32(0)
+			a.set(12)
+			a.block()
+			a.loop()
+			a.get(12)
+			a.get(10)
+			a.op(wasm.OpI32GeU)
+			a.ifThen(wasm.BlockEmpty)
+			a.get(11)
+			a.i32(1)
+			a.op(wasm.OpI32Add, wasm.OpF64ConvertI32U, wasm.OpReturn)
+			a.end()
+			a.get(6)
+			a.get(11)
+			a.op(wasm.OpI32Add)
+			a.get(12)
+			a.op(wasm.OpI32Add)
+			a.load(wasm.OpI32Load8U, 4)
+			a.get(7)
+			a.get(12)
+			a.op(wasm.OpI32Add)
+			a.load(wasm.OpI32Load8U, 4)
+			a.op(wasm.OpI32Ne)
+			a.brIf(1)
+			a.get(12)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.set(12)
+			a.br(0)
+			a.end()
+			a.end()
+			a.get(11)
+			a.i32(1)
+			a.op(wasm.OpI32Add)
+			a.set(11)
+			a.br(0)
+			a.end()
+			a.end()
+			a.f64(0)
+		}},
+	// instr2(s, sub) is INSTR without a start position
+	{name: "instr2", params: wasmPair, results: valTypes(wasm.F64),
+		body: func(a *wasmAsm) {
+			a.f64(1)
+			a.i32(tagNumber)
+			a.getAll(4)
+			a.call("instr")
+		}},
+	{name: "space", params: wasmValue, results: valTypes(wasm.I32), locals: valTypes(wasm.I64, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.get(1)
+			a.call("num")
+			a.call("toint")
+			a.tee(2)
+			a.i64(0)
+			a.op(wasm.OpI64LtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.i64(0)
+			a.set(2)
+			a.end()
+			a.get(2)
+			a.i64(wasmMaxAlloc)
+			a.op(wasm.OpI64GtS)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("out of memory")
+			a.end()
+			a.get(2)
+			a.op(wasm.OpI32WrapI64)
+			a.call("strnew")
+			a.tee(3)
+			a.i32(4)
+			a.op(wasm.OpI32Add)
+			a.i32(' ')
+			a.get(2)
+			a.op(wasm.OpI32WrapI64, wasm.OpMemoryFill)
+			a.get(3)
+		}},
+	// chr(n) returns the UTF-8 encoding of code point n, 0 to 255
+	{name: "chr", params: wasmValue, results: valTypes(wasm.I32), locals: valTypes(wasm.I64, wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.get(1)
+			a.call("num")
+			a.call("toint")
+			a.tee(2)
+			a.i64(0)
+			a.op(wasm.OpI64LtS)
+			a.get(2)
+			a.i64(255)
+			a.op(wasm.OpI64GtS, wasm.OpI32Or)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("CHR$ argument must be between 0 and 255")
+			a.end()
+			a.get(2)
+			a.i64(0x80)
+			a.op(wasm.OpI64LtS)
+			a.ifThen(wasm.BlockOf(wasm.I32))
+			a.i32(1)
+			a.call("strnew")
+			a.tee(3)
+			a.get(2)
+			a.op(wasm.OpI32WrapI64)
+			a.store(wasm.OpI32Store8, 4)
+			a.get(3)
+			a.els()
+			a.i32(2)
+			a.call("strnew")
+			a.tee(3)
+			a.get(2)
+			a.op(wasm.OpI32WrapI64)
+			a.i32(6)
+			a.op(wasm.OpI32ShrU)
+			a.i32(0xc0)
+			a.op(wasm.OpI32Or)
+			a.store(wasm.OpI32Store8, 4)
+			a.get(3)
+			a.get(2)
+			a.op(wasm.OpI32WrapI64)
+			a.i32(0x3f)
+			a.op(wasm.OpI32And)
+			a.i32(0x80)
+			a.op(wasm.OpI32Or)
+			a.store(wasm.OpI32Store8, 5)
+			a.get(3)
+			a.end()
+		}},
+	// asc(s) returns the first byte of s
+	{name: "asc", params: wasmValue, results: valTypes(wasm.F64), locals: valTypes(wasm.I32),
+		body: func(a *wasmAsm) {
+			a.get(0)
+			a.get(1)
+			a.call("str")
+			a.tee(2)
+			a.load(wasm.OpI32Load, 0)
+			a.op(wasm.OpI32Eqz)
+			a.ifThen(wasm.BlockEmpty)
+			a.fail("ASC argument is an empty string")
+			a.end()
+			a.get(2)
+			a.load(wasm.OpI32Load8U, 4)
+			a.op(wasm.OpF64ConvertI32U)
+		}},
+}
+
+// wasmAsm assembles one function body
+type wasmAsm struct {
+	l    *wasmLowering
+	code []wasm.Instr
+}
+
+func (a *wasmAsm) emit(in wasm.Instr) {
+	a.code = append(a.code, in)
+}
+
+func (a *wasmAsm) op(ops ...wasm.Opcode) {
+	for _, op := range ops {
+		a.emit(wasm.Instr{Op: op})
+	}
+}
+
+func (a *wasmAsm) i32(n int32)   { a.emit(wasm.Instr{Op: wasm.OpI32Const, Int: int64(n)}) }
+func (a *wasmAsm) i64(n int64)   { a.emit(wasm.Instr{Op: wasm.OpI64Const, Int: n}) }
+func (a *wasmAsm) f64(x float64) { a.emit(wasm.Instr{Op: wasm.OpF64Const, Float: x}) }
+func (a *wasmAsm) get(i uint32)  { a.emit(wasm.Instr{Op: wasm.OpLocalGet, Index: i}) }
+func (a *wasmAsm) set(i uint32)  { a.emit(wasm.Instr{Op: wasm.OpLocalSet, Index: i}) }
+func (a *wasmAsm) tee(i uint32)  { a.emit(wasm.Instr{Op: wasm.OpLocalTee, Index: i}) }
+func (a *wasmAsm) gget(i uint32) { a.emit(wasm.Instr{Op: wasm.OpGlobalGet, Index: i}) }
+func (a *wasmAsm) gset(i uint32) { a.emit(wasm.Instr{Op: wasm.OpGlobalSet, Index: i}) }
+func (a *wasmAsm) br(d uint32)   { a.emit(wasm.Instr{Op: wasm.OpBr, Index: d}) }
+func (a *wasmAsm) brIf(d uint32) { a.emit(wasm.Instr{Op: wasm.OpBrIf, Index: d}) }
+func (a *wasmAsm) block()        { a.emit(wasm.Instr{Op: wasm.OpBlock, Block: wasm.BlockEmpty}) }
+func (a *wasmAsm) loop()         { a.emit(wasm.Instr{Op: wasm.OpLoop, Block: wasm.BlockEmpty}) }
+func (a *wasmAsm) els()          { a.op(wasm.OpElse) }
+func (a *wasmAsm) end()          { a.op(wasm.OpEnd) }
+
+func (a *wasmAsm) ifThen(bt wasm.BlockType) {
+	a.emit(wasm.Instr{Op: wasm.OpIf, Block: bt})
+}
+
+// getAll pushes locals 0 to n-1
+func (a *wasmAsm) getAll(n uint32) {
+	for i := uint32(0); i < n; i++ {
+		a.get(i)
+	}
+}
+
+// call calls an import or runtime function by name
+func (a *wasmAsm) call(name string) {
+	idx, ok := a.l.funcs[name]
+	if !ok {
+		panic("codegen: unknown wasm function " + name)
+	}
+	a.emit(wasm.Instr{Op: wasm.OpCall, Index: idx})
+}
+
+// load and store access memory with natural alignment
+func (a *wasmAsm) load(op wasm.Opcode, offset uint32) {
+	a.emit(wasm.Instr{Op: op, Offset: offset, Align: wasmAlign(op)})
+}
+
+func (a *wasmAsm) store(op wasm.Opcode, offset uint32) {
+	a.emit(wasm.Instr{Op: op, Offset: offset, Align: wasmAlign(op)})
+}
+
+func wasmAlign(op wasm.Opcode) uint32 {
+	switch op {
+	case wasm.OpI32Load8U, wasm.OpI32Store8:
+		return 0
+	case wasm.OpI32Load, wasm.OpI32Store:
+		return 2
+	}
+	return 3
+}
+
+// str pushes the address of a string constant
+func (a *wasmAsm) str(s string) {
+	a.i32(int32(a.l.constant(s)))
+}
+
+// fail reports msg as a runtime error
+func (a *wasmAsm) fail(msg string) {
+	a.str(msg)
+	a.call("fail")
+}
+
+// isTag pushes whether the tag in local i is tag
+func (a *wasmAsm) isTag(i uint32, tag int32) {
+	a.get(i)
+	a.i32(tag)
+	a.op(wasm.OpI32Eq)
+}
+
+// forTop pushes the offset of the innermost FOR frame from wasmForBase
+func (a *wasmAsm) forTop() {
+	a.gget(wasmFsp)
+	a.i32(1)
+	a.op(wasm.OpI32Sub)
+	a.i32(24)
+	a.op(wasm.OpI32Mul)
+}
+
+// clampLen is the start of left and right: it stores str(s) in local 4
+// and n, limited to 0..len(s), in local 5
+func (a *wasmAsm) clampLen() {
+	a.get(0)
+	a.get(1)
+	a.call("str")
+	a.set(4)
+	a.get(2)
+	a.get(3)
+	a.call("num")
+	a.call("toint")
+	a.set(5)
+	a.get(5)
+	a.get(4)
+	a.load(wasm.OpI32Load, 0)
+	a.op(wasm.OpI64ExtendI32U, wasm.OpI64GtS)
+	a.ifThen(wasm.BlockEmpty)
+	a.get(4)
+	a.load(wasm.OpI32Load, 0)
+	a.op(wasm.OpI64ExtendI32U)
+	a.set(5)
+	a.end()
+	a.get(5)
+	a.i64(0)
+	a.op(wasm.OpI64LtS)
+	a.ifThen(wasm.BlockEmpty)
+	a.i64(0)
+	a.set(5)
+	a.end()
+}
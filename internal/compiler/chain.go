@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/interpreter"
+)
+
+// ChainLoader returns a program loader for vm.WithChain. Bytecode files
+// are read as they are; source files are parsed and compiled with opts.
+// A chained program can be entered at any line, so dead-code elimination
+// is skipped and coverage is not recorded for it.
+func ChainLoader(opts ...Option) func(filename string) (*bytecode.Chunk, error) {
+	return func(filename string) (*bytecode.Chunk, error) {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		header := make([]byte, 3)
+		if n, _ := f.Read(header); n == 3 && string(header) == "ZBC" {
+			if _, err := f.Seek(0, 0); err != nil {
+				return nil, err
+			}
+			chunk, err := bytecode.ReadChunk(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", filename, err)
+			}
+			return chunk, nil
+		}
+
+		prog, err := interpreter.LoadSource(filename)
+		if err != nil {
+			return nil, err
+		}
+		c := New(opts...)
+		c.cover = nil
+		c.optLevel = min(c.optLevel, OptFold)
+		chunk, err := c.Compile(prog)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		return chunk, nil
+	}
+}
+
+// chainLine returns the start line expression of a CHAIN; -1 asks for the
+// first line of the program
+func chainLine(n *ast.ChainStmt) ast.Node {
+	if n.Line == nil {
+		return &ast.Number{Value: -1}
+	}
+	return n.Line
+}
+
+// chainNames reserves the names constant of a CHAIN instruction. It is
+// not deduplicated, since fillChains overwrites it.
+func (c *Compiler) chainNames(n *ast.ChainStmt) int {
+	idx := c.chunk.AddConstant(interpreter.StringValue(""))
+	c.chains = append(c.chains, chainRef{constant: idx, all: n.All})
+	return idx
+}
+
+// fillChains stores the variables each CHAIN keeps: those declared by
+// COMMON anywhere in the program, or "*" (all of them) for CHAIN ... ALL
+func (c *Compiler) fillChains(prog *ast.Program) {
+	if len(c.chains) == 0 {
+		return
+	}
+	common := strings.Join(interpreter.CommonNames(prog), " ")
+	for _, ref := range c.chains {
+		names := common
+		if ref.all {
+			names = "*"
+		}
+		c.chunk.Constants[ref.constant] = interpreter.StringValue(names)
+	}
+}
@@ -3,53 +3,121 @@ package compiler
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"strings"
 
 	"zork-basic/internal/ast"
 	"zork-basic/internal/bytecode"
+	"zork-basic/internal/coverage"
 	"zork-basic/internal/interpreter"
 )
 
-// forInfo tracks a FOR loop's compilation state
+// jumpWidth is the size of a jump target operand
+const jumpWidth = 4
+
+// forInfo tracks a FOR or FOR EACH loop's compilation state
 type forInfo struct {
 	varName string // Loop variable name (uppercased)
 	varIdx  int    // Index of loop variable in globals
-	loopTop int    // Bytecode offset of the loop body start (after OpForInit)
+	loopTop int    // Bytecode offset of the loop body start (after OpForInit or OpForEach)
+	exit    int    // FOR EACH: offset of the jump operand that skips an empty map, patched at NEXT; -1 for FOR
+}
+
+// chainRef is the names constant of a CHAIN instruction. COMMON may
+// appear anywhere in the program, so the names are only known at the end.
+type chainRef struct {
+	constant int
+	all      bool // CHAIN ... ALL keeps every variable and array
 }
 
 // Compiler translates AST to bytecode
 type Compiler struct {
 	chunk       *bytecode.Chunk
-	lineOffsets map[int]int    // map[BasicLineNumber]BytecodeOffset
-	fixups      map[int][]int  // map[BasicLineNumber][]BytecodeOffsetToPatch
-	currentLine int            // Current source line number being compiled
-	globals     map[string]int // map[Name]Index (Global variables)
-	arrays      map[string]int // map[Name]Index (Arrays)
+	lineOffsets map[int]int      // map[BasicLineNumber]BytecodeOffset
+	fixups      map[int][]int    // map[BasicLineNumber][]BytecodeOffsetToPatch
+	currentLine int              // Current source line number being compiled
+	constants   map[constKey]int // Constant pool index for deduplication
+	globals     map[string]int   // map[Name]Index (Global variables)
+	arrays      map[string]int   // map[Name]Index (Arrays)
 	globalCount int
 	arrayCount  int
-	forStack    []forInfo // FOR loop stack for matching FOR/NEXT
+	forStack    []forInfo         // FOR loop stack for matching FOR/NEXT
+	cover       *coverage.Profile // Emit OpCover instrumentation when non-nil
+	optLevel    int               // AST optimization level (OptNone, OptFold, OptDeadCode)
+	optStats    OptStats          // Rewrites performed by the optimizer
+	chains      []chainRef        // Names constants of CHAIN instructions, filled in at the end
+	imports     map[string][]int  // OpGosub offsets calling each routine of another module
+
+	// Register backend (WithRegisters)
+	registers bool  // Emit register instructions instead of stack instructions
+	temps     int   // Temporaries in use by the current statement
+	maxTemps  int   // Most temporaries live at once
+	tempRefs  []int // Offsets of temporary operands, patched by finishRegisters
+}
+
+// Option represents a configuration option for the Compiler
+type Option func(*Compiler)
+
+// WithCoverage instruments the generated code with OpCover counters
+// for every line and IF branch described by the profile.
+func WithCoverage(p *coverage.Profile) Option {
+	return func(c *Compiler) { c.cover = p }
+}
+
+// WithOptimization runs the AST optimizer at the given level before
+// code generation and the peephole pass after it. The AST optimizer is
+// skipped when coverage is enabled, since the profile refers to the
+// statements of the unoptimized program.
+func WithOptimization(level int) Option {
+	return func(c *Compiler) { c.optLevel = level }
 }
 
 // New creates a new Compiler
-func New() *Compiler {
-	return &Compiler{
+func New(opts ...Option) *Compiler {
+	c := &Compiler{
 		chunk:       bytecode.NewChunk(),
 		lineOffsets: make(map[int]int),
 		fixups:      make(map[int][]int),
+		constants:   make(map[constKey]int),
 		globals:     make(map[string]int),
 		arrays:      make(map[string]int),
+		imports:     make(map[string][]int),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Compile compiles a program into a chunk
 func (c *Compiler) Compile(prog *ast.Program) (*bytecode.Chunk, error) {
-	for _, line := range prog.Lines {
+	if c.optLevel > OptNone && c.cover == nil {
+		prog, c.optStats = Optimize(prog, c.optLevel)
+	}
+
+	for lineIdx, line := range prog.Lines {
 		c.currentLine = line.LineNumber
 		// Record the bytecode offset for this line
 		c.lineOffsets[line.LineNumber] = len(c.chunk.Code)
+		if prog.Unnumbered {
+			// Internal line numbers mean nothing to the user; keep the
+			// source position for breaks and errors
+			if c.chunk.Positions == nil {
+				c.chunk.Positions = make(map[int]string)
+			}
+			c.chunk.Positions[line.LineNumber] = line.Pos.String()
+		}
+
+		if c.cover != nil {
+			c.emitCover(c.cover.LineCounter(lineIdx))
+		}
 
 		for _, stmt := range line.Statements {
-			if err := c.compileStatement(stmt); err != nil {
+			compile := c.compileStatement
+			if c.registers {
+				compile = c.compileRegStatement
+			}
+			if err := compile(stmt); err != nil {
 				return nil, err
 			}
 		}
@@ -62,46 +130,68 @@ func (c *Compiler) Compile(prog *ast.Program) (*bytecode.Chunk, error) {
 	for lineNum, offsets := range c.fixups {
 		targetOffset, ok := c.lineOffsets[lineNum]
 		if !ok {
-			return nil, fmt.Errorf("undefined line number %d", lineNum)
+			// Report the jump's own source line so the error is actionable
+			return nil, fmt.Errorf("%s: jump to undefined line number %d", c.chunk.Position(c.chunk.Lines[offsets[0]]), lineNum)
 		}
 
 		for _, offset := range offsets {
-			// Write the 2-byte target offset (offset in bytecode, not line number)
-			binary.BigEndian.PutUint16(c.chunk.Code[offset:], uint16(targetOffset))
+			// Write the target offset (offset in bytecode, not line number)
+			if err := c.patchJumpTo(offset, targetOffset); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	c.linkTables(prog)
+
+	// The superinstructions only exist for the stack machine, and fusing
+	// would merge instructions across the coverage counters
+	if c.optLevel > OptNone && !c.registers && c.cover == nil {
+		fused, err := c.chunk.Peephole()
+		if err != nil {
+			return nil, fmt.Errorf("peephole: %v", err)
 		}
+		c.optStats.Fused = fused
 	}
 
 	// Store counts in chunk
 	c.chunk.GlobalCount = c.globalCount
 	c.chunk.ArrayCount = c.arrayCount
+	c.chunk.GlobalNames = symbolNames(c.globals, c.globalCount)
+	c.chunk.ArrayNames = symbolNames(c.arrays, c.arrayCount)
+	c.fillChains(prog)
+	if c.registers {
+		if err := c.finishRegisters(); err != nil {
+			return nil, err
+		}
+	}
 
 	return c.chunk, nil
 }
 
+// symbolNames turns a name-to-index map into an index-ordered name list
+func symbolNames(symbols map[string]int, count int) []string {
+	names := make([]string, count)
+	for name, idx := range symbols {
+		names[idx] = name
+	}
+	return names
+}
+
+// OptStats returns what the optimizer changed during the last Compile
+func (c *Compiler) OptStats() OptStats {
+	return c.optStats
+}
+
 func (c *Compiler) compileStatement(stmt ast.Node) error {
 	switch n := stmt.(type) {
 	case *ast.Assignment:
-		if err := c.compileExpression(n.Value); err != nil {
+		if err := c.compileStore(n.Target, func() error { return c.compileExpression(n.Value) }); err != nil {
 			return err
 		}
-		switch target := n.Target.(type) {
-		case *ast.Identifier:
-			name := strings.ToUpper(target.Name)
-			idx := c.resolveGlobal(name)
-			c.emit(bytecode.OpSetGlobal, byte(idx>>8), byte(idx))
-		case *ast.ArrayAccess:
-			// Compile indices
-			for _, idxExpr := range target.Indices {
-				if err := c.compileExpression(idxExpr); err != nil {
-					return err
-				}
-			}
-			name := strings.ToUpper(target.Name)
-			idx := c.resolveArray(name)
-			c.emit(bytecode.OpSetArray, byte(idx>>8), byte(idx), byte(len(target.Indices)))
-		default:
-			return fmt.Errorf("invalid assignment target: %T", target)
-		}
+
+	case *ast.RecordAssignment:
+		return c.compileRecordAssignment(n)
 
 	case *ast.PrintStmt:
 		for i, val := range n.Values {
@@ -123,7 +213,7 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 			if sep == "," {
 				// Comma: print a tab or space? Interpreter uses " ".
 				spaceIdx := c.addConstant(interpreter.StringValue(" "))
-				c.emit(bytecode.OpConstant, byte(spaceIdx>>8), byte(spaceIdx))
+				c.emit(bytecode.OpConstant, spaceIdx)
 				c.emit(bytecode.OpPrint)
 			}
 			// Semicolon: no space, do nothing
@@ -143,6 +233,10 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 		// Jump if false -> to ELSE or END IF
 		jumpIfFalseOffset := c.emitJump(bytecode.OpJumpIfFalse)
 
+		if c.cover != nil {
+			c.emitCover(c.cover.BranchCounter(n, coverage.BranchThen))
+		}
+
 		// THEN block
 		for _, s := range n.ThenStmts {
 			if err := c.compileStatement(s); err != nil {
@@ -154,7 +248,13 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 		jumpToExitOffset := c.emitJump(bytecode.OpJump)
 
 		// Patch JumpIfFalse to here (start of ELSE)
-		c.patchJump(jumpIfFalseOffset)
+		if err := c.patchJump(jumpIfFalseOffset); err != nil {
+			return err
+		}
+
+		if c.cover != nil {
+			c.emitCover(c.cover.BranchCounter(n, coverage.BranchElse))
+		}
 
 		// ELSE block
 		for _, s := range n.ElseStmts {
@@ -164,7 +264,9 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 		}
 
 		// Patch JumpToExit to here (end of IF)
-		c.patchJump(jumpToExitOffset)
+		if err := c.patchJump(jumpToExitOffset); err != nil {
+			return err
+		}
 
 	case *ast.ForStmt:
 		varName := strings.ToUpper(n.Var)
@@ -174,7 +276,7 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 		if err := c.compileExpression(n.Start); err != nil {
 			return err
 		}
-		c.emit(bytecode.OpSetGlobal, byte(idx>>8), byte(idx))
+		c.emit(bytecode.OpSetGlobal, idx)
 
 		// Compile end and step expressions, push them on stack for OpForInit
 		if err := c.compileExpression(n.End); err != nil {
@@ -185,7 +287,7 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 		}
 
 		// Emit OpForInit: pops step and end from stack, stores in ForFrame
-		c.emit(bytecode.OpForInit, byte(idx>>8), byte(idx))
+		c.emit(bytecode.OpForInit, idx)
 
 		// Record loop body start (the ip AFTER OpForInit)
 		loopTop := len(c.chunk.Code)
@@ -195,8 +297,12 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 			varName: varName,
 			varIdx:  idx,
 			loopTop: loopTop,
+			exit:    -1,
 		})
 
+	case *ast.ForEachStmt:
+		c.emitForEach(n)
+
 	case *ast.NextStmt:
 		if len(c.forStack) == 0 {
 			return fmt.Errorf("NEXT without FOR")
@@ -214,19 +320,28 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 			}
 		}
 
+		if frame.exit >= 0 {
+			// FOR EACH: an empty map skips to after this NEXT
+			c.emit(bytecode.OpNextEach, frame.varIdx, frame.loopTop)
+			return c.patchJump(frame.exit)
+		}
+
 		// Emit OpNext with variable index and loop top offset
 		idx := frame.varIdx
 		loopTop := frame.loopTop
-		c.emit(bytecode.OpNext, byte(idx>>8), byte(idx), byte(loopTop>>8), byte(loopTop))
+		c.emit(bytecode.OpNext, idx, loopTop)
 
 	case *ast.GotoStmt:
-		c.emit(bytecode.OpJump, 0, 0) // Placeholder
-		offset := len(c.chunk.Code) - 2
+		offset := c.emitJump(bytecode.OpJump) // Placeholder
 		c.fixups[n.LineNumber] = append(c.fixups[n.LineNumber], offset)
 
 	case *ast.GosubStmt:
-		c.emit(bytecode.OpGosub, 0, 0) // Placeholder
-		offset := len(c.chunk.Code) - 2
+		offset := c.emitJump(bytecode.OpGosub) // Placeholder
+		if n.Extern != "" {
+			// Patched by bytecode.Link; record the instruction, not the operand
+			c.imports[n.Extern] = append(c.imports[n.Extern], offset-1)
+			break
+		}
 		c.fixups[n.LineNumber] = append(c.fixups[n.LineNumber], offset)
 
 	case *ast.ReturnStmt:
@@ -235,22 +350,60 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 	case *ast.EndStmt:
 		c.emit(bytecode.OpEnd)
 
+	case *ast.StopStmt:
+		c.emit(bytecode.OpStop)
+
 	case *ast.InputStmt:
-		if n.Prompt != "" {
-			idx := c.addConstant(interpreter.StringValue(n.Prompt))
-			c.emit(bytecode.OpConstant, byte(idx>>8), byte(idx))
-			c.emit(bytecode.OpPrint) // Print prompt
+		if err := c.emitInput(n); err != nil {
+			return err
+		}
+		// Each target takes the next field once the whole line is read
+		for _, target := range n.Vars {
+			if err := c.compileStore(target, func() error {
+				c.emit(bytecode.OpInputField)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+	case *ast.ChainStmt:
+		if err := c.compileExpression(n.File); err != nil {
+			return err
+		}
+		if err := c.compileExpression(chainLine(n)); err != nil {
+			return err
+		}
+		c.emit(bytecode.OpChain, c.chainNames(n))
+
+	case *ast.RemStmt, *ast.CommonStmt:
+		// Ignore comments; COMMON only matters to CHAIN (see fillChains)
+
+	case *ast.SaveLocalsStmt:
+		for _, name := range n.Vars {
+			c.emit(bytecode.OpSaveGlobal, c.resolveGlobal(name))
 		}
-		for _, varName := range n.Vars {
-			name := strings.ToUpper(varName)
-			idx := c.resolveGlobal(name)
-			c.emit(bytecode.OpInput, byte(idx>>8), byte(idx))
+		for _, name := range n.Arrays {
+			c.emit(bytecode.OpSaveArray, c.resolveArray(name))
 		}
 
-	case *ast.RemStmt:
-		// Ignore comments
+	case *ast.RestoreLocalsStmt:
+		// The save stack is last in, first out
+		for k := len(n.Saved.Arrays) - 1; k >= 0; k-- {
+			c.emit(bytecode.OpRestoreArray, c.resolveArray(n.Saved.Arrays[k]))
+		}
+		for k := len(n.Saved.Vars) - 1; k >= 0; k-- {
+			c.emit(bytecode.OpRestoreGlobal, c.resolveGlobal(n.Saved.Vars[k]))
+		}
 
 	case *ast.DimStmt:
+		if isMap(n) {
+			c.emit(bytecode.OpDimMap, c.resolveArray(strings.ToUpper(n.Name)))
+			break
+		}
+		if n.Record != nil {
+			return c.compileDimRecord(n)
+		}
 		// Compile dimension expressions
 		for _, sizeExpr := range n.Sizes {
 			if err := c.compileExpression(sizeExpr); err != nil {
@@ -262,7 +415,13 @@ func (c *Compiler) compileStatement(stmt ast.Node) error {
 		idx := c.resolveArray(name)
 
 		// Emit OpDim with name index and dimension count
-		c.emit(bytecode.OpDim, byte(idx>>8), byte(idx), byte(len(n.Sizes)))
+		c.emit(bytecode.OpDim, idx, len(n.Sizes))
+
+	case *ast.DeleteKeyStmt:
+		if err := c.compileExpression(n.Key); err != nil {
+			return err
+		}
+		c.emit(bytecode.OpMapDelete, c.resolveArray(strings.ToUpper(n.Map)))
 
 	default:
 		return fmt.Errorf("unknown statement: %T", stmt)
@@ -274,16 +433,16 @@ func (c *Compiler) compileExpression(expr ast.Node) error {
 	switch n := expr.(type) {
 	case *ast.Number:
 		idx := c.addConstant(interpreter.NumberValue(n.Value))
-		c.emit(bytecode.OpConstant, byte(idx>>8), byte(idx))
+		c.emit(bytecode.OpConstant, idx)
 
 	case *ast.StringLiteral:
 		idx := c.addConstant(interpreter.StringValue(n.Value))
-		c.emit(bytecode.OpConstant, byte(idx>>8), byte(idx))
+		c.emit(bytecode.OpConstant, idx)
 
 	case *ast.Identifier:
 		name := strings.ToUpper(n.Name)
 		idx := c.resolveGlobal(name)
-		c.emit(bytecode.OpGetGlobal, byte(idx>>8), byte(idx))
+		c.emit(bytecode.OpGetGlobal, idx)
 
 	case *ast.FunctionCall:
 		for _, arg := range n.Args {
@@ -296,7 +455,35 @@ func (c *Compiler) compileExpression(expr ast.Node) error {
 		if builtinID < 0 {
 			return fmt.Errorf("unknown builtin function: %s", name)
 		}
-		c.emit(bytecode.OpCallBuiltin, byte(builtinID>>8), byte(builtinID), byte(len(n.Args)))
+		c.emit(bytecode.OpCallBuiltin, builtinID, len(n.Args))
+
+	case *ast.ArrayAccess:
+		for _, idxExpr := range n.Indices {
+			if err := c.compileExpression(idxExpr); err != nil {
+				return err
+			}
+		}
+		name := strings.ToUpper(n.Name)
+		idx := c.resolveArray(name)
+		c.emit(bytecode.OpGetArray, idx, len(n.Indices))
+
+	case *ast.MapAccess:
+		if err := c.compileExpression(n.Key); err != nil {
+			return err
+		}
+		c.emit(bytecode.OpMapGet, c.resolveArray(strings.ToUpper(n.Name)))
+
+	case *ast.MapFunc:
+		op, err := mapFuncOpcode(n.Func, false)
+		if err != nil {
+			return err
+		}
+		if n.Arg != nil {
+			if err := c.compileExpression(n.Arg); err != nil {
+				return err
+			}
+		}
+		c.emit(op, c.resolveArray(strings.ToUpper(n.Map)))
 
 	case *ast.BinaryOp:
 		if err := c.compileExpression(n.Left); err != nil {
@@ -376,12 +563,60 @@ func (c *Compiler) compileExpression(expr ast.Node) error {
 			return fmt.Errorf("unknown unary op: %s", n.Op)
 		}
 
+	case *ast.FieldAccess:
+		return c.compileField(n)
+
 	default:
 		return fmt.Errorf("unknown expression: %T", expr)
 	}
 	return nil
 }
 
+// isMap reports whether a DIM creates a map (DIM ... AS MAP)
+func isMap(d *ast.DimStmt) bool {
+	return strings.EqualFold(d.Type, "MAP")
+}
+
+// mapFuncOpcode returns the instruction computing a map function, in its
+// register form when registers is set
+func mapFuncOpcode(name string, registers bool) (bytecode.OpCode, error) {
+	var stack, reg bytecode.OpCode
+	switch name {
+	case "HASKEY":
+		stack, reg = bytecode.OpMapHas, bytecode.OpRMapHas
+	case "KEYS$":
+		stack, reg = bytecode.OpMapKey, bytecode.OpRMapKey
+	case "COUNT":
+		stack, reg = bytecode.OpMapCount, bytecode.OpRMapCount
+	default:
+		return 0, fmt.Errorf("unknown map function: %s", name)
+	}
+	if registers {
+		return reg, nil
+	}
+	return stack, nil
+}
+
+// emitForEach starts a FOR EACH loop; both backends use it. The exit
+// operand is patched by the matching NEXT.
+func (c *Compiler) emitForEach(n *ast.ForEachStmt) {
+	varName := strings.ToUpper(n.Var)
+	idx := c.resolveGlobal(varName)
+	exit := c.emitJump(bytecode.OpForEach, c.resolveArray(strings.ToUpper(n.Map)), idx)
+	c.forStack = append(c.forStack, forInfo{
+		varName: varName,
+		varIdx:  idx,
+		loopTop: len(c.chunk.Code),
+		exit:    exit,
+	})
+}
+
+// errField reports a record field that parser.ParseProgram did not resolve,
+// as in a direct-mode statement, which has no TYPE declarations
+func errField(f *ast.FieldAccess) error {
+	return fmt.Errorf("record field %s outside a program with its TYPE", f)
+}
+
 func (c *Compiler) resolveGlobal(name string) int {
 	if idx, ok := c.globals[name]; ok {
 		return idx
@@ -402,35 +637,134 @@ func (c *Compiler) resolveArray(name string) int {
 	return idx
 }
 
-func (c *Compiler) emit(op bytecode.OpCode, operands ...byte) {
-	c.chunk.WriteByte(byte(op), c.currentLine)
-	for _, b := range operands {
-		c.chunk.WriteByte(b, c.currentLine)
+// compileStore stores the value that value pushes into a variable, array
+// element, map entry or record field
+func (c *Compiler) compileStore(target ast.Node, value func() error) error {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		if err := value(); err != nil {
+			return err
+		}
+		name := strings.ToUpper(target.Name)
+		idx := c.resolveGlobal(name)
+		c.emit(bytecode.OpSetGlobal, idx)
+	case *ast.ArrayAccess:
+		// Compile indices, then the value: OpSetArray pops the value first
+		for _, idxExpr := range target.Indices {
+			if err := c.compileExpression(idxExpr); err != nil {
+				return err
+			}
+		}
+		if err := value(); err != nil {
+			return err
+		}
+		name := strings.ToUpper(target.Name)
+		idx := c.resolveArray(name)
+		c.emit(bytecode.OpSetArray, idx, len(target.Indices))
+	case *ast.MapAccess:
+		// OpMapSet pops the value, then the key
+		if err := c.compileExpression(target.Key); err != nil {
+			return err
+		}
+		if err := value(); err != nil {
+			return err
+		}
+		c.emit(bytecode.OpMapSet, c.resolveArray(strings.ToUpper(target.Name)))
+	case *ast.FieldAccess:
+		return c.compileFieldStore(target, value)
+	default:
+		return fmt.Errorf("invalid assignment target: %T", target)
+	}
+	return nil
+}
+
+// emitInput emits the instruction that prompts for and reads the fields of
+// an INPUT or LINE INPUT; both backends use it
+func (c *Compiler) emitInput(n *ast.InputStmt) error {
+	prompt := c.addConstant(interpreter.StringValue(n.PromptText()))
+	types := interpreter.InputTypes(n.Vars)
+	if n.Line {
+		if types != string(interpreter.InputString) {
+			return fmt.Errorf("%s: LINE INPUT needs a string variable, got %s", c.chunk.Position(c.currentLine), n.Vars[0])
+		}
+		c.emit(bytecode.OpLineInput, prompt)
+		return nil
+	}
+	c.emit(bytecode.OpReadInput, prompt, c.addConstant(interpreter.StringValue(types)))
+	return nil
+}
+
+// emit appends an instruction, encoding each operand with the width given
+// by the opcode definition. Temporaries of the register backend are
+// recorded so finishRegisters can renumber them.
+func (c *Compiler) emit(op bytecode.OpCode, operands ...int) {
+	def, _ := bytecode.Lookup(op)
+	c.chunk.Emit(byte(op), c.currentLine)
+	for i, operand := range operands {
+		if operand&tempOperand != 0 {
+			c.tempRefs = append(c.tempRefs, len(c.chunk.Code))
+			operand &^= tempOperand
+		}
+		start := len(c.chunk.Code)
+		c.chunk.Code = bytecode.AppendOperand(c.chunk.Code, def.OperandWidths[i], operand)
+		for range c.chunk.Code[start:] {
+			c.chunk.Lines = append(c.chunk.Lines, c.currentLine)
+		}
+	}
+}
+
+// emitCover emits an OpCover instruction for the given counter.
+// Negative counters (nodes unknown to the profile) are skipped.
+func (c *Compiler) emitCover(counter int) {
+	if counter < 0 {
+		return
 	}
+	c.emit(bytecode.OpCover, counter)
+}
+
+// emitJump emits a jump with a placeholder target as its last operand and
+// returns the offset of that operand for patchJump
+func (c *Compiler) emitJump(op bytecode.OpCode, operands ...int) int {
+	c.emit(op, append(operands, 0)...)
+	return len(c.chunk.Code) - jumpWidth
 }
 
-func (c *Compiler) emitJump(op bytecode.OpCode) int {
-	c.emit(op, 0xff, 0xff)
-	return len(c.chunk.Code) - 2
+// patchJump points the jump operand at offset to the current end of code
+func (c *Compiler) patchJump(offset int) error {
+	return c.patchJumpTo(offset, len(c.chunk.Code))
 }
 
-func (c *Compiler) patchJump(offset int) {
-	target := len(c.chunk.Code)
-	if target > 0xFFFF {
-		panic("program too large")
+// patchJumpTo writes target into the jump operand at offset
+func (c *Compiler) patchJumpTo(offset, target int) error {
+	if int64(target) > bytecode.OperandLimit(jumpWidth) {
+		return fmt.Errorf("%s: program too large: jump target %d does not fit in a %d-byte operand", c.chunk.Position(c.chunk.Lines[offset]), target, jumpWidth)
 	}
-	c.chunk.Code[offset] = byte(target >> 8)
-	c.chunk.Code[offset+1] = byte(target)
+	binary.BigEndian.PutUint32(c.chunk.Code[offset:], uint32(target))
+	return nil
+}
+
+// constKey identifies a constant for deduplication. Numbers are keyed by
+// their bits, so -0 and 0 are kept apart (folding can produce -0 and PRINT
+// shows the sign).
+type constKey struct {
+	isString bool
+	bits     uint64
+	str      string
 }
 
 // addConstant adds a constant to the pool with deduplication.
 // If an identical constant already exists, returns its index instead of adding a duplicate.
 func (c *Compiler) addConstant(val interpreter.Value) int {
-	// Check for existing identical constant
-	for i, existing := range c.chunk.Constants {
-		if existing == val {
-			return i
-		}
+	key := constKey{isString: val.IsString()}
+	if key.isString {
+		key.str = val.String()
+	} else {
+		key.bits = math.Float64bits(val.AsNumber())
+	}
+	if idx, ok := c.constants[key]; ok {
+		return idx
 	}
-	return c.chunk.AddConstant(val)
+	idx := c.chunk.AddConstant(val)
+	c.constants[key] = idx
+	return idx
 }
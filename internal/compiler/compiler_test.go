@@ -0,0 +1,367 @@
+package compiler_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vm"
+)
+
+// TestLargeProgram checks programs whose code, constant pool and variable
+// count all exceed what 2-byte operands could address
+func TestLargeProgram(t *testing.T) {
+	// Built directly: parsing 70000 lines would dominate the test time
+	const lines = 70000
+	prog := &ast.Program{}
+	add := func(num int, stmts ...ast.Node) {
+		prog.Lines = append(prog.Lines, &ast.Line{LineNumber: num, Statements: stmts})
+	}
+	sum := &ast.Identifier{Name: "S"}
+	add(1, &ast.GosubStmt{LineNumber: 90000})
+	for i := 0; i < lines; i++ {
+		// A distinct variable and constant on every line
+		add(10+i,
+			&ast.Assignment{Target: &ast.Identifier{Name: fmt.Sprintf("V%d", i)}, Value: &ast.Number{Value: float64(i)}},
+			&ast.Assignment{Target: sum, Value: &ast.BinaryOp{Left: sum, Op: "+", Right: &ast.Number{Value: 1}}})
+	}
+	add(89999, &ast.PrintStmt{Values: []ast.Node{sum}}, &ast.EndStmt{})
+	add(90000, &ast.PrintStmt{Values: []ast.Node{&ast.StringLiteral{Value: "START"}}})
+	add(90010, &ast.ReturnStmt{})
+	want := fmt.Sprintf("START\n%d\n", lines)
+
+	for _, backend := range []string{"stack", "register"} {
+		t.Run(backend, func(t *testing.T) {
+			var opts []compiler.Option
+			if backend == "register" {
+				opts = append(opts, compiler.WithRegisters())
+			}
+			chunk, err := compiler.New(opts...).Compile(prog)
+			if err != nil {
+				t.Fatalf("compile error: %v", err)
+			}
+			if len(chunk.Code) <= 0xFFFF || len(chunk.Constants) <= 0xFFFF || chunk.GlobalCount <= 0xFFFF {
+				t.Fatalf("program not large enough: %d bytes, %d constants, %d globals", len(chunk.Code), len(chunk.Constants), chunk.GlobalCount)
+			}
+
+			var out bytes.Buffer
+			var runErr error
+			if backend == "register" {
+				runErr = vm.NewRegister(chunk, vm.WithOutput(&out)).Run()
+			} else {
+				// Round-trip through the .zbc format first
+				var file bytes.Buffer
+				if err := chunk.Write(&file); err != nil {
+					t.Fatalf("write error: %v", err)
+				}
+				chunk, err = bytecode.ReadChunk(&file)
+				if err != nil {
+					t.Fatalf("read error: %v", err)
+				}
+				runErr = vm.New(chunk, vm.WithOutput(&out)).Run()
+			}
+			if runErr != nil {
+				t.Fatalf("runtime error: %v", runErr)
+			}
+			if out.String() != want {
+				t.Errorf("output = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+// writeChunk compiles src and serializes it
+func writeChunk(t *testing.T, src string, opts ...compiler.Option) (*bytecode.Chunk, []byte) {
+	t.Helper()
+	chunk, err := compiler.New(opts...).Compile(parse(t, src))
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	chunk.Source = src
+	var file bytes.Buffer
+	if err := chunk.Write(&file); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	return chunk, file.Bytes()
+}
+
+func TestChunkFormatRoundTrip(t *testing.T) {
+	const src = "10 DIM A(3)\n20 FOR I = 1 TO 3\n30 A(I) = I * 2\n40 NEXT I\n50 PRINT \"sum\"; A(1) + A(3)\n"
+	for _, backend := range []string{"stack", "register"} {
+		t.Run(backend, func(t *testing.T) {
+			var opts []compiler.Option
+			if backend == "register" {
+				opts = append(opts, compiler.WithRegisters())
+			}
+			orig, data := writeChunk(t, src, opts...)
+			got, err := bytecode.ReadChunk(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("read error: %v", err)
+			}
+			if !bytes.Equal(got.Code, orig.Code) || fmt.Sprint(got.Lines) != fmt.Sprint(orig.Lines) ||
+				fmt.Sprint(got.Constants) != fmt.Sprint(orig.Constants) {
+				t.Errorf("code, lines or constants changed in the round trip")
+			}
+			if got.GlobalCount != orig.GlobalCount || got.ArrayCount != orig.ArrayCount || got.Registers != orig.Registers {
+				t.Errorf("header = %d globals, %d arrays, registers %v; want %d, %d, %v",
+					got.GlobalCount, got.ArrayCount, got.Registers, orig.GlobalCount, orig.ArrayCount, orig.Registers)
+			}
+			if strings.Join(got.GlobalNames, ",") != "I" || strings.Join(got.ArrayNames, ",") != "A" {
+				t.Errorf("names = %v %v, want [I] [A]", got.GlobalNames, got.ArrayNames)
+			}
+			if got.Source != src {
+				t.Errorf("source = %q, want %q", got.Source, src)
+			}
+
+			listing := got.Disassemble("test")
+			for _, want := range []string{"; 30 A(I) = I * 2", "(A)", "(I)"} {
+				if !strings.Contains(listing, want) {
+					t.Errorf("disassembly has no %q:\n%s", want, listing)
+				}
+			}
+		})
+	}
+}
+
+func TestReadChunkRejectsBadFiles(t *testing.T) {
+	_, data := writeChunk(t, "10 X = 1\n20 PRINT X\n")
+	corrupt := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), data...))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "invalid bytecode header"},
+		{"old version", []byte("ZBC\x02\x00\x00\x00\x00"), "unsupported bytecode version 2"},
+		{"future version", corrupt(func(b []byte) []byte { b[3] = 99; return b }), "unsupported bytecode version 99"},
+		{"flipped bit", corrupt(func(b []byte) []byte { b[len(b)/2] ^= 0x10; return b }), "checksum mismatch"},
+		{"truncated", data[:len(data)-9], "checksum mismatch"},
+		{"short header", data[:6], "truncated header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bytecode.ReadChunk(bytes.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestVerifySamples checks that everything the compiler emits for the
+// sample programs passes the bytecode verifier
+func TestVerifySamples(t *testing.T) {
+	files, err := filepath.Glob("../../samples/*.bas")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no samples found: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog := parse(t, string(data))
+		for _, registers := range []bool{false, true} {
+			for _, level := range []int{compiler.OptNone, compiler.OptDeadCode} {
+				opts := []compiler.Option{compiler.WithOptimization(level)}
+				if registers {
+					opts = append(opts, compiler.WithRegisters())
+				}
+				chunk, err := compiler.New(opts...).Compile(prog)
+				if err != nil {
+					continue // Compile errors are covered elsewhere
+				}
+				if _, err := bytecode.Verify(chunk); err != nil {
+					t.Errorf("%s (registers %v, -O%d): %v", filepath.Base(file), registers, level, err)
+				}
+			}
+		}
+	}
+}
+
+// TestChain runs a CHAIN through both virtual machines and the interpreter:
+// COMMON variables survive, ALL keeps everything and a line number selects
+// where the next program starts
+func TestChain(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	c := file("c.bas", "10 PRINT A; C; N$\n")
+	b := file("b.bas", fmt.Sprintf("10 PRINT \"SKIPPED\"\n20 PRINT A; N$\n30 C = 3\n40 CHAIN %q,, ALL\n", c))
+	src := fmt.Sprintf("10 COMMON A, N$\n20 A = 1: B = 2: N$ = \"X\"\n30 CHAIN %q, 15\n40 PRINT \"NOT REACHED\"\n", b)
+	const want = "1X\n13X\n"
+
+	var out bytes.Buffer
+	if err := interpreter.NewInterpreter(interpreter.WithOutput(&out)).Run(parse(t, src)); err != nil {
+		t.Errorf("interpreter: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("interpreter printed %q, want %q", out.String(), want)
+	}
+	for _, registers := range []bool{false, true} {
+		var opts []compiler.Option
+		if registers {
+			opts = append(opts, compiler.WithRegisters())
+		}
+		chunk, err := compiler.New(opts...).Compile(parse(t, src))
+		if err != nil {
+			t.Fatalf("compile error: %v", err)
+		}
+		out.Reset()
+		vmOpts := []vm.Option{vm.WithOutput(&out), vm.WithChain(compiler.ChainLoader(opts...))}
+		if registers {
+			err = vm.NewRegister(chunk, vmOpts...).Run()
+		} else {
+			err = vm.New(chunk, vmOpts...).Run()
+		}
+		if err != nil {
+			t.Errorf("registers %v: %v", registers, err)
+		}
+		if out.String() != want {
+			t.Errorf("registers %v printed %q, want %q", registers, out.String(), want)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	const lib = `MODULE mathutil
+EXPORT FUNCTION Hyp(A, B)
+  Hyp = SQR(Square(A) + Square(B))
+END FUNCTION
+FUNCTION Square(X)
+  Square = X * X
+END FUNCTION
+EXPORT SUB Bump(N)
+  SHARED Total
+  Total = Total + N
+END SUB
+`
+	const src = `DECLARE FUNCTION Hyp(A, B)
+DECLARE SUB Bump(N)
+Total = 10
+FOR I = 1 TO 3: CALL Bump(I): NEXT I
+PRINT Hyp(3, 4); Total
+`
+	const want = "516\n"
+
+	for _, level := range []int{0, 2} {
+		for _, registers := range []bool{false, true} {
+			opts := []compiler.Option{compiler.WithOptimization(level)}
+			if registers {
+				opts = append(opts, compiler.WithRegisters())
+			}
+			compile := func(src string) *bytecode.Chunk {
+				prog, err := parser.ParseProgram("test.bas", []byte(src))
+				if err != nil {
+					t.Fatalf("parse error: %v", err)
+				}
+				chunk, err := compiler.New(opts...).Compile(prog)
+				if err != nil {
+					t.Fatalf("compile error: %v", err)
+				}
+				return chunk
+			}
+			module, program := compile(lib), compile(src)
+			if err := program.Runnable(); err == nil || !strings.Contains(err.Error(), "unresolved imports HYP, BUMP") {
+				t.Errorf("unlinked program: Runnable() = %v", err)
+			}
+			if _, err := bytecode.Link(program, program); err == nil {
+				t.Errorf("linking two programs succeeded")
+			}
+
+			linked, err := bytecode.Link(module, program)
+			if err != nil {
+				t.Fatalf("O%d registers %v: link error: %v", level, registers, err)
+			}
+			var out bytes.Buffer
+			if registers {
+				err = vm.NewRegister(linked, vm.WithOutput(&out)).Run()
+			} else {
+				err = vm.New(linked, vm.WithOutput(&out)).Run()
+			}
+			if err != nil {
+				t.Errorf("O%d registers %v: %v", level, registers, err)
+			}
+			if out.String() != want {
+				t.Errorf("O%d registers %v printed %q, want %q", level, registers, out.String(), want)
+			}
+		}
+	}
+}
+
+// TestUnnumberedPositions checks that a break in a program without line
+// numbers reports its source position, through .zbc files and linking too
+func TestUnnumberedPositions(t *testing.T) {
+	parseFile := func(name, src string) *ast.Program {
+		prog, err := parser.ParseProgram(name, []byte(src))
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		return prog
+	}
+	const lib = "MODULE m\n\nEXPORT SUB Halt\n  STOP\nEND SUB\n"
+	const src = "DECLARE SUB Halt\nPRINT 1;\n\nSTOP\nCALL Halt\n"
+
+	var brk *interpreter.Break
+	err := interpreter.NewInterpreter(interpreter.WithOutput(io.Discard)).Run(parseFile("main.bas", src))
+	if !errors.As(err, &brk) || brk.Error() != "Break at main.bas:4" {
+		t.Errorf("interpreter: Run() = %v, want Break at main.bas:4", err)
+	}
+
+	for _, level := range []int{0, 2} {
+		for _, registers := range []bool{false, true} {
+			opts := []compiler.Option{compiler.WithOptimization(level)}
+			if registers {
+				opts = append(opts, compiler.WithRegisters())
+			}
+			compile := func(name, src string) *bytecode.Chunk {
+				chunk, err := compiler.New(opts...).Compile(parseFile(name, src))
+				if err != nil {
+					t.Fatalf("compile error: %v", err)
+				}
+				var file bytes.Buffer
+				if err := chunk.Write(&file); err != nil {
+					t.Fatalf("write error: %v", err)
+				}
+				chunk, err = bytecode.ReadChunk(&file)
+				if err != nil {
+					t.Fatalf("read error: %v", err)
+				}
+				return chunk
+			}
+			linked, err := bytecode.Link(compile("main.bas", src), compile("lib.bas", lib))
+			if err != nil {
+				t.Fatalf("link error: %v", err)
+			}
+			var machine interface{ Run() error }
+			if registers {
+				machine = vm.NewRegister(linked, vm.WithOutput(io.Discard))
+			} else {
+				machine = vm.New(linked, vm.WithOutput(io.Discard))
+			}
+			for _, want := range []string{"Break at main.bas:4", "Break at lib.bas:4"} {
+				if err := machine.Run(); err == nil || err.Error() != want {
+					t.Errorf("O%d registers %v: Run() = %v, want %s", level, registers, err, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package compiler_test
+
+import (
+	"bytes"
+	"testing"
+
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vm"
+)
+
+// runEngines runs a program on the interpreter, the stack VM and the register
+// VM (unoptimised and at -O2) and returns its output, failing the test if the
+// engines disagree.
+func runEngines(t *testing.T, src string) string {
+	t.Helper()
+	prog, err := parser.ParseProgram("test.bas", []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	var want bytes.Buffer
+	if err := interpreter.NewInterpreter(interpreter.WithOutput(&want)).Run(prog); err != nil {
+		t.Fatalf("interpreter: runtime error: %v", err)
+	}
+	for _, level := range []int{compiler.OptNone, compiler.OptDeadCode} {
+		for _, registers := range []bool{false, true} {
+			opts := []compiler.Option{compiler.WithOptimization(level)}
+			if registers {
+				opts = append(opts, compiler.WithRegisters())
+			}
+			chunk, err := compiler.New(opts...).Compile(prog)
+			if err != nil {
+				t.Fatalf("-O%d registers %v: compile error: %v", level, registers, err)
+			}
+			var out bytes.Buffer
+			var machine interface{ Run() error }
+			if registers {
+				machine = vm.NewRegister(chunk, vm.WithOutput(&out))
+			} else {
+				machine = vm.New(chunk, vm.WithOutput(&out))
+			}
+			if err := machine.Run(); err != nil {
+				t.Fatalf("-O%d registers %v: runtime error: %v", level, registers, err)
+			}
+			if out.String() != want.String() {
+				t.Errorf("-O%d registers %v: output differs from the interpreter:\nwant %q\ngot  %q", level, registers, want.String(), out.String())
+			}
+		}
+	}
+	return want.String()
+}
+
+func TestRecordExecution(t *testing.T) {
+	const types = "TYPE Pt: X AS DOUBLE: Y AS DOUBLE: END TYPE\nTYPE Item: Name AS STRING: Price AS DOUBLE: At AS Pt: END TYPE\n"
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"nested field assignment", "DIM It AS Item\nIt.At.X = 3\nIt.At.Y = It.At.X * 2\nIt.Name = \"bolt\"\nPRINT It.Name; \" \"; It.At.X; \" \"; It.At.Y; \" \"; It.Price\n", "bolt 3 6 0\n"},
+		{"record arrays", "DIM Inv(3) AS Item\nFOR I = 0 TO 2\nInv(I).Name = CHR$(65 + I)\nInv(I).Price = I * 1.5\nInv(I).At.Y = Inv(I).Price * 2\nNEXT I\nFOR I = 2 TO 0 STEP -1\nPRINT Inv(I).Name; \" \"; Inv(I).Price; \" \"; Inv(I).At.Y\nNEXT I\n", "C 3 6\nB 1.5 3\nA 0 0\n"},
+		{"LET of a whole record", "DIM It AS Item\nDIM Inv(2) AS Item\nIt.Name = \"nut\": It.At.X = 4\nLET Inv(1) = It\nIt.At.X = 9\nDIM Copy AS Item\nCopy = Inv(1)\nPRINT Inv(1).Name; \" \"; Inv(1).At.X; \" \"; It.At.X; \" \"; Copy.Name; \" \"; Copy.At.X\n", "nut 4 9 nut 4\n"},
+		{"nested record copy", "DIM It AS Item\nDIM Inv(2) AS Item\nIt.At.X = 1: It.At.Y = 2\nInv(1).At = It.At\nIt.At.Y = 5\nPRINT Inv(1).At.X; \" \"; Inv(1).At.Y; \" \"; It.At.Y\n", "1 2 5\n"},
+		{"record parameters are copied", "DIM It AS Item\nIt.Price = 2\nBump It\nPRINT It.Price; \" \"; Twice(It)\nSUB Bump(P AS Item)\nP.Price = P.Price + 1\nPRINT P.Price\nEND SUB\nFUNCTION Twice(P AS Item)\nTwice = P.Price * 2\nEND FUNCTION\n", "3\n2 4\n"},
+		{"recursion keeps each call's record", "DIM Start AS Pt\nStart.X = 3\nDown Start\nSUB Down(P AS Pt)\nIF P.X = 0 THEN EXIT SUB\nDIM Next1 AS Pt\nNext1.X = P.X - 1\nDown Next1\nPRINT P.X;\nEND SUB\n", "123"},
+		{"DIM clears a record", "DIM It AS Item\nIt.Name = \"x\": It.Price = 7\nDIM It AS Item\nPRINT \"[\"; It.Name; \"]\"; It.Price\n", "[]0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runEngines(t, types+tt.src); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestArrayExecution covers array reads in expressions and array assignments,
+// whose indices are compiled before the value because OpSetArray pops the
+// value first.
+func TestArrayExecution(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"read in expressions", "DIM A(5)\nA(1) = 2: A(2) = 3\nPRINT A(1) * A(2) + A(A(1)); \" \"; A(4)\n", "9 0\n"},
+		{"value reads the same array", "DIM A(6)\nA(0) = 1\nFOR I = 1 TO 5\nA(I) = A(I - 1) * 2\nNEXT I\nPRINT A(5); \" \"; A(4)\n", "32 16\n"},
+		{"two dimensions", "DIM M(4, 5)\nFOR I = 0 TO 3\nFOR J = 0 TO 4\nM(I, J) = I * 10 + J\nNEXT J\nNEXT I\nPRINT M(2, 3); \" \"; M(3, 2); \" \"; M(M(0, 1), 4)\n", "23 32 14\n"},
+		{"string arrays", "DIM N$(3)\nN$(0) = \"a\": N$(1) = N$(0) + \"b\"\nN$(2) = N$(1) + N$(1)\nPRINT N$(2); LEN(N$(1))\n", "abab2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runEngines(t, tt.src); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapExecution(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"missing keys", "DIM D AS MAP: DIM S$ AS MAP\nD(\"a\") = 1\nPRINT D(\"zz\"); HASKEY(D, \"zz\"); COUNT(D); \"[\"; S$(\"zz\"); \"]\"; COUNT(S$)\nDELETEKEY D, \"zz\"\nPRINT COUNT(D)\n", "001[]0\n1\n"},
+		{"KEYS$ ordering", "DIM D AS MAP\nD(\"pear\") = 1: D(\"Apple\") = 2: D(\"apple\") = 3: D(\"10\") = 4: D(\"9\") = 5\nFOR I = 0 TO COUNT(D) - 1\nPRINT KEYS$(D, I); \" \";\nNEXT I\nPRINT\nFOR EACH K$ IN D\nPRINT K$; \"=\"; D(K$); \" \";\nNEXT K$\nPRINT\n", "10 9 Apple apple pear \n10=4 9=5 Apple=2 apple=3 pear=1 \n"},
+		{"DELETEKEY during FOR EACH", "DIM D AS MAP\nD(\"a\") = 1: D(\"b\") = 2: D(\"c\") = 3\nFOR EACH K$ IN D\nDELETEKEY D, \"b\": D(\"d\") = 4\nPRINT K$; D(K$); \" \";\nNEXT\nPRINT\nPRINT COUNT(D); KEYS$(D, 2)\n", "a1 b0 c3 \n3d\n"},
+		{"empty map skips the loop", "DIM D AS MAP\nFOR EACH K$ IN D\nPRINT \"never\"\nNEXT: PRINT \"after\"\nPRINT \"end\"\n", "after\nend\n"},
+		{"one-line loop", "DIM D AS MAP\nD(\"x\") = 1: D(\"y\") = 2\nFOR EACH K$ IN D: PRINT K$;: NEXT: PRINT \".\"\n", "xy.\n"},
+		{"nested loops", "DIM D AS MAP\nD(\"x\") = 1: D(\"y\") = 2\nFOR EACH A$ IN D\nFOR EACH B$ IN D\nPRINT A$; B$; \" \";\nNEXT B$\nNEXT A$\nPRINT\n", "xx xy yx yy \n"},
+		{"in a SUB", "DIM D AS MAP\nD(\"x\") = 1: D(\"y\") = 2\nCALL Show\nSUB Show\nSHARED D()\nFOR EACH K$ IN D\nPRINT K$; D(K$);\nNEXT\nPRINT\nEND SUB\n", "x1y2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runEngines(t, tt.src); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutineExecution(t *testing.T) {
+	const routines = "FUNCTION Bump(X)\nSHARED I\nPRINT \"[\"; X; \"]\";\nI = I + 100\nBump = X * 10\nEND FUNCTION\nSUB Show(A, B$)\nPRINT B$; A\nEND SUB\n"
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"PRINT before the call", "I = 1\nPRINT I; \" \"; Bump(I); \" \"; I\nPRINT I, Bump(2), I\nEND\n", "1 [1]10 101\n101[2] 20 201\n"},
+		{"operands left of the call", "I = 1\nX = I + Bump(3) * 2 + I\nPRINT X\nEND\n", "[3]162\n"},
+		{"index before value", "DIM A(200)\nI = 5\nA(I) = Bump(I) + I\nPRINT A(5); \" \"; A(105)\nEND\n", "[5]155 0\n"},
+		{"FOR bounds", "I = 1\nFOR J = I TO Bump(0) + I - 98\nPRINT J; \" \";\nNEXT J\nPRINT\nEND\n", "[0]1 2 3 \n"},
+		{"recursion", "PRINT Fact(5); Fib(10)\nHanoi 2, \"A\", \"B\", \"C\"\nPRINT IsEven(7)\nEND\n" +
+			"FUNCTION Fact(N)\nIF N <= 1 THEN Fact = 1 ELSE Fact = N * Fact(N - 1)\nEND FUNCTION\n" +
+			"FUNCTION Fib(N)\nIF N < 2 THEN Fib = N ELSE Fib = Fib(N - 1) + Fib(N - 2)\nEND FUNCTION\n" +
+			"SUB Hanoi(N, A$, B$, C$)\nIF N = 0 THEN EXIT SUB\nHanoi N - 1, A$, C$, B$\nPRINT A$; C$; \" \";\nHanoi N - 1, B$, A$, C$\nEND SUB\n" +
+			"FUNCTION IsEven(N)\nIF N = 0 THEN IsEven = 1 ELSE IsEven = IsOdd(N - 1)\nEND FUNCTION\n" +
+			"FUNCTION IsOdd(N)\nIF N = 0 THEN IsOdd = 0 ELSE IsOdd = IsEven(N - 1)\nEND FUNCTION\n",
+			"12055\nAB AC BC 0\n"},
+		{"locals across recursion", "Fill 2\nFill 1\nPRINT\nEND\nSUB Fill(N)\nDIM T(3)\nT(1) = N\nK = K + N\nIF N > 0 THEN Fill N - 1\nPRINT T(1); K; \" \";\nEND SUB\n", "00 11 22 00 11 \n"},
+		{"SUB without CALL", "Show 1, \"a\"\nShow(2, \"b\"): CALL Show(3, \"c\")\nIF 1 THEN Show 4, \"d\" ELSE Show 5, \"e\"\nEND\n", "a1\nb2\nc3\nd4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runEngines(t, tt.src+routines); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package compiler
+
+import (
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+)
+
+// linkTables fills in the module name and the export and import tables
+// of the chunk. An export points at the first instruction of its routine;
+// an import lists every OpGosub that calls a routine of another module,
+// whose target stays zero until bytecode.Link patches it. Routines that
+// are declared but never called are not imported.
+func (c *Compiler) linkTables(prog *ast.Program) {
+	c.chunk.Module = prog.Module
+	for _, r := range prog.Routines {
+		sym := bytecode.Symbol{Name: r.Name, Params: r.Params, Function: r.Function}
+		switch {
+		case r.Export:
+			sym.Offset = c.lineOffsets[r.Line]
+			c.chunk.Exports = append(c.chunk.Exports, sym)
+		case r.Extern && len(c.imports[r.Name]) > 0:
+			sym.Calls = c.imports[r.Name]
+			c.chunk.Imports = append(c.chunk.Imports, sym)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/interpreter"
+)
+
+// Optimization levels accepted by WithOptimization and Optimize
+const (
+	OptNone     = 0 // No AST rewriting
+	OptFold     = 1 // Constant folding and superinstructions
+	OptDeadCode = 2 // OptFold plus dead-code elimination
+)
+
+// impureBuiltins lists builtins whose result is not determined by their
+// arguments; calls to them are never folded.
+var impureBuiltins = map[string]bool{
+	"RND": true,
+}
+
+// OptStats summarises the rewrites performed by Optimize
+type OptStats struct {
+	Folded   int // Constant expressions replaced by a literal
+	Branches int // IF statements with a constant condition resolved at compile time
+	Removed  int // Unreachable statements removed
+	Fused    int // Instruction sequences fused into superinstructions
+}
+
+// String formats the statistics for the disassembly header
+func (s OptStats) String() string {
+	return fmt.Sprintf("folded %d expressions, resolved %d branches, removed %d statements, fused %d instruction sequences",
+		s.Folded, s.Branches, s.Removed, s.Fused)
+}
+
+// optimizer holds the state of a single Optimize run
+type optimizer struct {
+	level int
+	stats OptStats
+}
+
+// Optimize rewrites the program before code generation. The input program is
+// left untouched; the result shares unchanged expression subtrees with it.
+// Every source line is kept (possibly empty) so line numbers stay valid jump
+// targets and line indices still match the original program.
+func Optimize(prog *ast.Program, level int) (*ast.Program, OptStats) {
+	o := &optimizer{level: level}
+	if level <= OptNone {
+		return prog, o.stats
+	}
+
+	out := &ast.Program{
+		Lines:      make([]*ast.Line, len(prog.Lines)),
+		Unnumbered: prog.Unnumbered,
+		Module:     prog.Module,
+		Routines:   prog.Routines,
+		Decls:      prog.Decls,
+	}
+	for i, line := range prog.Lines {
+		out.Lines[i] = &ast.Line{
+			LineNumber: line.LineNumber,
+			Label:      line.Label,
+			Statements: o.optimizeBlock(line.Statements),
+			Pos:        line.Pos,
+		}
+	}
+	if level >= OptDeadCode {
+		o.eliminateDeadCode(out)
+	}
+	return out, o.stats
+}
+
+// optimizeBlock folds every statement in a block, splicing in the taken
+// branch of IF statements whose condition is constant.
+func (o *optimizer) optimizeBlock(stmts []ast.Node) []ast.Node {
+	out := make([]ast.Node, 0, len(stmts))
+	for _, stmt := range stmts {
+		ifStmt, ok := stmt.(*ast.IfStmt)
+		if !ok {
+			out = append(out, o.foldStmt(stmt))
+			continue
+		}
+
+		cond := o.foldExpr(ifStmt.Condition)
+		thenStmts := o.optimizeBlock(ifStmt.ThenStmts)
+		elseStmts := o.optimizeBlock(ifStmt.ElseStmts)
+		if o.level >= OptDeadCode && isLiteral(cond) {
+			val, _ := interpreter.EvaluateConstant(cond)
+			taken, dropped := thenStmts, elseStmts
+			if !val.IsTrue() {
+				taken, dropped = elseStmts, thenStmts
+			}
+			// FOR/NEXT are matched lexically, so a discarded branch that
+			// contains one would change how the remaining loops pair up.
+			if !containsLoop(dropped) {
+				o.stats.Branches++
+				out = append(out, taken...)
+				continue
+			}
+		}
+		out = append(out, &ast.IfStmt{Condition: cond, ThenStmts: thenStmts, ElseStmts: elseStmts})
+	}
+	return out
+}
+
+// foldStmt returns a copy of stmt with all of its expressions folded
+func (o *optimizer) foldStmt(stmt ast.Node) ast.Node {
+	switch s := stmt.(type) {
+	case *ast.Assignment:
+		return &ast.Assignment{Target: o.foldExpr(s.Target), Value: o.foldExpr(s.Value)}
+	case *ast.RecordAssignment:
+		return &ast.RecordAssignment{Target: o.foldExpr(s.Target), Value: o.foldExpr(s.Value)}
+	case *ast.PrintStmt:
+		values := make([]ast.Node, len(s.Values))
+		for i, v := range s.Values {
+			values[i] = o.foldExpr(v)
+		}
+		return &ast.PrintStmt{Values: values, Separators: s.Separators, Trailer: s.Trailer}
+	case *ast.ForStmt:
+		return &ast.ForStmt{Var: s.Var, Start: o.foldExpr(s.Start), End: o.foldExpr(s.End), Step: o.foldExpr(s.Step)}
+	case *ast.DimStmt:
+		sizes := make([]ast.Node, len(s.Sizes))
+		for i, size := range s.Sizes {
+			sizes[i] = o.foldExpr(size)
+		}
+		return &ast.DimStmt{Name: s.Name, Sizes: sizes, Type: s.Type, Record: s.Record}
+	case *ast.DeleteKeyStmt:
+		return &ast.DeleteKeyStmt{Map: s.Map, Key: o.foldExpr(s.Key)}
+	case *ast.ChainStmt:
+		chain := &ast.ChainStmt{File: o.foldExpr(s.File), All: s.All}
+		if s.Line != nil {
+			chain.Line = o.foldExpr(s.Line)
+		}
+		return chain
+	}
+	return stmt
+}
+
+// foldExpr folds constant subexpressions bottom-up. Expressions whose
+// evaluation would fail are left alone so the error surfaces at run time.
+func (o *optimizer) foldExpr(expr ast.Node) ast.Node {
+	switch n := expr.(type) {
+	case *ast.BinaryOp:
+		left, right := o.foldExpr(n.Left), o.foldExpr(n.Right)
+		folded := &ast.BinaryOp{Left: left, Op: n.Op, Right: right}
+		if isLiteral(left) && isLiteral(right) {
+			return o.evaluate(folded)
+		}
+		return folded
+
+	case *ast.ComparisonOp:
+		left, right := o.foldExpr(n.Left), o.foldExpr(n.Right)
+		folded := &ast.ComparisonOp{Left: left, Op: n.Op, Right: right}
+		if isLiteral(left) && isLiteral(right) {
+			return o.evaluate(folded)
+		}
+		return folded
+
+	case *ast.LogicalOp:
+		left, right := o.foldExpr(n.Left), o.foldExpr(n.Right)
+		folded := &ast.LogicalOp{Left: left, Op: n.Op, Right: right}
+		if isLiteral(left) && isLiteral(right) {
+			return o.evaluate(folded)
+		}
+		return folded
+
+	case *ast.UnaryOp:
+		right := o.foldExpr(n.Right)
+		folded := &ast.UnaryOp{Op: n.Op, Right: right}
+		// Only numeric operands: the VM rejects -"X" while the AST
+		// interpreter converts the string to a number.
+		if _, ok := right.(*ast.Number); ok {
+			return o.evaluate(folded)
+		}
+		return folded
+
+	case *ast.FunctionCall:
+		args := make([]ast.Node, len(n.Args))
+		constant := true
+		for i, arg := range n.Args {
+			args[i] = o.foldExpr(arg)
+			constant = constant && isLiteral(args[i])
+		}
+		folded := &ast.FunctionCall{Name: n.Name, Args: args}
+		name := strings.ToUpper(n.Name)
+		if constant && !impureBuiltins[name] {
+			return o.evaluate(folded)
+		}
+		return folded
+
+	case *ast.ArrayAccess:
+		indices := make([]ast.Node, len(n.Indices))
+		for i, idx := range n.Indices {
+			indices[i] = o.foldExpr(idx)
+		}
+		return &ast.ArrayAccess{Name: n.Name, Indices: indices}
+
+	case *ast.MapAccess:
+		return &ast.MapAccess{Name: n.Name, Key: o.foldExpr(n.Key)}
+
+	case *ast.RecordRef:
+		indices := make([]ast.Node, len(n.Indices))
+		for i, idx := range n.Indices {
+			indices[i] = o.foldExpr(idx)
+		}
+		return &ast.RecordRef{Name: n.Name, Indices: indices, Type: n.Type}
+
+	case *ast.FieldAccess:
+		return &ast.FieldAccess{Record: o.foldExpr(n.Record), Field: n.Field, Type: n.Type}
+
+	case *ast.MapFunc:
+		if n.Arg == nil {
+			return n
+		}
+		return &ast.MapFunc{Func: n.Func, Map: n.Map, Arg: o.foldExpr(n.Arg)}
+	}
+	return expr
+}
+
+// evaluate replaces a constant expression with its literal value
+func (o *optimizer) evaluate(expr ast.Node) ast.Node {
+	val, ok := interpreter.EvaluateConstant(expr)
+	if !ok {
+		return expr
+	}
+	o.stats.Folded++
+	if val.IsString() {
+		return &ast.StringLiteral{Value: val.String()}
+	}
+	return &ast.Number{Value: val.AsNumber()}
+}
+
+// isLiteral reports whether expr is a number or string literal
+func isLiteral(expr ast.Node) bool {
+	switch expr.(type) {
+	case *ast.Number, *ast.StringLiteral:
+		return true
+	}
+	return false
+}
+
+// containsLoop reports whether a block contains FOR, FOR EACH or NEXT at any depth
+func containsLoop(stmts []ast.Node) bool {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.ForStmt, *ast.ForEachStmt, *ast.NextStmt:
+			return true
+		case *ast.IfStmt:
+			if containsLoop(s.ThenStmts) || containsLoop(s.ElseStmts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isStructural reports whether a statement must be kept even when it is
+// unreachable, because the compiler pairs it with another statement or,
+// for COMMON, reads it wherever it is.
+func isStructural(stmt ast.Node) bool {
+	switch stmt.(type) {
+	case *ast.ForStmt, *ast.ForEachStmt, *ast.NextStmt, *ast.IfBlockStmt, *ast.ElseBlockStmt, *ast.EndIfStmt, *ast.RemStmt, *ast.CommonStmt:
+		return true
+	}
+	return false
+}
+
+// isTerminator reports whether control never falls through a statement
+func isTerminator(stmt ast.Node) bool {
+	switch stmt.(type) {
+	case *ast.GotoStmt, *ast.ReturnStmt, *ast.EndStmt, *ast.ChainStmt:
+		return true
+	}
+	return false
+}
+
+// eliminateDeadCode removes top-level statements that can only be reached by
+// falling through an unconditional GOTO, RETURN or END. A line becomes
+// reachable again when a live GOTO/GOSUB targets it; the statement after a
+// FOR or FOR EACH becomes reachable when its matching NEXT is live, and the
+// statement after the NEXT of a live FOR EACH, where an empty map jumps.
+func (o *optimizer) eliminateDeadCode(prog *ast.Program) {
+	// Pair FOR and NEXT the same way the compiler does
+	forOf := make(map[*ast.NextStmt]ast.Node)
+	nextOf := make(map[*ast.ForEachStmt]*ast.NextStmt)
+	var stack []ast.Node
+	var match func(stmts []ast.Node)
+	match = func(stmts []ast.Node) {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *ast.ForStmt, *ast.ForEachStmt:
+				stack = append(stack, s)
+			case *ast.NextStmt:
+				if len(stack) > 0 {
+					forOf[s] = stack[len(stack)-1]
+					if each, ok := forOf[s].(*ast.ForEachStmt); ok {
+						nextOf[each] = s
+					}
+					stack = stack[:len(stack)-1]
+				}
+			case *ast.IfStmt:
+				match(s.ThenStmts)
+				match(s.ElseStmts)
+			}
+		}
+	}
+	for _, line := range prog.Lines {
+		match(line.Statements)
+	}
+
+	// Grow the set of labels until no new statement becomes live. Exported
+	// routines are called from other modules, so their entry lines start out
+	// live.
+	targets := make(map[int]bool)
+	for _, r := range prog.Routines {
+		if r.Export {
+			targets[r.Line] = true
+		}
+	}
+	loopTops := make(map[ast.Node]bool)
+	exits := make(map[*ast.NextStmt]bool)
+	var live [][]bool
+	for changed := true; changed; {
+		changed = false
+		live = scanLive(prog, targets, loopTops, exits)
+
+		var collect func(stmts []ast.Node)
+		collect = func(stmts []ast.Node) {
+			for _, stmt := range stmts {
+				switch s := stmt.(type) {
+				case *ast.GotoStmt:
+					if !targets[s.LineNumber] {
+						targets[s.LineNumber] = true
+						changed = true
+					}
+				case *ast.GosubStmt:
+					if !targets[s.LineNumber] {
+						targets[s.LineNumber] = true
+						changed = true
+					}
+				case *ast.NextStmt:
+					if f := forOf[s]; f != nil && !loopTops[f] {
+						loopTops[f] = true
+						changed = true
+					}
+				case *ast.ForEachStmt:
+					if next := nextOf[s]; next != nil && !exits[next] {
+						exits[next] = true
+						changed = true
+					}
+				case *ast.IfStmt:
+					collect(s.ThenStmts)
+					collect(s.ElseStmts)
+				}
+			}
+		}
+		for li, line := range prog.Lines {
+			for si, stmt := range line.Statements {
+				if live[li][si] {
+					collect([]ast.Node{stmt})
+				}
+			}
+		}
+	}
+
+	for li, line := range prog.Lines {
+		kept := line.Statements[:0]
+		for si, stmt := range line.Statements {
+			if live[li][si] || isStructural(stmt) {
+				kept = append(kept, stmt)
+				continue
+			}
+			o.stats.Removed++
+		}
+		line.Statements = kept
+	}
+}
+
+// scanLive marks each top-level statement as live or dead given the
+// currently known jump targets, loop tops and FOR EACH exits.
+func scanLive(prog *ast.Program, targets map[int]bool, loopTops map[ast.Node]bool, exits map[*ast.NextStmt]bool) [][]bool {
+	live := make([][]bool, len(prog.Lines))
+	reachable := true
+	for li, line := range prog.Lines {
+		if targets[line.LineNumber] {
+			reachable = true
+		}
+		live[li] = make([]bool, len(line.Statements))
+		for si, stmt := range line.Statements {
+			live[li][si] = reachable
+			if reachable && isTerminator(stmt) {
+				reachable = false
+			}
+			switch s := stmt.(type) {
+			case *ast.ForStmt, *ast.ForEachStmt:
+				if loopTops[s] {
+					reachable = true
+				}
+			case *ast.NextStmt:
+				if exits[s] {
+					reachable = true
+				}
+			}
+		}
+	}
+	return live
+}
@@ -0,0 +1,128 @@
+package compiler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/coverage"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vm"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	parsed, err := parser.Parse("test.bas", []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	return parsed.(*ast.Program)
+}
+
+func run(t *testing.T, prog *ast.Program, opts ...compiler.Option) (string, *compiler.Compiler) {
+	t.Helper()
+	comp := compiler.New(opts...)
+	chunk, err := comp.Compile(prog)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := vm.New(chunk, vm.WithOutput(&out)).Run(); err != nil {
+		t.Fatalf("runtime error: %v", err)
+	}
+	return out.String(), comp
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		level int
+		want  compiler.OptStats
+	}{
+		{"fold builtin arithmetic", "10 R = 2 * PI() / 360\n20 PRINT R\n", compiler.OptFold, compiler.OptStats{Folded: 3}},
+		{"fold strings and comparisons", "10 PRINT \"A\" + \"B\"; 1 < 2; LEN(\"XYZ\")\n", compiler.OptFold, compiler.OptStats{Folded: 3}},
+		{"keep RND", "10 PRINT INT(RND() * (1 - 1))\n", compiler.OptFold, compiler.OptStats{Folded: 1}},
+		{"keep division by zero", "10 IF 0 THEN PRINT 1 / 0\n", compiler.OptFold, compiler.OptStats{}},
+		{"keep -0", "10 PRINT INT(-0.5); 0\n", compiler.OptFold, compiler.OptStats{Folded: 2}},
+		{"constant IF", "10 IF 0 THEN PRINT 1 ELSE PRINT 2\n20 IF 1 = 1 THEN PRINT 3\n", compiler.OptDeadCode, compiler.OptStats{Folded: 1, Branches: 2}},
+		{"code after GOTO", "10 GOTO 40\n20 PRINT 1\n30 PRINT 2: END\n40 PRINT 3\n", compiler.OptDeadCode, compiler.OptStats{Removed: 3}},
+		{"jump target stays", "10 GOSUB 40\n20 END\n30 PRINT 1\n40 PRINT 2\n50 RETURN\n", compiler.OptDeadCode, compiler.OptStats{Removed: 1}},
+		{"loop top reached by NEXT", "10 FOR I = 1 TO 2\n20 GOTO 40\n30 PRINT 9\n40 PRINT I\n50 NEXT I\n", compiler.OptDeadCode, compiler.OptStats{Removed: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog := parse(t, tt.src)
+			want, _ := run(t, prog)
+			got, comp := run(t, prog, compiler.WithOptimization(tt.level))
+			if got != want {
+				t.Errorf("output changed by -O%d:\nwant %q\ngot  %q", tt.level, want, got)
+			}
+			if comp.OptStats() != tt.want {
+				t.Errorf("stats = %+v, want %+v", comp.OptStats(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOptimizeKeepsInput(t *testing.T) {
+	prog := parse(t, "10 PRINT 1 + 2\n20 GOTO 10\n30 END\n")
+	before := prog.String()
+	compiler.Optimize(prog, compiler.OptDeadCode)
+	if after := prog.String(); after != before {
+		t.Errorf("Optimize modified its input:\nbefore %s\nafter  %s", before, after)
+	}
+}
+
+func TestPeephole(t *testing.T) {
+	src := `10 DIM A(10)
+20 S = 0: N$ = "X"
+30 FOR I = 1 TO 10
+40 A(I - 1) = I * I
+50 S = S + A(I - 1): N$ = N$ + 1
+60 IF S > 100 THEN GOSUB 200
+70 NEXT I
+80 C = 0
+90 C = C + 1
+100 IF C < 5 THEN GOTO 90
+110 PRINT S; C; N$
+120 END
+200 S = S + 0.5
+210 RETURN
+`
+	prog := parse(t, src)
+	want, _ := run(t, prog)
+	got, comp := run(t, prog, compiler.WithOptimization(compiler.OptFold))
+	if got != want {
+		t.Errorf("output changed by peephole:\nwant %q\ngot  %q", want, got)
+	}
+	if comp.OptStats().Fused == 0 {
+		t.Errorf("expected fused instructions, got %+v", comp.OptStats())
+	}
+
+	chunk, err := compiler.New(compiler.WithOptimization(compiler.OptFold)).Compile(prog)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if len(chunk.Lines) != len(chunk.Code) {
+		t.Errorf("len(Lines) = %d, len(Code) = %d", len(chunk.Lines), len(chunk.Code))
+	}
+	listing := chunk.Disassemble("test")
+	for _, op := range []string{"IncGlobal", "AddGlobalConst", "CmpJump", "GetGlobal2"} {
+		if !strings.Contains(listing, op) {
+			t.Errorf("disassembly has no %s:\n%s", op, listing)
+		}
+	}
+
+	// -O is ignored with -cover, peephole included
+	comp = compiler.New(compiler.WithOptimization(compiler.OptFold), compiler.WithCoverage(coverage.New(prog)))
+	if _, err := comp.Compile(prog); err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if comp.OptStats().Fused != 0 {
+		t.Errorf("peephole ran with coverage: %+v", comp.OptStats())
+	}
+}
@@ -0,0 +1,224 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/interpreter"
+)
+
+// A TYPE record is stored in the array table under its variable's name,
+// with one slot per field after the declared dimensions (see
+// interpreter.NewRecordInfo). Field names are resolved to slot offsets
+// here, so the machines only see an array, its index values and a slot.
+
+// slotWidth is the size of a slot or slot count operand
+const slotWidth = 2
+
+// checkRecordSize rejects a record type whose slots do not fit in a slot
+// operand
+func checkRecordSize(t *ast.RecordType) error {
+	if int64(t.Size()) > bytecode.OperandLimit(slotWidth) {
+		return fmt.Errorf("TYPE %s has %d slots, more than %d", t.Name, t.Size(), bytecode.OperandLimit(slotWidth))
+	}
+	return nil
+}
+
+// fieldSlot returns the record holding a field and the field's slot. A
+// field that parser.ParseProgram did not resolve has no type.
+func fieldSlot(f *ast.FieldAccess) (*ast.RecordRef, int, error) {
+	if f.Type == nil {
+		return nil, 0, errField(f)
+	}
+	ref, slot := f.Slot()
+	if ref == nil {
+		return nil, 0, errField(f)
+	}
+	return ref, slot, checkRecordSize(ref.Type)
+}
+
+// wholeRecord returns the record variable or element a whole-record
+// operand lives in, its first slot and its type
+func wholeRecord(n ast.Node) (*ast.RecordRef, int, *ast.RecordType, error) {
+	ref, slot, t := ast.RecordOf(n)
+	if ref == nil {
+		return nil, 0, nil, fmt.Errorf("%s is not a record", n)
+	}
+	return ref, slot, t, checkRecordSize(ref.Type)
+}
+
+// recordArray resolves the array holding a record
+func (c *Compiler) recordArray(ref *ast.RecordRef) int {
+	return c.resolveArray(strings.ToUpper(ref.Name))
+}
+
+// recordShape returns the constant holding the slot types of a record
+func (c *Compiler) recordShape(t *ast.RecordType) (int, error) {
+	if err := checkRecordSize(t); err != nil {
+		return 0, err
+	}
+	return c.addConstant(interpreter.StringValue(t.Shape())), nil
+}
+
+// pushRecord pushes the indices of a record element and returns the record
+// operands of a field instruction: the array, the index count and the slot
+func (c *Compiler) pushRecord(ref *ast.RecordRef, slot int) ([]int, error) {
+	for _, idxExpr := range ref.Indices {
+		if err := c.compileExpression(idxExpr); err != nil {
+			return nil, err
+		}
+	}
+	return []int{c.recordArray(ref), len(ref.Indices), slot}, nil
+}
+
+// compileDimRecord creates a record variable or record array
+func (c *Compiler) compileDimRecord(n *ast.DimStmt) error {
+	shape, err := c.recordShape(n.Record)
+	if err != nil {
+		return err
+	}
+	for _, sizeExpr := range n.Sizes {
+		if err := c.compileExpression(sizeExpr); err != nil {
+			return err
+		}
+	}
+	c.emit(bytecode.OpDimRecord, c.resolveArray(strings.ToUpper(n.Name)), len(n.Sizes), shape)
+	return nil
+}
+
+// compileField pushes the value of a field
+func (c *Compiler) compileField(f *ast.FieldAccess) error {
+	ref, slot, err := fieldSlot(f)
+	if err != nil {
+		return err
+	}
+	operands, err := c.pushRecord(ref, slot)
+	if err != nil {
+		return err
+	}
+	c.emit(bytecode.OpGetField, operands...)
+	return nil
+}
+
+// compileFieldStore stores the value that value pushes into a field.
+// OpSetField pops the value, then the indices.
+func (c *Compiler) compileFieldStore(f *ast.FieldAccess, value func() error) error {
+	ref, slot, err := fieldSlot(f)
+	if err != nil {
+		return err
+	}
+	operands, err := c.pushRecord(ref, slot)
+	if err != nil {
+		return err
+	}
+	if err := value(); err != nil {
+		return err
+	}
+	c.emit(bytecode.OpSetField, operands...)
+	return nil
+}
+
+// compileRecordAssignment copies a whole record slot by slot. The target
+// indices are evaluated before the source indices, as in the interpreter.
+func (c *Compiler) compileRecordAssignment(n *ast.RecordAssignment) error {
+	dstRef, dstSlot, t, err := wholeRecord(n.Target)
+	if err != nil {
+		return err
+	}
+	srcRef, srcSlot, _, err := wholeRecord(n.Value)
+	if err != nil {
+		return err
+	}
+	dst, err := c.pushRecord(dstRef, dstSlot)
+	if err != nil {
+		return err
+	}
+	src, err := c.pushRecord(srcRef, srcSlot)
+	if err != nil {
+		return err
+	}
+	c.emit(bytecode.OpCopyRecord, append(append(dst, src...), t.Size())...)
+	return nil
+}
+
+// regRecord evaluates the indices of a record element into consecutive
+// registers and returns the record operands of a register field
+// instruction: the array, the first index register, the count and the slot
+func (c *Compiler) regRecord(ref *ast.RecordRef, slot int) ([]int, error) {
+	first, err := c.regArguments(ref.Indices)
+	if err != nil {
+		return nil, err
+	}
+	return []int{c.recordArray(ref), first, len(ref.Indices), slot}, nil
+}
+
+// regDimRecord is the register form of compileDimRecord
+func (c *Compiler) regDimRecord(n *ast.DimStmt) error {
+	shape, err := c.recordShape(n.Record)
+	if err != nil {
+		return err
+	}
+	first, err := c.regArguments(n.Sizes)
+	if err != nil {
+		return err
+	}
+	c.emit(bytecode.OpRDimRecord, c.resolveArray(strings.ToUpper(n.Name)), first, len(n.Sizes), shape)
+	return nil
+}
+
+// regField reads a field into a register chosen by result
+func (c *Compiler) regField(f *ast.FieldAccess, result func() int) (int, error) {
+	ref, slot, err := fieldSlot(f)
+	if err != nil {
+		return 0, err
+	}
+	operands, err := c.regRecord(ref, slot)
+	if err != nil {
+		return 0, err
+	}
+	dst := result()
+	c.emit(bytecode.OpRGetField, append([]int{dst}, operands...)...)
+	return dst, nil
+}
+
+// regFieldStore is the register form of compileFieldStore
+func (c *Compiler) regFieldStore(f *ast.FieldAccess, value func(dst int) (int, error)) error {
+	ref, slot, err := fieldSlot(f)
+	if err != nil {
+		return err
+	}
+	operands, err := c.regRecord(ref, slot)
+	if err != nil {
+		return err
+	}
+	val, err := value(noTarget)
+	if err != nil {
+		return err
+	}
+	c.emit(bytecode.OpRSetField, append(operands, val)...)
+	return nil
+}
+
+// regRecordAssignment is the register form of compileRecordAssignment
+func (c *Compiler) regRecordAssignment(n *ast.RecordAssignment) error {
+	dstRef, dstSlot, t, err := wholeRecord(n.Target)
+	if err != nil {
+		return err
+	}
+	srcRef, srcSlot, _, err := wholeRecord(n.Value)
+	if err != nil {
+		return err
+	}
+	dst, err := c.regRecord(dstRef, dstSlot)
+	if err != nil {
+		return err
+	}
+	src, err := c.regRecord(srcRef, srcSlot)
+	if err != nil {
+		return err
+	}
+	c.emit(bytecode.OpRCopyRecord, append(append(dst, src...), t.Size())...)
+	return nil
+}
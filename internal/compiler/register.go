@@ -0,0 +1,486 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/coverage"
+	"zork-basic/internal/interpreter"
+)
+
+// tempOperand marks a compile-time operand as a temporary. The final
+// register number (globalCount + temp) is only known once every variable
+// has been seen, so temporaries are patched at the end of Compile. Real
+// registers and constant indices stay below it.
+const tempOperand = 1 << 30
+
+// noTarget asks regExpression to pick the result register itself
+const noTarget = -1
+
+// WithRegisters selects the register backend: three-address instructions
+// that read variables and constants directly instead of going through the
+// operand stack. The chunk must be run by vm.RegisterVM.
+func WithRegisters() Option {
+	return func(c *Compiler) { c.registers = true }
+}
+
+// allocTemp reserves the next temporary register
+func (c *Compiler) allocTemp() int {
+	t := c.temps
+	c.temps++
+	if c.temps > c.maxTemps {
+		c.maxTemps = c.temps
+	}
+	return t | tempOperand
+}
+
+// constOperand returns the operand for a constant pool entry
+func (c *Compiler) constOperand(val interpreter.Value) int {
+	return bytecode.ConstOperand(c.addConstant(val))
+}
+
+// finishRegisters rewrites temporaries to real register numbers and
+// records the register file size in GlobalCount
+func (c *Compiler) finishRegisters() error {
+	total := c.globalCount + c.maxTemps
+	if total >= tempOperand || len(c.chunk.Constants) >= tempOperand {
+		return fmt.Errorf("program too large for the register machine: %d registers, %d constants", total, len(c.chunk.Constants))
+	}
+	for _, offset := range c.tempRefs {
+		t := binary.BigEndian.Uint32(c.chunk.Code[offset:])
+		binary.BigEndian.PutUint32(c.chunk.Code[offset:], uint32(c.globalCount)+t)
+	}
+	c.chunk.GlobalCount = total
+	c.chunk.Registers = true
+	return nil
+}
+
+// compileRegStatement is the register-backend counterpart of compileStatement
+func (c *Compiler) compileRegStatement(stmt ast.Node) error {
+	// Temporaries only live within one statement
+	mark := c.temps
+	defer func() { c.temps = mark }()
+
+	switch n := stmt.(type) {
+	case *ast.Assignment:
+		return c.regStore(n.Target, func(dst int) (int, error) {
+			if dst == noTarget {
+				return c.regExpression(n.Value, noTarget)
+			}
+			return dst, c.regExpressionInto(n.Value, dst)
+		})
+
+	case *ast.RecordAssignment:
+		return c.regRecordAssignment(n)
+
+	case *ast.PrintStmt:
+		for i, val := range n.Values {
+			operand, err := c.regExpression(val, noTarget)
+			if err != nil {
+				return err
+			}
+			c.emit(bytecode.OpRPrint, operand)
+			c.temps = mark
+
+			sep := ""
+			if i < len(n.Separators) {
+				sep = n.Separators[i]
+			} else if i == len(n.Values)-1 {
+				sep = n.Trailer
+			}
+			if sep == "," {
+				c.emit(bytecode.OpRPrint, c.constOperand(interpreter.StringValue(" ")))
+			}
+		}
+		if n.Trailer == "" {
+			c.emit(bytecode.OpPrintNl)
+		}
+
+	case *ast.IfStmt:
+		var jumpIfFalseOffset int
+		if cmp, ok := n.Condition.(*ast.ComparisonOp); ok {
+			op, err := comparisonOpcode(cmp.Op)
+			if err != nil {
+				return err
+			}
+			left, right, err := c.regOperands(cmp.Left, cmp.Right)
+			if err != nil {
+				return err
+			}
+			jumpIfFalseOffset = c.emitJump(bytecode.OpRCmpJump, int(op), left, right)
+		} else {
+			cond, err := c.regExpression(n.Condition, noTarget)
+			if err != nil {
+				return err
+			}
+			jumpIfFalseOffset = c.emitJump(bytecode.OpRJumpIfFalse, cond)
+		}
+		c.temps = mark
+
+		if c.cover != nil {
+			c.emitCover(c.cover.BranchCounter(n, coverage.BranchThen))
+		}
+		for _, s := range n.ThenStmts {
+			if err := c.compileRegStatement(s); err != nil {
+				return err
+			}
+		}
+		jumpToExitOffset := c.emitJump(bytecode.OpJump)
+		if err := c.patchJump(jumpIfFalseOffset); err != nil {
+			return err
+		}
+
+		if c.cover != nil {
+			c.emitCover(c.cover.BranchCounter(n, coverage.BranchElse))
+		}
+		for _, s := range n.ElseStmts {
+			if err := c.compileRegStatement(s); err != nil {
+				return err
+			}
+		}
+		if err := c.patchJump(jumpToExitOffset); err != nil {
+			return err
+		}
+
+	case *ast.ForStmt:
+		varName := strings.ToUpper(n.Var)
+		idx := c.resolveGlobal(varName)
+		if err := c.regExpressionInto(n.Start, idx); err != nil {
+			return err
+		}
+		end, step, err := c.regOperands(n.End, n.Step)
+		if err != nil {
+			return err
+		}
+		c.emit(bytecode.OpRForInit, idx, end, step)
+		c.forStack = append(c.forStack, forInfo{
+			varName: varName,
+			varIdx:  idx,
+			loopTop: len(c.chunk.Code),
+			exit:    -1,
+		})
+
+	case *ast.InputStmt:
+		if err := c.emitInput(n); err != nil {
+			return err
+		}
+		for _, target := range n.Vars {
+			if err := c.regStore(target, func(dst int) (int, error) {
+				if dst == noTarget {
+					dst = c.allocTemp()
+				}
+				c.emit(bytecode.OpRInputField, dst)
+				return dst, nil
+			}); err != nil {
+				return err
+			}
+			c.temps = mark
+		}
+
+	case *ast.ChainStmt:
+		file, line, err := c.regOperands(n.File, chainLine(n))
+		if err != nil {
+			return err
+		}
+		c.emit(bytecode.OpRChain, file, line, c.chainNames(n))
+
+	case *ast.DimStmt:
+		if isMap(n) {
+			// OpDimMap is shared with the stack machine
+			return c.compileStatement(stmt)
+		}
+		if n.Record != nil {
+			return c.regDimRecord(n)
+		}
+		first, err := c.regArguments(n.Sizes)
+		if err != nil {
+			return err
+		}
+		idx := c.resolveArray(strings.ToUpper(n.Name))
+		c.emit(bytecode.OpRDim, idx, first, len(n.Sizes))
+
+	case *ast.DeleteKeyStmt:
+		key, err := c.regExpression(n.Key, noTarget)
+		if err != nil {
+			return err
+		}
+		c.emit(bytecode.OpRMapDelete, c.resolveArray(strings.ToUpper(n.Map)), key)
+
+	default:
+		// NEXT, GOTO, GOSUB, RETURN, END, REM, COMMON, FOR EACH and the
+		// SAVE/RESTORE LOCALS of recursive calls do not touch the operand
+		// stack and compile to the shared instructions
+		return c.compileStatement(stmt)
+	}
+	return nil
+}
+
+// regStore stores a value into a variable, array element, map entry or
+// record field. value computes it into the given register, or into an
+// operand of its choosing when passed noTarget, and returns the operand
+// holding it.
+func (c *Compiler) regStore(target ast.Node, value func(dst int) (int, error)) error {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		_, err := value(c.resolveGlobal(strings.ToUpper(target.Name)))
+		return err
+	case *ast.ArrayAccess:
+		first, err := c.regArguments(target.Indices)
+		if err != nil {
+			return err
+		}
+		val, err := value(noTarget)
+		if err != nil {
+			return err
+		}
+		idx := c.resolveArray(strings.ToUpper(target.Name))
+		c.emit(bytecode.OpRSetArray, idx, first, len(target.Indices), val)
+		return nil
+	case *ast.MapAccess:
+		key, err := c.regExpression(target.Key, noTarget)
+		if err != nil {
+			return err
+		}
+		val, err := value(noTarget)
+		if err != nil {
+			return err
+		}
+		c.emit(bytecode.OpRMapSet, c.resolveArray(strings.ToUpper(target.Name)), key, val)
+		return nil
+	case *ast.FieldAccess:
+		return c.regFieldStore(target, value)
+	}
+	return fmt.Errorf("invalid assignment target: %T", target)
+}
+
+// regExpressionInto evaluates expr into register dst
+func (c *Compiler) regExpressionInto(expr ast.Node, dst int) error {
+	operand, err := c.regExpression(expr, dst)
+	if err != nil {
+		return err
+	}
+	if operand != dst {
+		c.emit(bytecode.OpRMove, dst, operand)
+	}
+	return nil
+}
+
+// regOperands evaluates two expressions and returns their operands. The
+// operands stay valid until the caller releases the temporaries.
+func (c *Compiler) regOperands(left, right ast.Node) (int, int, error) {
+	l, err := c.regExpression(left, noTarget)
+	if err != nil {
+		return 0, 0, err
+	}
+	r, err := c.regExpression(right, noTarget)
+	if err != nil {
+		return 0, 0, err
+	}
+	return l, r, nil
+}
+
+// regArguments evaluates exprs into consecutive registers and returns the
+// first one. A single argument already held in a register is used in place.
+func (c *Compiler) regArguments(exprs []ast.Node) (int, error) {
+	if len(exprs) == 1 {
+		operand, err := c.regExpression(exprs[0], noTarget)
+		if err != nil {
+			return 0, err
+		}
+		if _, isConst := bytecode.RegOperand(operand); !isConst {
+			return operand, nil
+		}
+		t := c.allocTemp()
+		c.emit(bytecode.OpRMove, t, operand)
+		return t, nil
+	}
+
+	first := 0
+	regs := make([]int, len(exprs))
+	for i := range exprs {
+		regs[i] = c.allocTemp()
+	}
+	if len(regs) > 0 {
+		first = regs[0]
+	}
+	for i, expr := range exprs {
+		if err := c.regExpressionInto(expr, regs[i]); err != nil {
+			return 0, err
+		}
+	}
+	return first, nil
+}
+
+// regExpression compiles expr and returns the operand holding its value.
+// Literals become constant operands and variables their own register, so
+// neither emits code. Other expressions are computed into target, or into
+// a fresh temporary when target is noTarget. Temporaries used by
+// subexpressions are released before the result register is chosen;
+// instructions read their operands before writing the destination.
+func (c *Compiler) regExpression(expr ast.Node, target int) (int, error) {
+	mark := c.temps
+	result := func() int {
+		c.temps = mark
+		if target != noTarget {
+			return target
+		}
+		return c.allocTemp()
+	}
+
+	switch n := expr.(type) {
+	case *ast.Number:
+		return c.constOperand(interpreter.NumberValue(n.Value)), nil
+
+	case *ast.StringLiteral:
+		return c.constOperand(interpreter.StringValue(n.Value)), nil
+
+	case *ast.Identifier:
+		return c.resolveGlobal(strings.ToUpper(n.Name)), nil
+
+	case *ast.FunctionCall:
+		name := strings.ToUpper(n.Name)
+		builtinID := bytecode.GetBuiltinID(name)
+		if builtinID < 0 {
+			return 0, fmt.Errorf("unknown builtin function: %s", name)
+		}
+		first, err := c.regArguments(n.Args)
+		if err != nil {
+			return 0, err
+		}
+		dst := result()
+		c.emit(bytecode.OpRCall, dst, builtinID, first, len(n.Args))
+		return dst, nil
+
+	case *ast.ArrayAccess:
+		first, err := c.regArguments(n.Indices)
+		if err != nil {
+			return 0, err
+		}
+		idx := c.resolveArray(strings.ToUpper(n.Name))
+		dst := result()
+		c.emit(bytecode.OpRGetArray, dst, idx, first, len(n.Indices))
+		return dst, nil
+
+	case *ast.MapAccess:
+		key, err := c.regExpression(n.Key, noTarget)
+		if err != nil {
+			return 0, err
+		}
+		dst := result()
+		c.emit(bytecode.OpRMapGet, dst, c.resolveArray(strings.ToUpper(n.Name)), key)
+		return dst, nil
+
+	case *ast.MapFunc:
+		op, err := mapFuncOpcode(n.Func, true)
+		if err != nil {
+			return 0, err
+		}
+		idx := c.resolveArray(strings.ToUpper(n.Map))
+		if n.Arg == nil {
+			dst := result()
+			c.emit(op, dst, idx)
+			return dst, nil
+		}
+		arg, err := c.regExpression(n.Arg, noTarget)
+		if err != nil {
+			return 0, err
+		}
+		dst := result()
+		c.emit(op, dst, idx, arg)
+		return dst, nil
+
+	case *ast.BinaryOp:
+		var op bytecode.OpCode
+		switch n.Op {
+		case "+":
+			op = bytecode.OpRAdd
+		case "-":
+			op = bytecode.OpRSub
+		case "*":
+			op = bytecode.OpRMul
+		case "/":
+			op = bytecode.OpRDiv
+		case "^":
+			op = bytecode.OpRPow
+		case "MOD":
+			op = bytecode.OpRMod
+		default:
+			return 0, fmt.Errorf("unknown binary op: %s", n.Op)
+		}
+		return c.regBinary(op, n.Left, n.Right, result)
+
+	case *ast.ComparisonOp:
+		op, err := comparisonOpcode(n.Op)
+		if err != nil {
+			return 0, err
+		}
+		return c.regBinary(bytecode.RegisterArith[op], n.Left, n.Right, result)
+
+	case *ast.LogicalOp:
+		switch n.Op {
+		case "AND":
+			return c.regBinary(bytecode.OpRAnd, n.Left, n.Right, result)
+		case "OR":
+			return c.regBinary(bytecode.OpROr, n.Left, n.Right, result)
+		}
+		return 0, fmt.Errorf("unknown logical op: %s", n.Op)
+
+	case *ast.UnaryOp:
+		var op bytecode.OpCode
+		switch n.Op {
+		case "-":
+			op = bytecode.OpRNeg
+		case "NOT":
+			op = bytecode.OpRNot
+		case "+":
+			return c.regExpression(n.Right, target)
+		default:
+			return 0, fmt.Errorf("unknown unary op: %s", n.Op)
+		}
+		operand, err := c.regExpression(n.Right, noTarget)
+		if err != nil {
+			return 0, err
+		}
+		dst := result()
+		c.emit(op, dst, operand)
+		return dst, nil
+
+	case *ast.FieldAccess:
+		return c.regField(n, result)
+	}
+	return 0, fmt.Errorf("unknown expression: %T", expr)
+}
+
+// regBinary emits a three-address instruction for left op right
+func (c *Compiler) regBinary(op bytecode.OpCode, left, right ast.Node, result func() int) (int, error) {
+	l, r, err := c.regOperands(left, right)
+	if err != nil {
+		return 0, err
+	}
+	dst := result()
+	c.emit(op, dst, l, r)
+	return dst, nil
+}
+
+// comparisonOpcode maps a comparison operator to its stack opcode, which
+// OpRCmpJump and the disassembler use to name the comparison
+func comparisonOpcode(op string) (bytecode.OpCode, error) {
+	switch op {
+	case "=":
+		return bytecode.OpEq, nil
+	case "<>":
+		return bytecode.OpNeq, nil
+	case ">":
+		return bytecode.OpGt, nil
+	case "<":
+		return bytecode.OpLt, nil
+	case ">=":
+		return bytecode.OpGte, nil
+	case "<=":
+		return bytecode.OpLte, nil
+	}
+	return 0, fmt.Errorf("unknown comparison op: %s", op)
+}
@@ -0,0 +1,71 @@
+package compiler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/vm"
+)
+
+func TestRegisterBackend(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"arithmetic", "10 A = 7: B = 2\n20 PRINT A + B; A - B; A * B; A / B; A ^ B; A MOD B; -A\n30 PRINT (A + B) * (A - B) / (1 + B)\n"},
+		{"strings", "10 S$ = \"AB\"\n20 S$ = S$ + S$ + 1\n30 PRINT S$, LEN(S$); MID$(S$, 2, 3); LEFT$(S$ + \"Z\", 2)\n"},
+		{"comparisons and logic", "10 A = 3\n20 PRINT A > 2; A = 3; A <> 3; \"B\" > \"A\"; A > 1 AND A < 3; NOT A OR 0\n"},
+		{"IF forms", "10 FOR I = 1 TO 4\n20 IF I MOD 2 = 0 THEN PRINT I; \"even\" ELSE PRINT I; \"odd\"\n30 IF I - 3 THEN PRINT \"not 3\"\n40 NEXT I\n"},
+		{"GOTO loop", "10 C = 0\n20 C = C + 1\n30 IF C < 5 THEN GOTO 20\n40 PRINT C\n"},
+		{"GOSUB", "10 X = 1: GOSUB 100: GOSUB 100\n20 PRINT X\n30 END\n100 X = X * 10\n110 RETURN\n"},
+		{"FOR with step", "10 FOR I = 10 TO 1 STEP -3\n20 FOR J = 1 TO I / 4: S = S + J: NEXT J\n30 PRINT I;\n40 NEXT I\n50 PRINT S\n"},
+		{"arrays", "10 DIM A(5): DIM M(2, 3)\n20 FOR I = 0 TO 4: A(I) = I * I: NEXT I\n30 M(1, 2) = A(3) + A(A(2))\n40 PRINT A(4); M(1, 2); M(0, 0)\n"},
+		{"nested calls", "10 X = 2\n20 PRINT INT(SQR(X * 8) + ABS(-X)); INSTR(\"HELLO\", \"L\"); CHR$(ASC(\"A\") + X)\n"},
+		{"assignment reads its target", "10 X = 5\n20 X = X * 2 + X\n30 Y = 1\n40 Y = LEN(STR$) + Y\n50 PRINT X; Y\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog := parse(t, tt.src)
+			want, _ := run(t, prog)
+			for _, level := range []int{compiler.OptNone, compiler.OptDeadCode} {
+				comp := compiler.New(compiler.WithRegisters(), compiler.WithOptimization(level))
+				chunk, err := comp.Compile(prog)
+				if err != nil {
+					t.Fatalf("-O%d: compile error: %v", level, err)
+				}
+				var out bytes.Buffer
+				if err := vm.NewRegister(chunk, vm.WithOutput(&out)).Run(); err != nil {
+					t.Fatalf("-O%d: runtime error: %v", level, err)
+				}
+				if out.String() != want {
+					t.Errorf("-O%d: register VM output differs:\nwant %q\ngot  %q\n%s", level, want, out.String(), chunk.Disassemble("test"))
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterDisassembly(t *testing.T) {
+	prog := parse(t, "10 S = 0\n20 FOR I = 1 TO 3\n30 S = S + SIN(I) * 2\n40 NEXT I\n50 IF S > 1 THEN PRINT \"big\"\n")
+	chunk, err := compiler.New(compiler.WithRegisters()).Compile(prog)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	listing := chunk.Disassemble("test")
+	for _, want := range []string{
+		"RForInit        r1(I) k2(3) k1(1)",
+		"RCall           r2 1 (SIN) r1(I) 1",
+		"RMul            r2 r2 k3(2)",
+		"RAdd            r0(S) r0(S) r2",
+		"Next            1 (I) 31",
+		"RCmpJump        Gt r0(S) k1(1)",
+		"RPrint          k4(\"big\")",
+	} {
+		if !strings.Contains(listing, want) {
+			t.Errorf("disassembly has no %q:\n%s", want, listing)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package coverage 提供 BASIC 程序的代码覆盖率统计
+// 记录每个 ast.Line 以及每个 IF 分支的执行次数，并生成覆盖率报告
+package coverage
+
+import (
+	"fmt"
+	"io"
+
+	"zork-basic/internal/ast"
+)
+
+// BranchKind 表示分支计数器的类型
+type BranchKind int
+
+const (
+	// BranchThen IF 条件为真时执行的分支
+	BranchThen BranchKind = iota
+	// BranchElse IF 条件为假时执行的分支（即使没有 ELSE 块也会计数）
+	BranchElse
+)
+
+// String 返回分支类型的名称
+func (k BranchKind) String() string {
+	if k == BranchThen {
+		return "then"
+	}
+	return "else"
+}
+
+// Branch 描述一个 IF 分支计数器
+type Branch struct {
+	LineNumber int        // IF 所在的行号
+	Ordinal    int        // IF 在该行中的序号（按先序遍历，从 0 开始）
+	Kind       BranchKind // THEN 或 ELSE
+	Counter    int        // 对应的计数器索引
+}
+
+// Profile 保存一次程序执行的覆盖率数据
+// 计数器布局：前 len(Lines) 个计数器对应程序行，其后每个 IF 占用两个计数器（THEN、ELSE）
+type Profile struct {
+	Lines      []*ast.Line         // 被统计的程序行（与 Program.Lines 顺序一致）
+	Branches   []Branch            // 所有 IF 分支
+	Counts     []uint64            // 计数器
+	executable []bool              // 行是否包含可执行语句（REM 和空行不计入覆盖率）
+	ifIndex    map[*ast.IfStmt]int // IF 节点 -> THEN 计数器索引
+	lineIndex  map[int]int         // 行号 -> 行计数器索引
+	unnumbered bool                // 程序没有行号，报告中用 Line.Pos 指出各行
+}
+
+// New 为程序创建覆盖率配置，分配所有行与分支计数器
+func New(prog *ast.Program) *Profile {
+	p := &Profile{
+		Lines:      prog.Lines,
+		ifIndex:    make(map[*ast.IfStmt]int),
+		lineIndex:  make(map[int]int),
+		unnumbered: prog.Unnumbered,
+	}
+	p.executable = make([]bool, len(prog.Lines))
+	for idx, line := range prog.Lines {
+		p.lineIndex[line.LineNumber] = idx
+		for _, stmt := range line.Statements {
+			if _, isRem := stmt.(*ast.RemStmt); !isRem {
+				p.executable[idx] = true
+			}
+		}
+	}
+
+	counter := len(prog.Lines)
+	for _, line := range prog.Lines {
+		ordinal := 0
+		var walk func(stmts []ast.Node)
+		walk = func(stmts []ast.Node) {
+			for _, stmt := range stmts {
+				ifStmt, ok := stmt.(*ast.IfStmt)
+				if !ok {
+					continue
+				}
+				p.ifIndex[ifStmt] = counter
+				p.Branches = append(p.Branches,
+					Branch{LineNumber: line.LineNumber, Ordinal: ordinal, Kind: BranchThen, Counter: counter},
+					Branch{LineNumber: line.LineNumber, Ordinal: ordinal, Kind: BranchElse, Counter: counter + 1},
+				)
+				counter += 2
+				ordinal++
+				walk(ifStmt.ThenStmts)
+				walk(ifStmt.ElseStmts)
+			}
+		}
+		walk(line.Statements)
+	}
+
+	p.Counts = make([]uint64, counter)
+	return p
+}
+
+// Hit 递增指定计数器
+func (p *Profile) Hit(counter int) {
+	p.Counts[counter]++
+}
+
+// LineCounter 返回程序行（按 Program.Lines 中的索引）的计数器索引
+func (p *Profile) LineCounter(lineIdx int) int {
+	return lineIdx
+}
+
+// BranchCounter 返回 IF 分支的计数器索引，IF 不在配置中时返回 -1
+func (p *Profile) BranchCounter(stmt *ast.IfStmt, kind BranchKind) int {
+	base, ok := p.ifIndex[stmt]
+	if !ok {
+		return -1
+	}
+	return base + int(kind)
+}
+
+// LineCount 返回指定行号的执行次数，以及该行是否存在
+func (p *Profile) LineCount(lineNumber int) (uint64, bool) {
+	idx, ok := p.lineIndex[lineNumber]
+	if !ok {
+		return 0, false
+	}
+	return p.Counts[idx], true
+}
+
+// IsExecutable 判断指定行号的行是否包含可执行语句
+func (p *Profile) IsExecutable(lineNumber int) bool {
+	idx, ok := p.lineIndex[lineNumber]
+	return ok && p.executable[idx]
+}
+
+// LineBranches 返回指定行号上的所有分支（按序号、THEN/ELSE 排序）
+func (p *Profile) LineBranches(lineNumber int) []Branch {
+	var result []Branch
+	for _, b := range p.Branches {
+		if b.LineNumber == lineNumber {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// Summary 返回覆盖率摘要：已执行行数、可执行行数、已执行分支数、分支总数
+func (p *Profile) Summary() (linesHit, linesTotal, branchesHit, branchesTotal int) {
+	for idx := range p.Lines {
+		if !p.executable[idx] {
+			continue
+		}
+		linesTotal++
+		if p.Counts[idx] > 0 {
+			linesHit++
+		}
+	}
+	for _, b := range p.Branches {
+		branchesTotal++
+		if p.Counts[b.Counter] > 0 {
+			branchesHit++
+		}
+	}
+	return
+}
+
+// SummaryString 返回可读的覆盖率摘要
+func (p *Profile) SummaryString() string {
+	linesHit, linesTotal, branchesHit, branchesTotal := p.Summary()
+	return fmt.Sprintf("coverage: %s of lines (%d/%d), %s of branches (%d/%d)",
+		percent(linesHit, linesTotal), linesHit, linesTotal,
+		percent(branchesHit, branchesTotal), branchesHit, branchesTotal)
+}
+
+// WriteProfile 以文本格式输出覆盖率数据
+// 格式（每行一条记录）：
+//
+//	mode: count
+//	<文件>:<行号> line <次数>
+//	<文件>:<行号> then.<序号> <次数>
+//	<文件>:<行号> else.<序号> <次数>
+//
+// 无行号程序的 <文件>:<行号> 是该行在源文件中的位置（Line.Pos）
+func (p *Profile) WriteProfile(w io.Writer, filename string) error {
+	if _, err := fmt.Fprintln(w, "mode: count"); err != nil {
+		return err
+	}
+	for idx, line := range p.Lines {
+		if !p.executable[idx] {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s line %d\n", p.position(filename, line.LineNumber), p.Counts[idx]); err != nil {
+			return err
+		}
+	}
+	for _, b := range p.Branches {
+		if _, err := fmt.Fprintf(w, "%s %s.%d %d\n", p.position(filename, b.LineNumber), b.Kind, b.Ordinal, p.Counts[b.Counter]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// position 返回行号为 lineNumber 的行在覆盖率数据中的位置
+func (p *Profile) position(filename string, lineNumber int) string {
+	if idx, ok := p.lineIndex[lineNumber]; ok && p.unnumbered {
+		return p.Lines[idx].Pos.String()
+	}
+	return fmt.Sprintf("%s:%d", filename, lineNumber)
+}
+
+func percent(hit, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(hit)*100/float64(total))
+}
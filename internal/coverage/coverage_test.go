@@ -0,0 +1,131 @@
+package coverage_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/coverage"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vm"
+)
+
+const coverSource = `10 REM coverage test
+20 FOR I = 1 TO 3
+30 IF I = 2 THEN PRINT "two" ELSE PRINT "other"
+40 NEXT I
+50 IF I > 100 THEN GOTO 70
+60 END
+70 PRINT "never"
+`
+
+func parseProgram(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	parsed, err := parser.Parse("test.bas", []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	return parsed.(*ast.Program)
+}
+
+func checkProfile(t *testing.T, p *coverage.Profile) {
+	t.Helper()
+	wantLines := map[int]uint64{20: 1, 30: 3, 40: 3, 50: 1, 60: 1, 70: 0}
+	for line, want := range wantLines {
+		if got, _ := p.LineCount(line); got != want {
+			t.Errorf("line %d: count = %d, want %d", line, got, want)
+		}
+	}
+
+	branches := p.LineBranches(30)
+	if len(branches) != 2 {
+		t.Fatalf("line 30: got %d branches, want 2", len(branches))
+	}
+	if then, els := p.Counts[branches[0].Counter], p.Counts[branches[1].Counter]; then != 1 || els != 2 {
+		t.Errorf("line 30 branches: then=%d else=%d, want then=1 else=2", then, els)
+	}
+
+	linesHit, linesTotal, branchesHit, branchesTotal := p.Summary()
+	if linesHit != 5 || linesTotal != 6 || branchesHit != 3 || branchesTotal != 4 {
+		t.Errorf("Summary() = %d/%d lines, %d/%d branches, want 5/6, 3/4",
+			linesHit, linesTotal, branchesHit, branchesTotal)
+	}
+}
+
+func TestCoverageAST(t *testing.T) {
+	prog := parseProgram(t, coverSource)
+	p := coverage.New(prog)
+	interp := interpreter.NewInterpreter(interpreter.WithOutput(io.Discard), interpreter.WithCoverage(p))
+	interp.ExecuteProgram(prog)
+	checkProfile(t, p)
+}
+
+func TestCoverageVM(t *testing.T) {
+	prog := parseProgram(t, coverSource)
+	p := coverage.New(prog)
+	chunk, err := compiler.New(compiler.WithCoverage(p)).Compile(prog)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if err := vm.New(chunk, vm.WithOutput(io.Discard), vm.WithCoverage(p)).Run(); err != nil {
+		t.Fatalf("runtime error: %v", err)
+	}
+	checkProfile(t, p)
+}
+
+func TestWriteListing(t *testing.T) {
+	prog := parseProgram(t, coverSource)
+	p := coverage.New(prog)
+	interpreter.NewInterpreter(interpreter.WithOutput(io.Discard), interpreter.WithCoverage(p)).ExecuteProgram(prog)
+
+	var out strings.Builder
+	if err := p.WriteListing(&out, "test.bas", coverSource); err != nil {
+		t.Fatal(err)
+	}
+	listing := out.String()
+	for _, want := range []string{
+		"        -: 10 REM coverage test",
+		"        3: 30 IF I = 2",
+		"branch 0: then taken 1, else taken 2",
+		"    #####: 70 PRINT \"never\"",
+	} {
+		if !strings.Contains(listing, want) {
+			t.Errorf("listing missing %q:\n%s", want, listing)
+		}
+	}
+}
+
+// TestUnnumberedReports checks that reports for a program without line
+// numbers point at source lines, not the internal line numbers
+func TestUnnumberedReports(t *testing.T) {
+	src := "' unnumbered\n\nFOR I = 1 TO 2\n  IF I = 2 THEN PRINT I\nNEXT I\n"
+	prog, err := parser.ParseProgram("test.bas", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := coverage.New(prog)
+	interpreter.NewInterpreter(interpreter.WithOutput(io.Discard), interpreter.WithCoverage(p)).ExecuteProgram(prog)
+
+	var listing, html, profile strings.Builder
+	if err := p.WriteListing(&listing, "test.bas", src); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteHTML(&html, "test.bas", src); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteProfile(&profile, "test.bas"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ report, want string }{
+		{listing.String(), "        -: ' unnumbered\n        -: \n        1: FOR I = 1 TO 2\n        2:   IF I = 2 THEN PRINT I\n"},
+		{html.String(), `<span class="count">2</span>  IF I = 2`},
+		{profile.String(), "test.bas:3 line 1\ntest.bas:4 line 2\ntest.bas:5 line 2\ntest.bas:4 then.0 1\n"},
+	} {
+		if !strings.Contains(tt.report, tt.want) {
+			t.Errorf("report missing %q:\n%s", tt.want, tt.report)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package coverage
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sourceLine 表示源文件中的一个物理行
+type sourceLine struct {
+	text       string
+	lineNumber int  // BASIC 行号（无行号程序中是内部行号）
+	numbered   bool // 是否对应程序中的一行（续行如多行 IF 的后续部分没有行号）
+}
+
+// sourceLines 将 filename 的源代码拆分为物理行，并找出每行对应的程序行：有行号的程序
+// 按行首的行号对应，无行号的程序按 Line.Pos 对应
+func (p *Profile) sourceLines(filename, source string) []sourceLine {
+	lines := splitSource(source)
+	if !p.unnumbered {
+		return lines
+	}
+	for idx := range lines {
+		lines[idx].lineNumber, lines[idx].numbered = 0, false
+	}
+	for _, line := range p.Lines {
+		if line.Pos.File == filename && line.Pos.Line >= 1 && line.Pos.Line <= len(lines) {
+			lines[line.Pos.Line-1].lineNumber = line.LineNumber
+			lines[line.Pos.Line-1].numbered = true
+		}
+	}
+	return lines
+}
+
+// splitSource 将源代码拆分为物理行，并提取每行开头的 BASIC 行号
+func splitSource(source string) []sourceLine {
+	raw := strings.Split(strings.TrimRight(source, "\n"), "\n")
+	lines := make([]sourceLine, len(raw))
+	for idx, text := range raw {
+		text = strings.TrimRight(text, "\r")
+		lines[idx].text = text
+		trimmed := strings.TrimLeft(text, " \t")
+		end := 0
+		for end < len(trimmed) && trimmed[end] >= '0' && trimmed[end] <= '9' {
+			end++
+		}
+		if end > 0 {
+			if n, err := strconv.Atoi(trimmed[:end]); err == nil {
+				lines[idx].lineNumber = n
+				lines[idx].numbered = true
+			}
+		}
+	}
+	return lines
+}
+
+// lineMarker 返回行的覆盖标记：执行次数、"#####"（未执行）或 "-"（不可执行）
+func (p *Profile) lineMarker(sl sourceLine) (string, bool) {
+	if !sl.numbered || !p.IsExecutable(sl.lineNumber) {
+		return "-", false
+	}
+	count, _ := p.LineCount(sl.lineNumber)
+	if count == 0 {
+		return "#####", true
+	}
+	return strconv.FormatUint(count, 10), false
+}
+
+// branchNotes 返回某行所有 IF 分支的说明文字
+func (p *Profile) branchNotes(lineNumber int) []string {
+	branches := p.LineBranches(lineNumber)
+	notes := make([]string, 0, len(branches)/2)
+	for i := 0; i+1 < len(branches); i += 2 {
+		then, els := branches[i], branches[i+1]
+		notes = append(notes, fmt.Sprintf("branch %d: then taken %d, else taken %d",
+			then.Ordinal, p.Counts[then.Counter], p.Counts[els.Counter]))
+	}
+	return notes
+}
+
+// WriteListing 输出源文件 filename 带注释的源码清单（类似 gcov）
+// 每行前显示执行次数，未执行的行显示 "#####"，不可执行的行显示 "-"
+// 包含 IF 的行在其后追加分支执行情况
+func (p *Profile) WriteListing(w io.Writer, filename, source string) error {
+	for _, sl := range p.sourceLines(filename, source) {
+		marker, _ := p.lineMarker(sl)
+		if _, err := fmt.Fprintf(w, "%9s: %s\n", marker, sl.text); err != nil {
+			return err
+		}
+		if !sl.numbered {
+			continue
+		}
+		for _, note := range p.branchNotes(sl.lineNumber) {
+			if _, err := fmt.Fprintf(w, "%9s  %s\n", "", note); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, p.SummaryString())
+	return err
+}
+
+// WriteHTML 输出 HTML 覆盖率报告
+// 已执行的行显示为绿色，未执行的行显示为红色，部分分支未覆盖的行显示为黄色
+func (p *Profile) WriteHTML(w io.Writer, filename string, source string) error {
+	var out strings.Builder
+	out.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&out, "<title>Coverage: %s</title>\n", html.EscapeString(filename))
+	out.WriteString("<style>\n")
+	out.WriteString("body { font-family: sans-serif; }\n")
+	out.WriteString("pre { font-family: monospace; line-height: 1.3; }\n")
+	out.WriteString(".count { color: #888; display: inline-block; width: 6em; text-align: right; padding-right: 1em; }\n")
+	out.WriteString(".hit { background: #dfd; }\n")
+	out.WriteString(".miss { background: #fdd; }\n")
+	out.WriteString(".partial { background: #ffc; }\n")
+	out.WriteString("</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&out, "<h1>%s</h1>\n", html.EscapeString(filename))
+	fmt.Fprintf(&out, "<p>%s</p>\n<pre>\n", html.EscapeString(p.SummaryString()))
+
+	for _, sl := range p.sourceLines(filename, source) {
+		marker, missed := p.lineMarker(sl)
+		class := ""
+		title := ""
+		if marker != "-" {
+			class = "hit"
+			if missed {
+				class = "miss"
+			}
+			if notes := p.branchNotes(sl.lineNumber); len(notes) > 0 {
+				title = strings.Join(notes, "; ")
+				if !missed && p.hasMissedBranch(sl.lineNumber) {
+					class = "partial"
+				}
+			}
+		}
+		if class == "" {
+			fmt.Fprintf(&out, "<span class=\"count\">%s</span>%s\n", marker, html.EscapeString(sl.text))
+			continue
+		}
+		fmt.Fprintf(&out, "<span class=\"%s\" title=\"%s\"><span class=\"count\">%s</span>%s</span>\n",
+			class, html.EscapeString(title), marker, html.EscapeString(sl.text))
+	}
+
+	out.WriteString("</pre>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// hasMissedBranch 判断某行是否存在未执行的 IF 分支
+func (p *Profile) hasMissedBranch(lineNumber int) bool {
+	for _, b := range p.LineBranches(lineNumber) {
+		if p.Counts[b.Counter] == 0 {
+			return true
+		}
+	}
+	return false
+}
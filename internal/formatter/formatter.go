@@ -17,10 +17,15 @@ func FormatLine(line *ast.Line, lineNumberMap map[int]int, indentLevel int) stri
 	if indentLevel > 0 {
 		result.WriteString(strings.Repeat("  ", indentLevel))
 	}
+	if line.Label != "" {
+		result.WriteString(line.Label + ":")
+	}
 
 	for i, stmt := range line.Statements {
 		if i > 0 {
 			result.WriteString(": ")
+		} else if line.Label != "" {
+			result.WriteString(" ")
 		}
 		result.WriteString(FormatStatement(stmt, lineNumberMap))
 	}
@@ -35,7 +40,7 @@ func GetIndentDelta(line *ast.Line) (before int, after int) {
 
 	for _, stmt := range line.Statements {
 		switch s := stmt.(type) {
-		case *ast.ForStmt:
+		case *ast.ForStmt, *ast.ForEachStmt:
 			afterDelta++
 		case *ast.NextStmt:
 			beforeDelta--
@@ -48,6 +53,16 @@ func GetIndentDelta(line *ast.Line) (before int, after int) {
 		case *ast.EndIfStmt:
 			beforeDelta--
 			afterDelta--
+		case *ast.SubStmt:
+			afterDelta++
+		case *ast.EndSubStmt:
+			beforeDelta--
+			afterDelta--
+		case *ast.TypeStmt:
+			afterDelta++
+		case *ast.EndTypeStmt:
+			beforeDelta--
+			afterDelta--
 		case *ast.IfStmt:
 			// IfStmt is a single-node multi-line construct.
 			// In our renumbering/formatting context, it stays as is.
@@ -61,7 +76,10 @@ func GetIndentDelta(line *ast.Line) (before int, after int) {
 func FormatStatement(stmt ast.Node, lineNumberMap map[int]int) string {
 	switch s := stmt.(type) {
 	case *ast.GotoStmt:
-		// 更新 GOTO 目标行号
+		// 更新 GOTO 目标行号，跳转到标签的不受重编号影响
+		if s.Label != "" {
+			return s.String()
+		}
 		if newNum, ok := lineNumberMap[s.LineNumber]; ok {
 			return fmt.Sprintf("GOTO %d", newNum)
 		}
@@ -69,6 +87,9 @@ func FormatStatement(stmt ast.Node, lineNumberMap map[int]int) string {
 
 	case *ast.GosubStmt:
 		// 更新 GOSUB 目标行号
+		if s.Label != "" {
+			return s.String()
+		}
 		if newNum, ok := lineNumberMap[s.LineNumber]; ok {
 			return fmt.Sprintf("GOSUB %d", newNum)
 		}
@@ -123,11 +144,7 @@ func FormatStatement(stmt ast.Node, lineNumberMap map[int]int) string {
 		return FormatInputStmt(s)
 
 	case *ast.DimStmt:
-		sizes := make([]string, len(s.Sizes))
-		for i, size := range s.Sizes {
-			sizes[i] = size.String()
-		}
-		return fmt.Sprintf("DIM %s(%s)", s.Name, strings.Join(sizes, ", "))
+		return s.String()
 
 	case *ast.ForStmt:
 		result := fmt.Sprintf("FOR %s = %s TO %s", s.Var, s.Start.String(), s.End.String())
@@ -159,6 +176,9 @@ func FormatStatement(stmt ast.Node, lineNumberMap map[int]int) string {
 	case *ast.EndStmt:
 		return "END"
 
+	case *ast.StopStmt:
+		return "STOP"
+
 	default:
 		return stmt.String()
 	}
@@ -202,22 +222,31 @@ func FormatPrintStmt(stmt *ast.PrintStmt) string {
 	return result.String()
 }
 
-// FormatInputStmt 格式化 INPUT 语句
+// FormatInputStmt 格式化 INPUT 和 LINE INPUT 语句
 func FormatInputStmt(stmt *ast.InputStmt) string {
 	var result strings.Builder
+	if stmt.Line {
+		result.WriteString("LINE ")
+	}
 	result.WriteString("INPUT")
 	if stmt.Prompt != "" {
 		result.WriteString(" \"")
 		result.WriteString(stmt.Prompt)
-		result.WriteString("\", ")
+		// 分号表示提示后显示 "? "，逗号不显示
+		if stmt.Question {
+			result.WriteString("\";")
+		} else {
+			result.WriteString("\",")
+		}
 	}
 
 	for i, v := range stmt.Vars {
-		// 变量之间以及 INPUT/Prompt 与第一个变量之间需要分隔
-		if i > 0 || stmt.Prompt == "" {
-			result.WriteString(" ")
+		// 目标之间以逗号分隔
+		if i > 0 {
+			result.WriteString(",")
 		}
-		result.WriteString(v)
+		result.WriteString(" ")
+		result.WriteString(v.String())
 	}
 	return result.String()
 }
@@ -16,14 +16,14 @@ func TestFormatInputStmt(t *testing.T) {
 			name: "Prompt and One Var",
 			stmt: &ast.InputStmt{
 				Prompt: "Enter value:",
-				Vars:   []string{"A"},
+				Vars:   []ast.Node{&ast.Identifier{Name: "A"}},
 			},
 			expected: "INPUT \"Enter value:\", A",
 		},
 		{
 			name: "No Prompt and One Var",
 			stmt: &ast.InputStmt{
-				Vars: []string{"A"},
+				Vars: []ast.Node{&ast.Identifier{Name: "A"}},
 			},
 			expected: "INPUT A",
 		},
@@ -31,16 +31,35 @@ func TestFormatInputStmt(t *testing.T) {
 			name: "Prompt and Two Vars",
 			stmt: &ast.InputStmt{
 				Prompt: "Enter coordinates:",
-				Vars:   []string{"X", "Y"},
+				Vars:   []ast.Node{&ast.Identifier{Name: "X"}, &ast.Identifier{Name: "Y"}},
 			},
-			expected: "INPUT \"Enter coordinates:\", X Y",
+			expected: "INPUT \"Enter coordinates:\", X, Y",
 		},
 		{
 			name: "No Prompt and Two Vars",
 			stmt: &ast.InputStmt{
-				Vars: []string{"A", "B"},
+				Vars: []ast.Node{&ast.Identifier{Name: "A"}, &ast.Identifier{Name: "B"}},
 			},
-			expected: "INPUT A B",
+			expected: "INPUT A, B",
+		},
+		{
+			name: "Question Prompt and Array Element",
+			stmt: &ast.InputStmt{
+				Prompt:   "Name",
+				Question: true,
+				Vars:     []ast.Node{&ast.Identifier{Name: "N$"}, &ast.ArrayAccess{Name: "A", Indices: []ast.Node{&ast.Identifier{Name: "I"}}}},
+			},
+			expected: "INPUT \"Name\"; N$, A(I)",
+		},
+		{
+			name: "Line Input",
+			stmt: &ast.InputStmt{
+				Prompt:   "Line: ",
+				Question: true,
+				Line:     true,
+				Vars:     []ast.Node{&ast.Identifier{Name: "L$"}},
+			},
+			expected: "LINE INPUT \"Line: \"; L$",
 		},
 	}
 
@@ -53,3 +72,22 @@ func TestFormatInputStmt(t *testing.T) {
 		})
 	}
 }
+
+func TestRenumberText(t *testing.T) {
+	lineNumberMap := map[int]int{100: 10, 200: 20}
+	tests := []struct {
+		code, want string
+	}{
+		{"goto 100", "goto 10"},
+		{"IF X > 1 THEN GOSUB   200 ELSE GOTO 300", "IF X > 1 THEN GOSUB   20 ELSE GOTO 300"},
+		{`PRINT "GOTO 100": GOTO 200`, `PRINT "GOTO 100": GOTO 20`},
+		{"REM GOTO 100", "REM GOTO 100"},
+		{"X = 1 ' GOTO 100", "X = 1 ' GOTO 100"},
+		{"XGOTO = 100", "XGOTO = 100"},
+	}
+	for _, tt := range tests {
+		if got := formatter.RenumberText(tt.code, lineNumberMap); got != tt.want {
+			t.Errorf("RenumberText(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package formatter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RenumberText 按 lineNumberMap 替换一行源码中 GOTO、GOSUB 的目标行号，
+// 其余内容（空格、关键字大小写）原样保留，用于只重新编号、不格式化的 RENUM。
+// 字符串字面量、REM 和 ' 注释中的内容不会被修改，不在映射中的目标保持不变
+func RenumberText(code string, lineNumberMap map[int]int) string {
+	var result strings.Builder
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '"':
+			// 字符串字面量原样保留；缺少右引号时取到行尾
+			end := strings.IndexByte(code[i+1:], '"')
+			if end < 0 {
+				result.WriteString(code[i:])
+				return result.String()
+			}
+			result.WriteString(code[i : i+end+2])
+			i += end + 2
+		case c == '\'':
+			result.WriteString(code[i:])
+			return result.String()
+		case IsIdentStart(c) && (i == 0 || !IsIdentByte(code[i-1])):
+			j := i
+			for j < len(code) && IsIdentByte(code[j]) {
+				j++
+			}
+			result.WriteString(code[i:j])
+			word := strings.ToUpper(code[i:j])
+			i = j
+			if word == "REM" {
+				result.WriteString(code[i:])
+				return result.String()
+			}
+			if word != "GOTO" && word != "GOSUB" {
+				continue
+			}
+			// 关键字之后是空格和目标行号
+			start := i
+			for start < len(code) && code[start] == ' ' {
+				start++
+			}
+			end := start
+			for end < len(code) && code[end] >= '0' && code[end] <= '9' {
+				end++
+			}
+			if end == start {
+				continue
+			}
+			if num, err := strconv.Atoi(code[start:end]); err == nil {
+				if newNum, ok := lineNumberMap[num]; ok {
+					result.WriteString(code[i:start])
+					result.WriteString(strconv.Itoa(newNum))
+					i = end
+				}
+			}
+		default:
+			result.WriteByte(c)
+			i++
+		}
+	}
+	return result.String()
+}
+
+// IsIdentStart 判断 c 能否作为关键字或标识符的开头，交互模式的语法着色也使用它
+func IsIdentStart(c byte) bool {
+	return c == '_' || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+}
+
+// IsIdentByte 判断 c 能否出现在关键字或标识符中
+func IsIdentByte(c byte) bool {
+	return IsIdentStart(c) || c == '$' || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,112 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/parser"
+)
+
+// WithChain 设置 CHAIN 载入程序的方式，默认使用 LoadSource 读取并解析源文件
+func WithChain(load func(filename string) (*ast.Program, error)) Option {
+	return func(i *Interpreter) {
+		i.chainLoader = load
+	}
+}
+
+// LoadSource 读取并解析 BASIC 源文件，是 CHAIN 默认的程序加载方式
+func LoadSource(filename string) (*ast.Program, error) {
+	return parser.LoadFile(filename)
+}
+
+// CommonNames 返回程序中所有 COMMON 语句声明的名称（大写、按出现顺序、不重复），
+// 数组名后加 "()"。COMMON 是静态声明，写在程序的哪一行、是否执行过都没有关系
+func CommonNames(prog *ast.Program) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var visit func(stmts []ast.Node)
+	visit = func(stmts []ast.Node) {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *ast.CommonStmt:
+				for _, v := range s.Vars {
+					name := strings.ToUpper(v.String())
+					if !seen[name] {
+						seen[name] = true
+						names = append(names, name)
+					}
+				}
+			case *ast.IfStmt:
+				visit(s.ThenStmts)
+				visit(s.ElseStmts)
+			}
+		}
+	}
+	for _, line := range prog.Lines {
+		visit(line.Statements)
+	}
+	return names
+}
+
+// chain 执行 CHAIN：载入另一个程序替换当前程序，不带 ALL 时只保留 COMMON 声明的
+// 变量和数组。GOSUB 栈和 FOR 栈被清空，从指定行（没有该行时为其后的第一行）开始执行
+func (i *Interpreter) chain(n *ast.ChainStmt) error {
+	file := i.evaluateExpr(n.File)
+	if !file.IsString() {
+		return fmt.Errorf("CHAIN needs a file name, got %s", file)
+	}
+	start := -1
+	if n.Line != nil {
+		start = int(i.evaluateExpr(n.Line).AsNumber())
+	}
+	load := i.chainLoader
+	if load == nil {
+		load = LoadSource
+	}
+	next, err := load(file.String())
+	if err != nil {
+		return err
+	}
+	lineIdx := 0
+	if start >= 0 {
+		lineIdx = len(next.Lines)
+		for idx, line := range next.Lines {
+			if line.LineNumber >= start {
+				lineIdx = idx
+				break
+			}
+		}
+		if lineIdx == len(next.Lines) {
+			return fmt.Errorf("CHAIN %s: no line %d", file, start)
+		}
+	}
+
+	if !n.All {
+		keep := make(map[string]bool)
+		for _, name := range CommonNames(i.program) {
+			keep[name] = true
+		}
+		for name := range i.variables {
+			if !keep[name] {
+				delete(i.variables, name)
+			}
+		}
+		for name := range i.arrays {
+			if !keep[name+"()"] {
+				delete(i.arrays, name)
+			}
+		}
+	}
+
+	i.LoadProgram(next)
+	i.currentLine = lineIdx
+	i.resume = nil
+	i.returnStack = i.returnStack[:0]
+	i.forStack = i.forStack[:0]
+	i.savedVars = i.savedVars[:0]
+	i.savedArrays = i.savedArrays[:0]
+	// 覆盖率统计只针对最初的程序
+	i.cover = nil
+	return nil
+}
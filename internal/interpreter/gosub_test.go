@@ -0,0 +1,39 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/parser"
+)
+
+// TestReturnMidLine 检查 RETURN 回到 GOSUB 之后的下一条语句，CONT 从 STOP 之后的下一条
+// 语句继续，单行 IF 分支中也是如此
+func TestReturnMidLine(t *testing.T) {
+	src := "10 PRINT \"a\";: GOSUB 100: PRINT \"c\";\n" +
+		"20 IF 1 THEN GOSUB 100: PRINT \"d\";\n" +
+		"30 IF 1 THEN STOP: PRINT \"e\";\n" +
+		"40 PRINT \"f\"\n50 END\n100 PRINT \"b\";: RETURN\n"
+	prog, err := parser.ParseProgram("test.bas", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	interp := interpreter.NewInterpreter(interpreter.WithOutput(&out), interpreter.WithErrOutput(&out))
+	var brk *interpreter.Break
+	if err := interp.Run(prog); !errors.As(err, &brk) || brk.Line != 30 {
+		t.Fatalf("Run = %v, want a break in line 30", err)
+	}
+	if want := "abcbd"; out.String() != want {
+		t.Errorf("output before STOP = %q, want %q", out.String(), want)
+	}
+	out.Reset()
+	if err := interp.Continue(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ef\n"; out.String() != want {
+		t.Errorf("output after CONT = %q, want %q", out.String(), want)
+	}
+}
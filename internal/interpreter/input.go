@@ -0,0 +1,188 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// ErrInputPastEnd 表示 INPUT 或 LINE INPUT 读取时输入已经结束
+var ErrInputPastEnd = errors.New("Input past end")
+
+// RedoFromStart 是输入与变量不符时 INPUT 显示的提示，之后重新提示输入
+const RedoFromStart = "?Redo from start\n"
+
+// INPUT 字段的类型，每个接收输入的变量一个
+const (
+	InputNumber = '#' // 数字变量：字段必须是数字，空字段为 0
+	InputString = '$' // 字符串变量：字段原样保存
+)
+
+// inputNumber 是数字字段允许的写法，与源码中的数字字面量一致，另外允许正负号
+var inputNumber = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// InputType 返回变量（或数组）名对应的字段类型：以 $ 结尾的是字符串，其余是数字
+func InputType(name string) byte {
+	if strings.HasSuffix(name, "$") {
+		return InputString
+	}
+	return InputNumber
+}
+
+// InputTypes 返回接收输入的各目标（Identifier、ArrayAccess、MapAccess 或记录的字段）的字段类型
+func InputTypes(targets []ast.Node) string {
+	types := make([]byte, len(targets))
+	for i, target := range targets {
+		switch t := target.(type) {
+		case *ast.Identifier:
+			types[i] = InputType(t.Name)
+		case *ast.ArrayAccess:
+			types[i] = InputType(t.Name)
+		case *ast.MapAccess:
+			types[i] = InputType(t.Name)
+		case *ast.FieldAccess:
+			types[i] = InputNumber
+			if t.Type == nil {
+				break // 没有解析的字段由执行时报告
+			}
+			if f, _ := t.Info(); f.String {
+				types[i] = InputString
+			}
+		}
+	}
+	return string(types)
+}
+
+// ReadLine 读取一行输入，不含行尾的 "\n" 或 "\r\n"。
+// 除非 r 实现了 io.ByteReader，否则逐字节读取，不会多读属于后续输入的内容。
+// 没有读到任何内容就到达输入末尾时返回 ErrInputPastEnd
+func ReadLine(r io.Reader) (string, error) {
+	var line []byte
+	br, ok := r.(io.ByteReader)
+	var buf [1]byte
+	for {
+		var b byte
+		var err error
+		if ok {
+			b, err = br.ReadByte()
+		} else {
+			var n int
+			n, err = r.Read(buf[:])
+			if n == 1 {
+				b, err = buf[0], nil
+			} else if err == nil {
+				continue
+			}
+		}
+		if err == io.EOF {
+			if len(line) == 0 {
+				return "", ErrInputPastEnd
+			}
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if b == '\n' {
+			break
+		}
+		line = append(line, b)
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
+
+// ParseInput 按 GW-BASIC 的规则把一行输入拆成逗号分隔的字段，并按 types
+// （每个变量一个 InputType）转换。字段可以用双引号括起来，以包含逗号和首尾
+// 空格；未加引号的字段去掉首尾空格。字段个数不符、引号后面还有其他内容、
+// 数字变量的字段不是数字时返回 false
+func ParseInput(line, types string) ([]Value, bool) {
+	values := make([]Value, 0, len(types))
+	rest := line
+	for i := 0; i < len(types); i++ {
+		field := strings.TrimLeft(rest, " \t")
+		quoted := strings.HasPrefix(field, `"`)
+		if quoted {
+			// 引号内的内容原样保留；缺少右引号时取到行尾
+			field = field[1:]
+			end := strings.IndexByte(field, '"')
+			if end < 0 {
+				end = len(field)
+				rest = ""
+			} else {
+				rest = strings.TrimLeft(field[end+1:], " \t")
+				if rest != "" && rest[0] != ',' {
+					return nil, false
+				}
+			}
+			field = field[:end]
+		} else if end := strings.IndexByte(field, ','); end >= 0 {
+			field, rest = field[:end], field[end:]
+		} else {
+			rest = ""
+		}
+		if !quoted {
+			field = strings.TrimRight(field, " \t")
+		}
+
+		switch {
+		case types[i] == InputString:
+			values = append(values, StringValue(field))
+		case quoted:
+			return nil, false
+		case field == "":
+			values = append(values, NumberValue(0))
+		default:
+			if !inputNumber.MatchString(field) {
+				return nil, false
+			}
+			n, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, false
+			}
+			values = append(values, NumberValue(n))
+		}
+
+		// 字段之间以逗号分隔，最后一个字段之后不能再有逗号
+		if i < len(types)-1 {
+			if !strings.HasPrefix(rest, ",") {
+				return nil, false
+			}
+			rest = rest[1:]
+		}
+	}
+	if rest != "" {
+		return nil, false
+	}
+	return values, true
+}
+
+// Input 执行一条 INPUT：显示提示并读取一行，按 types 转换。输入与变量不符时
+// 显示 "?Redo from start" 并重新提示
+func Input(r io.Reader, w io.Writer, prompt, types string) ([]Value, error) {
+	for {
+		fmt.Fprint(w, prompt)
+		line, err := ReadLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if values, ok := ParseInput(line, types); ok {
+			return values, nil
+		}
+		fmt.Fprint(w, RedoFromStart)
+	}
+}
+
+// LineInput 执行一条 LINE INPUT：显示提示，把读到的整行作为一个字符串返回
+func LineInput(r io.Reader, w io.Writer, prompt string) ([]Value, error) {
+	fmt.Fprint(w, prompt)
+	line, err := ReadLine(r)
+	if err != nil {
+		return nil, err
+	}
+	return []Value{StringValue(line)}, nil
+}
@@ -0,0 +1,61 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/interpreter"
+)
+
+func TestParseInput(t *testing.T) {
+	tests := []struct {
+		line, types string
+		want        []interpreter.Value // nil 表示需要重新输入
+	}{
+		{"John Smith", "$", []interpreter.Value{interpreter.StringValue("John Smith")}},
+		{"  12 , abc ,3e2", "#$#", []interpreter.Value{interpreter.NumberValue(12), interpreter.StringValue("abc"), interpreter.NumberValue(300)}},
+		{`"Smith, John" , -1.5`, "$#", []interpreter.Value{interpreter.StringValue("Smith, John"), interpreter.NumberValue(-1.5)}},
+		{`" padded `, "$", []interpreter.Value{interpreter.StringValue(" padded ")}},
+		{"", "#", []interpreter.Value{interpreter.NumberValue(0)}},
+		{",x", "#$", []interpreter.Value{interpreter.NumberValue(0), interpreter.StringValue("x")}},
+		{"abc", "#", nil},       // 数字变量不接受字符串
+		{`"1"`, "#", nil},       // 也不接受加引号的数字
+		{"1e999", "#", nil},     // 超出范围
+		{"1, 2", "#", nil},      // 字段过多
+		{"1", "##", nil},        // 字段过少
+		{`"a" b, 1`, "$#", nil}, // 引号后还有内容
+	}
+	for _, tt := range tests {
+		got, ok := interpreter.ParseInput(tt.line, tt.types)
+		if ok != (tt.want != nil) {
+			t.Errorf("ParseInput(%q, %q) ok = %v, want %v", tt.line, tt.types, ok, tt.want != nil)
+			continue
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseInput(%q, %q)[%d] = %v, want %v", tt.line, tt.types, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestInputRedo(t *testing.T) {
+	var out bytes.Buffer
+	in := strings.NewReader("x\r\n7, ok\r\n")
+	values, err := interpreter.Input(in, &out, "? ", "#$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].AsNumber() != 7 || values[1].String() != "ok" {
+		t.Errorf("values = %v", values)
+	}
+	if want := "? " + interpreter.RedoFromStart + "? "; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	// 输入结束后报告 Input past end，而不是不停地重新提示
+	if _, err := interpreter.Input(in, &out, "? ", "#"); !errors.Is(err, interpreter.ErrInputPastEnd) {
+		t.Errorf("Input at end of input: err = %v, want %v", err, interpreter.ErrInputPastEnd)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"io"
 	"math"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"zork-basic/internal/ast"
+	"zork-basic/internal/coverage"
 )
 
 // Value 表示 BASIC 解释器中的任意值
@@ -81,19 +84,30 @@ func (v Value) IsString() bool {
 // Interpreter BASIC 解释器
 // 负责解析和执行 AST（抽象语法树）
 type Interpreter struct {
-	variables    map[string]Value      // 变量存储表
-	arrays       map[string]*ArrayInfo // 数组存储表
-	program      *ast.Program          // 当前加载的程序
-	currentLine  int                   // 当前执行到的行索引
-	lineMap      map[int]int           // 行号 -> 程序行索引的映射表
-	returnStack  []int                 // GOSUB 返回地址栈
-	forStack     []*ForFrame           // FOR 循环栈
-	indexBuf     []int                 // 数组索引复用缓冲区（优化）
-	nameCache    map[string]string     // 名称规范化缓存（优化）
-	forFramePool *sync.Pool            // 循环帧对象池（优化）
-	output       io.Writer             // 正常输出（PRINT 语句等）
-	errOutput    io.Writer             // 错误输出
-	input        io.Reader             // 输入源（INPUT 语句）
+	variables    map[string]Value                 // 变量存储表
+	arrays       map[string]*ArrayInfo            // 数组存储表
+	program      *ast.Program                     // 当前加载的程序
+	currentLine  int                              // 当前执行到的行索引
+	execLine     int                              // 正在执行的行索引
+	stmtPath     []int                            // 正在执行的语句在行中的位置（见 executeStmts）
+	resume       []int                            // 当前行从哪个位置开始执行（RETURN 或 STOP 之后），nil 表示从头
+	stopped      bool                             // 刚执行了 STOP
+	lineMap      map[int]int                      // 行号 -> 程序行索引的映射表
+	returnStack  []returnPoint                    // GOSUB 返回地址栈
+	forStack     []*ForFrame                      // FOR 循环栈
+	savedVars    []Value                          // SAVE LOCALS 保存的变量，未赋值的是零值 Value
+	savedArrays  []*ArrayInfo                     // SAVE LOCALS 保存的数组，未声明的是 nil
+	eachExits    map[*ast.ForEachStmt]returnPoint // 字典为空时 FOR EACH 跳到的位置：对应的 NEXT 之后
+	indexBuf     []int                            // 数组索引复用缓冲区（优化）
+	nameCache    map[string]string                // 名称规范化缓存（优化）
+	forFramePool *sync.Pool                       // 循环帧对象池（优化）
+	output       io.Writer                        // 正常输出（PRINT 语句等）
+	errOutput    io.Writer                        // 错误输出
+	input        io.Reader                        // 输入源（INPUT 语句）
+	cover        *coverage.Profile                // 覆盖率统计（可选，nil 表示关闭）
+	interrupt    *atomic.Bool                     // 中断标志（WithInterrupt）
+
+	chainLoader func(string) (*ast.Program, error) // CHAIN 载入程序的方式（WithChain）
 }
 
 // Option 是解释器的配置选项函数
@@ -120,6 +134,19 @@ func WithInput(r io.Reader) Option {
 	}
 }
 
+// WithCoverage 启用覆盖率统计，记录执行过的行和 IF 分支
+func WithCoverage(p *coverage.Profile) Option {
+	return func(i *Interpreter) {
+		i.cover = p
+	}
+}
+
+// returnPoint 是 GOSUB 的返回地址：GOSUB 之后的下一条语句
+type returnPoint struct {
+	line int   // 行索引
+	stmt []int // 语句在行中的位置（见 executeStmts）
+}
+
 // ForFrame 表示 FOR 循环的栈帧
 // 用于存储循环状态，支持嵌套循环
 // 优化：缓存循环变量值，减少 map 查找
@@ -129,6 +156,10 @@ type ForFrame struct {
 	stepValue float64 // 循环步长
 	lineIdx   int     // NEXT 后要返回到的行索引
 	value     float64 // 循环变量当前值（缓存）
+
+	keys   []string // FOR EACH 开始时字典的全部键，FOR 循环为 nil
+	next   int      // 循环变量当前是 keys 中的第几个键
+	resume []int    // FOR EACH 之后的语句在 lineIdx 行中的位置（见 executeStmts）
 }
 
 // ArrayInfo 表示数组信息
@@ -136,6 +167,9 @@ type ForFrame struct {
 type ArrayInfo struct {
 	dims      []int     // 各维度的大小
 	Data      []float64 // 扁平化存储的数组数据
+	Strs      []string  // 字符串数组（名字以 $ 结尾）的数据，数值数组为 nil
+	Map       *MapInfo  // DIM ... AS MAP 创建的字典，数组为 nil
+	Shape     string    // 记录变量和记录数组各槽位的类型（见 NewRecordInfo），其他为空
 	totalSize int       // 总元素数量
 }
 
@@ -152,6 +186,19 @@ func NewArrayInfo(dims []int) *ArrayInfo {
 	}
 }
 
+// NewStringArrayInfo 创建一个字符串数组，元素初始为空串
+func NewStringArrayInfo(dims []int) *ArrayInfo {
+	totalSize := 1
+	for _, d := range dims {
+		totalSize *= d
+	}
+	return &ArrayInfo{
+		dims:      dims,
+		Strs:      make([]string, totalSize),
+		totalSize: totalSize,
+	}
+}
+
 // CalculateIndex 计算多维索引的一维位置
 // 将 (i1, i2, ..., in) 转换为扁平化索引
 func (a *ArrayInfo) CalculateIndex(indices []int) int {
@@ -188,6 +235,7 @@ func NewInterpreter(opts ...Option) *Interpreter {
 		output:    os.Stdout,
 		errOutput: os.Stderr,
 		input:     os.Stdin,
+		interrupt: new(atomic.Bool),
 	}
 	for _, opt := range opts {
 		opt(i)
@@ -218,27 +266,172 @@ func (i *Interpreter) LoadProgram(program *ast.Program) {
 	for idx, line := range program.Lines {
 		i.lineMap[line.LineNumber] = idx
 	}
+	i.eachExits = eachExits(program)
+}
+
+// eachExits 按编译器的方式为每个 FOR EACH 找到对应的 NEXT：NEXT 结束最近一个
+// 尚未结束的循环。parser.ParseProgram 保证 FOR EACH 和它的 NEXT 都不在单行 IF 中
+func eachExits(program *ast.Program) map[*ast.ForEachStmt]returnPoint {
+	exits := make(map[*ast.ForEachStmt]returnPoint)
+	var open []*ast.ForEachStmt // 尚未结束的循环，FOR 为 nil
+	var visit func(idx int, stmts []ast.Node, top bool)
+	visit = func(idx int, stmts []ast.Node, top bool) {
+		for k, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *ast.ForStmt:
+				open = append(open, nil)
+			case *ast.ForEachStmt:
+				open = append(open, s)
+			case *ast.NextStmt:
+				if len(open) == 0 {
+					continue
+				}
+				if each := open[len(open)-1]; each != nil && top {
+					exits[each] = returnPoint{line: idx, stmt: []int{k + 1}}
+				}
+				open = open[:len(open)-1]
+			case *ast.IfStmt:
+				visit(idx, s.ThenStmts, false)
+				visit(idx, s.ElseStmts, false)
+			}
+		}
+	}
+	for idx, line := range program.Lines {
+		visit(idx, line.Statements, true)
+	}
+	return exits
 }
 
 // ExecuteProgram 执行 BASIC 程序
 // 按行号顺序执行程序，支持 GOTO/GOSUB 改变执行流
 func (i *Interpreter) ExecuteProgram(program *ast.Program) {
+	i.Run(program)
+}
+
+// Run 从头执行程序，与 ExecuteProgram 相同，但程序被中断时返回 *Break
+func (i *Interpreter) Run(program *ast.Program) error {
+	if program.Module != "" {
+		// 模块没有主程序，只能编译后链接到程序中
+		fmt.Fprintf(i.errOutput, "Error: %s is a module; link it into a program with zb link\n", program.Module)
+		return nil
+	}
 	i.LoadProgram(program)
+	i.currentLine = 0
+	i.resume = nil
+	i.stopped = false
+	i.returnStack = i.returnStack[:0]
+	i.forStack = i.forStack[:0]
+	i.savedVars = i.savedVars[:0]
+	i.savedArrays = i.savedArrays[:0]
+	return i.Continue()
+}
 
-	// 按顺序执行各行
-	for i.currentLine = 0; i.currentLine < len(program.Lines); {
-		line := program.Lines[i.currentLine]
+// breakAt 返回在 line 处中断的 *Break，无行号程序报告该行在源文件中的位置
+func (i *Interpreter) breakAt(line *ast.Line) *Break {
+	b := &Break{Line: line.LineNumber}
+	if i.program.Unnumbered {
+		b.Pos = line.Pos.String()
+	}
+	return b
+}
+
+// Continue 从上次中断的位置继续执行，变量、GOSUB 栈和 FOR 栈保持中断时的状态
+// STOP 之后从下一条语句继续，STOP 位于单行 IF 中时也是如此
+func (i *Interpreter) Continue() error {
+	// 按顺序执行各行；CHAIN 会替换 i.program
+	for i.currentLine < len(i.program.Lines) {
+		if i.interrupt.Load() {
+			i.interrupt.Store(false)
+			return i.breakAt(i.program.Lines[i.currentLine])
+		}
+		line := i.program.Lines[i.currentLine]
+		resume := i.resume
+		i.resume = nil
+		if i.cover != nil && resume == nil {
+			i.cover.Hit(i.cover.LineCounter(i.currentLine))
+		}
+		i.execLine = i.currentLine
 		i.currentLine++ // 移动到下一行
-		for _, stmt := range line.Statements {
-			if i.executeStatement(stmt) {
-				// GOTO/GOSUB/END/RETURN 改变了 currentLine，跳出内层循环
-				// currentLine 已经被设置为正确的目标索引（下一行要执行的）
-				break
-			}
+		if i.executeStmts(line.Statements, resume) && i.stopped {
+			// STOP 已经在 resume 中记下了下一条语句的位置，以便继续执行
+			i.stopped = false
+			i.currentLine = i.execLine
+			return i.breakAt(line)
 		}
 		// 如果没有跳转，currentLine 已经指向下一行，继续循环
-		// 如果有跳转，currentLine 已被设置为要执行的目标行，继续循环
+		// 如果有跳转（GOTO/GOSUB/END/RETURN/CHAIN），currentLine 已被设置为要执行的目标行，
+		// RETURN 还会设置 resume
 	}
+	return nil
+}
+
+// executeStmts 依次执行 stmts 中的语句，返回 true 表示控制流发生了改变
+//
+// 语句在行中的位置用下标路径表示：[k] 是第 k 条语句；单行 IF 分支中的语句是
+// [k, 分支, j]，分支 0 为 THEN、1 为 ELSE，j 是语句在分支中的下标，依此类推。
+// from 不为空时从 from 表示的位置开始执行，GOSUB 因此能返回到行中的下一条语句
+func (i *Interpreter) executeStmts(stmts []ast.Node, from []int) bool {
+	depth := len(i.stmtPath)
+	start := 0
+	if len(from) > 0 {
+		start = from[0]
+		if len(from) > 1 {
+			// 从 IF 分支中间继续，分支执行完后继续执行 IF 之后的语句
+			branch := stmts[start].(*ast.IfStmt).ThenStmts
+			if from[1] == 1 {
+				branch = stmts[start].(*ast.IfStmt).ElseStmts
+			}
+			i.stmtPath = append(i.stmtPath, start, from[1])
+			jumped := i.executeStmts(branch, from[2:])
+			i.stmtPath = i.stmtPath[:depth]
+			if jumped {
+				return true
+			}
+			start++
+		}
+	}
+	i.stmtPath = append(i.stmtPath, 0)
+	for k := start; k < len(stmts); k++ {
+		i.stmtPath[depth] = k
+		if i.executeStatement(stmts[k]) {
+			i.stmtPath = i.stmtPath[:depth]
+			return true
+		}
+	}
+	i.stmtPath = i.stmtPath[:depth]
+	return false
+}
+
+// nextStmt 返回正在执行的语句之后的位置
+func (i *Interpreter) nextStmt() []int {
+	next := slices.Clone(i.stmtPath)
+	if len(next) > 0 {
+		next[len(next)-1]++
+	}
+	return next
+}
+
+// nextEach 执行 FOR EACH 循环的 NEXT：还有键时把下一个键赋给循环变量，回到 FOR EACH
+// 之后的语句，否则结束循环
+func (i *Interpreter) nextEach(frame *ForFrame) bool {
+	frame.next++
+	if frame.next == len(frame.keys) {
+		i.forStack = i.forStack[:len(i.forStack)-1]
+		return false
+	}
+	i.variables[frame.varName] = StringValue(frame.keys[frame.next])
+	i.currentLine = frame.lineIdx
+	i.resume = frame.resume
+	return true
+}
+
+// executeBranch 执行单行 IF 的一个分支（0 为 THEN、1 为 ELSE）
+func (i *Interpreter) executeBranch(stmts []ast.Node, branch int) bool {
+	depth := len(i.stmtPath)
+	i.stmtPath = append(i.stmtPath, branch)
+	jumped := i.executeStmts(stmts, nil)
+	i.stmtPath = i.stmtPath[:depth]
+	return jumped
 }
 
 // executeStatement 执行单个语句
@@ -247,34 +440,11 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 	switch n := stmt.(type) {
 	case *ast.Assignment:
 		// 赋值语句：支持变量赋值和数组元素赋值
-		value := i.evaluateExpr(n.Value)
-		switch target := n.Target.(type) {
-		case *ast.Identifier:
-			// 普通变量赋值 - 使用大写的变量名
-			normalizedName := i.normalizeName(target.Name)
-			i.variables[normalizedName] = value
-		case *ast.ArrayAccess:
-			// 数组元素赋值 - 使用大写的数组名
-			normalizedName := i.normalizeName(target.Name)
-			arr, ok := i.arrays[normalizedName]
-			if !ok {
-				fmt.Fprintf(i.errOutput, "Error: Array '%s' not declared\n", target.Name)
-				return false
-			}
-			// 计算多维索引
-			indices := i.getIndexBuf(len(target.Indices))
-			for idx, idxExpr := range target.Indices {
-				indices[idx] = int(i.evaluateExpr(idxExpr).AsNumber())
-			}
-			flatIndex := arr.CalculateIndex(indices)
-			if flatIndex < 0 {
-				fmt.Fprintf(i.errOutput, "Error: Array index out of bounds\n")
-				return false
-			}
-			arr.Data[flatIndex] = value.AsNumber()
-		default:
-			fmt.Fprintf(i.errOutput, "Error: Invalid assignment target type: %T\n", target)
-		}
+		i.assign(n.Target, i.evaluateExpr(n.Value))
+		return false
+
+	case *ast.RecordAssignment:
+		i.copyRecord(n)
 		return false
 
 	case *ast.PrintStmt:
@@ -302,20 +472,22 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 	case *ast.IfStmt:
 		// IF...THEN...ELSE...END IF 条件语句
 		cond := i.evaluateExpr(n.Condition)
+		if i.cover != nil {
+			kind := coverage.BranchElse
+			if cond.IsTrue() {
+				kind = coverage.BranchThen
+			}
+			if counter := i.cover.BranchCounter(n, kind); counter >= 0 {
+				i.cover.Hit(counter)
+			}
+		}
+		// 传播分支中 GOTO/GOSUB/END/RETURN 的控制流变更
 		if cond.IsTrue() {
 			// 条件为真，执行 THEN 块
-			for _, s := range n.ThenStmts {
-				if i.executeStatement(s) {
-					return true // 传播 GOTO/GOSUB/END/RETURN 的控制流变更
-				}
-			}
+			return i.executeBranch(n.ThenStmts, 0)
 		} else if len(n.ElseStmts) > 0 {
 			// 条件为假，执行 ELSE 块（如果有）
-			for _, s := range n.ElseStmts {
-				if i.executeStatement(s) {
-					return true // 传播 GOTO/GOSUB/END/RETURN 的控制流变更
-				}
-			}
+			return i.executeBranch(n.ElseStmts, 1)
 		}
 		return false
 
@@ -349,6 +521,9 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 		}
 
 		frame := i.forStack[len(i.forStack)-1]
+		if frame.keys != nil {
+			return i.nextEach(frame)
+		}
 
 		// 优化：直接使用缓存的值，避免 map 查找
 		newVal := frame.value + frame.stepValue
@@ -391,9 +566,14 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 
 	case *ast.GosubStmt:
 		// GOSUB 子程序调用语句
+		if n.Extern != "" {
+			fmt.Fprintf(i.errOutput, "Error: %s is defined in another module; compile the program and link it with zb link\n", n.Extern)
+			i.currentLine = len(i.program.Lines)
+			return true
+		}
 		if idx, ok := i.lineMap[n.LineNumber]; ok {
-			// 将返回地址压入栈（currentLine 已经被递增，指向调用行的下一行）
-			i.returnStack = append(i.returnStack, i.currentLine)
+			// 将返回地址（GOSUB 之后的下一条语句）压入栈
+			i.returnStack = append(i.returnStack, returnPoint{line: i.execLine, stmt: i.nextStmt()})
 			i.currentLine = idx
 		} else {
 			fmt.Fprintf(i.errOutput, "Error: Line %d not found\n", n.LineNumber)
@@ -406,9 +586,10 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 			fmt.Fprintln(i.errOutput, "Error: RETURN without GOSUB")
 			return false
 		}
-		retLine := i.returnStack[len(i.returnStack)-1]
+		ret := i.returnStack[len(i.returnStack)-1]
 		i.returnStack = i.returnStack[:len(i.returnStack)-1]
-		i.currentLine = retLine
+		i.currentLine = ret.line
+		i.resume = ret.stmt
 		return true
 
 	case *ast.EndStmt:
@@ -416,12 +597,64 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 		i.currentLine = len(i.program.Lines)
 		return true
 
-	case *ast.RemStmt:
-		// REM 注释语句：不做任何事
+	case *ast.StopStmt:
+		// STOP 暂停程序，由 Continue 返回 *Break
+		i.stopped = true
+		i.resume = i.nextStmt()
+		return true
+
+	case *ast.ChainStmt:
+		// CHAIN 载入并从头（或指定行）运行另一个程序；失败时结束程序
+		if err := i.chain(n); err != nil {
+			fmt.Fprintf(i.errOutput, "Error: %v\n", err)
+			i.currentLine = len(i.program.Lines)
+		}
+		return true
+
+	case *ast.RemStmt, *ast.CommonStmt:
+		// REM 注释语句、COMMON 声明：不做任何事
+		return false
+
+	case *ast.SaveLocalsStmt:
+		// 递归调用前保存调用者的局部变量和数组
+		for _, name := range n.Vars {
+			i.savedVars = append(i.savedVars, i.variables[name])
+		}
+		for _, name := range n.Arrays {
+			i.savedArrays = append(i.savedArrays, i.arrays[name])
+		}
+		return false
+
+	case *ast.RestoreLocalsStmt:
+		// 按相反的顺序恢复，保存时未赋值的变量和未声明的数组被删除
+		for k := len(n.Saved.Arrays) - 1; k >= 0; k-- {
+			arr := i.savedArrays[len(i.savedArrays)-1]
+			i.savedArrays = i.savedArrays[:len(i.savedArrays)-1]
+			if arr == nil {
+				delete(i.arrays, n.Saved.Arrays[k])
+			} else {
+				i.arrays[n.Saved.Arrays[k]] = arr
+			}
+		}
+		for k := len(n.Saved.Vars) - 1; k >= 0; k-- {
+			v := i.savedVars[len(i.savedVars)-1]
+			i.savedVars = i.savedVars[:len(i.savedVars)-1]
+			if !v.isNumber && !v.isString {
+				delete(i.variables, n.Saved.Vars[k])
+			} else {
+				i.variables[n.Saved.Vars[k]] = v
+			}
+		}
 		return false
 
 	case *ast.DimStmt:
 		// DIM 数组声明语句：创建多维数组（使用大写的数组名）
+		if strings.EqualFold(n.Type, "MAP") {
+			// DIM D AS MAP 创建空字典，已有的内容被清空
+			normalizedName := i.normalizeName(n.Name)
+			i.arrays[normalizedName] = NewMap(strings.HasSuffix(normalizedName, "$"))
+			return false
+		}
 		dims := make([]int, len(n.Sizes))
 		for idx, sizeExpr := range n.Sizes {
 			size := int(i.evaluateExpr(sizeExpr).AsNumber())
@@ -431,41 +664,74 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 			}
 			dims[idx] = size
 		}
+		if n.Record != nil {
+			// DIM X AS Item 创建记录变量，DIM Inv(10) AS Item 创建记录数组
+			i.arrays[i.normalizeName(n.Name)] = NewRecordInfo(dims, n.Record.Shape())
+			return false
+		}
 		// BASIC 数组索引从 0 开始
 		// DIM A(10) 创建 A(0) 到 A(9)，共 10 个元素
 		// DIM B(3, 4) 创建 3x4 的二维数组，共 12 个元素
 		normalizedName := i.normalizeName(n.Name)
-		i.arrays[normalizedName] = NewArrayInfo(dims)
+		if strings.HasSuffix(normalizedName, "$") {
+			i.arrays[normalizedName] = NewStringArrayInfo(dims)
+		} else {
+			i.arrays[normalizedName] = NewArrayInfo(dims)
+		}
 		return false
 
-	case *ast.InputStmt:
-		// INPUT 输入语句：从用户读取输入并存储到变量
-		// 支持提示字符串和多个变量
-		prompt := "? "
-		if n.Prompt != "" {
-			prompt = n.Prompt
+	case *ast.DeleteKeyStmt:
+		// DELETEKEY 从字典中删除一个键
+		if m := i.mapOf(n.Map); m != nil {
+			if key, ok := i.mapKey(n.Key); ok {
+				m.Delete(key)
+			}
 		}
+		return false
 
-		for idx, varName := range n.Vars {
-			// 多个变量时，后续变量显示序号
-			if len(n.Vars) > 1 {
-				fmt.Fprintf(i.output, "%s [%d]: ", prompt, idx+1)
-			} else {
-				fmt.Fprint(i.output, prompt)
+	case *ast.ForEachStmt:
+		// FOR EACH 遍历开始时字典的键，字典为空时跳到对应的 NEXT 之后
+		var keys []string
+		if m := i.mapOf(n.Map); m != nil {
+			keys = m.Keys()
+		}
+		if len(keys) == 0 {
+			exit, ok := i.eachExits[n]
+			if !ok {
+				fmt.Fprintln(i.errOutput, "Error: FOR EACH without NEXT")
+				exit.line = len(i.program.Lines)
 			}
+			i.currentLine = exit.line
+			i.resume = exit.stmt
+			return true
+		}
+		name := i.normalizeName(n.Var)
+		i.variables[name] = StringValue(keys[0])
+		i.forStack = append(i.forStack, &ForFrame{varName: name, lineIdx: i.execLine, keys: keys, resume: i.nextStmt()})
+		return false
 
-			var input string
-			fmt.Fscanln(i.input, &input)
-			num, err := strconv.ParseFloat(input, 64)
-			// 使用大写的变量名
-			normalizedName := i.normalizeName(varName)
-			if err != nil {
-				// 解析失败，作为字符串存储
-				i.variables[normalizedName] = StringValue(input)
-			} else {
-				// 解析成功，作为数字存储
-				i.variables[normalizedName] = NumberValue(num)
+	case *ast.InputStmt:
+		// INPUT 读取一行，按逗号拆分后依次存入各目标；LINE INPUT 把整行读入一个字符串变量
+		var values []Value
+		var err error
+		if n.Line {
+			if InputTypes(n.Vars) != string(InputString) {
+				fmt.Fprintf(i.errOutput, "Error: LINE INPUT needs a string variable: %s\n", n.Vars[0])
+				return false
 			}
+			values, err = LineInput(i.input, i.output, n.PromptText())
+		} else {
+			values, err = Input(i.input, i.output, n.PromptText(), InputTypes(n.Vars))
+		}
+		if err != nil {
+			// 输入结束后无法继续执行，结束程序
+			fmt.Fprintf(i.errOutput, "Error: %v\n", err)
+			i.currentLine = len(i.program.Lines)
+			return true
+		}
+		// 数组下标在读入输入之后依次计算，INPUT I, A(I) 使用新读入的 I
+		for idx, target := range n.Vars {
+			i.assign(target, values[idx])
 		}
 		return false
 
@@ -475,6 +741,52 @@ func (i *Interpreter) executeStatement(stmt ast.Node) bool {
 	}
 }
 
+// assign 把 value 存入变量或数组元素 target
+func (i *Interpreter) assign(target ast.Node, value Value) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		// 普通变量赋值 - 使用大写的变量名
+		normalizedName := i.normalizeName(target.Name)
+		i.variables[normalizedName] = value
+	case *ast.ArrayAccess:
+		// 数组元素赋值 - 使用大写的数组名
+		normalizedName := i.normalizeName(target.Name)
+		arr, ok := i.arrays[normalizedName]
+		if !ok {
+			fmt.Fprintf(i.errOutput, "Error: Array '%s' not declared\n", target.Name)
+			return
+		}
+		// 计算多维索引
+		indices := i.getIndexBuf(len(target.Indices))
+		for idx, idxExpr := range target.Indices {
+			indices[idx] = int(i.evaluateExpr(idxExpr).AsNumber())
+		}
+		flatIndex := arr.CalculateIndex(indices)
+		if flatIndex < 0 {
+			fmt.Fprintf(i.errOutput, "Error: Array index out of bounds\n")
+			return
+		}
+		if arr.Strs != nil {
+			arr.Strs[flatIndex] = value.String()
+		} else {
+			arr.Data[flatIndex] = value.AsNumber()
+		}
+	case *ast.MapAccess:
+		// 字典赋值：键不存在时添加
+		m := i.mapOf(target.Name)
+		if m == nil {
+			return
+		}
+		if key, ok := i.mapKey(target.Key); ok {
+			m.Set(key, value)
+		}
+	case *ast.FieldAccess:
+		i.assignField(target, value)
+	default:
+		fmt.Fprintf(i.errOutput, "Error: Invalid assignment target type: %T\n", target)
+	}
+}
+
 // evaluateExpr 计算表达式的值
 // 支持数字、字符串、变量、二元运算、比较运算、逻辑运算、一元运算
 func (i *Interpreter) evaluateExpr(node ast.Node) Value {
@@ -518,8 +830,31 @@ func (i *Interpreter) evaluateExpr(node ast.Node) Value {
 			fmt.Fprintf(i.errOutput, "Error: Array index out of bounds\n")
 			return NumberValue(0)
 		}
+		if arr.Strs != nil {
+			return StringValue(arr.Strs[flatIndex])
+		}
 		return NumberValue(arr.Data[flatIndex])
 
+	case *ast.MapAccess:
+		// 字典访问：不存在的键得到 0 或空串
+		m := i.mapOf(n.Name)
+		if m == nil {
+			return NumberValue(0)
+		}
+		key, ok := i.mapKey(n.Key)
+		if !ok {
+			return NumberValue(0)
+		}
+		return m.Get(key)
+
+	case *ast.MapFunc:
+		// HASKEY、KEYS$、COUNT
+		return i.evaluateMapFunc(n)
+
+	case *ast.FieldAccess:
+		// 记录的字段
+		return i.evaluateField(n)
+
 	case *ast.BinaryOp:
 		// 二元算术运算：+, -, *, /, ^, MOD
 		leftVal := i.evaluateExpr(n.Left)
@@ -666,6 +1001,16 @@ func (i *Interpreter) evaluateFunctionCall(node *ast.FunctionCall) Value {
 	return NumberValue(0)
 }
 
+// EvaluateConstant 计算只包含字面量的表达式（供编译器常量折叠使用）
+// 求值过程中出现任何错误（除零、参数越界、未定义变量等）时返回 false，
+// 此时调用方应保留原表达式，让错误在运行时按原样报告
+func EvaluateConstant(expr ast.Node) (Value, bool) {
+	var errBuf strings.Builder
+	i := NewInterpreter(WithOutput(io.Discard), WithErrOutput(&errBuf))
+	val := i.evaluateExpr(expr)
+	return val, errBuf.Len() == 0
+}
+
 // getIndexBuf 获取可复用的索引缓冲区，避免每次数组访问分配新切片
 func (i *Interpreter) getIndexBuf(size int) []int {
 	if cap(i.indexBuf) >= size {
@@ -0,0 +1,159 @@
+package interpreter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"zork-basic/internal/ast"
+)
+
+// MapInfo 是 DIM ... AS MAP 声明的字典，键是字符串。名字以 $ 结尾的字典保存字符串，
+// 其余的保存数值。字典和数组放在同一个表中（见 ArrayInfo.Map），KEYS$ 和 FOR EACH
+// 按键的字典序遍历，结果与插入的顺序无关
+type MapInfo struct {
+	Nums map[string]float64 // 数值字典的数据，字符串字典为 nil
+	Strs map[string]string  // 字符串字典的数据，数值字典为 nil
+	keys []string           // 排好序的键，增删键时作废，需要时重新排序
+}
+
+// NewMap 创建一个空字典，str 为 true 时字典保存字符串
+func NewMap(str bool) *ArrayInfo {
+	m := &MapInfo{}
+	if str {
+		m.Strs = make(map[string]string)
+	} else {
+		m.Nums = make(map[string]float64)
+	}
+	return &ArrayInfo{Map: m}
+}
+
+// Len 返回键的个数
+func (m *MapInfo) Len() int {
+	if m.Strs != nil {
+		return len(m.Strs)
+	}
+	return len(m.Nums)
+}
+
+// Has 返回字典中是否有键 key
+func (m *MapInfo) Has(key string) bool {
+	if m.Strs != nil {
+		_, ok := m.Strs[key]
+		return ok
+	}
+	_, ok := m.Nums[key]
+	return ok
+}
+
+// SetNumber 把数值字典中 key 的值设为 v
+func (m *MapInfo) SetNumber(key string, v float64) {
+	if _, ok := m.Nums[key]; !ok {
+		m.keys = nil
+	}
+	m.Nums[key] = v
+}
+
+// SetString 把字符串字典中 key 的值设为 v
+func (m *MapInfo) SetString(key, v string) {
+	if _, ok := m.Strs[key]; !ok {
+		m.keys = nil
+	}
+	m.Strs[key] = v
+}
+
+// Set 把 key 的值设为 v，并转换为字典保存的类型
+func (m *MapInfo) Set(key string, v Value) {
+	if m.Strs != nil {
+		m.SetString(key, v.String())
+	} else {
+		m.SetNumber(key, v.AsNumber())
+	}
+}
+
+// Get 返回 key 的值，没有这个键时返回 0 或空串
+func (m *MapInfo) Get(key string) Value {
+	if m.Strs != nil {
+		return StringValue(m.Strs[key])
+	}
+	return NumberValue(m.Nums[key])
+}
+
+// Delete 删除键 key，没有这个键时什么也不做
+func (m *MapInfo) Delete(key string) {
+	if !m.Has(key) {
+		return
+	}
+	delete(m.Strs, key)
+	delete(m.Nums, key)
+	m.keys = nil
+}
+
+// Keys 返回按字典序排列的全部键，调用方不能修改返回的切片。之后增删键时字典
+// 重新生成切片，已经返回的切片不变，FOR EACH 因此可以直接遍历它
+func (m *MapInfo) Keys() []string {
+	if m.keys == nil {
+		if m.Strs != nil {
+			m.keys = slices.Sorted(maps.Keys(m.Strs))
+		} else {
+			m.keys = slices.Sorted(maps.Keys(m.Nums))
+		}
+	}
+	return m.keys
+}
+
+// Key 返回第 idx 个键（从 0 开始），idx 超出范围时返回 false
+func (m *MapInfo) Key(idx int) (string, bool) {
+	keys := m.Keys()
+	if idx < 0 || idx >= len(keys) {
+		return "", false
+	}
+	return keys[idx], true
+}
+
+// mapOf 返回名为 name 的字典，它还没有用 DIM ... AS MAP 创建时报告错误并返回 nil
+func (i *Interpreter) mapOf(name string) *MapInfo {
+	arr, ok := i.arrays[i.normalizeName(name)]
+	if !ok || arr.Map == nil {
+		fmt.Fprintf(i.errOutput, "Error: MAP '%s' not declared\n", name)
+		return nil
+	}
+	return arr.Map
+}
+
+// mapKey 计算字典的键，键必须是字符串
+func (i *Interpreter) mapKey(expr ast.Node) (string, bool) {
+	v := i.evaluateExpr(expr)
+	if !v.IsString() {
+		fmt.Fprintf(i.errOutput, "Error: MAP key must be a string, got %s\n", v.String())
+		return "", false
+	}
+	return v.String(), true
+}
+
+// evaluateMapFunc 计算 HASKEY、KEYS$ 和 COUNT
+func (i *Interpreter) evaluateMapFunc(n *ast.MapFunc) Value {
+	m := i.mapOf(n.Map)
+	if m == nil {
+		return NumberValue(0)
+	}
+	switch n.Func {
+	case "HASKEY":
+		key, ok := i.mapKey(n.Arg)
+		if ok && m.Has(key) {
+			return NumberValue(1)
+		}
+		return NumberValue(0)
+	case "KEYS$":
+		idx := int(i.evaluateExpr(n.Arg).AsNumber())
+		key, ok := m.Key(idx)
+		if !ok {
+			fmt.Fprintf(i.errOutput, "Error: KEYS$ index %d out of range (%d keys)\n", idx, m.Len())
+		}
+		return StringValue(key)
+	case "COUNT":
+		return NumberValue(float64(m.Len()))
+	}
+	fmt.Fprintf(i.errOutput, "Error: Unknown function '%s'\n", n.Func)
+	return NumberValue(0)
+}
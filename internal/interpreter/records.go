@@ -0,0 +1,112 @@
+package interpreter
+
+import (
+	"fmt"
+	"slices"
+
+	"zork-basic/internal/ast"
+)
+
+// NewRecordInfo 创建记录变量（sizes 为空）或记录数组。shape 是 ast.RecordType.Shape，
+// 每个记录占 len(shape) 个槽位，最后一维就是槽位：记录数组元素 (i, j) 的第 s 个槽位
+// 在 CalculateIndex([i, j, s])。数值槽位存放在 Data 中，字符串槽位存放在 Strs 中，
+// 两者都按全部槽位分配，复制记录时整段复制
+func NewRecordInfo(sizes []int, shape string) *ArrayInfo {
+	dims := append(slices.Clone(sizes), len(shape))
+	totalSize := 1
+	for _, d := range dims {
+		totalSize *= d
+	}
+	return &ArrayInfo{
+		dims:      dims,
+		Data:      make([]float64, totalSize),
+		Strs:      make([]string, totalSize),
+		Shape:     shape,
+		totalSize: totalSize,
+	}
+}
+
+// IsString 判断第 i 个元素是否保存字符串：字符串数组的元素或记录的字符串槽位
+func (a *ArrayInfo) IsString(i int) bool {
+	if a.Shape != "" {
+		return a.Shape[i%len(a.Shape)] == InputString
+	}
+	return a.Strs != nil
+}
+
+// CopySlots 把 src 中从 from 开始的 n 个槽位复制到 a 中从 to 开始的槽位，两者可以是
+// 同一个记录数组
+func (a *ArrayInfo) CopySlots(to int, src *ArrayInfo, from, n int) {
+	copy(a.Data[to:to+n], src.Data[from:from+n])
+	copy(a.Strs[to:to+n], src.Strs[from:from+n])
+}
+
+// recordSlot 返回记录 ref 中第 slot 个槽位所在的记录数组和位置。记录还没有用 DIM
+// 创建或下标越界时报告错误并返回 nil
+func (i *Interpreter) recordSlot(ref *ast.RecordRef, slot int) (*ArrayInfo, int) {
+	indices := make([]int, len(ref.Indices)+1)
+	for idx, expr := range ref.Indices {
+		indices[idx] = int(i.evaluateExpr(expr).AsNumber())
+	}
+	indices[len(ref.Indices)] = slot
+	arr, ok := i.arrays[i.normalizeName(ref.Name)]
+	if !ok || arr.Shape == "" {
+		fmt.Fprintf(i.errOutput, "Error: Record '%s' not declared\n", ref.Name)
+		return nil, 0
+	}
+	flatIndex := arr.CalculateIndex(indices)
+	if flatIndex < 0 {
+		fmt.Fprintf(i.errOutput, "Error: Array index out of bounds\n")
+		return nil, 0
+	}
+	return arr, flatIndex
+}
+
+// fieldSlot 返回字段 n 所在的记录数组和位置。直接模式的语句没有 TYPE 定义，
+// 其中的字段没有被 parser.ParseProgram 解析，报告错误并返回 nil
+func (i *Interpreter) fieldSlot(n *ast.FieldAccess) (*ArrayInfo, int) {
+	if n.Type == nil {
+		fmt.Fprintf(i.errOutput, "Error: Record field %s outside a program with its TYPE\n", n)
+		return nil, 0
+	}
+	return i.recordSlot(n.Slot())
+}
+
+// evaluateField 读取记录的字段
+func (i *Interpreter) evaluateField(n *ast.FieldAccess) Value {
+	arr, idx := i.fieldSlot(n)
+	switch {
+	case arr == nil:
+		return NumberValue(0)
+	case arr.IsString(idx):
+		return StringValue(arr.Strs[idx])
+	}
+	return NumberValue(arr.Data[idx])
+}
+
+// assignField 给记录的字段赋值，并转换为字段的类型
+func (i *Interpreter) assignField(n *ast.FieldAccess, value Value) {
+	arr, idx := i.fieldSlot(n)
+	switch {
+	case arr == nil:
+	case arr.IsString(idx):
+		arr.Strs[idx] = value.String()
+	default:
+		arr.Data[idx] = value.AsNumber()
+	}
+}
+
+// copyRecord 执行记录的整体赋值：先计算目标的下标，再计算值的下标，然后逐个槽位复制
+func (i *Interpreter) copyRecord(n *ast.RecordAssignment) {
+	dstRef, dstSlot, t := ast.RecordOf(n.Target)
+	dst, to := i.recordSlot(dstRef, dstSlot)
+	if dst == nil {
+		return
+	}
+	srcRef, srcSlot, _ := ast.RecordOf(n.Value)
+	src, from := i.recordSlot(srcRef, srcSlot)
+	if src == nil {
+		return
+	}
+	dst.CopySlots(to, src, from, t.Size())
+}
@@ -0,0 +1,56 @@
+package interpreter
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Variables 按名称（大写）保存全局变量和数组
+// REPL 用它让直接模式语句和多次 RUN 共享同一组变量，AST 解释器和两种 VM 都可以使用
+type Variables struct {
+	Scalars map[string]Value      // 已赋值的变量
+	Arrays  map[string]*ArrayInfo // 已 DIM 的数组
+}
+
+// NewVariables 创建一个空的变量表
+func NewVariables() *Variables {
+	return &Variables{
+		Scalars: make(map[string]Value),
+		Arrays:  make(map[string]*ArrayInfo),
+	}
+}
+
+// Clear 删除所有变量和数组（REPL 的 CLEAR 命令）
+func (v *Variables) Clear() {
+	clear(v.Scalars)
+	clear(v.Arrays)
+}
+
+// Break 表示程序在执行过程中被中断：Ctrl-C 时 Line 是下一条要执行的语句所在的行号，
+// STOP 时是 STOP 所在的行号。返回 Break 的引擎保留了全部执行状态，可以继续执行
+type Break struct {
+	Line int
+	Pos  string // 无行号程序中该行在源文件中的位置（"文件:行"），此时 Line 是内部行号
+}
+
+func (b *Break) Error() string {
+	if b.Pos != "" {
+		return fmt.Sprintf("Break at %s", b.Pos)
+	}
+	return fmt.Sprintf("Break in line %d", b.Line)
+}
+
+// WithVariables 让解释器直接读写 vars 中的变量和数组，而不是使用自己的空表
+func WithVariables(vars *Variables) Option {
+	return func(i *Interpreter) {
+		i.variables = vars.Scalars
+		i.arrays = vars.Arrays
+	}
+}
+
+// WithInterrupt 设置中断标志：执行每一行之前检查，被置位时清除标志并返回 *Break
+func WithInterrupt(flag *atomic.Bool) Option {
+	return func(i *Interpreter) {
+		i.interrupt = flag
+	}
+}
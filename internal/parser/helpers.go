@@ -75,6 +75,33 @@ func toNodeSliceFromAny(nodes any) []ast.Node {
 	return result
 }
 
+// param is one entry of a ParamList: the name and, for a record
+// parameter, the type after AS
+type param struct {
+	name, typ string
+}
+
+// splitParams converts an optional []param (from any) to the parameter
+// names and types of SubStmt and DeclareStmt. The types only have a name
+// until ParseProgram resolves them; types is nil when no parameter has one
+func splitParams(values any) (names []string, types []*ast.RecordType) {
+	if values == nil {
+		return nil, nil
+	}
+	params := values.([]param)
+	names = make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.name
+		if p.typ != "" {
+			if types == nil {
+				types = make([]*ast.RecordType, len(params))
+			}
+			types[i] = &ast.RecordType{Name: p.typ}
+		}
+	}
+	return names, types
+}
+
 // buildBinaryOpFromAny is a helper function for building binary operations
 // from any (PEG parser output)
 func buildBinaryOpFromAny(left interface{}, rest interface{}) ast.Node {
@@ -157,3 +184,27 @@ func extractOpString(v interface{}) string {
 		return ""
 	}
 }
+
+// newLine 构造一行，num 为 -1 表示该行没有行号
+func newLine(c *current, num int, label, statements any) *ast.Line {
+	line := &ast.Line{
+		LineNumber: num,
+		Statements: []ast.Node{},
+		Pos:        ast.Position{Line: c.pos.line},
+	}
+	if label != nil {
+		line.Label = label.(string)
+	}
+	if statements != nil {
+		line.Statements = statements.([]ast.Node)
+	}
+	return line
+}
+
+// fieldAccess builds the nested FieldAccess for record.f1.f2...
+func fieldAccess(record ast.Node, fields any) ast.Node {
+	for _, field := range fields.([]string) {
+		record = &ast.FieldAccess{Record: record, Field: field}
+	}
+	return record
+}
@@ -0,0 +1,338 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// expandMaps 展开字典（DIM D AS MAP）的用法，展开后的程序中字典只通过 MapAccess、
+// MapFunc、DeleteKeyStmt 和 ForEachStmt 使用：
+//   - 字典和数组共用名字，DIM D AS MAP 保留在程序中，执行时创建一个空字典；名称以 $
+//     结尾的字典保存字符串，其余的保存数值
+//   - D(k) 换成 MapAccess；HASKEY、KEYS$ 和 COUNT 的第一个参数是字典时换成 MapFunc
+//   - FOR EACH K$ IN D 由执行引擎作为循环执行，这里只检查字典、循环变量和对应的 NEXT
+//
+// 与记录一样，字典的声明对整个程序有效。字典展开在记录之后、SUB/FUNCTION 之前进行，
+// 子程序中的字典因此和数组一样是局部的，也可以用 SHARED D() 共享
+func expandMaps(prog *ast.Program) error {
+	x := newMapExpander()
+	if err := x.collect(prog); err != nil {
+		return err
+	}
+	return x.expand(prog)
+}
+
+// newMapExpander 创建没有任何字典的展开器
+func newMapExpander() *mapExpander {
+	return &mapExpander{maps: make(map[string]bool), arrays: make(map[string]bool)}
+}
+
+// expand 检查 FOR EACH 循环并展开每一行中字典的用法
+func (x *mapExpander) expand(prog *ast.Program) error {
+	if err := checkLoops(prog); err != nil {
+		return err
+	}
+	for _, line := range prog.Lines {
+		stmts, err := x.expandStmts(line.Statements)
+		if err != nil {
+			return fmt.Errorf("%s: %v", line.Pos, err)
+		}
+		line.Statements = stmts
+	}
+	return nil
+}
+
+// isMapType 判断 DIM ... AS 之后的类型名是否是 MAP
+func isMapType(name string) bool {
+	return strings.EqualFold(name, "MAP")
+}
+
+// mapFuncs 是以字典为第一个参数的函数及其参数个数
+var mapFuncs = map[string]int{"HASKEY": 2, "KEYS$": 2, "COUNT": 1}
+
+// mapExpander 保存展开字典时的状态
+type mapExpander struct {
+	maps   map[string]bool // 字典名（大写）
+	arrays map[string]bool // 用 DIM 声明的普通数组名（大写）
+}
+
+// collect 收集 DIM 声明的字典和数组
+func (x *mapExpander) collect(prog *ast.Program) error {
+	var visit func(stmts []ast.Node) error
+	visit = func(stmts []ast.Node) error {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *ast.IfStmt:
+				if err := visit(s.ThenStmts); err != nil {
+					return err
+				}
+				if err := visit(s.ElseStmts); err != nil {
+					return err
+				}
+			case *ast.DimStmt:
+				name := strings.ToUpper(s.Name)
+				switch {
+				case s.Type == "":
+					x.arrays[name] = true
+				case isMapType(s.Type):
+					if len(s.Sizes) > 0 {
+						return fmt.Errorf("DIM %s AS MAP: a MAP has no dimensions", s.Name)
+					}
+					x.maps[name] = true
+				}
+			}
+		}
+		return nil
+	}
+	for _, line := range prog.Lines {
+		if err := visit(line.Statements); err != nil {
+			return fmt.Errorf("%s: %v", line.Pos, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(x.maps)) {
+		if x.arrays[name] {
+			return fmt.Errorf("%s is both an array and a MAP", name)
+		}
+	}
+	return nil
+}
+
+// checkLoops 检查 FOR EACH 和 NEXT 的配对。与执行引擎一样，NEXT 结束最内层尚未
+// 结束的循环。字典为空时 FOR EACH 跳到对应的 NEXT 之后，因此它们都不能在单行 IF 中
+func checkLoops(prog *ast.Program) error {
+	type loop struct {
+		each *ast.ForEachStmt // FOR EACH 循环，普通 FOR 为 nil
+		pos  ast.Position     // FOR EACH 所在的行
+	}
+	var open []loop
+	var branch func(stmts []ast.Node) error
+	branch = func(stmts []ast.Node) error {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *ast.ForStmt:
+				open = append(open, loop{})
+			case *ast.ForEachStmt:
+				return fmt.Errorf("%s cannot be inside a single-line IF", s)
+			case *ast.NextStmt:
+				if len(open) == 0 {
+					continue
+				}
+				if each := open[len(open)-1].each; each != nil {
+					return fmt.Errorf("NEXT of FOR EACH %s cannot be inside a single-line IF", each.Var)
+				}
+				open = open[:len(open)-1]
+			case *ast.IfStmt:
+				if err := branch(s.ThenStmts); err != nil {
+					return err
+				}
+				if err := branch(s.ElseStmts); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, line := range prog.Lines {
+		for _, stmt := range line.Statements {
+			switch s := stmt.(type) {
+			case *ast.ForStmt:
+				open = append(open, loop{})
+			case *ast.ForEachStmt:
+				open = append(open, loop{each: s, pos: line.Pos})
+			case *ast.NextStmt:
+				if len(open) == 0 {
+					// NEXT 没有对应的 FOR 由执行引擎报告
+					continue
+				}
+				if each := open[len(open)-1].each; each != nil && s.Var != "" && !strings.EqualFold(s.Var, each.Var) {
+					return fmt.Errorf("%s: %s does not match %s", line.Pos, s, each)
+				}
+				open = open[:len(open)-1]
+			case *ast.IfStmt:
+				if err := branch(s.ThenStmts); err != nil {
+					return fmt.Errorf("%s: %v", line.Pos, err)
+				}
+				if err := branch(s.ElseStmts); err != nil {
+					return fmt.Errorf("%s: %v", line.Pos, err)
+				}
+			}
+		}
+	}
+	for _, l := range open {
+		if l.each != nil {
+			return fmt.Errorf("%s: FOR EACH without NEXT", l.pos)
+		}
+	}
+	return nil
+}
+
+// expandStmts 展开语句列表中字典的用法
+func (x *mapExpander) expandStmts(stmts []ast.Node) ([]ast.Node, error) {
+	out := make([]ast.Node, 0, len(stmts))
+	for _, stmt := range stmts {
+		var err error
+		expr := func(n ast.Node) ast.Node {
+			if err == nil {
+				n, err = x.expr(n)
+			}
+			return n
+		}
+		switch s := stmt.(type) {
+		case *ast.Assignment:
+			s.Target = expr(s.Target)
+			s.Value = expr(s.Value)
+			if f, ok := s.Target.(*ast.MapFunc); ok {
+				return nil, fmt.Errorf("cannot assign to %s", f)
+			}
+		case *ast.RecordAssignment:
+			s.Target = expr(s.Target)
+			s.Value = expr(s.Value)
+		case *ast.PrintStmt:
+			for i, v := range s.Values {
+				s.Values[i] = expr(v)
+			}
+		case *ast.IfStmt:
+			s.Condition = expr(s.Condition)
+			then, err := x.expandStmts(s.ThenStmts)
+			if err != nil {
+				return nil, err
+			}
+			otherwise, err := x.expandStmts(s.ElseStmts)
+			if err != nil {
+				return nil, err
+			}
+			s.ThenStmts, s.ElseStmts = then, otherwise
+		case *ast.IfBlockStmt:
+			s.Condition = expr(s.Condition)
+		case *ast.ForStmt:
+			if x.maps[strings.ToUpper(s.Var)] {
+				return nil, fmt.Errorf("FOR %s: %s is a MAP", s.Var, s.Var)
+			}
+			s.Start, s.End, s.Step = expr(s.Start), expr(s.End), expr(s.Step)
+		case *ast.ForEachStmt:
+			if !x.maps[strings.ToUpper(s.Map)] {
+				return nil, fmt.Errorf("%s: %s is not a MAP", s, s.Map)
+			}
+			if !strings.HasSuffix(s.Var, "$") {
+				return nil, fmt.Errorf("%s: the loop variable must be a string variable", s)
+			}
+		case *ast.DeleteKeyStmt:
+			if !x.maps[strings.ToUpper(s.Map)] {
+				return nil, fmt.Errorf("%s: %s is not a MAP", s, s.Map)
+			}
+			s.Key = expr(s.Key)
+		case *ast.ChainStmt:
+			s.File = expr(s.File)
+			if s.Line != nil {
+				s.Line = expr(s.Line)
+			}
+		case *ast.InputStmt:
+			for i, v := range s.Vars {
+				s.Vars[i] = expr(v)
+				if f, ok := s.Vars[i].(*ast.MapFunc); ok && err == nil {
+					err = fmt.Errorf("cannot INPUT into %s", f)
+				}
+			}
+		case *ast.CallStmt:
+			for i, arg := range s.Args {
+				s.Args[i] = expr(arg)
+			}
+		case *ast.DimStmt:
+			for i, size := range s.Sizes {
+				s.Sizes[i] = expr(size)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, stmt)
+	}
+	return out, nil
+}
+
+// expr 把表达式中字典的用法换成 MapAccess 和 MapFunc
+func (x *mapExpander) expr(n ast.Node) (ast.Node, error) {
+	var err error
+	sub := func(n ast.Node) ast.Node {
+		if err == nil {
+			n, err = x.expr(n)
+		}
+		return n
+	}
+	switch e := n.(type) {
+	case *ast.Identifier:
+		if x.maps[strings.ToUpper(e.Name)] {
+			return nil, fmt.Errorf("%s is a MAP; use %s(key)", e.Name, e.Name)
+		}
+	case *ast.ArrayAccess:
+		if f, ferr := x.mapFunc(e.Name, e.Indices); f != nil || ferr != nil {
+			return f, ferr
+		}
+		if x.maps[strings.ToUpper(e.Name)] {
+			if len(e.Indices) != 1 {
+				return nil, fmt.Errorf("%s: a MAP takes one key", e)
+			}
+			return &ast.MapAccess{Name: e.Name, Key: sub(e.Indices[0])}, err
+		}
+		e.Indices, err = x.exprs(e.Indices)
+	case *ast.FunctionCall:
+		if f, ferr := x.mapFunc(e.Name, e.Args); f != nil || ferr != nil {
+			return f, ferr
+		}
+		e.Args, err = x.exprs(e.Args)
+	case *ast.RecordRef:
+		e.Indices, err = x.exprs(e.Indices)
+	case *ast.FieldAccess:
+		e.Record = sub(e.Record)
+	case *ast.BinaryOp:
+		e.Left, e.Right = sub(e.Left), sub(e.Right)
+	case *ast.ComparisonOp:
+		e.Left, e.Right = sub(e.Left), sub(e.Right)
+	case *ast.LogicalOp:
+		e.Left, e.Right = sub(e.Left), sub(e.Right)
+	case *ast.UnaryOp:
+		e.Right = sub(e.Right)
+	}
+	return n, err
+}
+
+func (x *mapExpander) exprs(nodes []ast.Node) ([]ast.Node, error) {
+	for i, n := range nodes {
+		var err error
+		if nodes[i], err = x.expr(n); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// mapFunc 在 name(args) 是 HASKEY、KEYS$ 或 COUNT 且第一个参数是字典时返回对应的
+// MapFunc，其他情况（如名为 COUNT 的数组）返回 nil
+func (x *mapExpander) mapFunc(name string, args []ast.Node) (ast.Node, error) {
+	fn := strings.ToUpper(name)
+	want, ok := mapFuncs[fn]
+	if !ok || len(args) == 0 {
+		return nil, nil
+	}
+	m, ok := args[0].(*ast.Identifier)
+	if !ok || !x.maps[strings.ToUpper(m.Name)] {
+		return nil, nil
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", fn, want, len(args))
+	}
+	f := &ast.MapFunc{Func: fn, Map: m.Name}
+	if want == 2 {
+		arg, err := x.expr(args[1])
+		if err != nil {
+			return nil, err
+		}
+		f.Arg = arg
+	}
+	return f, nil
+}
@@ -50,85 +50,252 @@ var g = &grammar{
 		},
 		{
 			name: "Line",
-			pos:  position{line: 19, col: 1, offset: 491},
+			pos:  position{line: 21, col: 1, offset: 652},
+			expr: &choiceExpr{
+				pos: position{line: 21, col: 9, offset: 660},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 21, col: 9, offset: 660},
+						run: (*parser).callonLine2,
+						expr: &seqExpr{
+							pos: position{line: 21, col: 9, offset: 660},
+							exprs: []any{
+								&zeroOrMoreExpr{
+									pos: position{line: 21, col: 9, offset: 660},
+									expr: &charClassMatcher{
+										pos:        position{line: 21, col: 9, offset: 660},
+										val:        "[ \\t]",
+										chars:      []rune{' ', '\t'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 21, col: 16, offset: 667},
+									label: "LineNumber",
+									expr: &ruleRefExpr{
+										pos:  position{line: 21, col: 27, offset: 678},
+										name: "LineNumber",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 21, col: 38, offset: 689},
+									expr: &charClassMatcher{
+										pos:        position{line: 21, col: 38, offset: 689},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 21, col: 43, offset: 694},
+									label: "Label",
+									expr: &zeroOrOneExpr{
+										pos: position{line: 21, col: 49, offset: 700},
+										expr: &ruleRefExpr{
+											pos:  position{line: 21, col: 49, offset: 700},
+											name: "LabelDef",
+										},
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 21, col: 59, offset: 710},
+									expr: &charClassMatcher{
+										pos:        position{line: 21, col: 59, offset: 710},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 21, col: 64, offset: 715},
+									label: "Statements",
+									expr: &zeroOrOneExpr{
+										pos: position{line: 21, col: 75, offset: 726},
+										expr: &ruleRefExpr{
+											pos:  position{line: 21, col: 75, offset: 726},
+											name: "StatementList",
+										},
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 21, col: 90, offset: 741},
+									name: "EndOfLine",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 24, col: 9, offset: 824},
+						run: (*parser).callonLine19,
+						expr: &seqExpr{
+							pos: position{line: 24, col: 9, offset: 824},
+							exprs: []any{
+								&zeroOrMoreExpr{
+									pos: position{line: 24, col: 9, offset: 824},
+									expr: &charClassMatcher{
+										pos:        position{line: 24, col: 9, offset: 824},
+										val:        "[ \\t]",
+										chars:      []rune{' ', '\t'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 24, col: 16, offset: 831},
+									label: "Label",
+									expr: &zeroOrOneExpr{
+										pos: position{line: 24, col: 22, offset: 837},
+										expr: &ruleRefExpr{
+											pos:  position{line: 24, col: 22, offset: 837},
+											name: "LabelDef",
+										},
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 24, col: 32, offset: 847},
+									expr: &charClassMatcher{
+										pos:        position{line: 24, col: 32, offset: 847},
+										val:        "[ \\t]",
+										chars:      []rune{' ', '\t'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 24, col: 39, offset: 854},
+									label: "Statements",
+									expr: &zeroOrOneExpr{
+										pos: position{line: 24, col: 50, offset: 865},
+										expr: &ruleRefExpr{
+											pos:  position{line: 24, col: 50, offset: 865},
+											name: "StatementList",
+										},
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 24, col: 65, offset: 880},
+									name: "EndOfLine",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "LabelDef",
+			pos:  position{line: 29, col: 1, offset: 1009},
 			expr: &actionExpr{
-				pos: position{line: 19, col: 9, offset: 499},
-				run: (*parser).callonLine1,
+				pos: position{line: 29, col: 13, offset: 1021},
+				run: (*parser).callonLabelDef1,
 				expr: &seqExpr{
-					pos: position{line: 19, col: 9, offset: 499},
+					pos: position{line: 29, col: 13, offset: 1021},
 					exprs: []any{
+						&notExpr{
+							pos: position{line: 29, col: 13, offset: 1021},
+							expr: &ruleRefExpr{
+								pos:  position{line: 29, col: 14, offset: 1022},
+								name: "Keyword",
+							},
+						},
 						&labeledExpr{
-							pos:   position{line: 19, col: 9, offset: 499},
-							label: "LineNumber",
+							pos:   position{line: 29, col: 22, offset: 1030},
+							label: "Name",
 							expr: &ruleRefExpr{
-								pos:  position{line: 19, col: 20, offset: 510},
-								name: "LineNumber",
+								pos:  position{line: 29, col: 27, offset: 1035},
+								name: "LabelName",
 							},
 						},
+						&litMatcher{
+							pos:        position{line: 29, col: 37, offset: 1045},
+							val:        ":",
+							ignoreCase: false,
+							want:       "\":\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "LabelName",
+			pos:  position{line: 33, col: 1, offset: 1072},
+			expr: &actionExpr{
+				pos: position{line: 33, col: 14, offset: 1085},
+				run: (*parser).callonLabelName1,
+				expr: &seqExpr{
+					pos: position{line: 33, col: 14, offset: 1085},
+					exprs: []any{
+						&charClassMatcher{
+							pos:        position{line: 33, col: 14, offset: 1085},
+							val:        "[A-Za-z_]",
+							chars:      []rune{'_'},
+							ranges:     []rune{'A', 'Z', 'a', 'z'},
+							ignoreCase: false,
+							inverted:   false,
+						},
 						&zeroOrMoreExpr{
-							pos: position{line: 19, col: 31, offset: 521},
+							pos: position{line: 33, col: 23, offset: 1094},
 							expr: &charClassMatcher{
-								pos:        position{line: 19, col: 31, offset: 521},
-								val:        "[ ]",
-								chars:      []rune{' '},
+								pos:        position{line: 33, col: 23, offset: 1094},
+								val:        "[A-Za-z0-9_]",
+								chars:      []rune{'_'},
+								ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
 								ignoreCase: false,
 								inverted:   false,
 							},
 						},
-						&labeledExpr{
-							pos:   position{line: 19, col: 36, offset: 526},
-							label: "Statements",
-							expr: &zeroOrOneExpr{
-								pos: position{line: 19, col: 47, offset: 537},
-								expr: &ruleRefExpr{
-									pos:  position{line: 19, col: 47, offset: 537},
-									name: "StatementList",
-								},
+						&notExpr{
+							pos: position{line: 33, col: 37, offset: 1108},
+							expr: &charClassMatcher{
+								pos:        position{line: 33, col: 38, offset: 1109},
+								val:        "[$]",
+								chars:      []rune{'$'},
+								ignoreCase: false,
+								inverted:   false,
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 19, col: 62, offset: 552},
-							name: "EndOfLine",
-						},
 					},
 				},
 			},
 		},
 		{
 			name: "StatementList",
-			pos:  position{line: 30, col: 1, offset: 746},
+			pos:  position{line: 37, col: 1, offset: 1146},
 			expr: &actionExpr{
-				pos: position{line: 30, col: 18, offset: 763},
+				pos: position{line: 37, col: 18, offset: 1163},
 				run: (*parser).callonStatementList1,
 				expr: &seqExpr{
-					pos: position{line: 30, col: 18, offset: 763},
+					pos: position{line: 37, col: 18, offset: 1163},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 30, col: 18, offset: 763},
+							pos:   position{line: 37, col: 18, offset: 1163},
 							label: "First",
 							expr: &ruleRefExpr{
-								pos:  position{line: 30, col: 24, offset: 769},
+								pos:  position{line: 37, col: 24, offset: 1169},
 								name: "Statement",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 30, col: 34, offset: 779},
+							pos:   position{line: 37, col: 34, offset: 1179},
 							label: "Rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 30, col: 39, offset: 784},
+								pos: position{line: 37, col: 39, offset: 1184},
 								expr: &seqExpr{
-									pos: position{line: 30, col: 40, offset: 785},
+									pos: position{line: 37, col: 40, offset: 1185},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 30, col: 40, offset: 785},
+											pos:        position{line: 37, col: 40, offset: 1185},
 											val:        ":",
 											ignoreCase: false,
 											want:       "\":\"",
 										},
 										&zeroOrMoreExpr{
-											pos: position{line: 30, col: 44, offset: 789},
+											pos: position{line: 37, col: 44, offset: 1189},
 											expr: &charClassMatcher{
-												pos:        position{line: 30, col: 44, offset: 789},
+												pos:        position{line: 37, col: 44, offset: 1189},
 												val:        "[ ]",
 												chars:      []rune{' '},
 												ignoreCase: false,
@@ -136,7 +303,7 @@ var g = &grammar{
 											},
 										},
 										&ruleRefExpr{
-											pos:  position{line: 30, col: 49, offset: 794},
+											pos:  position{line: 37, col: 49, offset: 1194},
 											name: "Statement",
 										},
 									},
@@ -149,14 +316,14 @@ var g = &grammar{
 		},
 		{
 			name: "LineNumber",
-			pos:  position{line: 42, col: 1, offset: 1067},
+			pos:  position{line: 49, col: 1, offset: 1467},
 			expr: &actionExpr{
-				pos: position{line: 42, col: 15, offset: 1081},
+				pos: position{line: 49, col: 15, offset: 1481},
 				run: (*parser).callonLineNumber1,
 				expr: &oneOrMoreExpr{
-					pos: position{line: 42, col: 15, offset: 1081},
+					pos: position{line: 49, col: 15, offset: 1481},
 					expr: &charClassMatcher{
-						pos:        position{line: 42, col: 15, offset: 1081},
+						pos:        position{line: 49, col: 15, offset: 1481},
 						val:        "[0-9]",
 						ranges:     []rune{'0', '9'},
 						ignoreCase: false,
@@ -167,14 +334,14 @@ var g = &grammar{
 		},
 		{
 			name: "EndOfLine",
-			pos:  position{line: 47, col: 1, offset: 1146},
+			pos:  position{line: 54, col: 1, offset: 1546},
 			expr: &seqExpr{
-				pos: position{line: 47, col: 14, offset: 1159},
+				pos: position{line: 54, col: 14, offset: 1559},
 				exprs: []any{
 					&zeroOrMoreExpr{
-						pos: position{line: 47, col: 14, offset: 1159},
+						pos: position{line: 54, col: 14, offset: 1559},
 						expr: &charClassMatcher{
-							pos:        position{line: 47, col: 14, offset: 1159},
+							pos:        position{line: 54, col: 14, offset: 1559},
 							val:        "[ \\t]",
 							chars:      []rune{' ', '\t'},
 							ignoreCase: false,
@@ -182,16 +349,16 @@ var g = &grammar{
 						},
 					},
 					&zeroOrOneExpr{
-						pos: position{line: 47, col: 21, offset: 1166},
+						pos: position{line: 54, col: 21, offset: 1566},
 						expr: &litMatcher{
-							pos:        position{line: 47, col: 21, offset: 1166},
+							pos:        position{line: 54, col: 21, offset: 1566},
 							val:        "\r",
 							ignoreCase: false,
 							want:       "\"\\r\"",
 						},
 					},
 					&litMatcher{
-						pos:        position{line: 47, col: 27, offset: 1172},
+						pos:        position{line: 54, col: 27, offset: 1572},
 						val:        "\n",
 						ignoreCase: false,
 						want:       "\"\\n\"",
@@ -201,30 +368,30 @@ var g = &grammar{
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 49, col: 1, offset: 1178},
+			pos:  position{line: 56, col: 1, offset: 1578},
 			expr: &notExpr{
-				pos: position{line: 49, col: 8, offset: 1185},
+				pos: position{line: 56, col: 8, offset: 1585},
 				expr: &anyMatcher{
-					line: 49, col: 9, offset: 1186,
+					line: 56, col: 9, offset: 1586,
 				},
 			},
 		},
 		{
 			name: "KW_END",
-			pos:  position{line: 56, col: 1, offset: 1438},
+			pos:  position{line: 63, col: 1, offset: 1838},
 			expr: &seqExpr{
-				pos: position{line: 56, col: 11, offset: 1448},
+				pos: position{line: 63, col: 11, offset: 1848},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 56, col: 11, offset: 1448},
+						pos:        position{line: 63, col: 11, offset: 1848},
 						val:        "end",
 						ignoreCase: true,
 						want:       "\"END\"i",
 					},
 					&notExpr{
-						pos: position{line: 56, col: 18, offset: 1455},
+						pos: position{line: 63, col: 18, offset: 1855},
 						expr: &charClassMatcher{
-							pos:        position{line: 56, col: 19, offset: 1456},
+							pos:        position{line: 63, col: 19, offset: 1856},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -237,20 +404,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_IF",
-			pos:  position{line: 57, col: 1, offset: 1470},
+			pos:  position{line: 64, col: 1, offset: 1870},
 			expr: &seqExpr{
-				pos: position{line: 57, col: 10, offset: 1479},
+				pos: position{line: 64, col: 10, offset: 1879},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 57, col: 10, offset: 1479},
+						pos:        position{line: 64, col: 10, offset: 1879},
 						val:        "if",
 						ignoreCase: true,
 						want:       "\"IF\"i",
 					},
 					&notExpr{
-						pos: position{line: 57, col: 16, offset: 1485},
+						pos: position{line: 64, col: 16, offset: 1885},
 						expr: &charClassMatcher{
-							pos:        position{line: 57, col: 17, offset: 1486},
+							pos:        position{line: 64, col: 17, offset: 1886},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -263,20 +430,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_THEN",
-			pos:  position{line: 58, col: 1, offset: 1500},
+			pos:  position{line: 65, col: 1, offset: 1900},
 			expr: &seqExpr{
-				pos: position{line: 58, col: 12, offset: 1511},
+				pos: position{line: 65, col: 12, offset: 1911},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 58, col: 12, offset: 1511},
+						pos:        position{line: 65, col: 12, offset: 1911},
 						val:        "then",
 						ignoreCase: true,
 						want:       "\"THEN\"i",
 					},
 					&notExpr{
-						pos: position{line: 58, col: 20, offset: 1519},
+						pos: position{line: 65, col: 20, offset: 1919},
 						expr: &charClassMatcher{
-							pos:        position{line: 58, col: 21, offset: 1520},
+							pos:        position{line: 65, col: 21, offset: 1920},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -289,20 +456,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_ELSE",
-			pos:  position{line: 59, col: 1, offset: 1534},
+			pos:  position{line: 66, col: 1, offset: 1934},
 			expr: &seqExpr{
-				pos: position{line: 59, col: 12, offset: 1545},
+				pos: position{line: 66, col: 12, offset: 1945},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 59, col: 12, offset: 1545},
+						pos:        position{line: 66, col: 12, offset: 1945},
 						val:        "else",
 						ignoreCase: true,
 						want:       "\"ELSE\"i",
 					},
 					&notExpr{
-						pos: position{line: 59, col: 20, offset: 1553},
+						pos: position{line: 66, col: 20, offset: 1953},
 						expr: &charClassMatcher{
-							pos:        position{line: 59, col: 21, offset: 1554},
+							pos:        position{line: 66, col: 21, offset: 1954},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -315,20 +482,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_PRINT",
-			pos:  position{line: 60, col: 1, offset: 1568},
+			pos:  position{line: 67, col: 1, offset: 1968},
 			expr: &seqExpr{
-				pos: position{line: 60, col: 13, offset: 1580},
+				pos: position{line: 67, col: 13, offset: 1980},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 60, col: 13, offset: 1580},
+						pos:        position{line: 67, col: 13, offset: 1980},
 						val:        "print",
 						ignoreCase: true,
 						want:       "\"PRINT\"i",
 					},
 					&notExpr{
-						pos: position{line: 60, col: 22, offset: 1589},
+						pos: position{line: 67, col: 22, offset: 1989},
 						expr: &charClassMatcher{
-							pos:        position{line: 60, col: 23, offset: 1590},
+							pos:        position{line: 67, col: 23, offset: 1990},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -341,20 +508,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_FOR",
-			pos:  position{line: 61, col: 1, offset: 1604},
+			pos:  position{line: 68, col: 1, offset: 2004},
 			expr: &seqExpr{
-				pos: position{line: 61, col: 11, offset: 1614},
+				pos: position{line: 68, col: 11, offset: 2014},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 61, col: 11, offset: 1614},
+						pos:        position{line: 68, col: 11, offset: 2014},
 						val:        "for",
 						ignoreCase: true,
 						want:       "\"FOR\"i",
 					},
 					&notExpr{
-						pos: position{line: 61, col: 18, offset: 1621},
+						pos: position{line: 68, col: 18, offset: 2021},
 						expr: &charClassMatcher{
-							pos:        position{line: 61, col: 19, offset: 1622},
+							pos:        position{line: 68, col: 19, offset: 2022},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -367,20 +534,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_TO",
-			pos:  position{line: 62, col: 1, offset: 1636},
+			pos:  position{line: 69, col: 1, offset: 2036},
 			expr: &seqExpr{
-				pos: position{line: 62, col: 10, offset: 1645},
+				pos: position{line: 69, col: 10, offset: 2045},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 62, col: 10, offset: 1645},
+						pos:        position{line: 69, col: 10, offset: 2045},
 						val:        "to",
 						ignoreCase: true,
 						want:       "\"TO\"i",
 					},
 					&notExpr{
-						pos: position{line: 62, col: 16, offset: 1651},
+						pos: position{line: 69, col: 16, offset: 2051},
 						expr: &charClassMatcher{
-							pos:        position{line: 62, col: 17, offset: 1652},
+							pos:        position{line: 69, col: 17, offset: 2052},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -393,20 +560,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_STEP",
-			pos:  position{line: 63, col: 1, offset: 1666},
+			pos:  position{line: 70, col: 1, offset: 2066},
 			expr: &seqExpr{
-				pos: position{line: 63, col: 12, offset: 1677},
+				pos: position{line: 70, col: 12, offset: 2077},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 63, col: 12, offset: 1677},
+						pos:        position{line: 70, col: 12, offset: 2077},
 						val:        "step",
 						ignoreCase: true,
 						want:       "\"STEP\"i",
 					},
 					&notExpr{
-						pos: position{line: 63, col: 20, offset: 1685},
+						pos: position{line: 70, col: 20, offset: 2085},
 						expr: &charClassMatcher{
-							pos:        position{line: 63, col: 21, offset: 1686},
+							pos:        position{line: 70, col: 21, offset: 2086},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -419,20 +586,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_NEXT",
-			pos:  position{line: 64, col: 1, offset: 1700},
+			pos:  position{line: 71, col: 1, offset: 2100},
 			expr: &seqExpr{
-				pos: position{line: 64, col: 12, offset: 1711},
+				pos: position{line: 71, col: 12, offset: 2111},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 64, col: 12, offset: 1711},
+						pos:        position{line: 71, col: 12, offset: 2111},
 						val:        "next",
 						ignoreCase: true,
 						want:       "\"NEXT\"i",
 					},
 					&notExpr{
-						pos: position{line: 64, col: 20, offset: 1719},
+						pos: position{line: 71, col: 20, offset: 2119},
 						expr: &charClassMatcher{
-							pos:        position{line: 64, col: 21, offset: 1720},
+							pos:        position{line: 71, col: 21, offset: 2120},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -445,20 +612,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_GOTO",
-			pos:  position{line: 65, col: 1, offset: 1734},
+			pos:  position{line: 72, col: 1, offset: 2134},
 			expr: &seqExpr{
-				pos: position{line: 65, col: 12, offset: 1745},
+				pos: position{line: 72, col: 12, offset: 2145},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 65, col: 12, offset: 1745},
+						pos:        position{line: 72, col: 12, offset: 2145},
 						val:        "goto",
 						ignoreCase: true,
 						want:       "\"GOTO\"i",
 					},
 					&notExpr{
-						pos: position{line: 65, col: 20, offset: 1753},
+						pos: position{line: 72, col: 20, offset: 2153},
 						expr: &charClassMatcher{
-							pos:        position{line: 65, col: 21, offset: 1754},
+							pos:        position{line: 72, col: 21, offset: 2154},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -471,20 +638,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_GOSUB",
-			pos:  position{line: 66, col: 1, offset: 1768},
+			pos:  position{line: 73, col: 1, offset: 2168},
 			expr: &seqExpr{
-				pos: position{line: 66, col: 13, offset: 1780},
+				pos: position{line: 73, col: 13, offset: 2180},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 66, col: 13, offset: 1780},
+						pos:        position{line: 73, col: 13, offset: 2180},
 						val:        "gosub",
 						ignoreCase: true,
 						want:       "\"GOSUB\"i",
 					},
 					&notExpr{
-						pos: position{line: 66, col: 22, offset: 1789},
+						pos: position{line: 73, col: 22, offset: 2189},
 						expr: &charClassMatcher{
-							pos:        position{line: 66, col: 23, offset: 1790},
+							pos:        position{line: 73, col: 23, offset: 2190},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -497,20 +664,46 @@ var g = &grammar{
 		},
 		{
 			name: "KW_RETURN",
-			pos:  position{line: 67, col: 1, offset: 1804},
+			pos:  position{line: 74, col: 1, offset: 2204},
 			expr: &seqExpr{
-				pos: position{line: 67, col: 14, offset: 1817},
+				pos: position{line: 74, col: 14, offset: 2217},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 67, col: 14, offset: 1817},
+						pos:        position{line: 74, col: 14, offset: 2217},
 						val:        "return",
 						ignoreCase: true,
 						want:       "\"RETURN\"i",
 					},
 					&notExpr{
-						pos: position{line: 67, col: 24, offset: 1827},
+						pos: position{line: 74, col: 24, offset: 2227},
+						expr: &charClassMatcher{
+							pos:        position{line: 74, col: 25, offset: 2228},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_STOP",
+			pos:  position{line: 75, col: 1, offset: 2242},
+			expr: &seqExpr{
+				pos: position{line: 75, col: 12, offset: 2253},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 75, col: 12, offset: 2253},
+						val:        "stop",
+						ignoreCase: true,
+						want:       "\"STOP\"i",
+					},
+					&notExpr{
+						pos: position{line: 75, col: 20, offset: 2261},
 						expr: &charClassMatcher{
-							pos:        position{line: 67, col: 25, offset: 1828},
+							pos:        position{line: 75, col: 21, offset: 2262},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -523,20 +716,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_LET",
-			pos:  position{line: 68, col: 1, offset: 1842},
+			pos:  position{line: 76, col: 1, offset: 2276},
 			expr: &seqExpr{
-				pos: position{line: 68, col: 11, offset: 1852},
+				pos: position{line: 76, col: 11, offset: 2286},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 68, col: 11, offset: 1852},
+						pos:        position{line: 76, col: 11, offset: 2286},
 						val:        "let",
 						ignoreCase: true,
 						want:       "\"LET\"i",
 					},
 					&notExpr{
-						pos: position{line: 68, col: 18, offset: 1859},
+						pos: position{line: 76, col: 18, offset: 2293},
 						expr: &charClassMatcher{
-							pos:        position{line: 68, col: 19, offset: 1860},
+							pos:        position{line: 76, col: 19, offset: 2294},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -549,20 +742,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_REM",
-			pos:  position{line: 69, col: 1, offset: 1874},
+			pos:  position{line: 77, col: 1, offset: 2308},
 			expr: &seqExpr{
-				pos: position{line: 69, col: 11, offset: 1884},
+				pos: position{line: 77, col: 11, offset: 2318},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 69, col: 11, offset: 1884},
+						pos:        position{line: 77, col: 11, offset: 2318},
 						val:        "rem",
 						ignoreCase: true,
 						want:       "\"REM\"i",
 					},
 					&notExpr{
-						pos: position{line: 69, col: 18, offset: 1891},
+						pos: position{line: 77, col: 18, offset: 2325},
 						expr: &charClassMatcher{
-							pos:        position{line: 69, col: 19, offset: 1892},
+							pos:        position{line: 77, col: 19, offset: 2326},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -575,20 +768,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_DIM",
-			pos:  position{line: 70, col: 1, offset: 1906},
+			pos:  position{line: 78, col: 1, offset: 2340},
 			expr: &seqExpr{
-				pos: position{line: 70, col: 11, offset: 1916},
+				pos: position{line: 78, col: 11, offset: 2350},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 70, col: 11, offset: 1916},
+						pos:        position{line: 78, col: 11, offset: 2350},
 						val:        "dim",
 						ignoreCase: true,
 						want:       "\"DIM\"i",
 					},
 					&notExpr{
-						pos: position{line: 70, col: 18, offset: 1923},
+						pos: position{line: 78, col: 18, offset: 2357},
 						expr: &charClassMatcher{
-							pos:        position{line: 70, col: 19, offset: 1924},
+							pos:        position{line: 78, col: 19, offset: 2358},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -601,20 +794,46 @@ var g = &grammar{
 		},
 		{
 			name: "KW_INPUT",
-			pos:  position{line: 71, col: 1, offset: 1938},
+			pos:  position{line: 79, col: 1, offset: 2372},
 			expr: &seqExpr{
-				pos: position{line: 71, col: 13, offset: 1950},
+				pos: position{line: 79, col: 13, offset: 2384},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 71, col: 13, offset: 1950},
+						pos:        position{line: 79, col: 13, offset: 2384},
 						val:        "input",
 						ignoreCase: true,
 						want:       "\"INPUT\"i",
 					},
 					&notExpr{
-						pos: position{line: 71, col: 22, offset: 1959},
+						pos: position{line: 79, col: 22, offset: 2393},
+						expr: &charClassMatcher{
+							pos:        position{line: 79, col: 23, offset: 2394},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_LINE",
+			pos:  position{line: 80, col: 1, offset: 2408},
+			expr: &seqExpr{
+				pos: position{line: 80, col: 12, offset: 2419},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 80, col: 12, offset: 2419},
+						val:        "line",
+						ignoreCase: true,
+						want:       "\"LINE\"i",
+					},
+					&notExpr{
+						pos: position{line: 80, col: 20, offset: 2427},
 						expr: &charClassMatcher{
-							pos:        position{line: 71, col: 23, offset: 1960},
+							pos:        position{line: 80, col: 21, offset: 2428},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -627,20 +846,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_NOT",
-			pos:  position{line: 72, col: 1, offset: 1974},
+			pos:  position{line: 81, col: 1, offset: 2442},
 			expr: &seqExpr{
-				pos: position{line: 72, col: 11, offset: 1984},
+				pos: position{line: 81, col: 11, offset: 2452},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 72, col: 11, offset: 1984},
+						pos:        position{line: 81, col: 11, offset: 2452},
 						val:        "not",
 						ignoreCase: true,
 						want:       "\"NOT\"i",
 					},
 					&notExpr{
-						pos: position{line: 72, col: 18, offset: 1991},
+						pos: position{line: 81, col: 18, offset: 2459},
 						expr: &charClassMatcher{
-							pos:        position{line: 72, col: 19, offset: 1992},
+							pos:        position{line: 81, col: 19, offset: 2460},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -653,20 +872,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_AND",
-			pos:  position{line: 73, col: 1, offset: 2006},
+			pos:  position{line: 82, col: 1, offset: 2474},
 			expr: &seqExpr{
-				pos: position{line: 73, col: 11, offset: 2016},
+				pos: position{line: 82, col: 11, offset: 2484},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 73, col: 11, offset: 2016},
+						pos:        position{line: 82, col: 11, offset: 2484},
 						val:        "and",
 						ignoreCase: true,
 						want:       "\"AND\"i",
 					},
 					&notExpr{
-						pos: position{line: 73, col: 18, offset: 2023},
+						pos: position{line: 82, col: 18, offset: 2491},
 						expr: &charClassMatcher{
-							pos:        position{line: 73, col: 19, offset: 2024},
+							pos:        position{line: 82, col: 19, offset: 2492},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -679,20 +898,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_OR",
-			pos:  position{line: 74, col: 1, offset: 2038},
+			pos:  position{line: 83, col: 1, offset: 2506},
 			expr: &seqExpr{
-				pos: position{line: 74, col: 10, offset: 2047},
+				pos: position{line: 83, col: 10, offset: 2515},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 74, col: 10, offset: 2047},
+						pos:        position{line: 83, col: 10, offset: 2515},
 						val:        "or",
 						ignoreCase: true,
 						want:       "\"OR\"i",
 					},
 					&notExpr{
-						pos: position{line: 74, col: 16, offset: 2053},
+						pos: position{line: 83, col: 16, offset: 2521},
 						expr: &charClassMatcher{
-							pos:        position{line: 74, col: 17, offset: 2054},
+							pos:        position{line: 83, col: 17, offset: 2522},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -705,20 +924,20 @@ var g = &grammar{
 		},
 		{
 			name: "KW_MOD",
-			pos:  position{line: 75, col: 1, offset: 2068},
+			pos:  position{line: 84, col: 1, offset: 2536},
 			expr: &seqExpr{
-				pos: position{line: 75, col: 11, offset: 2078},
+				pos: position{line: 84, col: 11, offset: 2546},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 75, col: 11, offset: 2078},
+						pos:        position{line: 84, col: 11, offset: 2546},
 						val:        "mod",
 						ignoreCase: true,
 						want:       "\"MOD\"i",
 					},
 					&notExpr{
-						pos: position{line: 75, col: 18, offset: 2085},
+						pos: position{line: 84, col: 18, offset: 2553},
 						expr: &charClassMatcher{
-							pos:        position{line: 75, col: 19, offset: 2086},
+							pos:        position{line: 84, col: 19, offset: 2554},
 							val:        "[A-Za-z0-9_$]",
 							chars:      []rune{'_', '$'},
 							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -730,201 +949,894 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Statement",
-			pos:  position{line: 81, col: 1, offset: 2240},
-			expr: &choiceExpr{
-				pos: position{line: 81, col: 14, offset: 2253},
-				alternatives: []any{
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 14, offset: 2253},
-						name: "SingleQuoteCommentStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 39, offset: 2278},
-						name: "RemStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 49, offset: 2288},
-						name: "PrintStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 61, offset: 2300},
-						name: "IfStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 70, offset: 2309},
-						name: "IfBlockStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 84, offset: 2323},
-						name: "ElseBlockStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 100, offset: 2339},
-						name: "EndIfStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 112, offset: 2351},
-						name: "ForStmt",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 122, offset: 2361},
-						name: "NextStmt",
+			name: "KW_CHAIN",
+			pos:  position{line: 85, col: 1, offset: 2568},
+			expr: &seqExpr{
+				pos: position{line: 85, col: 13, offset: 2580},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 85, col: 13, offset: 2580},
+						val:        "chain",
+						ignoreCase: true,
+						want:       "\"CHAIN\"i",
 					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 133, offset: 2372},
-						name: "GotoStmt",
+					&notExpr{
+						pos: position{line: 85, col: 22, offset: 2589},
+						expr: &charClassMatcher{
+							pos:        position{line: 85, col: 23, offset: 2590},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
 					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 144, offset: 2383},
-						name: "GosubStmt",
+				},
+			},
+		},
+		{
+			name: "KW_COMMON",
+			pos:  position{line: 86, col: 1, offset: 2604},
+			expr: &seqExpr{
+				pos: position{line: 86, col: 14, offset: 2617},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 86, col: 14, offset: 2617},
+						val:        "common",
+						ignoreCase: true,
+						want:       "\"COMMON\"i",
 					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 156, offset: 2395},
-						name: "ReturnStmt",
+					&notExpr{
+						pos: position{line: 86, col: 24, offset: 2627},
+						expr: &charClassMatcher{
+							pos:        position{line: 86, col: 25, offset: 2628},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
 					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 169, offset: 2408},
-						name: "EndStmt",
+				},
+			},
+		},
+		{
+			name: "KW_ALL",
+			pos:  position{line: 87, col: 1, offset: 2642},
+			expr: &seqExpr{
+				pos: position{line: 87, col: 11, offset: 2652},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 87, col: 11, offset: 2652},
+						val:        "all",
+						ignoreCase: true,
+						want:       "\"ALL\"i",
 					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 179, offset: 2418},
-						name: "DimStmt",
+					&notExpr{
+						pos: position{line: 87, col: 18, offset: 2659},
+						expr: &charClassMatcher{
+							pos:        position{line: 87, col: 19, offset: 2660},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
 					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 189, offset: 2428},
-						name: "InputStmt",
+				},
+			},
+		},
+		{
+			name: "KW_MODULE",
+			pos:  position{line: 88, col: 1, offset: 2674},
+			expr: &seqExpr{
+				pos: position{line: 88, col: 14, offset: 2687},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 88, col: 14, offset: 2687},
+						val:        "module",
+						ignoreCase: true,
+						want:       "\"MODULE\"i",
 					},
-					&ruleRefExpr{
-						pos:  position{line: 81, col: 201, offset: 2440},
-						name: "Assignment",
+					&notExpr{
+						pos: position{line: 88, col: 24, offset: 2697},
+						expr: &charClassMatcher{
+							pos:        position{line: 88, col: 25, offset: 2698},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
 					},
 				},
 			},
 		},
 		{
-			name: "NonIfStatement",
-			pos:  position{line: 85, col: 1, offset: 2570},
-			expr: &choiceExpr{
-				pos: position{line: 85, col: 19, offset: 2588},
-				alternatives: []any{
+			name: "KW_EXPORT",
+			pos:  position{line: 89, col: 1, offset: 2712},
+			expr: &seqExpr{
+				pos: position{line: 89, col: 14, offset: 2725},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 89, col: 14, offset: 2725},
+						val:        "export",
+						ignoreCase: true,
+						want:       "\"EXPORT\"i",
+					},
+					&notExpr{
+						pos: position{line: 89, col: 24, offset: 2735},
+						expr: &charClassMatcher{
+							pos:        position{line: 89, col: 25, offset: 2736},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_SUB",
+			pos:  position{line: 90, col: 1, offset: 2750},
+			expr: &seqExpr{
+				pos: position{line: 90, col: 11, offset: 2760},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 90, col: 11, offset: 2760},
+						val:        "sub",
+						ignoreCase: true,
+						want:       "\"SUB\"i",
+					},
+					&notExpr{
+						pos: position{line: 90, col: 18, offset: 2767},
+						expr: &charClassMatcher{
+							pos:        position{line: 90, col: 19, offset: 2768},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_FUNCTION",
+			pos:  position{line: 91, col: 1, offset: 2782},
+			expr: &seqExpr{
+				pos: position{line: 91, col: 16, offset: 2797},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 91, col: 16, offset: 2797},
+						val:        "function",
+						ignoreCase: true,
+						want:       "\"FUNCTION\"i",
+					},
+					&notExpr{
+						pos: position{line: 91, col: 28, offset: 2809},
+						expr: &charClassMatcher{
+							pos:        position{line: 91, col: 29, offset: 2810},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_EXIT",
+			pos:  position{line: 92, col: 1, offset: 2824},
+			expr: &seqExpr{
+				pos: position{line: 92, col: 12, offset: 2835},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 92, col: 12, offset: 2835},
+						val:        "exit",
+						ignoreCase: true,
+						want:       "\"EXIT\"i",
+					},
+					&notExpr{
+						pos: position{line: 92, col: 20, offset: 2843},
+						expr: &charClassMatcher{
+							pos:        position{line: 92, col: 21, offset: 2844},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_DECLARE",
+			pos:  position{line: 93, col: 1, offset: 2858},
+			expr: &seqExpr{
+				pos: position{line: 93, col: 15, offset: 2872},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 93, col: 15, offset: 2872},
+						val:        "declare",
+						ignoreCase: true,
+						want:       "\"DECLARE\"i",
+					},
+					&notExpr{
+						pos: position{line: 93, col: 26, offset: 2883},
+						expr: &charClassMatcher{
+							pos:        position{line: 93, col: 27, offset: 2884},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_CALL",
+			pos:  position{line: 94, col: 1, offset: 2898},
+			expr: &seqExpr{
+				pos: position{line: 94, col: 12, offset: 2909},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 94, col: 12, offset: 2909},
+						val:        "call",
+						ignoreCase: true,
+						want:       "\"CALL\"i",
+					},
+					&notExpr{
+						pos: position{line: 94, col: 20, offset: 2917},
+						expr: &charClassMatcher{
+							pos:        position{line: 94, col: 21, offset: 2918},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_SHARED",
+			pos:  position{line: 95, col: 1, offset: 2932},
+			expr: &seqExpr{
+				pos: position{line: 95, col: 14, offset: 2945},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 95, col: 14, offset: 2945},
+						val:        "shared",
+						ignoreCase: true,
+						want:       "\"SHARED\"i",
+					},
+					&notExpr{
+						pos: position{line: 95, col: 24, offset: 2955},
+						expr: &charClassMatcher{
+							pos:        position{line: 95, col: 25, offset: 2956},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_TYPE",
+			pos:  position{line: 96, col: 1, offset: 2970},
+			expr: &seqExpr{
+				pos: position{line: 96, col: 12, offset: 2981},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 96, col: 12, offset: 2981},
+						val:        "type",
+						ignoreCase: true,
+						want:       "\"TYPE\"i",
+					},
+					&notExpr{
+						pos: position{line: 96, col: 20, offset: 2989},
+						expr: &charClassMatcher{
+							pos:        position{line: 96, col: 21, offset: 2990},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_AS",
+			pos:  position{line: 97, col: 1, offset: 3004},
+			expr: &seqExpr{
+				pos: position{line: 97, col: 10, offset: 3013},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 97, col: 10, offset: 3013},
+						val:        "as",
+						ignoreCase: true,
+						want:       "\"AS\"i",
+					},
+					&notExpr{
+						pos: position{line: 97, col: 16, offset: 3019},
+						expr: &charClassMatcher{
+							pos:        position{line: 97, col: 17, offset: 3020},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_EACH",
+			pos:  position{line: 99, col: 1, offset: 3120},
+			expr: &seqExpr{
+				pos: position{line: 99, col: 12, offset: 3131},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 99, col: 12, offset: 3131},
+						val:        "each",
+						ignoreCase: true,
+						want:       "\"EACH\"i",
+					},
+					&notExpr{
+						pos: position{line: 99, col: 20, offset: 3139},
+						expr: &charClassMatcher{
+							pos:        position{line: 99, col: 21, offset: 3140},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_IN",
+			pos:  position{line: 100, col: 1, offset: 3154},
+			expr: &seqExpr{
+				pos: position{line: 100, col: 10, offset: 3163},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 100, col: 10, offset: 3163},
+						val:        "in",
+						ignoreCase: true,
+						want:       "\"IN\"i",
+					},
+					&notExpr{
+						pos: position{line: 100, col: 16, offset: 3169},
+						expr: &charClassMatcher{
+							pos:        position{line: 100, col: 17, offset: 3170},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KW_DELETEKEY",
+			pos:  position{line: 101, col: 1, offset: 3184},
+			expr: &seqExpr{
+				pos: position{line: 101, col: 17, offset: 3200},
+				exprs: []any{
+					&litMatcher{
+						pos:        position{line: 101, col: 17, offset: 3200},
+						val:        "deletekey",
+						ignoreCase: true,
+						want:       "\"DELETEKEY\"i",
+					},
+					&notExpr{
+						pos: position{line: 101, col: 30, offset: 3213},
+						expr: &charClassMatcher{
+							pos:        position{line: 101, col: 31, offset: 3214},
+							val:        "[A-Za-z0-9_$]",
+							chars:      []rune{'_', '$'},
+							ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Keyword",
+			pos:  position{line: 103, col: 1, offset: 3229},
+			expr: &choiceExpr{
+				pos: position{line: 103, col: 12, offset: 3240},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 19, offset: 2588},
-						name: "RemStmt",
+						pos:  position{line: 103, col: 12, offset: 3240},
+						name: "KW_END",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 29, offset: 2598},
-						name: "NonEmptyPrintStmt",
+						pos:  position{line: 103, col: 21, offset: 3249},
+						name: "KW_IF",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 49, offset: 2618},
-						name: "ForStmt",
+						pos:  position{line: 103, col: 29, offset: 3257},
+						name: "KW_THEN",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 59, offset: 2628},
-						name: "NextStmt",
+						pos:  position{line: 103, col: 39, offset: 3267},
+						name: "KW_ELSE",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 70, offset: 2639},
-						name: "GotoStmt",
+						pos:  position{line: 103, col: 49, offset: 3277},
+						name: "KW_PRINT",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 81, offset: 2650},
-						name: "GosubStmt",
+						pos:  position{line: 103, col: 60, offset: 3288},
+						name: "KW_FOR",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 93, offset: 2662},
-						name: "ReturnStmt",
+						pos:  position{line: 103, col: 69, offset: 3297},
+						name: "KW_TO",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 106, offset: 2675},
-						name: "EndStmt",
+						pos:  position{line: 103, col: 77, offset: 3305},
+						name: "KW_STEP",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 116, offset: 2685},
-						name: "DimStmt",
+						pos:  position{line: 103, col: 87, offset: 3315},
+						name: "KW_NEXT",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 126, offset: 2695},
-						name: "InputStmt",
+						pos:  position{line: 103, col: 97, offset: 3325},
+						name: "KW_GOTO",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 138, offset: 2707},
-						name: "Assignment",
+						pos:  position{line: 103, col: 107, offset: 3335},
+						name: "KW_GOSUB",
 					},
-				},
-			},
-		},
-		{
-			name: "NonIfNonPrintStatement",
-			pos:  position{line: 89, col: 1, offset: 2875},
-			expr: &choiceExpr{
-				pos: position{line: 89, col: 27, offset: 2901},
-				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 27, offset: 2901},
-						name: "SingleQuoteCommentStmt",
+						pos:  position{line: 103, col: 118, offset: 3346},
+						name: "KW_RETURN",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 52, offset: 2926},
-						name: "RemStmt",
+						pos:  position{line: 103, col: 130, offset: 3358},
+						name: "KW_STOP",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 62, offset: 2936},
-						name: "ForStmt",
+						pos:  position{line: 103, col: 140, offset: 3368},
+						name: "KW_LET",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 72, offset: 2946},
-						name: "NextStmt",
+						pos:  position{line: 103, col: 149, offset: 3377},
+						name: "KW_REM",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 83, offset: 2957},
-						name: "GotoStmt",
+						pos:  position{line: 103, col: 158, offset: 3386},
+						name: "KW_DIM",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 94, offset: 2968},
-						name: "GosubStmt",
+						pos:  position{line: 103, col: 167, offset: 3395},
+						name: "KW_INPUT",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 106, offset: 2980},
-						name: "ReturnStmt",
+						pos:  position{line: 103, col: 178, offset: 3406},
+						name: "KW_LINE",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 119, offset: 2993},
-						name: "EndStmt",
+						pos:  position{line: 103, col: 188, offset: 3416},
+						name: "KW_NOT",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 129, offset: 3003},
-						name: "DimStmt",
+						pos:  position{line: 103, col: 197, offset: 3425},
+						name: "KW_AND",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 139, offset: 3013},
-						name: "InputStmt",
+						pos:  position{line: 103, col: 206, offset: 3434},
+						name: "KW_OR",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 89, col: 151, offset: 3025},
-						name: "Assignment",
+						pos:  position{line: 103, col: 214, offset: 3442},
+						name: "KW_MOD",
 					},
-				},
-			},
-		},
-		{
-			name: "NonEmptyPrintStmt",
-			pos:  position{line: 93, col: 1, offset: 3182},
-			expr: &actionExpr{
-				pos: position{line: 93, col: 22, offset: 3203},
-				run: (*parser).callonNonEmptyPrintStmt1,
-				expr: &seqExpr{
-					pos: position{line: 93, col: 22, offset: 3203},
-					exprs: []any{
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 223, offset: 3451},
+						name: "KW_CHAIN",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 234, offset: 3462},
+						name: "KW_COMMON",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 246, offset: 3474},
+						name: "KW_ALL",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 255, offset: 3483},
+						name: "KW_MODULE",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 267, offset: 3495},
+						name: "KW_EXPORT",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 279, offset: 3507},
+						name: "KW_SUB",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 288, offset: 3516},
+						name: "KW_FUNCTION",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 302, offset: 3530},
+						name: "KW_EXIT",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 312, offset: 3540},
+						name: "KW_DECLARE",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 325, offset: 3553},
+						name: "KW_CALL",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 335, offset: 3563},
+						name: "KW_SHARED",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 347, offset: 3575},
+						name: "KW_TYPE",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 357, offset: 3585},
+						name: "KW_AS",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 103, col: 365, offset: 3593},
+						name: "KW_DELETEKEY",
+					},
+				},
+			},
+		},
+		{
+			name: "Statement",
+			pos:  position{line: 109, col: 1, offset: 3746},
+			expr: &choiceExpr{
+				pos: position{line: 109, col: 14, offset: 3759},
+				alternatives: []any{
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 14, offset: 3759},
+						name: "SingleQuoteCommentStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 39, offset: 3784},
+						name: "RemStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 49, offset: 3794},
+						name: "PrintStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 61, offset: 3806},
+						name: "IfStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 70, offset: 3815},
+						name: "IfBlockStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 84, offset: 3829},
+						name: "ElseBlockStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 100, offset: 3845},
+						name: "EndIfStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 112, offset: 3857},
+						name: "EndSubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 125, offset: 3870},
+						name: "EndTypeStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 139, offset: 3884},
+						name: "ForEachStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 153, offset: 3898},
+						name: "ForStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 163, offset: 3908},
+						name: "NextStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 174, offset: 3919},
+						name: "GotoStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 185, offset: 3930},
+						name: "GosubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 197, offset: 3942},
+						name: "ReturnStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 210, offset: 3955},
+						name: "EndStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 220, offset: 3965},
+						name: "StopStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 231, offset: 3976},
+						name: "ChainStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 243, offset: 3988},
+						name: "CommonStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 256, offset: 4001},
+						name: "DimStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 266, offset: 4011},
+						name: "InputStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 278, offset: 4023},
+						name: "LineInputStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 294, offset: 4039},
+						name: "ModuleStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 307, offset: 4052},
+						name: "SubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 317, offset: 4062},
+						name: "ExitSubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 331, offset: 4076},
+						name: "DeclareStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 345, offset: 4090},
+						name: "CallStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 356, offset: 4101},
+						name: "SharedStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 369, offset: 4114},
+						name: "TypeStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 380, offset: 4125},
+						name: "FieldStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 392, offset: 4137},
+						name: "DeleteKeyStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 408, offset: 4153},
+						name: "Assignment",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 109, col: 421, offset: 4166},
+						name: "BareCallStmt",
+					},
+				},
+			},
+		},
+		{
+			name: "NonIfStatement",
+			pos:  position{line: 113, col: 1, offset: 4298},
+			expr: &choiceExpr{
+				pos: position{line: 113, col: 19, offset: 4316},
+				alternatives: []any{
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 19, offset: 4316},
+						name: "RemStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 29, offset: 4326},
+						name: "NonEmptyPrintStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 49, offset: 4346},
+						name: "ForStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 59, offset: 4356},
+						name: "NextStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 70, offset: 4367},
+						name: "GotoStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 81, offset: 4378},
+						name: "GosubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 93, offset: 4390},
+						name: "ReturnStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 106, offset: 4403},
+						name: "EndStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 116, offset: 4413},
+						name: "StopStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 127, offset: 4424},
+						name: "ChainStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 139, offset: 4436},
+						name: "DimStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 149, offset: 4446},
+						name: "InputStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 161, offset: 4458},
+						name: "LineInputStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 177, offset: 4474},
+						name: "ExitSubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 191, offset: 4488},
+						name: "CallStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 202, offset: 4499},
+						name: "DeleteKeyStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 218, offset: 4515},
+						name: "Assignment",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 113, col: 231, offset: 4528},
+						name: "BareCallStmt",
+					},
+				},
+			},
+		},
+		{
+			name: "NonIfNonPrintStatement",
+			pos:  position{line: 117, col: 1, offset: 4698},
+			expr: &choiceExpr{
+				pos: position{line: 117, col: 27, offset: 4724},
+				alternatives: []any{
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 27, offset: 4724},
+						name: "SingleQuoteCommentStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 52, offset: 4749},
+						name: "RemStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 62, offset: 4759},
+						name: "ForStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 72, offset: 4769},
+						name: "NextStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 83, offset: 4780},
+						name: "GotoStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 94, offset: 4791},
+						name: "GosubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 106, offset: 4803},
+						name: "ReturnStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 119, offset: 4816},
+						name: "EndStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 129, offset: 4826},
+						name: "StopStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 140, offset: 4837},
+						name: "ChainStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 152, offset: 4849},
+						name: "DimStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 162, offset: 4859},
+						name: "InputStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 174, offset: 4871},
+						name: "LineInputStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 190, offset: 4887},
+						name: "ExitSubStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 204, offset: 4901},
+						name: "CallStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 215, offset: 4912},
+						name: "DeleteKeyStmt",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 231, offset: 4928},
+						name: "Assignment",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 117, col: 244, offset: 4941},
+						name: "BareCallStmt",
+					},
+				},
+			},
+		},
+		{
+			name: "NonEmptyPrintStmt",
+			pos:  position{line: 121, col: 1, offset: 5100},
+			expr: &actionExpr{
+				pos: position{line: 121, col: 22, offset: 5121},
+				run: (*parser).callonNonEmptyPrintStmt1,
+				expr: &seqExpr{
+					pos: position{line: 121, col: 22, offset: 5121},
+					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 93, col: 22, offset: 3203},
+							pos:  position{line: 121, col: 22, offset: 5121},
 							name: "KW_PRINT",
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 93, col: 31, offset: 3212},
+							pos: position{line: 121, col: 31, offset: 5130},
 							expr: &charClassMatcher{
-								pos:        position{line: 93, col: 31, offset: 3212},
+								pos:        position{line: 121, col: 31, offset: 5130},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -932,10 +1844,10 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 93, col: 36, offset: 3217},
+							pos:   position{line: 121, col: 36, offset: 5135},
 							label: "Args",
 							expr: &ruleRefExpr{
-								pos:  position{line: 93, col: 41, offset: 3222},
+								pos:  position{line: 121, col: 41, offset: 5140},
 								name: "PrintArgList",
 							},
 						},
@@ -945,24 +1857,24 @@ var g = &grammar{
 		},
 		{
 			name: "Assignment",
-			pos:  position{line: 107, col: 1, offset: 3606},
+			pos:  position{line: 135, col: 1, offset: 5524},
 			expr: &choiceExpr{
-				pos: position{line: 107, col: 15, offset: 3620},
+				pos: position{line: 135, col: 15, offset: 5538},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 107, col: 15, offset: 3620},
+						pos: position{line: 135, col: 15, offset: 5538},
 						run: (*parser).callonAssignment2,
 						expr: &seqExpr{
-							pos: position{line: 107, col: 15, offset: 3620},
+							pos: position{line: 135, col: 15, offset: 5538},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 107, col: 15, offset: 3620},
+									pos:  position{line: 135, col: 15, offset: 5538},
 									name: "KW_LET",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 107, col: 22, offset: 3627},
+									pos: position{line: 135, col: 22, offset: 5545},
 									expr: &charClassMatcher{
-										pos:        position{line: 107, col: 22, offset: 3627},
+										pos:        position{line: 135, col: 22, offset: 5545},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -970,17 +1882,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 107, col: 27, offset: 3632},
+									pos:   position{line: 135, col: 27, offset: 5550},
 									label: "Target",
 									expr: &ruleRefExpr{
-										pos:  position{line: 107, col: 34, offset: 3639},
+										pos:  position{line: 135, col: 34, offset: 5557},
 										name: "Primary",
 									},
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 107, col: 42, offset: 3647},
+									pos: position{line: 135, col: 42, offset: 5565},
 									expr: &charClassMatcher{
-										pos:        position{line: 107, col: 42, offset: 3647},
+										pos:        position{line: 135, col: 42, offset: 5565},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -988,15 +1900,15 @@ var g = &grammar{
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 107, col: 47, offset: 3652},
+									pos:        position{line: 135, col: 47, offset: 5570},
 									val:        "=",
 									ignoreCase: false,
 									want:       "\"=\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 107, col: 51, offset: 3656},
+									pos: position{line: 135, col: 51, offset: 5574},
 									expr: &charClassMatcher{
-										pos:        position{line: 107, col: 51, offset: 3656},
+										pos:        position{line: 135, col: 51, offset: 5574},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1004,10 +1916,10 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 107, col: 56, offset: 3661},
+									pos:   position{line: 135, col: 56, offset: 5579},
 									label: "Value",
 									expr: &ruleRefExpr{
-										pos:  position{line: 107, col: 62, offset: 3667},
+										pos:  position{line: 135, col: 62, offset: 5585},
 										name: "Expression",
 									},
 								},
@@ -1015,23 +1927,23 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 110, col: 15, offset: 3777},
+						pos: position{line: 138, col: 15, offset: 5695},
 						run: (*parser).callonAssignment16,
 						expr: &seqExpr{
-							pos: position{line: 110, col: 15, offset: 3777},
+							pos: position{line: 138, col: 15, offset: 5695},
 							exprs: []any{
 								&labeledExpr{
-									pos:   position{line: 110, col: 15, offset: 3777},
+									pos:   position{line: 138, col: 15, offset: 5695},
 									label: "Target",
 									expr: &ruleRefExpr{
-										pos:  position{line: 110, col: 22, offset: 3784},
+										pos:  position{line: 138, col: 22, offset: 5702},
 										name: "Primary",
 									},
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 110, col: 30, offset: 3792},
+									pos: position{line: 138, col: 30, offset: 5710},
 									expr: &charClassMatcher{
-										pos:        position{line: 110, col: 30, offset: 3792},
+										pos:        position{line: 138, col: 30, offset: 5710},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1039,15 +1951,15 @@ var g = &grammar{
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 110, col: 35, offset: 3797},
+									pos:        position{line: 138, col: 35, offset: 5715},
 									val:        "=",
 									ignoreCase: false,
 									want:       "\"=\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 110, col: 39, offset: 3801},
+									pos: position{line: 138, col: 39, offset: 5719},
 									expr: &charClassMatcher{
-										pos:        position{line: 110, col: 39, offset: 3801},
+										pos:        position{line: 138, col: 39, offset: 5719},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1055,10 +1967,10 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 110, col: 44, offset: 3806},
+									pos:   position{line: 138, col: 44, offset: 5724},
 									label: "Value",
 									expr: &ruleRefExpr{
-										pos:  position{line: 110, col: 50, offset: 3812},
+										pos:  position{line: 138, col: 50, offset: 5730},
 										name: "Expression",
 									},
 								},
@@ -1070,21 +1982,21 @@ var g = &grammar{
 		},
 		{
 			name: "PrintStmt",
-			pos:  position{line: 118, col: 1, offset: 4060},
+			pos:  position{line: 146, col: 1, offset: 5978},
 			expr: &actionExpr{
-				pos: position{line: 118, col: 14, offset: 4073},
+				pos: position{line: 146, col: 14, offset: 5991},
 				run: (*parser).callonPrintStmt1,
 				expr: &seqExpr{
-					pos: position{line: 118, col: 14, offset: 4073},
+					pos: position{line: 146, col: 14, offset: 5991},
 					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 118, col: 14, offset: 4073},
+							pos:  position{line: 146, col: 14, offset: 5991},
 							name: "KW_PRINT",
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 118, col: 23, offset: 4082},
+							pos: position{line: 146, col: 23, offset: 6000},
 							expr: &charClassMatcher{
-								pos:        position{line: 118, col: 23, offset: 4082},
+								pos:        position{line: 146, col: 23, offset: 6000},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -1092,32 +2004,32 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 118, col: 28, offset: 4087},
+							pos:   position{line: 146, col: 28, offset: 6005},
 							label: "Args",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 118, col: 33, offset: 4092},
+								pos: position{line: 146, col: 33, offset: 6010},
 								expr: &ruleRefExpr{
-									pos:  position{line: 118, col: 33, offset: 4092},
+									pos:  position{line: 146, col: 33, offset: 6010},
 									name: "PrintArgList",
 								},
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 118, col: 47, offset: 4106},
+							pos:   position{line: 146, col: 47, offset: 6024},
 							label: "Trailer",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 118, col: 55, offset: 4114},
+								pos: position{line: 146, col: 55, offset: 6032},
 								expr: &choiceExpr{
-									pos: position{line: 118, col: 56, offset: 4115},
+									pos: position{line: 146, col: 56, offset: 6033},
 									alternatives: []any{
 										&litMatcher{
-											pos:        position{line: 118, col: 56, offset: 4115},
+											pos:        position{line: 146, col: 56, offset: 6033},
 											val:        ",",
 											ignoreCase: false,
 											want:       "\",\"",
 										},
 										&litMatcher{
-											pos:        position{line: 118, col: 62, offset: 4121},
+											pos:        position{line: 146, col: 62, offset: 6039},
 											val:        ";",
 											ignoreCase: false,
 											want:       "\";\"",
@@ -1132,40 +2044,40 @@ var g = &grammar{
 		},
 		{
 			name: "PrintArgList",
-			pos:  position{line: 135, col: 1, offset: 4497},
+			pos:  position{line: 163, col: 1, offset: 6415},
 			expr: &actionExpr{
-				pos: position{line: 135, col: 17, offset: 4513},
+				pos: position{line: 163, col: 17, offset: 6431},
 				run: (*parser).callonPrintArgList1,
 				expr: &seqExpr{
-					pos: position{line: 135, col: 17, offset: 4513},
+					pos: position{line: 163, col: 17, offset: 6431},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 135, col: 17, offset: 4513},
+							pos:   position{line: 163, col: 17, offset: 6431},
 							label: "First",
 							expr: &ruleRefExpr{
-								pos:  position{line: 135, col: 23, offset: 4519},
+								pos:  position{line: 163, col: 23, offset: 6437},
 								name: "PrintArg",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 135, col: 32, offset: 4528},
+							pos:   position{line: 163, col: 32, offset: 6446},
 							label: "Rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 135, col: 37, offset: 4533},
+								pos: position{line: 163, col: 37, offset: 6451},
 								expr: &seqExpr{
-									pos: position{line: 135, col: 38, offset: 4534},
+									pos: position{line: 163, col: 38, offset: 6452},
 									exprs: []any{
 										&choiceExpr{
-											pos: position{line: 135, col: 39, offset: 4535},
+											pos: position{line: 163, col: 39, offset: 6453},
 											alternatives: []any{
 												&litMatcher{
-													pos:        position{line: 135, col: 39, offset: 4535},
+													pos:        position{line: 163, col: 39, offset: 6453},
 													val:        ",",
 													ignoreCase: false,
 													want:       "\",\"",
 												},
 												&litMatcher{
-													pos:        position{line: 135, col: 45, offset: 4541},
+													pos:        position{line: 163, col: 45, offset: 6459},
 													val:        ";",
 													ignoreCase: false,
 													want:       "\";\"",
@@ -1173,9 +2085,9 @@ var g = &grammar{
 											},
 										},
 										&zeroOrMoreExpr{
-											pos: position{line: 135, col: 50, offset: 4546},
+											pos: position{line: 163, col: 50, offset: 6464},
 											expr: &charClassMatcher{
-												pos:        position{line: 135, col: 50, offset: 4546},
+												pos:        position{line: 163, col: 50, offset: 6464},
 												val:        "[ ]",
 												chars:      []rune{' '},
 												ignoreCase: false,
@@ -1183,7 +2095,7 @@ var g = &grammar{
 											},
 										},
 										&ruleRefExpr{
-											pos:  position{line: 135, col: 55, offset: 4551},
+											pos:  position{line: 163, col: 55, offset: 6469},
 											name: "PrintArg",
 										},
 									},
@@ -1196,32 +2108,32 @@ var g = &grammar{
 		},
 		{
 			name: "PrintArg",
-			pos:  position{line: 152, col: 1, offset: 5095},
+			pos:  position{line: 180, col: 1, offset: 7013},
 			expr: &ruleRefExpr{
-				pos:  position{line: 152, col: 13, offset: 5107},
+				pos:  position{line: 180, col: 13, offset: 7025},
 				name: "Expression",
 			},
 		},
 		{
 			name: "IfStmt",
-			pos:  position{line: 158, col: 1, offset: 5290},
+			pos:  position{line: 186, col: 1, offset: 7208},
 			expr: &choiceExpr{
-				pos: position{line: 158, col: 11, offset: 5300},
+				pos: position{line: 186, col: 11, offset: 7218},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 158, col: 11, offset: 5300},
+						pos: position{line: 186, col: 11, offset: 7218},
 						run: (*parser).callonIfStmt2,
 						expr: &seqExpr{
-							pos: position{line: 158, col: 11, offset: 5300},
+							pos: position{line: 186, col: 11, offset: 7218},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 158, col: 11, offset: 5300},
+									pos:  position{line: 186, col: 11, offset: 7218},
 									name: "KW_IF",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 158, col: 17, offset: 5306},
+									pos: position{line: 186, col: 17, offset: 7224},
 									expr: &charClassMatcher{
-										pos:        position{line: 158, col: 17, offset: 5306},
+										pos:        position{line: 186, col: 17, offset: 7224},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1229,17 +2141,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 158, col: 28, offset: 5317},
+									pos:   position{line: 186, col: 28, offset: 7235},
 									label: "Condition",
 									expr: &ruleRefExpr{
-										pos:  position{line: 158, col: 38, offset: 5327},
+										pos:  position{line: 186, col: 38, offset: 7245},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 158, col: 49, offset: 5338},
+									pos: position{line: 186, col: 49, offset: 7256},
 									expr: &charClassMatcher{
-										pos:        position{line: 158, col: 49, offset: 5338},
+										pos:        position{line: 186, col: 49, offset: 7256},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1247,13 +2159,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 158, col: 60, offset: 5349},
+									pos:  position{line: 186, col: 60, offset: 7267},
 									name: "KW_THEN",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 158, col: 68, offset: 5357},
+									pos: position{line: 186, col: 68, offset: 7275},
 									expr: &charClassMatcher{
-										pos:        position{line: 158, col: 68, offset: 5357},
+										pos:        position{line: 186, col: 68, offset: 7275},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1261,13 +2173,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 158, col: 79, offset: 5368},
+									pos:  position{line: 186, col: 79, offset: 7286},
 									name: "KW_END",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 158, col: 86, offset: 5375},
+									pos: position{line: 186, col: 86, offset: 7293},
 									expr: &charClassMatcher{
-										pos:        position{line: 158, col: 86, offset: 5375},
+										pos:        position{line: 186, col: 86, offset: 7293},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1275,26 +2187,26 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 158, col: 97, offset: 5386},
+									pos:  position{line: 186, col: 97, offset: 7304},
 									name: "KW_IF",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 166, col: 11, offset: 5554},
+						pos: position{line: 194, col: 11, offset: 7472},
 						run: (*parser).callonIfStmt18,
 						expr: &seqExpr{
-							pos: position{line: 166, col: 11, offset: 5554},
+							pos: position{line: 194, col: 11, offset: 7472},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 166, col: 11, offset: 5554},
+									pos:  position{line: 194, col: 11, offset: 7472},
 									name: "KW_IF",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 166, col: 17, offset: 5560},
+									pos: position{line: 194, col: 17, offset: 7478},
 									expr: &charClassMatcher{
-										pos:        position{line: 166, col: 17, offset: 5560},
+										pos:        position{line: 194, col: 17, offset: 7478},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1302,17 +2214,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 166, col: 28, offset: 5571},
+									pos:   position{line: 194, col: 28, offset: 7489},
 									label: "Condition",
 									expr: &ruleRefExpr{
-										pos:  position{line: 166, col: 38, offset: 5581},
+										pos:  position{line: 194, col: 38, offset: 7499},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 166, col: 49, offset: 5592},
+									pos: position{line: 194, col: 49, offset: 7510},
 									expr: &charClassMatcher{
-										pos:        position{line: 166, col: 49, offset: 5592},
+										pos:        position{line: 194, col: 49, offset: 7510},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1320,13 +2232,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 166, col: 60, offset: 5603},
+									pos:  position{line: 194, col: 60, offset: 7521},
 									name: "KW_THEN",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 166, col: 68, offset: 5611},
+									pos: position{line: 194, col: 68, offset: 7529},
 									expr: &charClassMatcher{
-										pos:        position{line: 166, col: 68, offset: 5611},
+										pos:        position{line: 194, col: 68, offset: 7529},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1334,20 +2246,20 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 166, col: 79, offset: 5622},
+									pos:   position{line: 194, col: 79, offset: 7540},
 									label: "ThenStmts",
 									expr: &oneOrMoreExpr{
-										pos: position{line: 166, col: 89, offset: 5632},
+										pos: position{line: 194, col: 89, offset: 7550},
 										expr: &ruleRefExpr{
-											pos:  position{line: 166, col: 89, offset: 5632},
+											pos:  position{line: 194, col: 89, offset: 7550},
 											name: "Statement",
 										},
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 166, col: 100, offset: 5643},
+									pos: position{line: 194, col: 100, offset: 7561},
 									expr: &charClassMatcher{
-										pos:        position{line: 166, col: 100, offset: 5643},
+										pos:        position{line: 194, col: 100, offset: 7561},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1355,13 +2267,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 166, col: 111, offset: 5654},
+									pos:  position{line: 194, col: 111, offset: 7572},
 									name: "KW_END",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 166, col: 118, offset: 5661},
+									pos: position{line: 194, col: 118, offset: 7579},
 									expr: &charClassMatcher{
-										pos:        position{line: 166, col: 118, offset: 5661},
+										pos:        position{line: 194, col: 118, offset: 7579},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1369,26 +2281,26 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 166, col: 129, offset: 5672},
+									pos:  position{line: 194, col: 129, offset: 7590},
 									name: "KW_IF",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 175, col: 11, offset: 5902},
+						pos: position{line: 203, col: 11, offset: 7820},
 						run: (*parser).callonIfStmt39,
 						expr: &seqExpr{
-							pos: position{line: 175, col: 11, offset: 5902},
+							pos: position{line: 203, col: 11, offset: 7820},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 175, col: 11, offset: 5902},
+									pos:  position{line: 203, col: 11, offset: 7820},
 									name: "KW_IF",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 175, col: 17, offset: 5908},
+									pos: position{line: 203, col: 17, offset: 7826},
 									expr: &charClassMatcher{
-										pos:        position{line: 175, col: 17, offset: 5908},
+										pos:        position{line: 203, col: 17, offset: 7826},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1396,17 +2308,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 175, col: 28, offset: 5919},
+									pos:   position{line: 203, col: 28, offset: 7837},
 									label: "Condition",
 									expr: &ruleRefExpr{
-										pos:  position{line: 175, col: 38, offset: 5929},
+										pos:  position{line: 203, col: 38, offset: 7847},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 175, col: 49, offset: 5940},
+									pos: position{line: 203, col: 49, offset: 7858},
 									expr: &charClassMatcher{
-										pos:        position{line: 175, col: 49, offset: 5940},
+										pos:        position{line: 203, col: 49, offset: 7858},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1414,13 +2326,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 175, col: 60, offset: 5951},
+									pos:  position{line: 203, col: 60, offset: 7869},
 									name: "KW_THEN",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 175, col: 68, offset: 5959},
+									pos: position{line: 203, col: 68, offset: 7877},
 									expr: &charClassMatcher{
-										pos:        position{line: 175, col: 68, offset: 5959},
+										pos:        position{line: 203, col: 68, offset: 7877},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1428,20 +2340,20 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 175, col: 79, offset: 5970},
+									pos:   position{line: 203, col: 79, offset: 7888},
 									label: "ThenStmts",
 									expr: &oneOrMoreExpr{
-										pos: position{line: 175, col: 89, offset: 5980},
+										pos: position{line: 203, col: 89, offset: 7898},
 										expr: &ruleRefExpr{
-											pos:  position{line: 175, col: 89, offset: 5980},
+											pos:  position{line: 203, col: 89, offset: 7898},
 											name: "Statement",
 										},
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 175, col: 100, offset: 5991},
+									pos: position{line: 203, col: 100, offset: 7909},
 									expr: &charClassMatcher{
-										pos:        position{line: 175, col: 100, offset: 5991},
+										pos:        position{line: 203, col: 100, offset: 7909},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1449,13 +2361,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 175, col: 111, offset: 6002},
+									pos:  position{line: 203, col: 111, offset: 7920},
 									name: "KW_ELSE",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 175, col: 119, offset: 6010},
+									pos: position{line: 203, col: 119, offset: 7928},
 									expr: &charClassMatcher{
-										pos:        position{line: 175, col: 119, offset: 6010},
+										pos:        position{line: 203, col: 119, offset: 7928},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1463,20 +2375,20 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 175, col: 130, offset: 6021},
+									pos:   position{line: 203, col: 130, offset: 7939},
 									label: "ElseStmts",
 									expr: &oneOrMoreExpr{
-										pos: position{line: 175, col: 140, offset: 6031},
+										pos: position{line: 203, col: 140, offset: 7949},
 										expr: &ruleRefExpr{
-											pos:  position{line: 175, col: 140, offset: 6031},
+											pos:  position{line: 203, col: 140, offset: 7949},
 											name: "Statement",
 										},
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 175, col: 151, offset: 6042},
+									pos: position{line: 203, col: 151, offset: 7960},
 									expr: &charClassMatcher{
-										pos:        position{line: 175, col: 151, offset: 6042},
+										pos:        position{line: 203, col: 151, offset: 7960},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1484,13 +2396,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 175, col: 162, offset: 6053},
+									pos:  position{line: 203, col: 162, offset: 7971},
 									name: "KW_END",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 175, col: 169, offset: 6060},
+									pos: position{line: 203, col: 169, offset: 7978},
 									expr: &charClassMatcher{
-										pos:        position{line: 175, col: 169, offset: 6060},
+										pos:        position{line: 203, col: 169, offset: 7978},
 										val:        "[ \\t\\r\\n]",
 										chars:      []rune{' ', '\t', '\r', '\n'},
 										ignoreCase: false,
@@ -1498,26 +2410,26 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 175, col: 180, offset: 6071},
+									pos:  position{line: 203, col: 180, offset: 7989},
 									name: "KW_IF",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 185, col: 11, offset: 6336},
+						pos: position{line: 213, col: 11, offset: 8254},
 						run: (*parser).callonIfStmt68,
 						expr: &seqExpr{
-							pos: position{line: 185, col: 11, offset: 6336},
+							pos: position{line: 213, col: 11, offset: 8254},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 185, col: 11, offset: 6336},
+									pos:  position{line: 213, col: 11, offset: 8254},
 									name: "KW_IF",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 185, col: 17, offset: 6342},
+									pos: position{line: 213, col: 17, offset: 8260},
 									expr: &charClassMatcher{
-										pos:        position{line: 185, col: 17, offset: 6342},
+										pos:        position{line: 213, col: 17, offset: 8260},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1525,17 +2437,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 185, col: 22, offset: 6347},
+									pos:   position{line: 213, col: 22, offset: 8265},
 									label: "Condition",
 									expr: &ruleRefExpr{
-										pos:  position{line: 185, col: 32, offset: 6357},
+										pos:  position{line: 213, col: 32, offset: 8275},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 185, col: 43, offset: 6368},
+									pos: position{line: 213, col: 43, offset: 8286},
 									expr: &charClassMatcher{
-										pos:        position{line: 185, col: 43, offset: 6368},
+										pos:        position{line: 213, col: 43, offset: 8286},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1543,13 +2455,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 185, col: 48, offset: 6373},
+									pos:  position{line: 213, col: 48, offset: 8291},
 									name: "KW_THEN",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 185, col: 56, offset: 6381},
+									pos: position{line: 213, col: 56, offset: 8299},
 									expr: &charClassMatcher{
-										pos:        position{line: 185, col: 56, offset: 6381},
+										pos:        position{line: 213, col: 56, offset: 8299},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1557,13 +2469,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 185, col: 61, offset: 6386},
+									pos:  position{line: 213, col: 61, offset: 8304},
 									name: "KW_PRINT",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 185, col: 70, offset: 6395},
+									pos: position{line: 213, col: 70, offset: 8313},
 									expr: &charClassMatcher{
-										pos:        position{line: 185, col: 70, offset: 6395},
+										pos:        position{line: 213, col: 70, offset: 8313},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1571,32 +2483,32 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 185, col: 75, offset: 6400},
+									pos:   position{line: 213, col: 75, offset: 8318},
 									label: "FirstThenArg",
 									expr: &ruleRefExpr{
-										pos:  position{line: 185, col: 88, offset: 6413},
+										pos:  position{line: 213, col: 88, offset: 8331},
 										name: "PrintArg",
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 185, col: 97, offset: 6422},
+									pos:   position{line: 213, col: 97, offset: 8340},
 									label: "ThenRest",
 									expr: &zeroOrMoreExpr{
-										pos: position{line: 185, col: 107, offset: 6432},
+										pos: position{line: 213, col: 107, offset: 8350},
 										expr: &seqExpr{
-											pos: position{line: 185, col: 108, offset: 6433},
+											pos: position{line: 213, col: 108, offset: 8351},
 											exprs: []any{
 												&choiceExpr{
-													pos: position{line: 185, col: 109, offset: 6434},
+													pos: position{line: 213, col: 109, offset: 8352},
 													alternatives: []any{
 														&litMatcher{
-															pos:        position{line: 185, col: 109, offset: 6434},
+															pos:        position{line: 213, col: 109, offset: 8352},
 															val:        ";",
 															ignoreCase: false,
 															want:       "\";\"",
 														},
 														&litMatcher{
-															pos:        position{line: 185, col: 115, offset: 6440},
+															pos:        position{line: 213, col: 115, offset: 8358},
 															val:        ",",
 															ignoreCase: false,
 															want:       "\",\"",
@@ -1604,9 +2516,9 @@ var g = &grammar{
 													},
 												},
 												&zeroOrMoreExpr{
-													pos: position{line: 185, col: 120, offset: 6445},
+													pos: position{line: 213, col: 120, offset: 8363},
 													expr: &charClassMatcher{
-														pos:        position{line: 185, col: 120, offset: 6445},
+														pos:        position{line: 213, col: 120, offset: 8363},
 														val:        "[ ]",
 														chars:      []rune{' '},
 														ignoreCase: false,
@@ -1614,7 +2526,7 @@ var g = &grammar{
 													},
 												},
 												&ruleRefExpr{
-													pos:  position{line: 185, col: 125, offset: 6450},
+													pos:  position{line: 213, col: 125, offset: 8368},
 													name: "PrintArg",
 												},
 											},
@@ -1622,9 +2534,9 @@ var g = &grammar{
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 185, col: 137, offset: 6462},
+									pos: position{line: 213, col: 137, offset: 8380},
 									expr: &charClassMatcher{
-										pos:        position{line: 185, col: 137, offset: 6462},
+										pos:        position{line: 213, col: 137, offset: 8380},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1632,13 +2544,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 185, col: 142, offset: 6467},
+									pos:  position{line: 213, col: 142, offset: 8385},
 									name: "KW_ELSE",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 185, col: 150, offset: 6475},
+									pos: position{line: 213, col: 150, offset: 8393},
 									expr: &charClassMatcher{
-										pos:        position{line: 185, col: 150, offset: 6475},
+										pos:        position{line: 213, col: 150, offset: 8393},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1646,13 +2558,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 185, col: 155, offset: 6480},
+									pos:  position{line: 213, col: 155, offset: 8398},
 									name: "KW_PRINT",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 185, col: 164, offset: 6489},
+									pos: position{line: 213, col: 164, offset: 8407},
 									expr: &charClassMatcher{
-										pos:        position{line: 185, col: 164, offset: 6489},
+										pos:        position{line: 213, col: 164, offset: 8407},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1660,32 +2572,32 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 185, col: 169, offset: 6494},
+									pos:   position{line: 213, col: 169, offset: 8412},
 									label: "FirstElseArg",
 									expr: &ruleRefExpr{
-										pos:  position{line: 185, col: 182, offset: 6507},
+										pos:  position{line: 213, col: 182, offset: 8425},
 										name: "PrintArg",
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 185, col: 191, offset: 6516},
+									pos:   position{line: 213, col: 191, offset: 8434},
 									label: "ElseRest",
 									expr: &zeroOrMoreExpr{
-										pos: position{line: 185, col: 201, offset: 6526},
+										pos: position{line: 213, col: 201, offset: 8444},
 										expr: &seqExpr{
-											pos: position{line: 185, col: 202, offset: 6527},
+											pos: position{line: 213, col: 202, offset: 8445},
 											exprs: []any{
 												&choiceExpr{
-													pos: position{line: 185, col: 203, offset: 6528},
+													pos: position{line: 213, col: 203, offset: 8446},
 													alternatives: []any{
 														&litMatcher{
-															pos:        position{line: 185, col: 203, offset: 6528},
+															pos:        position{line: 213, col: 203, offset: 8446},
 															val:        ";",
 															ignoreCase: false,
 															want:       "\";\"",
 														},
 														&litMatcher{
-															pos:        position{line: 185, col: 209, offset: 6534},
+															pos:        position{line: 213, col: 209, offset: 8452},
 															val:        ",",
 															ignoreCase: false,
 															want:       "\",\"",
@@ -1693,9 +2605,9 @@ var g = &grammar{
 													},
 												},
 												&zeroOrMoreExpr{
-													pos: position{line: 185, col: 214, offset: 6539},
+													pos: position{line: 213, col: 214, offset: 8457},
 													expr: &charClassMatcher{
-														pos:        position{line: 185, col: 214, offset: 6539},
+														pos:        position{line: 213, col: 214, offset: 8457},
 														val:        "[ ]",
 														chars:      []rune{' '},
 														ignoreCase: false,
@@ -1703,7 +2615,7 @@ var g = &grammar{
 													},
 												},
 												&ruleRefExpr{
-													pos:  position{line: 185, col: 219, offset: 6544},
+													pos:  position{line: 213, col: 219, offset: 8462},
 													name: "PrintArg",
 												},
 											},
@@ -1714,19 +2626,19 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 213, col: 11, offset: 7481},
+						pos: position{line: 241, col: 11, offset: 9399},
 						run: (*parser).callonIfStmt113,
 						expr: &seqExpr{
-							pos: position{line: 213, col: 11, offset: 7481},
+							pos: position{line: 241, col: 11, offset: 9399},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 213, col: 11, offset: 7481},
+									pos:  position{line: 241, col: 11, offset: 9399},
 									name: "KW_IF",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 213, col: 17, offset: 7487},
+									pos: position{line: 241, col: 17, offset: 9405},
 									expr: &charClassMatcher{
-										pos:        position{line: 213, col: 17, offset: 7487},
+										pos:        position{line: 241, col: 17, offset: 9405},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1734,17 +2646,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 213, col: 22, offset: 7492},
+									pos:   position{line: 241, col: 22, offset: 9410},
 									label: "Condition",
 									expr: &ruleRefExpr{
-										pos:  position{line: 213, col: 32, offset: 7502},
+										pos:  position{line: 241, col: 32, offset: 9420},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 213, col: 43, offset: 7513},
+									pos: position{line: 241, col: 43, offset: 9431},
 									expr: &charClassMatcher{
-										pos:        position{line: 213, col: 43, offset: 7513},
+										pos:        position{line: 241, col: 43, offset: 9431},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1752,13 +2664,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 213, col: 48, offset: 7518},
+									pos:  position{line: 241, col: 48, offset: 9436},
 									name: "KW_THEN",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 213, col: 56, offset: 7526},
+									pos: position{line: 241, col: 56, offset: 9444},
 									expr: &charClassMatcher{
-										pos:        position{line: 213, col: 56, offset: 7526},
+										pos:        position{line: 241, col: 56, offset: 9444},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1766,13 +2678,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 213, col: 61, offset: 7531},
+									pos:  position{line: 241, col: 61, offset: 9449},
 									name: "KW_PRINT",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 213, col: 70, offset: 7540},
+									pos: position{line: 241, col: 70, offset: 9458},
 									expr: &charClassMatcher{
-										pos:        position{line: 213, col: 70, offset: 7540},
+										pos:        position{line: 241, col: 70, offset: 9458},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1780,10 +2692,10 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 213, col: 75, offset: 7545},
+									pos:   position{line: 241, col: 75, offset: 9463},
 									label: "PrintArgs",
 									expr: &ruleRefExpr{
-										pos:  position{line: 213, col: 85, offset: 7555},
+										pos:  position{line: 241, col: 85, offset: 9473},
 										name: "PrintArgList",
 									},
 								},
@@ -1791,19 +2703,19 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 227, col: 11, offset: 7958},
+						pos: position{line: 255, col: 11, offset: 9876},
 						run: (*parser).callonIfStmt130,
 						expr: &seqExpr{
-							pos: position{line: 227, col: 11, offset: 7958},
+							pos: position{line: 255, col: 11, offset: 9876},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 227, col: 11, offset: 7958},
+									pos:  position{line: 255, col: 11, offset: 9876},
 									name: "KW_IF",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 227, col: 17, offset: 7964},
+									pos: position{line: 255, col: 17, offset: 9882},
 									expr: &charClassMatcher{
-										pos:        position{line: 227, col: 17, offset: 7964},
+										pos:        position{line: 255, col: 17, offset: 9882},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1811,17 +2723,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 227, col: 22, offset: 7969},
+									pos:   position{line: 255, col: 22, offset: 9887},
 									label: "Condition",
 									expr: &ruleRefExpr{
-										pos:  position{line: 227, col: 32, offset: 7979},
+										pos:  position{line: 255, col: 32, offset: 9897},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 227, col: 43, offset: 7990},
+									pos: position{line: 255, col: 43, offset: 9908},
 									expr: &charClassMatcher{
-										pos:        position{line: 227, col: 43, offset: 7990},
+										pos:        position{line: 255, col: 43, offset: 9908},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1829,13 +2741,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 227, col: 48, offset: 7995},
+									pos:  position{line: 255, col: 48, offset: 9913},
 									name: "KW_THEN",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 227, col: 56, offset: 8003},
+									pos: position{line: 255, col: 56, offset: 9921},
 									expr: &charClassMatcher{
-										pos:        position{line: 227, col: 56, offset: 8003},
+										pos:        position{line: 255, col: 56, offset: 9921},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1843,17 +2755,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 227, col: 61, offset: 8008},
+									pos:   position{line: 255, col: 61, offset: 9926},
 									label: "ThenStmt",
 									expr: &ruleRefExpr{
-										pos:  position{line: 227, col: 70, offset: 8017},
+										pos:  position{line: 255, col: 70, offset: 9935},
 										name: "NonIfNonPrintStatement",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 227, col: 93, offset: 8040},
+									pos: position{line: 255, col: 93, offset: 9958},
 									expr: &charClassMatcher{
-										pos:        position{line: 227, col: 93, offset: 8040},
+										pos:        position{line: 255, col: 93, offset: 9958},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1861,13 +2773,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 227, col: 98, offset: 8045},
+									pos:  position{line: 255, col: 98, offset: 9963},
 									name: "KW_ELSE",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 227, col: 106, offset: 8053},
+									pos: position{line: 255, col: 106, offset: 9971},
 									expr: &charClassMatcher{
-										pos:        position{line: 227, col: 106, offset: 8053},
+										pos:        position{line: 255, col: 106, offset: 9971},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1875,10 +2787,10 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 227, col: 111, offset: 8058},
+									pos:   position{line: 255, col: 111, offset: 9976},
 									label: "ElseStmt",
 									expr: &ruleRefExpr{
-										pos:  position{line: 227, col: 120, offset: 8067},
+										pos:  position{line: 255, col: 120, offset: 9985},
 										name: "NonIfNonPrintStatement",
 									},
 								},
@@ -1886,19 +2798,19 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 235, col: 11, offset: 8294},
+						pos: position{line: 263, col: 11, offset: 10212},
 						run: (*parser).callonIfStmt151,
 						expr: &seqExpr{
-							pos: position{line: 235, col: 11, offset: 8294},
+							pos: position{line: 263, col: 11, offset: 10212},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 235, col: 11, offset: 8294},
+									pos:  position{line: 263, col: 11, offset: 10212},
 									name: "KW_IF",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 235, col: 17, offset: 8300},
+									pos: position{line: 263, col: 17, offset: 10218},
 									expr: &charClassMatcher{
-										pos:        position{line: 235, col: 17, offset: 8300},
+										pos:        position{line: 263, col: 17, offset: 10218},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1906,17 +2818,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 235, col: 22, offset: 8305},
+									pos:   position{line: 263, col: 22, offset: 10223},
 									label: "Condition",
 									expr: &ruleRefExpr{
-										pos:  position{line: 235, col: 32, offset: 8315},
+										pos:  position{line: 263, col: 32, offset: 10233},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 235, col: 43, offset: 8326},
+									pos: position{line: 263, col: 43, offset: 10244},
 									expr: &charClassMatcher{
-										pos:        position{line: 235, col: 43, offset: 8326},
+										pos:        position{line: 263, col: 43, offset: 10244},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1924,13 +2836,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 235, col: 48, offset: 8331},
+									pos:  position{line: 263, col: 48, offset: 10249},
 									name: "KW_THEN",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 235, col: 56, offset: 8339},
+									pos: position{line: 263, col: 56, offset: 10257},
 									expr: &charClassMatcher{
-										pos:        position{line: 235, col: 56, offset: 8339},
+										pos:        position{line: 263, col: 56, offset: 10257},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -1938,10 +2850,10 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 235, col: 61, offset: 8344},
+									pos:   position{line: 263, col: 61, offset: 10262},
 									label: "ThenStmt",
 									expr: &ruleRefExpr{
-										pos:  position{line: 235, col: 70, offset: 8353},
+										pos:  position{line: 263, col: 70, offset: 10271},
 										name: "NonIfNonPrintStatement",
 									},
 								},
@@ -1953,21 +2865,21 @@ var g = &grammar{
 		},
 		{
 			name: "IfBlockStmt",
-			pos:  position{line: 244, col: 1, offset: 8545},
+			pos:  position{line: 272, col: 1, offset: 10463},
 			expr: &actionExpr{
-				pos: position{line: 244, col: 16, offset: 8560},
+				pos: position{line: 272, col: 16, offset: 10478},
 				run: (*parser).callonIfBlockStmt1,
 				expr: &seqExpr{
-					pos: position{line: 244, col: 16, offset: 8560},
+					pos: position{line: 272, col: 16, offset: 10478},
 					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 244, col: 16, offset: 8560},
+							pos:  position{line: 272, col: 16, offset: 10478},
 							name: "KW_IF",
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 244, col: 22, offset: 8566},
+							pos: position{line: 272, col: 22, offset: 10484},
 							expr: &charClassMatcher{
-								pos:        position{line: 244, col: 22, offset: 8566},
+								pos:        position{line: 272, col: 22, offset: 10484},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -1975,17 +2887,17 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 244, col: 27, offset: 8571},
+							pos:   position{line: 272, col: 27, offset: 10489},
 							label: "Condition",
 							expr: &ruleRefExpr{
-								pos:  position{line: 244, col: 37, offset: 8581},
+								pos:  position{line: 272, col: 37, offset: 10499},
 								name: "Expression",
 							},
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 244, col: 48, offset: 8592},
+							pos: position{line: 272, col: 48, offset: 10510},
 							expr: &charClassMatcher{
-								pos:        position{line: 244, col: 48, offset: 8592},
+								pos:        position{line: 272, col: 48, offset: 10510},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -1993,7 +2905,7 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 244, col: 53, offset: 8597},
+							pos:  position{line: 272, col: 53, offset: 10515},
 							name: "KW_THEN",
 						},
 					},
@@ -2002,33 +2914,33 @@ var g = &grammar{
 		},
 		{
 			name: "ElseBlockStmt",
-			pos:  position{line: 248, col: 1, offset: 8674},
+			pos:  position{line: 276, col: 1, offset: 10592},
 			expr: &actionExpr{
-				pos: position{line: 248, col: 18, offset: 8691},
+				pos: position{line: 276, col: 18, offset: 10609},
 				run: (*parser).callonElseBlockStmt1,
 				expr: &ruleRefExpr{
-					pos:  position{line: 248, col: 18, offset: 8691},
+					pos:  position{line: 276, col: 18, offset: 10609},
 					name: "KW_ELSE",
 				},
 			},
 		},
 		{
 			name: "EndIfStmt",
-			pos:  position{line: 252, col: 1, offset: 8739},
+			pos:  position{line: 280, col: 1, offset: 10657},
 			expr: &actionExpr{
-				pos: position{line: 252, col: 14, offset: 8752},
+				pos: position{line: 280, col: 14, offset: 10670},
 				run: (*parser).callonEndIfStmt1,
 				expr: &seqExpr{
-					pos: position{line: 252, col: 14, offset: 8752},
+					pos: position{line: 280, col: 14, offset: 10670},
 					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 252, col: 14, offset: 8752},
+							pos:  position{line: 280, col: 14, offset: 10670},
 							name: "KW_END",
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 252, col: 21, offset: 8759},
+							pos: position{line: 280, col: 21, offset: 10677},
 							expr: &charClassMatcher{
-								pos:        position{line: 252, col: 21, offset: 8759},
+								pos:        position{line: 280, col: 21, offset: 10677},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -2036,7 +2948,7 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 252, col: 26, offset: 8764},
+							pos:  position{line: 280, col: 26, offset: 10682},
 							name: "KW_IF",
 						},
 					},
@@ -2045,24 +2957,24 @@ var g = &grammar{
 		},
 		{
 			name: "ForStmt",
-			pos:  position{line: 260, col: 1, offset: 8962},
+			pos:  position{line: 288, col: 1, offset: 10880},
 			expr: &choiceExpr{
-				pos: position{line: 260, col: 12, offset: 8973},
+				pos: position{line: 288, col: 12, offset: 10891},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 260, col: 12, offset: 8973},
+						pos: position{line: 288, col: 12, offset: 10891},
 						run: (*parser).callonForStmt2,
 						expr: &seqExpr{
-							pos: position{line: 260, col: 12, offset: 8973},
+							pos: position{line: 288, col: 12, offset: 10891},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 260, col: 12, offset: 8973},
+									pos:  position{line: 288, col: 12, offset: 10891},
 									name: "KW_FOR",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 260, col: 19, offset: 8980},
+									pos: position{line: 288, col: 19, offset: 10898},
 									expr: &charClassMatcher{
-										pos:        position{line: 260, col: 19, offset: 8980},
+										pos:        position{line: 288, col: 19, offset: 10898},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2070,17 +2982,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 260, col: 24, offset: 8985},
+									pos:   position{line: 288, col: 24, offset: 10903},
 									label: "Var",
 									expr: &ruleRefExpr{
-										pos:  position{line: 260, col: 28, offset: 8989},
+										pos:  position{line: 288, col: 28, offset: 10907},
 										name: "Identifier",
 									},
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 260, col: 39, offset: 9000},
+									pos: position{line: 288, col: 39, offset: 10918},
 									expr: &charClassMatcher{
-										pos:        position{line: 260, col: 39, offset: 9000},
+										pos:        position{line: 288, col: 39, offset: 10918},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2088,15 +3000,15 @@ var g = &grammar{
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 260, col: 44, offset: 9005},
+									pos:        position{line: 288, col: 44, offset: 10923},
 									val:        "=",
 									ignoreCase: false,
 									want:       "\"=\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 260, col: 48, offset: 9009},
+									pos: position{line: 288, col: 48, offset: 10927},
 									expr: &charClassMatcher{
-										pos:        position{line: 260, col: 48, offset: 9009},
+										pos:        position{line: 288, col: 48, offset: 10927},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2104,17 +3016,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 260, col: 53, offset: 9014},
+									pos:   position{line: 288, col: 53, offset: 10932},
 									label: "Start",
 									expr: &ruleRefExpr{
-										pos:  position{line: 260, col: 59, offset: 9020},
+										pos:  position{line: 288, col: 59, offset: 10938},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 260, col: 70, offset: 9031},
+									pos: position{line: 288, col: 70, offset: 10949},
 									expr: &charClassMatcher{
-										pos:        position{line: 260, col: 70, offset: 9031},
+										pos:        position{line: 288, col: 70, offset: 10949},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2122,13 +3034,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 260, col: 75, offset: 9036},
+									pos:  position{line: 288, col: 75, offset: 10954},
 									name: "KW_TO",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 260, col: 81, offset: 9042},
+									pos: position{line: 288, col: 81, offset: 10960},
 									expr: &charClassMatcher{
-										pos:        position{line: 260, col: 81, offset: 9042},
+										pos:        position{line: 288, col: 81, offset: 10960},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2136,17 +3048,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 260, col: 86, offset: 9047},
+									pos:   position{line: 288, col: 86, offset: 10965},
 									label: "End",
 									expr: &ruleRefExpr{
-										pos:  position{line: 260, col: 90, offset: 9051},
+										pos:  position{line: 288, col: 90, offset: 10969},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 260, col: 101, offset: 9062},
+									pos: position{line: 288, col: 101, offset: 10980},
 									expr: &charClassMatcher{
-										pos:        position{line: 260, col: 101, offset: 9062},
+										pos:        position{line: 288, col: 101, offset: 10980},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2154,13 +3066,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 260, col: 106, offset: 9067},
+									pos:  position{line: 288, col: 106, offset: 10985},
 									name: "KW_STEP",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 260, col: 114, offset: 9075},
+									pos: position{line: 288, col: 114, offset: 10993},
 									expr: &charClassMatcher{
-										pos:        position{line: 260, col: 114, offset: 9075},
+										pos:        position{line: 288, col: 114, offset: 10993},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2168,10 +3080,10 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 260, col: 119, offset: 9080},
+									pos:   position{line: 288, col: 119, offset: 10998},
 									label: "StepExpr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 260, col: 128, offset: 9089},
+										pos:  position{line: 288, col: 128, offset: 11007},
 										name: "Expression",
 									},
 								},
@@ -2179,19 +3091,19 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 268, col: 11, offset: 9249},
+						pos: position{line: 296, col: 11, offset: 11167},
 						run: (*parser).callonForStmt30,
 						expr: &seqExpr{
-							pos: position{line: 268, col: 11, offset: 9249},
+							pos: position{line: 296, col: 11, offset: 11167},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 268, col: 11, offset: 9249},
+									pos:  position{line: 296, col: 11, offset: 11167},
 									name: "KW_FOR",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 268, col: 18, offset: 9256},
+									pos: position{line: 296, col: 18, offset: 11174},
 									expr: &charClassMatcher{
-										pos:        position{line: 268, col: 18, offset: 9256},
+										pos:        position{line: 296, col: 18, offset: 11174},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2199,17 +3111,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 268, col: 23, offset: 9261},
+									pos:   position{line: 296, col: 23, offset: 11179},
 									label: "Var",
 									expr: &ruleRefExpr{
-										pos:  position{line: 268, col: 27, offset: 9265},
+										pos:  position{line: 296, col: 27, offset: 11183},
 										name: "Identifier",
 									},
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 268, col: 38, offset: 9276},
+									pos: position{line: 296, col: 38, offset: 11194},
 									expr: &charClassMatcher{
-										pos:        position{line: 268, col: 38, offset: 9276},
+										pos:        position{line: 296, col: 38, offset: 11194},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2217,15 +3129,15 @@ var g = &grammar{
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 268, col: 43, offset: 9281},
+									pos:        position{line: 296, col: 43, offset: 11199},
 									val:        "=",
 									ignoreCase: false,
 									want:       "\"=\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 268, col: 47, offset: 9285},
+									pos: position{line: 296, col: 47, offset: 11203},
 									expr: &charClassMatcher{
-										pos:        position{line: 268, col: 47, offset: 9285},
+										pos:        position{line: 296, col: 47, offset: 11203},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2233,17 +3145,17 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 268, col: 52, offset: 9290},
+									pos:   position{line: 296, col: 52, offset: 11208},
 									label: "Start",
 									expr: &ruleRefExpr{
-										pos:  position{line: 268, col: 58, offset: 9296},
+										pos:  position{line: 296, col: 58, offset: 11214},
 										name: "Expression",
 									},
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 268, col: 69, offset: 9307},
+									pos: position{line: 296, col: 69, offset: 11225},
 									expr: &charClassMatcher{
-										pos:        position{line: 268, col: 69, offset: 9307},
+										pos:        position{line: 296, col: 69, offset: 11225},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2251,13 +3163,13 @@ var g = &grammar{
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 268, col: 74, offset: 9312},
+									pos:  position{line: 296, col: 74, offset: 11230},
 									name: "KW_TO",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 268, col: 80, offset: 9318},
+									pos: position{line: 296, col: 80, offset: 11236},
 									expr: &charClassMatcher{
-										pos:        position{line: 268, col: 80, offset: 9318},
+										pos:        position{line: 296, col: 80, offset: 11236},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2265,10 +3177,10 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 268, col: 85, offset: 9323},
+									pos:   position{line: 296, col: 85, offset: 11241},
 									label: "End",
 									expr: &ruleRefExpr{
-										pos:  position{line: 268, col: 89, offset: 9327},
+										pos:  position{line: 296, col: 89, offset: 11245},
 										name: "Expression",
 									},
 								},
@@ -2279,60 +3191,36 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "NextStmt",
-			pos:  position{line: 277, col: 1, offset: 9480},
+			name: "ForEachStmt",
+			pos:  position{line: 306, col: 1, offset: 11478},
 			expr: &actionExpr{
-				pos: position{line: 277, col: 13, offset: 9492},
-				run: (*parser).callonNextStmt1,
+				pos: position{line: 306, col: 16, offset: 11493},
+				run: (*parser).callonForEachStmt1,
 				expr: &seqExpr{
-					pos: position{line: 277, col: 13, offset: 9492},
+					pos: position{line: 306, col: 16, offset: 11493},
 					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 277, col: 13, offset: 9492},
-							name: "KW_NEXT",
+							pos:  position{line: 306, col: 16, offset: 11493},
+							name: "KW_FOR",
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 277, col: 21, offset: 9500},
+							pos: position{line: 306, col: 23, offset: 11500},
 							expr: &charClassMatcher{
-								pos:        position{line: 277, col: 21, offset: 9500},
+								pos:        position{line: 306, col: 23, offset: 11500},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
 								inverted:   false,
 							},
 						},
-						&labeledExpr{
-							pos:   position{line: 277, col: 26, offset: 9505},
-							label: "Var",
-							expr: &zeroOrOneExpr{
-								pos: position{line: 277, col: 30, offset: 9509},
-								expr: &ruleRefExpr{
-									pos:  position{line: 277, col: 30, offset: 9509},
-									name: "Identifier",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-		{
-			name: "GotoStmt",
-			pos:  position{line: 289, col: 1, offset: 9794},
-			expr: &actionExpr{
-				pos: position{line: 289, col: 13, offset: 9806},
-				run: (*parser).callonGotoStmt1,
-				expr: &seqExpr{
-					pos: position{line: 289, col: 13, offset: 9806},
-					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 289, col: 13, offset: 9806},
-							name: "KW_GOTO",
+							pos:  position{line: 306, col: 28, offset: 11505},
+							name: "KW_EACH",
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 289, col: 21, offset: 9814},
+							pos: position{line: 306, col: 36, offset: 11513},
 							expr: &charClassMatcher{
-								pos:        position{line: 289, col: 21, offset: 9814},
+								pos:        position{line: 306, col: 36, offset: 11513},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -2340,34 +3228,31 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 289, col: 26, offset: 9819},
-							label: "Num",
+							pos:   position{line: 306, col: 41, offset: 11518},
+							label: "Var",
 							expr: &ruleRefExpr{
-								pos:  position{line: 289, col: 30, offset: 9823},
-								name: "LineNumber",
+								pos:  position{line: 306, col: 45, offset: 11522},
+								name: "Identifier",
+							},
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 306, col: 56, offset: 11533},
+							expr: &charClassMatcher{
+								pos:        position{line: 306, col: 56, offset: 11533},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
 							},
 						},
-					},
-				},
-			},
-		},
-		{
-			name: "GosubStmt",
-			pos:  position{line: 293, col: 1, offset: 9889},
-			expr: &actionExpr{
-				pos: position{line: 293, col: 14, offset: 9902},
-				run: (*parser).callonGosubStmt1,
-				expr: &seqExpr{
-					pos: position{line: 293, col: 14, offset: 9902},
-					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 293, col: 14, offset: 9902},
-							name: "KW_GOSUB",
+							pos:  position{line: 306, col: 61, offset: 11538},
+							name: "KW_IN",
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 293, col: 23, offset: 9911},
+							pos: position{line: 306, col: 67, offset: 11544},
 							expr: &charClassMatcher{
-								pos:        position{line: 293, col: 23, offset: 9911},
+								pos:        position{line: 306, col: 67, offset: 11544},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -2375,11 +3260,11 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 293, col: 28, offset: 9916},
-							label: "Num",
+							pos:   position{line: 306, col: 72, offset: 11549},
+							label: "Map",
 							expr: &ruleRefExpr{
-								pos:  position{line: 293, col: 32, offset: 9920},
-								name: "LineNumber",
+								pos:  position{line: 306, col: 76, offset: 11553},
+								name: "Identifier",
 							},
 						},
 					},
@@ -2387,122 +3272,22 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "ReturnStmt",
-			pos:  position{line: 297, col: 1, offset: 9987},
-			expr: &actionExpr{
-				pos: position{line: 297, col: 15, offset: 10001},
-				run: (*parser).callonReturnStmt1,
-				expr: &ruleRefExpr{
-					pos:  position{line: 297, col: 15, offset: 10001},
-					name: "KW_RETURN",
-				},
-			},
-		},
-		{
-			name: "EndStmt",
-			pos:  position{line: 305, col: 1, offset: 10216},
-			expr: &actionExpr{
-				pos: position{line: 305, col: 12, offset: 10227},
-				run: (*parser).callonEndStmt1,
-				expr: &ruleRefExpr{
-					pos:  position{line: 305, col: 12, offset: 10227},
-					name: "KW_END",
-				},
-			},
-		},
-		{
-			name: "RemStmt",
-			pos:  position{line: 309, col: 1, offset: 10267},
+			name: "NextStmt",
+			pos:  position{line: 310, col: 1, offset: 11637},
 			expr: &actionExpr{
-				pos: position{line: 309, col: 12, offset: 10278},
-				run: (*parser).callonRemStmt1,
+				pos: position{line: 310, col: 13, offset: 11649},
+				run: (*parser).callonNextStmt1,
 				expr: &seqExpr{
-					pos: position{line: 309, col: 12, offset: 10278},
+					pos: position{line: 310, col: 13, offset: 11649},
 					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 309, col: 12, offset: 10278},
-							name: "KW_REM",
-						},
-						&zeroOrMoreExpr{
-							pos: position{line: 309, col: 19, offset: 10285},
-							expr: &seqExpr{
-								pos: position{line: 309, col: 20, offset: 10286},
-								exprs: []any{
-									&notExpr{
-										pos: position{line: 309, col: 20, offset: 10286},
-										expr: &litMatcher{
-											pos:        position{line: 309, col: 21, offset: 10287},
-											val:        "\n",
-											ignoreCase: false,
-											want:       "\"\\n\"",
-										},
-									},
-									&anyMatcher{
-										line: 309, col: 26, offset: 10292,
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-		{
-			name: "SingleQuoteCommentStmt",
-			pos:  position{line: 313, col: 1, offset: 10349},
-			expr: &actionExpr{
-				pos: position{line: 313, col: 27, offset: 10375},
-				run: (*parser).callonSingleQuoteCommentStmt1,
-				expr: &seqExpr{
-					pos: position{line: 313, col: 27, offset: 10375},
-					exprs: []any{
-						&litMatcher{
-							pos:        position{line: 313, col: 27, offset: 10375},
-							val:        "'",
-							ignoreCase: false,
-							want:       "\"'\"",
+							pos:  position{line: 310, col: 13, offset: 11649},
+							name: "KW_NEXT",
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 313, col: 31, offset: 10379},
-							expr: &seqExpr{
-								pos: position{line: 313, col: 32, offset: 10380},
-								exprs: []any{
-									&notExpr{
-										pos: position{line: 313, col: 32, offset: 10380},
-										expr: &litMatcher{
-											pos:        position{line: 313, col: 33, offset: 10381},
-											val:        "\n",
-											ignoreCase: false,
-											want:       "\"\\n\"",
-										},
-									},
-									&anyMatcher{
-										line: 313, col: 38, offset: 10386,
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-		{
-			name: "DimStmt",
-			pos:  position{line: 317, col: 1, offset: 10443},
-			expr: &actionExpr{
-				pos: position{line: 317, col: 12, offset: 10454},
-				run: (*parser).callonDimStmt1,
-				expr: &seqExpr{
-					pos: position{line: 317, col: 12, offset: 10454},
-					exprs: []any{
-						&ruleRefExpr{
-							pos:  position{line: 317, col: 12, offset: 10454},
-							name: "KW_DIM",
-						},
-						&oneOrMoreExpr{
-							pos: position{line: 317, col: 19, offset: 10461},
+							pos: position{line: 310, col: 21, offset: 11657},
 							expr: &charClassMatcher{
-								pos:        position{line: 317, col: 19, offset: 10461},
+								pos:        position{line: 310, col: 21, offset: 11657},
 								val:        "[ ]",
 								chars:      []rune{' '},
 								ignoreCase: false,
@@ -2510,91 +3295,40 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 317, col: 24, offset: 10466},
-							label: "Name",
-							expr: &ruleRefExpr{
-								pos:  position{line: 317, col: 29, offset: 10471},
-								name: "Identifier",
-							},
-						},
-						&litMatcher{
-							pos:        position{line: 317, col: 40, offset: 10482},
-							val:        "(",
-							ignoreCase: false,
-							want:       "\"(\"",
-						},
-						&labeledExpr{
-							pos:   position{line: 317, col: 44, offset: 10486},
-							label: "Sizes",
-							expr: &ruleRefExpr{
-								pos:  position{line: 317, col: 50, offset: 10492},
-								name: "ExpressionList",
+							pos:   position{line: 310, col: 26, offset: 11662},
+							label: "Var",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 310, col: 30, offset: 11666},
+								expr: &ruleRefExpr{
+									pos:  position{line: 310, col: 30, offset: 11666},
+									name: "Identifier",
+								},
 							},
 						},
-						&litMatcher{
-							pos:        position{line: 317, col: 65, offset: 10507},
-							val:        ")",
-							ignoreCase: false,
-							want:       "\")\"",
-						},
 					},
 				},
 			},
 		},
 		{
-			name: "InputStmt",
-			pos:  position{line: 321, col: 1, offset: 10590},
+			name: "GotoStmt",
+			pos:  position{line: 323, col: 1, offset: 12029},
 			expr: &choiceExpr{
-				pos: position{line: 321, col: 14, offset: 10603},
+				pos: position{line: 323, col: 13, offset: 12041},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 321, col: 14, offset: 10603},
-						run: (*parser).callonInputStmt2,
+						pos: position{line: 323, col: 13, offset: 12041},
+						run: (*parser).callonGotoStmt2,
 						expr: &seqExpr{
-							pos: position{line: 321, col: 14, offset: 10603},
+							pos: position{line: 323, col: 13, offset: 12041},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 321, col: 14, offset: 10603},
-									name: "KW_INPUT",
+									pos:  position{line: 323, col: 13, offset: 12041},
+									name: "KW_GOTO",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 321, col: 23, offset: 10612},
-									expr: &charClassMatcher{
-										pos:        position{line: 321, col: 23, offset: 10612},
-										val:        "[ ]",
-										chars:      []rune{' '},
-										ignoreCase: false,
-										inverted:   false,
-									},
-								},
-								&labeledExpr{
-									pos:   position{line: 321, col: 28, offset: 10617},
-									label: "Prompt",
-									expr: &ruleRefExpr{
-										pos:  position{line: 321, col: 35, offset: 10624},
-										name: "StringLiteral",
-									},
-								},
-								&zeroOrMoreExpr{
-									pos: position{line: 321, col: 49, offset: 10638},
-									expr: &charClassMatcher{
-										pos:        position{line: 321, col: 49, offset: 10638},
-										val:        "[ ]",
-										chars:      []rune{' '},
-										ignoreCase: false,
-										inverted:   false,
-									},
-								},
-								&litMatcher{
-									pos:        position{line: 321, col: 54, offset: 10643},
-									val:        ",",
-									ignoreCase: false,
-									want:       "\",\"",
-								},
-								&zeroOrMoreExpr{
-									pos: position{line: 321, col: 58, offset: 10647},
+									pos: position{line: 323, col: 21, offset: 12049},
 									expr: &charClassMatcher{
-										pos:        position{line: 321, col: 58, offset: 10647},
+										pos:        position{line: 323, col: 21, offset: 12049},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2602,30 +3336,30 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 321, col: 63, offset: 10652},
-									label: "Vars",
+									pos:   position{line: 323, col: 26, offset: 12054},
+									label: "Num",
 									expr: &ruleRefExpr{
-										pos:  position{line: 321, col: 68, offset: 10657},
-										name: "IdentifierList",
+										pos:  position{line: 323, col: 30, offset: 12058},
+										name: "LineNumber",
 									},
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 324, col: 15, offset: 10784},
-						run: (*parser).callonInputStmt16,
+						pos: position{line: 326, col: 13, offset: 12135},
+						run: (*parser).callonGotoStmt9,
 						expr: &seqExpr{
-							pos: position{line: 324, col: 15, offset: 10784},
+							pos: position{line: 326, col: 13, offset: 12135},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 324, col: 15, offset: 10784},
-									name: "KW_INPUT",
+									pos:  position{line: 326, col: 13, offset: 12135},
+									name: "KW_GOTO",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 324, col: 24, offset: 10793},
+									pos: position{line: 326, col: 21, offset: 12143},
 									expr: &charClassMatcher{
-										pos:        position{line: 324, col: 24, offset: 10793},
+										pos:        position{line: 326, col: 21, offset: 12143},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2633,17 +3367,39 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 324, col: 29, offset: 10798},
-									label: "Prompt",
+									pos:   position{line: 326, col: 26, offset: 12148},
+									label: "Label",
 									expr: &ruleRefExpr{
-										pos:  position{line: 324, col: 36, offset: 10805},
-										name: "StringLiteral",
+										pos:  position{line: 326, col: 32, offset: 12154},
+										name: "LabelName",
 									},
 								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "GosubStmt",
+			pos:  position{line: 330, col: 1, offset: 12219},
+			expr: &choiceExpr{
+				pos: position{line: 330, col: 14, offset: 12232},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 330, col: 14, offset: 12232},
+						run: (*parser).callonGosubStmt2,
+						expr: &seqExpr{
+							pos: position{line: 330, col: 14, offset: 12232},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 330, col: 14, offset: 12232},
+									name: "KW_GOSUB",
+								},
 								&oneOrMoreExpr{
-									pos: position{line: 324, col: 50, offset: 10819},
+									pos: position{line: 330, col: 23, offset: 12241},
 									expr: &charClassMatcher{
-										pos:        position{line: 324, col: 50, offset: 10819},
+										pos:        position{line: 330, col: 23, offset: 12241},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2651,30 +3407,30 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 324, col: 55, offset: 10824},
-									label: "Vars",
+									pos:   position{line: 330, col: 28, offset: 12246},
+									label: "Num",
 									expr: &ruleRefExpr{
-										pos:  position{line: 324, col: 60, offset: 10829},
-										name: "IdentifierList",
+										pos:  position{line: 330, col: 32, offset: 12250},
+										name: "LineNumber",
 									},
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 327, col: 15, offset: 10956},
-						run: (*parser).callonInputStmt27,
+						pos: position{line: 333, col: 14, offset: 12329},
+						run: (*parser).callonGosubStmt9,
 						expr: &seqExpr{
-							pos: position{line: 327, col: 15, offset: 10956},
+							pos: position{line: 333, col: 14, offset: 12329},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 327, col: 15, offset: 10956},
-									name: "KW_INPUT",
+									pos:  position{line: 333, col: 14, offset: 12329},
+									name: "KW_GOSUB",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 327, col: 24, offset: 10965},
+									pos: position{line: 333, col: 23, offset: 12338},
 									expr: &charClassMatcher{
-										pos:        position{line: 327, col: 24, offset: 10965},
+										pos:        position{line: 333, col: 23, offset: 12338},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2682,11 +3438,11 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 327, col: 29, offset: 10970},
-									label: "Vars",
+									pos:   position{line: 333, col: 28, offset: 12343},
+									label: "Label",
 									expr: &ruleRefExpr{
-										pos:  position{line: 327, col: 34, offset: 10975},
-										name: "IdentifierList",
+										pos:  position{line: 333, col: 34, offset: 12349},
+										name: "LabelName",
 									},
 								},
 							},
@@ -2696,86 +3452,95 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "IdentifierList",
-			pos:  position{line: 331, col: 1, offset: 11046},
+			name: "ReturnStmt",
+			pos:  position{line: 337, col: 1, offset: 12415},
 			expr: &actionExpr{
-				pos: position{line: 331, col: 19, offset: 11064},
-				run: (*parser).callonIdentifierList1,
-				expr: &seqExpr{
-					pos: position{line: 331, col: 19, offset: 11064},
-					exprs: []any{
-						&labeledExpr{
-							pos:   position{line: 331, col: 19, offset: 11064},
-							label: "First",
-							expr: &ruleRefExpr{
-								pos:  position{line: 331, col: 25, offset: 11070},
-								name: "Identifier",
-							},
-						},
-						&labeledExpr{
-							pos:   position{line: 331, col: 36, offset: 11081},
-							label: "Rest",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 331, col: 41, offset: 11086},
-								expr: &seqExpr{
-									pos: position{line: 331, col: 42, offset: 11087},
-									exprs: []any{
-										&litMatcher{
-											pos:        position{line: 331, col: 42, offset: 11087},
-											val:        ",",
-											ignoreCase: false,
-											want:       "\",\"",
-										},
-										&zeroOrMoreExpr{
-											pos: position{line: 331, col: 46, offset: 11091},
-											expr: &charClassMatcher{
-												pos:        position{line: 331, col: 46, offset: 11091},
-												val:        "[ ]",
-												chars:      []rune{' '},
-												ignoreCase: false,
-												inverted:   false,
-											},
-										},
-										&ruleRefExpr{
-											pos:  position{line: 331, col: 51, offset: 11096},
-											name: "Identifier",
-										},
-									},
-								},
-							},
-						},
-					},
+				pos: position{line: 337, col: 15, offset: 12429},
+				run: (*parser).callonReturnStmt1,
+				expr: &ruleRefExpr{
+					pos:  position{line: 337, col: 15, offset: 12429},
+					name: "KW_RETURN",
 				},
 			},
 		},
 		{
-			name: "Expression",
-			pos:  position{line: 347, col: 1, offset: 11537},
-			expr: &ruleRefExpr{
-				pos:  position{line: 347, col: 15, offset: 11551},
-				name: "LogicalNot",
+			name: "EndStmt",
+			pos:  position{line: 345, col: 1, offset: 12651},
+			expr: &actionExpr{
+				pos: position{line: 345, col: 12, offset: 12662},
+				run: (*parser).callonEndStmt1,
+				expr: &ruleRefExpr{
+					pos:  position{line: 345, col: 12, offset: 12662},
+					name: "KW_END",
+				},
 			},
 		},
 		{
-			name: "LogicalNot",
-			pos:  position{line: 349, col: 1, offset: 11563},
+			name: "StopStmt",
+			pos:  position{line: 349, col: 1, offset: 12702},
+			expr: &actionExpr{
+				pos: position{line: 349, col: 13, offset: 12714},
+				run: (*parser).callonStopStmt1,
+				expr: &ruleRefExpr{
+					pos:  position{line: 349, col: 13, offset: 12714},
+					name: "KW_STOP",
+				},
+			},
+		},
+		{
+			name: "ChainStmt",
+			pos:  position{line: 354, col: 1, offset: 12841},
 			expr: &choiceExpr{
-				pos: position{line: 349, col: 15, offset: 11577},
+				pos: position{line: 354, col: 14, offset: 12854},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 349, col: 15, offset: 11577},
-						run: (*parser).callonLogicalNot2,
+						pos: position{line: 354, col: 14, offset: 12854},
+						run: (*parser).callonChainStmt2,
 						expr: &seqExpr{
-							pos: position{line: 349, col: 15, offset: 11577},
+							pos: position{line: 354, col: 14, offset: 12854},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 349, col: 15, offset: 11577},
-									name: "KW_NOT",
+									pos:  position{line: 354, col: 14, offset: 12854},
+									name: "KW_CHAIN",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 354, col: 23, offset: 12863},
+									expr: &charClassMatcher{
+										pos:        position{line: 354, col: 23, offset: 12863},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 354, col: 28, offset: 12868},
+									label: "File",
+									expr: &ruleRefExpr{
+										pos:  position{line: 354, col: 33, offset: 12873},
+										name: "Expression",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 354, col: 44, offset: 12884},
+									expr: &charClassMatcher{
+										pos:        position{line: 354, col: 44, offset: 12884},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 354, col: 49, offset: 12889},
+									val:        ",",
+									ignoreCase: false,
+									want:       "\",\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 349, col: 22, offset: 11584},
+									pos: position{line: 354, col: 53, offset: 12893},
 									expr: &charClassMatcher{
-										pos:        position{line: 349, col: 22, offset: 11584},
+										pos:        position{line: 354, col: 53, offset: 12893},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -2783,82 +3548,231 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 349, col: 27, offset: 11589},
-									label: "Right",
+									pos:   position{line: 354, col: 58, offset: 12898},
+									label: "Line",
 									expr: &ruleRefExpr{
-										pos:  position{line: 349, col: 33, offset: 11595},
-										name: "LogicalOr",
+										pos:  position{line: 354, col: 63, offset: 12903},
+										name: "Expression",
 									},
 								},
+								&zeroOrMoreExpr{
+									pos: position{line: 354, col: 74, offset: 12914},
+									expr: &charClassMatcher{
+										pos:        position{line: 354, col: 74, offset: 12914},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 354, col: 79, offset: 12919},
+									val:        ",",
+									ignoreCase: false,
+									want:       "\",\"",
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 354, col: 83, offset: 12923},
+									expr: &charClassMatcher{
+										pos:        position{line: 354, col: 83, offset: 12923},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 354, col: 88, offset: 12928},
+									name: "KW_ALL",
+								},
 							},
 						},
-					},
-					&ruleRefExpr{
-						pos:  position{line: 352, col: 15, offset: 11685},
-						name: "LogicalOr",
-					},
-				},
-			},
-		},
-		{
-			name: "LogicalOr",
-			pos:  position{line: 354, col: 1, offset: 11696},
-			expr: &actionExpr{
-				pos: position{line: 354, col: 14, offset: 11709},
-				run: (*parser).callonLogicalOr1,
-				expr: &seqExpr{
-					pos: position{line: 354, col: 14, offset: 11709},
-					exprs: []any{
-						&labeledExpr{
-							pos:   position{line: 354, col: 14, offset: 11709},
-							label: "Left",
-							expr: &ruleRefExpr{
-								pos:  position{line: 354, col: 19, offset: 11714},
-								name: "LogicalAnd",
-							},
-						},
-						&labeledExpr{
-							pos:   position{line: 354, col: 30, offset: 11725},
-							label: "Rest",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 354, col: 35, offset: 11730},
-								expr: &seqExpr{
-									pos: position{line: 354, col: 37, offset: 11732},
-									exprs: []any{
-										&zeroOrMoreExpr{
-											pos: position{line: 354, col: 37, offset: 11732},
-											expr: &charClassMatcher{
-												pos:        position{line: 354, col: 37, offset: 11732},
-												val:        "[ ]",
-												chars:      []rune{' '},
-												ignoreCase: false,
-												inverted:   false,
-											},
-										},
-										&ruleRefExpr{
-											pos:  position{line: 354, col: 42, offset: 11737},
-											name: "KW_OR",
-										},
-										&zeroOrMoreExpr{
-											pos: position{line: 354, col: 48, offset: 11743},
-											expr: &charClassMatcher{
-												pos:        position{line: 354, col: 48, offset: 11743},
-												val:        "[ ]",
-												chars:      []rune{' '},
+					},
+					&actionExpr{
+						pos: position{line: 357, col: 15, offset: 13038},
+						run: (*parser).callonChainStmt22,
+						expr: &seqExpr{
+							pos: position{line: 357, col: 15, offset: 13038},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 357, col: 15, offset: 13038},
+									name: "KW_CHAIN",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 357, col: 24, offset: 13047},
+									expr: &charClassMatcher{
+										pos:        position{line: 357, col: 24, offset: 13047},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 357, col: 29, offset: 13052},
+									label: "File",
+									expr: &ruleRefExpr{
+										pos:  position{line: 357, col: 34, offset: 13057},
+										name: "Expression",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 357, col: 45, offset: 13068},
+									expr: &charClassMatcher{
+										pos:        position{line: 357, col: 45, offset: 13068},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 357, col: 50, offset: 13073},
+									val:        ",",
+									ignoreCase: false,
+									want:       "\",\"",
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 357, col: 54, offset: 13077},
+									expr: &charClassMatcher{
+										pos:        position{line: 357, col: 54, offset: 13077},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&zeroOrOneExpr{
+									pos: position{line: 357, col: 59, offset: 13082},
+									expr: &seqExpr{
+										pos: position{line: 357, col: 60, offset: 13083},
+										exprs: []any{
+											&litMatcher{
+												pos:        position{line: 357, col: 60, offset: 13083},
+												val:        ",",
 												ignoreCase: false,
-												inverted:   false,
+												want:       "\",\"",
 											},
-										},
-										&labeledExpr{
-											pos:   position{line: 354, col: 53, offset: 11748},
-											label: "Right",
-											expr: &ruleRefExpr{
-												pos:  position{line: 354, col: 59, offset: 11754},
-												name: "LogicalAnd",
+											&zeroOrMoreExpr{
+												pos: position{line: 357, col: 64, offset: 13087},
+												expr: &charClassMatcher{
+													pos:        position{line: 357, col: 64, offset: 13087},
+													val:        "[ ]",
+													chars:      []rune{' '},
+													ignoreCase: false,
+													inverted:   false,
+												},
 											},
 										},
 									},
 								},
+								&ruleRefExpr{
+									pos:  position{line: 357, col: 71, offset: 13094},
+									name: "KW_ALL",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 360, col: 15, offset: 13181},
+						run: (*parser).callonChainStmt40,
+						expr: &seqExpr{
+							pos: position{line: 360, col: 15, offset: 13181},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 360, col: 15, offset: 13181},
+									name: "KW_CHAIN",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 360, col: 24, offset: 13190},
+									expr: &charClassMatcher{
+										pos:        position{line: 360, col: 24, offset: 13190},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 360, col: 29, offset: 13195},
+									label: "File",
+									expr: &ruleRefExpr{
+										pos:  position{line: 360, col: 34, offset: 13200},
+										name: "Expression",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 360, col: 45, offset: 13211},
+									expr: &charClassMatcher{
+										pos:        position{line: 360, col: 45, offset: 13211},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 360, col: 50, offset: 13216},
+									val:        ",",
+									ignoreCase: false,
+									want:       "\",\"",
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 360, col: 54, offset: 13220},
+									expr: &charClassMatcher{
+										pos:        position{line: 360, col: 54, offset: 13220},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&notExpr{
+									pos: position{line: 360, col: 59, offset: 13225},
+									expr: &ruleRefExpr{
+										pos:  position{line: 360, col: 60, offset: 13226},
+										name: "KW_ALL",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 360, col: 67, offset: 13233},
+									label: "Line",
+									expr: &ruleRefExpr{
+										pos:  position{line: 360, col: 72, offset: 13238},
+										name: "Expression",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 363, col: 15, offset: 13341},
+						run: (*parser).callonChainStmt56,
+						expr: &seqExpr{
+							pos: position{line: 363, col: 15, offset: 13341},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 363, col: 15, offset: 13341},
+									name: "KW_CHAIN",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 363, col: 24, offset: 13350},
+									expr: &charClassMatcher{
+										pos:        position{line: 363, col: 24, offset: 13350},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 363, col: 29, offset: 13355},
+									label: "File",
+									expr: &ruleRefExpr{
+										pos:  position{line: 363, col: 34, offset: 13360},
+										name: "Expression",
+									},
+								},
 							},
 						},
 					},
@@ -2866,61 +3780,73 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "LogicalAnd",
-			pos:  position{line: 358, col: 1, offset: 11826},
+			name: "CommonStmt",
+			pos:  position{line: 367, col: 1, offset: 13427},
 			expr: &actionExpr{
-				pos: position{line: 358, col: 15, offset: 11840},
-				run: (*parser).callonLogicalAnd1,
+				pos: position{line: 367, col: 15, offset: 13441},
+				run: (*parser).callonCommonStmt1,
 				expr: &seqExpr{
-					pos: position{line: 358, col: 15, offset: 11840},
+					pos: position{line: 367, col: 15, offset: 13441},
 					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 367, col: 15, offset: 13441},
+							name: "KW_COMMON",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 367, col: 25, offset: 13451},
+							expr: &charClassMatcher{
+								pos:        position{line: 367, col: 25, offset: 13451},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
 						&labeledExpr{
-							pos:   position{line: 358, col: 15, offset: 11840},
-							label: "Left",
+							pos:   position{line: 367, col: 30, offset: 13456},
+							label: "First",
 							expr: &ruleRefExpr{
-								pos:  position{line: 358, col: 20, offset: 11845},
-								name: "Comparison",
+								pos:  position{line: 367, col: 36, offset: 13462},
+								name: "CommonTarget",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 358, col: 31, offset: 11856},
+							pos:   position{line: 367, col: 49, offset: 13475},
 							label: "Rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 358, col: 36, offset: 11861},
+								pos: position{line: 367, col: 54, offset: 13480},
 								expr: &seqExpr{
-									pos: position{line: 358, col: 38, offset: 11863},
+									pos: position{line: 367, col: 55, offset: 13481},
 									exprs: []any{
 										&zeroOrMoreExpr{
-											pos: position{line: 358, col: 38, offset: 11863},
+											pos: position{line: 367, col: 55, offset: 13481},
 											expr: &charClassMatcher{
-												pos:        position{line: 358, col: 38, offset: 11863},
+												pos:        position{line: 367, col: 55, offset: 13481},
 												val:        "[ ]",
 												chars:      []rune{' '},
 												ignoreCase: false,
 												inverted:   false,
 											},
 										},
-										&ruleRefExpr{
-											pos:  position{line: 358, col: 43, offset: 11868},
-											name: "KW_AND",
+										&litMatcher{
+											pos:        position{line: 367, col: 60, offset: 13486},
+											val:        ",",
+											ignoreCase: false,
+											want:       "\",\"",
 										},
 										&zeroOrMoreExpr{
-											pos: position{line: 358, col: 50, offset: 11875},
+											pos: position{line: 367, col: 64, offset: 13490},
 											expr: &charClassMatcher{
-												pos:        position{line: 358, col: 50, offset: 11875},
+												pos:        position{line: 367, col: 64, offset: 13490},
 												val:        "[ ]",
 												chars:      []rune{' '},
 												ignoreCase: false,
 												inverted:   false,
 											},
 										},
-										&labeledExpr{
-											pos:   position{line: 358, col: 55, offset: 11880},
-											label: "Right",
-											expr: &ruleRefExpr{
-												pos:  position{line: 358, col: 61, offset: 11886},
-												name: "Comparison",
-											},
+										&ruleRefExpr{
+											pos:  position{line: 367, col: 69, offset: 13495},
+											name: "CommonTarget",
 										},
 									},
 								},
@@ -2931,110 +3857,69 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Comparison",
-			pos:  position{line: 362, col: 1, offset: 11959},
+			name: "CommonTarget",
+			pos:  position{line: 380, col: 1, offset: 13874},
 			expr: &choiceExpr{
-				pos: position{line: 362, col: 15, offset: 11973},
+				pos: position{line: 380, col: 17, offset: 13890},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 362, col: 15, offset: 11973},
-						run: (*parser).callonComparison2,
+						pos: position{line: 380, col: 17, offset: 13890},
+						run: (*parser).callonCommonTarget2,
 						expr: &seqExpr{
-							pos: position{line: 362, col: 15, offset: 11973},
+							pos: position{line: 380, col: 17, offset: 13890},
 							exprs: []any{
 								&labeledExpr{
-									pos:   position{line: 362, col: 15, offset: 11973},
-									label: "Left",
+									pos:   position{line: 380, col: 17, offset: 13890},
+									label: "id",
 									expr: &ruleRefExpr{
-										pos:  position{line: 362, col: 20, offset: 11978},
-										name: "Additive",
+										pos:  position{line: 380, col: 20, offset: 13893},
+										name: "Identifier",
 									},
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 362, col: 29, offset: 11987},
+									pos: position{line: 380, col: 31, offset: 13904},
 									expr: &charClassMatcher{
-										pos:        position{line: 362, col: 29, offset: 11987},
+										pos:        position{line: 380, col: 31, offset: 13904},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
 										inverted:   false,
 									},
 								},
-								&labeledExpr{
-									pos:   position{line: 362, col: 34, offset: 11992},
-									label: "Op",
-									expr: &choiceExpr{
-										pos: position{line: 362, col: 38, offset: 11996},
-										alternatives: []any{
-											&litMatcher{
-												pos:        position{line: 362, col: 38, offset: 11996},
-												val:        ">=",
-												ignoreCase: false,
-												want:       "\">=\"",
-											},
-											&litMatcher{
-												pos:        position{line: 362, col: 45, offset: 12003},
-												val:        "<=",
-												ignoreCase: false,
-												want:       "\"<=\"",
-											},
-											&litMatcher{
-												pos:        position{line: 362, col: 52, offset: 12010},
-												val:        "<>",
-												ignoreCase: false,
-												want:       "\"<>\"",
-											},
-											&litMatcher{
-												pos:        position{line: 362, col: 59, offset: 12017},
-												val:        "=",
-												ignoreCase: false,
-												want:       "\"=\"",
-											},
-											&litMatcher{
-												pos:        position{line: 362, col: 65, offset: 12023},
-												val:        ">",
-												ignoreCase: false,
-												want:       "\">\"",
-											},
-											&litMatcher{
-												pos:        position{line: 362, col: 71, offset: 12029},
-												val:        "<",
-												ignoreCase: false,
-												want:       "\"<\"",
-											},
-										},
-									},
+								&litMatcher{
+									pos:        position{line: 380, col: 36, offset: 13909},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 362, col: 76, offset: 12034},
+									pos: position{line: 380, col: 40, offset: 13913},
 									expr: &charClassMatcher{
-										pos:        position{line: 362, col: 76, offset: 12034},
+										pos:        position{line: 380, col: 40, offset: 13913},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
 										inverted:   false,
 									},
 								},
-								&labeledExpr{
-									pos:   position{line: 362, col: 81, offset: 12039},
-									label: "Right",
-									expr: &ruleRefExpr{
-										pos:  position{line: 362, col: 87, offset: 12045},
-										name: "Additive",
-									},
+								&litMatcher{
+									pos:        position{line: 380, col: 45, offset: 13918},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 365, col: 15, offset: 12176},
-						run: (*parser).callonComparison20,
+						pos: position{line: 383, col: 13, offset: 13987},
+						run: (*parser).callonCommonTarget12,
 						expr: &labeledExpr{
-							pos:   position{line: 365, col: 15, offset: 12176},
-							label: "Left",
+							pos:   position{line: 383, col: 13, offset: 13987},
+							label: "id",
 							expr: &ruleRefExpr{
-								pos:  position{line: 365, col: 20, offset: 12181},
-								name: "Additive",
+								pos:  position{line: 383, col: 16, offset: 13990},
+								name: "Identifier",
 							},
 						},
 					},
@@ -3042,158 +3927,263 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Additive",
-			pos:  position{line: 369, col: 1, offset: 12224},
+			name: "ModuleStmt",
+			pos:  position{line: 391, col: 1, offset: 14257},
 			expr: &actionExpr{
-				pos: position{line: 369, col: 13, offset: 12236},
-				run: (*parser).callonAdditive1,
+				pos: position{line: 391, col: 15, offset: 14271},
+				run: (*parser).callonModuleStmt1,
 				expr: &seqExpr{
-					pos: position{line: 369, col: 13, offset: 12236},
+					pos: position{line: 391, col: 15, offset: 14271},
 					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 391, col: 15, offset: 14271},
+							name: "KW_MODULE",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 391, col: 25, offset: 14281},
+							expr: &charClassMatcher{
+								pos:        position{line: 391, col: 25, offset: 14281},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
 						&labeledExpr{
-							pos:   position{line: 369, col: 13, offset: 12236},
-							label: "Left",
+							pos:   position{line: 391, col: 30, offset: 14286},
+							label: "Name",
 							expr: &ruleRefExpr{
-								pos:  position{line: 369, col: 18, offset: 12241},
-								name: "Multiplicative",
+								pos:  position{line: 391, col: 35, offset: 14291},
+								name: "LabelName",
 							},
 						},
+					},
+				},
+			},
+		},
+		{
+			name: "SubStmt",
+			pos:  position{line: 395, col: 1, offset: 14356},
+			expr: &actionExpr{
+				pos: position{line: 395, col: 12, offset: 14367},
+				run: (*parser).callonSubStmt1,
+				expr: &seqExpr{
+					pos: position{line: 395, col: 12, offset: 14367},
+					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 369, col: 33, offset: 12256},
-							label: "Rest",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 369, col: 38, offset: 12261},
+							pos:   position{line: 395, col: 12, offset: 14367},
+							label: "Export",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 395, col: 19, offset: 14374},
 								expr: &seqExpr{
-									pos: position{line: 369, col: 40, offset: 12263},
+									pos: position{line: 395, col: 20, offset: 14375},
 									exprs: []any{
-										&zeroOrMoreExpr{
-											pos: position{line: 369, col: 40, offset: 12263},
-											expr: &charClassMatcher{
-												pos:        position{line: 369, col: 40, offset: 12263},
-												val:        "[ ]",
-												chars:      []rune{' '},
-												ignoreCase: false,
-												inverted:   false,
-											},
-										},
-										&choiceExpr{
-											pos: position{line: 369, col: 46, offset: 12269},
-											alternatives: []any{
-												&litMatcher{
-													pos:        position{line: 369, col: 46, offset: 12269},
-													val:        "+",
-													ignoreCase: false,
-													want:       "\"+\"",
-												},
-												&litMatcher{
-													pos:        position{line: 369, col: 52, offset: 12275},
-													val:        "-",
-													ignoreCase: false,
-													want:       "\"-\"",
-												},
-											},
+										&ruleRefExpr{
+											pos:  position{line: 395, col: 20, offset: 14375},
+											name: "KW_EXPORT",
 										},
-										&zeroOrMoreExpr{
-											pos: position{line: 369, col: 57, offset: 12280},
+										&oneOrMoreExpr{
+											pos: position{line: 395, col: 30, offset: 14385},
 											expr: &charClassMatcher{
-												pos:        position{line: 369, col: 57, offset: 12280},
+												pos:        position{line: 395, col: 30, offset: 14385},
 												val:        "[ ]",
 												chars:      []rune{' '},
 												ignoreCase: false,
 												inverted:   false,
 											},
 										},
-										&labeledExpr{
-											pos:   position{line: 369, col: 62, offset: 12285},
-											label: "Right",
-											expr: &ruleRefExpr{
-												pos:  position{line: 369, col: 68, offset: 12291},
-												name: "Multiplicative",
-											},
-										},
 									},
 								},
 							},
 						},
+						&labeledExpr{
+							pos:   position{line: 395, col: 37, offset: 14392},
+							label: "Function",
+							expr: &ruleRefExpr{
+								pos:  position{line: 395, col: 46, offset: 14401},
+								name: "RoutineKind",
+							},
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 395, col: 58, offset: 14413},
+							expr: &charClassMatcher{
+								pos:        position{line: 395, col: 58, offset: 14413},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 395, col: 63, offset: 14418},
+							label: "Name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 395, col: 68, offset: 14423},
+								name: "Identifier",
+							},
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 395, col: 79, offset: 14434},
+							expr: &charClassMatcher{
+								pos:        position{line: 395, col: 79, offset: 14434},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 395, col: 84, offset: 14439},
+							label: "Params",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 395, col: 91, offset: 14446},
+								expr: &ruleRefExpr{
+									pos:  position{line: 395, col: 91, offset: 14446},
+									name: "ParamList",
+								},
+							},
+						},
 					},
 				},
 			},
 		},
 		{
-			name: "Multiplicative",
-			pos:  position{line: 373, col: 1, offset: 12360},
+			name: "EndSubStmt",
+			pos:  position{line: 400, col: 1, offset: 14625},
 			expr: &actionExpr{
-				pos: position{line: 373, col: 19, offset: 12378},
-				run: (*parser).callonMultiplicative1,
+				pos: position{line: 400, col: 15, offset: 14639},
+				run: (*parser).callonEndSubStmt1,
 				expr: &seqExpr{
-					pos: position{line: 373, col: 19, offset: 12378},
+					pos: position{line: 400, col: 15, offset: 14639},
 					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 400, col: 15, offset: 14639},
+							name: "KW_END",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 400, col: 22, offset: 14646},
+							expr: &charClassMatcher{
+								pos:        position{line: 400, col: 22, offset: 14646},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
 						&labeledExpr{
-							pos:   position{line: 373, col: 19, offset: 12378},
-							label: "Left",
+							pos:   position{line: 400, col: 27, offset: 14651},
+							label: "Function",
 							expr: &ruleRefExpr{
-								pos:  position{line: 373, col: 24, offset: 12383},
-								name: "Power",
+								pos:  position{line: 400, col: 36, offset: 14660},
+								name: "RoutineKind",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ExitSubStmt",
+			pos:  position{line: 404, col: 1, offset: 14733},
+			expr: &actionExpr{
+				pos: position{line: 404, col: 16, offset: 14748},
+				run: (*parser).callonExitSubStmt1,
+				expr: &seqExpr{
+					pos: position{line: 404, col: 16, offset: 14748},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 404, col: 16, offset: 14748},
+							name: "KW_EXIT",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 404, col: 24, offset: 14756},
+							expr: &charClassMatcher{
+								pos:        position{line: 404, col: 24, offset: 14756},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 373, col: 30, offset: 12389},
-							label: "Rest",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 373, col: 35, offset: 12394},
-								expr: &seqExpr{
-									pos: position{line: 373, col: 37, offset: 12396},
-									exprs: []any{
-										&zeroOrMoreExpr{
-											pos: position{line: 373, col: 37, offset: 12396},
-											expr: &charClassMatcher{
-												pos:        position{line: 373, col: 37, offset: 12396},
-												val:        "[ ]",
-												chars:      []rune{' '},
-												ignoreCase: false,
-												inverted:   false,
-											},
-										},
-										&choiceExpr{
-											pos: position{line: 373, col: 43, offset: 12402},
-											alternatives: []any{
-												&litMatcher{
-													pos:        position{line: 373, col: 43, offset: 12402},
-													val:        "*",
-													ignoreCase: false,
-													want:       "\"*\"",
-												},
-												&litMatcher{
-													pos:        position{line: 373, col: 49, offset: 12408},
-													val:        "/",
-													ignoreCase: false,
-													want:       "\"/\"",
-												},
-												&ruleRefExpr{
-													pos:  position{line: 373, col: 55, offset: 12414},
-													name: "KW_MOD",
-												},
-											},
-										},
-										&zeroOrMoreExpr{
-											pos: position{line: 373, col: 63, offset: 12422},
-											expr: &charClassMatcher{
-												pos:        position{line: 373, col: 63, offset: 12422},
-												val:        "[ ]",
-												chars:      []rune{' '},
-												ignoreCase: false,
-												inverted:   false,
-											},
-										},
-										&labeledExpr{
-											pos:   position{line: 373, col: 68, offset: 12427},
-											label: "Right",
-											expr: &ruleRefExpr{
-												pos:  position{line: 373, col: 74, offset: 12433},
-												name: "Power",
-											},
-										},
-									},
+							pos:   position{line: 404, col: 29, offset: 14761},
+							label: "Function",
+							expr: &ruleRefExpr{
+								pos:  position{line: 404, col: 38, offset: 14770},
+								name: "RoutineKind",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "DeclareStmt",
+			pos:  position{line: 408, col: 1, offset: 14844},
+			expr: &actionExpr{
+				pos: position{line: 408, col: 16, offset: 14859},
+				run: (*parser).callonDeclareStmt1,
+				expr: &seqExpr{
+					pos: position{line: 408, col: 16, offset: 14859},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 408, col: 16, offset: 14859},
+							name: "KW_DECLARE",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 408, col: 27, offset: 14870},
+							expr: &charClassMatcher{
+								pos:        position{line: 408, col: 27, offset: 14870},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 408, col: 32, offset: 14875},
+							label: "Function",
+							expr: &ruleRefExpr{
+								pos:  position{line: 408, col: 41, offset: 14884},
+								name: "RoutineKind",
+							},
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 408, col: 53, offset: 14896},
+							expr: &charClassMatcher{
+								pos:        position{line: 408, col: 53, offset: 14896},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 408, col: 58, offset: 14901},
+							label: "Name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 408, col: 63, offset: 14906},
+								name: "Identifier",
+							},
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 408, col: 74, offset: 14917},
+							expr: &charClassMatcher{
+								pos:        position{line: 408, col: 74, offset: 14917},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 408, col: 79, offset: 14922},
+							label: "Params",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 408, col: 86, offset: 14929},
+								expr: &ruleRefExpr{
+									pos:  position{line: 408, col: 86, offset: 14929},
+									name: "ParamList",
 								},
 							},
 						},
@@ -3202,29 +4192,43 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Power",
-			pos:  position{line: 378, col: 1, offset: 12557},
+			name: "CallStmt",
+			pos:  position{line: 413, col: 1, offset: 15089},
 			expr: &choiceExpr{
-				pos: position{line: 378, col: 10, offset: 12566},
+				pos: position{line: 413, col: 13, offset: 15101},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 378, col: 10, offset: 12566},
-						run: (*parser).callonPower2,
+						pos: position{line: 413, col: 13, offset: 15101},
+						run: (*parser).callonCallStmt2,
 						expr: &seqExpr{
-							pos: position{line: 378, col: 10, offset: 12566},
+							pos: position{line: 413, col: 13, offset: 15101},
 							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 413, col: 13, offset: 15101},
+									name: "KW_CALL",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 413, col: 21, offset: 15109},
+									expr: &charClassMatcher{
+										pos:        position{line: 413, col: 21, offset: 15109},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
 								&labeledExpr{
-									pos:   position{line: 378, col: 10, offset: 12566},
-									label: "Left",
+									pos:   position{line: 413, col: 26, offset: 15114},
+									label: "Name",
 									expr: &ruleRefExpr{
-										pos:  position{line: 378, col: 15, offset: 12571},
-										name: "Unary",
+										pos:  position{line: 413, col: 31, offset: 15119},
+										name: "Identifier",
 									},
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 378, col: 21, offset: 12577},
+									pos: position{line: 413, col: 42, offset: 15130},
 									expr: &charClassMatcher{
-										pos:        position{line: 378, col: 21, offset: 12577},
+										pos:        position{line: 413, col: 42, offset: 15130},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -3232,15 +4236,15 @@ var g = &grammar{
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 378, col: 26, offset: 12582},
-									val:        "^",
+									pos:        position{line: 413, col: 47, offset: 15135},
+									val:        "(",
 									ignoreCase: false,
-									want:       "\"^\"",
+									want:       "\"(\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 378, col: 30, offset: 12586},
+									pos: position{line: 413, col: 51, offset: 15139},
 									expr: &charClassMatcher{
-										pos:        position{line: 378, col: 30, offset: 12586},
+										pos:        position{line: 413, col: 51, offset: 15139},
 										val:        "[ ]",
 										chars:      []rune{' '},
 										ignoreCase: false,
@@ -3248,118 +4252,97 @@ var g = &grammar{
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 378, col: 35, offset: 12591},
-									label: "Right",
+									pos:   position{line: 413, col: 56, offset: 15144},
+									label: "Args",
 									expr: &ruleRefExpr{
-										pos:  position{line: 378, col: 41, offset: 12597},
-										name: "Power",
+										pos:  position{line: 413, col: 61, offset: 15149},
+										name: "ExpressionList",
 									},
 								},
-							},
-						},
-					},
-					&ruleRefExpr{
-						pos:  position{line: 381, col: 9, offset: 12699},
-						name: "Unary",
-					},
-				},
-			},
-		},
-		{
-			name: "Unary",
-			pos:  position{line: 383, col: 1, offset: 12706},
-			expr: &choiceExpr{
-				pos: position{line: 383, col: 10, offset: 12715},
-				alternatives: []any{
+								&zeroOrMoreExpr{
+									pos: position{line: 413, col: 76, offset: 15164},
+									expr: &charClassMatcher{
+										pos:        position{line: 413, col: 76, offset: 15164},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 413, col: 81, offset: 15169},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
 					&actionExpr{
-						pos: position{line: 383, col: 10, offset: 12715},
-						run: (*parser).callonUnary2,
+						pos: position{line: 416, col: 13, offset: 15262},
+						run: (*parser).callonCallStmt19,
 						expr: &seqExpr{
-							pos: position{line: 383, col: 10, offset: 12715},
+							pos: position{line: 416, col: 13, offset: 15262},
 							exprs: []any{
-								&labeledExpr{
-									pos:   position{line: 383, col: 10, offset: 12715},
-									label: "op",
-									expr: &choiceExpr{
-										pos: position{line: 383, col: 14, offset: 12719},
-										alternatives: []any{
-											&litMatcher{
-												pos:        position{line: 383, col: 14, offset: 12719},
-												val:        "+",
-												ignoreCase: false,
-												want:       "\"+\"",
-											},
-											&litMatcher{
-												pos:        position{line: 383, col: 20, offset: 12725},
-												val:        "-",
-												ignoreCase: false,
-												want:       "\"-\"",
-											},
-										},
+								&ruleRefExpr{
+									pos:  position{line: 416, col: 13, offset: 15262},
+									name: "KW_CALL",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 416, col: 21, offset: 15270},
+									expr: &charClassMatcher{
+										pos:        position{line: 416, col: 21, offset: 15270},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
 									},
 								},
 								&labeledExpr{
-									pos:   position{line: 383, col: 25, offset: 12730},
-									label: "operand",
+									pos:   position{line: 416, col: 26, offset: 15275},
+									label: "Name",
 									expr: &ruleRefExpr{
-										pos:  position{line: 383, col: 33, offset: 12738},
-										name: "Unary",
+										pos:  position{line: 416, col: 31, offset: 15280},
+										name: "Identifier",
 									},
 								},
-							},
-						},
-					},
-					&ruleRefExpr{
-						pos:  position{line: 386, col: 9, offset: 12834},
-						name: "Primary",
-					},
-				},
-			},
-		},
-		{
-			name: "ExpressionList",
-			pos:  position{line: 388, col: 1, offset: 12843},
-			expr: &actionExpr{
-				pos: position{line: 388, col: 19, offset: 12861},
-				run: (*parser).callonExpressionList1,
-				expr: &seqExpr{
-					pos: position{line: 388, col: 19, offset: 12861},
-					exprs: []any{
-						&labeledExpr{
-							pos:   position{line: 388, col: 19, offset: 12861},
-							label: "First",
-							expr: &ruleRefExpr{
-								pos:  position{line: 388, col: 25, offset: 12867},
-								name: "Expression",
-							},
-						},
-						&labeledExpr{
-							pos:   position{line: 388, col: 36, offset: 12878},
-							label: "Rest",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 388, col: 41, offset: 12883},
-								expr: &seqExpr{
-									pos: position{line: 388, col: 42, offset: 12884},
-									exprs: []any{
-										&litMatcher{
-											pos:        position{line: 388, col: 42, offset: 12884},
-											val:        ",",
-											ignoreCase: false,
-											want:       "\",\"",
-										},
-										&zeroOrMoreExpr{
-											pos: position{line: 388, col: 46, offset: 12888},
-											expr: &charClassMatcher{
-												pos:        position{line: 388, col: 46, offset: 12888},
-												val:        "[ ]",
-												chars:      []rune{' '},
+								&zeroOrOneExpr{
+									pos: position{line: 416, col: 42, offset: 15291},
+									expr: &seqExpr{
+										pos: position{line: 416, col: 43, offset: 15292},
+										exprs: []any{
+											&zeroOrMoreExpr{
+												pos: position{line: 416, col: 43, offset: 15292},
+												expr: &charClassMatcher{
+													pos:        position{line: 416, col: 43, offset: 15292},
+													val:        "[ ]",
+													chars:      []rune{' '},
+													ignoreCase: false,
+													inverted:   false,
+												},
+											},
+											&litMatcher{
+												pos:        position{line: 416, col: 48, offset: 15297},
+												val:        "(",
 												ignoreCase: false,
-												inverted:   false,
+												want:       "\"(\"",
+											},
+											&zeroOrMoreExpr{
+												pos: position{line: 416, col: 52, offset: 15301},
+												expr: &charClassMatcher{
+													pos:        position{line: 416, col: 52, offset: 15301},
+													val:        "[ ]",
+													chars:      []rune{' '},
+													ignoreCase: false,
+													inverted:   false,
+												},
+											},
+											&litMatcher{
+												pos:        position{line: 416, col: 57, offset: 15306},
+												val:        ")",
+												ignoreCase: false,
+												want:       "\")\"",
 											},
-										},
-										&ruleRefExpr{
-											pos:  position{line: 388, col: 51, offset: 12893},
-											name: "Expression",
 										},
 									},
 								},
@@ -3370,122 +4353,168 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Primary",
-			pos:  position{line: 408, col: 1, offset: 13419},
+			name: "BareCallStmt",
+			pos:  position{line: 422, col: 1, offset: 15587},
 			expr: &choiceExpr{
-				pos: position{line: 408, col: 12, offset: 13430},
+				pos: position{line: 422, col: 17, offset: 15603},
 				alternatives: []any{
-					&ruleRefExpr{
-						pos:  position{line: 408, col: 12, offset: 13430},
-						name: "Number",
-					},
 					&actionExpr{
-						pos: position{line: 409, col: 13, offset: 13449},
-						run: (*parser).callonPrimary3,
+						pos: position{line: 422, col: 17, offset: 15603},
+						run: (*parser).callonBareCallStmt2,
 						expr: &seqExpr{
-							pos: position{line: 409, col: 13, offset: 13449},
+							pos: position{line: 422, col: 17, offset: 15603},
 							exprs: []any{
+								&notExpr{
+									pos: position{line: 422, col: 17, offset: 15603},
+									expr: &ruleRefExpr{
+										pos:  position{line: 422, col: 18, offset: 15604},
+										name: "Keyword",
+									},
+								},
 								&labeledExpr{
-									pos:   position{line: 409, col: 13, offset: 13449},
-									label: "id",
+									pos:   position{line: 422, col: 26, offset: 15612},
+									label: "Name",
 									expr: &ruleRefExpr{
-										pos:  position{line: 409, col: 16, offset: 13452},
+										pos:  position{line: 422, col: 31, offset: 15617},
 										name: "Identifier",
 									},
 								},
+								&zeroOrMoreExpr{
+									pos: position{line: 422, col: 42, offset: 15628},
+									expr: &charClassMatcher{
+										pos:        position{line: 422, col: 42, offset: 15628},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
 								&litMatcher{
-									pos:        position{line: 409, col: 27, offset: 13463},
+									pos:        position{line: 422, col: 47, offset: 15633},
 									val:        "(",
 									ignoreCase: false,
 									want:       "\"(\"",
 								},
+								&zeroOrMoreExpr{
+									pos: position{line: 422, col: 51, offset: 15637},
+									expr: &charClassMatcher{
+										pos:        position{line: 422, col: 51, offset: 15637},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
 								&labeledExpr{
-									pos:   position{line: 409, col: 31, offset: 13467},
-									label: "args",
+									pos:   position{line: 422, col: 56, offset: 15642},
+									label: "Args",
 									expr: &ruleRefExpr{
-										pos:  position{line: 409, col: 36, offset: 13472},
+										pos:  position{line: 422, col: 61, offset: 15647},
 										name: "ExpressionList",
 									},
 								},
+								&zeroOrMoreExpr{
+									pos: position{line: 422, col: 76, offset: 15662},
+									expr: &charClassMatcher{
+										pos:        position{line: 422, col: 76, offset: 15662},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
 								&litMatcher{
-									pos:        position{line: 409, col: 51, offset: 13487},
+									pos:        position{line: 422, col: 81, offset: 15667},
 									val:        ")",
 									ignoreCase: false,
 									want:       "\")\"",
 								},
+								&andExpr{
+									pos: position{line: 422, col: 85, offset: 15671},
+									expr: &ruleRefExpr{
+										pos:  position{line: 422, col: 86, offset: 15672},
+										name: "CallEnd",
+									},
+								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 417, col: 13, offset: 13766},
-						run: (*parser).callonPrimary11,
+						pos: position{line: 425, col: 17, offset: 15785},
+						run: (*parser).callonBareCallStmt20,
 						expr: &seqExpr{
-							pos: position{line: 417, col: 13, offset: 13766},
+							pos: position{line: 425, col: 17, offset: 15785},
 							exprs: []any{
+								&notExpr{
+									pos: position{line: 425, col: 17, offset: 15785},
+									expr: &ruleRefExpr{
+										pos:  position{line: 425, col: 18, offset: 15786},
+										name: "Keyword",
+									},
+								},
 								&labeledExpr{
-									pos:   position{line: 417, col: 13, offset: 13766},
-									label: "id",
+									pos:   position{line: 425, col: 26, offset: 15794},
+									label: "Name",
 									expr: &ruleRefExpr{
-										pos:  position{line: 417, col: 16, offset: 13769},
+										pos:  position{line: 425, col: 31, offset: 15799},
 										name: "Identifier",
 									},
 								},
-								&litMatcher{
-									pos:        position{line: 417, col: 27, offset: 13780},
-									val:        "(",
-									ignoreCase: false,
-									want:       "\"(\"",
+								&oneOrMoreExpr{
+									pos: position{line: 425, col: 42, offset: 15810},
+									expr: &charClassMatcher{
+										pos:        position{line: 425, col: 42, offset: 15810},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
 								},
-								&litMatcher{
-									pos:        position{line: 417, col: 31, offset: 13784},
-									val:        ")",
-									ignoreCase: false,
-									want:       "\")\"",
+								&labeledExpr{
+									pos:   position{line: 425, col: 47, offset: 15815},
+									label: "Args",
+									expr: &ruleRefExpr{
+										pos:  position{line: 425, col: 52, offset: 15820},
+										name: "ExpressionList",
+									},
+								},
+								&andExpr{
+									pos: position{line: 425, col: 67, offset: 15835},
+									expr: &ruleRefExpr{
+										pos:  position{line: 425, col: 68, offset: 15836},
+										name: "CallEnd",
+									},
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 421, col: 13, offset: 13927},
-						run: (*parser).callonPrimary17,
-						expr: &labeledExpr{
-							pos:   position{line: 421, col: 13, offset: 13927},
-							label: "id",
-							expr: &ruleRefExpr{
-								pos:  position{line: 421, col: 16, offset: 13930},
-								name: "Identifier",
-							},
-						},
-					},
-					&ruleRefExpr{
-						pos:  position{line: 424, col: 13, offset: 14005},
-						name: "StringLiteral",
-					},
-					&actionExpr{
-						pos: position{line: 425, col: 13, offset: 14031},
-						run: (*parser).callonPrimary21,
+						pos: position{line: 428, col: 17, offset: 15949},
+						run: (*parser).callonBareCallStmt32,
 						expr: &seqExpr{
-							pos: position{line: 425, col: 13, offset: 14031},
+							pos: position{line: 428, col: 17, offset: 15949},
 							exprs: []any{
-								&litMatcher{
-									pos:        position{line: 425, col: 13, offset: 14031},
-									val:        "(",
-									ignoreCase: false,
-									want:       "\"(\"",
+								&notExpr{
+									pos: position{line: 428, col: 17, offset: 15949},
+									expr: &ruleRefExpr{
+										pos:  position{line: 428, col: 18, offset: 15950},
+										name: "Keyword",
+									},
 								},
 								&labeledExpr{
-									pos:   position{line: 425, col: 17, offset: 14035},
-									label: "expr",
+									pos:   position{line: 428, col: 26, offset: 15958},
+									label: "Name",
 									expr: &ruleRefExpr{
-										pos:  position{line: 425, col: 22, offset: 14040},
-										name: "Expression",
+										pos:  position{line: 428, col: 31, offset: 15963},
+										name: "Identifier",
 									},
 								},
-								&litMatcher{
-									pos:        position{line: 425, col: 33, offset: 14051},
-									val:        ")",
-									ignoreCase: false,
-									want:       "\")\"",
+								&andExpr{
+									pos: position{line: 428, col: 42, offset: 15974},
+									expr: &ruleRefExpr{
+										pos:  position{line: 428, col: 43, offset: 15975},
+										name: "CallEnd",
+									},
 								},
 							},
 						},
@@ -3494,81 +4523,270 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Number",
-			pos:  position{line: 433, col: 1, offset: 14229},
+			name: "CallEnd",
+			pos:  position{line: 433, col: 1, offset: 16179},
+			expr: &seqExpr{
+				pos: position{line: 433, col: 12, offset: 16190},
+				exprs: []any{
+					&zeroOrMoreExpr{
+						pos: position{line: 433, col: 12, offset: 16190},
+						expr: &charClassMatcher{
+							pos:        position{line: 433, col: 12, offset: 16190},
+							val:        "[ ]",
+							chars:      []rune{' '},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+					&choiceExpr{
+						pos: position{line: 433, col: 18, offset: 16196},
+						alternatives: []any{
+							&litMatcher{
+								pos:        position{line: 433, col: 18, offset: 16196},
+								val:        ":",
+								ignoreCase: false,
+								want:       "\":\"",
+							},
+							&ruleRefExpr{
+								pos:  position{line: 433, col: 24, offset: 16202},
+								name: "EndOfLine",
+							},
+							&ruleRefExpr{
+								pos:  position{line: 433, col: 36, offset: 16214},
+								name: "KW_ELSE",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "SharedStmt",
+			pos:  position{line: 435, col: 1, offset: 16224},
 			expr: &actionExpr{
-				pos: position{line: 433, col: 11, offset: 14239},
-				run: (*parser).callonNumber1,
+				pos: position{line: 435, col: 15, offset: 16238},
+				run: (*parser).callonSharedStmt1,
 				expr: &seqExpr{
-					pos: position{line: 433, col: 11, offset: 14239},
+					pos: position{line: 435, col: 15, offset: 16238},
 					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 435, col: 15, offset: 16238},
+							name: "KW_SHARED",
+						},
 						&oneOrMoreExpr{
-							pos: position{line: 433, col: 11, offset: 14239},
+							pos: position{line: 435, col: 25, offset: 16248},
 							expr: &charClassMatcher{
-								pos:        position{line: 433, col: 11, offset: 14239},
-								val:        "[0-9]",
-								ranges:     []rune{'0', '9'},
+								pos:        position{line: 435, col: 25, offset: 16248},
+								val:        "[ ]",
+								chars:      []rune{' '},
 								ignoreCase: false,
 								inverted:   false,
 							},
 						},
-						&zeroOrOneExpr{
-							pos: position{line: 433, col: 18, offset: 14246},
-							expr: &seqExpr{
-								pos: position{line: 433, col: 19, offset: 14247},
-								exprs: []any{
-									&litMatcher{
-										pos:        position{line: 433, col: 19, offset: 14247},
-										val:        ".",
-										ignoreCase: false,
-										want:       "\".\"",
-									},
-									&oneOrMoreExpr{
-										pos: position{line: 433, col: 23, offset: 14251},
-										expr: &charClassMatcher{
-											pos:        position{line: 433, col: 23, offset: 14251},
-											val:        "[0-9]",
-											ranges:     []rune{'0', '9'},
+						&labeledExpr{
+							pos:   position{line: 435, col: 30, offset: 16253},
+							label: "First",
+							expr: &ruleRefExpr{
+								pos:  position{line: 435, col: 36, offset: 16259},
+								name: "CommonTarget",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 435, col: 49, offset: 16272},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 435, col: 54, offset: 16277},
+								expr: &seqExpr{
+									pos: position{line: 435, col: 55, offset: 16278},
+									exprs: []any{
+										&zeroOrMoreExpr{
+											pos: position{line: 435, col: 55, offset: 16278},
+											expr: &charClassMatcher{
+												pos:        position{line: 435, col: 55, offset: 16278},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&litMatcher{
+											pos:        position{line: 435, col: 60, offset: 16283},
+											val:        ",",
 											ignoreCase: false,
-											inverted:   false,
+											want:       "\",\"",
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 435, col: 64, offset: 16287},
+											expr: &charClassMatcher{
+												pos:        position{line: 435, col: 64, offset: 16287},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 435, col: 69, offset: 16292},
+											name: "CommonTarget",
 										},
 									},
 								},
 							},
 						},
-						&zeroOrOneExpr{
-							pos: position{line: 433, col: 32, offset: 14260},
-							expr: &seqExpr{
-								pos: position{line: 433, col: 33, offset: 14261},
-								exprs: []any{
-									&charClassMatcher{
-										pos:        position{line: 433, col: 33, offset: 14261},
-										val:        "[eE]",
-										chars:      []rune{'e', 'E'},
+					},
+				},
+			},
+		},
+		{
+			name: "RoutineKind",
+			pos:  position{line: 448, col: 1, offset: 16668},
+			expr: &choiceExpr{
+				pos: position{line: 448, col: 16, offset: 16683},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 448, col: 16, offset: 16683},
+						run: (*parser).callonRoutineKind2,
+						expr: &ruleRefExpr{
+							pos:  position{line: 448, col: 16, offset: 16683},
+							name: "KW_SUB",
+						},
+					},
+					&actionExpr{
+						pos: position{line: 451, col: 13, offset: 16725},
+						run: (*parser).callonRoutineKind4,
+						expr: &ruleRefExpr{
+							pos:  position{line: 451, col: 13, offset: 16725},
+							name: "KW_FUNCTION",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ParamList",
+			pos:  position{line: 456, col: 1, offset: 16816},
+			expr: &choiceExpr{
+				pos: position{line: 456, col: 14, offset: 16829},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 456, col: 14, offset: 16829},
+						run: (*parser).callonParamList2,
+						expr: &seqExpr{
+							pos: position{line: 456, col: 14, offset: 16829},
+							exprs: []any{
+								&litMatcher{
+									pos:        position{line: 456, col: 14, offset: 16829},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 456, col: 18, offset: 16833},
+									expr: &charClassMatcher{
+										pos:        position{line: 456, col: 18, offset: 16833},
+										val:        "[ ]",
+										chars:      []rune{' '},
 										ignoreCase: false,
 										inverted:   false,
 									},
-									&zeroOrOneExpr{
-										pos: position{line: 433, col: 38, offset: 14266},
-										expr: &charClassMatcher{
-											pos:        position{line: 433, col: 38, offset: 14266},
-											val:        "[+-]",
-											chars:      []rune{'+', '-'},
-											ignoreCase: false,
-											inverted:   false,
-										},
+								},
+								&labeledExpr{
+									pos:   position{line: 456, col: 23, offset: 16838},
+									label: "First",
+									expr: &ruleRefExpr{
+										pos:  position{line: 456, col: 29, offset: 16844},
+										name: "Param",
 									},
-									&oneOrMoreExpr{
-										pos: position{line: 433, col: 44, offset: 14272},
-										expr: &charClassMatcher{
-											pos:        position{line: 433, col: 44, offset: 14272},
-											val:        "[0-9]",
-											ranges:     []rune{'0', '9'},
-											ignoreCase: false,
-											inverted:   false,
+								},
+								&labeledExpr{
+									pos:   position{line: 456, col: 35, offset: 16850},
+									label: "Rest",
+									expr: &zeroOrMoreExpr{
+										pos: position{line: 456, col: 40, offset: 16855},
+										expr: &seqExpr{
+											pos: position{line: 456, col: 41, offset: 16856},
+											exprs: []any{
+												&zeroOrMoreExpr{
+													pos: position{line: 456, col: 41, offset: 16856},
+													expr: &charClassMatcher{
+														pos:        position{line: 456, col: 41, offset: 16856},
+														val:        "[ ]",
+														chars:      []rune{' '},
+														ignoreCase: false,
+														inverted:   false,
+													},
+												},
+												&litMatcher{
+													pos:        position{line: 456, col: 46, offset: 16861},
+													val:        ",",
+													ignoreCase: false,
+													want:       "\",\"",
+												},
+												&zeroOrMoreExpr{
+													pos: position{line: 456, col: 50, offset: 16865},
+													expr: &charClassMatcher{
+														pos:        position{line: 456, col: 50, offset: 16865},
+														val:        "[ ]",
+														chars:      []rune{' '},
+														ignoreCase: false,
+														inverted:   false,
+													},
+												},
+												&ruleRefExpr{
+													pos:  position{line: 456, col: 55, offset: 16870},
+													name: "Param",
+												},
+											},
 										},
 									},
 								},
+								&zeroOrMoreExpr{
+									pos: position{line: 456, col: 63, offset: 16878},
+									expr: &charClassMatcher{
+										pos:        position{line: 456, col: 63, offset: 16878},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 456, col: 68, offset: 16883},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 467, col: 13, offset: 17161},
+						run: (*parser).callonParamList21,
+						expr: &seqExpr{
+							pos: position{line: 467, col: 13, offset: 17161},
+							exprs: []any{
+								&litMatcher{
+									pos:        position{line: 467, col: 13, offset: 17161},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 467, col: 17, offset: 17165},
+									expr: &charClassMatcher{
+										pos:        position{line: 467, col: 17, offset: 17165},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 467, col: 22, offset: 17170},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
 							},
 						},
 					},
@@ -3576,539 +4794,2968 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "StringLiteral",
-			pos:  position{line: 438, col: 1, offset: 14369},
+			name: "Param",
+			pos:  position{line: 472, col: 1, offset: 17263},
 			expr: &actionExpr{
-				pos: position{line: 438, col: 18, offset: 14386},
-				run: (*parser).callonStringLiteral1,
+				pos: position{line: 472, col: 10, offset: 17272},
+				run: (*parser).callonParam1,
 				expr: &seqExpr{
-					pos: position{line: 438, col: 18, offset: 14386},
+					pos: position{line: 472, col: 10, offset: 17272},
 					exprs: []any{
-						&litMatcher{
-							pos:        position{line: 438, col: 18, offset: 14386},
-							val:        "\"",
-							ignoreCase: false,
-							want:       "\"\\\"\"",
+						&labeledExpr{
+							pos:   position{line: 472, col: 10, offset: 17272},
+							label: "Name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 472, col: 15, offset: 17277},
+								name: "Identifier",
+							},
 						},
 						&labeledExpr{
-							pos:   position{line: 438, col: 22, offset: 14390},
-							label: "Text",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 438, col: 27, offset: 14395},
-								expr: &charClassMatcher{
-									pos:        position{line: 438, col: 27, offset: 14395},
-									val:        "[^\"]",
-									chars:      []rune{'"'},
-									ignoreCase: false,
-									inverted:   true,
+							pos:   position{line: 472, col: 26, offset: 17288},
+							label: "Type",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 472, col: 31, offset: 17293},
+								expr: &seqExpr{
+									pos: position{line: 472, col: 32, offset: 17294},
+									exprs: []any{
+										&oneOrMoreExpr{
+											pos: position{line: 472, col: 32, offset: 17294},
+											expr: &charClassMatcher{
+												pos:        position{line: 472, col: 32, offset: 17294},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 472, col: 37, offset: 17299},
+											name: "KW_AS",
+										},
+										&oneOrMoreExpr{
+											pos: position{line: 472, col: 43, offset: 17305},
+											expr: &charClassMatcher{
+												pos:        position{line: 472, col: 43, offset: 17305},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 472, col: 48, offset: 17310},
+											name: "LabelName",
+										},
+									},
 								},
 							},
 						},
-						&litMatcher{
-							pos:        position{line: 438, col: 33, offset: 14401},
-							val:        "\"",
-							ignoreCase: false,
-							want:       "\"\\\"\"",
+					},
+				},
+			},
+		},
+		{
+			name: "TypeStmt",
+			pos:  position{line: 484, col: 1, offset: 17651},
+			expr: &actionExpr{
+				pos: position{line: 484, col: 13, offset: 17663},
+				run: (*parser).callonTypeStmt1,
+				expr: &seqExpr{
+					pos: position{line: 484, col: 13, offset: 17663},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 484, col: 13, offset: 17663},
+							name: "KW_TYPE",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 484, col: 21, offset: 17671},
+							expr: &charClassMatcher{
+								pos:        position{line: 484, col: 21, offset: 17671},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 484, col: 26, offset: 17676},
+							label: "Name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 484, col: 31, offset: 17681},
+								name: "LabelName",
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name: "Identifier",
-			pos:  position{line: 452, col: 1, offset: 14668},
+			name: "FieldStmt",
+			pos:  position{line: 489, col: 1, offset: 17807},
 			expr: &actionExpr{
-				pos: position{line: 452, col: 15, offset: 14682},
-				run: (*parser).callonIdentifier1,
+				pos: position{line: 489, col: 14, offset: 17820},
+				run: (*parser).callonFieldStmt1,
 				expr: &seqExpr{
-					pos: position{line: 452, col: 15, offset: 14682},
+					pos: position{line: 489, col: 14, offset: 17820},
 					exprs: []any{
-						&charClassMatcher{
-							pos:        position{line: 452, col: 15, offset: 14682},
-							val:        "[A-Za-z_]",
-							chars:      []rune{'_'},
-							ranges:     []rune{'A', 'Z', 'a', 'z'},
-							ignoreCase: false,
-							inverted:   false,
+						&labeledExpr{
+							pos:   position{line: 489, col: 14, offset: 17820},
+							label: "Name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 489, col: 19, offset: 17825},
+								name: "LabelName",
+							},
 						},
-						&zeroOrMoreExpr{
-							pos: position{line: 452, col: 24, offset: 14691},
+						&oneOrMoreExpr{
+							pos: position{line: 489, col: 29, offset: 17835},
 							expr: &charClassMatcher{
-								pos:        position{line: 452, col: 24, offset: 14691},
-								val:        "[A-Za-z0-9_$]",
-								chars:      []rune{'_', '$'},
-								ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+								pos:        position{line: 489, col: 29, offset: 17835},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 489, col: 34, offset: 17840},
+							name: "KW_AS",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 489, col: 40, offset: 17846},
+							expr: &charClassMatcher{
+								pos:        position{line: 489, col: 40, offset: 17846},
+								val:        "[ ]",
+								chars:      []rune{' '},
 								ignoreCase: false,
 								inverted:   false,
 							},
 						},
+						&labeledExpr{
+							pos:   position{line: 489, col: 45, offset: 17851},
+							label: "Type",
+							expr: &ruleRefExpr{
+								pos:  position{line: 489, col: 50, offset: 17856},
+								name: "LabelName",
+							},
+						},
 					},
 				},
 			},
 		},
-	},
-}
-
-func (c *current) onProgram1(Lines any) (any, error) {
-	return &ast.Program{Lines: toLineSliceFromAny(Lines)}, nil
-}
-
-func (p *parser) callonProgram1() (any, error) {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onProgram1(stack["Lines"])
-}
-
-func (c *current) onLine1(LineNumber, Statements any) (any, error) {
-	statements := []ast.Node{}
-	if Statements != nil {
-		statements = Statements.([]ast.Node)
-	}
-	return &ast.Line{
-		LineNumber: LineNumber.(int),
-		Statements: statements,
-	}, nil
-}
-
-func (p *parser) callonLine1() (any, error) {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onLine1(stack["LineNumber"], stack["Statements"])
-}
-
-func (c *current) onStatementList1(First, Rest any) (any, error) {
-	values := []ast.Node{First.(ast.Node)}
-	if Rest != nil {
-		for _, v := range Rest.([]interface{}) {
-			seq := v.([]interface{})
-			// seq[0] = ':', seq[1] = [ ]*, seq[2] = Statement
-			values = append(values, seq[2].(ast.Node))
-		}
-	}
-	return values, nil
+		{
+			name: "EndTypeStmt",
+			pos:  position{line: 493, col: 1, offset: 17941},
+			expr: &actionExpr{
+				pos: position{line: 493, col: 16, offset: 17956},
+				run: (*parser).callonEndTypeStmt1,
+				expr: &seqExpr{
+					pos: position{line: 493, col: 16, offset: 17956},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 493, col: 16, offset: 17956},
+							name: "KW_END",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 493, col: 23, offset: 17963},
+							expr: &charClassMatcher{
+								pos:        position{line: 493, col: 23, offset: 17963},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 493, col: 28, offset: 17968},
+							name: "KW_TYPE",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Fields",
+			pos:  position{line: 498, col: 1, offset: 18057},
+			expr: &actionExpr{
+				pos: position{line: 498, col: 11, offset: 18067},
+				run: (*parser).callonFields1,
+				expr: &seqExpr{
+					pos: position{line: 498, col: 11, offset: 18067},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 498, col: 11, offset: 18067},
+							label: "First",
+							expr: &seqExpr{
+								pos: position{line: 498, col: 18, offset: 18074},
+								exprs: []any{
+									&litMatcher{
+										pos:        position{line: 498, col: 18, offset: 18074},
+										val:        ".",
+										ignoreCase: false,
+										want:       "\".\"",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 498, col: 22, offset: 18078},
+										name: "LabelName",
+									},
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 498, col: 33, offset: 18089},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 498, col: 38, offset: 18094},
+								expr: &seqExpr{
+									pos: position{line: 498, col: 39, offset: 18095},
+									exprs: []any{
+										&litMatcher{
+											pos:        position{line: 498, col: 39, offset: 18095},
+											val:        ".",
+											ignoreCase: false,
+											want:       "\".\"",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 498, col: 43, offset: 18099},
+											name: "LabelName",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "RemStmt",
+			pos:  position{line: 508, col: 1, offset: 18317},
+			expr: &actionExpr{
+				pos: position{line: 508, col: 12, offset: 18328},
+				run: (*parser).callonRemStmt1,
+				expr: &seqExpr{
+					pos: position{line: 508, col: 12, offset: 18328},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 508, col: 12, offset: 18328},
+							name: "KW_REM",
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 508, col: 19, offset: 18335},
+							expr: &seqExpr{
+								pos: position{line: 508, col: 20, offset: 18336},
+								exprs: []any{
+									&notExpr{
+										pos: position{line: 508, col: 20, offset: 18336},
+										expr: &litMatcher{
+											pos:        position{line: 508, col: 21, offset: 18337},
+											val:        "\n",
+											ignoreCase: false,
+											want:       "\"\\n\"",
+										},
+									},
+									&anyMatcher{
+										line: 508, col: 26, offset: 18342,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "SingleQuoteCommentStmt",
+			pos:  position{line: 512, col: 1, offset: 18399},
+			expr: &actionExpr{
+				pos: position{line: 512, col: 27, offset: 18425},
+				run: (*parser).callonSingleQuoteCommentStmt1,
+				expr: &seqExpr{
+					pos: position{line: 512, col: 27, offset: 18425},
+					exprs: []any{
+						&litMatcher{
+							pos:        position{line: 512, col: 27, offset: 18425},
+							val:        "'",
+							ignoreCase: false,
+							want:       "\"'\"",
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 512, col: 31, offset: 18429},
+							expr: &seqExpr{
+								pos: position{line: 512, col: 32, offset: 18430},
+								exprs: []any{
+									&notExpr{
+										pos: position{line: 512, col: 32, offset: 18430},
+										expr: &litMatcher{
+											pos:        position{line: 512, col: 33, offset: 18431},
+											val:        "\n",
+											ignoreCase: false,
+											want:       "\"\\n\"",
+										},
+									},
+									&anyMatcher{
+										line: 512, col: 38, offset: 18436,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "DimStmt",
+			pos:  position{line: 516, col: 1, offset: 18493},
+			expr: &choiceExpr{
+				pos: position{line: 516, col: 12, offset: 18504},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 516, col: 12, offset: 18504},
+						run: (*parser).callonDimStmt2,
+						expr: &seqExpr{
+							pos: position{line: 516, col: 12, offset: 18504},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 516, col: 12, offset: 18504},
+									name: "KW_DIM",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 516, col: 19, offset: 18511},
+									expr: &charClassMatcher{
+										pos:        position{line: 516, col: 19, offset: 18511},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 516, col: 24, offset: 18516},
+									label: "Name",
+									expr: &ruleRefExpr{
+										pos:  position{line: 516, col: 29, offset: 18521},
+										name: "Identifier",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 516, col: 40, offset: 18532},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 516, col: 44, offset: 18536},
+									label: "Sizes",
+									expr: &ruleRefExpr{
+										pos:  position{line: 516, col: 50, offset: 18542},
+										name: "ExpressionList",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 516, col: 65, offset: 18557},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 516, col: 69, offset: 18561},
+									expr: &charClassMatcher{
+										pos:        position{line: 516, col: 69, offset: 18561},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 516, col: 74, offset: 18566},
+									name: "KW_AS",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 516, col: 80, offset: 18572},
+									expr: &charClassMatcher{
+										pos:        position{line: 516, col: 80, offset: 18572},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 516, col: 85, offset: 18577},
+									label: "Type",
+									expr: &ruleRefExpr{
+										pos:  position{line: 516, col: 90, offset: 18582},
+										name: "LabelName",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 519, col: 13, offset: 18703},
+						run: (*parser).callonDimStmt20,
+						expr: &seqExpr{
+							pos: position{line: 519, col: 13, offset: 18703},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 519, col: 13, offset: 18703},
+									name: "KW_DIM",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 519, col: 20, offset: 18710},
+									expr: &charClassMatcher{
+										pos:        position{line: 519, col: 20, offset: 18710},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 519, col: 25, offset: 18715},
+									label: "Name",
+									expr: &ruleRefExpr{
+										pos:  position{line: 519, col: 30, offset: 18720},
+										name: "Identifier",
+									},
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 519, col: 41, offset: 18731},
+									expr: &charClassMatcher{
+										pos:        position{line: 519, col: 41, offset: 18731},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 519, col: 46, offset: 18736},
+									name: "KW_AS",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 519, col: 52, offset: 18742},
+									expr: &charClassMatcher{
+										pos:        position{line: 519, col: 52, offset: 18742},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 519, col: 57, offset: 18747},
+									label: "Type",
+									expr: &ruleRefExpr{
+										pos:  position{line: 519, col: 62, offset: 18752},
+										name: "LabelName",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 522, col: 13, offset: 18846},
+						run: (*parser).callonDimStmt34,
+						expr: &seqExpr{
+							pos: position{line: 522, col: 13, offset: 18846},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 522, col: 13, offset: 18846},
+									name: "KW_DIM",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 522, col: 20, offset: 18853},
+									expr: &charClassMatcher{
+										pos:        position{line: 522, col: 20, offset: 18853},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 522, col: 25, offset: 18858},
+									label: "Name",
+									expr: &ruleRefExpr{
+										pos:  position{line: 522, col: 30, offset: 18863},
+										name: "Identifier",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 522, col: 41, offset: 18874},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 522, col: 45, offset: 18878},
+									label: "Sizes",
+									expr: &ruleRefExpr{
+										pos:  position{line: 522, col: 51, offset: 18884},
+										name: "ExpressionList",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 522, col: 66, offset: 18899},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "DeleteKeyStmt",
+			pos:  position{line: 526, col: 1, offset: 18982},
+			expr: &actionExpr{
+				pos: position{line: 526, col: 18, offset: 18999},
+				run: (*parser).callonDeleteKeyStmt1,
+				expr: &seqExpr{
+					pos: position{line: 526, col: 18, offset: 18999},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 526, col: 18, offset: 18999},
+							name: "KW_DELETEKEY",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 526, col: 31, offset: 19012},
+							expr: &charClassMatcher{
+								pos:        position{line: 526, col: 31, offset: 19012},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 526, col: 36, offset: 19017},
+							label: "Map",
+							expr: &ruleRefExpr{
+								pos:  position{line: 526, col: 40, offset: 19021},
+								name: "Identifier",
+							},
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 526, col: 51, offset: 19032},
+							expr: &charClassMatcher{
+								pos:        position{line: 526, col: 51, offset: 19032},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 526, col: 56, offset: 19037},
+							val:        ",",
+							ignoreCase: false,
+							want:       "\",\"",
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 526, col: 60, offset: 19041},
+							expr: &charClassMatcher{
+								pos:        position{line: 526, col: 60, offset: 19041},
+								val:        "[ ]",
+								chars:      []rune{' '},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 526, col: 65, offset: 19046},
+							label: "Key",
+							expr: &ruleRefExpr{
+								pos:  position{line: 526, col: 69, offset: 19050},
+								name: "Expression",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "InputStmt",
+			pos:  position{line: 530, col: 1, offset: 19138},
+			expr: &choiceExpr{
+				pos: position{line: 530, col: 14, offset: 19151},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 530, col: 14, offset: 19151},
+						run: (*parser).callonInputStmt2,
+						expr: &seqExpr{
+							pos: position{line: 530, col: 14, offset: 19151},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 530, col: 14, offset: 19151},
+									name: "KW_INPUT",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 530, col: 23, offset: 19160},
+									expr: &charClassMatcher{
+										pos:        position{line: 530, col: 23, offset: 19160},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 530, col: 28, offset: 19165},
+									label: "Prompt",
+									expr: &ruleRefExpr{
+										pos:  position{line: 530, col: 35, offset: 19172},
+										name: "StringLiteral",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 530, col: 49, offset: 19186},
+									expr: &charClassMatcher{
+										pos:        position{line: 530, col: 49, offset: 19186},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 530, col: 54, offset: 19191},
+									label: "Sep",
+									expr: &charClassMatcher{
+										pos:        position{line: 530, col: 58, offset: 19195},
+										val:        "[,;]",
+										chars:      []rune{',', ';'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 530, col: 63, offset: 19200},
+									expr: &charClassMatcher{
+										pos:        position{line: 530, col: 63, offset: 19200},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 530, col: 68, offset: 19205},
+									label: "Vars",
+									expr: &ruleRefExpr{
+										pos:  position{line: 530, col: 73, offset: 19210},
+										name: "InputTargetList",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 533, col: 15, offset: 19379},
+						run: (*parser).callonInputStmt17,
+						expr: &seqExpr{
+							pos: position{line: 533, col: 15, offset: 19379},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 533, col: 15, offset: 19379},
+									name: "KW_INPUT",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 533, col: 24, offset: 19388},
+									expr: &charClassMatcher{
+										pos:        position{line: 533, col: 24, offset: 19388},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 533, col: 29, offset: 19393},
+									label: "Prompt",
+									expr: &ruleRefExpr{
+										pos:  position{line: 533, col: 36, offset: 19400},
+										name: "StringLiteral",
+									},
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 533, col: 50, offset: 19414},
+									expr: &charClassMatcher{
+										pos:        position{line: 533, col: 50, offset: 19414},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 533, col: 55, offset: 19419},
+									label: "Vars",
+									expr: &ruleRefExpr{
+										pos:  position{line: 533, col: 60, offset: 19424},
+										name: "InputTargetList",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 536, col: 15, offset: 19554},
+						run: (*parser).callonInputStmt28,
+						expr: &seqExpr{
+							pos: position{line: 536, col: 15, offset: 19554},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 536, col: 15, offset: 19554},
+									name: "KW_INPUT",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 536, col: 24, offset: 19563},
+									expr: &charClassMatcher{
+										pos:        position{line: 536, col: 24, offset: 19563},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 536, col: 29, offset: 19568},
+									label: "Vars",
+									expr: &ruleRefExpr{
+										pos:  position{line: 536, col: 34, offset: 19573},
+										name: "InputTargetList",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "LineInputStmt",
+			pos:  position{line: 541, col: 1, offset: 19716},
+			expr: &choiceExpr{
+				pos: position{line: 541, col: 18, offset: 19733},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 541, col: 18, offset: 19733},
+						run: (*parser).callonLineInputStmt2,
+						expr: &seqExpr{
+							pos: position{line: 541, col: 18, offset: 19733},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 541, col: 18, offset: 19733},
+									name: "KW_LINE",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 541, col: 26, offset: 19741},
+									expr: &charClassMatcher{
+										pos:        position{line: 541, col: 26, offset: 19741},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 541, col: 31, offset: 19746},
+									name: "KW_INPUT",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 541, col: 40, offset: 19755},
+									expr: &charClassMatcher{
+										pos:        position{line: 541, col: 40, offset: 19755},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 541, col: 45, offset: 19760},
+									label: "Prompt",
+									expr: &ruleRefExpr{
+										pos:  position{line: 541, col: 52, offset: 19767},
+										name: "StringLiteral",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 541, col: 66, offset: 19781},
+									expr: &charClassMatcher{
+										pos:        position{line: 541, col: 66, offset: 19781},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 541, col: 71, offset: 19786},
+									label: "Sep",
+									expr: &charClassMatcher{
+										pos:        position{line: 541, col: 75, offset: 19790},
+										val:        "[,;]",
+										chars:      []rune{',', ';'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 541, col: 80, offset: 19795},
+									expr: &charClassMatcher{
+										pos:        position{line: 541, col: 80, offset: 19795},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 541, col: 85, offset: 19800},
+									label: "Var",
+									expr: &ruleRefExpr{
+										pos:  position{line: 541, col: 89, offset: 19804},
+										name: "InputTarget",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 544, col: 15, offset: 19990},
+						run: (*parser).callonLineInputStmt20,
+						expr: &seqExpr{
+							pos: position{line: 544, col: 15, offset: 19990},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 544, col: 15, offset: 19990},
+									name: "KW_LINE",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 544, col: 23, offset: 19998},
+									expr: &charClassMatcher{
+										pos:        position{line: 544, col: 23, offset: 19998},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 544, col: 28, offset: 20003},
+									name: "KW_INPUT",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 544, col: 37, offset: 20012},
+									expr: &charClassMatcher{
+										pos:        position{line: 544, col: 37, offset: 20012},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 544, col: 42, offset: 20017},
+									label: "Var",
+									expr: &ruleRefExpr{
+										pos:  position{line: 544, col: 46, offset: 20021},
+										name: "InputTarget",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "InputTargetList",
+			pos:  position{line: 548, col: 1, offset: 20112},
+			expr: &actionExpr{
+				pos: position{line: 548, col: 20, offset: 20131},
+				run: (*parser).callonInputTargetList1,
+				expr: &seqExpr{
+					pos: position{line: 548, col: 20, offset: 20131},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 548, col: 20, offset: 20131},
+							label: "First",
+							expr: &ruleRefExpr{
+								pos:  position{line: 548, col: 26, offset: 20137},
+								name: "InputTarget",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 548, col: 38, offset: 20149},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 548, col: 43, offset: 20154},
+								expr: &seqExpr{
+									pos: position{line: 548, col: 44, offset: 20155},
+									exprs: []any{
+										&zeroOrMoreExpr{
+											pos: position{line: 548, col: 44, offset: 20155},
+											expr: &charClassMatcher{
+												pos:        position{line: 548, col: 44, offset: 20155},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&litMatcher{
+											pos:        position{line: 548, col: 49, offset: 20160},
+											val:        ",",
+											ignoreCase: false,
+											want:       "\",\"",
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 548, col: 53, offset: 20164},
+											expr: &charClassMatcher{
+												pos:        position{line: 548, col: 53, offset: 20164},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 548, col: 58, offset: 20169},
+											name: "InputTarget",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "InputTarget",
+			pos:  position{line: 561, col: 1, offset: 20516},
+			expr: &choiceExpr{
+				pos: position{line: 561, col: 16, offset: 20531},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 561, col: 16, offset: 20531},
+						run: (*parser).callonInputTarget2,
+						expr: &seqExpr{
+							pos: position{line: 561, col: 16, offset: 20531},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 561, col: 16, offset: 20531},
+									label: "id",
+									expr: &ruleRefExpr{
+										pos:  position{line: 561, col: 19, offset: 20534},
+										name: "Identifier",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 561, col: 30, offset: 20545},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 561, col: 34, offset: 20549},
+									label: "args",
+									expr: &ruleRefExpr{
+										pos:  position{line: 561, col: 39, offset: 20554},
+										name: "ExpressionList",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 561, col: 54, offset: 20569},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 561, col: 58, offset: 20573},
+									label: "fields",
+									expr: &ruleRefExpr{
+										pos:  position{line: 561, col: 65, offset: 20580},
+										name: "Fields",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 564, col: 13, offset: 20701},
+						run: (*parser).callonInputTarget12,
+						expr: &seqExpr{
+							pos: position{line: 564, col: 13, offset: 20701},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 564, col: 13, offset: 20701},
+									label: "id",
+									expr: &ruleRefExpr{
+										pos:  position{line: 564, col: 16, offset: 20704},
+										name: "Identifier",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 564, col: 27, offset: 20715},
+									label: "fields",
+									expr: &ruleRefExpr{
+										pos:  position{line: 564, col: 34, offset: 20722},
+										name: "Fields",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 567, col: 13, offset: 20814},
+						run: (*parser).callonInputTarget18,
+						expr: &seqExpr{
+							pos: position{line: 567, col: 13, offset: 20814},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 567, col: 13, offset: 20814},
+									label: "id",
+									expr: &ruleRefExpr{
+										pos:  position{line: 567, col: 16, offset: 20817},
+										name: "Identifier",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 567, col: 27, offset: 20828},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 567, col: 31, offset: 20832},
+									label: "args",
+									expr: &ruleRefExpr{
+										pos:  position{line: 567, col: 36, offset: 20837},
+										name: "ExpressionList",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 567, col: 51, offset: 20852},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 570, col: 13, offset: 20949},
+						run: (*parser).callonInputTarget26,
+						expr: &labeledExpr{
+							pos:   position{line: 570, col: 13, offset: 20949},
+							label: "id",
+							expr: &ruleRefExpr{
+								pos:  position{line: 570, col: 16, offset: 20952},
+								name: "Identifier",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Expression",
+			pos:  position{line: 578, col: 1, offset: 21188},
+			expr: &ruleRefExpr{
+				pos:  position{line: 578, col: 15, offset: 21202},
+				name: "LogicalNot",
+			},
+		},
+		{
+			name: "LogicalNot",
+			pos:  position{line: 580, col: 1, offset: 21214},
+			expr: &choiceExpr{
+				pos: position{line: 580, col: 15, offset: 21228},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 580, col: 15, offset: 21228},
+						run: (*parser).callonLogicalNot2,
+						expr: &seqExpr{
+							pos: position{line: 580, col: 15, offset: 21228},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 580, col: 15, offset: 21228},
+									name: "KW_NOT",
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 580, col: 22, offset: 21235},
+									expr: &charClassMatcher{
+										pos:        position{line: 580, col: 22, offset: 21235},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 580, col: 27, offset: 21240},
+									label: "Right",
+									expr: &ruleRefExpr{
+										pos:  position{line: 580, col: 33, offset: 21246},
+										name: "LogicalOr",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 583, col: 15, offset: 21336},
+						name: "LogicalOr",
+					},
+				},
+			},
+		},
+		{
+			name: "LogicalOr",
+			pos:  position{line: 585, col: 1, offset: 21347},
+			expr: &actionExpr{
+				pos: position{line: 585, col: 14, offset: 21360},
+				run: (*parser).callonLogicalOr1,
+				expr: &seqExpr{
+					pos: position{line: 585, col: 14, offset: 21360},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 585, col: 14, offset: 21360},
+							label: "Left",
+							expr: &ruleRefExpr{
+								pos:  position{line: 585, col: 19, offset: 21365},
+								name: "LogicalAnd",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 585, col: 30, offset: 21376},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 585, col: 35, offset: 21381},
+								expr: &seqExpr{
+									pos: position{line: 585, col: 37, offset: 21383},
+									exprs: []any{
+										&zeroOrMoreExpr{
+											pos: position{line: 585, col: 37, offset: 21383},
+											expr: &charClassMatcher{
+												pos:        position{line: 585, col: 37, offset: 21383},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 585, col: 42, offset: 21388},
+											name: "KW_OR",
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 585, col: 48, offset: 21394},
+											expr: &charClassMatcher{
+												pos:        position{line: 585, col: 48, offset: 21394},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&labeledExpr{
+											pos:   position{line: 585, col: 53, offset: 21399},
+											label: "Right",
+											expr: &ruleRefExpr{
+												pos:  position{line: 585, col: 59, offset: 21405},
+												name: "LogicalAnd",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "LogicalAnd",
+			pos:  position{line: 589, col: 1, offset: 21477},
+			expr: &actionExpr{
+				pos: position{line: 589, col: 15, offset: 21491},
+				run: (*parser).callonLogicalAnd1,
+				expr: &seqExpr{
+					pos: position{line: 589, col: 15, offset: 21491},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 589, col: 15, offset: 21491},
+							label: "Left",
+							expr: &ruleRefExpr{
+								pos:  position{line: 589, col: 20, offset: 21496},
+								name: "Comparison",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 589, col: 31, offset: 21507},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 589, col: 36, offset: 21512},
+								expr: &seqExpr{
+									pos: position{line: 589, col: 38, offset: 21514},
+									exprs: []any{
+										&zeroOrMoreExpr{
+											pos: position{line: 589, col: 38, offset: 21514},
+											expr: &charClassMatcher{
+												pos:        position{line: 589, col: 38, offset: 21514},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 589, col: 43, offset: 21519},
+											name: "KW_AND",
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 589, col: 50, offset: 21526},
+											expr: &charClassMatcher{
+												pos:        position{line: 589, col: 50, offset: 21526},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&labeledExpr{
+											pos:   position{line: 589, col: 55, offset: 21531},
+											label: "Right",
+											expr: &ruleRefExpr{
+												pos:  position{line: 589, col: 61, offset: 21537},
+												name: "Comparison",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Comparison",
+			pos:  position{line: 593, col: 1, offset: 21610},
+			expr: &choiceExpr{
+				pos: position{line: 593, col: 15, offset: 21624},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 593, col: 15, offset: 21624},
+						run: (*parser).callonComparison2,
+						expr: &seqExpr{
+							pos: position{line: 593, col: 15, offset: 21624},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 593, col: 15, offset: 21624},
+									label: "Left",
+									expr: &ruleRefExpr{
+										pos:  position{line: 593, col: 20, offset: 21629},
+										name: "Additive",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 593, col: 29, offset: 21638},
+									expr: &charClassMatcher{
+										pos:        position{line: 593, col: 29, offset: 21638},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 593, col: 34, offset: 21643},
+									label: "Op",
+									expr: &choiceExpr{
+										pos: position{line: 593, col: 38, offset: 21647},
+										alternatives: []any{
+											&litMatcher{
+												pos:        position{line: 593, col: 38, offset: 21647},
+												val:        ">=",
+												ignoreCase: false,
+												want:       "\">=\"",
+											},
+											&litMatcher{
+												pos:        position{line: 593, col: 45, offset: 21654},
+												val:        "<=",
+												ignoreCase: false,
+												want:       "\"<=\"",
+											},
+											&litMatcher{
+												pos:        position{line: 593, col: 52, offset: 21661},
+												val:        "<>",
+												ignoreCase: false,
+												want:       "\"<>\"",
+											},
+											&litMatcher{
+												pos:        position{line: 593, col: 59, offset: 21668},
+												val:        "=",
+												ignoreCase: false,
+												want:       "\"=\"",
+											},
+											&litMatcher{
+												pos:        position{line: 593, col: 65, offset: 21674},
+												val:        ">",
+												ignoreCase: false,
+												want:       "\">\"",
+											},
+											&litMatcher{
+												pos:        position{line: 593, col: 71, offset: 21680},
+												val:        "<",
+												ignoreCase: false,
+												want:       "\"<\"",
+											},
+										},
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 593, col: 76, offset: 21685},
+									expr: &charClassMatcher{
+										pos:        position{line: 593, col: 76, offset: 21685},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 593, col: 81, offset: 21690},
+									label: "Right",
+									expr: &ruleRefExpr{
+										pos:  position{line: 593, col: 87, offset: 21696},
+										name: "Additive",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 596, col: 15, offset: 21827},
+						run: (*parser).callonComparison20,
+						expr: &labeledExpr{
+							pos:   position{line: 596, col: 15, offset: 21827},
+							label: "Left",
+							expr: &ruleRefExpr{
+								pos:  position{line: 596, col: 20, offset: 21832},
+								name: "Additive",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Additive",
+			pos:  position{line: 600, col: 1, offset: 21875},
+			expr: &actionExpr{
+				pos: position{line: 600, col: 13, offset: 21887},
+				run: (*parser).callonAdditive1,
+				expr: &seqExpr{
+					pos: position{line: 600, col: 13, offset: 21887},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 600, col: 13, offset: 21887},
+							label: "Left",
+							expr: &ruleRefExpr{
+								pos:  position{line: 600, col: 18, offset: 21892},
+								name: "Multiplicative",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 600, col: 33, offset: 21907},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 600, col: 38, offset: 21912},
+								expr: &seqExpr{
+									pos: position{line: 600, col: 40, offset: 21914},
+									exprs: []any{
+										&zeroOrMoreExpr{
+											pos: position{line: 600, col: 40, offset: 21914},
+											expr: &charClassMatcher{
+												pos:        position{line: 600, col: 40, offset: 21914},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&choiceExpr{
+											pos: position{line: 600, col: 46, offset: 21920},
+											alternatives: []any{
+												&litMatcher{
+													pos:        position{line: 600, col: 46, offset: 21920},
+													val:        "+",
+													ignoreCase: false,
+													want:       "\"+\"",
+												},
+												&litMatcher{
+													pos:        position{line: 600, col: 52, offset: 21926},
+													val:        "-",
+													ignoreCase: false,
+													want:       "\"-\"",
+												},
+											},
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 600, col: 57, offset: 21931},
+											expr: &charClassMatcher{
+												pos:        position{line: 600, col: 57, offset: 21931},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&labeledExpr{
+											pos:   position{line: 600, col: 62, offset: 21936},
+											label: "Right",
+											expr: &ruleRefExpr{
+												pos:  position{line: 600, col: 68, offset: 21942},
+												name: "Multiplicative",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Multiplicative",
+			pos:  position{line: 604, col: 1, offset: 22011},
+			expr: &actionExpr{
+				pos: position{line: 604, col: 19, offset: 22029},
+				run: (*parser).callonMultiplicative1,
+				expr: &seqExpr{
+					pos: position{line: 604, col: 19, offset: 22029},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 604, col: 19, offset: 22029},
+							label: "Left",
+							expr: &ruleRefExpr{
+								pos:  position{line: 604, col: 24, offset: 22034},
+								name: "Power",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 604, col: 30, offset: 22040},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 604, col: 35, offset: 22045},
+								expr: &seqExpr{
+									pos: position{line: 604, col: 37, offset: 22047},
+									exprs: []any{
+										&zeroOrMoreExpr{
+											pos: position{line: 604, col: 37, offset: 22047},
+											expr: &charClassMatcher{
+												pos:        position{line: 604, col: 37, offset: 22047},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&choiceExpr{
+											pos: position{line: 604, col: 43, offset: 22053},
+											alternatives: []any{
+												&litMatcher{
+													pos:        position{line: 604, col: 43, offset: 22053},
+													val:        "*",
+													ignoreCase: false,
+													want:       "\"*\"",
+												},
+												&litMatcher{
+													pos:        position{line: 604, col: 49, offset: 22059},
+													val:        "/",
+													ignoreCase: false,
+													want:       "\"/\"",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 604, col: 55, offset: 22065},
+													name: "KW_MOD",
+												},
+											},
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 604, col: 63, offset: 22073},
+											expr: &charClassMatcher{
+												pos:        position{line: 604, col: 63, offset: 22073},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&labeledExpr{
+											pos:   position{line: 604, col: 68, offset: 22078},
+											label: "Right",
+											expr: &ruleRefExpr{
+												pos:  position{line: 604, col: 74, offset: 22084},
+												name: "Power",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Power",
+			pos:  position{line: 609, col: 1, offset: 22208},
+			expr: &choiceExpr{
+				pos: position{line: 609, col: 10, offset: 22217},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 609, col: 10, offset: 22217},
+						run: (*parser).callonPower2,
+						expr: &seqExpr{
+							pos: position{line: 609, col: 10, offset: 22217},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 609, col: 10, offset: 22217},
+									label: "Left",
+									expr: &ruleRefExpr{
+										pos:  position{line: 609, col: 15, offset: 22222},
+										name: "Unary",
+									},
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 609, col: 21, offset: 22228},
+									expr: &charClassMatcher{
+										pos:        position{line: 609, col: 21, offset: 22228},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 609, col: 26, offset: 22233},
+									val:        "^",
+									ignoreCase: false,
+									want:       "\"^\"",
+								},
+								&zeroOrMoreExpr{
+									pos: position{line: 609, col: 30, offset: 22237},
+									expr: &charClassMatcher{
+										pos:        position{line: 609, col: 30, offset: 22237},
+										val:        "[ ]",
+										chars:      []rune{' '},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 609, col: 35, offset: 22242},
+									label: "Right",
+									expr: &ruleRefExpr{
+										pos:  position{line: 609, col: 41, offset: 22248},
+										name: "Power",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 612, col: 9, offset: 22350},
+						name: "Unary",
+					},
+				},
+			},
+		},
+		{
+			name: "Unary",
+			pos:  position{line: 614, col: 1, offset: 22357},
+			expr: &choiceExpr{
+				pos: position{line: 614, col: 10, offset: 22366},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 614, col: 10, offset: 22366},
+						run: (*parser).callonUnary2,
+						expr: &seqExpr{
+							pos: position{line: 614, col: 10, offset: 22366},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 614, col: 10, offset: 22366},
+									label: "op",
+									expr: &choiceExpr{
+										pos: position{line: 614, col: 14, offset: 22370},
+										alternatives: []any{
+											&litMatcher{
+												pos:        position{line: 614, col: 14, offset: 22370},
+												val:        "+",
+												ignoreCase: false,
+												want:       "\"+\"",
+											},
+											&litMatcher{
+												pos:        position{line: 614, col: 20, offset: 22376},
+												val:        "-",
+												ignoreCase: false,
+												want:       "\"-\"",
+											},
+										},
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 614, col: 25, offset: 22381},
+									label: "operand",
+									expr: &ruleRefExpr{
+										pos:  position{line: 614, col: 33, offset: 22389},
+										name: "Unary",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 617, col: 9, offset: 22485},
+						name: "Primary",
+					},
+				},
+			},
+		},
+		{
+			name: "ExpressionList",
+			pos:  position{line: 619, col: 1, offset: 22494},
+			expr: &actionExpr{
+				pos: position{line: 619, col: 19, offset: 22512},
+				run: (*parser).callonExpressionList1,
+				expr: &seqExpr{
+					pos: position{line: 619, col: 19, offset: 22512},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 619, col: 19, offset: 22512},
+							label: "First",
+							expr: &ruleRefExpr{
+								pos:  position{line: 619, col: 25, offset: 22518},
+								name: "Expression",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 619, col: 36, offset: 22529},
+							label: "Rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 619, col: 41, offset: 22534},
+								expr: &seqExpr{
+									pos: position{line: 619, col: 42, offset: 22535},
+									exprs: []any{
+										&litMatcher{
+											pos:        position{line: 619, col: 42, offset: 22535},
+											val:        ",",
+											ignoreCase: false,
+											want:       "\",\"",
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 619, col: 46, offset: 22539},
+											expr: &charClassMatcher{
+												pos:        position{line: 619, col: 46, offset: 22539},
+												val:        "[ ]",
+												chars:      []rune{' '},
+												ignoreCase: false,
+												inverted:   false,
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 619, col: 51, offset: 22544},
+											name: "Expression",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Primary",
+			pos:  position{line: 639, col: 1, offset: 23070},
+			expr: &choiceExpr{
+				pos: position{line: 639, col: 12, offset: 23081},
+				alternatives: []any{
+					&ruleRefExpr{
+						pos:  position{line: 639, col: 12, offset: 23081},
+						name: "Number",
+					},
+					&actionExpr{
+						pos: position{line: 640, col: 13, offset: 23100},
+						run: (*parser).callonPrimary3,
+						expr: &seqExpr{
+							pos: position{line: 640, col: 13, offset: 23100},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 640, col: 13, offset: 23100},
+									label: "id",
+									expr: &ruleRefExpr{
+										pos:  position{line: 640, col: 16, offset: 23103},
+										name: "Identifier",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 640, col: 27, offset: 23114},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 640, col: 31, offset: 23118},
+									label: "args",
+									expr: &ruleRefExpr{
+										pos:  position{line: 640, col: 36, offset: 23123},
+										name: "ExpressionList",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 640, col: 51, offset: 23138},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 640, col: 55, offset: 23142},
+									label: "fields",
+									expr: &ruleRefExpr{
+										pos:  position{line: 640, col: 62, offset: 23149},
+										name: "Fields",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 644, col: 13, offset: 23321},
+						run: (*parser).callonPrimary13,
+						expr: &seqExpr{
+							pos: position{line: 644, col: 13, offset: 23321},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 644, col: 13, offset: 23321},
+									label: "id",
+									expr: &ruleRefExpr{
+										pos:  position{line: 644, col: 16, offset: 23324},
+										name: "Identifier",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 644, col: 27, offset: 23335},
+									label: "fields",
+									expr: &ruleRefExpr{
+										pos:  position{line: 644, col: 34, offset: 23342},
+										name: "Fields",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 647, col: 13, offset: 23434},
+						run: (*parser).callonPrimary19,
+						expr: &seqExpr{
+							pos: position{line: 647, col: 13, offset: 23434},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 647, col: 13, offset: 23434},
+									label: "id",
+									expr: &ruleRefExpr{
+										pos:  position{line: 647, col: 16, offset: 23437},
+										name: "Identifier",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 647, col: 27, offset: 23448},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 647, col: 31, offset: 23452},
+									label: "args",
+									expr: &ruleRefExpr{
+										pos:  position{line: 647, col: 36, offset: 23457},
+										name: "ExpressionList",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 647, col: 51, offset: 23472},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 655, col: 13, offset: 23751},
+						run: (*parser).callonPrimary27,
+						expr: &seqExpr{
+							pos: position{line: 655, col: 13, offset: 23751},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 655, col: 13, offset: 23751},
+									label: "id",
+									expr: &ruleRefExpr{
+										pos:  position{line: 655, col: 16, offset: 23754},
+										name: "Identifier",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 655, col: 27, offset: 23765},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&litMatcher{
+									pos:        position{line: 655, col: 31, offset: 23769},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 659, col: 13, offset: 23912},
+						run: (*parser).callonPrimary33,
+						expr: &labeledExpr{
+							pos:   position{line: 659, col: 13, offset: 23912},
+							label: "id",
+							expr: &ruleRefExpr{
+								pos:  position{line: 659, col: 16, offset: 23915},
+								name: "Identifier",
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 662, col: 13, offset: 23990},
+						name: "StringLiteral",
+					},
+					&actionExpr{
+						pos: position{line: 663, col: 13, offset: 24016},
+						run: (*parser).callonPrimary37,
+						expr: &seqExpr{
+							pos: position{line: 663, col: 13, offset: 24016},
+							exprs: []any{
+								&litMatcher{
+									pos:        position{line: 663, col: 13, offset: 24016},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 663, col: 17, offset: 24020},
+									label: "expr",
+									expr: &ruleRefExpr{
+										pos:  position{line: 663, col: 22, offset: 24025},
+										name: "Expression",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 663, col: 33, offset: 24036},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Number",
+			pos:  position{line: 671, col: 1, offset: 24214},
+			expr: &actionExpr{
+				pos: position{line: 671, col: 11, offset: 24224},
+				run: (*parser).callonNumber1,
+				expr: &seqExpr{
+					pos: position{line: 671, col: 11, offset: 24224},
+					exprs: []any{
+						&oneOrMoreExpr{
+							pos: position{line: 671, col: 11, offset: 24224},
+							expr: &charClassMatcher{
+								pos:        position{line: 671, col: 11, offset: 24224},
+								val:        "[0-9]",
+								ranges:     []rune{'0', '9'},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 671, col: 18, offset: 24231},
+							expr: &seqExpr{
+								pos: position{line: 671, col: 19, offset: 24232},
+								exprs: []any{
+									&litMatcher{
+										pos:        position{line: 671, col: 19, offset: 24232},
+										val:        ".",
+										ignoreCase: false,
+										want:       "\".\"",
+									},
+									&oneOrMoreExpr{
+										pos: position{line: 671, col: 23, offset: 24236},
+										expr: &charClassMatcher{
+											pos:        position{line: 671, col: 23, offset: 24236},
+											val:        "[0-9]",
+											ranges:     []rune{'0', '9'},
+											ignoreCase: false,
+											inverted:   false,
+										},
+									},
+								},
+							},
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 671, col: 32, offset: 24245},
+							expr: &seqExpr{
+								pos: position{line: 671, col: 33, offset: 24246},
+								exprs: []any{
+									&charClassMatcher{
+										pos:        position{line: 671, col: 33, offset: 24246},
+										val:        "[eE]",
+										chars:      []rune{'e', 'E'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+									&zeroOrOneExpr{
+										pos: position{line: 671, col: 38, offset: 24251},
+										expr: &charClassMatcher{
+											pos:        position{line: 671, col: 38, offset: 24251},
+											val:        "[+-]",
+											chars:      []rune{'+', '-'},
+											ignoreCase: false,
+											inverted:   false,
+										},
+									},
+									&oneOrMoreExpr{
+										pos: position{line: 671, col: 44, offset: 24257},
+										expr: &charClassMatcher{
+											pos:        position{line: 671, col: 44, offset: 24257},
+											val:        "[0-9]",
+											ranges:     []rune{'0', '9'},
+											ignoreCase: false,
+											inverted:   false,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "StringLiteral",
+			pos:  position{line: 676, col: 1, offset: 24354},
+			expr: &actionExpr{
+				pos: position{line: 676, col: 18, offset: 24371},
+				run: (*parser).callonStringLiteral1,
+				expr: &seqExpr{
+					pos: position{line: 676, col: 18, offset: 24371},
+					exprs: []any{
+						&litMatcher{
+							pos:        position{line: 676, col: 18, offset: 24371},
+							val:        "\"",
+							ignoreCase: false,
+							want:       "\"\\\"\"",
+						},
+						&labeledExpr{
+							pos:   position{line: 676, col: 22, offset: 24375},
+							label: "Text",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 676, col: 27, offset: 24380},
+								expr: &charClassMatcher{
+									pos:        position{line: 676, col: 27, offset: 24380},
+									val:        "[^\"]",
+									chars:      []rune{'"'},
+									ignoreCase: false,
+									inverted:   true,
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 676, col: 33, offset: 24386},
+							val:        "\"",
+							ignoreCase: false,
+							want:       "\"\\\"\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Identifier",
+			pos:  position{line: 690, col: 1, offset: 24653},
+			expr: &actionExpr{
+				pos: position{line: 690, col: 15, offset: 24667},
+				run: (*parser).callonIdentifier1,
+				expr: &seqExpr{
+					pos: position{line: 690, col: 15, offset: 24667},
+					exprs: []any{
+						&charClassMatcher{
+							pos:        position{line: 690, col: 15, offset: 24667},
+							val:        "[A-Za-z_]",
+							chars:      []rune{'_'},
+							ranges:     []rune{'A', 'Z', 'a', 'z'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 690, col: 24, offset: 24676},
+							expr: &charClassMatcher{
+								pos:        position{line: 690, col: 24, offset: 24676},
+								val:        "[A-Za-z0-9_$]",
+								chars:      []rune{'_', '$'},
+								ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func (c *current) onProgram1(Lines any) (any, error) {
+	return &ast.Program{Lines: toLineSliceFromAny(Lines)}, nil
+}
+
+func (p *parser) callonProgram1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onProgram1(stack["Lines"])
+}
+
+func (c *current) onLine2(LineNumber, Label, Statements any) (any, error) {
+	return newLine(c, LineNumber.(int), Label, Statements), nil
+}
+
+func (p *parser) callonLine2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLine2(stack["LineNumber"], stack["Label"], stack["Statements"])
+}
+
+func (c *current) onLine19(Label, Statements any) (any, error) {
+	return newLine(c, -1, Label, Statements), nil
+}
+
+func (p *parser) callonLine19() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLine19(stack["Label"], stack["Statements"])
+}
+
+func (c *current) onLabelDef1(Name any) (any, error) {
+	return Name, nil
+}
+
+func (p *parser) callonLabelDef1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLabelDef1(stack["Name"])
+}
+
+func (c *current) onLabelName1() (any, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonLabelName1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLabelName1()
+}
+
+func (c *current) onStatementList1(First, Rest any) (any, error) {
+	values := []ast.Node{First.(ast.Node)}
+	if Rest != nil {
+		for _, v := range Rest.([]interface{}) {
+			seq := v.([]interface{})
+			// seq[0] = ':', seq[1] = [ ]*, seq[2] = Statement
+			values = append(values, seq[2].(ast.Node))
+		}
+	}
+	return values, nil
+}
+
+func (p *parser) callonStatementList1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onStatementList1(stack["First"], stack["Rest"])
+}
+
+func (c *current) onLineNumber1() (any, error) {
+	n, _ := strconv.Atoi(string(c.text))
+	return n, nil
+}
+
+func (p *parser) callonLineNumber1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLineNumber1()
+}
+
+func (c *current) onNonEmptyPrintStmt1(Args any) (any, error) {
+	result := Args.([]interface{})
+	values := result[0].([]ast.Node)
+	separators := []string{}
+	if len(result) > 1 {
+		separators = result[1].([]string)
+	}
+	return &ast.PrintStmt{Values: values, Separators: separators}, nil
+}
+
+func (p *parser) callonNonEmptyPrintStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNonEmptyPrintStmt1(stack["Args"])
+}
+
+func (c *current) onAssignment2(Target, Value any) (any, error) {
+	return &ast.Assignment{Target: Target.(ast.Node), Value: Value.(ast.Node)}, nil
+}
+
+func (p *parser) callonAssignment2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onAssignment2(stack["Target"], stack["Value"])
+}
+
+func (c *current) onAssignment16(Target, Value any) (any, error) {
+	return &ast.Assignment{Target: Target.(ast.Node), Value: Value.(ast.Node)}, nil
+}
+
+func (p *parser) callonAssignment16() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onAssignment16(stack["Target"], stack["Value"])
+}
+
+func (c *current) onPrintStmt1(Args, Trailer any) (any, error) {
+	values := []ast.Node{}
+	separators := []string{}
+	if Args != nil {
+		result := Args.([]interface{})
+		values = result[0].([]ast.Node)
+		if len(result) > 1 {
+			separators = result[1].([]string)
+		}
+	}
+	trailer := ""
+	if Trailer != nil {
+		trailer = string(Trailer.([]uint8))
+	}
+	return &ast.PrintStmt{Values: values, Separators: separators, Trailer: trailer}, nil
+}
+
+func (p *parser) callonPrintStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrintStmt1(stack["Args"], stack["Trailer"])
+}
+
+func (c *current) onPrintArgList1(First, Rest any) (any, error) {
+	values := []ast.Node{First.(ast.Node)}
+	separators := []string{}
+	if Rest != nil {
+		for _, v := range Rest.([]interface{}) {
+			seq := v.([]interface{})
+			// seq[0] 是分隔符，seq[2] 是 PrintArg
+			sep := string(seq[0].([]uint8))
+			separators = append(separators, sep)
+			values = append(values, seq[2].(ast.Node))
+		}
+	}
+	return []interface{}{values, separators}, nil
+}
+
+func (p *parser) callonPrintArgList1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrintArgList1(stack["First"], stack["Rest"])
+}
+
+func (c *current) onIfStmt2(Condition any) (any, error) {
+	// 多行空 IF 语句
+	return &ast.IfStmt{
+		Condition: Condition.(ast.Node),
+		ThenStmts: []ast.Node{},
+		ElseStmts: []ast.Node{},
+	}, nil
+}
+
+func (p *parser) callonIfStmt2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfStmt2(stack["Condition"])
+}
+
+func (c *current) onIfStmt18(Condition, ThenStmts any) (any, error) {
+	// 多行 IF...END IF 语句（无 ELSE）
+	thenStmts := toNodeSliceFromAny(ThenStmts)
+	return &ast.IfStmt{
+		Condition: Condition.(ast.Node),
+		ThenStmts: thenStmts,
+		ElseStmts: []ast.Node{},
+	}, nil
+}
+
+func (p *parser) callonIfStmt18() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfStmt18(stack["Condition"], stack["ThenStmts"])
+}
+
+func (c *current) onIfStmt39(Condition, ThenStmts, ElseStmts any) (any, error) {
+	// 多行 IF...ELSE...END IF 语句
+	thenStmts := toNodeSliceFromAny(ThenStmts)
+	elseStmts := toNodeSliceFromAny(ElseStmts)
+	return &ast.IfStmt{
+		Condition: Condition.(ast.Node),
+		ThenStmts: thenStmts,
+		ElseStmts: elseStmts,
+	}, nil
+}
+
+func (p *parser) callonIfStmt39() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfStmt39(stack["Condition"], stack["ThenStmts"], stack["ElseStmts"])
+}
+
+func (c *current) onIfStmt68(Condition, FirstThenArg, ThenRest, FirstElseArg, ElseRest any) (any, error) {
+	// 单行 IF...THEN PRINT...ELSE PRINT 语句
+	thenValues := []ast.Node{FirstThenArg.(ast.Node)}
+	thenSeps := []string{}
+	if ThenRest != nil {
+		for _, v := range ThenRest.([]interface{}) {
+			seq := v.([]interface{})
+			sep := string(seq[0].([]uint8))
+			thenSeps = append(thenSeps, sep)
+			thenValues = append(thenValues, seq[2].(ast.Node))
+		}
+	}
+	elseValues := []ast.Node{FirstElseArg.(ast.Node)}
+	elseSeps := []string{}
+	if ElseRest != nil {
+		for _, v := range ElseRest.([]interface{}) {
+			seq := v.([]interface{})
+			sep := string(seq[0].([]uint8))
+			elseSeps = append(elseSeps, sep)
+			elseValues = append(elseValues, seq[2].(ast.Node))
+		}
+	}
+	return &ast.IfStmt{
+		Condition: Condition.(ast.Node),
+		ThenStmts: []ast.Node{&ast.PrintStmt{Values: thenValues, Separators: thenSeps}},
+		ElseStmts: []ast.Node{&ast.PrintStmt{Values: elseValues, Separators: elseSeps}},
+	}, nil
+}
+
+func (p *parser) callonIfStmt68() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfStmt68(stack["Condition"], stack["FirstThenArg"], stack["ThenRest"], stack["FirstElseArg"], stack["ElseRest"])
+}
+
+func (c *current) onIfStmt113(Condition, PrintArgs any) (any, error) {
+	// 单行 IF...THEN PRINT 语句
+	result := PrintArgs.([]interface{})
+	values := result[0].([]ast.Node)
+	separators := []string{}
+	if len(result) > 1 {
+		separators = result[1].([]string)
+	}
+	return &ast.IfStmt{
+		Condition: Condition.(ast.Node),
+		ThenStmts: []ast.Node{&ast.PrintStmt{Values: values, Separators: separators}},
+		ElseStmts: []ast.Node{},
+	}, nil
+}
+
+func (p *parser) callonIfStmt113() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfStmt113(stack["Condition"], stack["PrintArgs"])
+}
+
+func (c *current) onIfStmt130(Condition, ThenStmt, ElseStmt any) (any, error) {
+	// 单行 IF...THEN...ELSE
+	return &ast.IfStmt{
+		Condition: Condition.(ast.Node),
+		ThenStmts: []ast.Node{ThenStmt.(ast.Node)},
+		ElseStmts: []ast.Node{ElseStmt.(ast.Node)},
+	}, nil
+}
+
+func (p *parser) callonIfStmt130() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfStmt130(stack["Condition"], stack["ThenStmt"], stack["ElseStmt"])
+}
+
+func (c *current) onIfStmt151(Condition, ThenStmt any) (any, error) {
+	// 单行 IF...THEN
+	return &ast.IfStmt{
+		Condition: Condition.(ast.Node),
+		ThenStmts: []ast.Node{ThenStmt.(ast.Node)},
+		ElseStmts: []ast.Node{},
+	}, nil
+}
+
+func (p *parser) callonIfStmt151() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfStmt151(stack["Condition"], stack["ThenStmt"])
+}
+
+func (c *current) onIfBlockStmt1(Condition any) (any, error) {
+	return &ast.IfBlockStmt{Condition: Condition.(ast.Node)}, nil
+}
+
+func (p *parser) callonIfBlockStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIfBlockStmt1(stack["Condition"])
+}
+
+func (c *current) onElseBlockStmt1() (any, error) {
+	return &ast.ElseBlockStmt{}, nil
+}
+
+func (p *parser) callonElseBlockStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onElseBlockStmt1()
+}
+
+func (c *current) onEndIfStmt1() (any, error) {
+	return &ast.EndIfStmt{}, nil
+}
+
+func (p *parser) callonEndIfStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onEndIfStmt1()
+}
+
+func (c *current) onForStmt2(Var, Start, End, StepExpr any) (any, error) {
+	return &ast.ForStmt{
+		Var:   Var.(string),
+		Start: Start.(ast.Node),
+		End:   End.(ast.Node),
+		Step:  StepExpr.(ast.Node),
+	}, nil
+}
+
+func (p *parser) callonForStmt2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onForStmt2(stack["Var"], stack["Start"], stack["End"], stack["StepExpr"])
+}
+
+func (c *current) onForStmt30(Var, Start, End any) (any, error) {
+	return &ast.ForStmt{
+		Var:   Var.(string),
+		Start: Start.(ast.Node),
+		End:   End.(ast.Node),
+		Step:  &ast.Number{Value: 1},
+	}, nil
+}
+
+func (p *parser) callonForStmt30() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onForStmt30(stack["Var"], stack["Start"], stack["End"])
+}
+
+func (c *current) onForEachStmt1(Var, Map any) (any, error) {
+	return &ast.ForEachStmt{Var: Var.(string), Map: Map.(string)}, nil
+}
+
+func (p *parser) callonForEachStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onForEachStmt1(stack["Var"], stack["Map"])
+}
+
+func (c *current) onNextStmt1(Var any) (any, error) {
+	varName := ""
+	if Var != nil {
+		varName = Var.(string)
+	}
+	return &ast.NextStmt{Var: varName}, nil
+}
+
+func (p *parser) callonNextStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNextStmt1(stack["Var"])
+}
+
+func (c *current) onGotoStmt2(Num any) (any, error) {
+	return &ast.GotoStmt{LineNumber: Num.(int)}, nil
+}
+
+func (p *parser) callonGotoStmt2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onGotoStmt2(stack["Num"])
+}
+
+func (c *current) onGotoStmt9(Label any) (any, error) {
+	return &ast.GotoStmt{Label: Label.(string)}, nil
+}
+
+func (p *parser) callonGotoStmt9() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onGotoStmt9(stack["Label"])
+}
+
+func (c *current) onGosubStmt2(Num any) (any, error) {
+	return &ast.GosubStmt{LineNumber: Num.(int)}, nil
+}
+
+func (p *parser) callonGosubStmt2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onGosubStmt2(stack["Num"])
+}
+
+func (c *current) onGosubStmt9(Label any) (any, error) {
+	return &ast.GosubStmt{Label: Label.(string)}, nil
+}
+
+func (p *parser) callonGosubStmt9() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onGosubStmt9(stack["Label"])
+}
+
+func (c *current) onReturnStmt1() (any, error) {
+	return &ast.ReturnStmt{}, nil
+}
+
+func (p *parser) callonReturnStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onReturnStmt1()
+}
+
+func (c *current) onEndStmt1() (any, error) {
+	return &ast.EndStmt{}, nil
+}
+
+func (p *parser) callonEndStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onEndStmt1()
+}
+
+func (c *current) onStopStmt1() (any, error) {
+	return &ast.StopStmt{}, nil
+}
+
+func (p *parser) callonStopStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onStopStmt1()
+}
+
+func (c *current) onChainStmt2(File, Line any) (any, error) {
+	return &ast.ChainStmt{File: File.(ast.Node), Line: Line.(ast.Node), All: true}, nil
+}
+
+func (p *parser) callonChainStmt2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onChainStmt2(stack["File"], stack["Line"])
+}
+
+func (c *current) onChainStmt22(File any) (any, error) {
+	return &ast.ChainStmt{File: File.(ast.Node), All: true}, nil
+}
+
+func (p *parser) callonChainStmt22() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onChainStmt22(stack["File"])
+}
+
+func (c *current) onChainStmt40(File, Line any) (any, error) {
+	return &ast.ChainStmt{File: File.(ast.Node), Line: Line.(ast.Node)}, nil
+}
+
+func (p *parser) callonChainStmt40() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onChainStmt40(stack["File"], stack["Line"])
+}
+
+func (c *current) onChainStmt56(File any) (any, error) {
+	return &ast.ChainStmt{File: File.(ast.Node)}, nil
+}
+
+func (p *parser) callonChainStmt56() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onChainStmt56(stack["File"])
+}
+
+func (c *current) onCommonStmt1(First, Rest any) (any, error) {
+	values := []ast.Node{First.(ast.Node)}
+	if Rest != nil {
+		for _, v := range Rest.([]interface{}) {
+			seq := v.([]interface{})
+			// seq[0] = [ ]*, seq[1] = ',', seq[2] = [ ]*, seq[3] = CommonTarget
+			values = append(values, seq[3].(ast.Node))
+		}
+	}
+	return &ast.CommonStmt{Vars: values}, nil
+}
+
+func (p *parser) callonCommonStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCommonStmt1(stack["First"], stack["Rest"])
+}
+
+func (c *current) onCommonTarget2(id any) (any, error) {
+	return &ast.ArrayAccess{Name: id.(string)}, nil
+}
+
+func (p *parser) callonCommonTarget2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCommonTarget2(stack["id"])
+}
+
+func (c *current) onCommonTarget12(id any) (any, error) {
+	return &ast.Identifier{Name: id.(string)}, nil
+}
+
+func (p *parser) callonCommonTarget12() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCommonTarget12(stack["id"])
+}
+
+func (c *current) onModuleStmt1(Name any) (any, error) {
+	return &ast.ModuleStmt{Name: Name.(string)}, nil
+}
+
+func (p *parser) callonModuleStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onModuleStmt1(stack["Name"])
+}
+
+func (c *current) onSubStmt1(Export, Function, Name, Params any) (any, error) {
+	names, types := splitParams(Params)
+	return &ast.SubStmt{Name: Name.(string), Params: names, Types: types, Function: Function.(bool), Export: Export != nil}, nil
+}
+
+func (p *parser) callonSubStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSubStmt1(stack["Export"], stack["Function"], stack["Name"], stack["Params"])
+}
+
+func (c *current) onEndSubStmt1(Function any) (any, error) {
+	return &ast.EndSubStmt{Function: Function.(bool)}, nil
+}
+
+func (p *parser) callonEndSubStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onEndSubStmt1(stack["Function"])
+}
+
+func (c *current) onExitSubStmt1(Function any) (any, error) {
+	return &ast.ExitSubStmt{Function: Function.(bool)}, nil
+}
+
+func (p *parser) callonExitSubStmt1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExitSubStmt1(stack["Function"])
 }
 
-func (p *parser) callonStatementList1() (any, error) {
+func (c *current) onDeclareStmt1(Function, Name, Params any) (any, error) {
+	names, types := splitParams(Params)
+	return &ast.DeclareStmt{Name: Name.(string), Params: names, Types: types, Function: Function.(bool)}, nil
+}
+
+func (p *parser) callonDeclareStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onStatementList1(stack["First"], stack["Rest"])
+	return p.cur.onDeclareStmt1(stack["Function"], stack["Name"], stack["Params"])
 }
 
-func (c *current) onLineNumber1() (any, error) {
-	n, _ := strconv.Atoi(string(c.text))
-	return n, nil
+func (c *current) onCallStmt2(Name, Args any) (any, error) {
+	return &ast.CallStmt{Name: Name.(string), Args: Args.([]ast.Node)}, nil
 }
 
-func (p *parser) callonLineNumber1() (any, error) {
+func (p *parser) callonCallStmt2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onLineNumber1()
+	return p.cur.onCallStmt2(stack["Name"], stack["Args"])
 }
 
-func (c *current) onNonEmptyPrintStmt1(Args any) (any, error) {
-	result := Args.([]interface{})
-	values := result[0].([]ast.Node)
-	separators := []string{}
-	if len(result) > 1 {
-		separators = result[1].([]string)
-	}
-	return &ast.PrintStmt{Values: values, Separators: separators}, nil
+func (c *current) onCallStmt19(Name any) (any, error) {
+	return &ast.CallStmt{Name: Name.(string), Args: []ast.Node{}}, nil
 }
 
-func (p *parser) callonNonEmptyPrintStmt1() (any, error) {
+func (p *parser) callonCallStmt19() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onNonEmptyPrintStmt1(stack["Args"])
+	return p.cur.onCallStmt19(stack["Name"])
 }
 
-func (c *current) onAssignment2(Target, Value any) (any, error) {
-	return &ast.Assignment{Target: Target.(ast.Node), Value: Value.(ast.Node)}, nil
+func (c *current) onBareCallStmt2(Name, Args any) (any, error) {
+	return &ast.CallStmt{Name: Name.(string), Args: Args.([]ast.Node), Bare: true}, nil
 }
 
-func (p *parser) callonAssignment2() (any, error) {
+func (p *parser) callonBareCallStmt2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onAssignment2(stack["Target"], stack["Value"])
+	return p.cur.onBareCallStmt2(stack["Name"], stack["Args"])
 }
 
-func (c *current) onAssignment16(Target, Value any) (any, error) {
-	return &ast.Assignment{Target: Target.(ast.Node), Value: Value.(ast.Node)}, nil
+func (c *current) onBareCallStmt20(Name, Args any) (any, error) {
+	return &ast.CallStmt{Name: Name.(string), Args: Args.([]ast.Node), Bare: true}, nil
 }
 
-func (p *parser) callonAssignment16() (any, error) {
+func (p *parser) callonBareCallStmt20() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onAssignment16(stack["Target"], stack["Value"])
+	return p.cur.onBareCallStmt20(stack["Name"], stack["Args"])
 }
 
-func (c *current) onPrintStmt1(Args, Trailer any) (any, error) {
-	values := []ast.Node{}
-	separators := []string{}
-	if Args != nil {
-		result := Args.([]interface{})
-		values = result[0].([]ast.Node)
-		if len(result) > 1 {
-			separators = result[1].([]string)
-		}
-	}
-	trailer := ""
-	if Trailer != nil {
-		trailer = string(Trailer.([]uint8))
-	}
-	return &ast.PrintStmt{Values: values, Separators: separators, Trailer: trailer}, nil
+func (c *current) onBareCallStmt32(Name any) (any, error) {
+	return &ast.CallStmt{Name: Name.(string), Args: []ast.Node{}, Bare: true}, nil
 }
 
-func (p *parser) callonPrintStmt1() (any, error) {
+func (p *parser) callonBareCallStmt32() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrintStmt1(stack["Args"], stack["Trailer"])
+	return p.cur.onBareCallStmt32(stack["Name"])
 }
 
-func (c *current) onPrintArgList1(First, Rest any) (any, error) {
+func (c *current) onSharedStmt1(First, Rest any) (any, error) {
 	values := []ast.Node{First.(ast.Node)}
-	separators := []string{}
 	if Rest != nil {
 		for _, v := range Rest.([]interface{}) {
 			seq := v.([]interface{})
-			// seq[0] 是分隔符，seq[2] 是 PrintArg
-			sep := string(seq[0].([]uint8))
-			separators = append(separators, sep)
-			values = append(values, seq[2].(ast.Node))
+			// seq[0] = [ ]*, seq[1] = ',', seq[2] = [ ]*, seq[3] = CommonTarget
+			values = append(values, seq[3].(ast.Node))
 		}
 	}
-	return []interface{}{values, separators}, nil
+	return &ast.SharedStmt{Vars: values}, nil
 }
 
-func (p *parser) callonPrintArgList1() (any, error) {
+func (p *parser) callonSharedStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrintArgList1(stack["First"], stack["Rest"])
+	return p.cur.onSharedStmt1(stack["First"], stack["Rest"])
 }
 
-func (c *current) onIfStmt2(Condition any) (any, error) {
-	// 多行空 IF 语句
-	return &ast.IfStmt{
-		Condition: Condition.(ast.Node),
-		ThenStmts: []ast.Node{},
-		ElseStmts: []ast.Node{},
-	}, nil
+func (c *current) onRoutineKind2() (any, error) {
+	return false, nil
 }
 
-func (p *parser) callonIfStmt2() (any, error) {
+func (p *parser) callonRoutineKind2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfStmt2(stack["Condition"])
+	return p.cur.onRoutineKind2()
 }
 
-func (c *current) onIfStmt18(Condition, ThenStmts any) (any, error) {
-	// 多行 IF...END IF 语句（无 ELSE）
-	thenStmts := toNodeSliceFromAny(ThenStmts)
-	return &ast.IfStmt{
-		Condition: Condition.(ast.Node),
-		ThenStmts: thenStmts,
-		ElseStmts: []ast.Node{},
-	}, nil
+func (c *current) onRoutineKind4() (any, error) {
+	return true, nil
 }
 
-func (p *parser) callonIfStmt18() (any, error) {
+func (p *parser) callonRoutineKind4() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfStmt18(stack["Condition"], stack["ThenStmts"])
+	return p.cur.onRoutineKind4()
 }
 
-func (c *current) onIfStmt39(Condition, ThenStmts, ElseStmts any) (any, error) {
-	// 多行 IF...ELSE...END IF 语句
-	thenStmts := toNodeSliceFromAny(ThenStmts)
-	elseStmts := toNodeSliceFromAny(ElseStmts)
-	return &ast.IfStmt{
-		Condition: Condition.(ast.Node),
-		ThenStmts: thenStmts,
-		ElseStmts: elseStmts,
-	}, nil
+func (c *current) onParamList2(First, Rest any) (any, error) {
+	params := []param{First.(param)}
+	if Rest != nil {
+		for _, v := range Rest.([]interface{}) {
+			seq := v.([]interface{})
+			// seq[0] = [ ]*, seq[1] = ',', seq[2] = [ ]*, seq[3] = Param
+			params = append(params, seq[3].(param))
+		}
+	}
+	return params, nil
 }
 
-func (p *parser) callonIfStmt39() (any, error) {
+func (p *parser) callonParamList2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfStmt39(stack["Condition"], stack["ThenStmts"], stack["ElseStmts"])
+	return p.cur.onParamList2(stack["First"], stack["Rest"])
 }
 
-func (c *current) onIfStmt68(Condition, FirstThenArg, ThenRest, FirstElseArg, ElseRest any) (any, error) {
-	// 单行 IF...THEN PRINT...ELSE PRINT 语句
-	thenValues := []ast.Node{FirstThenArg.(ast.Node)}
-	thenSeps := []string{}
-	if ThenRest != nil {
-		for _, v := range ThenRest.([]interface{}) {
-			seq := v.([]interface{})
-			sep := string(seq[0].([]uint8))
-			thenSeps = append(thenSeps, sep)
-			thenValues = append(thenValues, seq[2].(ast.Node))
-		}
-	}
-	elseValues := []ast.Node{FirstElseArg.(ast.Node)}
-	elseSeps := []string{}
-	if ElseRest != nil {
-		for _, v := range ElseRest.([]interface{}) {
-			seq := v.([]interface{})
-			sep := string(seq[0].([]uint8))
-			elseSeps = append(elseSeps, sep)
-			elseValues = append(elseValues, seq[2].(ast.Node))
-		}
-	}
-	return &ast.IfStmt{
-		Condition: Condition.(ast.Node),
-		ThenStmts: []ast.Node{&ast.PrintStmt{Values: thenValues, Separators: thenSeps}},
-		ElseStmts: []ast.Node{&ast.PrintStmt{Values: elseValues, Separators: elseSeps}},
-	}, nil
+func (c *current) onParamList21() (any, error) {
+	return []param{}, nil
 }
 
-func (p *parser) callonIfStmt68() (any, error) {
+func (p *parser) callonParamList21() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfStmt68(stack["Condition"], stack["FirstThenArg"], stack["ThenRest"], stack["FirstElseArg"], stack["ElseRest"])
+	return p.cur.onParamList21()
 }
 
-func (c *current) onIfStmt113(Condition, PrintArgs any) (any, error) {
-	// 单行 IF...THEN PRINT 语句
-	result := PrintArgs.([]interface{})
-	values := result[0].([]ast.Node)
-	separators := []string{}
-	if len(result) > 1 {
-		separators = result[1].([]string)
+func (c *current) onParam1(Name, Type any) (any, error) {
+	p := param{name: Name.(string)}
+	if Type != nil {
+		p.typ = Type.([]interface{})[3].(string)
 	}
-	return &ast.IfStmt{
-		Condition: Condition.(ast.Node),
-		ThenStmts: []ast.Node{&ast.PrintStmt{Values: values, Separators: separators}},
-		ElseStmts: []ast.Node{},
-	}, nil
+	return p, nil
 }
 
-func (p *parser) callonIfStmt113() (any, error) {
+func (p *parser) callonParam1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfStmt113(stack["Condition"], stack["PrintArgs"])
+	return p.cur.onParam1(stack["Name"], stack["Type"])
 }
 
-func (c *current) onIfStmt130(Condition, ThenStmt, ElseStmt any) (any, error) {
-	// 单行 IF...THEN...ELSE
-	return &ast.IfStmt{
-		Condition: Condition.(ast.Node),
-		ThenStmts: []ast.Node{ThenStmt.(ast.Node)},
-		ElseStmts: []ast.Node{ElseStmt.(ast.Node)},
-	}, nil
+func (c *current) onTypeStmt1(Name any) (any, error) {
+	return &ast.TypeStmt{Name: Name.(string)}, nil
 }
 
-func (p *parser) callonIfStmt130() (any, error) {
+func (p *parser) callonTypeStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfStmt130(stack["Condition"], stack["ThenStmt"], stack["ElseStmt"])
+	return p.cur.onTypeStmt1(stack["Name"])
 }
 
-func (c *current) onIfStmt151(Condition, ThenStmt any) (any, error) {
-	// 单行 IF...THEN
-	return &ast.IfStmt{
-		Condition: Condition.(ast.Node),
-		ThenStmts: []ast.Node{ThenStmt.(ast.Node)},
-		ElseStmts: []ast.Node{},
-	}, nil
+func (c *current) onFieldStmt1(Name, Type any) (any, error) {
+	return &ast.FieldStmt{Name: Name.(string), Type: Type.(string)}, nil
 }
 
-func (p *parser) callonIfStmt151() (any, error) {
+func (p *parser) callonFieldStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfStmt151(stack["Condition"], stack["ThenStmt"])
+	return p.cur.onFieldStmt1(stack["Name"], stack["Type"])
 }
 
-func (c *current) onIfBlockStmt1(Condition any) (any, error) {
-	return &ast.IfBlockStmt{Condition: Condition.(ast.Node)}, nil
+func (c *current) onEndTypeStmt1() (any, error) {
+	return &ast.EndTypeStmt{}, nil
 }
 
-func (p *parser) callonIfBlockStmt1() (any, error) {
+func (p *parser) callonEndTypeStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIfBlockStmt1(stack["Condition"])
+	return p.cur.onEndTypeStmt1()
 }
 
-func (c *current) onElseBlockStmt1() (any, error) {
-	return &ast.ElseBlockStmt{}, nil
+func (c *current) onFields1(First, Rest any) (any, error) {
+	fields := []string{First.([]interface{})[1].(string)}
+	if Rest != nil {
+		for _, v := range Rest.([]interface{}) {
+			fields = append(fields, v.([]interface{})[1].(string))
+		}
+	}
+	return fields, nil
 }
 
-func (p *parser) callonElseBlockStmt1() (any, error) {
+func (p *parser) callonFields1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onElseBlockStmt1()
+	return p.cur.onFields1(stack["First"], stack["Rest"])
 }
 
-func (c *current) onEndIfStmt1() (any, error) {
-	return &ast.EndIfStmt{}, nil
+func (c *current) onRemStmt1() (any, error) {
+	return &ast.RemStmt{Text: string(c.text)}, nil
 }
 
-func (p *parser) callonEndIfStmt1() (any, error) {
+func (p *parser) callonRemStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onEndIfStmt1()
+	return p.cur.onRemStmt1()
 }
 
-func (c *current) onForStmt2(Var, Start, End, StepExpr any) (any, error) {
-	return &ast.ForStmt{
-		Var:   Var.(string),
-		Start: Start.(ast.Node),
-		End:   End.(ast.Node),
-		Step:  StepExpr.(ast.Node),
-	}, nil
+func (c *current) onSingleQuoteCommentStmt1() (any, error) {
+	return &ast.RemStmt{Text: string(c.text)}, nil
 }
 
-func (p *parser) callonForStmt2() (any, error) {
+func (p *parser) callonSingleQuoteCommentStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onForStmt2(stack["Var"], stack["Start"], stack["End"], stack["StepExpr"])
+	return p.cur.onSingleQuoteCommentStmt1()
 }
 
-func (c *current) onForStmt30(Var, Start, End any) (any, error) {
-	return &ast.ForStmt{
-		Var:   Var.(string),
-		Start: Start.(ast.Node),
-		End:   End.(ast.Node),
-		Step:  &ast.Number{Value: 1},
-	}, nil
+func (c *current) onDimStmt2(Name, Sizes, Type any) (any, error) {
+	return &ast.DimStmt{Name: Name.(string), Sizes: Sizes.([]ast.Node), Type: Type.(string)}, nil
 }
 
-func (p *parser) callonForStmt30() (any, error) {
+func (p *parser) callonDimStmt2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onForStmt30(stack["Var"], stack["Start"], stack["End"])
+	return p.cur.onDimStmt2(stack["Name"], stack["Sizes"], stack["Type"])
 }
 
-func (c *current) onNextStmt1(Var any) (any, error) {
-	varName := ""
-	if Var != nil {
-		varName = Var.(string)
-	}
-	return &ast.NextStmt{Var: varName}, nil
+func (c *current) onDimStmt20(Name, Type any) (any, error) {
+	return &ast.DimStmt{Name: Name.(string), Type: Type.(string)}, nil
 }
 
-func (p *parser) callonNextStmt1() (any, error) {
+func (p *parser) callonDimStmt20() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onNextStmt1(stack["Var"])
+	return p.cur.onDimStmt20(stack["Name"], stack["Type"])
 }
 
-func (c *current) onGotoStmt1(Num any) (any, error) {
-	return &ast.GotoStmt{LineNumber: Num.(int)}, nil
+func (c *current) onDimStmt34(Name, Sizes any) (any, error) {
+	return &ast.DimStmt{Name: Name.(string), Sizes: Sizes.([]ast.Node)}, nil
 }
 
-func (p *parser) callonGotoStmt1() (any, error) {
+func (p *parser) callonDimStmt34() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onGotoStmt1(stack["Num"])
+	return p.cur.onDimStmt34(stack["Name"], stack["Sizes"])
 }
 
-func (c *current) onGosubStmt1(Num any) (any, error) {
-	return &ast.GosubStmt{LineNumber: Num.(int)}, nil
+func (c *current) onDeleteKeyStmt1(Map, Key any) (any, error) {
+	return &ast.DeleteKeyStmt{Map: Map.(string), Key: Key.(ast.Node)}, nil
 }
 
-func (p *parser) callonGosubStmt1() (any, error) {
+func (p *parser) callonDeleteKeyStmt1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onGosubStmt1(stack["Num"])
+	return p.cur.onDeleteKeyStmt1(stack["Map"], stack["Key"])
 }
 
-func (c *current) onReturnStmt1() (any, error) {
-	return &ast.ReturnStmt{}, nil
+func (c *current) onInputStmt2(Prompt, Sep, Vars any) (any, error) {
+	return &ast.InputStmt{Prompt: Prompt.(*ast.StringLiteral).Value, Question: string(Sep.([]byte)) == ";", Vars: Vars.([]ast.Node)}, nil
 }
 
-func (p *parser) callonReturnStmt1() (any, error) {
+func (p *parser) callonInputStmt2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onReturnStmt1()
+	return p.cur.onInputStmt2(stack["Prompt"], stack["Sep"], stack["Vars"])
 }
 
-func (c *current) onEndStmt1() (any, error) {
-	return &ast.EndStmt{}, nil
+func (c *current) onInputStmt17(Prompt, Vars any) (any, error) {
+	return &ast.InputStmt{Prompt: Prompt.(*ast.StringLiteral).Value, Vars: Vars.([]ast.Node)}, nil
 }
 
-func (p *parser) callonEndStmt1() (any, error) {
+func (p *parser) callonInputStmt17() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onEndStmt1()
+	return p.cur.onInputStmt17(stack["Prompt"], stack["Vars"])
 }
 
-func (c *current) onRemStmt1() (any, error) {
-	return &ast.RemStmt{Text: string(c.text)}, nil
+func (c *current) onInputStmt28(Vars any) (any, error) {
+	return &ast.InputStmt{Vars: Vars.([]ast.Node)}, nil
 }
 
-func (p *parser) callonRemStmt1() (any, error) {
+func (p *parser) callonInputStmt28() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onRemStmt1()
+	return p.cur.onInputStmt28(stack["Vars"])
 }
 
-func (c *current) onSingleQuoteCommentStmt1() (any, error) {
-	return &ast.RemStmt{Text: string(c.text)}, nil
+func (c *current) onLineInputStmt2(Prompt, Sep, Var any) (any, error) {
+	return &ast.InputStmt{Prompt: Prompt.(*ast.StringLiteral).Value, Question: string(Sep.([]byte)) == ";", Line: true, Vars: []ast.Node{Var.(ast.Node)}}, nil
 }
 
-func (p *parser) callonSingleQuoteCommentStmt1() (any, error) {
+func (p *parser) callonLineInputStmt2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onSingleQuoteCommentStmt1()
+	return p.cur.onLineInputStmt2(stack["Prompt"], stack["Sep"], stack["Var"])
 }
 
-func (c *current) onDimStmt1(Name, Sizes any) (any, error) {
-	return &ast.DimStmt{Name: Name.(string), Sizes: Sizes.([]ast.Node)}, nil
+func (c *current) onLineInputStmt20(Var any) (any, error) {
+	return &ast.InputStmt{Line: true, Vars: []ast.Node{Var.(ast.Node)}}, nil
+}
+
+func (p *parser) callonLineInputStmt20() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLineInputStmt20(stack["Var"])
+}
+
+func (c *current) onInputTargetList1(First, Rest any) (any, error) {
+	values := []ast.Node{First.(ast.Node)}
+	if Rest != nil {
+		for _, v := range Rest.([]interface{}) {
+			seq := v.([]interface{})
+			// seq[0] = [ ]*, seq[1] = ',', seq[2] = [ ]*, seq[3] = InputTarget
+			values = append(values, seq[3].(ast.Node))
+		}
+	}
+	return values, nil
 }
 
-func (p *parser) callonDimStmt1() (any, error) {
+func (p *parser) callonInputTargetList1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onDimStmt1(stack["Name"], stack["Sizes"])
+	return p.cur.onInputTargetList1(stack["First"], stack["Rest"])
 }
 
-func (c *current) onInputStmt2(Prompt, Vars any) (any, error) {
-	return &ast.InputStmt{Prompt: Prompt.(*ast.StringLiteral).Value, Vars: Vars.([]string)}, nil
+func (c *current) onInputTarget2(id, args, fields any) (any, error) {
+	return fieldAccess(&ast.ArrayAccess{Name: id.(string), Indices: args.([]ast.Node)}, fields), nil
 }
 
-func (p *parser) callonInputStmt2() (any, error) {
+func (p *parser) callonInputTarget2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onInputStmt2(stack["Prompt"], stack["Vars"])
+	return p.cur.onInputTarget2(stack["id"], stack["args"], stack["fields"])
 }
 
-func (c *current) onInputStmt16(Prompt, Vars any) (any, error) {
-	return &ast.InputStmt{Prompt: Prompt.(*ast.StringLiteral).Value, Vars: Vars.([]string)}, nil
+func (c *current) onInputTarget12(id, fields any) (any, error) {
+	return fieldAccess(&ast.Identifier{Name: id.(string)}, fields), nil
 }
 
-func (p *parser) callonInputStmt16() (any, error) {
+func (p *parser) callonInputTarget12() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onInputStmt16(stack["Prompt"], stack["Vars"])
+	return p.cur.onInputTarget12(stack["id"], stack["fields"])
 }
 
-func (c *current) onInputStmt27(Vars any) (any, error) {
-	return &ast.InputStmt{Vars: Vars.([]string)}, nil
+func (c *current) onInputTarget18(id, args any) (any, error) {
+	return &ast.ArrayAccess{Name: id.(string), Indices: args.([]ast.Node)}, nil
 }
 
-func (p *parser) callonInputStmt27() (any, error) {
+func (p *parser) callonInputTarget18() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onInputStmt27(stack["Vars"])
+	return p.cur.onInputTarget18(stack["id"], stack["args"])
 }
 
-func (c *current) onIdentifierList1(First, Rest any) (any, error) {
-	values := []string{First.(string)}
-	if Rest != nil {
-		for _, v := range Rest.([]interface{}) {
-			seq := v.([]interface{})
-			// seq[0] = ',', seq[1] = [ ]*, seq[2] = Identifier
-			values = append(values, seq[2].(string))
-		}
-	}
-	return values, nil
+func (c *current) onInputTarget26(id any) (any, error) {
+	return &ast.Identifier{Name: id.(string)}, nil
 }
 
-func (p *parser) callonIdentifierList1() (any, error) {
+func (p *parser) callonInputTarget26() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onIdentifierList1(stack["First"], stack["Rest"])
+	return p.cur.onInputTarget26(stack["id"])
 }
 
 func (c *current) onLogicalNot2(Right any) (any, error) {
@@ -4227,7 +7874,28 @@ func (p *parser) callonExpressionList1() (any, error) {
 	return p.cur.onExpressionList1(stack["First"], stack["Rest"])
 }
 
-func (c *current) onPrimary3(id, args any) (any, error) {
+func (c *current) onPrimary3(id, args, fields any) (any, error) {
+	// 记录数组元素的字段，如 Inv(I).Price
+	return fieldAccess(&ast.ArrayAccess{Name: id.(string), Indices: args.([]ast.Node)}, fields), nil
+}
+
+func (p *parser) callonPrimary3() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrimary3(stack["id"], stack["args"], stack["fields"])
+}
+
+func (c *current) onPrimary13(id, fields any) (any, error) {
+	return fieldAccess(&ast.Identifier{Name: id.(string)}, fields), nil
+}
+
+func (p *parser) callonPrimary13() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrimary13(stack["id"], stack["fields"])
+}
+
+func (c *current) onPrimary19(id, args any) (any, error) {
 	// 多个参数：可能是函数调用或多维数组访问
 	idStr := id.(string)
 	if isBuiltinFunction(idStr) {
@@ -4236,41 +7904,41 @@ func (c *current) onPrimary3(id, args any) (any, error) {
 	return &ast.ArrayAccess{Name: idStr, Indices: args.([]ast.Node)}, nil
 }
 
-func (p *parser) callonPrimary3() (any, error) {
+func (p *parser) callonPrimary19() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrimary3(stack["id"], stack["args"])
+	return p.cur.onPrimary19(stack["id"], stack["args"])
 }
 
-func (c *current) onPrimary11(id any) (any, error) {
+func (c *current) onPrimary27(id any) (any, error) {
 	// 无参数：一定是函数调用（如 RND()）
 	return &ast.FunctionCall{Name: id.(string), Args: []ast.Node{}}, nil
 }
 
-func (p *parser) callonPrimary11() (any, error) {
+func (p *parser) callonPrimary27() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrimary11(stack["id"])
+	return p.cur.onPrimary27(stack["id"])
 }
 
-func (c *current) onPrimary17(id any) (any, error) {
+func (c *current) onPrimary33(id any) (any, error) {
 	return &ast.Identifier{Name: id.(string)}, nil
 }
 
-func (p *parser) callonPrimary17() (any, error) {
+func (p *parser) callonPrimary33() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrimary17(stack["id"])
+	return p.cur.onPrimary33(stack["id"])
 }
 
-func (c *current) onPrimary21(expr any) (any, error) {
+func (c *current) onPrimary37(expr any) (any, error) {
 	return expr, nil
 }
 
-func (p *parser) callonPrimary21() (any, error) {
+func (p *parser) callonPrimary37() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrimary21(stack["expr"])
+	return p.cur.onPrimary37(stack["expr"])
 }
 
 func (c *current) onNumber1() (any, error) {
@@ -0,0 +1,521 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// expandRecords 解析 TYPE 记录，解析后的程序中记录只通过 RecordRef、FieldAccess、
+// RecordAssignment 和带 Record 的 DimStmt 使用：
+//   - TYPE ... END TYPE 定义记录类型（ast.RecordType），定义本身不产生代码
+//   - DIM X AS Item 声明记录变量，DIM Inv(10) AS Item 声明记录数组。DIM 保留在程序中并
+//     填上类型，执行时创建字段都是 0 或空串的记录
+//   - 记录名换成 RecordRef；X.Price、Inv(I).Pos.X 中的记录换成 RecordRef 或 FieldAccess
+//     并填上类型，执行引擎据此算出字段所在的槽位
+//   - 目标是完整记录的赋值换成 RecordAssignment，两边的类型必须相同
+//   - SUB/FUNCTION 的参数可以是记录（It AS Item），对应的参数必须是同类型的记录
+//   - COMMON 和 SHARED 列出的记录变量换成没有索引的 ArrayAccess
+//
+// 记录变量和记录数组与数组、字典放在同一个表中，不能与它们同名。与 COMMON 一样，
+// 声明对整个程序有效；记录解析在字典和 SUB/FUNCTION 之前进行，子程序中的记录因此
+// 和数组一样是局部的
+func expandRecords(prog *ast.Program) error {
+	x := newRecordExpander()
+	if err := x.collectTypes(prog); err != nil {
+		return err
+	}
+	if err := x.collectRecords(prog); err != nil {
+		return err
+	}
+	for _, line := range prog.Lines {
+		stmts, err := x.expandStmts(line.Statements)
+		if err != nil {
+			return fmt.Errorf("%s: %v", line.Pos, err)
+		}
+		line.Statements = stmts
+	}
+	return nil
+}
+
+// typeDef 是收集中的 TYPE 定义，字段的类型在所有 TYPE 都收集之后解析
+type typeDef struct {
+	typ    *ast.RecordType
+	pos    ast.Position
+	types  []string       // 各字段 AS 之后的类型名
+	fields []ast.Position // 各字段的位置
+}
+
+// numericTypes 是数值字段可以使用的类型名，都按双精度浮点数存储
+var numericTypes = map[string]bool{"DOUBLE": true, "SINGLE": true, "INTEGER": true, "LONG": true}
+
+// recordExpander 保存解析记录时的状态
+type recordExpander struct {
+	types   map[string]*typeDef          // 类型名（大写）-> 定义
+	order   []*typeDef                   // 按定义顺序排列的类型，使错误信息与 map 的遍历顺序无关
+	scalars map[string]*ast.RecordType   // 记录变量名（大写）-> 类型
+	arrays  map[string]*ast.RecordType   // 记录数组名（大写）-> 类型
+	params  map[string][]*ast.RecordType // 子程序（routineKey）-> 各参数的记录类型
+}
+
+// newRecordExpander 创建没有任何类型和记录的解析器
+func newRecordExpander() *recordExpander {
+	return &recordExpander{
+		types:   make(map[string]*typeDef),
+		scalars: make(map[string]*ast.RecordType),
+		arrays:  make(map[string]*ast.RecordType),
+		params:  make(map[string][]*ast.RecordType),
+	}
+}
+
+// collectTypes 收集 TYPE 定义并把它们从程序中删除
+func (x *recordExpander) collectTypes(prog *ast.Program) error {
+	var open *typeDef
+	for _, line := range prog.Lines {
+		kept := line.Statements[:0]
+		for _, stmt := range line.Statements {
+			switch s := stmt.(type) {
+			case *ast.TypeStmt:
+				if open != nil {
+					return fmt.Errorf("%s: TYPE inside TYPE %s", line.Pos, open.typ.Name)
+				}
+				key := strings.ToUpper(s.Name)
+				if prev, ok := x.types[key]; ok {
+					return fmt.Errorf("%s: TYPE %s is already defined at %s", line.Pos, s.Name, prev.pos)
+				}
+				if key == "STRING" || isMapType(key) || numericTypes[key] {
+					return fmt.Errorf("%s: %s is a built-in type", line.Pos, s.Name)
+				}
+				open = &typeDef{typ: &ast.RecordType{Name: s.Name}, pos: line.Pos}
+				x.types[key] = open
+				x.order = append(x.order, open)
+			case *ast.FieldStmt:
+				if open == nil {
+					return fmt.Errorf("%s: field %s outside TYPE", line.Pos, s)
+				}
+				name := strings.ToUpper(s.Name)
+				if f, _ := open.typ.Field(name); f != nil {
+					return fmt.Errorf("%s: TYPE %s has two fields named %s", line.Pos, open.typ.Name, s.Name)
+				}
+				open.typ.Fields = append(open.typ.Fields, &ast.RecordField{Name: name})
+				open.types = append(open.types, s.Type)
+				open.fields = append(open.fields, line.Pos)
+			case *ast.EndTypeStmt:
+				if open == nil {
+					return fmt.Errorf("%s: END TYPE without TYPE", line.Pos)
+				}
+				if len(open.typ.Fields) == 0 {
+					return fmt.Errorf("%s: TYPE %s has no fields", open.pos, open.typ.Name)
+				}
+				open = nil
+			case *ast.RemStmt:
+				kept = append(kept, stmt)
+			default:
+				if open != nil {
+					return fmt.Errorf("%s: %s inside TYPE %s", line.Pos, statementKeyword(stmt), open.typ.Name)
+				}
+				kept = append(kept, stmt)
+			}
+		}
+		line.Statements = kept
+	}
+	if open != nil {
+		return fmt.Errorf("%s: TYPE %s has no END TYPE", open.pos, open.typ.Name)
+	}
+
+	for _, t := range x.order {
+		for i, f := range t.typ.Fields {
+			key := strings.ToUpper(t.types[i])
+			switch {
+			case key == "STRING":
+				f.String = true
+			case numericTypes[key]:
+			case x.types[key] != nil:
+				f.Record = x.types[key].typ
+			case isMapType(key):
+				return fmt.Errorf("%s: field %s of TYPE %s cannot be a MAP", t.fields[i], f.Name, t.typ.Name)
+			default:
+				return fmt.Errorf("%s: unknown type %s", t.fields[i], t.types[i])
+			}
+		}
+	}
+	for _, t := range x.order {
+		if cycle := contains(t.typ, t.typ, []string{t.typ.Name}); cycle != nil {
+			return fmt.Errorf("%s: TYPE %s contains itself (%s)", t.pos, t.typ.Name, strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+// contains 返回从 t 的字段到 target 的类型路径，没有时返回 nil
+func contains(t, target *ast.RecordType, path []string) []string {
+	for _, f := range t.Fields {
+		if f.Record == nil {
+			continue
+		}
+		next := append(path[:len(path):len(path)], f.Record.Name)
+		if f.Record == target {
+			return next
+		}
+		if cycle := contains(f.Record, target, next); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// lookup 返回 AS 之后的类型名对应的记录类型
+func (x *recordExpander) lookup(name string) (*ast.RecordType, error) {
+	if t := x.types[strings.ToUpper(name)]; t != nil {
+		return t.typ, nil
+	}
+	if key := strings.ToUpper(name); key == "STRING" || numericTypes[key] {
+		return nil, fmt.Errorf("only TYPE records and MAP can be declared with AS, not %s", name)
+	}
+	return nil, fmt.Errorf("unknown type %s", name)
+}
+
+// collectRecords 收集 DIM ... AS 声明的记录变量和记录数组，以及 SUB/FUNCTION 的记录
+// 参数。与 COMMON 一样，声明对整个程序有效
+func (x *recordExpander) collectRecords(prog *ast.Program) error {
+	routines := make(map[string]bool)
+	others := make(map[string]string) // DIM 声明的数组和字典（大写）-> "an array" 或 "a MAP"
+	var visit func(stmts []ast.Node) error
+	visit = func(stmts []ast.Node) error {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *ast.IfStmt:
+				if err := visit(s.ThenStmts); err != nil {
+					return err
+				}
+				if err := visit(s.ElseStmts); err != nil {
+					return err
+				}
+			case *ast.SubStmt:
+				routines[routineKey(s.Name)] = true
+				if err := x.declareParams(s.Name, s.Params, s.Types, true); err != nil {
+					return err
+				}
+			case *ast.DeclareStmt:
+				routines[routineKey(s.Name)] = true
+				if err := x.declareParams(s.Name, s.Params, s.Types, false); err != nil {
+					return err
+				}
+			case *ast.DimStmt:
+				switch {
+				case s.Type == "":
+					others[strings.ToUpper(s.Name)] = "an array"
+				case isMapType(s.Type):
+					others[strings.ToUpper(s.Name)] = "a MAP"
+				default:
+					if err := x.declare(s.Name, s.Type, len(s.Sizes) > 0); err != nil {
+						return fmt.Errorf("DIM %s AS %s: %v", s.Name, s.Type, err)
+					}
+				}
+			}
+		}
+		return nil
+	}
+	for _, line := range prog.Lines {
+		if err := visit(line.Statements); err != nil {
+			return fmt.Errorf("%s: %v", line.Pos, err)
+		}
+	}
+
+	// 与子程序同名时分不清 F(I) 是记录数组的元素还是调用；记录与数组和字典在同一个表中
+	for _, names := range []map[string]*ast.RecordType{x.scalars, x.arrays} {
+		for name := range names {
+			if routines[name] {
+				return fmt.Errorf("%s is both a record and a SUB or FUNCTION", name)
+			}
+			if kind := others[name]; kind != "" {
+				return fmt.Errorf("%s is both a record and %s", name, kind)
+			}
+		}
+	}
+	return nil
+}
+
+// declare 登记名为 name、类型为 typeName 的记录变量或记录数组
+func (x *recordExpander) declare(name, typeName string, array bool) error {
+	t, err := x.lookup(typeName)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(name, "$") {
+		return fmt.Errorf("record name %s cannot end with $", name)
+	}
+	names, others := x.scalars, x.arrays
+	if array {
+		names, others = x.arrays, x.scalars
+	}
+	key := strings.ToUpper(name)
+	if prev, ok := names[key]; ok && prev != t {
+		return fmt.Errorf("%s is already declared AS %s", name, prev.Name)
+	}
+	if _, ok := others[key]; ok {
+		return fmt.Errorf("%s is both a record and an array of records", name)
+	}
+	names[key] = t
+	return nil
+}
+
+// declareParams 解析子程序 name 的记录参数的类型。SUB/FUNCTION 的记录参数（define 为
+// true）同时登记为记录变量，子程序中按名称使用它们
+func (x *recordExpander) declareParams(name string, params []string, types []*ast.RecordType, define bool) error {
+	for i, t := range types {
+		if t == nil {
+			continue
+		}
+		resolved, err := x.lookup(t.Name)
+		if err != nil {
+			return fmt.Errorf("%s AS %s: %v", params[i], t.Name, err)
+		}
+		types[i] = resolved
+		if define {
+			if err := x.declare(params[i], t.Name, false); err != nil {
+				return fmt.Errorf("%s AS %s: %v", params[i], t.Name, err)
+			}
+		}
+	}
+	key := routineKey(name)
+	if _, ok := x.params[key]; !ok || define {
+		x.params[key] = types
+	}
+	return nil
+}
+
+// expandStmts 解析语句列表中的记录
+func (x *recordExpander) expandStmts(stmts []ast.Node) ([]ast.Node, error) {
+	out := make([]ast.Node, 0, len(stmts))
+	for _, stmt := range stmts {
+		var err error
+		expr := func(n ast.Node) ast.Node {
+			if err == nil {
+				n, err = x.expr(n)
+			}
+			return n
+		}
+		switch s := stmt.(type) {
+		case *ast.Assignment:
+			target, typ, terr := x.record(s.Target)
+			if terr != nil {
+				return nil, terr
+			}
+			if target != nil {
+				value, vtyp, verr := x.record(s.Value)
+				switch {
+				case verr != nil:
+					return nil, verr
+				case value == nil:
+					return nil, fmt.Errorf("cannot assign %s to record %s", s.Value, s.Target)
+				case vtyp != typ:
+					return nil, fmt.Errorf("cannot assign %s (TYPE %s) to %s (TYPE %s)", s.Value, vtyp.Name, s.Target, typ.Name)
+				}
+				out = append(out, &ast.RecordAssignment{Target: target, Value: value})
+				continue
+			}
+			s.Target = expr(s.Target)
+			s.Value = expr(s.Value)
+		case *ast.PrintStmt:
+			for i, v := range s.Values {
+				s.Values[i] = expr(v)
+			}
+		case *ast.IfStmt:
+			s.Condition = expr(s.Condition)
+			then, err := x.expandStmts(s.ThenStmts)
+			if err != nil {
+				return nil, err
+			}
+			otherwise, err := x.expandStmts(s.ElseStmts)
+			if err != nil {
+				return nil, err
+			}
+			s.ThenStmts, s.ElseStmts = then, otherwise
+		case *ast.IfBlockStmt:
+			s.Condition = expr(s.Condition)
+		case *ast.ForStmt:
+			if x.scalars[strings.ToUpper(s.Var)] != nil {
+				return nil, fmt.Errorf("FOR %s: %s is a record", s.Var, s.Var)
+			}
+			s.Start, s.End, s.Step = expr(s.Start), expr(s.End), expr(s.Step)
+		case *ast.ChainStmt:
+			s.File = expr(s.File)
+			if s.Line != nil {
+				s.Line = expr(s.Line)
+			}
+		case *ast.InputStmt:
+			for i, v := range s.Vars {
+				s.Vars[i] = expr(v)
+			}
+		case *ast.CallStmt:
+			s.Args, err = x.args(s.Name, s.Args)
+		case *ast.DeleteKeyStmt:
+			s.Key = expr(s.Key)
+		case *ast.CommonStmt:
+			s.Vars = x.sharedVars(s.Vars)
+		case *ast.SharedStmt:
+			s.Vars = x.sharedVars(s.Vars)
+		case *ast.DimStmt:
+			for i, size := range s.Sizes {
+				s.Sizes[i] = expr(size)
+			}
+			if s.Type != "" && !isMapType(s.Type) {
+				s.Record, _ = x.lookup(s.Type)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, stmt)
+	}
+	return out, nil
+}
+
+// sharedVars 把 COMMON/SHARED 列表中的记录变量换成没有索引的 ArrayAccess：记录和
+// 数组一样按名称共享
+func (x *recordExpander) sharedVars(vars []ast.Node) []ast.Node {
+	for i, v := range vars {
+		if id, ok := v.(*ast.Identifier); ok && x.scalars[strings.ToUpper(id.Name)] != nil {
+			vars[i] = &ast.ArrayAccess{Name: id.Name}
+		}
+	}
+	return vars
+}
+
+// record 在 n 表示一个完整的记录时返回解析后的记录（RecordRef 或 FieldAccess）和它的
+// 类型，n 是其他表达式时返回 nil
+func (x *recordExpander) record(n ast.Node) (ast.Node, *ast.RecordType, error) {
+	switch n := n.(type) {
+	case *ast.RecordRef:
+		return n, n.Type, nil
+	case *ast.Identifier:
+		if t := x.scalars[strings.ToUpper(n.Name)]; t != nil {
+			return &ast.RecordRef{Name: n.Name, Type: t}, t, nil
+		}
+	case *ast.ArrayAccess:
+		if t := x.arrays[strings.ToUpper(n.Name)]; t != nil {
+			indices, err := x.exprs(n.Indices)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &ast.RecordRef{Name: n.Name, Indices: indices, Type: t}, t, nil
+		}
+		if x.scalars[strings.ToUpper(n.Name)] != nil {
+			return nil, nil, fmt.Errorf("%s: %s is a record, not an array", n, n.Name)
+		}
+	case *ast.FieldAccess:
+		f, err := x.field(n)
+		if err != nil || f.Record == nil {
+			return nil, nil, err
+		}
+		return n, f.Record, nil
+	}
+	return nil, nil, nil
+}
+
+// field 解析字段访问：把 n.Record 换成解析后的记录，填上它的类型并返回访问的字段
+func (x *recordExpander) field(n *ast.FieldAccess) (*ast.RecordField, error) {
+	rec, t, err := x.record(n.Record)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, fmt.Errorf("%s: %s is not a record", n, n.Record)
+	}
+	n.Record, n.Type = rec, t
+	f, _ := t.Field(n.Field)
+	if f == nil {
+		return nil, fmt.Errorf("TYPE %s has no field %s", t.Name, n.Field)
+	}
+	return f, nil
+}
+
+// args 解析调用 name 的参数：记录参数对应的参数必须是同类型的记录，其余的是表达式
+func (x *recordExpander) args(name string, args []ast.Node) ([]ast.Node, error) {
+	types := x.params[routineKey(name)]
+	for i, arg := range args {
+		var t *ast.RecordType
+		if i < len(types) {
+			t = types[i]
+		}
+		if t == nil {
+			var err error
+			if args[i], err = x.expr(arg); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rec, typ, err := x.record(arg)
+		switch {
+		case err != nil:
+			return nil, err
+		case rec == nil:
+			return nil, fmt.Errorf("%s: argument %d must be a record of TYPE %s", name, i+1, t.Name)
+		case typ != t:
+			return nil, fmt.Errorf("%s: cannot pass %s (TYPE %s) as a %s", name, arg, typ.Name, t.Name)
+		}
+		args[i] = rec
+	}
+	return args, nil
+}
+
+// expr 解析表达式中的字段访问。完整的记录只能整体赋值或作为记录参数，不能出现在
+// 表达式中
+func (x *recordExpander) expr(n ast.Node) (ast.Node, error) {
+	var err error
+	sub := func(n ast.Node) ast.Node {
+		if err == nil {
+			n, err = x.expr(n)
+		}
+		return n
+	}
+	switch e := n.(type) {
+	case *ast.FieldAccess:
+		f, ferr := x.field(e)
+		if ferr != nil {
+			return nil, ferr
+		}
+		if f.Record != nil {
+			return nil, fmt.Errorf("%s is a record; use one of its fields", e)
+		}
+	case *ast.Identifier:
+		if x.scalars[strings.ToUpper(e.Name)] != nil {
+			return nil, fmt.Errorf("%s is a record; use one of its fields", e)
+		}
+	case *ast.ArrayAccess:
+		if _, ok := x.params[routineKey(e.Name)]; ok {
+			e.Indices, err = x.args(e.Name, e.Indices)
+			break
+		}
+		if x.arrays[strings.ToUpper(e.Name)] != nil {
+			return nil, fmt.Errorf("%s is a record; use one of its fields", e)
+		}
+		if x.scalars[strings.ToUpper(e.Name)] != nil {
+			return nil, fmt.Errorf("%s: %s is a record, not an array", e, e.Name)
+		}
+		e.Indices, err = x.exprs(e.Indices)
+	case *ast.FunctionCall:
+		e.Args, err = x.args(e.Name, e.Args)
+	case *ast.BinaryOp:
+		e.Left, e.Right = sub(e.Left), sub(e.Right)
+	case *ast.ComparisonOp:
+		e.Left, e.Right = sub(e.Left), sub(e.Right)
+	case *ast.LogicalOp:
+		e.Left, e.Right = sub(e.Left), sub(e.Right)
+	case *ast.UnaryOp:
+		e.Right = sub(e.Right)
+	}
+	return n, err
+}
+
+func (x *recordExpander) exprs(nodes []ast.Node) ([]ast.Node, error) {
+	for i, n := range nodes {
+		var err error
+		if nodes[i], err = x.expr(n); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
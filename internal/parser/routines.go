@@ -0,0 +1,919 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// expandRoutines 展开 MODULE、SUB/FUNCTION、DECLARE、CALL 和 SHARED，展开后的程序只包含
+// 各执行引擎都支持的语句：
+//   - SUB 的第一行换成跳过整个子程序的 GOTO（子程序之后没有其他行时换成 END），
+//     子程序从下一行开始；END SUB 换成把局部变量清零的赋值和 RETURN，EXIT SUB 换成跳到
+//     END SUB 所在行的 GOTO，因此每次调用时局部变量都从 0 或空字符串开始
+//   - 子程序中的变量、数组和记录换成 "<名称>.<变量>"，SHARED 列出的除外；参数是
+//     "<名称>.1"、"<名称>.2$"……，FUNCTION 的返回值是与函数同名的变量（见 ast.Routine）。
+//     记录参数是同名的记录变量，调用时用 DIM 创建并复制传入的记录，被调用者修改的是副本
+//   - CALL（可以不写 CALL）和表达式中的函数调用在所在语句之前展开：给参数赋值、清空返回值、
+//     GOSUB，再把返回值复制到临时变量中。调用左边的操作数先保存到临时变量，PRINT 在调用处
+//     拆成两条，因此求值和输出仍然按从左到右的顺序；AND/OR 不会跳过其中的调用
+//   - 被调用的子程序可能再调用到调用者时（递归），调用前后用 SAVE LOCALS 和 RESTORE LOCALS
+//     保存调用者的参数、局部变量、数组和临时变量，GOSUB 之前把被调用者的局部变量清零。
+//     经过其他模块的递归无法发现，局部变量仍会被覆盖
+//   - 只有 DECLARE 的子程序定义在其他模块中，调用它的 GOSUB 把名称记录在 Extern 中，
+//     编译后由 zb link 解析
+func expandRoutines(prog *ast.Program) error {
+	e := &expander{prog: prog, byName: make(map[string]*routine), lineIdx: make(map[int]int), saved: make(map[*ast.Identifier]bool), savedRecords: make(map[*ast.RecordRef]bool)}
+	for idx, line := range prog.Lines {
+		e.lineIdx[line.LineNumber] = idx
+	}
+	if err := e.collect(); err != nil {
+		return err
+	}
+	for idx, line := range prog.Lines {
+		if err := e.expandLine(idx, line); err != nil {
+			return err
+		}
+	}
+	e.saveLocals()
+	for _, r := range e.list {
+		prog.Routines = append(prog.Routines, r.Routine)
+	}
+	return nil
+}
+
+// routine 是展开过程中一个 SUB/FUNCTION 的信息
+type routine struct {
+	*ast.Routine
+	source     string            // 源程序中的名称，用于错误信息和 GOSUB 的显示
+	prefix     string            // 局部变量的前缀：Name 去掉末尾的 $
+	start, end int               // SUB 行和 END SUB 行的下标，DECLARE 时为 -1
+	pos        ast.Position      // 定义或声明的位置
+	records    []*ast.RecordType // 各参数的记录类型，不是记录的参数为 nil
+	shared     map[string]bool   // SHARED 列出的变量，数组后加 "()"
+	local      map[string]bool   // 已经记录在 Locals 中的名称
+	vars       []string          // 局部变量（不含参数、返回值和临时变量），返回时清零
+	calls      []*site           // 子程序中的调用
+}
+
+// site 记录子程序中的一次调用。调用前后的 SAVE LOCALS 和 RESTORE LOCALS 先不保存
+// 任何变量，展开完所有行之后由 saveLocals 为递归调用填上
+type site struct {
+	caller, callee *routine
+	save           *ast.SaveLocalsStmt
+	gosub          *ast.GosubStmt
+	result         string // 保存返回值的临时变量，调用之后才赋值，不需要保存
+}
+
+// expander 保存展开过程的状态
+type expander struct {
+	prog    *ast.Program
+	byName  map[string]*routine // 大写名称（不含 $）-> 子程序
+	list    []*routine          // 按定义顺序
+	lineIdx map[int]int         // 行号 -> 行下标
+	owner   []*routine          // 每一行所属的子程序，不在子程序中时为 nil
+
+	cur          *routine                 // 正在展开的子程序
+	temps        int                      // 当前语句已经使用的临时变量数
+	saved        map[*ast.Identifier]bool // save 创建的临时变量
+	savedRecords map[*ast.RecordRef]bool  // saveRecord 创建的临时记录
+	err          error                    // 展开表达式时遇到的第一个错误
+}
+
+// routineKey 返回查找子程序使用的名称：大写并去掉末尾的 $
+func routineKey(name string) string {
+	return strings.TrimSuffix(strings.ToUpper(name), "$")
+}
+
+// soleStatement 返回 line 中唯一的 MODULE/SUB/END SUB/DECLARE 语句。这些语句必须
+// 单独占一行，返回 nil 表示该行没有这些语句
+func soleStatement(line *ast.Line) (ast.Node, error) {
+	for _, stmt := range line.Statements {
+		switch stmt.(type) {
+		case *ast.ModuleStmt, *ast.SubStmt, *ast.EndSubStmt, *ast.DeclareStmt:
+			if len(line.Statements) > 1 || line.Label != "" {
+				return nil, fmt.Errorf("%s: %s must be on a line by itself", line.Pos, statementKeyword(stmt))
+			}
+			return stmt, nil
+		}
+	}
+	return nil, nil
+}
+
+// statementKeyword 返回错误信息中使用的语句名称
+func statementKeyword(stmt ast.Node) string {
+	switch s := stmt.(type) {
+	case *ast.ModuleStmt:
+		return "MODULE"
+	case *ast.SubStmt:
+		return routineKind(s.Function)
+	case *ast.EndSubStmt:
+		return s.String()
+	case *ast.DeclareStmt:
+		return "DECLARE"
+	}
+	return stmt.String()
+}
+
+// routineKind 返回 "SUB" 或 "FUNCTION"
+func routineKind(function bool) string {
+	if function {
+		return "FUNCTION"
+	}
+	return "SUB"
+}
+
+// collect 找出 MODULE 声明和所有 SUB/FUNCTION 的定义与声明，检查它们的结构
+func (e *expander) collect() error {
+	prog := e.prog
+	e.owner = make([]*routine, len(prog.Lines))
+	var open *routine
+	var declares []*ast.DeclareStmt
+	var declarePos []ast.Position
+	for idx, line := range prog.Lines {
+		stmt, err := soleStatement(line)
+		if err != nil {
+			return err
+		}
+		if open != nil {
+			e.owner[idx] = open
+		}
+		switch s := stmt.(type) {
+		case *ast.ModuleStmt:
+			if prog.Module != "" {
+				return fmt.Errorf("%s: MODULE %s is already declared", line.Pos, prog.Module)
+			}
+			if idx > 0 {
+				return fmt.Errorf("%s: MODULE must be the first line", line.Pos)
+			}
+			prog.Module = s.Name
+
+		case *ast.SubStmt:
+			if open != nil {
+				return fmt.Errorf("%s: %s inside %s %s", line.Pos, routineKind(s.Function), routineKind(open.Function), open.source)
+			}
+			r, err := e.define(s.Name, s.Params, s.Types, s.Function, line.Pos)
+			if err != nil {
+				return err
+			}
+			r.Export = s.Export
+			r.start = idx
+			open = r
+			e.owner[idx] = r
+
+		case *ast.EndSubStmt:
+			if open == nil {
+				return fmt.Errorf("%s: %s without %s", line.Pos, s, routineKind(s.Function))
+			}
+			if s.Function != open.Function {
+				return fmt.Errorf("%s: %s does not match %s %s", line.Pos, s, routineKind(open.Function), open.source)
+			}
+			open.end = idx
+			open = nil
+
+		case *ast.DeclareStmt:
+			declares = append(declares, s)
+			declarePos = append(declarePos, line.Pos)
+		}
+	}
+	if open != nil {
+		return fmt.Errorf("%s: %s %s has no END %s", open.pos, routineKind(open.Function), open.source, routineKind(open.Function))
+	}
+
+	// DECLARE 可以声明本文件中定义的子程序，参数必须一致；其余的定义在其他模块中
+	for k, d := range declares {
+		if r, ok := e.byName[routineKey(d.Name)]; ok {
+			if err := sameSignature(r, d); err != nil {
+				return fmt.Errorf("%s: %v", declarePos[k], err)
+			}
+			continue
+		}
+		r, err := e.define(d.Name, d.Params, d.Types, d.Function, declarePos[k])
+		if err != nil {
+			return err
+		}
+		r.Extern = true
+		r.Line = -1
+	}
+
+	for _, r := range e.list {
+		if r.Export && prog.Module == "" {
+			return fmt.Errorf("%s: EXPORT is only allowed in a MODULE", r.pos)
+		}
+		if (r.Export || r.Extern) && slices.ContainsFunc(r.records, func(t *ast.RecordType) bool { return t != nil }) {
+			// 其他模块中没有这个 TYPE，zb link 也不检查参数的类型
+			return fmt.Errorf("%s: %s %s: record parameters cannot be passed between modules", r.pos, routineKind(r.Function), r.source)
+		}
+		if !r.Extern {
+			// 模块中未导出的子程序以模块名开头，链接后不会和其他模块冲突
+			if prog.Module != "" && !r.Export {
+				r.Name = strings.ToUpper(prog.Module) + "." + r.Name
+				r.prefix = strings.ToUpper(prog.Module) + "." + r.prefix
+			}
+			r.Line = prog.Lines[r.start+1].LineNumber
+		}
+		for i := range r.Params {
+			r.ParamVars = append(r.ParamVars, r.paramVar(i))
+			if r.records[i] != nil {
+				r.addLocal(r.paramVar(i) + "()")
+			} else {
+				r.addLocal(r.paramVar(i))
+			}
+		}
+		if r.Function {
+			r.addLocal(r.Name)
+		}
+	}
+	return nil
+}
+
+// define 登记一个 SUB/FUNCTION，types 是各参数的记录类型
+func (e *expander) define(name string, params []string, types []*ast.RecordType, function bool, pos ast.Position) (*routine, error) {
+	kind := routineKind(function)
+	key := routineKey(name)
+	if prev, ok := e.byName[key]; ok {
+		return nil, fmt.Errorf("%s: %s is already defined at %s", pos, name, prev.pos)
+	}
+	if isBuiltinFunction(name) {
+		return nil, fmt.Errorf("%s: %s is a built-in function", pos, name)
+	}
+	if !function && strings.HasSuffix(name, "$") {
+		return nil, fmt.Errorf("%s: SUB name %s cannot end with $", pos, name)
+	}
+	r := &routine{
+		Routine: &ast.Routine{Name: strings.ToUpper(name), Function: function},
+		source:  name,
+		prefix:  key,
+		start:   -1,
+		end:     -1,
+		pos:     pos,
+		shared:  make(map[string]bool),
+		local:   make(map[string]bool),
+	}
+	seen := make(map[string]bool)
+	for i, param := range params {
+		upper := strings.ToUpper(param)
+		if seen[upper] {
+			return nil, fmt.Errorf("%s: %s %s has two parameters named %s", pos, kind, name, param)
+		}
+		seen[upper] = true
+		r.Params = append(r.Params, upper)
+		r.records = append(r.records, paramType(types, i))
+	}
+	e.byName[key] = r
+	e.list = append(e.list, r)
+	return r, nil
+}
+
+// sameSignature 检查 DECLARE 与本文件中的定义是否一致
+func sameSignature(r *routine, d *ast.DeclareStmt) error {
+	if r.Function != d.Function || r.Name != strings.ToUpper(d.Name) || len(r.Params) != len(d.Params) {
+		return fmt.Errorf("DECLARE %s does not match %s %s at %s", d.Name, routineKind(r.Function), r.source, r.pos)
+	}
+	for i, param := range d.Params {
+		if strings.HasSuffix(param, "$") != strings.HasSuffix(r.Params[i], "$") || paramType(d.Types, i) != r.records[i] {
+			return fmt.Errorf("DECLARE %s: parameter %s does not match %s at %s", d.Name, param, r.Params[i], r.pos)
+		}
+	}
+	return nil
+}
+
+// paramType 返回第 i 个参数的记录类型，不是记录时返回 nil
+func paramType(types []*ast.RecordType, i int) *ast.RecordType {
+	if i < len(types) {
+		return types[i]
+	}
+	return nil
+}
+
+// paramVar 返回第 i 个参数对应的变量名
+func (r *routine) paramVar(i int) string {
+	name := r.prefix + "." + strconv.Itoa(i+1)
+	if strings.HasSuffix(r.Params[i], "$") {
+		name += "$"
+	}
+	return name
+}
+
+// addLocal 把展开后的名称记录到 Locals 中，返回它是否是新的
+func (r *routine) addLocal(name string) bool {
+	if r.local[name] {
+		return false
+	}
+	r.local[name] = true
+	r.Locals = append(r.Locals, name)
+	return true
+}
+
+// expandLine 展开一行
+func (e *expander) expandLine(idx int, line *ast.Line) error {
+	e.cur = e.owner[idx]
+	stmt, err := soleStatement(line)
+	if err != nil {
+		return err
+	}
+	switch stmt.(type) {
+	case *ast.SubStmt:
+		// 顺序执行到子程序时跳过它
+		r := e.cur
+		if r.end+1 < len(e.prog.Lines) {
+			line.Statements = []ast.Node{&ast.GotoStmt{LineNumber: e.prog.Lines[r.end+1].LineNumber}}
+		} else {
+			line.Statements = []ast.Node{&ast.EndStmt{}}
+		}
+		return nil
+	case *ast.EndSubStmt:
+		// END SUB 在子程序的最后一行，这时所有局部变量都已记录
+		line.Statements = nil
+		for _, name := range e.cur.vars {
+			line.Statements = append(line.Statements, &ast.Assignment{Target: &ast.Identifier{Name: name}, Value: zeroValue(name)})
+		}
+		line.Statements = append(line.Statements, &ast.ReturnStmt{})
+		return nil
+	case *ast.ModuleStmt, *ast.DeclareStmt:
+		line.Statements = []ast.Node{}
+		return nil
+	}
+
+	if e.cur != nil {
+		// SHARED 必须在使用这些变量之前，放在子程序的开头
+		for _, stmt := range line.Statements {
+			if s, ok := stmt.(*ast.SharedStmt); ok {
+				for _, v := range s.Vars {
+					e.cur.shared[strings.ToUpper(v.String())] = true
+				}
+			}
+		}
+	} else if e.prog.Module != "" {
+		for _, stmt := range line.Statements {
+			if _, ok := stmt.(*ast.RemStmt); !ok {
+				return fmt.Errorf("%s: MODULE %s can only contain SUB, FUNCTION and DECLARE", line.Pos, e.prog.Module)
+			}
+		}
+	}
+	stmts, err := e.expandStmts(line.Statements)
+	if err != nil {
+		return fmt.Errorf("%s: %v", line.Pos, err)
+	}
+	line.Statements = stmts
+	return nil
+}
+
+// expandStmts 展开语句列表，返回新的语句列表
+func (e *expander) expandStmts(stmts []ast.Node) ([]ast.Node, error) {
+	out := make([]ast.Node, 0, len(stmts))
+	for _, stmt := range stmts {
+		e.temps = 0
+		var pre []ast.Node
+		expr := func(n ast.Node) ast.Node {
+			return e.expr(n, &pre)
+		}
+		switch s := stmt.(type) {
+		case *ast.Assignment:
+			if target, ok := s.Target.(*ast.ArrayAccess); ok && e.function(target.Name) != nil {
+				return nil, fmt.Errorf("cannot assign to FUNCTION %s", target.Name)
+			}
+			// 先计算目标的下标或键，再计算值
+			switch target := s.Target.(type) {
+			case *ast.Identifier:
+				if r := e.function(target.Name); r != nil && r != e.cur {
+					return nil, fmt.Errorf("cannot assign to FUNCTION %s outside it", target.Name)
+				}
+				s.Value = expr(s.Value)
+				s.Target = &ast.Identifier{Name: e.scalar(target.Name)}
+			case *ast.ArrayAccess:
+				ops := e.operands(append(slices.Clone(target.Indices), s.Value), &pre)
+				s.Target = &ast.ArrayAccess{Name: e.array(target.Name), Indices: ops[:len(target.Indices)]}
+				s.Value = ops[len(target.Indices)]
+			case *ast.MapAccess:
+				ops := e.operands([]ast.Node{target.Key, s.Value}, &pre)
+				target.Name, target.Key, s.Value = e.array(target.Name), ops[0], ops[1]
+			case *ast.FieldAccess:
+				ref, _ := target.Slot()
+				ops := e.operands(append(slices.Clone(ref.Indices), s.Value), &pre)
+				ref.Name, ref.Indices, s.Value = e.array(ref.Name), ops[:len(ref.Indices)], ops[len(ref.Indices)]
+			default:
+				s.Target, s.Value = expr(s.Target), expr(s.Value)
+			}
+		case *ast.RecordAssignment:
+			e.records([]ast.Node{s.Target, s.Value}, &pre)
+		case *ast.PrintStmt:
+			stmt = e.print(s, &pre)
+		case *ast.IfStmt:
+			s.Condition = expr(s.Condition)
+			then, err := e.expandStmts(s.ThenStmts)
+			if err != nil {
+				return nil, err
+			}
+			otherwise, err := e.expandStmts(s.ElseStmts)
+			if err != nil {
+				return nil, err
+			}
+			s.ThenStmts, s.ElseStmts = then, otherwise
+		case *ast.IfBlockStmt:
+			s.Condition = expr(s.Condition)
+		case *ast.ForStmt:
+			s.Var = e.scalar(s.Var)
+			if s.Step == nil {
+				ops := e.operands([]ast.Node{s.Start, s.End}, &pre)
+				s.Start, s.End = ops[0], ops[1]
+			} else {
+				ops := e.operands([]ast.Node{s.Start, s.End, s.Step}, &pre)
+				s.Start, s.End, s.Step = ops[0], ops[1], ops[2]
+			}
+		case *ast.NextStmt:
+			if s.Var != "" {
+				s.Var = e.scalar(s.Var)
+			}
+		case *ast.GotoStmt:
+			if err := e.checkJump("GOTO", s.LineNumber); err != nil {
+				return nil, err
+			}
+		case *ast.GosubStmt:
+			if err := e.checkJump("GOSUB", s.LineNumber); err != nil {
+				return nil, err
+			}
+		case *ast.ChainStmt:
+			if s.Line == nil {
+				s.File = expr(s.File)
+			} else {
+				ops := e.operands([]ast.Node{s.File, s.Line}, &pre)
+				s.File, s.Line = ops[0], ops[1]
+			}
+		case *ast.CommonStmt:
+			if e.cur != nil {
+				return nil, fmt.Errorf("COMMON is not allowed in %s %s", routineKind(e.cur.Function), e.cur.source)
+			}
+		case *ast.DimStmt:
+			s.Sizes = e.operands(s.Sizes, &pre)
+			s.Name = e.array(s.Name)
+		case *ast.DeleteKeyStmt:
+			s.Key = expr(s.Key)
+			s.Map = e.array(s.Map)
+		case *ast.ForEachStmt:
+			s.Var, s.Map = e.scalar(s.Var), e.array(s.Map)
+		case *ast.InputStmt:
+			for i, v := range s.Vars {
+				s.Vars[i] = expr(v)
+			}
+			if len(pre) > 0 {
+				return nil, fmt.Errorf("INPUT targets cannot call a FUNCTION")
+			}
+		case *ast.ExitSubStmt:
+			if e.cur == nil {
+				return nil, fmt.Errorf("%s outside %s", s, routineKind(s.Function))
+			}
+			if s.Function != e.cur.Function {
+				return nil, fmt.Errorf("%s inside %s %s", s, routineKind(e.cur.Function), e.cur.source)
+			}
+			stmt = &ast.GotoStmt{LineNumber: e.prog.Lines[e.cur.end].LineNumber}
+		case *ast.CallStmt:
+			r := e.byName[routineKey(s.Name)]
+			if r == nil && s.Bare {
+				return nil, fmt.Errorf("unknown statement %s (use DECLARE for a SUB in another module)", s.Name)
+			}
+			if r == nil {
+				return nil, fmt.Errorf("CALL %s: %s is not a SUB or FUNCTION (use DECLARE for one in another module)", s.Name, s.Name)
+			}
+			if c := e.call(r, s.Args, &pre); c != nil {
+				pre = append(pre, &ast.RestoreLocalsStmt{Saved: c.save})
+			}
+			stmt = nil
+		case *ast.SharedStmt:
+			if e.cur == nil {
+				return nil, fmt.Errorf("SHARED outside SUB or FUNCTION")
+			}
+			stmt = nil
+		}
+		if e.err != nil {
+			return nil, e.err
+		}
+		out = append(out, pre...)
+		if stmt != nil {
+			out = append(out, stmt)
+		}
+	}
+	return out, nil
+}
+
+// fail 记录展开表达式时遇到的错误，只保留第一个
+func (e *expander) fail(format string, args ...any) {
+	if e.err == nil {
+		e.err = fmt.Errorf(format, args...)
+	}
+}
+
+// function 返回名为 name 的 FUNCTION，name 不是 FUNCTION 时返回 nil
+func (e *expander) function(name string) *routine {
+	r := e.byName[routineKey(name)]
+	if r == nil || !r.Function || !strings.EqualFold(r.source, name) {
+		return nil
+	}
+	return r
+}
+
+// scalar 返回变量在展开后的名称
+func (e *expander) scalar(name string) string {
+	r := e.cur
+	upper := strings.ToUpper(name)
+	if r == nil || r.shared[upper] {
+		return name
+	}
+	if r.Function && upper == strings.ToUpper(r.source) {
+		return r.Name
+	}
+	if i := slices.Index(r.Params, upper); i >= 0 {
+		return r.paramVar(i)
+	}
+	local := r.prefix + "." + upper
+	if r.addLocal(local) {
+		r.vars = append(r.vars, local)
+	}
+	return local
+}
+
+// array 返回数组、字典或记录在展开后的名称
+func (e *expander) array(name string) string {
+	r := e.cur
+	upper := strings.ToUpper(name)
+	if r == nil || r.shared[upper+"()"] {
+		return name
+	}
+	if i := slices.Index(r.Params, upper); i >= 0 && r.records[i] != nil {
+		return r.paramVar(i)
+	}
+	r.addLocal(r.prefix + "." + upper + "()")
+	return r.prefix + "." + upper
+}
+
+// expr 展开表达式：换掉子程序中的变量名，把函数调用换成临时变量，调用前的语句追加到 pre
+func (e *expander) expr(n ast.Node, pre *[]ast.Node) ast.Node {
+	switch x := n.(type) {
+	case *ast.Identifier:
+		// 不带括号的函数名调用函数，在函数自身中则是返回值
+		if r := e.function(x.Name); r != nil && r != e.cur {
+			return e.callValue(r, nil, pre)
+		}
+		if name := e.scalar(x.Name); name != x.Name {
+			return &ast.Identifier{Name: name}
+		}
+	case *ast.ArrayAccess:
+		if r := e.function(x.Name); r != nil {
+			return e.callValue(r, x.Indices, pre)
+		}
+		if r := e.byName[routineKey(x.Name)]; r != nil && !r.Function {
+			e.fail("SUB %s has no value; use CALL %s", r.source, r.source)
+			return n
+		}
+		return &ast.ArrayAccess{Name: e.array(x.Name), Indices: e.operands(slices.Clone(x.Indices), pre)}
+	case *ast.MapAccess:
+		// 字典和数组一样是局部的，SHARED D() 共享主程序的字典
+		x.Key = e.expr(x.Key, pre)
+		x.Name = e.array(x.Name)
+	case *ast.MapFunc:
+		if x.Arg != nil {
+			x.Arg = e.expr(x.Arg, pre)
+		}
+		x.Map = e.array(x.Map)
+	case *ast.RecordRef:
+		x.Indices = e.operands(x.Indices, pre)
+		x.Name = e.array(x.Name)
+	case *ast.FieldAccess:
+		x.Record = e.expr(x.Record, pre)
+	case *ast.FunctionCall:
+		if r := e.function(x.Name); r != nil {
+			return e.callValue(r, x.Args, pre)
+		}
+		if r := e.byName[routineKey(x.Name)]; r != nil && !r.Function {
+			e.fail("SUB %s has no value; use CALL %s", r.source, r.source)
+			return n
+		}
+		x.Args = e.operands(x.Args, pre)
+	case *ast.BinaryOp:
+		ops := e.operands([]ast.Node{x.Left, x.Right}, pre)
+		x.Left, x.Right = ops[0], ops[1]
+	case *ast.ComparisonOp:
+		ops := e.operands([]ast.Node{x.Left, x.Right}, pre)
+		x.Left, x.Right = ops[0], ops[1]
+	case *ast.LogicalOp:
+		ops := e.operands([]ast.Node{x.Left, x.Right}, pre)
+		x.Left, x.Right = ops[0], ops[1]
+	case *ast.UnaryOp:
+		x.Right = e.expr(x.Right, pre)
+	}
+	return n
+}
+
+// records 展开整体赋值两边的记录（RecordRef 或 FieldAccess），按从左到右的顺序计算
+// 它们的下标
+func (e *expander) records(nodes []ast.Node, pre *[]ast.Node) {
+	var refs []*ast.RecordRef
+	var indices []ast.Node
+	for _, n := range nodes {
+		ref, _, _ := ast.RecordOf(n)
+		refs = append(refs, ref)
+		indices = append(indices, ref.Indices...)
+	}
+	ops := e.operands(indices, pre)
+	for _, ref := range refs {
+		n := len(ref.Indices)
+		ref.Name, ref.Indices = e.array(ref.Name), ops[:n:n]
+		ops = ops[n:]
+	}
+}
+
+// call 把调用 r 的语句追加到 pre：给参数赋值，清空 FUNCTION 的返回值，然后 GOSUB。
+// 在子程序中调用时先追加 SAVE LOCALS 并返回这次调用，调用者在 GOSUB（和复制返回值）
+// 之后追加对应的 RESTORE LOCALS
+func (e *expander) call(r *routine, args []ast.Node, pre *[]ast.Node) *site {
+	if len(args) != len(r.Params) {
+		e.fail("%s %s expects %d arguments, got %d", routineKind(r.Function), r.source, len(r.Params), len(args))
+		return nil
+	}
+	// 先计算所有参数，参数中的函数调用不会覆盖已经赋值的参数
+	values := e.operands(slices.Clone(args), pre)
+	if r == e.cur {
+		// 参数依次赋值，后面的参数可能引用已经赋值的参数，先把它们保存到临时变量中。
+		// 记录参数赋值前用 DIM 清空，第一个参数也可能是它自己
+		for i := range values {
+			if i > 0 || r.records[i] != nil {
+				values[i] = e.keep(values[i], pre)
+			}
+		}
+	}
+	gosub := &ast.GosubStmt{LineNumber: r.Line, Label: r.source}
+	if r.Extern {
+		gosub = &ast.GosubStmt{LineNumber: -1, Extern: r.Name}
+	}
+	var c *site
+	if e.cur != nil {
+		c = &site{caller: e.cur, callee: r, save: &ast.SaveLocalsStmt{}, gosub: gosub}
+		e.cur.calls = append(e.cur.calls, c)
+		*pre = append(*pre, c.save)
+	}
+	for i, value := range values {
+		if t := r.records[i]; t != nil {
+			name := r.paramVar(i)
+			*pre = append(*pre, &ast.DimStmt{Name: name, Type: t.Name, Record: t},
+				&ast.RecordAssignment{Target: &ast.RecordRef{Name: name, Type: t}, Value: value})
+			continue
+		}
+		*pre = append(*pre, &ast.Assignment{Target: &ast.Identifier{Name: r.paramVar(i)}, Value: value})
+	}
+	if r.Function {
+		*pre = append(*pre, &ast.Assignment{Target: &ast.Identifier{Name: r.Name}, Value: zeroValue(r.Name)})
+	}
+	*pre = append(*pre, gosub)
+	return c
+}
+
+// zeroValue 返回变量 name 的初始值：0 或空字符串
+func zeroValue(name string) ast.Node {
+	if strings.HasSuffix(name, "$") {
+		return &ast.StringLiteral{}
+	}
+	return &ast.Number{Value: 0}
+}
+
+// callValue 调用 FUNCTION r，返回保存返回值的临时变量。主程序的临时变量是 "CALL.1"、
+// "CALL.2"……，子程序中的以子程序名开头；CALL 是关键字，不会与变量或子程序重名
+func (e *expander) callValue(r *routine, args []ast.Node, pre *[]ast.Node) ast.Node {
+	c := e.call(r, args, pre)
+	result := e.save(&ast.Identifier{Name: r.Name}, strings.HasSuffix(r.Name, "$"), pre)
+	if c != nil {
+		c.result = result.Name
+		*pre = append(*pre, &ast.RestoreLocalsStmt{Saved: c.save})
+	}
+	return result
+}
+
+// save 把 value 赋给一个新的临时变量，返回这个变量
+func (e *expander) save(value ast.Node, str bool, pre *[]ast.Node) *ast.Identifier {
+	temp := e.temp()
+	if str {
+		temp += "$"
+	}
+	e.addTemp(temp)
+	t := &ast.Identifier{Name: temp}
+	*pre = append(*pre, &ast.Assignment{Target: t, Value: value})
+	e.saved[t] = true
+	return t
+}
+
+// saveRecord 把记录 value 复制到一个新的临时记录中，返回这个记录
+func (e *expander) saveRecord(value ast.Node, t *ast.RecordType, pre *[]ast.Node) *ast.RecordRef {
+	temp := e.temp()
+	e.addTemp(temp + "()")
+	ref := &ast.RecordRef{Name: temp, Type: t}
+	*pre = append(*pre, &ast.DimStmt{Name: temp, Type: t.Name, Record: t}, &ast.RecordAssignment{Target: ref, Value: value})
+	e.savedRecords[ref] = true
+	return ref
+}
+
+// temp 返回下一个临时变量的名称
+func (e *expander) temp() string {
+	e.temps++
+	temp := "CALL." + strconv.Itoa(e.temps)
+	if e.cur != nil {
+		temp = e.cur.prefix + "." + temp
+	}
+	return temp
+}
+
+// addTemp 记录临时变量 name，临时数组后加 "()"
+func (e *expander) addTemp(name string) {
+	if e.cur != nil {
+		e.cur.addLocal(name)
+	} else if !slices.Contains(e.prog.Temps, name) {
+		e.prog.Temps = append(e.prog.Temps, name)
+	}
+}
+
+// operands 按从左到右的顺序展开 nodes 并返回它。某个操作数调用了 FUNCTION 时，先把它
+// 左边的操作数保存到临时变量中，调用中的赋值不会改变已经计算过的值
+func (e *expander) operands(nodes []ast.Node, pre *[]ast.Node) []ast.Node {
+	kept := 0 // nodes[:kept] 已经保存过或不需要保存
+	for i, n := range nodes {
+		var own []ast.Node
+		nodes[i] = e.expr(n, &own)
+		if len(own) == 0 {
+			continue
+		}
+		for ; kept < i; kept++ {
+			nodes[kept] = e.keep(nodes[kept], pre)
+		}
+		*pre = append(*pre, own...)
+	}
+	return nodes
+}
+
+// keep 返回在之后的调用中保持不变的 n：常量和临时变量原样返回，其余的保存到临时变量
+func (e *expander) keep(n ast.Node, pre *[]ast.Node) ast.Node {
+	switch x := n.(type) {
+	case *ast.Number, *ast.StringLiteral:
+		return n
+	case *ast.Identifier:
+		if e.saved[x] {
+			return n
+		}
+	case *ast.RecordRef:
+		if e.savedRecords[x] {
+			return n
+		}
+	}
+	if _, _, t := ast.RecordOf(n); t != nil {
+		return e.saveRecord(n, t, pre)
+	}
+	return e.save(n, isStringExpr(n), pre)
+}
+
+// isStringExpr 判断展开后的表达式的值是否是字符串
+func isStringExpr(n ast.Node) bool {
+	switch x := n.(type) {
+	case *ast.StringLiteral:
+		return true
+	case *ast.Identifier:
+		return strings.HasSuffix(x.Name, "$")
+	case *ast.ArrayAccess:
+		return strings.HasSuffix(x.Name, "$")
+	case *ast.MapAccess:
+		return strings.HasSuffix(x.Name, "$")
+	case *ast.FunctionCall:
+		return strings.HasSuffix(x.Name, "$")
+	case *ast.MapFunc:
+		return x.Func == "KEYS$"
+	case *ast.FieldAccess:
+		f, _ := x.Info()
+		return f.String
+	case *ast.BinaryOp:
+		return isStringExpr(x.Left)
+	}
+	return false
+}
+
+// print 展开 PRINT：调用 FUNCTION 的值之前的部分先输出，函数中输出的内容出现在它们之后
+func (e *expander) print(s *ast.PrintStmt, pre *[]ast.Node) *ast.PrintStmt {
+	part := &ast.PrintStmt{Trailer: s.Trailer}
+	for i, v := range s.Values {
+		var own []ast.Node
+		v = e.expr(v, &own)
+		sep := ";"
+		if i > 0 {
+			sep = s.Separators[i-1]
+		}
+		if len(own) > 0 && len(part.Values) > 0 {
+			part.Trailer = ";"
+			*pre = append(*pre, part)
+			part = &ast.PrintStmt{Trailer: s.Trailer}
+			if sep == "," {
+				// 行尾的逗号不输出空格，把它换成下一部分开头的空格
+				part.Values = append(part.Values, &ast.StringLiteral{Value: " "})
+			}
+			sep = ";"
+		}
+		*pre = append(*pre, own...)
+		if len(part.Values) > 0 {
+			part.Separators = append(part.Separators, sep)
+		}
+		part.Values = append(part.Values, v)
+	}
+	return part
+}
+
+// checkJump 检查 GOTO/GOSUB 没有跳进或跳出子程序
+func (e *expander) checkJump(kind string, target int) error {
+	idx, ok := e.lineIdx[target]
+	if !ok {
+		// 目标行不存在由执行引擎报告
+		return nil
+	}
+	owner := e.owner[idx]
+	if owner != nil && (owner != e.cur || idx == owner.start) {
+		return fmt.Errorf("%s %d jumps into %s %s", kind, target, routineKind(owner.Function), owner.source)
+	}
+	if owner == nil && e.cur != nil {
+		return fmt.Errorf("%s %d leaves %s %s", kind, target, routineKind(e.cur.Function), e.cur.source)
+	}
+	return nil
+}
+
+// saveLocals 为递归调用（被调用的子程序能直接或间接调用到调用者）填上要保存的变量和
+// 数组：调用者的 Locals，返回值的临时变量除外，并在 GOSUB 之前把被调用者的局部变量清零。
+// 其余调用前后的 SAVE LOCALS 和 RESTORE LOCALS 被删除
+func (e *expander) saveLocals() {
+	resets := make(map[*ast.GosubStmt][]ast.Node)
+	for _, r := range e.list {
+		for _, c := range r.calls {
+			if !reaches(c.callee, r) {
+				continue
+			}
+			for _, name := range r.Locals {
+				if array, ok := strings.CutSuffix(name, "()"); ok {
+					c.save.Arrays = append(c.save.Arrays, array)
+				} else if name != c.result {
+					c.save.Vars = append(c.save.Vars, name)
+				}
+			}
+			// 被调用的子程序可能正在执行，它的局部变量还没有在 END SUB 清零
+			for _, name := range c.callee.vars {
+				resets[c.gosub] = append(resets[c.gosub], &ast.Assignment{Target: &ast.Identifier{Name: name}, Value: zeroValue(name)})
+			}
+		}
+	}
+	for _, line := range e.prog.Lines {
+		line.Statements = fillSaves(line.Statements, resets)
+	}
+}
+
+// reaches 判断 from 是否直接或间接调用 to（from == to 时为真）
+func reaches(from, to *routine) bool {
+	seen := make(map[*routine]bool)
+	var visit func(r *routine) bool
+	visit = func(r *routine) bool {
+		if r == to {
+			return true
+		}
+		if seen[r] {
+			return false
+		}
+		seen[r] = true
+		for _, c := range r.calls {
+			if visit(c.callee) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(from)
+}
+
+// fillSaves 删除 stmts 中不保存任何变量的 SAVE LOCALS 和 RESTORE LOCALS，并在递归调用的
+// GOSUB 之前插入 resets 中清零局部变量的赋值
+func fillSaves(stmts []ast.Node, resets map[*ast.GosubStmt][]ast.Node) []ast.Node {
+	empty := func(s *ast.SaveLocalsStmt) bool {
+		return len(s.Vars) == 0 && len(s.Arrays) == 0
+	}
+	out := stmts[:0:0]
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.GosubStmt:
+			out = append(out, resets[s]...)
+		case *ast.SaveLocalsStmt:
+			if empty(s) {
+				continue
+			}
+		case *ast.RestoreLocalsStmt:
+			if empty(s.Saved) {
+				continue
+			}
+		case *ast.IfStmt:
+			s.ThenStmts = fillSaves(s.ThenStmts, resets)
+			s.ElseStmts = fillSaves(s.ElseStmts, resets)
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
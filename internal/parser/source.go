@@ -0,0 +1,313 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// includeDirective 匹配 $INCLUDE 元命令：'$INCLUDE: "lib.bas"，也可以写在 REM 之后、
+// 用单引号括起文件名
+var includeDirective = regexp.MustCompile(`(?i)^('|REM)\s*\$INCLUDE\s*:\s*(?:"([^"]*)"|'([^']*)')\s*$`)
+
+// LoadFile 读取并解析 BASIC 源文件，见 ParseProgram
+func LoadFile(filename string) (*ast.Program, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProgram(filename, data)
+}
+
+// ParseProgram 解析 BASIC 程序，并完成只有看到整个程序才能做的处理：
+//   - 展开 '$INCLUDE: "file" 元命令，被包含的文件相对于包含它的文件查找，循环包含报错
+//   - 程序没有行号时按顺序分配内部行号（1, 2, 3...），行号和无行号的行不能混用
+//   - 把 GOTO/GOSUB 的标签换成标签所在行的行号；无行号程序只能跳转到标签
+//   - 解析 TYPE 记录的声明、字段访问和整体赋值（见 expandRecords）
+//   - 把字典的用法和 FOR EACH 换成执行引擎支持的语句和表达式（见 expandMaps）
+//   - 把 SUB/FUNCTION 展开为 GOSUB 子程序（见 expandRoutines）
+//
+// 错误信息用 "文件:行" 指出出错的位置，每行的位置记录在 Line.Pos 中
+func ParseProgram(filename string, data []byte) (*ast.Program, error) {
+	l := &loader{}
+	lines, err := l.load(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	prog := &ast.Program{Lines: lines}
+	if err := numberLines(prog, l.included); err != nil {
+		return nil, err
+	}
+	if err := resolveLabels(prog); err != nil {
+		return nil, err
+	}
+	prog.Decls = declarations(prog)
+	if err := expandRecords(prog); err != nil {
+		return nil, err
+	}
+	if err := expandMaps(prog); err != nil {
+		return nil, err
+	}
+	if err := expandRoutines(prog); err != nil {
+		return nil, err
+	}
+	return prog, nil
+}
+
+// ExpandDirect 展开直接模式语句中的记录字段和字典。decls 是最近运行的程序的
+// Program.Decls，直接模式语句因此可以像程序中一样读写 X.Price、Inv(I).Name、D(K$)，
+// 在一行中写完整个 FOR EACH 循环。错误信息以 "direct:" 开头
+func ExpandDirect(prog *ast.Program, decls []ast.Node) error {
+	for _, line := range prog.Lines {
+		line.Pos = ast.Position{File: "direct"}
+	}
+	records := newRecordExpander()
+	declared := &ast.Program{Lines: []*ast.Line{{Statements: slices.Clone(decls)}}}
+	if err := records.collectTypes(declared); err != nil {
+		return err
+	}
+	if err := records.collectRecords(declared); err != nil {
+		return err
+	}
+	for _, line := range prog.Lines {
+		stmts, err := records.expandStmts(line.Statements)
+		if err != nil {
+			return fmt.Errorf("%s: %v", line.Pos, err)
+		}
+		line.Statements = stmts
+	}
+
+	maps := newMapExpander()
+	for _, p := range []*ast.Program{declared, prog} {
+		if err := maps.collect(p); err != nil {
+			return err
+		}
+	}
+	return maps.expand(prog)
+}
+
+// declarations 返回程序中的 TYPE 定义和子程序之外 DIM ... AS 的声明
+func declarations(prog *ast.Program) []ast.Node {
+	var decls []ast.Node
+	inRoutine := false
+	var visit func(stmts []ast.Node)
+	visit = func(stmts []ast.Node) {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *ast.TypeStmt, *ast.FieldStmt, *ast.EndTypeStmt:
+				decls = append(decls, stmt)
+			case *ast.SubStmt:
+				inRoutine = true
+			case *ast.EndSubStmt:
+				inRoutine = false
+			case *ast.IfStmt:
+				visit(s.ThenStmts)
+				visit(s.ElseStmts)
+			case *ast.DimStmt:
+				if s.Type != "" && !inRoutine {
+					decls = append(decls, stmt)
+				}
+			}
+		}
+	}
+	for _, line := range prog.Lines {
+		visit(line.Statements)
+	}
+	return decls
+}
+
+// loader 展开 $INCLUDE
+type loader struct {
+	files    []string // 正在展开的文件（绝对路径），用于检测循环包含
+	included bool     // 是否展开过 $INCLUDE
+}
+
+// load 解析一个文件，返回展开 $INCLUDE 之后的所有非空行
+func (l *loader) load(filename string, data []byte) ([]*ast.Line, error) {
+	parsed, err := Parse(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	prog, ok := parsed.(*ast.Program)
+	if !ok {
+		return nil, fmt.Errorf("%s: not a program", filename)
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	l.files = append(l.files, abs)
+	defer func() { l.files = l.files[:len(l.files)-1] }()
+
+	var lines []*ast.Line
+	for _, line := range prog.Lines {
+		line.Pos.File = filename
+		name, ok, err := includeName(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", line.Pos, err)
+		}
+		if !ok {
+			// 空行不属于程序，也不参与行号检查
+			if line.LineNumber >= 0 || line.Label != "" || len(line.Statements) > 0 {
+				lines = append(lines, line)
+			}
+			continue
+		}
+
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filename), name)
+		}
+		if err := l.checkCycle(path); err != nil {
+			return nil, fmt.Errorf("%s: %v", line.Pos, err)
+		}
+		included, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: $INCLUDE: %v", line.Pos, err)
+		}
+		more, err := l.load(path, included)
+		if err != nil {
+			return nil, err
+		}
+		l.included = true
+		// 带行号的 $INCLUDE 行保留为注释，GOTO 到这一行仍然有效
+		if line.LineNumber >= 0 {
+			lines = append(lines, line)
+		}
+		lines = append(lines, more...)
+	}
+	return lines, nil
+}
+
+// checkCycle 检查包含 path 是否会形成循环
+func (l *loader) checkCycle(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	idx := slices.Index(l.files, abs)
+	if idx < 0 {
+		return nil
+	}
+	cycle := make([]string, 0, len(l.files)-idx+1)
+	for _, file := range l.files[idx:] {
+		cycle = append(cycle, filepath.Base(file))
+	}
+	cycle = append(cycle, filepath.Base(abs))
+	return fmt.Errorf("$INCLUDE cycle: %s", strings.Join(cycle, " -> "))
+}
+
+// includeName 返回 line 中 $INCLUDE 元命令的文件名。元命令必须单独占一行
+func includeName(line *ast.Line) (string, bool, error) {
+	for idx, stmt := range line.Statements {
+		rem, ok := stmt.(*ast.RemStmt)
+		if !ok {
+			continue
+		}
+		m := includeDirective.FindStringSubmatch(strings.TrimSpace(rem.Text))
+		if m == nil {
+			continue
+		}
+		if idx > 0 || line.Label != "" {
+			return "", false, fmt.Errorf("$INCLUDE must be on a line by itself")
+		}
+		name := m[2] + m[3]
+		if name == "" {
+			return "", false, fmt.Errorf("$INCLUDE needs a file name")
+		}
+		return name, true, nil
+	}
+	return "", false, nil
+}
+
+// numberLines 检查行号：程序要么每行都有行号，要么都没有。没有行号时按顺序分配内部
+// 行号；有行号且展开过 $INCLUDE 时按行号排序，不同文件使用同一行号报错
+func numberLines(prog *ast.Program, included bool) error {
+	if len(prog.Lines) == 0 {
+		return nil
+	}
+	numbered := prog.Lines[0].LineNumber >= 0
+	for _, line := range prog.Lines {
+		if (line.LineNumber >= 0) != numbered {
+			return fmt.Errorf("%s: cannot mix numbered and unnumbered lines", line.Pos)
+		}
+	}
+
+	if !numbered {
+		prog.Unnumbered = true
+		for idx, line := range prog.Lines {
+			line.LineNumber = idx + 1
+		}
+		return nil
+	}
+	if !included {
+		return nil
+	}
+	slices.SortStableFunc(prog.Lines, func(a, b *ast.Line) int {
+		return a.LineNumber - b.LineNumber
+	})
+	for idx := 1; idx < len(prog.Lines); idx++ {
+		prev, line := prog.Lines[idx-1], prog.Lines[idx]
+		if line.LineNumber == prev.LineNumber && line.Pos.File != prev.Pos.File {
+			return fmt.Errorf("%s: line %d is also defined at %s", line.Pos, line.LineNumber, prev.Pos)
+		}
+	}
+	return nil
+}
+
+// resolveLabels 把 GOTO/GOSUB 的标签换成行号。标签不区分大小写。无行号程序的
+// 内部行号不属于源文件，跳转到行号的 GOTO/GOSUB 报错
+func resolveLabels(prog *ast.Program) error {
+	labels := make(map[string]*ast.Line)
+	for _, line := range prog.Lines {
+		if line.Label == "" {
+			continue
+		}
+		key := strings.ToUpper(line.Label)
+		if prev, ok := labels[key]; ok {
+			return fmt.Errorf("%s: label %s is already defined at %s", line.Pos, line.Label, prev.Pos)
+		}
+		labels[key] = line
+	}
+
+	for _, line := range prog.Lines {
+		var err error
+		resolve := func(keyword, label string, num *int) {
+			if label == "" {
+				if prog.Unnumbered && err == nil {
+					err = fmt.Errorf("%s: %s %d: a program without line numbers must jump to a label", line.Pos, keyword, *num)
+				}
+				return
+			}
+			if target, ok := labels[strings.ToUpper(label)]; ok {
+				*num = target.LineNumber
+			} else if err == nil {
+				err = fmt.Errorf("%s: undefined label %s", line.Pos, label)
+			}
+		}
+		var visit func(stmts []ast.Node)
+		visit = func(stmts []ast.Node) {
+			for _, stmt := range stmts {
+				switch s := stmt.(type) {
+				case *ast.GotoStmt:
+					resolve("GOTO", s.Label, &s.LineNumber)
+				case *ast.GosubStmt:
+					resolve("GOSUB", s.Label, &s.LineNumber)
+				case *ast.IfStmt:
+					visit(s.ThenStmts)
+					visit(s.ElseStmts)
+				}
+			}
+		}
+		visit(line.Statements)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
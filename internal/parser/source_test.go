@@ -0,0 +1,257 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/parser"
+)
+
+func TestParseProgram(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("lib.bas", "Show: PRINT N\nRETURN\n")
+	main := write("main.bas", "N = 3\n\nloop:\n  GOSUB show\n  N = N - 1\n  IF N > 0 THEN GOTO Loop\nEND\n'$INCLUDE: \"lib.bas\"\n")
+
+	prog, err := parser.LoadFile(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prog.Unnumbered || len(prog.Lines) != 8 {
+		t.Fatalf("got %d lines (unnumbered %v), want 8 unnumbered lines", len(prog.Lines), prog.Unnumbered)
+	}
+	for idx, line := range prog.Lines {
+		if line.LineNumber != idx+1 {
+			t.Errorf("line %d has number %d", idx, line.LineNumber)
+		}
+	}
+	if gosub := prog.Lines[2].Statements[0].(*ast.GosubStmt); gosub.LineNumber != 7 {
+		t.Errorf("GOSUB show jumps to line %d, want 7", gosub.LineNumber)
+	}
+	ifStmt := prog.Lines[4].Statements[0].(*ast.IfStmt)
+	if jump := ifStmt.ThenStmts[0].(*ast.GotoStmt); jump.LineNumber != 2 || jump.String() != "GOTO Loop" {
+		t.Errorf("GOTO Loop = %q to line %d, want line 2", jump, jump.LineNumber)
+	}
+	if pos := prog.Lines[6].Pos; filepath.Base(pos.File) != "lib.bas" || pos.Line != 1 {
+		t.Errorf("included line at %s, want lib.bas:1", pos)
+	}
+
+	write("a.bas", "'$INCLUDE: 'b.bas'\n")
+	write("b.bas", "PRINT 1\n'$INCLUDE: 'a.bas'\n")
+	for _, tt := range []struct{ src, want string }{
+		{"'$INCLUDE: 'a.bas'\n", "b.bas:2: $INCLUDE cycle: a.bas -> b.bas -> a.bas"},
+		{"'$INCLUDE: 'missing.bas'\n", "main.bas:1: $INCLUDE"},
+		{"PRINT 1\nGOTO Nowhere\n", "main.bas:2: undefined label Nowhere"},
+		{"A: PRINT 1\na: PRINT 2\n", "main.bas:2: label a is already defined at"},
+		{"10 PRINT 1\nPRINT 2\n", "main.bas:2: cannot mix numbered and unnumbered lines"},
+		{"PRINT 1\nGOTO 1\n", "main.bas:2: GOTO 1: a program without line numbers must jump to a label"},
+		{"Top: PRINT 1\nIF 1 THEN GOSUB 1\n", "main.bas:2: GOSUB 1: a program without line numbers"},
+	} {
+		_, err := parser.ParseProgram(main, []byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseProgram(%q) = %v, want an error containing %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestRoutines(t *testing.T) {
+	src := "DECLARE SUB Show(N$)\nCALL Show(Twice$(\"ab\"))\nEND\nSUB Show(N$)\n  PRINT N$\nEND SUB\nFUNCTION Twice$(S$)\n  Twice$ = S$ + S$\nEND FUNCTION\n"
+	prog, err := parser.ParseProgram("main.bas", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prog.Routines) != 2 || prog.Routines[0].Line != 5 || prog.Routines[1].Name != "TWICE$" {
+		t.Fatalf("routines %+v, want SHOW at line 5 and TWICE$", prog.Routines)
+	}
+	if got := prog.Routines[0].ParamVars; len(got) != 1 || got[0] != "SHOW.1$" {
+		t.Errorf("SHOW passes its parameter in %v, want [SHOW.1$]", got)
+	}
+	if jump := prog.Lines[3].Statements[0].(*ast.GotoStmt); jump.LineNumber != 7 {
+		t.Errorf("SUB line jumps to line %d, want 7 after END SUB", jump.LineNumber)
+	}
+	var calls []string
+	for _, stmt := range prog.Lines[1].Statements {
+		calls = append(calls, stmt.String())
+	}
+	want := "LET TWICE.1$ = \"ab\"|LET TWICE$ = \"\"|GOSUB Twice$|LET CALL.1$ = TWICE$|LET SHOW.1$ = CALL.1$|GOSUB Show"
+	if got := strings.Join(calls, "|"); got != want {
+		t.Errorf("CALL expands to %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct{ src, want string }{
+		{"SUB A\nSUB B\nEND SUB\nEND SUB\n", "main.bas:2: SUB inside SUB A"},
+		{"SUB A\nPRINT 1\n", "main.bas:1: SUB A has no END SUB"},
+		{"FUNCTION F(X, X)\nEND FUNCTION\n", "FUNCTION F has two parameters named X"},
+		{"SUB A(X)\nEND SUB\nCALL A\n", "main.bas:3: SUB A expects 1 arguments, got 0"},
+		{"SUB A\nEND SUB\nPRINT A()\n", "SUB A has no value; use CALL A"},
+		{"FUNCTION F\nEND FUNCTION\nF = 1\n", "main.bas:3: cannot assign to FUNCTION F outside it"},
+		{"PRNT \"x\"\n", "main.bas:1: unknown statement PRNT"},
+		{"GOTO In\nSUB A\nIn: PRINT 1\nEND SUB\n", "main.bas:1: GOTO 3 jumps into SUB A"},
+		{"EXPORT SUB A\nEND SUB\n", "EXPORT is only allowed in a MODULE"},
+		{"MODULE M\nPRINT 1\n", "MODULE M can only contain SUB, FUNCTION and DECLARE"},
+		{"DECLARE FUNCTION F(A$)\nFUNCTION F(A)\nEND FUNCTION\n", "DECLARE F: parameter A$ does not match A"},
+	} {
+		_, err := parser.ParseProgram("main.bas", []byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseProgram(%q) = %v, want an error containing %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	src := "TYPE Pt: X AS DOUBLE: Y AS DOUBLE: END TYPE\nTYPE Item\n  Name AS STRING\n  At AS Pt\nEND TYPE\nDIM Inv(2) AS Item\nDIM It AS Item\nIt.At.X = 1\nInv(1) = It\nPRINT Inv(1).Name; It.At.Y\n"
+	prog, err := parser.ParseProgram("main.bas", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := func(line int) ast.Node { return prog.Lines[line-1].Statements[0] }
+	if len(prog.Lines[1].Statements) != 0 {
+		t.Errorf("TYPE lines keep %v", prog.Lines[1].Statements)
+	}
+	dim := stmt(6).(*ast.DimStmt)
+	if dim.Record == nil || dim.Record.Name != "Item" || dim.Record.Shape() != "$##" {
+		t.Errorf("DIM Inv(2) AS Item has record type %+v", dim.Record)
+	}
+	for _, tt := range []struct {
+		field  ast.Node
+		record string
+		slot   int
+	}{
+		{stmt(8).(*ast.Assignment).Target, "It", 1},
+		{stmt(10).(*ast.PrintStmt).Values[0], "Inv(1)", 0},
+		{stmt(10).(*ast.PrintStmt).Values[1], "It", 2},
+	} {
+		ref, slot := tt.field.(*ast.FieldAccess).Slot()
+		if ref.String() != tt.record || slot != tt.slot {
+			t.Errorf("%s is slot %d of %s, want slot %d of %s", tt.field, slot, ref, tt.slot, tt.record)
+		}
+	}
+	if copy, ok := stmt(9).(*ast.RecordAssignment); !ok || copy.String() != "LET Inv(1) = It" {
+		t.Errorf("line 9 is %#v, want a RecordAssignment", stmt(9))
+	}
+
+	// 记录参数在调用处用 DIM 创建，再复制传入的记录
+	src = "TYPE Pt: X AS DOUBLE: END TYPE\nDIM P AS Pt\nSUB Show(Q AS Pt, N)\nPRINT Q.X\nEND SUB\nShow P, 1\n"
+	prog, err = parser.ParseProgram("main.bas", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stmts []string
+	for _, stmt := range prog.Lines[5].Statements {
+		stmts = append(stmts, stmt.String())
+	}
+	if got, want := strings.Join(stmts, "|"), "DIM SHOW.1 AS Pt|LET SHOW.1 = P|LET SHOW.2 = 1|GOSUB Show"; got != want {
+		t.Errorf("Show P, 1 lowers to %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct{ src, want string }{
+		{"TYPE A\nX AS DOUBLE\n", "main.bas:1: TYPE A has no END TYPE"},
+		{"TYPE A: X AS B: END TYPE\nTYPE B: Y AS A: END TYPE\n", "TYPE A contains itself (A -> B -> A)"},
+		{"TYPE A: X AS WORD: END TYPE\n", "main.bas:1: unknown type WORD"},
+		{"TYPE A: X AS DOUBLE: END TYPE\nDIM R AS A\nPRINT R.Y\n", "main.bas:3: TYPE A has no field Y"},
+		{"TYPE A: X AS DOUBLE: END TYPE\nDIM R AS A\nPRINT R\n", "R is a record; use one of its fields"},
+		{"TYPE A: X AS DOUBLE: END TYPE\nTYPE B: X AS DOUBLE: END TYPE\nDIM R AS A\nDIM S AS B\nR = S\n", "cannot assign S (TYPE B) to R (TYPE A)"},
+		{"TYPE A: X AS DOUBLE: END TYPE\nDIM R AS A\nDIM R(3)\n", "R is both a record and an array"},
+		{"TYPE A: X AS DOUBLE: END TYPE\nSUB S(R AS A)\nEND SUB\nS 1\n", "S: argument 1 must be a record of TYPE A"},
+		{"MODULE M\nTYPE A: X AS DOUBLE: END TYPE\nEXPORT SUB S(R AS A)\nEND SUB\n", "record parameters cannot be passed between modules"},
+	} {
+		_, err := parser.ParseProgram("main.bas", []byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseProgram(%q) = %v, want an error containing %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestExpandDirect(t *testing.T) {
+	src := "TYPE Pt: X AS DOUBLE: Y AS DOUBLE: END TYPE\nDIM P AS Pt\nDIM Ps(3) AS Pt\nDIM M AS MAP\nSUB S\nDIM L AS Pt\nEND SUB\n"
+	prog, err := parser.ParseProgram("main.bas", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ input, want, err string }{
+		{"PRINT P.X; Ps(1).Y", "PRINT P.X, Ps(1).Y", ""},
+		{"Ps(2) = P", "LET Ps(2) = P", ""},
+		{"FOR EACH K$ IN M: PRINT K$; M(K$): DELETEKEY M, K$: NEXT", "FOR EACH K$ IN M|PRINT K$, M(K$)|DELETEKEY M, K$|NEXT", ""},
+		{"PRINT P.Z", "", "direct: TYPE Pt has no field Z"},
+		{"PRINT L.X", "", "direct: L.X: L is not a record"},
+		{"FOR EACH K$ IN M", "", "direct: FOR EACH without NEXT"},
+	} {
+		parsed, err := parser.Parse("direct", []byte("0 "+tt.input+"\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		direct := parsed.(*ast.Program)
+		err = parser.ExpandDirect(direct, prog.Decls)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ExpandDirect(%q) = %v, want %s", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ExpandDirect(%q) = %v", tt.input, err)
+		}
+		var stmts []string
+		for _, stmt := range direct.Lines[0].Statements {
+			stmts = append(stmts, stmt.String())
+		}
+		if got := strings.Join(stmts, "|"); got != tt.want {
+			t.Errorf("%q lowers to %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMaps(t *testing.T) {
+	src := "DIM D AS MAP\nD(\"a\") = 1\nPRINT COUNT(D); HASKEY(D, \"b\"); KEYS$(D, 0)\nFOR EACH K$ IN D\nPRINT K$, D(K$)\nNEXT\nEND\n"
+	prog, err := parser.ParseProgram("main.bas", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		line int
+		want string
+	}{
+		{2, "LET D(\"a\") = 1"},
+		{3, "PRINT COUNT(D), HASKEY(D, \"b\"), KEYS$(D, 0)"},
+		{4, "FOR EACH K$ IN D"},
+		{5, "PRINT K$, D(K$)"},
+		{6, "NEXT"},
+	} {
+		var stmts []string
+		for _, stmt := range prog.Lines[tt.line-1].Statements {
+			stmts = append(stmts, stmt.String())
+		}
+		if got := strings.Join(stmts, "|"); got != tt.want {
+			t.Errorf("line %d lowers to %q, want %q", tt.line, got, tt.want)
+		}
+	}
+	if _, ok := prog.Lines[1].Statements[0].(*ast.Assignment).Target.(*ast.MapAccess); !ok {
+		t.Errorf("D(\"a\") is not lowered to a MapAccess")
+	}
+
+	for _, tt := range []struct{ src, want string }{
+		{"DIM D(3) AS MAP\n", "main.bas:1: DIM D AS MAP: a MAP has no dimensions"},
+		{"DIM D AS MAP\nDIM D(2)\n", "D is both an array and a MAP"},
+		{"DIM D AS MAP\nPRINT D\n", "main.bas:2: D is a MAP; use D(key)"},
+		{"DIM D AS MAP\nFOR EACH K IN D\nNEXT\n", "the loop variable must be a string variable"},
+		{"DIM D AS MAP\nFOR EACH K$ IN D: FOR I = 1 TO 2: NEXT\nNEXT I\n", "main.bas:3: NEXT I does not match FOR EACH K$ IN D"},
+		{"DIM D AS MAP\nFOR EACH K$ IN D\nIF K$ = \"a\" THEN NEXT\nNEXT\n", "main.bas:3: NEXT of FOR EACH K$ cannot be inside a single-line IF"},
+		{"DIM D AS MAP\nFOR EACH K$ IN D\n", "main.bas:2: FOR EACH without NEXT"},
+		{"DIM A(2)\nDELETEKEY A, \"x\"\n", "A is not a MAP"},
+		{"TYPE T: M AS MAP: END TYPE\n", "field M of TYPE T cannot be a MAP"},
+	} {
+		_, err := parser.ParseProgram("main.bas", []byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseProgram(%q) = %v, want an error containing %q", tt.src, err, tt.want)
+		}
+	}
+}
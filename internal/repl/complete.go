@@ -0,0 +1,134 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+)
+
+// HistoryFileName 历史记录文件名，保存在用户主目录下
+const HistoryFileName = ".zb_history"
+
+// basicKeywords 语句和运算符关键字
+var basicKeywords = []string{
+	"ALL", "AND", "AS", "CALL", "CHAIN", "COMMON", "COUNT", "DECLARE", "DELETEKEY", "DIM", "EACH",
+	"ELSE", "END", "EXPORT", "FOR", "FUNCTION", "GOSUB", "GOTO", "HASKEY", "IF", "IN", "INPUT",
+	"KEYS$", "LET", "LINE", "MAP", "MOD", "MODULE", "NEXT", "NOT", "OR", "PRINT", "REM", "RETURN",
+	"SHARED", "STEP", "STOP", "SUB", "THEN", "TO", "TYPE",
+}
+
+// replCommands 交互命令（不含单字母缩写）
+var replCommands = []string{
+	"AST", "AUTO", "CLEAR", "CONT", "DELETE", "DIFF", "DISASM", "EDIT", "EXIT", "FORMAT",
+	"HELP", "LIST", "LOAD", "MERGE", "NEW", "QUIT", "REDO", "RENUM", "RESTORE", "RUN",
+	"SAVE", "SNAPSHOT", "UNDO",
+}
+
+// historyFile 返回历史记录文件的路径，找不到用户主目录时返回空字符串（不保存历史）
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HistoryFileName)
+}
+
+// Completions 返回 Tab 补全的候选（已排序、不重复）：
+// prefix 全是数字时补全程序中的行号，否则不区分大小写地匹配关键字、交互命令、
+// 内置函数以及会话中已有的变量和数组名（展开子程序时生成的名称除外，记录变量换成它的各个字段）。
+// prefix 为空时没有候选
+func Completions(prefix string, store *CodeStore, session *Session) []string {
+	if prefix == "" {
+		return nil
+	}
+	var candidates []string
+	if _, err := strconv.Atoi(prefix); err == nil {
+		for _, num := range store.GetLineNumbers() {
+			if s := strconv.Itoa(num); strings.HasPrefix(s, prefix) {
+				candidates = append(candidates, s)
+			}
+		}
+		return candidates
+	}
+
+	upper := strings.ToUpper(prefix)
+	seen := make(map[string]bool)
+	add := func(names ...string) {
+		for _, name := range names {
+			if strings.HasPrefix(name, upper) && !seen[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	add(basicKeywords...)
+	add(replCommands...)
+	add(bytecode.BuiltinNames...)
+	for name := range session.vars.Scalars {
+		if !session.internal[name] {
+			add(name)
+		}
+	}
+	fields := recordFields(session.decls)
+	for name := range session.vars.Arrays {
+		if session.internal[name+"()"] {
+			continue
+		}
+		if paths, ok := fields[name]; ok {
+			add(paths...)
+		} else {
+			add(name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// internalNames 返回 parser.ParseProgram 展开 prog 的子程序时生成的变量和数组名（数组名后加 "()"）：
+// 参数（"SHOW.1"，记录参数是数组 "SHOW.1()"）、局部变量（"SHOW.X"）和临时变量
+// （"CALL.1"）。FUNCTION 的返回值与函数同名，仍然参与补全
+func internalNames(prog *ast.Program) map[string]bool {
+	names := make(map[string]bool)
+	for _, r := range prog.Routines {
+		for _, name := range r.Locals {
+			if name != r.Name {
+				names[name] = true
+			}
+		}
+	}
+	for _, name := range prog.Temps {
+		names[name] = true
+	}
+	return names
+}
+
+// recordFields 返回 decls 中每个记录变量（不含记录数组）的字段，如 "POS.X"、"IT.AT.Y"
+func recordFields(decls []ast.Node) map[string][]string {
+	records := make(map[string][]string)
+	for _, decl := range decls {
+		if s, ok := decl.(*ast.DimStmt); ok && s.Record != nil && len(s.Sizes) == 0 {
+			name := strings.ToUpper(s.Name)
+			records[name] = fieldPaths(name, s.Record)
+		}
+	}
+	return records
+}
+
+// fieldPaths 返回记录 prefix 的各个字段，嵌套的记录展开为它的字段
+func fieldPaths(prefix string, t *ast.RecordType) []string {
+	var paths []string
+	for _, f := range t.Fields {
+		path := prefix + "." + f.Name
+		if f.Record != nil {
+			paths = append(paths, fieldPaths(path, f.Record)...)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,229 @@
+package repl
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+)
+
+// JournalLimit UNDO 最多能撤销的修改次数
+const JournalLimit = 100
+
+// lineChange 一行在一次修改前后的内容
+type lineChange struct {
+	before, after   string
+	existed, exists bool // 修改前、修改后这一行是否存在
+}
+
+// journalEntry 一次修改（一条命令）改动的所有行
+type journalEntry struct {
+	label   string // 修改的说明，UNDO/REDO 时显示
+	changes map[int]lineChange
+}
+
+// change 把一次修改应用到程序并记入修改日志，清空 REDO；没有改动任何行时不记录
+func (cs *CodeStore) change(label string, changes map[int]lineChange) {
+	if len(changes) == 0 {
+		return
+	}
+	cs.apply(changes, true)
+	cs.undo = append(cs.undo, journalEntry{label: label, changes: changes})
+	if len(cs.undo) > JournalLimit {
+		cs.undo = cs.undo[len(cs.undo)-JournalLimit:]
+	}
+	cs.redo = nil
+}
+
+// apply 把修改应用到程序：forward 为 true 时改为修改后的内容，否则恢复修改前的内容
+func (cs *CodeStore) apply(changes map[int]lineChange, forward bool) {
+	for num, c := range changes {
+		code, exists := c.after, c.exists
+		if !forward {
+			code, exists = c.before, c.existed
+		}
+		if exists {
+			cs.lines[num] = code
+		} else {
+			delete(cs.lines, num)
+		}
+	}
+	cs.isDirty = true
+	cs.cachedAST = nil
+	cs.revision++
+}
+
+// diff 计算把整个程序替换为 lines 需要改动的行
+func (cs *CodeStore) diff(lines map[int]string) map[int]lineChange {
+	changes := make(map[int]lineChange)
+	for num, before := range cs.lines {
+		after, exists := lines[num]
+		if !exists || after != before {
+			changes[num] = lineChange{before: before, existed: true, after: after, exists: exists}
+		}
+	}
+	for num, after := range lines {
+		if _, existed := cs.lines[num]; !existed {
+			changes[num] = lineChange{after: after, exists: true}
+		}
+	}
+	return changes
+}
+
+// Undo 撤销最近一次修改，返回它的说明；没有可以撤销的修改时返回 false
+func (cs *CodeStore) Undo() (string, bool) {
+	if len(cs.undo) == 0 {
+		return "", false
+	}
+	entry := cs.undo[len(cs.undo)-1]
+	cs.undo = cs.undo[:len(cs.undo)-1]
+	cs.apply(entry.changes, false)
+	cs.redo = append(cs.redo, entry)
+	return entry.label, true
+}
+
+// Redo 重做最近一次被撤销的修改，返回它的说明；之后有新的修改时不能再重做
+func (cs *CodeStore) Redo() (string, bool) {
+	if len(cs.redo) == 0 {
+		return "", false
+	}
+	entry := cs.redo[len(cs.redo)-1]
+	cs.redo = cs.redo[:len(cs.redo)-1]
+	cs.apply(entry.changes, true)
+	cs.undo = append(cs.undo, entry)
+	return entry.label, true
+}
+
+// SaveSnapshot 以 name（不区分大小写）保存当前程序的快照，覆盖同名的快照
+func (cs *CodeStore) SaveSnapshot(name string) {
+	if cs.snapshots == nil {
+		cs.snapshots = make(map[string]map[int]string)
+	}
+	cs.snapshots[strings.ToUpper(name)] = maps.Clone(cs.lines)
+}
+
+// RestoreSnapshot 把程序恢复为快照的内容；这是一次普通的修改，可以用 UNDO 撤销
+func (cs *CodeStore) RestoreSnapshot(name string) error {
+	snapshot, ok := cs.snapshots[strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("no snapshot named %s", name)
+	}
+	cs.ReplaceAll("RESTORE SNAPSHOT "+strings.ToUpper(name), maps.Clone(snapshot))
+	return nil
+}
+
+// SnapshotNames 返回所有快照的名称（大写、排序后）
+func (cs *CodeStore) SnapshotNames() []string {
+	names := make([]string, 0, len(cs.snapshots))
+	for name := range cs.snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Diff 按行号比较快照和当前程序：只在快照中的行以 "- " 开头，只在当前程序中的
+// 行以 "+ " 开头，内容不同的行先后给出两者。没有差别时返回空切片
+func (cs *CodeStore) Diff(name string) ([]string, error) {
+	snapshot, ok := cs.snapshots[strings.ToUpper(name)]
+	if !ok {
+		return nil, fmt.Errorf("no snapshot named %s", name)
+	}
+	numbers := make([]int, 0, len(snapshot)+len(cs.lines))
+	for num := range snapshot {
+		numbers = append(numbers, num)
+	}
+	for num := range cs.lines {
+		if _, ok := snapshot[num]; !ok {
+			numbers = append(numbers, num)
+		}
+	}
+	sort.Ints(numbers)
+
+	var diff []string
+	for _, num := range numbers {
+		before, existed := snapshot[num]
+		after, exists := cs.lines[num]
+		if existed && exists && before == after {
+			continue
+		}
+		if existed {
+			diff = append(diff, fmt.Sprintf("- %d %s", num, before))
+		}
+		if exists {
+			diff = append(diff, fmt.Sprintf("+ %d %s", num, after))
+		}
+	}
+	return diff, nil
+}
+
+// cmdUndo UNDO 和 REDO 命令
+func cmdUndo(store *CodeStore, redo bool) bool {
+	if redo {
+		if label, ok := store.Redo(); ok {
+			fmt.Printf("Redone: %s\n", label)
+		} else {
+			fmt.Println("Nothing to redo")
+		}
+		return true
+	}
+	if label, ok := store.Undo(); ok {
+		fmt.Printf("Undone: %s\n", label)
+	} else {
+		fmt.Println("Nothing to undo")
+	}
+	return true
+}
+
+// cmdSnapshot SNAPSHOT 命令：SNAPSHOT name 保存快照，不带名称时列出所有快照
+func cmdSnapshot(store *CodeStore, name string) bool {
+	if name == "" {
+		names := store.SnapshotNames()
+		if len(names) == 0 {
+			fmt.Println("(No snapshots)")
+			return true
+		}
+		for _, name := range names {
+			fmt.Printf("%s (%d lines)\n", name, len(store.snapshots[name]))
+		}
+		return true
+	}
+	store.SaveSnapshot(name)
+	fmt.Printf("Snapshot %s saved (%d lines)\n", strings.ToUpper(name), store.Count())
+	return true
+}
+
+// cmdRestoreSnapshot RESTORE SNAPSHOT name 命令
+func cmdRestoreSnapshot(store *CodeStore, name string) bool {
+	if name == "" {
+		fmt.Println("Usage: RESTORE SNAPSHOT <name>")
+		return true
+	}
+	if err := store.RestoreSnapshot(name); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+	fmt.Printf("Snapshot %s restored (%d lines)\n", strings.ToUpper(name), store.Count())
+	return true
+}
+
+// cmdDiff DIFF name 命令：显示快照之后程序的改动
+func cmdDiff(store *CodeStore, name string) bool {
+	if name == "" {
+		fmt.Println("Usage: DIFF <snapshot>")
+		return true
+	}
+	diff, err := store.Diff(name)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+	if len(diff) == 0 {
+		fmt.Println("No differences")
+		return true
+	}
+	for _, line := range diff {
+		fmt.Println(line)
+	}
+	return true
+}
@@ -0,0 +1,528 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"zork-basic/internal/formatter"
+	"zork-basic/internal/interpreter"
+)
+
+// HistoryLimit 历史记录最多保留的条数
+const HistoryLimit = 1000
+
+// LineEditor 交互模式的行编辑器
+// 输入是终端时切换到原始模式，支持光标移动、历史记录、Ctrl-R 反向搜索和 Tab 补全；
+// 否则（管道、文件）逐行读取，不回显也不记录历史
+type LineEditor struct {
+	in          io.Reader
+	out         io.Writer
+	editing     bool                         // 是否启用行编辑
+	fd          int                          // 输入是终端时的文件描述符，-1 表示不切换终端模式
+	history     []string                     // 从旧到新
+	historyFile string                       // 历史记录文件，空表示不保存
+	complete    func(prefix string) []string // 返回以 prefix 开头的补全候选
+}
+
+// EditorOption 行编辑器的配置选项
+type EditorOption func(*LineEditor)
+
+// WithHistoryFile 从 path 加载历史记录，并把之后输入的每一行追加到该文件
+func WithHistoryFile(path string) EditorOption {
+	return func(e *LineEditor) {
+		e.historyFile = path
+	}
+}
+
+// WithCompleter 设置 Tab 补全的候选来源
+func WithCompleter(complete func(prefix string) []string) EditorOption {
+	return func(e *LineEditor) {
+		e.complete = complete
+	}
+}
+
+// WithEditing 强制启用或关闭行编辑，默认只在输入是终端时启用
+func WithEditing(editing bool) EditorOption {
+	return func(e *LineEditor) {
+		e.editing = editing
+	}
+}
+
+// NewLineEditor 创建从 in 读取、向 out 回显的行编辑器
+func NewLineEditor(in io.Reader, out io.Writer, options ...EditorOption) *LineEditor {
+	e := &LineEditor{in: in, out: out, fd: -1}
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) && os.Getenv("TERM") != "dumb" {
+		e.fd = int(f.Fd())
+		e.editing = true
+	}
+	for _, opt := range options {
+		opt(e)
+	}
+	if !e.editing {
+		e.fd = -1
+	}
+	if e.editing && e.historyFile != "" {
+		e.loadHistory()
+	}
+	return e
+}
+
+// ReadLine 显示提示并读取一行，不含行尾换行符；输入结束（或在空行上按 Ctrl-D）时返回 io.EOF
+// 启用行编辑时，非空的行被加入历史记录；Ctrl-C 放弃正在编辑的行，返回空行
+func (e *LineEditor) ReadLine(prompt string) (string, error) {
+	if !e.editing {
+		fmt.Fprint(e.out, prompt)
+		// 逐字节读取，不会读走程序中 INPUT 语句要读的后续输入
+		line, err := interpreter.ReadLine(e.in)
+		if errors.Is(err, interpreter.ErrInputPastEnd) {
+			return "", io.EOF
+		}
+		return line, err
+	}
+
+	if e.fd >= 0 {
+		state, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		// 程序运行时终端恢复为普通模式，INPUT 照常回显
+		defer restoreTerminal(e.fd, state)
+	}
+	ls := &lineState{editor: e, r: bufio.NewReaderSize(oneByteReader{e.in}, 16), prompt: prompt, histPos: len(e.history)}
+	line, err := ls.edit()
+	if err != nil {
+		return "", err
+	}
+	e.addHistory(line)
+	return line, nil
+}
+
+// loadHistory 读取历史记录文件，文件过长时截断为最近的 HistoryLimit 条
+func (e *LineEditor) loadHistory() {
+	data, err := os.ReadFile(e.historyFile)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > HistoryLimit {
+		e.history = e.history[len(e.history)-HistoryLimit:]
+		os.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+	}
+}
+
+// addHistory 把一行加入历史记录并追加到历史记录文件；空行和与上一条相同的行不记录
+func (e *LineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > HistoryLimit {
+		e.history = e.history[1:]
+	}
+	if e.historyFile == "" {
+		return
+	}
+	// 每输入一行就写入文件，EXIT 直接结束进程时也不会丢失
+	f, err := os.OpenFile(e.historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// oneByteReader 每次只读一个字节，让 bufio.Reader 不会预读用户在回车之后键入的内容
+type oneByteReader struct {
+	r io.Reader
+}
+
+func (o oneByteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return o.r.Read(p)
+}
+
+// 控制键
+const (
+	ctrlA     = 1
+	ctrlB     = 2
+	ctrlC     = 3
+	ctrlD     = 4
+	ctrlE     = 5
+	ctrlF     = 6
+	ctrlG     = 7
+	ctrlH     = 8
+	tab       = 9
+	ctrlK     = 11
+	ctrlL     = 12
+	enter     = 13
+	ctrlN     = 14
+	ctrlP     = 16
+	ctrlQ     = 17
+	ctrlR     = 18
+	ctrlS     = 19
+	ctrlU     = 21
+	ctrlW     = 23
+	ctrlX     = 24
+	esc       = 27
+	backspace = 127
+)
+
+// 由转义序列得到的按键，取负值以免与字符冲突
+const (
+	keyUp rune = -1 - iota
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyWordLeft
+	keyWordRight
+	keyPageUp
+	keyPageDown
+	keyUnknown
+)
+
+// lineState 正在编辑的一行
+type lineState struct {
+	editor  *LineEditor
+	r       *bufio.Reader
+	prompt  string
+	buf     []rune
+	pos     int    // 光标在 buf 中的位置
+	histPos int    // 正在显示的历史记录，len(history) 表示正在编辑的新行
+	saved   []rune // 浏览历史记录之前正在编辑的新行
+}
+
+func (ls *lineState) readKey() (rune, error) {
+	return readKey(ls.r)
+}
+
+// readKey 读取一个按键：字符、控制键或转义序列对应的 key* 常量
+func readKey(br *bufio.Reader) (rune, error) {
+	r, _, err := br.ReadRune()
+	if err != nil || r != esc {
+		return r, err
+	}
+	next, _, err := br.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch next {
+	case 'b', 'B':
+		return keyWordLeft, nil
+	case 'f', 'F':
+		return keyWordRight, nil
+	case '[', 'O':
+	default:
+		return keyUnknown, nil
+	}
+	// CSI 序列：参数字节之后是一个 0x40-0x7E 之间的结束字节
+	var params []byte
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b >= 0x40 && b <= 0x7e {
+			switch {
+			case b == 'A':
+				return keyUp, nil
+			case b == 'B':
+				return keyDown, nil
+			case b == 'C':
+				return keyRight, nil
+			case b == 'D':
+				return keyLeft, nil
+			case b == 'H':
+				return keyHome, nil
+			case b == 'F':
+				return keyEnd, nil
+			case b == '~':
+				switch string(params) {
+				case "1", "7":
+					return keyHome, nil
+				case "4", "8":
+					return keyEnd, nil
+				case "3":
+					return keyDelete, nil
+				case "5":
+					return keyPageUp, nil
+				case "6":
+					return keyPageDown, nil
+				}
+			}
+			return keyUnknown, nil
+		}
+		params = append(params, b)
+	}
+}
+
+// edit 处理按键直到回车，返回编辑好的行
+func (ls *lineState) edit() (string, error) {
+	ls.refresh()
+	for {
+		key, err := ls.readKey()
+		if err == io.EOF && len(ls.buf) > 0 {
+			// 输入在行中间结束（例如管道），把已有内容当作一行
+			fmt.Fprint(ls.editor.out, "\r\n")
+			return string(ls.buf), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if key == ctrlR {
+			if key, err = ls.search(); err != nil {
+				return "", err
+			}
+		}
+		line, done, err := ls.handle(key)
+		if done || err != nil {
+			return line, err
+		}
+	}
+}
+
+// handle 处理一个按键；done 表示这一行已经输入完毕
+func (ls *lineState) handle(key rune) (line string, done bool, err error) {
+	switch key {
+	case enter, '\n':
+		fmt.Fprint(ls.editor.out, "\r\n")
+		return string(ls.buf), true, nil
+	case ctrlC:
+		fmt.Fprint(ls.editor.out, "^C\r\n")
+		return "", true, nil
+	case ctrlD:
+		if len(ls.buf) == 0 {
+			return "", true, io.EOF
+		}
+		ls.deleteAt(ls.pos)
+	case ctrlA, keyHome:
+		ls.pos = 0
+	case ctrlE, keyEnd:
+		ls.pos = len(ls.buf)
+	case ctrlB, keyLeft:
+		if ls.pos > 0 {
+			ls.pos--
+		}
+	case ctrlF, keyRight:
+		if ls.pos < len(ls.buf) {
+			ls.pos++
+		}
+	case keyWordLeft:
+		ls.pos = ls.wordStart(ls.pos)
+	case keyWordRight:
+		for ls.pos < len(ls.buf) && !isWordRune(ls.buf[ls.pos]) {
+			ls.pos++
+		}
+		for ls.pos < len(ls.buf) && isWordRune(ls.buf[ls.pos]) {
+			ls.pos++
+		}
+	case backspace, ctrlH:
+		if ls.pos > 0 {
+			ls.pos--
+			ls.deleteAt(ls.pos)
+		}
+	case keyDelete:
+		ls.deleteAt(ls.pos)
+	case ctrlK:
+		ls.buf = ls.buf[:ls.pos]
+	case ctrlU:
+		ls.buf = append(ls.buf[:0], ls.buf[ls.pos:]...)
+		ls.pos = 0
+	case ctrlW:
+		start := ls.wordStart(ls.pos)
+		ls.buf = append(ls.buf[:start], ls.buf[ls.pos:]...)
+		ls.pos = start
+	case ctrlP, keyUp:
+		ls.showHistory(ls.histPos - 1)
+	case ctrlN, keyDown:
+		ls.showHistory(ls.histPos + 1)
+	case ctrlL:
+		fmt.Fprint(ls.editor.out, "\x1b[H\x1b[2J")
+	case tab:
+		ls.completeWord()
+	default:
+		if key < ' ' || key == keyUnknown || key < 0 {
+			return "", false, nil
+		}
+		ls.insert([]rune{key})
+	}
+	ls.refresh()
+	return "", false, nil
+}
+
+// refresh 重新显示提示和正在编辑的行，并把光标移到 pos
+func (ls *lineState) refresh() {
+	var sb strings.Builder
+	sb.WriteString("\r")
+	sb.WriteString(ls.prompt)
+	sb.WriteString(string(ls.buf))
+	sb.WriteString("\x1b[K")
+	if back := len(ls.buf) - ls.pos; back > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dD", back)
+	}
+	io.WriteString(ls.editor.out, sb.String())
+}
+
+func (ls *lineState) insert(text []rune) {
+	ls.buf = append(ls.buf[:ls.pos], append(text, ls.buf[ls.pos:]...)...)
+	ls.pos += len(text)
+}
+
+func (ls *lineState) deleteAt(i int) {
+	if i < len(ls.buf) {
+		ls.buf = append(ls.buf[:i], ls.buf[i+1:]...)
+	}
+}
+
+// wordStart 返回 pos 之前那个单词的开头，跳过中间的空白和符号
+func (ls *lineState) wordStart(pos int) int {
+	for pos > 0 && !isWordRune(ls.buf[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordRune(ls.buf[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// isWordRune 判断 r 能否出现在标识符、关键字或行号中
+func isWordRune(r rune) bool {
+	return r < 128 && formatter.IsIdentByte(byte(r))
+}
+
+// showHistory 显示第 i 条历史记录；i 为 len(history) 时回到正在编辑的新行
+func (ls *lineState) showHistory(i int) {
+	history := ls.editor.history
+	if i < 0 || i > len(history) || i == ls.histPos {
+		return
+	}
+	if ls.histPos == len(history) {
+		ls.saved = append([]rune(nil), ls.buf...)
+	}
+	ls.histPos = i
+	if i == len(history) {
+		ls.buf = ls.saved
+	} else {
+		ls.buf = []rune(history[i])
+	}
+	ls.pos = len(ls.buf)
+}
+
+// search 执行 Ctrl-R 反向增量搜索（不区分大小写），返回结束搜索的按键。
+// 回车和其他编辑键接受找到的行并照常处理，Ctrl-G 或 Ctrl-C 放弃搜索、恢复原来的行
+func (ls *lineState) search() (rune, error) {
+	history := ls.editor.history
+	origBuf, origPos := append([]rune(nil), ls.buf...), ls.pos
+	var query []rune
+	found := len(history) // 当前匹配的历史记录，len(history) 表示还没有匹配
+	failed := false
+
+	// find 从 from 开始向前查找包含 query 的历史记录
+	find := func(from int) {
+		q := strings.ToUpper(string(query))
+		for i := min(from, len(history)-1); i >= 0; i-- {
+			if strings.Contains(strings.ToUpper(history[i]), q) {
+				found, failed = i, false
+				return
+			}
+		}
+		failed = true
+	}
+
+	for {
+		label := "reverse-i-search"
+		if failed {
+			label = "failed " + label
+		}
+		match := ""
+		if found < len(history) {
+			match = history[found]
+		}
+		fmt.Fprintf(ls.editor.out, "\r(%s)`%s': %s\x1b[K", label, string(query), match)
+
+		key, err := ls.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == ctrlR:
+			if len(query) > 0 {
+				find(found - 1)
+			}
+		case key == backspace || key == ctrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(history) - 1)
+			}
+		case key == ctrlG || key == ctrlC:
+			ls.buf, ls.pos = origBuf, origPos
+			ls.refresh()
+			return keyUnknown, nil
+		case key >= ' ':
+			query = append(query, key)
+			find(found)
+		default:
+			if found < len(history) {
+				ls.buf = []rune(history[found])
+				ls.pos = len(ls.buf)
+				ls.histPos = found
+			}
+			ls.refresh()
+			return key, nil
+		}
+	}
+}
+
+// completeWord 补全光标前的单词：只有一个候选时直接补全；有多个时补全到公共前缀，
+// 无法再补全时列出所有候选
+func (ls *lineState) completeWord() {
+	if ls.editor.complete == nil {
+		return
+	}
+	start := ls.pos
+	for start > 0 && isWordRune(ls.buf[start-1]) {
+		start--
+	}
+	prefix := string(ls.buf[start:ls.pos])
+	candidates := ls.editor.complete(prefix)
+	if len(candidates) == 0 {
+		fmt.Fprint(ls.editor.out, "\a")
+		return
+	}
+	common := candidates[0]
+	for _, c := range candidates[1:] {
+		common = commonPrefix(common, c)
+	}
+	if len(candidates) > 1 && utf8.RuneCountInString(common) <= len(ls.buf[start:ls.pos]) {
+		fmt.Fprintf(ls.editor.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		return
+	}
+	// 候选一般是大写的，替换整个单词，让关键字和变量名统一为大写
+	rest := append([]rune(nil), ls.buf[ls.pos:]...)
+	ls.buf = append(ls.buf[:start], []rune(common)...)
+	ls.pos = len(ls.buf)
+	ls.buf = append(ls.buf, rest...)
+}
+
+// commonPrefix 返回 a 和 b 不区分大小写的公共前缀（取 a 的写法）
+func commonPrefix(a, b string) string {
+	ra, rb := []rune(a), []rune(b)
+	n := 0
+	for n < len(ra) && n < len(rb) && strings.EqualFold(string(ra[n]), string(rb[n])) {
+		n++
+	}
+	return string(ra[:n])
+}
@@ -0,0 +1,98 @@
+package repl_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/parser"
+	"zork-basic/internal/repl"
+)
+
+func TestLineEditor(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(history, []byte("PRINT 1\nLET A = 2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys := strings.Join([]string{
+		"PRNT\x1b[D\x1b[DI\r",  // 左移光标后插入
+		"\x1bOA\x10\x1b[A 0\r", // 上翻三条历史后追加
+		"\x12let\r",            // Ctrl-R 不区分大小写搜索
+		"X = 1\x17\x17Y\r",     // Ctrl-W 删除单词
+		"pr\t \"a\"\r",         // Tab 补全关键字
+		"abc\x03",              // Ctrl-C 放弃当前行
+		"\x04",                 // 空行上的 Ctrl-D
+	}, "")
+	complete := func(prefix string) []string {
+		if strings.HasPrefix("PRINT", strings.ToUpper(prefix)) {
+			return []string{"PRINT"}
+		}
+		return nil
+	}
+	e := repl.NewLineEditor(strings.NewReader(keys), io.Discard,
+		repl.WithEditing(true), repl.WithHistoryFile(history), repl.WithCompleter(complete))
+
+	want := []string{"PRINT", "PRINT 1 0", "LET A = 2", "Y", `PRINT "a"`, ""}
+	for _, w := range want {
+		line, err := e.ReadLine("> ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != w {
+			t.Errorf("ReadLine = %q, want %q", line, w)
+		}
+	}
+	if _, err := e.ReadLine("> "); err != io.EOF {
+		t.Errorf("ReadLine after Ctrl-D: err = %v, want EOF", err)
+	}
+
+	// 新输入的行追加到历史记录文件，重复的上一条不再记录
+	data, err := os.ReadFile(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFile := "PRINT 1\nLET A = 2\nPRINT\nPRINT 1 0\nLET A = 2\nY\nPRINT \"a\"\n"
+	if string(data) != wantFile {
+		t.Errorf("history file = %q, want %q", data, wantFile)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	store := repl.NewCodeStore()
+	store.Set(10, "PRINT 1")
+	store.Set(100, "END")
+	store.Set(20, "GOTO 10")
+	session := repl.NewSession("ast")
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"1", []string{"10", "100"}},
+		{"le", []string{"LEFT$", "LEN", "LET"}},
+		{"Go", []string{"GOSUB", "GOTO"}},
+		{"dis", []string{"DISASM"}},
+		{"", nil},
+		{"zz", nil},
+	}
+	for _, tt := range tests {
+		if got := repl.Completions(tt.prefix, store, session); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Completions(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+
+	// 子程序的参数、局部变量和临时变量不是用户写的名称，记录字段是
+	prog, err := parser.ParseProgram("test.bas", []byte("TYPE Pt: X AS DOUBLE: END TYPE\nDIM Pos AS Pt\nPos.X = F(2)\nPRINT Pos.X\nFUNCTION F(N)\nDIM T(2)\nF = N + G(N): Part = 1\nEND FUNCTION\nFUNCTION G(N)\nG = N\nEND FUNCTION\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Run(prog, store.Revision())
+	for prefix, want := range map[string][]string{"pos": {"POS.X"}, "f": {"F", "FOR", "FORMAT", "FUNCTION"}, "g": {"G", "GOSUB", "GOTO"}, "ca": {"CALL"}} {
+		if got := repl.Completions(prefix, store, session); !reflect.DeepEqual(got, want) {
+			t.Errorf("after RUN, Completions(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package repl
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+
+	"zork-basic/internal/formatter"
+)
+
+// LineNumbersIn 获取 from 到 to（含两端）之间的行号（排序后）
+func (cs *CodeStore) LineNumbersIn(from, to int) []int {
+	var numbers []int
+	for _, num := range cs.GetLineNumbers() {
+		if num >= from && num <= to {
+			numbers = append(numbers, num)
+		}
+	}
+	return numbers
+}
+
+// DeleteRange 删除 from 到 to（含两端）之间的所有行，返回删除的行数；
+// 这是一次修改，UNDO 一次恢复所有被删除的行
+func (cs *CodeStore) DeleteRange(from, to int) int {
+	numbers := cs.LineNumbersIn(from, to)
+	lines := maps.Clone(cs.lines)
+	for _, num := range numbers {
+		delete(lines, num)
+	}
+	cs.ReplaceAll(fmt.Sprintf("delete %d lines", len(numbers)), lines)
+	return len(numbers)
+}
+
+// Renumber 把从 old 行开始的所有行依次编号为 start、start+step、...（RENUM），
+// 整个程序中指向这些行的 GOTO/GOSUB 目标随之更新，代码的其余部分原样保留。
+// old 之前的行保持不变；新行号会覆盖已有的行或排到它们前面时拒绝重新编号，程序不变。
+// 返回旧行号到新行号的映射
+func (cs *CodeStore) Renumber(start, old, step int) (map[int]int, error) {
+	if start < 0 || step <= 0 {
+		return nil, fmt.Errorf("invalid RENUM arguments: new line %d, increment %d", start, step)
+	}
+	numbers := cs.GetLineNumbers()
+	lineNumberMap := make(map[int]int)
+	kept := make(map[int]bool)
+	lastKept := -1
+	next := start
+	for _, num := range numbers {
+		if num < old {
+			kept[num] = true
+			lastKept = num
+			continue
+		}
+		if next > math.MaxInt32 {
+			return nil, fmt.Errorf("line number %d is too large", next)
+		}
+		lineNumberMap[num] = next
+		next += step
+	}
+	if len(lineNumberMap) == 0 {
+		return nil, fmt.Errorf("no lines at or after line %d", old)
+	}
+	for _, num := range numbers {
+		if newNum, ok := lineNumberMap[num]; ok && kept[newNum] {
+			return nil, fmt.Errorf("RENUM would overwrite line %d", newNum)
+		}
+	}
+	if start <= lastKept {
+		return nil, fmt.Errorf("RENUM would move lines before line %d", lastKept)
+	}
+
+	lines := make(map[int]string, len(cs.lines))
+	for num, code := range cs.lines {
+		if newNum, ok := lineNumberMap[num]; ok {
+			num = newNum
+		}
+		lines[num] = formatter.RenumberText(code, lineNumberMap)
+	}
+	cs.ReplaceAll("RENUM", lines)
+	return lineNumberMap, nil
+}
+
+// parseLineRange 解析 LIST、DELETE 的行号范围：空字符串表示全部，
+// "N" 只有一行，"N-M" 从 N 到 M，"-M" 从开头到 M，"N-" 从 N 到末尾
+func parseLineRange(arg string) (from, to int, err error) {
+	arg = strings.ReplaceAll(arg, " ", "")
+	if arg == "" {
+		return 0, math.MaxInt, nil
+	}
+	first, last, isRange := strings.Cut(arg, "-")
+	if !isRange {
+		num, err := strconv.Atoi(first)
+		if err != nil || num < 0 {
+			return 0, 0, fmt.Errorf("invalid line number %q", arg)
+		}
+		return num, num, nil
+	}
+	from, to = 0, math.MaxInt
+	if first != "" {
+		if from, err = strconv.Atoi(first); err != nil || from < 0 {
+			return 0, 0, fmt.Errorf("invalid line range %q", arg)
+		}
+	}
+	if last != "" {
+		if to, err = strconv.Atoi(last); err != nil || to < 0 {
+			return 0, 0, fmt.Errorf("invalid line range %q", arg)
+		}
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("invalid line range %q", arg)
+	}
+	return from, to, nil
+}
+
+// cmdRenum RENUM 命令：RENUM [new[, old[, inc]]]，默认从第一行开始、编号为 10、20、30...
+func cmdRenum(store *CodeStore, args string) bool {
+	if store.IsEmpty() {
+		fmt.Println("Error: No program to renumber")
+		return true
+	}
+
+	// 省略的参数（包括 "RENUM , , 5" 中的空位）使用默认值
+	params := []int{10, store.GetLineNumbers()[0], 10}
+	if strings.TrimSpace(args) != "" {
+		fields := strings.Split(args, ",")
+		if len(fields) > len(params) {
+			fmt.Println("Usage: RENUM [new[, old[, increment]]]")
+			return true
+		}
+		for i, field := range fields {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			num, err := strconv.Atoi(field)
+			if err != nil {
+				fmt.Println("Usage: RENUM [new[, old[, increment]]]")
+				return true
+			}
+			params[i] = num
+		}
+	}
+
+	lineNumberMap, err := store.Renumber(params[0], params[1], params[2])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+	fmt.Printf("Program renumbered: %d lines\n", len(lineNumberMap))
+	return true
+}
@@ -4,8 +4,12 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -28,6 +32,10 @@ type CodeStore struct {
 	lines     map[int]string
 	cachedAST *ast.Program // AST 缓存
 	isDirty   bool         // 缓存失效标记
+	revision  int          // 每次修改加一，CONT 据此判断程序是否被修改过
+
+	undo, redo []journalEntry            // 修改日志：可以撤销、重做的修改，最近的在最后
+	snapshots  map[string]map[int]string // 命名快照（名称大写）
 }
 
 // NewCodeStore 创建一个新的代码存储实例
@@ -38,24 +46,50 @@ func NewCodeStore() *CodeStore {
 	}
 }
 
-// Set 添加或更新代码行
+// Set 添加或更新代码行，覆盖的旧内容可以用 UNDO 恢复
 func (cs *CodeStore) Set(lineNumber int, code string) {
-	cs.lines[lineNumber] = code
-	cs.isDirty = true
-	cs.cachedAST = nil
+	before, existed := cs.lines[lineNumber]
+	if existed && before == code {
+		return
+	}
+	cs.change(fmt.Sprintf("line %d", lineNumber), map[int]lineChange{
+		lineNumber: {before: before, existed: existed, after: code, exists: true},
+	})
 }
 
 // Delete 删除代码行
 func (cs *CodeStore) Delete(lineNumber int) bool {
-	if _, exists := cs.lines[lineNumber]; !exists {
+	before, exists := cs.lines[lineNumber]
+	if !exists {
 		return false
 	}
-	delete(cs.lines, lineNumber)
-	cs.isDirty = true
-	cs.cachedAST = nil
+	cs.change(fmt.Sprintf("delete line %d", lineNumber), map[int]lineChange{
+		lineNumber: {before: before, existed: true},
+	})
 	return true
 }
 
+// ReplaceAll 用 lines（行号 -> 代码）替换整个程序，作为一次可以撤销的修改记入日志，
+// label 是 UNDO/REDO 时显示的说明
+func (cs *CodeStore) ReplaceAll(label string, lines map[int]string) {
+	cs.change(label, cs.diff(lines))
+}
+
+// Merge 把 lines 叠加到程序上，行号相同时 lines 中的代码替换原有的行，作为一次
+// 修改记入日志。返回内容被替换的行号（升序）
+func (cs *CodeStore) Merge(label string, lines map[int]string) []int {
+	merged := maps.Clone(cs.lines)
+	var replaced []int
+	for _, num := range slices.Sorted(maps.Keys(lines)) {
+		if old, exists := merged[num]; exists && old != lines[num] {
+			replaced = append(replaced, num)
+		}
+		merged[num] = lines[num]
+	}
+	cs.ReplaceAll(label, merged)
+	return replaced
+}
+
 // GetLineNumbers 获取所有行号（排序后）
 func (cs *CodeStore) GetLineNumbers() []int {
 	numbers := make([]int, 0, len(cs.lines))
@@ -93,27 +127,25 @@ func (cs *CodeStore) GetProgram() (*ast.Program, error) {
 		return nil, nil
 	}
 
-	parsedAST, err := parser.Parse("memory", []byte(code))
+	prog, err := parser.ParseProgram("memory", []byte(code))
 	if err != nil {
 		return nil, err
 	}
 
-	prog, ok := parsedAST.(*ast.Program)
-	if !ok {
-		return nil, fmt.Errorf("parsed result is not a program")
-	}
-
 	// 更新缓存
 	cs.cachedAST = prog
 	cs.isDirty = false
 	return prog, nil
 }
 
-// Clear 清空所有代码
+// Clear 清空所有代码（可以用 UNDO 恢复）
 func (cs *CodeStore) Clear() {
-	cs.lines = make(map[int]string)
-	cs.isDirty = true
-	cs.cachedAST = nil
+	cs.ReplaceAll("NEW", map[int]string{})
+}
+
+// Revision 返回程序的修订号，程序每次被修改都会改变
+func (cs *CodeStore) Revision() int {
+	return cs.revision
 }
 
 // IsEmpty 是否为空
@@ -131,18 +163,26 @@ func Run(version string, mode string) {
 	printWelcome(version, mode)
 
 	store := NewCodeStore()
-	scanner := bufio.NewScanner(os.Stdin)
+	session := NewSession(mode)
+	defer session.HandleInterrupts()()
+	editor := NewLineEditor(os.Stdin, os.Stdout,
+		WithHistoryFile(historyFile()),
+		WithCompleter(func(prefix string) []string {
+			return Completions(prefix, store, session)
+		}))
 
 	for {
-		fmt.Printf("%s ", Prompt)
-
-		if !scanner.Scan() {
+		rawInput, err := editor.ReadLine(Prompt + " ")
+		if err == io.EOF {
 			// EOF (Ctrl+D)
 			fmt.Println("\nGoodbye!")
 			break
 		}
+		if err != nil {
+			fmt.Printf("\nError: %v\n", err)
+			break
+		}
 
-		rawInput := scanner.Text()
 		trimmedInput := strings.TrimSpace(rawInput)
 
 		input := trimmedInput
@@ -151,7 +191,7 @@ func Run(version string, mode string) {
 		}
 
 		// 处理命令
-		if handleCommand(input, store, scanner, mode) {
+		if handleCommand(input, store, editor, session) {
 			continue
 		}
 
@@ -167,18 +207,14 @@ func Run(version string, mode string) {
 				fmt.Printf("Line %d updated\n", lineNumber)
 			}
 		} else {
-			// 直接模式：没有行号，赋予临时行号 0 并立即执行
-			ExecuteProgram("0 "+input+"\n", "direct", mode)
+			// 直接模式：没有行号，赋予临时行号 0 并立即执行，变量与程序共享
+			runDirect(input, session)
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("\nError: %v\n", err)
-	}
 }
 
 // handleCommand 处理交互命令
-func handleCommand(input string, store *CodeStore, scanner *bufio.Scanner, mode string) bool {
+func handleCommand(input string, store *CodeStore, editor *LineEditor, session *Session) bool {
 	trimmed := strings.TrimSpace(input)
 	upper := strings.ToUpper(trimmed)
 
@@ -190,12 +226,15 @@ func handleCommand(input string, store *CodeStore, scanner *bufio.Scanner, mode
 
 	switch firstWord {
 	case "LIST", "L":
-		return cmdList(store)
+		return cmdList(store, commandArgs(trimmed))
 	case "RUN", "R":
-		return cmdRun(store, mode)
+		return cmdRun(store, session)
+	case "CONT":
+		session.Continue(store.Revision())
+		return true
 	case "CLEAR":
-		store.Clear()
-		fmt.Println("Program cleared")
+		session.Clear()
+		fmt.Println("Variables cleared")
 		return true
 	case "HELP", "H", "?":
 		printInteractiveHelp()
@@ -205,30 +244,36 @@ func handleCommand(input string, store *CodeStore, scanner *bufio.Scanner, mode
 		os.Exit(0)
 	case "NEW":
 		store.Clear()
+		session.Clear()
 		fmt.Println("Ready for new program")
 		return true
 	case "DELETE", "D":
-		// 从原始输入中提取参数
-		parts := strings.Fields(trimmed)
-		if len(parts) >= 2 {
-			if num, err := strconv.Atoi(parts[1]); err == nil {
-				if store.Delete(num) {
-					fmt.Printf("Line %d deleted\n", num)
-				} else {
-					fmt.Printf("Line %d not found\n", num)
-				}
-				return true
-			}
-		}
-		fmt.Println("Usage: DELETE <line_number>")
-		return true
+		return cmdDelete(store, commandArgs(trimmed))
+	case "RENUM":
+		return cmdRenum(store, commandArgs(trimmed))
+	case "UNDO":
+		return cmdUndo(store, false)
+	case "REDO":
+		return cmdUndo(store, true)
+	case "SNAPSHOT", "SNAPSHOTS":
+		return cmdSnapshot(store, commandArgs(trimmed))
+	case "RESTORE":
+		// 只处理 RESTORE SNAPSHOT，其余交给 BASIC 解析
+		args := commandArgs(trimmed)
+		if word, name, _ := strings.Cut(args, " "); strings.EqualFold(word, "SNAPSHOT") {
+			return cmdRestoreSnapshot(store, strings.TrimSpace(name))
+		}
+		if strings.EqualFold(args, "SNAPSHOT") {
+			return cmdRestoreSnapshot(store, "")
+		}
+	case "DIFF":
+		return cmdDiff(store, commandArgs(trimmed))
 	case "EDIT", "E":
 		parts := strings.Fields(trimmed)
 		if len(parts) >= 2 {
-			return cmdEdit(store, parts[1], scanner)
+			return cmdEdit(store, parts[1], editor)
 		}
-		fmt.Println("Usage: EDIT <line_number>")
-		return true
+		return cmdScreenEdit(store)
 	case "SAVE":
 		parts := strings.Fields(trimmed)
 		if len(parts) >= 2 {
@@ -251,10 +296,16 @@ func handleCommand(input string, store *CodeStore, scanner *bufio.Scanner, mode
 		}
 		fmt.Println("Usage: LOAD <filename>")
 		return true
+	case "MERGE":
+		if filename := commandArgs(trimmed); filename != "" {
+			return cmdMerge(store, filename)
+		}
+		fmt.Println("Usage: MERGE <filename>")
+		return true
 	case "FORMAT", "F":
 		return cmdFormat(store)
 	case "AUTO":
-		return cmdAuto(store, scanner)
+		return cmdAuto(store, editor)
 	case "DISASM", "DS":
 		return cmdDisasm(store)
 	case "AST":
@@ -264,22 +315,61 @@ func handleCommand(input string, store *CodeStore, scanner *bufio.Scanner, mode
 	return false
 }
 
-// cmdList LIST 命令
-func cmdList(store *CodeStore) bool {
+// commandArgs 返回命令第一个单词之后的参数（保留原始大小写）
+func commandArgs(input string) string {
+	if idx := strings.Index(input, " "); idx > 0 {
+		return strings.TrimSpace(input[idx+1:])
+	}
+	return ""
+}
+
+// cmdList LIST 命令：LIST [N | N-M | -M | N-]
+func cmdList(store *CodeStore, args string) bool {
 	if store.IsEmpty() {
 		fmt.Println("(No program in memory)")
 		return true
 	}
 
-	numbers := store.GetLineNumbers()
-	for _, num := range numbers {
+	from, to, err := parseLineRange(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+	for _, num := range store.LineNumbersIn(from, to) {
 		fmt.Printf("%d %s\n", num, store.lines[num])
 	}
 	return true
 }
 
-// cmdRun RUN 命令
-func cmdRun(store *CodeStore, mode string) bool {
+// cmdDelete DELETE 命令：DELETE N 删除一行，DELETE N-M、-M、N- 删除范围内的所有行
+func cmdDelete(store *CodeStore, args string) bool {
+	if args == "" {
+		fmt.Println("Usage: DELETE <line_number> | <from>-<to>")
+		return true
+	}
+	from, to, err := parseLineRange(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+	if !strings.Contains(args, "-") {
+		if store.Delete(from) {
+			fmt.Printf("Line %d deleted\n", from)
+		} else {
+			fmt.Printf("Line %d not found\n", from)
+		}
+		return true
+	}
+	if n := store.DeleteRange(from, to); n > 0 {
+		fmt.Printf("%d lines deleted\n", n)
+	} else {
+		fmt.Println("No lines in range")
+	}
+	return true
+}
+
+// cmdRun RUN 命令：变量保留直接模式语句和上次运行的值，用 CLEAR 清除
+func cmdRun(store *CodeStore, session *Session) bool {
 	if store.IsEmpty() {
 		fmt.Println("Error: No program to run")
 		return true
@@ -296,28 +386,62 @@ func cmdRun(store *CodeStore, mode string) bool {
 		return true
 	}
 
-	// 执行程序
-	if mode == "vm" {
-		comp := compiler.New()
-		chunk, err := comp.Compile(prog)
-		if err != nil {
-			fmt.Printf("Compilation error: %v\n", err)
-			return true
-		}
-		vm := vm.New(chunk)
-		if err := vm.Run(); err != nil {
-			fmt.Printf("Runtime error: %v\n", err)
+	session.Run(prog, store.Revision())
+	return true
+}
+
+// runDirect 执行一条直接模式语句
+func runDirect(input string, session *Session) {
+	parsedAST, err := parser.Parse("direct", []byte("0 "+input+"\n"))
+	if err != nil {
+		fmt.Printf("Parse error: %v\n", err)
+		return
+	}
+	prog, ok := parsedAST.(*ast.Program)
+	if !ok {
+		fmt.Println("Parse error: not a program")
+		return
+	}
+	// SUB/FUNCTION 在整个程序解析时才展开，直接模式中无法使用；TYPE 和 MAP 只能在程序中
+	// 声明，直接模式语句按最近运行的程序中的声明使用记录字段和字典
+	for _, line := range prog.Lines {
+		for _, stmt := range line.Statements {
+			switch s := stmt.(type) {
+			case *ast.CallStmt:
+				if s.Bare {
+					fmt.Printf("Error: unknown statement %s (SUB calls can only be used in program lines)\n", s.Name)
+				} else {
+					fmt.Println("Error: SUB and FUNCTION statements can only be used in program lines")
+				}
+				return
+			case *ast.SubStmt, *ast.EndSubStmt, *ast.ExitSubStmt,
+				*ast.DeclareStmt, *ast.ModuleStmt, *ast.SharedStmt:
+				fmt.Println("Error: SUB and FUNCTION statements can only be used in program lines")
+				return
+			case *ast.TypeStmt, *ast.FieldStmt, *ast.EndTypeStmt:
+				fmt.Println("Error: TYPE statements can only be used in program lines")
+				return
+			case *ast.DimStmt:
+				if strings.EqualFold(s.Type, "MAP") {
+					fmt.Println("Error: DIM ... AS MAP can only be used in program lines")
+					return
+				}
+				if s.Type != "" {
+					fmt.Println("Error: TYPE statements can only be used in program lines")
+					return
+				}
+			}
 		}
-	} else {
-		interp := interpreter.NewInterpreter()
-		interp.ExecuteProgram(prog)
 	}
-	fmt.Println("\nProgram complete.")
-	return true
+	if err := parser.ExpandDirect(prog, session.decls); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	session.Direct(prog)
 }
 
 // cmdEdit EDIT 命令
-func cmdEdit(store *CodeStore, lineNumStr string, scanner *bufio.Scanner) bool {
+func cmdEdit(store *CodeStore, lineNumStr string, editor *LineEditor) bool {
 	num, err := strconv.Atoi(lineNumStr)
 	if err != nil {
 		fmt.Println("Error: Invalid line number")
@@ -326,13 +450,12 @@ func cmdEdit(store *CodeStore, lineNumStr string, scanner *bufio.Scanner) bool {
 
 	if code, exists := store.lines[num]; exists {
 		fmt.Printf("Current line %d: %s\n", num, code)
-		fmt.Print("Enter new line (or press Enter to cancel): ")
-
-		if !scanner.Scan() {
+		text, err := editor.ReadLine("Enter new line (or press Enter to cancel): ")
+		if err != nil {
 			return true
 		}
 
-		newLine := strings.TrimSpace(scanner.Text())
+		newLine := strings.TrimSpace(text)
 		if newLine == "" {
 			fmt.Println("Edit cancelled")
 			return true
@@ -372,17 +495,16 @@ func cmdSave(store *CodeStore, filename string) bool {
 	return true
 }
 
-// cmdLoad LOAD 命令
-func cmdLoad(store *CodeStore, filename string) bool {
+// readProgramFile 读取 BASIC 程序文件，返回其中带行号的行（行号 -> 代码）。
+// 没有行号的源文件按 10, 20, 30... 编号，标签和 $INCLUDE 保持原样
+func readProgramFile(filename string) (map[int]string, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
-		return true
+		return nil, err
 	}
 
-	store.Clear()
-	lineCount := 0
-
+	lines := make(map[int]string)
+	numbered := true
 	scanner := bufio.NewScanner(strings.NewReader(string(data)))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -390,13 +512,48 @@ func cmdLoad(store *CodeStore, filename string) bool {
 			continue
 		}
 
-		if lineNumber, code, isDelete, ok := ParseBasicLine(line); ok && !isDelete {
-			store.Set(lineNumber, code)
-			lineCount++
+		lineNumber, code, isDelete, ok := ParseBasicLine(line)
+		if len(lines) == 0 && !ok {
+			numbered = false
+		}
+		if !numbered {
+			lines[(len(lines)+1)*10] = line
+		} else if ok && !isDelete {
+			lines[lineNumber] = code
 		}
 	}
+	return lines, nil
+}
 
-	fmt.Printf("Loaded %d lines from %s\n", lineCount, filename)
+// cmdLoad LOAD 命令
+func cmdLoad(store *CodeStore, filename string) bool {
+	lines, err := readProgramFile(filename)
+	if err != nil {
+		fmt.Printf("Error reading file: %v\n", err)
+		return true
+	}
+	// 整个 LOAD 是一次修改，UNDO 恢复加载前的程序
+	store.ReplaceAll("LOAD "+filename, lines)
+
+	fmt.Printf("Loaded %d lines from %s\n", len(lines), filename)
+	return true
+}
+
+// cmdMerge MERGE 命令：把文件中的行叠加到当前程序上，行号相同时以文件为准，
+// 并逐行报告被替换的行
+func cmdMerge(store *CodeStore, filename string) bool {
+	lines, err := readProgramFile(filename)
+	if err != nil {
+		fmt.Printf("Error reading file: %v\n", err)
+		return true
+	}
+
+	replaced := store.Merge("MERGE "+filename, lines)
+	for _, num := range replaced {
+		fmt.Printf("Line %d replaced\n", num)
+	}
+
+	fmt.Printf("Merged %d lines from %s (%d replaced)\n", len(lines), filename, len(replaced))
 	return true
 }
 
@@ -407,14 +564,15 @@ func cmdFormat(store *CodeStore) bool {
 		return true
 	}
 
-	// 获取 AST（利用缓存）
-	prog, err := store.GetProgram()
+	// 格式化源代码本身，不能用 GetProgram：它展开了 SUB/FUNCTION 和 TYPE 记录
+	parsed, err := parser.Parse("memory", []byte(store.GetCode()))
 	if err != nil {
 		fmt.Printf("Parse error: %v\n", err)
 		return true
 	}
-
-	if prog == nil {
+	prog, ok := parsed.(*ast.Program)
+	if !ok {
+		fmt.Println("Parse error: not a program")
 		return true
 	}
 
@@ -458,15 +616,15 @@ func cmdFormat(store *CodeStore) bool {
 		}
 	}
 
-	// 替换旧存储
-	store.lines = newStore.lines
+	// 替换旧存储（可以用 UNDO 撤销）
+	store.ReplaceAll("FORMAT", newStore.lines)
 
 	fmt.Printf("Program formatted: %d lines renumbered\n", store.Count())
 	return true
 }
 
 // cmdAuto AUTO 命令 - 自动编号模式
-func cmdAuto(store *CodeStore, scanner *bufio.Scanner) bool {
+func cmdAuto(store *CodeStore, editor *LineEditor) bool {
 	nextLine := 10
 	if !store.IsEmpty() {
 		nums := store.GetLineNumbers()
@@ -477,12 +635,12 @@ func cmdAuto(store *CodeStore, scanner *bufio.Scanner) bool {
 
 	fmt.Println("Entering AUTO mode. Press Enter on an empty line to exit.")
 	for {
-		fmt.Printf("%d ", nextLine)
-		if !scanner.Scan() {
+		text, err := editor.ReadLine(fmt.Sprintf("%d ", nextLine))
+		if err != nil {
 			break
 		}
 
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(text)
 		if input == "" {
 			break
 		}
@@ -546,7 +704,11 @@ func cmdAST(store *CodeStore) bool {
 
 	fmt.Println("== Abstract Syntax Tree ==")
 	for _, line := range prog.Lines {
-		fmt.Printf("[Line %d]\n", line.LineNumber)
+		if line.Label != "" {
+			fmt.Printf("[Line %d] %s:\n", line.LineNumber, line.Label)
+		} else {
+			fmt.Printf("[Line %d]\n", line.LineNumber)
+		}
 		for _, stmt := range line.Statements {
 			dumpNode(stmt, 1)
 		}
@@ -554,6 +716,14 @@ func cmdAST(store *CodeStore) bool {
 	return true
 }
 
+// labelSuffix 返回 AST 视图中跳转标签的说明，没有标签时为空
+func labelSuffix(label string) string {
+	if label == "" {
+		return ""
+	}
+	return ", Label: " + label
+}
+
 func dumpNode(node ast.Node, indent int) {
 	prefix := strings.Repeat("  ", indent)
 	if node == nil {
@@ -605,13 +775,32 @@ func dumpNode(node ast.Node, indent int) {
 	case *ast.NextStmt:
 		fmt.Printf("%sNextStmt (Var: %q)\n", prefix, n.Var)
 	case *ast.GotoStmt:
-		fmt.Printf("%sGotoStmt (Line: %d)\n", prefix, n.LineNumber)
+		fmt.Printf("%sGotoStmt (Line: %d%s)\n", prefix, n.LineNumber, labelSuffix(n.Label))
 	case *ast.GosubStmt:
-		fmt.Printf("%sGosubStmt (Line: %d)\n", prefix, n.LineNumber)
+		if n.Extern != "" {
+			fmt.Printf("%sGosubStmt (Extern: %s)\n", prefix, n.Extern)
+			break
+		}
+		fmt.Printf("%sGosubStmt (Line: %d%s)\n", prefix, n.LineNumber, labelSuffix(n.Label))
 	case *ast.ReturnStmt:
 		fmt.Printf("%sReturnStmt\n", prefix)
 	case *ast.EndStmt:
 		fmt.Printf("%sEndStmt\n", prefix)
+	case *ast.StopStmt:
+		fmt.Printf("%sStopStmt\n", prefix)
+	case *ast.ChainStmt:
+		fmt.Printf("%sChainStmt (All: %v)\n", prefix, n.All)
+		fmt.Printf("%s  File:\n", prefix)
+		dumpNode(n.File, indent+2)
+		if n.Line != nil {
+			fmt.Printf("%s  Line:\n", prefix)
+			dumpNode(n.Line, indent+2)
+		}
+	case *ast.CommonStmt:
+		fmt.Printf("%sCommonStmt\n", prefix)
+		for _, v := range n.Vars {
+			dumpNode(v, indent+1)
+		}
 	case *ast.RemStmt:
 		fmt.Printf("%sRemStmt (%s)\n", prefix, n.Text)
 	case *ast.DimStmt:
@@ -620,7 +809,10 @@ func dumpNode(node ast.Node, indent int) {
 			dumpNode(sz, indent+2)
 		}
 	case *ast.InputStmt:
-		fmt.Printf("%sInputStmt (Prompt: %q, Vars: %v)\n", prefix, n.Prompt, n.Vars)
+		fmt.Printf("%sInputStmt (Prompt: %q, Question: %v, Line: %v)\n", prefix, n.Prompt, n.Question, n.Line)
+		for _, v := range n.Vars {
+			dumpNode(v, indent+1)
+		}
 	case *ast.BinaryOp:
 		fmt.Printf("%sBinaryOp (%s)\n", prefix, n.Op)
 		dumpNode(n.Left, indent+1)
@@ -688,37 +880,58 @@ func ParseBasicLine(input string) (int, string, bool, bool) {
 }
 
 // ExecuteProgram 执行 BASIC 程序
-func ExecuteProgram(code string, source string, mode string) {
-	// 使用 pigeon 生成的解析器
-	parsedAST, err := parser.Parse(source, []byte(code))
+// opts 传递给 VM 模式下的编译器（如优化级别）
+func ExecuteProgram(code string, source string, mode string, opts ...compiler.Option) {
+	// 使用 pigeon 生成的解析器，并展开 $INCLUDE、处理标签
+	prog, err := parser.ParseProgram(source, []byte(code))
 	if err != nil {
 		fmt.Printf("Parse error: %v\n", err)
 		return
 	}
 
-	prog, ok := parsedAST.(*ast.Program)
-	if !ok {
-		fmt.Println("Parse error: not a program")
+	// 执行程序
+	var brk error
+	if mode == "vm" || mode == "rvm" {
+		brk = runBytecode(prog, mode, opts...)
+	} else {
+		brk = interpreter.NewInterpreter().Run(prog)
+	}
+	if brk != nil {
+		// 程序被 STOP 暂停；非交互模式下不能继续，到此结束
+		fmt.Printf("\n%v\n", brk)
 		return
 	}
+	fmt.Println("\nProgram complete.")
+}
 
-	// 执行程序
-	if mode == "vm" {
-		comp := compiler.New()
-		chunk, err := comp.Compile(prog)
-		if err != nil {
-			fmt.Printf("Compilation error: %v\n", err)
-			return
-		}
-		vm := vm.New(chunk)
-		if err := vm.Run(); err != nil {
-			fmt.Printf("Runtime error: %v\n", err)
-		}
+// runBytecode 编译并执行程序：vm 模式使用栈式虚拟机，rvm 模式使用寄存器虚拟机
+// 编译错误和运行时错误直接输出；程序被 STOP 暂停时返回 *interpreter.Break
+func runBytecode(prog *ast.Program, mode string, opts ...compiler.Option) error {
+	if mode == "rvm" {
+		opts = append(opts, compiler.WithRegisters())
+	}
+	comp := compiler.New(opts...)
+	chunk, err := comp.Compile(prog)
+	if err != nil {
+		fmt.Printf("Compilation error: %v\n", err)
+		return nil
+	}
+	var machine interface{ Run() error }
+	chain := vm.WithChain(compiler.ChainLoader(opts...))
+	if mode == "rvm" {
+		machine = vm.NewRegister(chunk, chain)
 	} else {
-		interp := interpreter.NewInterpreter()
-		interp.ExecuteProgram(prog)
+		machine = vm.New(chunk, chain)
 	}
-	fmt.Println("\nProgram complete.")
+	err = machine.Run()
+	var brk *interpreter.Break
+	if errors.As(err, &brk) {
+		return brk
+	}
+	if err != nil {
+		fmt.Printf("Runtime error: %v\n", err)
+	}
+	return nil
 }
 
 // printWelcome 打印欢迎信息
@@ -735,22 +948,35 @@ func printWelcome(version string, mode string) {
 // printInteractiveHelp 打印交互模式帮助
 func printInteractiveHelp() {
 	fmt.Println("\nAvailable Commands:")
+	fmt.Println("  LIST [n-m]     - List the program, or lines n to m (also n, -m, n-)")
+	fmt.Println("  EDIT           - Full-screen editor (^S save, ^X save and exit, ^Q quit)")
 	fmt.Println("  EDIT <n>       - Edit line number n")
-	fmt.Println("  DELETE <n>     - Delete line number n")
+	fmt.Println("  DELETE <n-m>   - Delete line n, or lines n to m (also -m, n-)")
+	fmt.Println("  RENUM [n,o,i]  - Renumber lines from o as n, n+i, ... (GOTO/GOSUB follow)")
 	fmt.Println("  AUTO           - Entering automatic line numbering mode")
 	fmt.Println("  FORMAT, f      - Format program (renumber lines, uppercase keywords)")
+	fmt.Println("  UNDO, REDO     - Undo or redo the last change to the program")
+	fmt.Println("  SNAPSHOT <name> - Save a named copy of the program (no name: list them)")
+	fmt.Println("  RESTORE SNAPSHOT <name> - Replace the program with a snapshot")
+	fmt.Println("  DIFF <name>    - Show changes since a snapshot")
 	fmt.Println("  DISASM, ds     - View bytecode disassembly")
 	fmt.Println("  AST            - View abstract syntax tree")
-	fmt.Println("  CLEAR          - Clear all program lines")
-	fmt.Println("  NEW            - Start a new program")
+	fmt.Println("  RUN, r         - Run the program (variables keep their values)")
+	fmt.Println("  CONT           - Continue a program stopped by Ctrl-C or STOP")
+	fmt.Println("  CLEAR          - Reset all variables and arrays")
+	fmt.Println("  NEW            - Start a new program (also resets variables)")
 	fmt.Println("  SAVE <file>    - Save program to file")
 	fmt.Println("  LOAD <file>    - Load program from file")
+	fmt.Println("  MERGE <file>   - Add the lines of a file to the program")
 	fmt.Println("  HELP, ?, H     - Show this help message")
 	fmt.Println("  EXIT, QUIT, Q  - Exit the interpreter")
+	fmt.Println("\nLine editing: arrows move, Up/Down browse history (~/.zb_history),")
+	fmt.Println("Ctrl-R searches history, Tab completes keywords, variables and line numbers.")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  10 PRINT \"Hello World\"")
 	fmt.Println("  PRINT 1 + 2    - Direct mode (executes immediately)")
+	fmt.Println("  A = 5          - Direct mode variables are shared with the program")
 	fmt.Println("  AUTO           - Start entry with auto-line numbers")
 	fmt.Println("  LIST")
 	fmt.Println("  RUN")
@@ -0,0 +1,164 @@
+package repl_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/repl"
+)
+
+func TestRenumber(t *testing.T) {
+	newStore := func() *repl.CodeStore {
+		store := repl.NewCodeStore()
+		store.Set(5, "REM START")
+		store.Set(100, "GOSUB 300")
+		store.Set(110, "if x then goto 100")
+		store.Set(300, "RETURN")
+		return store
+	}
+
+	store := newStore()
+	if _, err := store.Renumber(1000, 100, 5); err != nil {
+		t.Fatal(err)
+	}
+	want := "5 REM START\n1000 GOSUB 1010\n1005 if x then goto 1000\n1010 RETURN\n"
+	if got := store.GetCode(); got != want {
+		t.Errorf("after RENUM 1000, 100, 5:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 新行号会覆盖或排到不重新编号的行之前时拒绝，程序保持不变
+	for _, args := range [][3]int{{5, 100, 10}, {1, 100, 10}, {10, 400, 10}} {
+		store := newStore()
+		before := store.GetCode()
+		if _, err := store.Renumber(args[0], args[1], args[2]); err == nil {
+			t.Errorf("Renumber%v succeeded, want error", args)
+		}
+		if store.GetCode() != before {
+			t.Errorf("Renumber%v changed the program", args)
+		}
+	}
+
+	store = newStore()
+	if got := store.LineNumbersIn(50, 200); !reflect.DeepEqual(got, []int{100, 110}) {
+		t.Errorf("LineNumbersIn(50, 200) = %v", got)
+	}
+	if n := store.DeleteRange(100, 300); n != 3 || store.Count() != 1 {
+		t.Errorf("DeleteRange(100, 300) = %d, %d lines left", n, store.Count())
+	}
+}
+
+func TestEditProgram(t *testing.T) {
+	store := repl.NewCodeStore()
+	store.Set(10, `PRINT "A"`)
+	store.Set(20, "GOTO 10")
+
+	keys := strings.Join([]string{
+		"\x1b[B\x05\r",             // 第 20 行行末回车，新行自动编号为 30
+		"END",                      // 输入第 30 行
+		"\x1b[A\x01\x04\x04" + "5", // 第 20 行改为第 5 行
+		"\x13",                     // 保存后按行号排序
+		"\x06GOTO\r",               // 搜索 GOTO，光标移到第 5 行
+		"\x0b",                     // 删除该行
+		"\x18",                     // 保存并退出
+	}, "")
+	var out strings.Builder
+	if err := repl.EditProgram(store, strings.NewReader(keys), &out, 100, 10); err != nil {
+		t.Fatal(err)
+	}
+	want := "10 PRINT \"A\"\n30 END\n"
+	if got := store.GetCode(); got != want {
+		t.Errorf("program after editing:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 行号重复时拒绝保存，Ctrl-Q 两次放弃修改
+	keys = "\x01\x04\x04" + "30\x13\x11\x11"
+	out.Reset()
+	if err := repl.EditProgram(store, strings.NewReader(keys), &out, 100, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.GetCode(); got != want {
+		t.Errorf("program after rejected save:\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.Contains(out.String(), "line 30 appears twice") {
+		t.Errorf("duplicate line number not reported")
+	}
+}
+
+func TestUndo(t *testing.T) {
+	store := repl.NewCodeStore()
+	store.Set(10, "PRINT 1")
+	store.Set(20, "PRINT 2")
+	store.SaveSnapshot("base")
+
+	store.Set(10, "GOTO 20") // 输错行号覆盖了第 10 行
+	if _, err := store.Renumber(100, 10, 100); err != nil {
+		t.Fatal(err)
+	}
+	if label, ok := store.Undo(); !ok || label != "RENUM" {
+		t.Errorf("Undo() = %q, %v, want RENUM", label, ok)
+	}
+	if label, _ := store.Undo(); label != "line 10" {
+		t.Errorf("Undo() = %q, want line 10", label)
+	}
+	if got, want := store.GetCode(), "10 PRINT 1\n20 PRINT 2\n"; got != want {
+		t.Errorf("after two UNDOs:\n%s\nwant:\n%s", got, want)
+	}
+	store.Redo()
+	if got, want := store.GetCode(), "10 GOTO 20\n20 PRINT 2\n"; got != want {
+		t.Errorf("after REDO:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 新的修改之后不能再重做
+	store.Set(30, "END")
+	if _, ok := store.Redo(); ok {
+		t.Error("Redo() succeeded after a new change")
+	}
+
+	diff, err := store.Diff("BASE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"- 10 PRINT 1", "+ 10 GOTO 20", "+ 30 END"}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("Diff = %q, want %q", diff, want)
+	}
+
+	// 恢复快照也可以撤销
+	if err := store.RestoreSnapshot("base"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.GetCode(), "10 PRINT 1\n20 PRINT 2\n"; got != want {
+		t.Errorf("after RESTORE SNAPSHOT:\n%s\nwant:\n%s", got, want)
+	}
+	store.Undo()
+	if store.Count() != 3 {
+		t.Errorf("UNDO after RESTORE SNAPSHOT left %d lines, want 3", store.Count())
+	}
+	if err := store.RestoreSnapshot("missing"); err == nil {
+		t.Error("RestoreSnapshot of a missing snapshot succeeded")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	store := repl.NewCodeStore()
+	store.Set(10, "GOSUB 1000")
+	store.Set(20, "END")
+	store.Set(1000, "RETURN")
+
+	replaced := store.Merge("MERGE lib.bas", map[int]string{
+		1000: `PRINT "LIB"`,
+		1010: "RETURN",
+		20:   "END",
+	})
+	if !reflect.DeepEqual(replaced, []int{1000}) {
+		t.Errorf("Merge replaced %v, want [1000]", replaced)
+	}
+	want := "10 GOSUB 1000\n20 END\n1000 PRINT \"LIB\"\n1010 RETURN\n"
+	if got := store.GetCode(); got != want {
+		t.Errorf("after MERGE:\n%s\nwant:\n%s", got, want)
+	}
+	if label, _ := store.Undo(); label != "MERGE lib.bas" || store.Count() != 3 {
+		t.Errorf("Undo() = %q with %d lines, want MERGE lib.bas with 3", label, store.Count())
+	}
+}
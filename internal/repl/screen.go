@@ -0,0 +1,561 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/formatter"
+	"zork-basic/internal/parser"
+)
+
+// 全屏编辑器的按键说明，显示在状态栏上
+const screenHelp = "^S Save  ^X Save+Exit  ^Q Quit  ^F Find  ^K Delete line"
+
+// 语法高亮使用的 ANSI 颜色
+const (
+	colorReset      = "\x1b[0m"
+	colorLineNumber = "\x1b[33m"
+	colorKeyword    = "\x1b[1;34m"
+	colorBuiltin    = "\x1b[36m"
+	colorString     = "\x1b[32m"
+	colorNumber     = "\x1b[35m"
+	colorComment    = "\x1b[2m"
+	colorError      = "\x1b[97;41m"
+	colorStatus     = "\x1b[7m"
+)
+
+// parseErrorPos 从解析器的错误信息中取出字节偏移和说明，如 "edit:1:16 (15): no match found"；
+// 错误在行尾时行号和列号指向下一行，偏移总是准确的
+var parseErrorPos = regexp.MustCompile(`^[^:]*:\d+:\d+ \((\d+)\): (.*)`)
+
+// lineError 一行代码的解析错误
+type lineError struct {
+	col int    // 出错位置（从 0 开始的字符下标）
+	msg string // 错误说明
+}
+
+// screenEditor 全屏程序编辑器的状态
+type screenEditor struct {
+	store         *CodeStore
+	r             *bufio.Reader
+	out           io.Writer
+	width, height int
+	lines         [][]rune // 每行是 "行号 代码"，与 LIST 的输出相同
+	row, col      int      // 光标所在的行和列
+	top, left     int      // 屏幕左上角对应的行和列
+	modified      bool
+	message       string // 状态栏上的提示，按下一个键后消失
+	query         string // 上一次搜索的内容
+	quitPending   bool   // 有未保存的修改时已经按过一次 Ctrl-Q
+	errors        map[string]*lineError
+}
+
+// EditProgram 在全屏编辑器中编辑 store 中的程序（不带行号的 EDIT 命令）。
+// 按键从 in 读取（终端应已处于原始模式），画面以 ANSI 转义序列输出到 out，
+// 屏幕大小为 width 列、height 行。保存时按行号重新排序写回 store；
+// 按 Ctrl-Q 或 Ctrl-X 退出后返回，只有读写出错时返回错误
+func EditProgram(store *CodeStore, in io.Reader, out io.Writer, width, height int) error {
+	e := &screenEditor{
+		store:  store,
+		r:      bufio.NewReaderSize(oneByteReader{in}, 16),
+		out:    out,
+		width:  max(width, 20),
+		height: max(height, 3),
+		errors: make(map[string]*lineError),
+	}
+	e.load()
+	if len(e.lines) == 1 && len(e.lines[0]) == 0 {
+		// 空程序从第 10 行开始
+		e.lines[0] = []rune("10 ")
+		e.col = 3
+	}
+
+	io.WriteString(out, "\x1b[?1049h") // 切换到备用屏幕，退出后恢复原来的内容
+	defer io.WriteString(out, "\x1b[?1049l")
+	for {
+		e.render()
+		key, err := readKey(e.r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if quit, err := e.handle(key); quit || err != nil {
+			return err
+		}
+	}
+}
+
+// load 从 store 读取程序，每行一项，按行号排序
+func (e *screenEditor) load() {
+	e.lines = e.lines[:0]
+	for _, num := range e.store.GetLineNumbers() {
+		e.lines = append(e.lines, []rune(fmt.Sprintf("%d %s", num, e.store.lines[num])))
+	}
+	if len(e.lines) == 0 {
+		e.lines = append(e.lines, nil)
+	}
+}
+
+// handle 处理一个按键，返回是否退出编辑器
+func (e *screenEditor) handle(key rune) (bool, error) {
+	e.message = ""
+	if key != ctrlQ {
+		e.quitPending = false
+	}
+	line := e.lines[e.row]
+	switch key {
+	case ctrlQ:
+		if e.modified && !e.quitPending {
+			e.quitPending = true
+			e.message = "Unsaved changes: ^S to save, ^Q again to discard them"
+			return false, nil
+		}
+		return true, nil
+	case ctrlS:
+		e.save()
+	case ctrlX:
+		return e.save(), nil
+	case ctrlF:
+		return false, e.find()
+	case ctrlL:
+		io.WriteString(e.out, "\x1b[2J")
+	case keyUp:
+		e.row--
+	case keyDown:
+		e.row++
+	case keyPageUp:
+		e.row -= e.textHeight()
+	case keyPageDown:
+		e.row += e.textHeight()
+	case keyLeft, ctrlB:
+		if e.col > 0 {
+			e.col--
+		} else if e.row > 0 {
+			e.row--
+			e.col = len(e.lines[e.row])
+		}
+	case keyRight:
+		if e.col < len(line) {
+			e.col++
+		} else if e.row < len(e.lines)-1 {
+			e.row++
+			e.col = 0
+		}
+	case keyHome, ctrlA:
+		e.col = 0
+	case keyEnd, ctrlE:
+		e.col = len(line)
+	case enter, '\n':
+		e.splitLine()
+	case backspace, ctrlH:
+		switch {
+		case e.col > 0:
+			e.lines[e.row] = slices.Delete(line, e.col-1, e.col)
+			e.col--
+			e.modified = true
+		case e.row > 0:
+			// 行首退格与上一行合并
+			prev := e.lines[e.row-1]
+			e.col = len(prev)
+			e.lines[e.row-1] = append(prev, line...)
+			e.lines = slices.Delete(e.lines, e.row, e.row+1)
+			e.row--
+			e.modified = true
+		}
+	case keyDelete, ctrlD:
+		switch {
+		case e.col < len(line):
+			e.lines[e.row] = slices.Delete(line, e.col, e.col+1)
+			e.modified = true
+		case e.row < len(e.lines)-1:
+			// 行尾删除与下一行合并
+			e.lines[e.row] = append(line, e.lines[e.row+1]...)
+			e.lines = slices.Delete(e.lines, e.row+1, e.row+2)
+			e.modified = true
+		}
+	case ctrlK:
+		if len(e.lines) == 1 {
+			e.lines[0] = nil
+		} else {
+			e.lines = slices.Delete(e.lines, e.row, e.row+1)
+		}
+		e.modified = true
+	default:
+		if key >= ' ' {
+			e.lines[e.row] = slices.Insert(line, e.col, key)
+			e.col++
+			e.modified = true
+		}
+	}
+	e.row = min(max(e.row, 0), len(e.lines)-1)
+	e.col = min(e.col, len(e.lines[e.row]))
+	return false, nil
+}
+
+// splitLine 在光标处把一行分成两行；在有行号的行末回车时，新行预先填好一个
+// 介于当前行和下一行之间的行号
+func (e *screenEditor) splitLine() {
+	line := e.lines[e.row]
+	rest := slices.Clone(line[e.col:])
+	e.lines[e.row] = line[:e.col]
+	if len(rest) == 0 {
+		if num, ok := e.nextLineNumber(); ok {
+			rest = []rune(strconv.Itoa(num) + " ")
+		}
+		e.lines = slices.Insert(e.lines, e.row+1, rest)
+		e.row++
+		e.col = len(rest)
+	} else {
+		e.lines = slices.Insert(e.lines, e.row+1, rest)
+		e.row++
+		e.col = 0
+	}
+	e.modified = true
+}
+
+// nextLineNumber 返回可以插入在当前行之后的行号：当前行号加 10，
+// 与下一行冲突时取两者的中间值；没有空位时返回 false
+func (e *screenEditor) nextLineNumber() (int, bool) {
+	cur, _, _, ok := ParseBasicLine(strings.TrimSpace(string(e.lines[e.row])))
+	if !ok {
+		return 0, false
+	}
+	num := cur + 10
+	if e.row+1 < len(e.lines) {
+		if next, _, _, ok := ParseBasicLine(strings.TrimSpace(string(e.lines[e.row+1]))); ok && next <= num {
+			num = (cur + next) / 2
+		}
+	}
+	return num, num > cur
+}
+
+// save 把编辑的内容写回 store 并按行号重新排序，返回是否保存成功。
+// 没有行号或行号重复的行使保存失败，光标移到该行；有解析错误的行照常保存
+func (e *screenEditor) save() bool {
+	lines := make(map[int]string)
+	bad := 0
+	for i, text := range e.lines {
+		trimmed := strings.TrimSpace(string(text))
+		if trimmed == "" {
+			continue
+		}
+		num, code, isDelete, ok := ParseBasicLine(trimmed)
+		if !ok {
+			e.row, e.col = i, 0
+			e.message = "Not saved: this line has no line number"
+			return false
+		}
+		if isDelete {
+			continue // 只有行号的行视为空行
+		}
+		if _, dup := lines[num]; dup {
+			e.row, e.col = i, 0
+			e.message = fmt.Sprintf("Not saved: line %d appears twice", num)
+			return false
+		}
+		lines[num] = code
+		if e.parseError(string(text)) != nil {
+			bad++
+		}
+	}
+
+	// 保存后按行号重新排序，光标停留在原来的行
+	curNum, _, _, hasNum := ParseBasicLine(strings.TrimSpace(string(e.lines[e.row])))
+	e.store.ReplaceAll("EDIT", lines)
+	e.load()
+	e.row, e.col = 0, 0
+	if hasNum {
+		for i, text := range e.lines {
+			if num, _, _, ok := ParseBasicLine(string(text)); ok && num >= curNum {
+				e.row = i
+				break
+			}
+		}
+	}
+	e.modified = false
+	e.message = fmt.Sprintf("Saved %d lines", len(lines))
+	if bad > 0 {
+		e.message += fmt.Sprintf(" (%d with errors)", bad)
+	}
+	return true
+}
+
+// find 在状态栏上读取要搜索的内容（不区分大小写），把光标移到下一个匹配处；
+// 直接回车重复上一次搜索，Ctrl-C 或 Ctrl-G 取消
+func (e *screenEditor) find() error {
+	var input []rune
+	for {
+		prompt := "Find: " + string(input)
+		if e.query != "" && len(input) == 0 {
+			prompt = fmt.Sprintf("Find [%s]: ", e.query)
+		}
+		e.renderStatus(prompt)
+		key, err := readKey(e.r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case key == ctrlC || key == ctrlG:
+			return nil
+		case key == backspace || key == ctrlH:
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		case key == enter || key == '\n':
+			if len(input) > 0 {
+				e.query = string(input)
+			}
+			if e.query == "" {
+				return nil
+			}
+			e.findNext()
+			return nil
+		case key >= ' ':
+			input = append(input, key)
+		}
+	}
+}
+
+// findNext 从光标之后开始查找 query，到末尾后从头继续
+func (e *screenEditor) findNext() {
+	query := []rune(strings.ToUpper(e.query))
+	n := len(e.lines)
+	for i := 0; i <= n; i++ {
+		row := (e.row + i) % n
+		line := []rune(strings.ToUpper(string(e.lines[row])))
+		from := 0
+		if i == 0 {
+			from = e.col + 1
+		}
+		for col := from; col+len(query) <= len(line); col++ {
+			if slices.Equal(line[col:col+len(query)], query) {
+				e.row, e.col = row, col
+				return
+			}
+		}
+	}
+	e.message = fmt.Sprintf("%q not found", e.query)
+}
+
+// parseError 用 PEG 解析器检查一行代码，结果按行的内容缓存
+func (e *screenEditor) parseError(text string) *lineError {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return nil
+	}
+	if le, ok := e.errors[text]; ok {
+		return le
+	}
+	var le *lineError
+	if _, _, _, ok := ParseBasicLine(trimmed); !ok {
+		le = &lineError{col: 0, msg: "missing line number"}
+	} else if _, err := parser.Parse("edit", []byte(text+"\n")); err != nil {
+		le = &lineError{col: len([]rune(text)), msg: err.Error()}
+		first, _, _ := strings.Cut(err.Error(), "\n")
+		if m := parseErrorPos.FindStringSubmatch(first); m != nil {
+			offset, _ := strconv.Atoi(m[1])
+			le.col = utf8.RuneCountInString(text[:min(offset, len(text))])
+			le.msg = strings.TrimPrefix(m[2], "no match found, ")
+		}
+	}
+	e.errors[text] = le
+	return le
+}
+
+// textHeight 文本区的行数（最后一行是状态栏）
+func (e *screenEditor) textHeight() int {
+	return e.height - 1
+}
+
+// textWidth 文本区的列数（左边两列显示错误标记）
+func (e *screenEditor) textWidth() int {
+	return e.width - 2
+}
+
+// scroll 调整滚动位置，使光标可见
+func (e *screenEditor) scroll() {
+	if e.row < e.top {
+		e.top = e.row
+	}
+	if e.row >= e.top+e.textHeight() {
+		e.top = e.row - e.textHeight() + 1
+	}
+	if e.col < e.left {
+		e.left = e.col
+	}
+	if e.col >= e.left+e.textWidth() {
+		e.left = e.col - e.textWidth() + 1
+	}
+}
+
+// render 重画整个屏幕
+func (e *screenEditor) render() {
+	e.scroll()
+	var sb strings.Builder
+	sb.WriteString("\x1b[?25l\x1b[H")
+	for y := 0; y < e.textHeight(); y++ {
+		row := e.top + y
+		if row < len(e.lines) {
+			e.renderLine(&sb, e.lines[row])
+		} else {
+			sb.WriteString(colorComment + "~" + colorReset)
+		}
+		sb.WriteString("\x1b[K\r\n")
+	}
+	sb.WriteString(e.statusLine())
+	fmt.Fprintf(&sb, "\x1b[%d;%dH\x1b[?25h", e.row-e.top+1, e.col-e.left+3)
+	io.WriteString(e.out, sb.String())
+}
+
+// renderLine 画出一行：错误标记、语法高亮后的可见部分，出错位置以红底标出
+func (e *screenEditor) renderLine(sb *strings.Builder, line []rune) {
+	le := e.parseError(string(line))
+	if le != nil {
+		sb.WriteString(colorError + ">" + colorReset + " ")
+	} else {
+		sb.WriteString("  ")
+	}
+	colors := highlight(line)
+	end := min(len(line), e.left+e.textWidth())
+	current := ""
+	for i := e.left; i < end; i++ {
+		color := colors[i]
+		if le != nil && i == le.col {
+			color = colorError
+		}
+		if color != current {
+			sb.WriteString(colorReset + color)
+			current = color
+		}
+		sb.WriteRune(line[i])
+	}
+	// 错误在行尾（例如缺少表达式）时在行尾后标出一格
+	if le != nil && le.col >= len(line) && le.col >= e.left && le.col < e.left+e.textWidth() {
+		sb.WriteString(colorReset + colorError + " ")
+	}
+	sb.WriteString(colorReset)
+}
+
+// statusLine 返回状态栏：位置、修改标记，以及提示、当前行的解析错误或按键说明
+func (e *screenEditor) statusLine() string {
+	status := fmt.Sprintf(" EDIT  Ln %d/%d  Col %d", e.row+1, len(e.lines), e.col+1)
+	if e.modified {
+		status += "  [modified]"
+	}
+	info := screenHelp
+	if e.message != "" {
+		info = e.message
+	} else if le := e.parseError(string(e.lines[e.row])); le != nil {
+		info = "Error: " + le.msg
+	}
+	return e.statusText(status + "  |  " + info)
+}
+
+// renderStatus 只重画状态栏，并把光标留在状态栏的末尾（用于输入搜索内容）
+func (e *screenEditor) renderStatus(text string) {
+	fmt.Fprintf(e.out, "\x1b[%d;1H%s\x1b[%d;%dH", e.height, e.statusText(" "+text), e.height, min(len([]rune(text))+2, e.width))
+}
+
+// statusText 把文字截断或补齐到屏幕宽度，以反色显示
+func (e *screenEditor) statusText(text string) string {
+	runes := []rune(text)
+	if len(runes) > e.width {
+		runes = runes[:e.width]
+	}
+	return colorStatus + string(runes) + strings.Repeat(" ", e.width-len(runes)) + colorReset
+}
+
+// highlight 返回一行中每个字符的颜色：行号、关键字（与语法中的 KW_ 规则相同）、
+// 内置函数、字符串、数字和注释
+func highlight(line []rune) []string {
+	colors := make([]string, len(line))
+	i := 0
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		colors[i] = colorLineNumber
+		i++
+	}
+	paint := func(from, to int, color string) {
+		for k := from; k < to; k++ {
+			colors[k] = color
+		}
+	}
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				end++
+			}
+			end = min(end+1, len(line))
+			paint(i, end, colorString)
+			i = end
+		case c == '\'':
+			paint(i, len(line), colorComment)
+			return colors
+		case c < 128 && formatter.IsIdentStart(byte(c)):
+			end := i
+			for end < len(line) && line[end] < 128 && isWordRune(line[end]) {
+				end++
+			}
+			word := strings.ToUpper(string(line[i:end]))
+			switch {
+			case word == "REM":
+				paint(i, len(line), colorComment)
+				return colors
+			case slices.Contains(basicKeywords, word):
+				paint(i, end, colorKeyword)
+			case bytecode.GetBuiltinID(word) >= 0:
+				paint(i, end, colorBuiltin)
+			}
+			i = end
+		case (c >= '0' && c <= '9') || c == '.':
+			end := i
+			for end < len(line) && ((line[end] >= '0' && line[end] <= '9') || line[end] == '.') {
+				end++
+			}
+			paint(i, end, colorNumber)
+			i = end
+		default:
+			i++
+		}
+	}
+	return colors
+}
+
+// cmdScreenEdit 不带行号的 EDIT 命令：在终端中打开全屏编辑器
+func cmdScreenEdit(store *CodeStore) bool {
+	fd := int(os.Stdin.Fd())
+	if !isTerminal(fd) || os.Getenv("TERM") == "dumb" {
+		fmt.Println("Error: Full-screen EDIT needs a terminal; use EDIT <line_number>")
+		return true
+	}
+	width, height, err := terminalSize(int(os.Stdout.Fd()))
+	if err != nil || width == 0 || height == 0 {
+		width, height = 80, 24
+	}
+	state, err := makeRaw(fd)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+	err = EditProgram(store, os.Stdin, os.Stdout, width, height)
+	restoreTerminal(fd, state)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	return true
+}
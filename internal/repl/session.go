@@ -0,0 +1,156 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/vm"
+)
+
+// Session 交互模式的运行状态
+// 变量和数组在直接模式语句和多次 RUN 之间保留，被 Ctrl-C 中断的程序可以用 CONT 继续执行
+type Session struct {
+	mode      string                 // 执行引擎：ast、vm 或 rvm
+	vars      *interpreter.Variables // 所有程序和直接模式语句共享的变量
+	interrupt atomic.Bool            // Ctrl-C 置位，引擎在下一个跳转处或下一行之前中断
+	stopped   *stopped               // 被中断、可以 CONT 的程序；nil 表示不能继续
+	decls     []ast.Node             // 最近运行的程序中的 TYPE 定义和记录声明，用于展开直接模式语句
+	internal  map[string]bool        // 最近运行的程序展开子程序时生成的名称（数组名后加 "()"），不参与 Tab 补全
+}
+
+// stopped 被中断的程序
+type stopped struct {
+	resume   func() error // 继续执行；再次被中断时返回 *interpreter.Break
+	revision int          // 中断时程序的修订号，程序被修改后不能继续
+}
+
+// NewSession 创建使用指定执行引擎的会话
+func NewSession(mode string) *Session {
+	return &Session{mode: mode, vars: interpreter.NewVariables()}
+}
+
+// HandleInterrupts 把 Ctrl-C 转为中断正在运行的程序，而不是结束整个进程
+// 返回的函数恢复默认的信号处理
+func (s *Session) HandleInterrupts() func() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		for range sigs {
+			s.interrupt.Store(true)
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}
+}
+
+// Run 从头执行程序（RUN 命令），变量保留之前的值
+// revision 是程序的修订号，用于判断中断后能否继续
+func (s *Session) Run(prog *ast.Program, revision int) {
+	s.decls = prog.Decls
+	s.internal = internalNames(prog)
+	resume, err := s.start(prog)
+	if err != nil {
+		fmt.Printf("Compilation error: %v\n", err)
+		return
+	}
+	s.stopped = nil
+	if s.execute(resume, false) {
+		s.stopped = &stopped{resume: resume, revision: revision}
+		return
+	}
+	fmt.Println("\nProgram complete.")
+}
+
+// Direct 执行一条直接模式语句，它读写的变量与程序共享
+// 直接模式语句被中断后不能继续，但不影响之前被中断的程序
+func (s *Session) Direct(prog *ast.Program) {
+	resume, err := s.start(prog)
+	if err != nil {
+		fmt.Printf("Compilation error: %v\n", err)
+		return
+	}
+	if s.execute(resume, true) {
+		return
+	}
+	fmt.Println("\nProgram complete.")
+}
+
+// Continue 继续执行被中断的程序（CONT 命令）
+// 程序在中断后被修改过（revision 不同）时不能继续
+func (s *Session) Continue(revision int) {
+	if s.stopped == nil || s.stopped.revision != revision {
+		s.stopped = nil
+		fmt.Println("Error: Can't continue")
+		return
+	}
+	resume := s.stopped.resume
+	s.stopped = nil
+	if s.execute(resume, false) {
+		s.stopped = &stopped{resume: resume, revision: revision}
+		return
+	}
+	fmt.Println("\nProgram complete.")
+}
+
+// Clear 清除所有变量和数组（CLEAR 命令），被中断的程序也不能再继续
+func (s *Session) Clear() {
+	s.vars.Clear()
+	s.stopped = nil
+}
+
+// start 编译程序并返回开始执行它的函数；该函数在程序被中断后再次调用时从中断处继续
+func (s *Session) start(prog *ast.Program) (func() error, error) {
+	if s.mode != "vm" && s.mode != "rvm" {
+		interp := interpreter.NewInterpreter(interpreter.WithVariables(s.vars), interpreter.WithInterrupt(&s.interrupt))
+		started := false
+		return func() error {
+			if started {
+				return interp.Continue()
+			}
+			started = true
+			return interp.Run(prog)
+		}, nil
+	}
+
+	var opts []compiler.Option
+	if s.mode == "rvm" {
+		opts = append(opts, compiler.WithRegisters())
+	}
+	chunk, err := compiler.New(opts...).Compile(prog)
+	if err != nil {
+		return nil, err
+	}
+	vmOpts := []vm.Option{vm.WithVariables(s.vars), vm.WithInterrupt(&s.interrupt), vm.WithChain(compiler.ChainLoader(opts...))}
+	if s.mode == "rvm" {
+		return vm.NewRegister(chunk, vmOpts...).Run, nil
+	}
+	return vm.New(chunk, vmOpts...).Run, nil
+}
+
+// execute 运行或继续运行程序，报告中断和运行时错误；返回程序是否被中断
+// direct 表示直接模式语句，它没有行号，中断时不显示行号
+func (s *Session) execute(resume func() error, direct bool) bool {
+	s.interrupt.Store(false)
+	err := resume()
+	var brk *interpreter.Break
+	if errors.As(err, &brk) {
+		if direct {
+			fmt.Println("\nBreak")
+		} else {
+			fmt.Printf("\n%v\n", brk)
+		}
+		return true
+	}
+	if err != nil {
+		fmt.Printf("Runtime error: %v\n", err)
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || netbsd || openbsd
+
+package repl
+
+import "syscall"
+
+// BSD 系（含 macOS）上读取和设置终端属性的 ioctl 请求
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package repl
+
+import "errors"
+
+// terminalState 在不支持 termios 的系统上不保存任何内容
+type terminalState struct{}
+
+// isTerminal 在不支持 termios 的系统上总是返回 false，REPL 逐行读取输入
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errors.New("raw terminal mode is not supported on this system")
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	return nil
+}
+
+func terminalSize(fd int) (width, height int, err error) {
+	return 0, 0, errors.New("terminal size is not available on this system")
+}
@@ -0,0 +1,283 @@
+package vet
+
+import (
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// nodeKind 表示控制流图节点的类型
+type nodeKind int
+
+const (
+	kindStmt   nodeKind = iota // 普通语句，顺序执行到下一个节点
+	kindIf                     // IF 条件判断，两个后继：THEN 入口和 ELSE 入口
+	kindJump                   // 编译器生成的跳转（THEN 块结束后跳过 ELSE 块）
+	kindGoto                   // GOTO
+	kindGosub                  // GOSUB，后继为子程序入口，返回点由 RETURN 连接
+	kindReturn                 // RETURN，后继为所有 GOSUB 的返回点
+	kindEnd                    // END，以及不再回到本程序的 CHAIN
+	kindFor                    // FOR；FOR EACH 还有一个后继：字典为空时跳到的 NEXT 之后
+	kindNext                   // NEXT，后继为循环体入口和下一个节点
+)
+
+// node 表示语句级控制流图中的一个节点
+type node struct {
+	kind   nodeKind
+	stmt   ast.Node // 对应的语句（编译器生成的跳转为 nil）
+	line   int      // 所在的 BASIC 行号
+	target int      // GOTO/GOSUB 的目标行号
+	succ   []int    // 后继节点
+	pred   []int    // 前驱节点
+}
+
+// forLoop 记录一个静态匹配的 FOR/NEXT 或 FOR EACH/NEXT 循环
+type forLoop struct {
+	each      bool // FOR EACH 循环
+	varName   string
+	forNode   int // FOR 节点索引
+	nextNode  int // 匹配的 NEXT 节点索引（-1 表示没有匹配）
+	forLine   int
+	nextLine  int
+	bodyStart int // 循环体第一个节点索引
+}
+
+// graph 是程序的语句级控制流图
+type graph struct {
+	nodes       []*node
+	lineStart   map[int]int // 行号 -> 该行第一个节点
+	lineOrder   []int       // 按顺序排列的行号
+	loops       []*forLoop
+	returnSites []int // 所有 GOSUB 的返回点（GOSUB 之后的节点）
+	exit        int   // 程序末尾的隐式 END 节点
+}
+
+// builder 负责把 AST 降级为控制流图，同时收集 FOR/NEXT 匹配诊断
+type builder struct {
+	g        *graph
+	forStack []*forLoop
+	diags    []Diagnostic
+}
+
+// buildGraph 构建程序的控制流图
+func buildGraph(prog *ast.Program) (*graph, []Diagnostic) {
+	b := &builder{g: &graph{lineStart: make(map[int]int)}}
+	for _, line := range prog.Lines {
+		b.g.lineStart[line.LineNumber] = len(b.g.nodes)
+		b.g.lineOrder = append(b.g.lineOrder, line.LineNumber)
+		if len(line.Statements) == 0 {
+			// 空行也需要一个节点，使其可以作为跳转目标
+			b.add(&node{kind: kindStmt, line: line.LineNumber})
+			continue
+		}
+		b.emitBlock(line.Statements, line.LineNumber)
+	}
+	b.g.exit = b.add(&node{kind: kindEnd})
+
+	for _, loop := range b.forStack {
+		b.diags = append(b.diags, Diagnostic{
+			Line:     loop.forLine,
+			Severity: SeverityError,
+			Check:    "fornext",
+			Message:  "FOR " + loop.varName + " without matching NEXT",
+		})
+	}
+
+	b.link()
+	return b.g, b.diags
+}
+
+func (b *builder) add(n *node) int {
+	b.g.nodes = append(b.g.nodes, n)
+	return len(b.g.nodes) - 1
+}
+
+// emitBlock 按顺序降级语句列表，IF 的 THEN/ELSE 块递归展开
+func (b *builder) emitBlock(stmts []ast.Node, line int) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			cond := b.add(&node{kind: kindIf, stmt: s, line: line})
+			thenEntry := len(b.g.nodes)
+			b.emitBlock(s.ThenStmts, line)
+			skip := b.add(&node{kind: kindJump, line: line})
+			elseEntry := len(b.g.nodes)
+			b.emitBlock(s.ElseStmts, line)
+			b.g.nodes[cond].succ = []int{thenEntry, elseEntry}
+			b.g.nodes[skip].succ = []int{len(b.g.nodes)}
+
+		case *ast.GotoStmt:
+			b.add(&node{kind: kindGoto, stmt: s, line: line, target: s.LineNumber})
+
+		case *ast.GosubStmt:
+			if s.Extern != "" {
+				// 调用其他模块的例程：目标在链接前未知，视为会返回的普通语句
+				b.add(&node{kind: kindStmt, stmt: s, line: line})
+				break
+			}
+			b.add(&node{kind: kindGosub, stmt: s, line: line, target: s.LineNumber})
+			b.g.returnSites = append(b.g.returnSites, len(b.g.nodes))
+
+		case *ast.ReturnStmt:
+			b.add(&node{kind: kindReturn, stmt: s, line: line})
+
+		case *ast.EndStmt, *ast.ChainStmt:
+			b.add(&node{kind: kindEnd, stmt: s, line: line})
+
+		case *ast.ForStmt:
+			idx := b.add(&node{kind: kindFor, stmt: s, line: line})
+			b.forStack = append(b.forStack, &forLoop{
+				varName:   strings.ToUpper(s.Var),
+				forNode:   idx,
+				nextNode:  -1,
+				forLine:   line,
+				bodyStart: idx + 1,
+			})
+
+		case *ast.ForEachStmt:
+			idx := b.add(&node{kind: kindFor, stmt: s, line: line})
+			b.forStack = append(b.forStack, &forLoop{
+				each:      true,
+				varName:   strings.ToUpper(s.Var),
+				forNode:   idx,
+				nextNode:  -1,
+				forLine:   line,
+				bodyStart: idx + 1,
+			})
+
+		case *ast.NextStmt:
+			idx := b.add(&node{kind: kindNext, stmt: s, line: line})
+			if len(b.forStack) == 0 {
+				b.diags = append(b.diags, Diagnostic{
+					Line:     line,
+					Severity: SeverityError,
+					Check:    "fornext",
+					Message:  "NEXT without FOR",
+				})
+				continue
+			}
+			loop := b.forStack[len(b.forStack)-1]
+			b.forStack = b.forStack[:len(b.forStack)-1]
+			if s.Var != "" && strings.ToUpper(s.Var) != loop.varName {
+				b.diags = append(b.diags, Diagnostic{
+					Line:     line,
+					Severity: SeverityError,
+					Check:    "fornext",
+					Message:  "NEXT " + strings.ToUpper(s.Var) + " does not match FOR " + loop.varName,
+				})
+			}
+			loop.nextNode = idx
+			loop.nextLine = line
+			b.g.loops = append(b.g.loops, loop)
+
+		default:
+			b.add(&node{kind: kindStmt, stmt: s, line: line})
+		}
+	}
+}
+
+// link 计算所有节点的后继和前驱
+func (b *builder) link() {
+	g := b.g
+	nextOf := make(map[int]*forLoop)
+	eachOf := make(map[int]*forLoop)
+	for _, loop := range g.loops {
+		nextOf[loop.nextNode] = loop
+		if loop.each {
+			eachOf[loop.forNode] = loop
+		}
+	}
+
+	for idx, n := range g.nodes {
+		switch n.kind {
+		case kindStmt:
+			n.succ = []int{idx + 1}
+		case kindFor:
+			n.succ = []int{idx + 1}
+			if loop, ok := eachOf[idx]; ok {
+				n.succ = append(n.succ, loop.nextNode+1)
+			}
+		case kindGoto, kindGosub:
+			if target, ok := g.lineStart[n.target]; ok {
+				n.succ = []int{target}
+			}
+		case kindReturn:
+			n.succ = append([]int(nil), g.returnSites...)
+		case kindNext:
+			n.succ = []int{idx + 1}
+			if loop, ok := nextOf[idx]; ok {
+				n.succ = append(n.succ, loop.bodyStart)
+			}
+		case kindEnd:
+			n.succ = nil
+		}
+	}
+
+	for idx, n := range g.nodes {
+		for _, s := range n.succ {
+			if s < len(g.nodes) {
+				g.nodes[s].pred = append(g.nodes[s].pred, idx)
+			}
+		}
+	}
+}
+
+// reachable 返回从 start 出发可以到达的所有节点
+func (g *graph) reachable(start int) []bool {
+	seen := make([]bool, len(g.nodes))
+	work := []int{start}
+	seen[start] = true
+	for len(work) > 0 {
+		cur := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, s := range g.nodes[cur].succ {
+			if s < len(g.nodes) && !seen[s] {
+				seen[s] = true
+				work = append(work, s)
+			}
+		}
+	}
+	return seen
+}
+
+// live 返回从程序入口可达的节点。模块导出的子程序由其他模块调用，其入口也算作程序入口
+func (g *graph) live(prog *ast.Program) []bool {
+	live := g.reachable(0)
+	for _, r := range prog.Routines {
+		start, ok := g.lineStart[r.Line]
+		if !r.Export || !ok {
+			continue
+		}
+		for idx, reached := range g.reachable(start) {
+			live[idx] = live[idx] || reached
+		}
+	}
+	return live
+}
+
+// reachesReturn 判断从 start 出发（把 GOSUB 视为调用后返回）能否执行到 RETURN
+func (g *graph) reachesReturn(start int) bool {
+	seen := make([]bool, len(g.nodes))
+	work := []int{start}
+	seen[start] = true
+	for len(work) > 0 {
+		cur := work[len(work)-1]
+		work = work[:len(work)-1]
+		n := g.nodes[cur]
+		if n.kind == kindReturn {
+			return true
+		}
+		succ := n.succ
+		if n.kind == kindGosub {
+			// 嵌套子程序调用：假设其返回，继续执行下一个节点
+			succ = []int{cur + 1}
+		}
+		for _, s := range succ {
+			if s < len(g.nodes) && !seen[s] {
+				seen[s] = true
+				work = append(work, s)
+			}
+		}
+	}
+	return false
+}
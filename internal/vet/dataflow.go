@@ -0,0 +1,393 @@
+package vet
+
+import (
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// access 描述一个控制流节点读写的变量和数组
+type access struct {
+	readVars   []string
+	readArrays []string
+	writeVars  []string
+	dimArrays  []string
+}
+
+// accessOf 计算节点的读写集合（变量名均已大写）
+func accessOf(n *node) access {
+	var acc access
+	readExpr := func(e ast.Node) {
+		walkExpr(e, func(x ast.Node) {
+			switch v := x.(type) {
+			case *ast.Identifier:
+				acc.readVars = append(acc.readVars, strings.ToUpper(v.Name))
+			case *ast.ArrayAccess:
+				acc.readArrays = append(acc.readArrays, strings.ToUpper(v.Name))
+			case *ast.MapAccess:
+				acc.readArrays = append(acc.readArrays, strings.ToUpper(v.Name))
+			case *ast.MapFunc:
+				acc.readArrays = append(acc.readArrays, strings.ToUpper(v.Map))
+			case *ast.RecordRef:
+				// 记录存放在数组表中，使用前同样需要 DIM
+				acc.readArrays = append(acc.readArrays, strings.ToUpper(v.Name))
+			}
+		})
+	}
+
+	switch s := n.stmt.(type) {
+	case *ast.Assignment:
+		readExpr(s.Value)
+		switch target := s.Target.(type) {
+		case *ast.Identifier:
+			acc.writeVars = append(acc.writeVars, strings.ToUpper(target.Name))
+		case *ast.ArrayAccess, *ast.MapAccess, *ast.FieldAccess:
+			readExpr(target)
+		}
+	case *ast.RecordAssignment:
+		readExpr(s.Target)
+		readExpr(s.Value)
+	case *ast.PrintStmt:
+		for _, v := range s.Values {
+			readExpr(v)
+		}
+	case *ast.IfStmt:
+		readExpr(s.Condition)
+	case *ast.IfBlockStmt:
+		readExpr(s.Condition)
+	case *ast.ForStmt:
+		readExpr(s.Start)
+		readExpr(s.End)
+		readExpr(s.Step)
+		acc.writeVars = append(acc.writeVars, strings.ToUpper(s.Var))
+	case *ast.DimStmt:
+		for _, size := range s.Sizes {
+			readExpr(size)
+		}
+		acc.dimArrays = append(acc.dimArrays, strings.ToUpper(s.Name))
+	case *ast.DeleteKeyStmt:
+		readExpr(s.Key)
+		acc.readArrays = append(acc.readArrays, strings.ToUpper(s.Map))
+	case *ast.ForEachStmt:
+		// FOR EACH 读取 MAP 的键并赋给循环变量
+		acc.readArrays = append(acc.readArrays, strings.ToUpper(s.Map))
+		acc.writeVars = append(acc.writeVars, strings.ToUpper(s.Var))
+	case *ast.ChainStmt:
+		readExpr(s.File)
+		readExpr(s.Line)
+	case *ast.CommonStmt:
+		// COMMON 的变量可能由 CHAIN 到本程序的上一个程序赋值
+		for _, v := range s.Vars {
+			switch target := v.(type) {
+			case *ast.Identifier:
+				acc.writeVars = append(acc.writeVars, strings.ToUpper(target.Name))
+			case *ast.ArrayAccess:
+				acc.dimArrays = append(acc.dimArrays, strings.ToUpper(target.Name))
+			}
+		}
+	case *ast.InputStmt:
+		for _, v := range s.Vars {
+			switch target := v.(type) {
+			case *ast.Identifier:
+				acc.writeVars = append(acc.writeVars, strings.ToUpper(target.Name))
+			case *ast.ArrayAccess, *ast.MapAccess, *ast.FieldAccess:
+				readExpr(target)
+			}
+		}
+	}
+	return acc
+}
+
+// walkExpr 先序遍历表达式树
+func walkExpr(e ast.Node, visit func(ast.Node)) {
+	if e == nil {
+		return
+	}
+	visit(e)
+	switch n := e.(type) {
+	case *ast.BinaryOp:
+		walkExpr(n.Left, visit)
+		walkExpr(n.Right, visit)
+	case *ast.ComparisonOp:
+		walkExpr(n.Left, visit)
+		walkExpr(n.Right, visit)
+	case *ast.LogicalOp:
+		walkExpr(n.Left, visit)
+		walkExpr(n.Right, visit)
+	case *ast.UnaryOp:
+		walkExpr(n.Right, visit)
+	case *ast.FunctionCall:
+		for _, arg := range n.Args {
+			walkExpr(arg, visit)
+		}
+	case *ast.ArrayAccess:
+		for _, idx := range n.Indices {
+			walkExpr(idx, visit)
+		}
+	case *ast.MapAccess:
+		walkExpr(n.Key, visit)
+	case *ast.MapFunc:
+		walkExpr(n.Arg, visit)
+	case *ast.FieldAccess:
+		walkExpr(n.Record, visit)
+	case *ast.RecordRef:
+		for _, idx := range n.Indices {
+			walkExpr(idx, visit)
+		}
+	}
+}
+
+// bitset 是定长位集合，用于数据流分析
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (uint(i) % 64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<(uint(i)%64)) != 0
+}
+
+func (b bitset) fill() {
+	for i := range b {
+		b[i] = ^uint64(0)
+	}
+}
+
+func (b bitset) intersect(o bitset) {
+	for i := range b {
+		b[i] &= o[i]
+	}
+}
+
+func (b bitset) union(o bitset) {
+	for i := range b {
+		b[i] |= o[i]
+	}
+}
+
+func (b bitset) equal(o bitset) bool {
+	for i := range b {
+		if b[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// definedBefore 运行"必定已赋值"前向数据流分析，返回每个节点的读写集合、
+// 变量和数组（数组使用 "NAME()" 作为键）的位索引，以及每个节点入口处必定已赋值的集合
+func (a *analyzer) definedBefore() ([]access, map[string]int, []bitset) {
+	nodes := a.g.nodes
+	index := make(map[string]int)
+	keyOf := func(name string) int {
+		if idx, ok := index[name]; ok {
+			return idx
+		}
+		index[name] = len(index)
+		return index[name]
+	}
+	accesses := make([]access, len(nodes))
+	for idx, n := range nodes {
+		accesses[idx] = accessOf(n)
+		for _, v := range accesses[idx].readVars {
+			keyOf(v)
+		}
+		for _, v := range accesses[idx].writeVars {
+			keyOf(v)
+		}
+		for _, arr := range accesses[idx].readArrays {
+			keyOf(arr + "()")
+		}
+		for _, arr := range accesses[idx].dimArrays {
+			keyOf(arr + "()")
+		}
+	}
+	size := len(index)
+
+	gen := make([]bitset, len(nodes))
+	out := make([]bitset, len(nodes))
+	in := make([]bitset, len(nodes))
+	for idx := range nodes {
+		gen[idx] = newBitset(size)
+		for _, v := range accesses[idx].writeVars {
+			gen[idx].set(index[v])
+		}
+		for _, arr := range accesses[idx].dimArrays {
+			gen[idx].set(index[arr+"()"])
+		}
+		in[idx] = newBitset(size)
+		out[idx] = newBitset(size)
+		out[idx].fill()
+	}
+
+	// 迭代直到不动点
+	for changed := true; changed; {
+		changed = false
+		for idx := range nodes {
+			if !a.live[idx] {
+				continue
+			}
+			newIn := newBitset(size)
+			if idx != 0 {
+				newIn.fill()
+				for _, p := range nodes[idx].pred {
+					if !a.live[p] {
+						continue
+					}
+					if nodes[p].kind == kindReturn {
+						// 返回点紧跟在 GOSUB 之后：调用前已赋值的变量返回后仍然有效
+						ret := newBitset(size)
+						copy(ret, out[p])
+						ret.union(out[idx-1])
+						newIn.intersect(ret)
+						continue
+					}
+					newIn.intersect(out[p])
+				}
+			}
+			newOut := newBitset(size)
+			copy(newOut, newIn)
+			newOut.union(gen[idx])
+			if !newOut.equal(out[idx]) || !newIn.equal(in[idx]) {
+				in[idx] = newIn
+				out[idx] = newOut
+				changed = true
+			}
+		}
+	}
+	return accesses, index, in
+}
+
+// MaybeUnassigned 返回在某个可达位置可能于赋值前被读取的变量（名称已大写）。
+// zb build 据此判断哪些变量可以使用静态类型
+func MaybeUnassigned(prog *ast.Program) map[string]bool {
+	result := make(map[string]bool)
+	g, _ := buildGraph(prog)
+	if len(g.nodes) == 0 {
+		return result
+	}
+	a := &analyzer{prog: prog, g: g, live: g.live(prog)}
+	accesses, index, in := a.definedBefore()
+	for idx := range g.nodes {
+		if !a.live[idx] {
+			continue
+		}
+		for _, v := range accesses[idx].readVars {
+			if !in[idx].has(index[v]) {
+				result[v] = true
+			}
+		}
+	}
+	return result
+}
+
+// checkDefinedBeforeUse 使用"必定已赋值"前向数据流分析，
+// 检查变量在赋值前被读取、数组在 DIM 前被访问的情况
+func (a *analyzer) checkDefinedBeforeUse() {
+	nodes := a.g.nodes
+	if len(nodes) == 0 {
+		return
+	}
+	accesses, index, in := a.definedBefore()
+	assigned := make(map[string]bool)
+	dimmed := make(map[string]bool)
+	for _, acc := range accesses {
+		for _, v := range acc.writeVars {
+			assigned[v] = true
+		}
+		for _, arr := range acc.dimArrays {
+			dimmed[arr] = true
+		}
+	}
+
+	type seenKey struct {
+		name string
+		line int
+	}
+	seen := make(map[seenKey]bool)
+	for idx, n := range nodes {
+		if !a.live[idx] {
+			continue
+		}
+		for _, v := range accesses[idx].readVars {
+			key := seenKey{v, n.line}
+			if in[idx].has(index[v]) || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if !assigned[v] {
+				a.report(n.line, SeverityWarning, "uninit", "variable %s is never assigned", v)
+			} else {
+				a.report(n.line, SeverityWarning, "uninit", "variable %s may be used before assignment", v)
+			}
+		}
+		for _, arr := range accesses[idx].readArrays {
+			key := seenKey{arr + "()", n.line}
+			if in[idx].has(index[arr+"()"]) || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if !dimmed[arr] {
+				a.report(n.line, SeverityError, "nodim", "array %s used without DIM", arr)
+			} else {
+				a.report(n.line, SeverityWarning, "nodim", "array %s may be used before DIM", arr)
+			}
+		}
+	}
+}
+
+// checkUnused 报告被赋值但从未被读取的变量（FOR 循环变量除外）。
+// COMMON 声明的变量由 CHAIN 传给下一个程序，CHAIN ... ALL 传递所有变量，都不算未使用
+func (a *analyzer) checkUnused() {
+	read := make(map[string]bool)
+	loopVars := make(map[string]bool)
+	firstWrite := make(map[string]int)
+	var order []string
+	for _, n := range a.g.nodes {
+		switch s := n.stmt.(type) {
+		case *ast.CommonStmt:
+			for _, v := range s.Vars {
+				read[strings.ToUpper(v.String())] = true
+			}
+		case *ast.ChainStmt:
+			if s.All {
+				return
+			}
+		}
+		acc := accessOf(n)
+		for _, v := range acc.readVars {
+			read[v] = true
+		}
+		if f, ok := n.stmt.(*ast.ForStmt); ok {
+			loopVars[strings.ToUpper(f.Var)] = true
+		}
+		for _, v := range acc.writeVars {
+			if _, ok := firstWrite[v]; !ok {
+				firstWrite[v] = n.line
+				order = append(order, v)
+			}
+		}
+	}
+	// 其他模块中的子程序读取参数，调用者读取导出函数的返回值
+	for _, r := range a.prog.Routines {
+		if r.Extern {
+			for _, v := range r.ParamVars {
+				read[v] = true
+			}
+		}
+		if r.Export && r.Function {
+			read[r.Name] = true
+		}
+	}
+	for _, v := range order {
+		if read[v] || loopVars[v] {
+			continue
+		}
+		a.report(firstWrite[v], SeverityWarning, "unused", "variable %s is assigned but never used", v)
+	}
+}
@@ -0,0 +1,224 @@
+package vet
+
+import (
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// typeName 返回类型的可读名称
+func typeName(t valueType) string {
+	switch t {
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	}
+	return "unknown"
+}
+
+// builtinArgTypes 返回内置函数各参数的期望类型（nil 表示不检查）
+func builtinArgTypes(name string, argc int) []valueType {
+	num, str := typeNumber, typeString
+	switch name {
+	case "LEN", "UCASE$", "LCASE$", "ASC":
+		return []valueType{str}
+	case "LEFT$", "RIGHT$":
+		return []valueType{str, num}
+	case "MID$":
+		return []valueType{str, num, num}
+	case "INSTR":
+		if argc == 3 {
+			return []valueType{num, str, str}
+		}
+		return []valueType{str, str}
+	case "ABS", "SIN", "COS", "TAN", "INT", "EXP", "SQR", "LOG", "SPACE$", "CHR$":
+		return []valueType{num}
+	}
+	return nil
+}
+
+// checkTypes 检查字符串与数字混用的表达式和赋值
+func (a *analyzer) checkTypes() {
+	for idx, n := range a.g.nodes {
+		if !a.live[idx] {
+			continue
+		}
+		switch s := n.stmt.(type) {
+		case *ast.Assignment:
+			valueType := a.exprType(s.Value, n.line)
+			switch target := s.Target.(type) {
+			case *ast.Identifier:
+				name := strings.ToUpper(target.Name)
+				want := typeNumber
+				if isStringName(name) {
+					want = typeString
+				}
+				if valueType != typeUnknown && valueType != want {
+					a.report(n.line, SeverityWarning, "types", "assigning %s to %s variable %s",
+						typeName(valueType), typeName(want), name)
+				}
+			case *ast.ArrayAccess:
+				want := a.exprType(target, n.line)
+				if valueType != typeUnknown && valueType != want {
+					kind := "numeric"
+					if want == typeString {
+						kind = "string"
+					}
+					a.report(n.line, SeverityWarning, "types", "assigning %s to element of %s array %s",
+						typeName(valueType), kind, strings.ToUpper(target.Name))
+				}
+			case *ast.MapAccess:
+				want := a.exprType(target, n.line)
+				if valueType != typeUnknown && valueType != want {
+					a.report(n.line, SeverityWarning, "types", "assigning %s to %s MAP %s",
+						typeName(valueType), typeName(want), strings.ToUpper(target.Name))
+				}
+			case *ast.FieldAccess:
+				want := a.exprType(target, n.line)
+				if valueType != typeUnknown && valueType != want {
+					a.report(n.line, SeverityWarning, "types", "assigning %s to %s field %s",
+						typeName(valueType), typeName(want), target)
+				}
+			}
+		case *ast.PrintStmt:
+			for _, v := range s.Values {
+				a.exprType(v, n.line)
+			}
+		case *ast.IfStmt:
+			a.exprType(s.Condition, n.line)
+		case *ast.IfBlockStmt:
+			a.exprType(s.Condition, n.line)
+		case *ast.ForStmt:
+			if isStringName(s.Var) {
+				a.report(n.line, SeverityWarning, "types", "FOR loop variable %s is a string variable", strings.ToUpper(s.Var))
+			}
+			for _, e := range []ast.Node{s.Start, s.End, s.Step} {
+				if a.exprType(e, n.line) == typeString {
+					a.report(n.line, SeverityWarning, "types", "FOR %s bound %s is a string", strings.ToUpper(s.Var), e.String())
+				}
+			}
+		case *ast.DimStmt:
+			for _, size := range s.Sizes {
+				if a.exprType(size, n.line) == typeString {
+					a.report(n.line, SeverityWarning, "types", "DIM %s size %s is a string", strings.ToUpper(s.Name), size.String())
+				}
+			}
+		case *ast.DeleteKeyStmt:
+			a.mapKeyType(s.Map, s.Key, n.line)
+		case *ast.ChainStmt:
+			if a.exprType(s.File, n.line) == typeNumber {
+				a.report(n.line, SeverityWarning, "types", "CHAIN file name %s is a number", s.File.String())
+			}
+			if s.Line != nil && a.exprType(s.Line, n.line) == typeString {
+				a.report(n.line, SeverityWarning, "types", "CHAIN line %s is a string", s.Line.String())
+			}
+		}
+	}
+}
+
+// exprType 推断表达式类型，并报告其中的类型不匹配
+func (a *analyzer) exprType(e ast.Node, line int) valueType {
+	switch n := e.(type) {
+	case *ast.Number:
+		return typeNumber
+	case *ast.StringLiteral:
+		return typeString
+	case *ast.Identifier:
+		if isStringName(n.Name) {
+			return typeString
+		}
+		return typeNumber
+	case *ast.ArrayAccess:
+		for _, idx := range n.Indices {
+			if a.exprType(idx, line) == typeString {
+				a.report(line, SeverityWarning, "types", "array %s index %s is a string", strings.ToUpper(n.Name), idx.String())
+			}
+		}
+		if isStringName(n.Name) {
+			return typeString
+		}
+		return typeNumber
+	case *ast.MapAccess:
+		a.mapKeyType(n.Name, n.Key, line)
+		if isStringName(n.Name) {
+			return typeString
+		}
+		return typeNumber
+	case *ast.FieldAccess:
+		ref, _ := n.Slot()
+		for _, idx := range ref.Indices {
+			if a.exprType(idx, line) == typeString {
+				a.report(line, SeverityWarning, "types", "array %s index %s is a string", strings.ToUpper(ref.Name), idx.String())
+			}
+		}
+		if f, _ := n.Info(); f.String {
+			return typeString
+		}
+		return typeNumber
+	case *ast.MapFunc:
+		switch strings.ToUpper(n.Func) {
+		case "HASKEY":
+			a.mapKeyType(n.Map, n.Arg, line)
+		case "KEYS$":
+			if a.exprType(n.Arg, line) == typeString {
+				a.report(line, SeverityWarning, "types", "KEYS$ index %s is a string", n.Arg.String())
+			}
+			return typeString
+		}
+		return typeNumber
+	case *ast.FunctionCall:
+		name := strings.ToUpper(n.Name)
+		want := builtinArgTypes(name, len(n.Args))
+		for i, arg := range n.Args {
+			got := a.exprType(arg, line)
+			if i < len(want) && got != typeUnknown && got != want[i] {
+				a.report(line, SeverityWarning, "types", "%s argument %d: expected %s, got %s",
+					name, i+1, typeName(want[i]), typeName(got))
+			}
+		}
+		if strings.HasSuffix(name, "$") {
+			return typeString
+		}
+		return typeNumber
+	case *ast.BinaryOp:
+		left := a.exprType(n.Left, line)
+		right := a.exprType(n.Right, line)
+		if n.Op == "+" {
+			if left != typeUnknown && right != typeUnknown && left != right {
+				a.report(line, SeverityWarning, "types", "mixing string and number in %s", n.String())
+				return typeString
+			}
+			return left
+		}
+		if left == typeString || right == typeString {
+			a.report(line, SeverityWarning, "types", "string operand in arithmetic %s", n.String())
+		}
+		return typeNumber
+	case *ast.ComparisonOp:
+		left := a.exprType(n.Left, line)
+		right := a.exprType(n.Right, line)
+		if left != typeUnknown && right != typeUnknown && left != right {
+			a.report(line, SeverityWarning, "types", "comparing string and number in %s", n.String())
+		}
+		return typeNumber
+	case *ast.LogicalOp:
+		a.exprType(n.Left, line)
+		a.exprType(n.Right, line)
+		return typeNumber
+	case *ast.UnaryOp:
+		operand := a.exprType(n.Right, line)
+		if n.Op == "-" && operand == typeString {
+			a.report(line, SeverityWarning, "types", "negating string %s", n.Right.String())
+		}
+		return typeNumber
+	}
+	return typeUnknown
+}
+
+// mapKeyType 检查 MAP 的键是否为字符串
+func (a *analyzer) mapKeyType(name string, key ast.Node, line int) {
+	if a.exprType(key, line) == typeNumber {
+		a.report(line, SeverityWarning, "types", "MAP %s key %s is a number", strings.ToUpper(name), key.String())
+	}
+}
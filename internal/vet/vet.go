@@ -0,0 +1,267 @@
+// Package vet 提供 BASIC 程序的静态分析（zb vet）
+// 在运行前检查跳转目标、不可达代码、未初始化变量、未声明数组、
+// FOR/NEXT 匹配、子程序返回、类型不匹配和未使用变量等问题
+package vet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"zork-basic/internal/ast"
+)
+
+// Severity 表示诊断的严重程度
+type Severity string
+
+const (
+	// SeverityError 几乎一定会导致运行时错误的问题
+	SeverityError Severity = "error"
+	// SeverityWarning 可疑但不一定出错的代码
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic 表示一条静态分析诊断
+type Diagnostic struct {
+	File     string   `json:"file,omitempty"` // 所在的源文件，为空时是被检查的文件
+	Line     int      `json:"line"`           // BASIC 行号；无行号程序中是源文件中的行号
+	Severity Severity `json:"severity"`       // 严重程度
+	Check    string   `json:"check"`          // 检查项名称（如 "unreachable"）
+	Message  string   `json:"message"`        // 诊断信息
+}
+
+// valueType 表示表达式的静态类型
+type valueType int
+
+const (
+	typeUnknown valueType = iota
+	typeNumber
+	typeString
+)
+
+// analyzer 保存一次分析的状态
+type analyzer struct {
+	prog  *ast.Program
+	g     *graph
+	diags []Diagnostic
+	live  []bool // 从程序入口可达的节点
+}
+
+// Check 对程序进行静态分析，返回按行号排序的诊断列表
+func Check(prog *ast.Program) []Diagnostic {
+	g, diags := buildGraph(prog)
+	a := &analyzer{prog: prog, g: g, diags: diags}
+	if len(g.nodes) > 0 {
+		a.live = g.live(prog)
+	}
+
+	a.checkJumpTargets()
+	a.checkUnreachable()
+	a.checkLoopJumps()
+	a.checkGosubReturns()
+	a.checkDefinedBeforeUse()
+	a.checkTypes()
+	a.checkUnused()
+
+	sort.SliceStable(a.diags, func(i, j int) bool {
+		return a.diags[i].Line < a.diags[j].Line
+	})
+	a.sourcePositions()
+	return a.diags
+}
+
+// sourcePositions 把诊断指向 $INCLUDE 进来的文件；无行号程序的内部行号换成源文件中的行号
+func (a *analyzer) sourcePositions() {
+	lines := make(map[int]*ast.Line, len(a.prog.Lines))
+	for _, line := range a.prog.Lines {
+		lines[line.LineNumber] = line
+	}
+	for idx := range a.diags {
+		d := &a.diags[idx]
+		line, ok := lines[d.Line]
+		if !ok {
+			continue
+		}
+		d.File = line.Pos.File
+		if a.prog.Unnumbered {
+			d.Line = line.Pos.Line
+		}
+	}
+}
+
+func (a *analyzer) report(line int, sev Severity, check string, format string, args ...interface{}) {
+	a.diags = append(a.diags, Diagnostic{
+		Line:     line,
+		Severity: sev,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// checkJumpTargets 检查 GOTO/GOSUB 的目标行是否存在
+func (a *analyzer) checkJumpTargets() {
+	for _, n := range a.g.nodes {
+		if n.kind != kindGoto && n.kind != kindGosub {
+			continue
+		}
+		if _, ok := a.g.lineStart[n.target]; !ok {
+			keyword := "GOTO"
+			if n.kind == kindGosub {
+				keyword = "GOSUB"
+			}
+			a.report(n.line, SeverityError, "jumptarget", "%s %d: line %d does not exist", keyword, n.target, n.target)
+		}
+	}
+}
+
+// checkUnreachable 报告无法从程序入口到达的行，连续的不可达行合并为一条诊断
+func (a *analyzer) checkUnreachable() {
+	headers := routineHeaders(a.prog)
+	runStart, runEnd := -1, -1
+	flush := func() {
+		if runStart < 0 {
+			return
+		}
+		if runStart == runEnd {
+			a.report(runStart, SeverityWarning, "unreachable", "unreachable code")
+		} else {
+			a.report(runStart, SeverityWarning, "unreachable", "unreachable code (lines %d-%d)", runStart, runEnd)
+		}
+		runStart, runEnd = -1, -1
+	}
+
+	for _, line := range a.prog.Lines {
+		if !hasCode(line) || headers[line.LineNumber] {
+			continue
+		}
+		start := a.g.lineStart[line.LineNumber]
+		reached := false
+		for idx := start; idx < len(a.g.nodes) && a.g.nodes[idx].line == line.LineNumber; idx++ {
+			if a.live[idx] {
+				reached = true
+				break
+			}
+		}
+		if reached {
+			flush()
+			continue
+		}
+		if runStart < 0 {
+			runStart = line.LineNumber
+		}
+		runEnd = line.LineNumber
+	}
+	flush()
+}
+
+// checkLoopJumps 检查跳入 FOR 循环体或从循环体中跳出的 GOTO
+func (a *analyzer) checkLoopJumps() {
+	for idx, n := range a.g.nodes {
+		if n.kind != kindGoto {
+			continue
+		}
+		target, ok := a.g.lineStart[n.target]
+		if !ok {
+			continue
+		}
+		for _, loop := range a.g.loops {
+			inside := idx > loop.forNode && idx < loop.nextNode
+			targetInside := target > loop.forNode && target <= loop.nextNode
+			switch {
+			case inside && !targetInside:
+				a.report(n.line, SeverityWarning, "fornext",
+					"GOTO %d leaves FOR %s loop (lines %d-%d) without completing NEXT",
+					n.target, loop.varName, loop.forLine, loop.nextLine)
+			case !inside && targetInside:
+				a.report(n.line, SeverityError, "fornext",
+					"GOTO %d jumps into FOR %s loop body (lines %d-%d)",
+					n.target, loop.varName, loop.forLine, loop.nextLine)
+			}
+		}
+	}
+}
+
+// checkGosubReturns 检查 GOSUB 调用的子程序是否可能执行到 RETURN
+func (a *analyzer) checkGosubReturns() {
+	checked := make(map[int]bool)
+	for _, n := range a.g.nodes {
+		if n.kind != kindGosub {
+			continue
+		}
+		target, ok := a.g.lineStart[n.target]
+		if !ok {
+			continue
+		}
+		if returns, seen := checked[n.target]; seen {
+			if !returns {
+				a.report(n.line, SeverityWarning, "noreturn", "GOSUB %d: subroutine never RETURNs", n.target)
+			}
+			continue
+		}
+		returns := a.g.reachesReturn(target)
+		checked[n.target] = returns
+		if !returns {
+			a.report(n.line, SeverityWarning, "noreturn", "GOSUB %d: subroutine never RETURNs", n.target)
+		}
+	}
+}
+
+// hasCode 判断一行是否包含 REM 以外的语句
+// routineHeaders 返回 SUB/FUNCTION 所在的行。这些行展开为跳过子程序的 GOTO，
+// 子程序放在 END 之后时执行不到也不算不可达代码
+func routineHeaders(prog *ast.Program) map[int]bool {
+	headers := make(map[int]bool)
+	for idx, line := range prog.Lines {
+		if idx+1 < len(prog.Lines) {
+			for _, r := range prog.Routines {
+				if !r.Extern && r.Line == prog.Lines[idx+1].LineNumber {
+					headers[line.LineNumber] = true
+				}
+			}
+		}
+	}
+	return headers
+}
+
+func hasCode(line *ast.Line) bool {
+	for _, stmt := range line.Statements {
+		if _, isRem := stmt.(*ast.RemStmt); !isRem {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText 以文本格式输出诊断：<文件>:<行号>: <级别>: <信息> (<检查项>)
+func WriteText(w io.Writer, filename string, diags []Diagnostic) error {
+	for _, d := range diags {
+		file := filename
+		if d.File != "" {
+			file = d.File
+		}
+		if _, err := fmt.Fprintf(w, "%s:%d: %s: %s (%s)\n", file, d.Line, d.Severity, d.Message, d.Check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON 以 JSON 格式输出诊断
+func WriteJSON(w io.Writer, filename string, diags []Diagnostic) error {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		File        string       `json:"file"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}{filename, diags})
+}
+
+// isStringName 判断变量名是否为字符串变量（以 $ 结尾）
+func isStringName(name string) bool {
+	return strings.HasSuffix(name, "$")
+}
@@ -0,0 +1,86 @@
+package vet_test
+
+import (
+	"strings"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vet"
+)
+
+func check(t *testing.T, src string) []vet.Diagnostic {
+	t.Helper()
+	parsed, err := parser.Parse("test.bas", []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	return vet.Check(parsed.(*ast.Program))
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		line  int
+		check string
+		msg   string
+	}{
+		{"missing GOTO target", "10 GOTO 50\n20 END\n", 10, "jumptarget", "line 50 does not exist"},
+		{"unreachable", "10 GOTO 30\n20 PRINT 1\n30 END\n", 20, "unreachable", "unreachable code"},
+		{"read before assignment", "10 PRINT X\n20 X = 1\n30 GOTO 10\n", 10, "uninit", "may be used before assignment"},
+		{"never assigned", "10 PRINT Y\n", 10, "uninit", "never assigned"},
+		{"array without DIM", "10 A(1) = 2\n", 10, "nodim", "array A used without DIM"},
+		{"FOR without NEXT", "10 FOR I = 1 TO 3\n20 PRINT I\n", 10, "fornext", "FOR I without matching NEXT"},
+		{"GOTO out of loop", "10 FOR I = 1 TO 3\n20 IF I = 2 THEN GOTO 40\n30 NEXT I\n40 END\n", 20, "fornext", "leaves FOR I loop"},
+		{"GOSUB without RETURN", "10 GOSUB 100\n20 END\n100 PRINT 1\n110 END\n", 10, "noreturn", "never RETURNs"},
+		{"string to numeric", "10 A = \"x\"\n20 PRINT A\n", 10, "types", "assigning string to number variable A"},
+		{"mixed comparison", "10 A$ = \"x\"\n20 IF A$ = 1 THEN PRINT 1\n", 20, "types", "comparing string and number"},
+		{"unused", "10 A = 1\n", 10, "unused", "A is assigned but never used"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := check(t, tt.src)
+			for _, d := range diags {
+				if d.Line == tt.line && d.Check == tt.check && strings.Contains(d.Message, tt.msg) {
+					return
+				}
+			}
+			t.Errorf("missing %s diagnostic %q on line %d; got %+v", tt.check, tt.msg, tt.line, diags)
+		})
+	}
+}
+
+func TestCheckCleanProgram(t *testing.T) {
+	src := `10 DIM A(5)
+20 FOR I = 0 TO 4
+30 A(I) = I * 2
+40 NEXT I
+50 GOSUB 100
+60 PRINT "TOTAL"; T
+70 END
+100 T = 0
+110 FOR J = 0 TO 4
+120 T = T + A(J)
+130 NEXT J
+140 RETURN
+`
+	if diags := check(t, src); len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %+v", diags)
+	}
+}
+
+func TestMaybeUnassigned(t *testing.T) {
+	src := "10 A = 1\n20 IF A THEN B = 2\n30 PRINT A; B; C\n40 FOR I = 1 TO 2: PRINT I: NEXT I\n"
+	parsed, err := parser.Parse("test.bas", []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	got := vet.MaybeUnassigned(parsed.(*ast.Program))
+	for name, want := range map[string]bool{"A": false, "B": true, "C": true, "I": false} {
+		if got[name] != want {
+			t.Errorf("MaybeUnassigned[%s] = %v, want %v", name, got[name], want)
+		}
+	}
+}
@@ -5,10 +5,9 @@ import (
 	"math"
 	"math/rand"
 	"strings"
-	"zork-basic/internal/interpreter"
 )
 
-type BuiltinFunc func(vm *VM, args []interpreter.Value) (interpreter.Value, error)
+type BuiltinFunc func(vm *VM, args []Value) (Value, error)
 
 // Use slice instead of map for O(1) access
 var builtinImpls = []BuiltinFunc{
@@ -35,87 +34,87 @@ var builtinImpls = []BuiltinFunc{
 	builtinEuler, // E (EULER)
 }
 
-func builtinAbs(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinAbs(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("ABS requires 1 argument")
+		return NumberValue(0), fmt.Errorf("ABS requires 1 argument")
 	}
-	return interpreter.NumberValue(math.Abs(args[0].AsNumber())), nil
+	return NumberValue(math.Abs(args[0].AsNumber())), nil
 }
 
-func builtinSin(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinSin(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("SIN requires 1 argument")
+		return NumberValue(0), fmt.Errorf("SIN requires 1 argument")
 	}
-	return interpreter.NumberValue(math.Sin(args[0].AsNumber())), nil
+	return NumberValue(math.Sin(args[0].AsNumber())), nil
 }
 
-func builtinCos(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinCos(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("COS requires 1 argument")
+		return NumberValue(0), fmt.Errorf("COS requires 1 argument")
 	}
-	return interpreter.NumberValue(math.Cos(args[0].AsNumber())), nil
+	return NumberValue(math.Cos(args[0].AsNumber())), nil
 }
 
-func builtinTan(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinTan(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("TAN requires 1 argument")
+		return NumberValue(0), fmt.Errorf("TAN requires 1 argument")
 	}
-	return interpreter.NumberValue(math.Tan(args[0].AsNumber())), nil
+	return NumberValue(math.Tan(args[0].AsNumber())), nil
 }
 
-func builtinInt(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinInt(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("INT requires 1 argument")
+		return NumberValue(0), fmt.Errorf("INT requires 1 argument")
 	}
-	return interpreter.NumberValue(math.Trunc(args[0].AsNumber())), nil
+	return NumberValue(math.Trunc(args[0].AsNumber())), nil
 }
 
-func builtinExp(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinExp(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("EXP requires 1 argument")
+		return NumberValue(0), fmt.Errorf("EXP requires 1 argument")
 	}
-	return interpreter.NumberValue(math.Exp(args[0].AsNumber())), nil
+	return NumberValue(math.Exp(args[0].AsNumber())), nil
 }
 
-func builtinSqr(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinSqr(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("SQR requires 1 argument")
+		return NumberValue(0), fmt.Errorf("SQR requires 1 argument")
 	}
 	val := args[0].AsNumber()
 	if val < 0 {
-		return interpreter.NumberValue(0), fmt.Errorf("SQR of negative number")
+		return NumberValue(0), fmt.Errorf("SQR of negative number")
 	}
-	return interpreter.NumberValue(math.Sqrt(val)), nil
+	return NumberValue(math.Sqrt(val)), nil
 }
 
-func builtinLog(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinLog(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("LOG requires 1 argument")
+		return NumberValue(0), fmt.Errorf("LOG requires 1 argument")
 	}
 	val := args[0].AsNumber()
 	if val <= 0 {
-		return interpreter.NumberValue(0), fmt.Errorf("LOG of non-positive number")
+		return NumberValue(0), fmt.Errorf("LOG of non-positive number")
 	}
-	return interpreter.NumberValue(math.Log(val)), nil
+	return NumberValue(math.Log(val)), nil
 }
 
-func builtinRnd(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinRnd(vm *VM, args []Value) (Value, error) {
 	if len(args) != 0 {
-		return interpreter.NumberValue(0), fmt.Errorf("RND requires 0 arguments")
+		return NumberValue(0), fmt.Errorf("RND requires 0 arguments")
 	}
-	return interpreter.NumberValue(rand.Float64()), nil
+	return NumberValue(rand.Float64()), nil
 }
 
-func builtinLen(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinLen(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("LEN requires 1 argument")
+		return NumberValue(0), fmt.Errorf("LEN requires 1 argument")
 	}
-	return interpreter.NumberValue(float64(len(args[0].String()))), nil
+	return NumberValue(float64(len(args[0].String()))), nil
 }
 
-func builtinLeft(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinLeft(vm *VM, args []Value) (Value, error) {
 	if len(args) != 2 {
-		return interpreter.NumberValue(0), fmt.Errorf("LEFT$ requires 2 arguments")
+		return NumberValue(0), fmt.Errorf("LEFT$ requires 2 arguments")
 	}
 	str := args[0].String()
 	n := int(args[1].AsNumber())
@@ -125,12 +124,12 @@ func builtinLeft(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
 	if n < 0 {
 		n = 0
 	}
-	return interpreter.StringValue(str[:n]), nil
+	return StringValue(str[:n]), nil
 }
 
-func builtinRight(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinRight(vm *VM, args []Value) (Value, error) {
 	if len(args) != 2 {
-		return interpreter.NumberValue(0), fmt.Errorf("RIGHT$ requires 2 arguments")
+		return NumberValue(0), fmt.Errorf("RIGHT$ requires 2 arguments")
 	}
 	str := args[0].String()
 	n := int(args[1].AsNumber())
@@ -140,12 +139,12 @@ func builtinRight(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
 	if n < 0 {
 		n = 0
 	}
-	return interpreter.StringValue(str[len(str)-n:]), nil
+	return StringValue(str[len(str)-n:]), nil
 }
 
-func builtinMid(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinMid(vm *VM, args []Value) (Value, error) {
 	if len(args) < 2 || len(args) > 3 {
-		return interpreter.NumberValue(0), fmt.Errorf("MID$ requires 2 or 3 arguments")
+		return NumberValue(0), fmt.Errorf("MID$ requires 2 or 3 arguments")
 	}
 	str := args[0].String()
 	start := int(args[1].AsNumber())
@@ -162,14 +161,14 @@ func builtinMid(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
 		endIdx = len(str)
 	}
 	if startIdx >= len(str) || startIdx < 0 {
-		return interpreter.StringValue(""), nil
+		return StringValue(""), nil
 	}
-	return interpreter.StringValue(str[startIdx:endIdx]), nil
+	return StringValue(str[startIdx:endIdx]), nil
 }
 
-func builtinInstr(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinInstr(vm *VM, args []Value) (Value, error) {
 	if len(args) < 2 || len(args) > 3 {
-		return interpreter.NumberValue(0), fmt.Errorf("INSTR requires 2 or 3 arguments")
+		return NumberValue(0), fmt.Errorf("INSTR requires 2 or 3 arguments")
 	}
 	var start int = 1
 	var str, substr string
@@ -185,72 +184,72 @@ func builtinInstr(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
 		start = 1
 	}
 	if start > len(str) {
-		return interpreter.NumberValue(0), nil
+		return NumberValue(0), nil
 	}
 	pos := strings.Index(str[start-1:], substr)
 	if pos == -1 {
-		return interpreter.NumberValue(0), nil
+		return NumberValue(0), nil
 	}
-	return interpreter.NumberValue(float64(start + pos)), nil
+	return NumberValue(float64(start + pos)), nil
 }
 
-func builtinUcase(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinUcase(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("UCASE$ requires 1 argument")
+		return NumberValue(0), fmt.Errorf("UCASE$ requires 1 argument")
 	}
-	return interpreter.StringValue(strings.ToUpper(args[0].String())), nil
+	return StringValue(strings.ToUpper(args[0].String())), nil
 }
 
-func builtinLcase(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinLcase(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("LCASE$ requires 1 argument")
+		return NumberValue(0), fmt.Errorf("LCASE$ requires 1 argument")
 	}
-	return interpreter.StringValue(strings.ToLower(args[0].String())), nil
+	return StringValue(strings.ToLower(args[0].String())), nil
 }
 
-func builtinSpace(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinSpace(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("SPACE$ requires 1 argument")
+		return NumberValue(0), fmt.Errorf("SPACE$ requires 1 argument")
 	}
 	n := int(args[0].AsNumber())
 	if n < 0 {
 		n = 0
 	}
-	return interpreter.StringValue(strings.Repeat(" ", n)), nil
+	return StringValue(strings.Repeat(" ", n)), nil
 }
 
-func builtinChr(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinChr(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("CHR$ requires 1 argument")
+		return NumberValue(0), fmt.Errorf("CHR$ requires 1 argument")
 	}
 	code := int(args[0].AsNumber())
 	if code < 0 || code > 255 {
-		return interpreter.NumberValue(0), fmt.Errorf("CHR$ argument must be between 0 and 255")
+		return NumberValue(0), fmt.Errorf("CHR$ argument must be between 0 and 255")
 	}
-	return interpreter.StringValue(string(rune(code))), nil
+	return StringValue(string(rune(code))), nil
 }
 
-func builtinAsc(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinAsc(vm *VM, args []Value) (Value, error) {
 	if len(args) != 1 {
-		return interpreter.NumberValue(0), fmt.Errorf("ASC requires 1 argument")
+		return NumberValue(0), fmt.Errorf("ASC requires 1 argument")
 	}
 	str := args[0].String()
 	if len(str) == 0 {
-		return interpreter.NumberValue(0), fmt.Errorf("ASC argument is an empty string")
+		return NumberValue(0), fmt.Errorf("ASC argument is an empty string")
 	}
-	return interpreter.NumberValue(float64(str[0])), nil
+	return NumberValue(float64(str[0])), nil
 }
 
-func builtinPi(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinPi(vm *VM, args []Value) (Value, error) {
 	if len(args) != 0 {
-		return interpreter.NumberValue(0), fmt.Errorf("PI requires 0 arguments")
+		return NumberValue(0), fmt.Errorf("PI requires 0 arguments")
 	}
-	return interpreter.NumberValue(math.Pi), nil
+	return NumberValue(math.Pi), nil
 }
 
-func builtinEuler(vm *VM, args []interpreter.Value) (interpreter.Value, error) {
+func builtinEuler(vm *VM, args []Value) (Value, error) {
 	if len(args) != 0 {
-		return interpreter.NumberValue(0), fmt.Errorf("EULER requires 0 arguments")
+		return NumberValue(0), fmt.Errorf("EULER requires 0 arguments")
 	}
-	return interpreter.NumberValue(math.E), nil
+	return NumberValue(math.E), nil
 }
@@ -0,0 +1,152 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/interpreter"
+)
+
+// WithChain sets how CHAIN loads the next program, usually
+// compiler.ChainLoader with the options the first program was compiled
+// with. Without a loader CHAIN is a run-time error.
+func WithChain(load func(filename string) (*bytecode.Chunk, error)) Option {
+	return func(vm *VM) { vm.chainLoader = load }
+}
+
+// constantPool converts the constant pool of a chunk to the VM's compact
+// representation
+func constantPool(c *bytecode.Chunk) []Value {
+	constants := make([]Value, len(c.Constants))
+	for i, k := range c.Constants {
+		constants[i] = fromInterpreter(k)
+	}
+	return constants
+}
+
+// emptyGlobals returns n unassigned variables
+func emptyGlobals(n int) []Value {
+	globals := make([]Value, n)
+	for i := range globals {
+		globals[i] = emptyValue
+	}
+	return globals
+}
+
+// loadChain loads and verifies the program for CHAIN file, line and finds
+// where it starts: at its first instruction, or at the first line numbered
+// line or higher when line is not negative
+func loadChain(load func(string) (*bytecode.Chunk, error), registers bool, file, line Value) (*bytecode.Chunk, int, error) {
+	if load == nil {
+		return nil, 0, fmt.Errorf("CHAIN is not available")
+	}
+	if !file.IsString() {
+		return nil, 0, fmt.Errorf("CHAIN needs a file name, got %s", file)
+	}
+	next, err := load(file.String())
+	if err != nil {
+		return nil, 0, err
+	}
+	depth, err := bytecode.Verify(next)
+	if err == nil && depth > StackSize {
+		err = fmt.Errorf("invalid bytecode: needs a stack of %d values, the VM has %d", depth, StackSize)
+	}
+	if err == nil && next.Registers != registers {
+		err = fmt.Errorf("bytecode is for the other virtual machine")
+	}
+	if err == nil {
+		err = next.Runnable()
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", file, err)
+	}
+
+	start := int(line.AsNumber())
+	if start < 0 {
+		return next, 0, nil
+	}
+	for ip, l := range next.Lines {
+		if l >= start {
+			return next, ip, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("CHAIN %s: no line %d", file, start)
+}
+
+// keepOnly removes from vars every variable and array that names (the
+// operand of OpChain) does not list
+func keepOnly(vars *interpreter.Variables, names string) {
+	if names == "*" {
+		return
+	}
+	keep := make(map[string]bool)
+	for _, name := range strings.Fields(names) {
+		keep[name] = true
+	}
+	for name := range vars.Scalars {
+		if !keep[name] {
+			delete(vars.Scalars, name)
+		}
+	}
+	for name := range vars.Arrays {
+		if !keep[name+"()"] {
+			delete(vars.Arrays, name)
+		}
+	}
+}
+
+// chain replaces the running program for OpChain. The variables that
+// survive are passed through vars by name; the GOSUB and FOR stacks start
+// empty.
+func (vm *VM) chain(names, file, line Value) error {
+	next, ip, err := loadChain(vm.chainLoader, false, file, line)
+	if err != nil {
+		return err
+	}
+	vars := vm.vars
+	if vars == nil {
+		vars = interpreter.NewVariables()
+	}
+	saveVariables(vars, vm.chunk.GlobalNames, vm.chunk.ArrayNames, vm.globals, vm.arrays)
+	keepOnly(vars, names.String())
+
+	vm.chunk = next
+	vm.constants = constantPool(next)
+	vm.globals = emptyGlobals(next.GlobalCount)
+	vm.arrays = make([]*interpreter.ArrayInfo, next.ArrayCount)
+	loadVariables(vars, next.GlobalNames, next.ArrayNames, vm.globals, vm.arrays)
+	vm.ip = ip
+	vm.sp = 0
+	vm.returnStack = vm.returnStack[:0]
+	vm.forStack = vm.forStack[:0]
+	vm.saved = saveStack{}
+	vm.fields = nil
+	return nil
+}
+
+// chain is the register machine's counterpart of VM.chain. It returns the
+// instruction pointer to continue at.
+func (vm *RegisterVM) chain(names, file, line Value) (int, error) {
+	next, ip, err := loadChain(vm.chainLoader, true, file, line)
+	if err != nil {
+		return 0, err
+	}
+	vars := vm.vars
+	if vars == nil {
+		vars = interpreter.NewVariables()
+	}
+	saveVariables(vars, vm.chunk.GlobalNames, vm.chunk.ArrayNames, vm.regs, vm.arrays)
+	keepOnly(vars, names.String())
+
+	vm.chunk = next
+	vm.constants = constantPool(next)
+	vm.regs = emptyGlobals(next.GlobalCount)
+	vm.arrays = make([]*interpreter.ArrayInfo, next.ArrayCount)
+	loadVariables(vars, next.GlobalNames, next.ArrayNames, vm.regs, vm.arrays)
+	vm.returnStack = vm.returnStack[:0]
+	vm.forStack = vm.forStack[:0]
+	vm.saved = saveStack{}
+	vm.fields = nil
+	return ip, nil
+}
@@ -0,0 +1,44 @@
+package vm
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/interpreter"
+)
+
+// errNoField reports an OpInputField with no field left to take; compiled
+// code never does this, but assembled code can
+var errNoField = errors.New("input field read without INPUT")
+
+// readInput runs OpReadInput or OpLineInput: it prompts until a line
+// matches types and returns the fields
+func readInput(in io.Reader, out io.Writer, op bytecode.OpCode, prompt, types Value) ([]Value, error) {
+	var fields []interpreter.Value
+	var err error
+	if op == bytecode.OpLineInput {
+		fields, err = interpreter.LineInput(in, out, prompt.String())
+	} else {
+		fields, err = interpreter.Input(in, out, prompt.String(), types.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, len(fields))
+	for i, f := range fields {
+		values[i] = fromInterpreter(f)
+	}
+	return values, nil
+}
+
+// legacyInput runs OpInput, which older compilers emitted for each INPUT
+// variable: the whole line, as a number if it parses
+func legacyInput(in io.Reader) Value {
+	line, _ := interpreter.ReadLine(in)
+	if num, err := strconv.ParseFloat(line, 64); err == nil {
+		return NumberValue(num)
+	}
+	return StringValue(line)
+}
@@ -0,0 +1,621 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync/atomic"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/coverage"
+	"zork-basic/internal/interpreter"
+)
+
+// RegisterVM executes chunks produced by the compiler's register backend
+// (compiler.WithRegisters). Variables and temporaries share one register
+// file; instructions name their operands directly, so there is no operand
+// stack.
+type RegisterVM struct {
+	chunk     *bytecode.Chunk
+	constants []Value
+	regs      []Value // Variables followed by temporaries
+	arrays    []*interpreter.ArrayInfo
+
+	output io.Writer
+	input  io.Reader
+
+	returnStack []int
+	forStack    []ForFrame
+	saved       saveStack // Variables and arrays saved around recursive calls
+	indexBuf    []int
+	fields      []Value // Fields of the last INPUT not yet stored
+	cover       *coverage.Profile
+	vars        *interpreter.Variables
+	interrupt   *atomic.Bool
+	ip          int // Where Run continues after a break
+	chainLoader func(string) (*bytecode.Chunk, error)
+}
+
+// NewRegister creates a register VM. It accepts the same options as New.
+func NewRegister(c *bytecode.Chunk, opts ...Option) *RegisterVM {
+	cfg := &VM{output: os.Stdout, errOutput: os.Stderr, input: os.Stdin, interrupt: new(atomic.Bool)}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return &RegisterVM{
+		chunk:       c,
+		constants:   constantPool(c),
+		regs:        emptyGlobals(c.GlobalCount),
+		arrays:      make([]*interpreter.ArrayInfo, c.ArrayCount),
+		output:      cfg.output,
+		input:       cfg.input,
+		returnStack: make([]int, 0, 16),
+		forStack:    make([]ForFrame, 0, 8),
+		cover:       cfg.cover,
+		vars:        cfg.vars,
+		interrupt:   cfg.interrupt,
+		chainLoader: cfg.chainLoader,
+	}
+}
+
+// load reads a register-or-constant operand
+func load(regs, constants []Value, operand uint32) Value {
+	if operand&bytecode.RegConstant != 0 {
+		return constants[operand&^bytecode.RegConstant]
+	}
+	return regs[operand]
+}
+
+// boolValue converts a condition to the numbers 0 and 1
+func boolValue(cond bool) Value {
+	if cond {
+		return valOne
+	}
+	return valZero
+}
+
+// Run executes the bytecode. Like VM.Run, it continues where the program
+// was interrupted when called again after an *interpreter.Break.
+func (vm *RegisterVM) Run() error {
+	if err := vm.chunk.Runnable(); err != nil {
+		return err
+	}
+	loadVariables(vm.vars, vm.chunk.GlobalNames, vm.chunk.ArrayNames, vm.regs, vm.arrays)
+	err := vm.run()
+	saveVariables(vm.vars, vm.chunk.GlobalNames, vm.chunk.ArrayNames, vm.regs, vm.arrays)
+	return err
+}
+
+func (vm *RegisterVM) run() error {
+	code := vm.chunk.Code
+	constants := vm.constants
+	regs := vm.regs
+	ip := vm.ip
+
+	// brk saves the position and reports a break before the instruction at ip
+	brk := func() error {
+		vm.ip = ip
+		return breakAt(vm.interrupt, vm.chunk, ip)
+	}
+
+	// u32 reads the 4-byte operand at ip+off
+	u32 := func(off int) uint32 {
+		return binary.BigEndian.Uint32(code[ip+off:])
+	}
+
+	for ip < len(code) {
+		op := bytecode.OpCode(code[ip])
+		ip++
+
+		switch op {
+		case bytecode.OpRMove:
+			regs[u32(0)] = load(regs, constants, u32(4))
+			ip += 8
+
+		case bytecode.OpRAdd:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			if left.IsNumber() && right.IsNumber() {
+				regs[u32(0)] = NumberValue(left.num() + right.num())
+			} else if left.IsString() || right.IsString() {
+				regs[u32(0)] = StringValue(left.String() + right.String())
+			} else {
+				regs[u32(0)] = NumberValue(left.AsNumber() + right.AsNumber())
+			}
+			ip += 12
+
+		case bytecode.OpRSub:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			regs[u32(0)] = NumberValue(left.AsNumber() - right.AsNumber())
+			ip += 12
+
+		case bytecode.OpRMul:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			regs[u32(0)] = NumberValue(left.AsNumber() * right.AsNumber())
+			ip += 12
+
+		case bytecode.OpRDiv:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			if right.AsNumber() == 0 {
+				return fmt.Errorf("division by zero")
+			}
+			regs[u32(0)] = NumberValue(left.AsNumber() / right.AsNumber())
+			ip += 12
+
+		case bytecode.OpRPow:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			regs[u32(0)] = NumberValue(math.Pow(left.AsNumber(), right.AsNumber()))
+			ip += 12
+
+		case bytecode.OpRMod:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			regs[u32(0)] = NumberValue(math.Mod(left.AsNumber(), right.AsNumber()))
+			ip += 12
+
+		case bytecode.OpRNeg:
+			val := load(regs, constants, u32(4))
+			if !val.IsNumber() {
+				return fmt.Errorf("operand must be a number")
+			}
+			regs[u32(0)] = NumberValue(-val.num())
+			ip += 8
+
+		case bytecode.OpRNot:
+			val := load(regs, constants, u32(4))
+			regs[u32(0)] = boolValue(!val.IsTrue())
+			ip += 8
+
+		case bytecode.OpRAnd:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			regs[u32(0)] = boolValue(left.IsTrue() && right.IsTrue())
+			ip += 12
+
+		case bytecode.OpROr:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			regs[u32(0)] = boolValue(left.IsTrue() || right.IsTrue())
+			ip += 12
+
+		case bytecode.OpREq, bytecode.OpRNeq, bytecode.OpRGt, bytecode.OpRGte, bytecode.OpRLt, bytecode.OpRLte:
+			left := load(regs, constants, u32(4))
+			right := load(regs, constants, u32(8))
+			regs[u32(0)] = boolValue(compare(bytecode.OpEq+(op-bytecode.OpREq), left, right))
+			ip += 12
+
+		case bytecode.OpRJumpIfFalse:
+			if !load(regs, constants, u32(0)).IsTrue() {
+				ip = int(u32(4))
+			} else {
+				ip += 8
+			}
+
+		case bytecode.OpRCmpJump:
+			cmp := bytecode.OpCode(code[ip])
+			left := load(regs, constants, u32(1))
+			right := load(regs, constants, u32(5))
+			var ok bool
+			if left.IsNumber() && right.IsNumber() {
+				l, r := left.num(), right.num()
+				switch cmp {
+				case bytecode.OpEq:
+					ok = l == r
+				case bytecode.OpNeq:
+					ok = l != r
+				case bytecode.OpGt:
+					ok = l > r
+				case bytecode.OpGte:
+					ok = l >= r
+				case bytecode.OpLt:
+					ok = l < r
+				case bytecode.OpLte:
+					ok = l <= r
+				}
+			} else {
+				ok = compare(cmp, left, right)
+			}
+			if !ok {
+				ip = int(u32(9))
+			} else {
+				ip += 13
+			}
+
+		case bytecode.OpJump:
+			ip = int(u32(0))
+			if vm.interrupt.Load() {
+				return brk()
+			}
+
+		case bytecode.OpGosub:
+			vm.returnStack = append(vm.returnStack, ip+4)
+			ip = int(u32(0))
+			if vm.interrupt.Load() {
+				return brk()
+			}
+
+		case bytecode.OpReturn:
+			if len(vm.returnStack) == 0 {
+				return fmt.Errorf("return without gosub")
+			}
+			ip = vm.returnStack[len(vm.returnStack)-1]
+			vm.returnStack = vm.returnStack[:len(vm.returnStack)-1]
+			if vm.interrupt.Load() {
+				return brk()
+			}
+
+		case bytecode.OpEnd:
+			return nil
+
+		case bytecode.OpStop:
+			vm.ip = ip
+			return lineBreak(vm.chunk, vm.chunk.Lines[ip-1])
+
+		case bytecode.OpRChain:
+			file := load(regs, constants, u32(0))
+			line := load(regs, constants, u32(4))
+			next, err := vm.chain(constants[u32(8)], file, line)
+			if err != nil {
+				return err
+			}
+			code = vm.chunk.Code
+			constants = vm.constants
+			regs = vm.regs
+			ip = next
+
+		case bytecode.OpRForInit:
+			varIdx := int(u32(0))
+			endVal := load(regs, constants, u32(4)).AsNumber()
+			stepVal := load(regs, constants, u32(8)).AsNumber()
+			ip += 12
+			vm.forStack = append(vm.forStack, ForFrame{
+				varIdx:    varIdx,
+				endValue:  endVal,
+				stepValue: stepVal,
+				loopTop:   ip,
+			})
+
+		case bytecode.OpNext:
+			varIdx := int(u32(0))
+			loopTop := int(u32(4))
+			ip += 8
+
+			if len(vm.forStack) == 0 {
+				return fmt.Errorf("NEXT without FOR")
+			}
+			frame := &vm.forStack[len(vm.forStack)-1]
+			if frame.varIdx != varIdx {
+				return fmt.Errorf("NEXT variable mismatch")
+			}
+
+			newVal := regs[varIdx].AsNumber() + frame.stepValue
+			regs[varIdx] = NumberValue(newVal)
+			if (frame.stepValue > 0 && newVal <= frame.endValue) || (frame.stepValue < 0 && newVal >= frame.endValue) {
+				ip = loopTop
+				if vm.interrupt.Load() {
+					return brk()
+				}
+			} else {
+				vm.forStack = vm.forStack[:len(vm.forStack)-1]
+			}
+
+		case bytecode.OpRGetArray:
+			dst := u32(0)
+			arr, flatIdx, err := vm.element(int(u32(4)), int(u32(8)), int(code[ip+12]))
+			if err != nil {
+				return err
+			}
+			regs[dst] = getElement(arr, flatIdx)
+			ip += 13
+
+		case bytecode.OpRSetArray:
+			arr, flatIdx, err := vm.element(int(u32(0)), int(u32(4)), int(code[ip+8]))
+			if err != nil {
+				return err
+			}
+			setElement(arr, flatIdx, load(regs, constants, u32(9)))
+			ip += 13
+
+		case bytecode.OpRDim:
+			arrIdx := int(u32(0))
+			first := int(u32(4))
+			dims := make([]int, code[ip+8])
+			ip += 9
+			for i := range dims {
+				dim := int(regs[first+i].AsNumber())
+				if dim < 0 {
+					return fmt.Errorf("negative array dimension: %d", dim)
+				}
+				dims[i] = dim
+			}
+			if arrIdx >= len(vm.arrays) {
+				return fmt.Errorf("array index out of bounds: %d", arrIdx)
+			}
+			vm.arrays[arrIdx] = newArray(vm.chunk, arrIdx, dims)
+
+		case bytecode.OpDimMap:
+			idx := int(u32(0))
+			ip += 4
+			if idx >= len(vm.arrays) {
+				return fmt.Errorf("array index out of bounds: %d", idx)
+			}
+			vm.arrays[idx] = newMap(vm.chunk, idx)
+
+		case bytecode.OpSaveGlobal:
+			vm.saved.values = append(vm.saved.values, regs[u32(0)])
+			ip += 4
+
+		case bytecode.OpRestoreGlobal:
+			v, err := vm.saved.popValue()
+			if err != nil {
+				return err
+			}
+			regs[u32(0)] = v
+			ip += 4
+
+		case bytecode.OpSaveArray:
+			idx := int(u32(0))
+			ip += 4
+			if idx >= len(vm.arrays) {
+				return fmt.Errorf("array index out of bounds: %d", idx)
+			}
+			vm.saved.arrays = append(vm.saved.arrays, vm.arrays[idx])
+
+		case bytecode.OpRestoreArray:
+			idx := int(u32(0))
+			ip += 4
+			if idx >= len(vm.arrays) {
+				return fmt.Errorf("array index out of bounds: %d", idx)
+			}
+			arr, err := vm.saved.popArray()
+			if err != nil {
+				return err
+			}
+			vm.arrays[idx] = arr
+
+		case bytecode.OpRDimRecord:
+			arrIdx := int(u32(0))
+			first := int(u32(4))
+			dims := make([]int, code[ip+8])
+			shape := constants[u32(9)]
+			ip += 13
+			for i := range dims {
+				dim := int(regs[first+i].AsNumber())
+				if dim < 0 {
+					return fmt.Errorf("negative array dimension: %d", dim)
+				}
+				dims[i] = dim
+			}
+			arr, err := newRecord(dims, shape)
+			if err != nil {
+				return err
+			}
+			vm.arrays[arrIdx] = arr
+
+		case bytecode.OpRGetField:
+			dst := u32(0)
+			arr, flatIdx, err := vm.record(code[ip+4:])
+			if err != nil {
+				return err
+			}
+			regs[dst] = getElement(arr, flatIdx)
+			ip += 15
+
+		case bytecode.OpRSetField:
+			arr, flatIdx, err := vm.record(code[ip:])
+			if err != nil {
+				return err
+			}
+			setElement(arr, flatIdx, load(regs, constants, u32(11)))
+			ip += 15
+
+		case bytecode.OpRCopyRecord:
+			dst, to, err := vm.record(code[ip:])
+			if err != nil {
+				return err
+			}
+			src, from, err := vm.record(code[ip+11:])
+			if err != nil {
+				return err
+			}
+			n := int(binary.BigEndian.Uint16(code[ip+22:]))
+			ip += 24
+			if err := copyRecord(dst, to, src, from, n); err != nil {
+				return err
+			}
+
+		case bytecode.OpForEach:
+			m, err := mapAt(vm.arrays, int(u32(0)))
+			if err != nil {
+				return err
+			}
+			varIdx, exit := int(u32(4)), int(u32(8))
+			ip += 12
+			if keys := m.Keys(); len(keys) > 0 {
+				regs[varIdx] = StringValue(keys[0])
+				vm.forStack = append(vm.forStack, ForFrame{varIdx: varIdx, loopTop: ip, keys: keys})
+			} else {
+				ip = exit
+			}
+
+		case bytecode.OpNextEach:
+			varIdx, loopTop := int(u32(0)), int(u32(4))
+			ip += 8
+			key, more, err := nextKey(vm.forStack, varIdx)
+			if err != nil {
+				return err
+			}
+			if more {
+				regs[varIdx] = StringValue(key)
+				ip = loopTop
+				if vm.interrupt.Load() {
+					return brk()
+				}
+			} else {
+				vm.forStack = vm.forStack[:len(vm.forStack)-1]
+			}
+
+		case bytecode.OpRMapGet, bytecode.OpRMapHas, bytecode.OpRMapKey:
+			dst := u32(0)
+			m, err := mapAt(vm.arrays, int(u32(4)))
+			if err != nil {
+				return err
+			}
+			arg := load(regs, constants, u32(8))
+			ip += 12
+			switch op {
+			case bytecode.OpRMapGet:
+				regs[dst], err = mapGet(m, arg)
+			case bytecode.OpRMapHas:
+				regs[dst], err = mapHas(m, arg)
+			default:
+				regs[dst], err = mapKeyAt(m, arg)
+			}
+			if err != nil {
+				return err
+			}
+
+		case bytecode.OpRMapSet:
+			m, err := mapAt(vm.arrays, int(u32(0)))
+			if err != nil {
+				return err
+			}
+			key, err := mapKey(load(regs, constants, u32(4)))
+			if err != nil {
+				return err
+			}
+			mapSet(m, key, load(regs, constants, u32(8)))
+			ip += 12
+
+		case bytecode.OpRMapDelete:
+			m, err := mapAt(vm.arrays, int(u32(0)))
+			if err != nil {
+				return err
+			}
+			key, err := mapKey(load(regs, constants, u32(4)))
+			if err != nil {
+				return err
+			}
+			m.Delete(key)
+			ip += 8
+
+		case bytecode.OpRMapCount:
+			m, err := mapAt(vm.arrays, int(u32(4)))
+			if err != nil {
+				return err
+			}
+			regs[u32(0)] = NumberValue(float64(m.Len()))
+			ip += 8
+
+		case bytecode.OpRCall:
+			dst := u32(0)
+			builtinIdx := int(binary.BigEndian.Uint16(code[ip+4:]))
+			first := int(u32(6))
+			argCount := int(code[ip+10])
+			ip += 11
+			if builtinIdx >= len(builtinImpls) {
+				return fmt.Errorf("unknown builtin function index: %d", builtinIdx)
+			}
+			// Builtins only look at their arguments
+			res, err := builtinImpls[builtinIdx](nil, regs[first:first+argCount])
+			if err != nil {
+				return err
+			}
+			regs[dst] = res
+
+		case bytecode.OpRPrint:
+			fmt.Fprint(vm.output, load(regs, constants, u32(0)).String())
+			ip += 4
+
+		case bytecode.OpPrintNl:
+			fmt.Fprintln(vm.output)
+
+		case bytecode.OpInput:
+			nameIdx := int(u32(0))
+			ip += 4
+			if nameIdx >= len(regs) {
+				return fmt.Errorf("global index out of bounds: %d", nameIdx)
+			}
+			regs[nameIdx] = legacyInput(vm.input)
+
+		case bytecode.OpReadInput:
+			fields, err := readInput(vm.input, vm.output, op, constants[u32(0)], constants[u32(4)])
+			if err != nil {
+				return err
+			}
+			vm.fields = fields
+			ip += 8
+
+		case bytecode.OpLineInput:
+			fields, err := readInput(vm.input, vm.output, op, constants[u32(0)], Value{})
+			if err != nil {
+				return err
+			}
+			vm.fields = fields
+			ip += 4
+
+		case bytecode.OpRInputField:
+			if len(vm.fields) == 0 {
+				return errNoField
+			}
+			regs[u32(0)] = vm.fields[0]
+			vm.fields = vm.fields[1:]
+			ip += 4
+
+		case bytecode.OpCover:
+			counter := int(u32(0))
+			ip += 4
+			if vm.cover != nil && counter < len(vm.cover.Counts) {
+				vm.cover.Hit(counter)
+			}
+
+		default:
+			return fmt.Errorf("unknown opcode %d", op)
+		}
+	}
+	return nil
+}
+
+// record locates a record slot from the operands at the start of operands:
+// the array, the first index register, the index count and the slot
+func (vm *RegisterVM) record(operands []byte) (*interpreter.ArrayInfo, int, error) {
+	count := int(operands[8])
+	if cap(vm.indexBuf) < count+1 {
+		vm.indexBuf = make([]int, count+1)
+	}
+	indices := vm.indexBuf[:count+1]
+	first := int(binary.BigEndian.Uint32(operands[4:]))
+	for i := range count {
+		indices[i] = int(vm.regs[first+i].AsNumber())
+	}
+	indices[count] = int(binary.BigEndian.Uint16(operands[9:]))
+	return recordAt(vm.arrays, int(binary.BigEndian.Uint32(operands)), indices)
+}
+
+// element locates an array element whose indices are held in count
+// consecutive registers starting at first
+func (vm *RegisterVM) element(arrIdx, first, count int) (*interpreter.ArrayInfo, int, error) {
+	arr := vm.arrays[arrIdx]
+	if arr == nil {
+		return nil, 0, fmt.Errorf("array not declared (index %d)", arrIdx)
+	}
+	if cap(vm.indexBuf) < count {
+		vm.indexBuf = make([]int, count)
+	}
+	indices := vm.indexBuf[:count]
+	for i := range indices {
+		indices[i] = int(vm.regs[first+i].AsNumber())
+	}
+	flatIdx := arr.CalculateIndex(indices)
+	if flatIdx < 0 {
+		return nil, 0, fmt.Errorf("array index out of bounds")
+	}
+	return arr, flatIdx, nil
+}
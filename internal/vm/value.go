@@ -0,0 +1,127 @@
+package vm
+
+import (
+	"math"
+	"strconv"
+	"unsafe"
+
+	"zork-basic/internal/interpreter"
+)
+
+// NaN-boxing layout. Numbers are stored as their raw float64 bits; the other
+// kinds use signalling-NaN bit patterns, which floating-point arithmetic
+// never produces (operations on NaN always yield a quiet NaN).
+//
+//	number  any float64 outside the tag range
+//	empty   0x7FF4_0000_0000_0000
+//	string  0x7FF5_LLLL_LLLL_LLLL  (L = 48-bit length, bytes at ptr)
+const (
+	tagMask    uint64 = 0xFFFF_0000_0000_0000
+	lengthMask uint64 = 0x0000_FFFF_FFFF_FFFF
+	tagEmpty   uint64 = 0x7FF4_0000_0000_0000 // Unassigned variable
+	tagString  uint64 = 0x7FF5_0000_0000_0000 // String, length in the low 48 bits
+	tagRange   uint64 = 0x0002_0000_0000_0000 // bits-tagEmpty < tagRange for every tagged value
+	quietNaN   uint64 = 0x7FF8_0000_0000_0001 // math.NaN(), which NumberValue stores in place of tag-like NaNs
+)
+
+// Value is the VM's compact runtime value (16 bytes, versus 32 for
+// interpreter.Value). The kind and, for strings, the length live in the
+// NaN space of bits; ptr points at the string bytes so the garbage collector
+// still sees them, and building a Value from a string never allocates.
+type Value struct {
+	bits uint64
+	ptr  unsafe.Pointer // String data (only for strings)
+}
+
+// emptyValue is the value of a variable that has never been assigned
+var emptyValue = Value{bits: tagEmpty}
+
+// NumberValue creates a numeric Value. Arithmetic only ever produces quiet
+// NaNs, but a signalling NaN from elsewhere (a constant in a .zbc file, a
+// caller of this function) could carry a tag's bit pattern, so NaNs in the
+// tag range are replaced by the quiet NaN; all other bits are kept as is.
+func NumberValue(v float64) Value {
+	bits := math.Float64bits(v)
+	if bits-tagEmpty < tagRange {
+		bits = quietNaN
+	}
+	return Value{bits: bits}
+}
+
+// StringValue creates a string Value
+func StringValue(s string) Value {
+	return Value{bits: tagString | uint64(len(s)), ptr: unsafe.Pointer(unsafe.StringData(s))}
+}
+
+// fromInterpreter converts a constant from the chunk's pool
+func fromInterpreter(v interpreter.Value) Value {
+	switch {
+	case v.IsNumber():
+		return NumberValue(v.AsNumber())
+	case v.IsString():
+		return StringValue(v.String())
+	}
+	return emptyValue
+}
+
+// IsNumber reports whether v holds a number
+func (v Value) IsNumber() bool {
+	return v.bits-tagEmpty >= tagRange
+}
+
+// IsString reports whether v holds a string
+func (v Value) IsString() bool {
+	return v.bits&tagMask == tagString
+}
+
+// num returns the number payload; v must be a number
+func (v Value) num() float64 {
+	return math.Float64frombits(v.bits)
+}
+
+// str returns the string payload; v must be a string
+func (v Value) str() string {
+	return unsafe.String((*byte)(v.ptr), int(v.bits&lengthMask))
+}
+
+// AsNumber returns v as a number, parsing strings and treating an
+// unassigned value as 0
+func (v Value) AsNumber() float64 {
+	if v.IsNumber() {
+		return math.Float64frombits(v.bits)
+	}
+	return v.convertToNumber()
+}
+
+// convertToNumber is the out-of-line slow path of AsNumber, kept separate
+// so AsNumber stays small enough to inline
+func (v Value) convertToNumber() float64 {
+	if v.IsString() {
+		f, _ := strconv.ParseFloat(v.str(), 64)
+		return f
+	}
+	return 0
+}
+
+// String returns v formatted the same way as interpreter.Value
+func (v Value) String() string {
+	if v.IsNumber() {
+		return strconv.FormatFloat(math.Float64frombits(v.bits), 'g', -1, 64)
+	}
+	if v.IsString() {
+		return v.str()
+	}
+	return ""
+}
+
+// IsTrue reports whether v counts as true: non-zero numbers and
+// non-empty strings
+func (v Value) IsTrue() bool {
+	if v.IsNumber() {
+		return math.Float64frombits(v.bits) != 0
+	}
+	if v.IsString() {
+		return v.bits&lengthMask != 0
+	}
+	return false
+}
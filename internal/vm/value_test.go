@@ -0,0 +1,99 @@
+package vm_test
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/vm"
+)
+
+func TestNumberValue(t *testing.T) {
+	tests := []struct {
+		name string
+		bits uint64
+		want uint64 // bits read back through AsNumber
+	}{
+		{"zero", 0, 0},
+		{"negative zero", 0x8000_0000_0000_0000, 0x8000_0000_0000_0000},
+		{"one and a half", math.Float64bits(1.5), math.Float64bits(1.5)},
+		{"largest", math.Float64bits(math.MaxFloat64), math.Float64bits(math.MaxFloat64)},
+		{"smallest denormal", 1, 1},
+		{"+Inf", math.Float64bits(math.Inf(1)), math.Float64bits(math.Inf(1))},
+		{"-Inf", math.Float64bits(math.Inf(-1)), math.Float64bits(math.Inf(-1))},
+		{"quiet NaN", math.Float64bits(math.NaN()), math.Float64bits(math.NaN())},
+		{"negative quiet NaN", 0xFFF8_0000_0000_0000, 0xFFF8_0000_0000_0000},
+		// Signalling NaNs with a tag's bit pattern must not read back as a string or as unassigned
+		{"empty tag", 0x7FF4_0000_0000_0000, math.Float64bits(math.NaN())},
+		{"string tag", 0x7FF5_0000_0000_0003, math.Float64bits(math.NaN())},
+		{"top of tag range", 0x7FF5_FFFF_FFFF_FFFF, math.Float64bits(math.NaN())},
+		{"below tag range", 0x7FF3_FFFF_FFFF_FFFF, 0x7FF3_FFFF_FFFF_FFFF},
+		{"above tag range", 0x7FF6_0000_0000_0000, 0x7FF6_0000_0000_0000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := vm.NumberValue(math.Float64frombits(tt.bits))
+			if !v.IsNumber() || v.IsString() {
+				t.Fatalf("IsNumber = %v, IsString = %v, want a number", v.IsNumber(), v.IsString())
+			}
+			if got := math.Float64bits(v.AsNumber()); got != tt.want {
+				t.Errorf("AsNumber bits = %#x, want %#x", got, tt.want)
+			}
+			f := math.Float64frombits(tt.want)
+			if got, want := v.String(), strconv.FormatFloat(f, 'g', -1, 64); got != want {
+				t.Errorf("String() = %q, want %q", got, want)
+			}
+			if got, want := v.IsTrue(), f != 0; got != want {
+				t.Errorf("IsTrue() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStringValue(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		num  float64
+	}{
+		{"empty", "", 0},
+		{"one byte", "x", 0},
+		{"number", "12.5", 12.5},
+		{"NUL bytes", "a\x00b\x00", 0},
+		{"non-ASCII", "héllo, 世界 🎉", 0},
+		{"invalid UTF-8", "\xff\xfe", 0},
+		{"long", strings.Repeat("abc", 1<<16) + "d", 0},
+		{"looks like NaN", "NaN", math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := vm.StringValue(tt.s)
+			if !v.IsString() || v.IsNumber() {
+				t.Fatalf("IsNumber = %v, IsString = %v, want a string", v.IsNumber(), v.IsString())
+			}
+			if got := v.String(); got != tt.s {
+				t.Errorf("String() has %d bytes, want %d (%.20q)", len(got), len(tt.s), got)
+			}
+			if got := v.AsNumber(); got != tt.num && !(got != got && tt.num != tt.num) {
+				t.Errorf("AsNumber() = %v, want %v", got, tt.num)
+			}
+			if got := v.IsTrue(); got != (tt.s != "") {
+				t.Errorf("IsTrue() = %v, want %v", got, tt.s != "")
+			}
+		})
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	var v vm.Value
+	if !v.IsNumber() || v.IsString() {
+		t.Fatalf("IsNumber = %v, IsString = %v, want the number 0", v.IsNumber(), v.IsString())
+	}
+	if v.AsNumber() != 0 || v.String() != "0" || v.IsTrue() {
+		t.Errorf("zero Value = %v (%q, true: %v), want 0", v.AsNumber(), v.String(), v.IsTrue())
+	}
+	if v != vm.NumberValue(0) {
+		t.Errorf("zero Value differs from NumberValue(0)")
+	}
+}
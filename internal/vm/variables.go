@@ -0,0 +1,258 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/interpreter"
+)
+
+// WithVariables makes the VM share variables and arrays with vars by name.
+// Each Run loads the chunk's named globals and arrays from vars and stores
+// them back when it returns, so a later chunk (or the AST interpreter)
+// sees the values this one left.
+func WithVariables(vars *interpreter.Variables) Option {
+	return func(vm *VM) { vm.vars = vars }
+}
+
+// WithInterrupt makes Run check flag at every jump and GOSUB/RETURN. When
+// it is set, Run clears it and returns an *interpreter.Break; calling Run
+// again continues from the same instruction. STOP returns a break whether
+// or not the VM has an interrupt flag.
+func WithInterrupt(flag *atomic.Bool) Option {
+	return func(vm *VM) { vm.interrupt = flag }
+}
+
+// toInterpreter converts a value back to the interpreter's representation
+func toInterpreter(v Value) interpreter.Value {
+	switch {
+	case v.IsNumber():
+		return interpreter.NumberValue(v.num())
+	case v.IsString():
+		return interpreter.StringValue(v.str())
+	}
+	return interpreter.Value{}
+}
+
+// loadVariables copies the named globals and arrays of the chunk from vars
+func loadVariables(vars *interpreter.Variables, names, arrayNames []string, globals []Value, arrays []*interpreter.ArrayInfo) {
+	if vars == nil {
+		return
+	}
+	for i, name := range names {
+		if v, ok := vars.Scalars[name]; ok && i < len(globals) {
+			globals[i] = fromInterpreter(v)
+		}
+	}
+	for i, name := range arrayNames {
+		if arr, ok := vars.Arrays[name]; ok && i < len(arrays) {
+			arrays[i] = arr
+		}
+	}
+}
+
+// saveVariables stores the named globals and arrays of the chunk into vars.
+// Unassigned variables are left out.
+func saveVariables(vars *interpreter.Variables, names, arrayNames []string, globals []Value, arrays []*interpreter.ArrayInfo) {
+	if vars == nil {
+		return
+	}
+	for i, name := range names {
+		if i >= len(globals) || name == "" {
+			continue
+		}
+		if !globals[i].IsNumber() && !globals[i].IsString() {
+			delete(vars.Scalars, name)
+		} else {
+			vars.Scalars[name] = toInterpreter(globals[i])
+		}
+	}
+	for i, name := range arrayNames {
+		if i < len(arrays) && name != "" && arrays[i] != nil {
+			vars.Arrays[name] = arrays[i]
+		}
+	}
+}
+
+// saveStack holds what recursive SUB and FUNCTION calls save with
+// OpSaveGlobal and OpSaveArray until OpRestoreGlobal and OpRestoreArray
+// pop it back
+type saveStack struct {
+	values []Value
+	arrays []*interpreter.ArrayInfo
+}
+
+func (s *saveStack) popValue() (Value, error) {
+	if len(s.values) == 0 {
+		return Value{}, fmt.Errorf("restore without save")
+	}
+	v := s.values[len(s.values)-1]
+	s.values = s.values[:len(s.values)-1]
+	return v, nil
+}
+
+func (s *saveStack) popArray() (*interpreter.ArrayInfo, error) {
+	if len(s.arrays) == 0 {
+		return nil, fmt.Errorf("restore without save")
+	}
+	arr := s.arrays[len(s.arrays)-1]
+	s.arrays = s.arrays[:len(s.arrays)-1]
+	return arr, nil
+}
+
+// breakAt clears the interrupt flag and reports a break before the
+// instruction at ip
+func breakAt(flag *atomic.Bool, c *bytecode.Chunk, ip int) error {
+	flag.Store(false)
+	line := 0
+	if ip < len(c.Lines) {
+		line = c.Lines[ip]
+	}
+	return lineBreak(c, line)
+}
+
+// lineBreak reports a break in line, with its source position when the
+// program was written without line numbers
+func lineBreak(c *bytecode.Chunk, line int) *interpreter.Break {
+	return &interpreter.Break{Line: line, Pos: c.Positions[line]}
+}
+
+// newArray creates array idx of a DIM in c; arrays whose names end in $
+// hold strings, the rest numbers
+func newArray(c *bytecode.Chunk, idx int, dims []int) *interpreter.ArrayInfo {
+	if idx < len(c.ArrayNames) && strings.HasSuffix(c.ArrayNames[idx], "$") {
+		return interpreter.NewStringArrayInfo(dims)
+	}
+	return interpreter.NewArrayInfo(dims)
+}
+
+// getElement returns element i of arr
+func getElement(arr *interpreter.ArrayInfo, i int) Value {
+	if arr.IsString(i) {
+		return StringValue(arr.Strs[i])
+	}
+	return NumberValue(arr.Data[i])
+}
+
+// setElement stores v in element i of arr, converting it to the array's type
+func setElement(arr *interpreter.ArrayInfo, i int, v Value) {
+	if arr.IsString(i) {
+		arr.Strs[i] = v.String()
+	} else {
+		arr.Data[i] = v.AsNumber()
+	}
+}
+
+// newRecord creates a record variable or record array of OpDimRecord;
+// shape holds '#' or '$' for each slot of a record
+func newRecord(dims []int, shape Value) (*interpreter.ArrayInfo, error) {
+	if !shape.IsString() || shape.str() == "" {
+		return nil, fmt.Errorf("invalid record shape %s", shape.String())
+	}
+	return interpreter.NewRecordInfo(dims, shape.str()), nil
+}
+
+// recordAt locates a slot of record array idx; indices are the record's
+// indices followed by the slot
+func recordAt(arrays []*interpreter.ArrayInfo, idx int, indices []int) (*interpreter.ArrayInfo, int, error) {
+	arr := arrays[idx]
+	if arr == nil || arr.Shape == "" {
+		return nil, 0, fmt.Errorf("array not declared (index %d)", idx)
+	}
+	flatIdx := arr.CalculateIndex(indices)
+	if flatIdx < 0 {
+		return nil, 0, fmt.Errorf("array index out of bounds")
+	}
+	return arr, flatIdx, nil
+}
+
+// copyRecord copies n slots of src starting at from to dst starting at to
+// (LET A = B for records); both ranges must lie within one record
+func copyRecord(dst *interpreter.ArrayInfo, to int, src *interpreter.ArrayInfo, from, n int) error {
+	if to%len(dst.Shape)+n > len(dst.Shape) || from%len(src.Shape)+n > len(src.Shape) {
+		return fmt.Errorf("record copy out of bounds")
+	}
+	dst.CopySlots(to, src, from, n)
+	return nil
+}
+
+// newMap creates map idx of a DIM ... AS MAP in c; maps whose names end in
+// $ hold strings, the rest numbers
+func newMap(c *bytecode.Chunk, idx int) *interpreter.ArrayInfo {
+	return interpreter.NewMap(idx < len(c.ArrayNames) && strings.HasSuffix(c.ArrayNames[idx], "$"))
+}
+
+// mapAt returns the map in array slot idx
+func mapAt(arrays []*interpreter.ArrayInfo, idx int) (*interpreter.MapInfo, error) {
+	if idx >= len(arrays) || arrays[idx] == nil || arrays[idx].Map == nil {
+		return nil, fmt.Errorf("map not declared (index %d)", idx)
+	}
+	return arrays[idx].Map, nil
+}
+
+// mapKey returns the string held by a map key
+func mapKey(v Value) (string, error) {
+	if !v.IsString() {
+		return "", fmt.Errorf("map key must be a string, got %s", v.String())
+	}
+	return v.str(), nil
+}
+
+// mapGet returns the value of a key in m: 0 or "" when it is missing
+func mapGet(m *interpreter.MapInfo, k Value) (Value, error) {
+	key, err := mapKey(k)
+	if err != nil {
+		return Value{}, err
+	}
+	if m.Strs != nil {
+		return StringValue(m.Strs[key]), nil
+	}
+	return NumberValue(m.Nums[key]), nil
+}
+
+// mapHas returns 1 if m has a key, else 0 (HASKEY)
+func mapHas(m *interpreter.MapInfo, k Value) (Value, error) {
+	key, err := mapKey(k)
+	if err != nil {
+		return Value{}, err
+	}
+	return boolValue(m.Has(key)), nil
+}
+
+// mapSet stores v under key in m, converting it to the map's type
+func mapSet(m *interpreter.MapInfo, key string, v Value) {
+	if m.Strs != nil {
+		m.SetString(key, v.String())
+	} else {
+		m.SetNumber(key, v.AsNumber())
+	}
+}
+
+// mapKeyAt returns the key at position v of m (KEYS$)
+func mapKeyAt(m *interpreter.MapInfo, v Value) (Value, error) {
+	idx := int(v.AsNumber())
+	key, ok := m.Key(idx)
+	if !ok {
+		return Value{}, fmt.Errorf("KEYS$ index %d out of range (%d keys)", idx, m.Len())
+	}
+	return StringValue(key), nil
+}
+
+// nextKey advances the FOR EACH loop on top of forStack and returns the key
+// its variable takes next, or more false when every key has been visited
+func nextKey(forStack []ForFrame, varIdx int) (key string, more bool, err error) {
+	if len(forStack) == 0 {
+		return "", false, fmt.Errorf("NEXT without FOR")
+	}
+	frame := &forStack[len(forStack)-1]
+	if frame.keys == nil || frame.varIdx != varIdx {
+		return "", false, fmt.Errorf("NEXT variable mismatch")
+	}
+	frame.next++
+	if frame.next == len(frame.keys) {
+		return "", false, nil
+	}
+	return frame.keys[frame.next], true, nil
+}
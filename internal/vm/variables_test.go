@@ -0,0 +1,116 @@
+package vm_test
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/bytecode"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/interpreter"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vm"
+)
+
+func compile(t *testing.T, src string, opts ...compiler.Option) *bytecode.Chunk {
+	t.Helper()
+	parsed, err := parser.Parse("test.bas", []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	chunk, err := compiler.New(opts...).Compile(parsed.(*ast.Program))
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	return chunk
+}
+
+// machines runs the same checks on both VMs
+var machines = []struct {
+	name string
+	opts []compiler.Option
+	new  func(*bytecode.Chunk, ...vm.Option) interface{ Run() error }
+}{
+	{"stack", nil, func(c *bytecode.Chunk, opts ...vm.Option) interface{ Run() error } { return vm.New(c, opts...) }},
+	{"register", []compiler.Option{compiler.WithRegisters()}, func(c *bytecode.Chunk, opts ...vm.Option) interface{ Run() error } {
+		return vm.NewRegister(c, opts...)
+	}},
+}
+
+func TestVariablesShared(t *testing.T) {
+	for _, m := range machines {
+		t.Run(m.name, func(t *testing.T) {
+			vars := interpreter.NewVariables()
+			vars.Scalars["C"] = interpreter.NumberValue(2)
+			first := compile(t, "10 A = 5 * C: S$ = \"x\": DIM B(3): B(1) = 7\n", m.opts...)
+			if err := m.new(first, vm.WithVariables(vars)).Run(); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			second := compile(t, "10 PRINT A; S$; B(1); C\n", m.opts...)
+			if err := m.new(second, vm.WithVariables(vars), vm.WithOutput(&out)).Run(); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != "10x72\n" {
+				t.Errorf("output = %q, want %q", got, "10x72\n")
+			}
+		})
+	}
+}
+
+func TestBreakAndContinue(t *testing.T) {
+	for _, m := range machines {
+		t.Run(m.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var flag atomic.Bool
+			vars := interpreter.NewVariables()
+			chunk := compile(t, "10 I = I + 1\n20 IF I < 50 THEN GOTO 10\n30 FOR J = 1 TO 3: PRINT J;: NEXT J\n40 PRINT I\n", m.opts...)
+			machine := m.new(chunk, vm.WithOutput(&out), vm.WithInterrupt(&flag), vm.WithVariables(vars))
+
+			flag.Store(true)
+			var brk *interpreter.Break
+			if err := machine.Run(); !errors.As(err, &brk) || brk.Line != 10 {
+				t.Fatalf("Run() = %v, want a break in line 10", err)
+			}
+			if flag.Load() {
+				t.Error("interrupt flag not cleared")
+			}
+			// Variables are visible while the program is stopped and
+			// changes to them are picked up when it continues
+			if got := vars.Scalars["I"].AsNumber(); got != 1 {
+				t.Errorf("I = %v after the break, want 1", got)
+			}
+			vars.Scalars["I"] = interpreter.NumberValue(40)
+			if err := machine.Run(); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != "12350\n" {
+				t.Errorf("output = %q, want %q", got, "12350\n")
+			}
+		})
+	}
+}
+
+func TestStop(t *testing.T) {
+	for _, m := range machines {
+		t.Run(m.name, func(t *testing.T) {
+			var out bytes.Buffer
+			chunk := compile(t, "10 PRINT 1;: STOP: PRINT 2;\n20 PRINT 3\n", m.opts...)
+			machine := m.new(chunk, vm.WithOutput(&out))
+
+			var brk *interpreter.Break
+			if err := machine.Run(); !errors.As(err, &brk) || brk.Line != 10 {
+				t.Fatalf("Run() = %v, want a break in line 10", err)
+			}
+			// Continuing resumes with the statement after STOP
+			if err := machine.Run(); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != "123\n" {
+				t.Errorf("output = %q, want %q", got, "123\n")
+			}
+		})
+	}
+}
@@ -6,9 +6,10 @@ import (
 	"io"
 	"math"
 	"os"
-	"strconv"
+	"sync/atomic"
 
 	"zork-basic/internal/bytecode"
+	"zork-basic/internal/coverage"
 	"zork-basic/internal/interpreter"
 )
 
@@ -18,8 +19,8 @@ const (
 
 // Pre-cached boolean values to avoid allocation in hot path
 var (
-	valZero = interpreter.NumberValue(0)
-	valOne  = interpreter.NumberValue(1)
+	valZero = NumberValue(0)
+	valOne  = NumberValue(1)
 )
 
 // ForFrame stores the state of a FOR loop
@@ -27,17 +28,21 @@ type ForFrame struct {
 	varIdx    int     // Index of loop variable in globals
 	endValue  float64 // Loop end value
 	stepValue float64 // Loop step value
-	loopTop   int     // Bytecode offset to jump back to (after OpForInit)
+	loopTop   int     // Bytecode offset to jump back to (after OpForInit or OpForEach)
+
+	keys []string // Keys a FOR EACH loop visits, taken when it starts; nil for FOR
+	next int      // Index in keys of the key the loop variable holds
 }
 
 // VM is the virtual machine
 type VM struct {
-	chunk   *bytecode.Chunk
-	ip      int // Instruction pointer
-	stack   []interpreter.Value
-	sp      int // Stack pointer
-	globals []interpreter.Value
-	arrays  []*interpreter.ArrayInfo
+	chunk     *bytecode.Chunk
+	constants []Value // chunk.Constants converted to Value
+	ip        int     // Instruction pointer
+	stack     []Value
+	sp        int // Stack pointer
+	globals   []Value
+	arrays    []*interpreter.ArrayInfo
 
 	// I/O
 	output    io.Writer
@@ -50,8 +55,22 @@ type VM struct {
 	// FOR loop stack
 	forStack []ForFrame
 
+	// Variables and arrays saved around recursive calls
+	saved saveStack
+
 	// Reusable buffer for array indices
 	indexBuf []int
+
+	// Fields of the last INPUT not yet stored (OpInputField)
+	fields []Value
+
+	// Coverage counters updated by OpCover (nil when not instrumented)
+	cover *coverage.Profile
+
+	vars      *interpreter.Variables // Shared variables (WithVariables), or nil
+	interrupt *atomic.Bool           // Checked at jumps (WithInterrupt)
+
+	chainLoader func(string) (*bytecode.Chunk, error) // Loads programs for CHAIN (WithChain)
 }
 
 // Option represents a configuration option for the VM
@@ -69,24 +88,27 @@ func WithInput(r io.Reader) Option {
 	return func(vm *VM) { vm.input = r }
 }
 
+// WithCoverage records OpCover hits into the given profile
+func WithCoverage(p *coverage.Profile) Option {
+	return func(vm *VM) { vm.cover = p }
+}
+
 // New creates a new VM
 func New(c *bytecode.Chunk, opts ...Option) *VM {
-	// Initialize globals and arrays based on chunk counts
-	globals := make([]interpreter.Value, c.GlobalCount)
-	arrays := make([]*interpreter.ArrayInfo, c.ArrayCount)
-
 	vm := &VM{
 		chunk:       c,
+		constants:   constantPool(c), // Converted to the VM's compact representation once
 		ip:          0,
-		stack:       make([]interpreter.Value, StackSize),
+		stack:       make([]Value, StackSize),
 		sp:          0,
-		globals:     globals,
-		arrays:      arrays,
+		globals:     emptyGlobals(c.GlobalCount),
+		arrays:      make([]*interpreter.ArrayInfo, c.ArrayCount),
 		output:      os.Stdout,          // Default output
 		errOutput:   os.Stderr,          // Default error output
 		input:       os.Stdin,           // Default input
 		returnStack: make([]int, 0, 16), // Pre-allocate capacity
 		forStack:    make([]ForFrame, 0, 8),
+		interrupt:   new(atomic.Bool),
 	}
 	for _, opt := range opts {
 		opt(vm)
@@ -96,7 +118,7 @@ func New(c *bytecode.Chunk, opts ...Option) *VM {
 
 // pushUnchecked pushes a value onto the stack without bounds checking.
 // Use only when we know the stack has space (e.g., after popping 2 values and pushing 1).
-func (vm *VM) pushUnchecked(val interpreter.Value) {
+func (vm *VM) pushUnchecked(val Value) {
 	vm.stack[vm.sp] = val
 	vm.sp++
 }
@@ -120,10 +142,22 @@ func (vm *VM) getIndexBuf(size int) []int {
 	return vm.indexBuf
 }
 
-// Run executes the bytecode
+// Run executes the bytecode. After an *interpreter.Break it can be called
+// again to continue where the program was interrupted. CHAIN may have
+// replaced the chunk by the time run returns.
 func (vm *VM) Run() error {
+	if err := vm.chunk.Runnable(); err != nil {
+		return err
+	}
+	loadVariables(vm.vars, vm.chunk.GlobalNames, vm.chunk.ArrayNames, vm.globals, vm.arrays)
+	err := vm.run()
+	saveVariables(vm.vars, vm.chunk.GlobalNames, vm.chunk.ArrayNames, vm.globals, vm.arrays)
+	return err
+}
+
+func (vm *VM) run() error {
 	code := vm.chunk.Code
-	constants := vm.chunk.Constants
+	constants := vm.constants
 	globals := vm.globals
 
 	for vm.ip < len(code) {
@@ -132,7 +166,7 @@ func (vm *VM) Run() error {
 
 		switch op {
 		case bytecode.OpConstant:
-			constIdx := vm.readUint16()
+			constIdx := vm.readUint32()
 			if err := vm.push(constants[constIdx]); err != nil {
 				return err
 			}
@@ -143,21 +177,23 @@ func (vm *VM) Run() error {
 		case bytecode.OpAdd:
 			right := vm.pop()
 			left := vm.pop()
-			if left.IsString() || right.IsString() {
-				vm.pushUnchecked(interpreter.StringValue(left.String() + right.String()))
+			if left.IsNumber() && right.IsNumber() {
+				vm.pushUnchecked(NumberValue(left.num() + right.num()))
+			} else if left.IsString() || right.IsString() {
+				vm.pushUnchecked(StringValue(left.String() + right.String()))
 			} else {
-				vm.pushUnchecked(interpreter.NumberValue(left.AsNumber() + right.AsNumber()))
+				vm.pushUnchecked(NumberValue(left.AsNumber() + right.AsNumber()))
 			}
 
 		case bytecode.OpSub:
 			right := vm.pop()
 			left := vm.pop()
-			vm.pushUnchecked(interpreter.NumberValue(left.AsNumber() - right.AsNumber()))
+			vm.pushUnchecked(NumberValue(left.AsNumber() - right.AsNumber()))
 
 		case bytecode.OpMul:
 			right := vm.pop()
 			left := vm.pop()
-			vm.pushUnchecked(interpreter.NumberValue(left.AsNumber() * right.AsNumber()))
+			vm.pushUnchecked(NumberValue(left.AsNumber() * right.AsNumber()))
 
 		case bytecode.OpDiv:
 			right := vm.pop()
@@ -165,22 +201,24 @@ func (vm *VM) Run() error {
 			if right.AsNumber() == 0 {
 				return fmt.Errorf("division by zero")
 			}
-			vm.pushUnchecked(interpreter.NumberValue(left.AsNumber() / right.AsNumber()))
+			vm.pushUnchecked(NumberValue(left.AsNumber() / right.AsNumber()))
 
 		case bytecode.OpPow:
 			right := vm.pop()
 			left := vm.pop()
-			vm.pushUnchecked(interpreter.NumberValue(math.Pow(left.AsNumber(), right.AsNumber())))
+			vm.pushUnchecked(NumberValue(math.Pow(left.AsNumber(), right.AsNumber())))
 
 		case bytecode.OpMod:
 			right := vm.pop()
 			left := vm.pop()
-			vm.pushUnchecked(interpreter.NumberValue(math.Mod(left.AsNumber(), right.AsNumber())))
+			vm.pushUnchecked(NumberValue(math.Mod(left.AsNumber(), right.AsNumber())))
 
 		case bytecode.OpEq:
 			right := vm.pop()
 			left := vm.pop()
-			if left.IsString() || right.IsString() {
+			if left.IsNumber() && right.IsNumber() {
+				vm.pushBool(left.num() == right.num())
+			} else if left.IsString() || right.IsString() {
 				vm.pushBool(left.String() == right.String())
 			} else {
 				vm.pushBool(left.AsNumber() == right.AsNumber())
@@ -189,7 +227,9 @@ func (vm *VM) Run() error {
 		case bytecode.OpNeq:
 			right := vm.pop()
 			left := vm.pop()
-			if left.IsString() || right.IsString() {
+			if left.IsNumber() && right.IsNumber() {
+				vm.pushBool(left.num() != right.num())
+			} else if left.IsString() || right.IsString() {
 				vm.pushBool(left.String() != right.String())
 			} else {
 				vm.pushBool(left.AsNumber() != right.AsNumber())
@@ -198,7 +238,9 @@ func (vm *VM) Run() error {
 		case bytecode.OpGt:
 			right := vm.pop()
 			left := vm.pop()
-			if left.IsString() || right.IsString() {
+			if left.IsNumber() && right.IsNumber() {
+				vm.pushBool(left.num() > right.num())
+			} else if left.IsString() || right.IsString() {
 				vm.pushBool(left.String() > right.String())
 			} else {
 				vm.pushBool(left.AsNumber() > right.AsNumber())
@@ -207,7 +249,9 @@ func (vm *VM) Run() error {
 		case bytecode.OpGte:
 			right := vm.pop()
 			left := vm.pop()
-			if left.IsString() || right.IsString() {
+			if left.IsNumber() && right.IsNumber() {
+				vm.pushBool(left.num() >= right.num())
+			} else if left.IsString() || right.IsString() {
 				vm.pushBool(left.String() >= right.String())
 			} else {
 				vm.pushBool(left.AsNumber() >= right.AsNumber())
@@ -216,7 +260,9 @@ func (vm *VM) Run() error {
 		case bytecode.OpLt:
 			right := vm.pop()
 			left := vm.pop()
-			if left.IsString() || right.IsString() {
+			if left.IsNumber() && right.IsNumber() {
+				vm.pushBool(left.num() < right.num())
+			} else if left.IsString() || right.IsString() {
 				vm.pushBool(left.String() < right.String())
 			} else {
 				vm.pushBool(left.AsNumber() < right.AsNumber())
@@ -225,7 +271,9 @@ func (vm *VM) Run() error {
 		case bytecode.OpLte:
 			right := vm.pop()
 			left := vm.pop()
-			if left.IsString() || right.IsString() {
+			if left.IsNumber() && right.IsNumber() {
+				vm.pushBool(left.num() <= right.num())
+			} else if left.IsString() || right.IsString() {
 				vm.pushBool(left.String() <= right.String())
 			} else {
 				vm.pushBool(left.AsNumber() <= right.AsNumber())
@@ -246,7 +294,7 @@ func (vm *VM) Run() error {
 			if !val.IsNumber() {
 				return fmt.Errorf("operand must be a number")
 			}
-			vm.pushUnchecked(interpreter.NumberValue(-val.AsNumber()))
+			vm.pushUnchecked(NumberValue(-val.AsNumber()))
 
 		case bytecode.OpNot:
 			val := vm.pop()
@@ -260,48 +308,60 @@ func (vm *VM) Run() error {
 			fmt.Fprintln(vm.output)
 
 		case bytecode.OpInput:
-			nameIdx := vm.readUint16()
-			var input string
-			fmt.Fscanln(vm.input, &input)
+			nameIdx := vm.readUint32()
+			if int(nameIdx) >= len(globals) {
+				return fmt.Errorf("global index out of bounds: %d", nameIdx)
+			}
+			globals[int(nameIdx)] = legacyInput(vm.input)
 
-			// Try parse number
-			num, err := strconv.ParseFloat(input, 64)
-			var val interpreter.Value
+		case bytecode.OpReadInput, bytecode.OpLineInput:
+			prompt := vm.readUint32()
+			var types Value
+			if op == bytecode.OpReadInput {
+				types = constants[vm.readUint32()]
+			}
+			fields, err := readInput(vm.input, vm.output, op, constants[prompt], types)
 			if err != nil {
-				val = interpreter.StringValue(input)
-			} else {
-				val = interpreter.NumberValue(num)
+				return err
 			}
+			vm.fields = fields
 
-			if int(nameIdx) >= len(globals) {
-				return fmt.Errorf("global index out of bounds: %d", nameIdx)
+		case bytecode.OpInputField:
+			if len(vm.fields) == 0 {
+				return errNoField
 			}
-			globals[int(nameIdx)] = val
+			if err := vm.push(vm.fields[0]); err != nil {
+				return err
+			}
+			vm.fields = vm.fields[1:]
 
 		case bytecode.OpJump:
-			offset := vm.readUint16()
+			offset := vm.readUint32()
 			vm.ip = int(offset)
+			if vm.interrupt.Load() {
+				return breakAt(vm.interrupt, vm.chunk, vm.ip)
+			}
 
 		case bytecode.OpJumpIfFalse:
-			offset := vm.readUint16()
+			offset := vm.readUint32()
 			cond := vm.pop()
 			if !cond.IsTrue() {
 				vm.ip = int(offset)
 			}
 
 		case bytecode.OpGetGlobal:
-			nameIdx := vm.readUint16()
+			nameIdx := vm.readUint32()
 			if err := vm.push(globals[int(nameIdx)]); err != nil {
 				return err
 			}
 
 		case bytecode.OpSetGlobal:
-			nameIdx := vm.readUint16()
+			nameIdx := vm.readUint32()
 			val := vm.pop()
 			globals[int(nameIdx)] = val
 
 		case bytecode.OpGetArray:
-			nameIdx := vm.readUint16()
+			nameIdx := vm.readUint32()
 			dimCount := int(vm.readUint8())
 
 			indices := vm.getIndexBuf(dimCount)
@@ -318,12 +378,12 @@ func (vm *VM) Run() error {
 			if flatIdx < 0 {
 				return fmt.Errorf("array index out of bounds")
 			}
-			if err := vm.push(interpreter.NumberValue(arr.Data[flatIdx])); err != nil {
+			if err := vm.push(getElement(arr, flatIdx)); err != nil {
 				return err
 			}
 
 		case bytecode.OpSetArray:
-			nameIdx := vm.readUint16()
+			nameIdx := vm.readUint32()
 			dimCount := int(vm.readUint8())
 			val := vm.pop()
 
@@ -341,13 +401,16 @@ func (vm *VM) Run() error {
 			if flatIdx < 0 {
 				return fmt.Errorf("array index out of bounds")
 			}
-			arr.Data[flatIdx] = val.AsNumber()
+			setElement(arr, flatIdx, val)
 
 		case bytecode.OpGosub:
-			target := vm.readUint16()
+			target := vm.readUint32()
 			// Push return address (current ip)
 			vm.returnStack = append(vm.returnStack, vm.ip)
 			vm.ip = int(target)
+			if vm.interrupt.Load() {
+				return breakAt(vm.interrupt, vm.chunk, vm.ip)
+			}
 
 		case bytecode.OpReturn:
 			if len(vm.returnStack) == 0 {
@@ -356,12 +419,29 @@ func (vm *VM) Run() error {
 			addr := vm.returnStack[len(vm.returnStack)-1]
 			vm.returnStack = vm.returnStack[:len(vm.returnStack)-1]
 			vm.ip = addr
+			if vm.interrupt.Load() {
+				return breakAt(vm.interrupt, vm.chunk, vm.ip)
+			}
 
 		case bytecode.OpEnd:
 			return nil
 
+		case bytecode.OpStop:
+			return lineBreak(vm.chunk, vm.chunk.Lines[vm.ip-1])
+
+		case bytecode.OpChain:
+			names := constants[vm.readUint32()]
+			line := vm.pop()
+			file := vm.pop()
+			if err := vm.chain(names, file, line); err != nil {
+				return err
+			}
+			code = vm.chunk.Code
+			constants = vm.constants
+			globals = vm.globals
+
 		case bytecode.OpForInit:
-			varIdx := vm.readUint16()
+			varIdx := vm.readUint32()
 			stepVal := vm.pop().AsNumber()
 			endVal := vm.pop().AsNumber()
 
@@ -374,8 +454,8 @@ func (vm *VM) Run() error {
 			})
 
 		case bytecode.OpNext:
-			varIdx := int(vm.readUint16())
-			loopTop := int(vm.readUint16())
+			varIdx := int(vm.readUint32())
+			loopTop := int(vm.readUint32())
 
 			if len(vm.forStack) == 0 {
 				return fmt.Errorf("NEXT without FOR")
@@ -390,7 +470,7 @@ func (vm *VM) Run() error {
 
 			// Increment loop variable
 			newVal := globals[varIdx].AsNumber() + frame.stepValue
-			globals[varIdx] = interpreter.NumberValue(newVal)
+			globals[varIdx] = NumberValue(newVal)
 
 			// Check loop condition
 			shouldContinue := false
@@ -402,13 +482,16 @@ func (vm *VM) Run() error {
 
 			if shouldContinue {
 				vm.ip = loopTop
+				if vm.interrupt.Load() {
+					return breakAt(vm.interrupt, vm.chunk, vm.ip)
+				}
 			} else {
 				// Pop frame
 				vm.forStack = vm.forStack[:len(vm.forStack)-1]
 			}
 
 		case bytecode.OpDim:
-			nameIdx := vm.readUint16()
+			nameIdx := vm.readUint32()
 			dimCount := int(vm.readUint8())
 
 			dims := make([]int, dimCount)
@@ -426,10 +509,184 @@ func (vm *VM) Run() error {
 			}
 
 			// Create new array info
-			vm.arrays[int(nameIdx)] = interpreter.NewArrayInfo(dims)
+			vm.arrays[int(nameIdx)] = newArray(vm.chunk, int(nameIdx), dims)
+
+		case bytecode.OpDimMap:
+			idx := int(vm.readUint32())
+			if idx >= len(vm.arrays) {
+				return fmt.Errorf("array index out of bounds: %d", idx)
+			}
+			vm.arrays[idx] = newMap(vm.chunk, idx)
+
+		case bytecode.OpSaveGlobal:
+			vm.saved.values = append(vm.saved.values, globals[int(vm.readUint32())])
+
+		case bytecode.OpRestoreGlobal:
+			idx := int(vm.readUint32())
+			v, err := vm.saved.popValue()
+			if err != nil {
+				return err
+			}
+			globals[idx] = v
+
+		case bytecode.OpSaveArray:
+			idx := int(vm.readUint32())
+			if idx >= len(vm.arrays) {
+				return fmt.Errorf("array index out of bounds: %d", idx)
+			}
+			vm.saved.arrays = append(vm.saved.arrays, vm.arrays[idx])
+
+		case bytecode.OpRestoreArray:
+			idx := int(vm.readUint32())
+			if idx >= len(vm.arrays) {
+				return fmt.Errorf("array index out of bounds: %d", idx)
+			}
+			arr, err := vm.saved.popArray()
+			if err != nil {
+				return err
+			}
+			vm.arrays[idx] = arr
+
+		case bytecode.OpDimRecord:
+			idx := int(vm.readUint32())
+			dims := make([]int, vm.readUint8())
+			shape := constants[vm.readUint32()]
+			for i := len(dims) - 1; i >= 0; i-- {
+				dim := int(vm.pop().AsNumber())
+				if dim < 0 {
+					return fmt.Errorf("negative array dimension: %d", dim)
+				}
+				dims[i] = dim
+			}
+			arr, err := newRecord(dims, shape)
+			if err != nil {
+				return err
+			}
+			vm.arrays[idx] = arr
+
+		case bytecode.OpGetField:
+			arr, flatIdx, err := vm.popRecord()
+			if err != nil {
+				return err
+			}
+			if err := vm.push(getElement(arr, flatIdx)); err != nil {
+				return err
+			}
+
+		case bytecode.OpSetField:
+			val := vm.pop()
+			arr, flatIdx, err := vm.popRecord()
+			if err != nil {
+				return err
+			}
+			setElement(arr, flatIdx, val)
+
+		case bytecode.OpCopyRecord:
+			// The source indices are on top, so read past the target operands first
+			dstIP := vm.ip
+			vm.ip += 7
+			src, from, err := vm.popRecord()
+			if err != nil {
+				return err
+			}
+			n := int(vm.readUint16())
+			end := vm.ip
+			vm.ip = dstIP
+			dst, to, err := vm.popRecord()
+			if err != nil {
+				return err
+			}
+			vm.ip = end
+			if err := copyRecord(dst, to, src, from, n); err != nil {
+				return err
+			}
+
+		case bytecode.OpForEach:
+			m, err := mapAt(vm.arrays, int(vm.readUint32()))
+			if err != nil {
+				return err
+			}
+			varIdx := int(vm.readUint32())
+			exit := int(vm.readUint32())
+			if keys := m.Keys(); len(keys) > 0 {
+				globals[varIdx] = StringValue(keys[0])
+				vm.forStack = append(vm.forStack, ForFrame{varIdx: varIdx, loopTop: vm.ip, keys: keys})
+			} else {
+				vm.ip = exit
+			}
+
+		case bytecode.OpNextEach:
+			varIdx := int(vm.readUint32())
+			loopTop := int(vm.readUint32())
+			key, more, err := nextKey(vm.forStack, varIdx)
+			if err != nil {
+				return err
+			}
+			if more {
+				globals[varIdx] = StringValue(key)
+				vm.ip = loopTop
+				if vm.interrupt.Load() {
+					return breakAt(vm.interrupt, vm.chunk, vm.ip)
+				}
+			} else {
+				vm.forStack = vm.forStack[:len(vm.forStack)-1]
+			}
+
+		case bytecode.OpMapGet, bytecode.OpMapHas, bytecode.OpMapKey:
+			m, err := mapAt(vm.arrays, int(vm.readUint32()))
+			if err != nil {
+				return err
+			}
+			var result Value
+			switch op {
+			case bytecode.OpMapGet:
+				result, err = mapGet(m, vm.pop())
+			case bytecode.OpMapHas:
+				result, err = mapHas(m, vm.pop())
+			default:
+				result, err = mapKeyAt(m, vm.pop())
+			}
+			if err != nil {
+				return err
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case bytecode.OpMapSet:
+			m, err := mapAt(vm.arrays, int(vm.readUint32()))
+			if err != nil {
+				return err
+			}
+			val := vm.pop()
+			key, err := mapKey(vm.pop())
+			if err != nil {
+				return err
+			}
+			mapSet(m, key, val)
+
+		case bytecode.OpMapDelete:
+			m, err := mapAt(vm.arrays, int(vm.readUint32()))
+			if err != nil {
+				return err
+			}
+			key, err := mapKey(vm.pop())
+			if err != nil {
+				return err
+			}
+			m.Delete(key)
+
+		case bytecode.OpMapCount:
+			m, err := mapAt(vm.arrays, int(vm.readUint32()))
+			if err != nil {
+				return err
+			}
+			if err := vm.push(NumberValue(float64(m.Len()))); err != nil {
+				return err
+			}
 
 		case bytecode.OpCallBuiltin:
-			builtinIdx := vm.readUint16()
+			builtinIdx := vm.readBuiltin()
 			argCount := int(vm.readUint8())
 
 			if int(builtinIdx) >= len(builtinImpls) {
@@ -458,6 +715,50 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case bytecode.OpCover:
+			counter := int(vm.readUint32())
+			if vm.cover != nil && counter < len(vm.cover.Counts) {
+				vm.cover.Hit(counter)
+			}
+
+		case bytecode.OpGetGlobal2:
+			first := vm.readUint32()
+			second := vm.readUint32()
+			if vm.sp+2 > StackSize {
+				return fmt.Errorf("stack overflow")
+			}
+			vm.pushUnchecked(globals[int(first)])
+			vm.pushUnchecked(globals[int(second)])
+
+		case bytecode.OpAddGlobalConst:
+			nameIdx := int(vm.readUint32())
+			constIdx := vm.readUint32()
+			left := globals[nameIdx]
+			right := constants[constIdx]
+			if left.IsString() || right.IsString() {
+				globals[nameIdx] = StringValue(left.String() + right.String())
+			} else {
+				globals[nameIdx] = NumberValue(left.AsNumber() + right.AsNumber())
+			}
+
+		case bytecode.OpIncGlobal:
+			nameIdx := int(vm.readUint32())
+			val := globals[nameIdx]
+			if val.IsString() {
+				globals[nameIdx] = StringValue(val.String() + "1")
+			} else {
+				globals[nameIdx] = NumberValue(val.AsNumber() + 1)
+			}
+
+		case bytecode.OpCmpJump:
+			cmp := bytecode.OpCode(vm.readUint8())
+			offset := vm.readUint32()
+			right := vm.pop()
+			left := vm.pop()
+			if !compare(cmp, left, right) {
+				vm.ip = int(offset)
+			}
+
 		default:
 			return fmt.Errorf("unknown opcode %d", op)
 		}
@@ -465,13 +766,52 @@ func (vm *VM) Run() error {
 	return nil
 }
 
+// compare evaluates a comparison opcode with the same rules as
+// OpEq..OpLte: strings compare as strings, everything else as numbers.
+func compare(op bytecode.OpCode, left, right Value) bool {
+	if left.IsString() || right.IsString() {
+		l, r := left.String(), right.String()
+		switch op {
+		case bytecode.OpEq:
+			return l == r
+		case bytecode.OpNeq:
+			return l != r
+		case bytecode.OpGt:
+			return l > r
+		case bytecode.OpGte:
+			return l >= r
+		case bytecode.OpLt:
+			return l < r
+		case bytecode.OpLte:
+			return l <= r
+		}
+		return false
+	}
+	l, r := left.AsNumber(), right.AsNumber()
+	switch op {
+	case bytecode.OpEq:
+		return l == r
+	case bytecode.OpNeq:
+		return l != r
+	case bytecode.OpGt:
+		return l > r
+	case bytecode.OpGte:
+		return l >= r
+	case bytecode.OpLt:
+		return l < r
+	case bytecode.OpLte:
+		return l <= r
+	}
+	return false
+}
+
 func (vm *VM) readUint8() uint8 {
 	val := vm.chunk.Code[vm.ip]
 	vm.ip++
 	return val
 }
 
-func (vm *VM) push(val interpreter.Value) error {
+func (vm *VM) push(val Value) error {
 	if vm.sp >= StackSize {
 		return fmt.Errorf("stack overflow")
 	}
@@ -480,7 +820,7 @@ func (vm *VM) push(val interpreter.Value) error {
 	return nil
 }
 
-func (vm *VM) pop() interpreter.Value {
+func (vm *VM) pop() Value {
 	if vm.sp == 0 {
 		panic("stack underflow")
 	}
@@ -488,8 +828,35 @@ func (vm *VM) pop() interpreter.Value {
 	return vm.stack[vm.sp]
 }
 
+func (vm *VM) readUint32() uint32 {
+	val := binary.BigEndian.Uint32(vm.chunk.Code[vm.ip:])
+	vm.ip += 4
+	return val
+}
+
+// readUint16 reads a 2-byte operand
 func (vm *VM) readUint16() uint16 {
 	val := binary.BigEndian.Uint16(vm.chunk.Code[vm.ip:])
 	vm.ip += 2
 	return val
 }
+
+// popRecord reads the record operands of a field instruction (array, index
+// count and slot), pops the indices and locates the slot
+func (vm *VM) popRecord() (*interpreter.ArrayInfo, int, error) {
+	idx := int(vm.readUint32())
+	count := int(vm.readUint8())
+	indices := vm.getIndexBuf(count + 1)
+	indices[count] = int(vm.readUint16())
+	for i := count - 1; i >= 0; i-- {
+		indices[i] = int(vm.pop().AsNumber())
+	}
+	return recordAt(vm.arrays, idx, indices)
+}
+
+// readBuiltin reads the 2-byte builtin index of OpCallBuiltin
+func (vm *VM) readBuiltin() uint16 {
+	val := binary.BigEndian.Uint16(vm.chunk.Code[vm.ip:])
+	vm.ip += 2
+	return val
+}
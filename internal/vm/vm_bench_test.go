@@ -0,0 +1,69 @@
+package vm_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"zork-basic/internal/ast"
+	"zork-basic/internal/compiler"
+	"zork-basic/internal/parser"
+	"zork-basic/internal/vm"
+)
+
+var benchPrograms = []struct {
+	name string
+	src  string
+}{
+	{"SinSum", "10 SUM = 0\n20 FOR I = 1 TO 1000000\n30 SUM = SUM + SIN(I)\n40 NEXT I\n"},
+	{"GotoLoop", "10 C = 0\n20 C = C + 1\n30 IF C < 1000000 THEN GOTO 20\n"},
+	{"Arrays", "10 DIM A(100)\n20 FOR J = 1 TO 10000\n30 FOR I = 0 TO 99\n40 A(I) = A(I) + I * J\n50 NEXT I\n60 NEXT J\n"},
+	{"Strings", "10 S$ = \"\"\n20 FOR I = 1 TO 100000\n30 S$ = LEFT$(S$ + \"AB\", 10)\n40 NEXT I\n"},
+}
+
+// BenchmarkRun measures VM throughput on small hot loops at -O0 and -O1
+// (see PERFORMANCE.md)
+func BenchmarkRun(b *testing.B) {
+	for _, bp := range benchPrograms {
+		parsed, err := parser.Parse(bp.name, []byte(bp.src))
+		if err != nil {
+			b.Fatalf("%s: parse error: %v", bp.name, err)
+		}
+		for _, level := range []int{compiler.OptNone, compiler.OptFold} {
+			chunk, err := compiler.New(compiler.WithOptimization(level)).Compile(parsed.(*ast.Program))
+			if err != nil {
+				b.Fatalf("%s: compile error: %v", bp.name, err)
+			}
+			b.Run(fmt.Sprintf("%s/O%d", bp.name, level), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := vm.New(chunk, vm.WithOutput(io.Discard)).Run(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkRunRegister runs the same programs on the register VM
+func BenchmarkRunRegister(b *testing.B) {
+	for _, bp := range benchPrograms {
+		parsed, err := parser.Parse(bp.name, []byte(bp.src))
+		if err != nil {
+			b.Fatalf("%s: parse error: %v", bp.name, err)
+		}
+		chunk, err := compiler.New(compiler.WithRegisters()).Compile(parsed.(*ast.Program))
+		if err != nil {
+			b.Fatalf("%s: compile error: %v", bp.name, err)
+		}
+		b.Run(bp.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := vm.NewRegister(chunk, vm.WithOutput(io.Discard)).Run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
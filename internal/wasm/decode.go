@@ -0,0 +1,415 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Decode parses a binary module in the subset Encode produces. Custom
+// sections are skipped.
+func Decode(data []byte) (*Module, error) {
+	if len(data) < len(magic) || !bytes.Equal(data[:len(magic)], magic) {
+		return nil, errors.New("not a WebAssembly module")
+	}
+	r := &reader{data: data, pos: len(magic)}
+	m := &Module{}
+	var funcTypes []uint32
+	last := 0
+	for r.pos < len(r.data) {
+		id := r.byte()
+		size := r.u32()
+		if r.err != nil {
+			break
+		}
+		if int(size) > len(r.data)-r.pos {
+			return nil, fmt.Errorf("section %d: size %d exceeds input", id, size)
+		}
+		end := r.pos + int(size)
+		if id != secCustom {
+			if int(id) <= last {
+				return nil, fmt.Errorf("section %d out of order", id)
+			}
+			last = int(id)
+		}
+		s := &reader{data: r.data[:end], pos: r.pos}
+		var err error
+		switch id {
+		case secCustom:
+			s.pos = end
+		case secType:
+			m.Types, err = decodeVec(s, decodeFuncType)
+		case secImport:
+			m.Imports, err = decodeVec(s, decodeImport)
+		case secFunction:
+			funcTypes, err = decodeVec(s, func(s *reader) (uint32, error) { return s.u32(), s.err })
+		case secMemory:
+			m.Memories, err = decodeVec(s, decodeMemory)
+		case secGlobal:
+			m.Globals, err = decodeVec(s, decodeGlobal)
+		case secExport:
+			m.Exports, err = decodeVec(s, decodeExport)
+		case secCode:
+			m.Funcs, err = decodeVec(s, decodeFunc)
+		case secData:
+			m.Data, err = decodeVec(s, decodeData)
+		default:
+			err = errors.New("unsupported section")
+		}
+		if err == nil && s.pos != end {
+			err = errors.New("trailing bytes")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("section %d: %v", id, err)
+		}
+		r.pos = end
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(funcTypes) != len(m.Funcs) {
+		return nil, fmt.Errorf("%d function declarations but %d bodies", len(funcTypes), len(m.Funcs))
+	}
+	for i, t := range funcTypes {
+		m.Funcs[i].Type = t
+	}
+	return m, nil
+}
+
+// reader reads the binary format. The first error sticks; reads after it
+// return zero values.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+var errEOF = errors.New("unexpected end of input")
+
+func (r *reader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos >= len(r.data) {
+		r.err = errEOF
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *reader) bytes(n uint32) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if int(n) > len(r.data)-r.pos {
+		r.err = errEOF
+		return nil
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b
+}
+
+func (r *reader) u32() uint32 {
+	var v uint64
+	for shift := 0; ; shift += 7 {
+		b := r.byte()
+		if r.err != nil {
+			return 0
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if shift >= 28 {
+			r.err = errors.New("integer too long")
+			return 0
+		}
+	}
+	if v > math.MaxUint32 {
+		r.err = errors.New("integer too large")
+		return 0
+	}
+	return uint32(v)
+}
+
+// s64 reads a signed integer of at most bits bits
+func (r *reader) s64(bits int) int64 {
+	var v int64
+	shift := 0
+	for {
+		b := r.byte()
+		if r.err != nil {
+			return 0
+		}
+		v |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				v |= -1 << shift
+			}
+			break
+		}
+		if shift >= bits {
+			r.err = errors.New("integer too long")
+			return 0
+		}
+	}
+	if bits < 64 && (v < -1<<(bits-1) || v >= 1<<(bits-1)) {
+		r.err = errors.New("integer too large")
+		return 0
+	}
+	return v
+}
+
+func (r *reader) name() string {
+	return string(r.bytes(r.u32()))
+}
+
+func (r *reader) valType() ValType {
+	t := ValType(r.byte())
+	switch t {
+	case I32, I64, F32, F64:
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("bad value type 0x%02x", byte(t))
+		}
+	}
+	return t
+}
+
+func decodeVec[T any](r *reader, item func(*reader) (T, error)) ([]T, error) {
+	n := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if int(n) > len(r.data)-r.pos {
+		return nil, errEOF // Every item takes at least one byte
+	}
+	out := make([]T, 0, n)
+	for i := uint32(0); i < n; i++ {
+		v, err := item(r)
+		if err == nil {
+			err = r.err
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func decodeTypes(r *reader) ([]ValType, error) {
+	return decodeVec(r, func(r *reader) (ValType, error) { return r.valType(), r.err })
+}
+
+func decodeFuncType(r *reader) (FuncType, error) {
+	if b := r.byte(); b != 0x60 && r.err == nil {
+		return FuncType{}, fmt.Errorf("bad function type 0x%02x", b)
+	}
+	params, err := decodeTypes(r)
+	if err != nil {
+		return FuncType{}, err
+	}
+	results, err := decodeTypes(r)
+	return FuncType{params, results}, err
+}
+
+func decodeImport(r *reader) (Import, error) {
+	im := Import{Module: r.name(), Name: r.name()}
+	if kind := ExternKind(r.byte()); kind != ExternFunc && r.err == nil {
+		return im, fmt.Errorf("import %s.%s: only functions can be imported", im.Module, im.Name)
+	}
+	im.Type = r.u32()
+	return im, r.err
+}
+
+func decodeMemory(r *reader) (Memory, error) {
+	var mem Memory
+	switch flags := r.byte(); flags {
+	case 0:
+		mem.Min = r.u32()
+	case 1:
+		mem.Min = r.u32()
+		mem.Max = r.u32()
+		mem.HasMax = true
+	default:
+		if r.err == nil {
+			return mem, fmt.Errorf("bad limits flags %d", flags)
+		}
+	}
+	return mem, r.err
+}
+
+func decodeGlobal(r *reader) (Global, error) {
+	g := Global{Type: r.valType()}
+	switch mut := r.byte(); mut {
+	case 0, 1:
+		g.Mutable = mut == 1
+	default:
+		if r.err == nil {
+			return g, fmt.Errorf("bad mutability %d", mut)
+		}
+	}
+	var err error
+	g.Init, err = decodeConst(r)
+	return g, err
+}
+
+// decodeConst reads a constant expression: one const instruction and end
+func decodeConst(r *reader) (Instr, error) {
+	in, err := decodeInstr(r)
+	if err != nil {
+		return in, err
+	}
+	switch in.Op {
+	case OpI32Const, OpI64Const, OpF64Const:
+	default:
+		return in, fmt.Errorf("%s in constant expression", in)
+	}
+	if b := r.byte(); Opcode(b) != OpEnd && r.err == nil {
+		return in, errors.New("constant expression is not a single constant")
+	}
+	return in, r.err
+}
+
+func decodeExport(r *reader) (Export, error) {
+	e := Export{Name: r.name(), Kind: ExternKind(r.byte()), Index: r.u32()}
+	switch e.Kind {
+	case ExternFunc, ExternMemory, ExternGlobal:
+	default:
+		if r.err == nil {
+			return e, fmt.Errorf("export %q: bad kind %d", e.Name, e.Kind)
+		}
+	}
+	return e, r.err
+}
+
+func decodeFunc(r *reader) (Func, error) {
+	var f Func
+	size := r.u32()
+	if r.err != nil {
+		return f, r.err
+	}
+	if int(size) > len(r.data)-r.pos {
+		return f, errEOF
+	}
+	end := r.pos + int(size)
+	b := &reader{data: r.data[:end], pos: r.pos}
+	runs := b.u32()
+	total := 0
+	for i := uint32(0); i < runs && b.err == nil; i++ {
+		n := b.u32()
+		t := b.valType()
+		total += int(n)
+		if total > 50000 {
+			return f, errors.New("too many locals")
+		}
+		for j := uint32(0); j < n; j++ {
+			f.Locals = append(f.Locals, t)
+		}
+	}
+	// The body ends at the end matching the implicit function block
+	depth := 0
+	for b.err == nil {
+		if b.pos >= end {
+			return f, errors.New("function body without end")
+		}
+		in, err := decodeInstr(b)
+		if err != nil {
+			return f, err
+		}
+		switch in.Op {
+		case OpBlock, OpLoop, OpIf:
+			depth++
+		case OpEnd:
+			if depth == 0 {
+				if b.pos != end {
+					return f, errors.New("bytes after function end")
+				}
+				r.pos = end
+				return f, nil
+			}
+			depth--
+		}
+		f.Body = append(f.Body, in)
+	}
+	return f, b.err
+}
+
+func decodeData(r *reader) (Data, error) {
+	var d Data
+	if mode := r.u32(); mode != 0 && r.err == nil {
+		return d, fmt.Errorf("unsupported data segment mode %d", mode)
+	}
+	in, err := decodeConst(r)
+	if err != nil {
+		return d, err
+	}
+	if in.Op != OpI32Const {
+		return d, errors.New("data offset is not an i32.const")
+	}
+	d.Offset = uint32(in.Int)
+	d.Bytes = r.bytes(r.u32())
+	return d, r.err
+}
+
+func decodeInstr(r *reader) (Instr, error) {
+	op := Opcode(r.byte())
+	if op == 0xfc {
+		sub := r.u32()
+		if sub > 0xff && r.err == nil {
+			return Instr{}, fmt.Errorf("unknown opcode 0xfc %d", sub)
+		}
+		op = 0xfc00 | Opcode(sub)
+	}
+	if r.err != nil {
+		return Instr{}, r.err
+	}
+	info, ok := opcodes[op]
+	if !ok {
+		return Instr{}, fmt.Errorf("unknown opcode 0x%x", uint16(op))
+	}
+	in := Instr{Op: op}
+	switch info.imm {
+	case immBlock:
+		in.Block = BlockType(r.s64(33))
+	case immIndex:
+		in.Index = r.u32()
+	case immBrTable:
+		labels, err := decodeVec(r, func(r *reader) (uint32, error) { return r.u32(), r.err })
+		if err != nil {
+			return in, err
+		}
+		in.Labels = labels
+		in.Index = r.u32()
+	case immMem:
+		in.Align = r.u32()
+		in.Offset = r.u32()
+	case immI32:
+		in.Int = r.s64(32)
+	case immI64:
+		in.Int = r.s64(64)
+	case immF64:
+		if b := r.bytes(8); b != nil {
+			in.Float = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+	case immMemory:
+		if b := r.byte(); b != 0 && r.err == nil {
+			return in, fmt.Errorf("%s: memory index %d", info.name, b)
+		}
+	case immMemory2:
+		if b := r.bytes(2); b != nil && (b[0] != 0 || b[1] != 0) {
+			return in, fmt.Errorf("%s: bad memory index", info.name)
+		}
+	}
+	return in, r.err
+}
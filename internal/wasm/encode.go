@@ -0,0 +1,263 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Section ids
+const (
+	secCustom   = 0
+	secType     = 1
+	secImport   = 2
+	secFunction = 3
+	secMemory   = 5
+	secGlobal   = 6
+	secExport   = 7
+	secCode     = 10
+	secData     = 11
+)
+
+var magic = []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+
+// Encode returns the binary encoding of m. Empty sections are left out,
+// so decoding the result and encoding again gives the same bytes. It
+// fails only on instructions with an unknown opcode.
+func (m *Module) Encode() ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(magic)
+
+	section := func(id byte, count int, write func(*bytes.Buffer) error) error {
+		if count == 0 {
+			return nil
+		}
+		var payload bytes.Buffer
+		writeU32(&payload, uint32(count))
+		if err := write(&payload); err != nil {
+			return err
+		}
+		out.WriteByte(id)
+		writeU32(&out, uint32(payload.Len()))
+		out.Write(payload.Bytes())
+		return nil
+	}
+
+	err := section(secType, len(m.Types), func(b *bytes.Buffer) error {
+		for _, t := range m.Types {
+			b.WriteByte(0x60)
+			writeTypes(b, t.Params)
+			writeTypes(b, t.Results)
+		}
+		return nil
+	})
+	if err == nil {
+		err = section(secImport, len(m.Imports), func(b *bytes.Buffer) error {
+			for _, im := range m.Imports {
+				writeName(b, im.Module)
+				writeName(b, im.Name)
+				b.WriteByte(byte(ExternFunc))
+				writeU32(b, im.Type)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = section(secFunction, len(m.Funcs), func(b *bytes.Buffer) error {
+			for _, f := range m.Funcs {
+				writeU32(b, f.Type)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = section(secMemory, len(m.Memories), func(b *bytes.Buffer) error {
+			for _, mem := range m.Memories {
+				writeLimits(b, mem)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = section(secGlobal, len(m.Globals), func(b *bytes.Buffer) error {
+			for _, g := range m.Globals {
+				b.WriteByte(byte(g.Type))
+				if g.Mutable {
+					b.WriteByte(1)
+				} else {
+					b.WriteByte(0)
+				}
+				if err := writeInstr(b, g.Init); err != nil {
+					return err
+				}
+				b.WriteByte(byte(OpEnd))
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = section(secExport, len(m.Exports), func(b *bytes.Buffer) error {
+			for _, e := range m.Exports {
+				writeName(b, e.Name)
+				b.WriteByte(byte(e.Kind))
+				writeU32(b, e.Index)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = section(secCode, len(m.Funcs), func(b *bytes.Buffer) error {
+			for i, f := range m.Funcs {
+				body, err := encodeBody(f)
+				if err != nil {
+					return fmt.Errorf("func %d: %v", len(m.Imports)+i, err)
+				}
+				writeU32(b, uint32(len(body)))
+				b.Write(body)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = section(secData, len(m.Data), func(b *bytes.Buffer) error {
+			for _, d := range m.Data {
+				writeU32(b, 0) // Active segment of memory 0
+				writeInstr(b, Instr{Op: OpI32Const, Int: int64(int32(d.Offset))})
+				b.WriteByte(byte(OpEnd))
+				writeU32(b, uint32(len(d.Bytes)))
+				b.Write(d.Bytes)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encodeBody encodes the locals and instructions of f. Runs of locals of
+// the same type are grouped, as the format requires.
+func encodeBody(f Func) ([]byte, error) {
+	var b bytes.Buffer
+	type run struct {
+		n   uint32
+		typ ValType
+	}
+	var runs []run
+	for _, t := range f.Locals {
+		if len(runs) > 0 && runs[len(runs)-1].typ == t {
+			runs[len(runs)-1].n++
+		} else {
+			runs = append(runs, run{1, t})
+		}
+	}
+	writeU32(&b, uint32(len(runs)))
+	for _, r := range runs {
+		writeU32(&b, r.n)
+		b.WriteByte(byte(r.typ))
+	}
+	for _, in := range f.Body {
+		if err := writeInstr(&b, in); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte(byte(OpEnd))
+	return b.Bytes(), nil
+}
+
+func writeInstr(b *bytes.Buffer, in Instr) error {
+	info, ok := opcodes[in.Op]
+	if !ok {
+		return fmt.Errorf("unknown opcode 0x%x", uint16(in.Op))
+	}
+	if in.Op > 0xff {
+		b.WriteByte(byte(in.Op >> 8))
+		writeU32(b, uint32(in.Op&0xff))
+	} else {
+		b.WriteByte(byte(in.Op))
+	}
+	switch info.imm {
+	case immBlock:
+		writeS64(b, int64(in.Block))
+	case immIndex:
+		writeU32(b, in.Index)
+	case immBrTable:
+		writeU32(b, uint32(len(in.Labels)))
+		for _, l := range in.Labels {
+			writeU32(b, l)
+		}
+		writeU32(b, in.Index)
+	case immMem:
+		writeU32(b, in.Align)
+		writeU32(b, in.Offset)
+	case immI32:
+		writeS64(b, int64(int32(in.Int)))
+	case immI64:
+		writeS64(b, in.Int)
+	case immF64:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(in.Float))
+		b.Write(buf[:])
+	case immMemory:
+		b.WriteByte(0)
+	case immMemory2:
+		b.Write([]byte{0, 0})
+	}
+	return nil
+}
+
+func writeTypes(b *bytes.Buffer, types []ValType) {
+	writeU32(b, uint32(len(types)))
+	for _, t := range types {
+		b.WriteByte(byte(t))
+	}
+}
+
+func writeLimits(b *bytes.Buffer, mem Memory) {
+	if mem.HasMax {
+		b.WriteByte(1)
+		writeU32(b, mem.Min)
+		writeU32(b, mem.Max)
+		return
+	}
+	b.WriteByte(0)
+	writeU32(b, mem.Min)
+}
+
+func writeName(b *bytes.Buffer, s string) {
+	writeU32(b, uint32(len(s)))
+	b.WriteString(s)
+}
+
+// writeU32 writes an unsigned LEB128 integer
+func writeU32(b *bytes.Buffer, v uint32) {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			c |= 0x80
+		}
+		b.WriteByte(c)
+		if v == 0 {
+			return
+		}
+	}
+}
+
+// writeS64 writes a signed LEB128 integer
+func writeS64(b *bytes.Buffer, v int64) {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		done := v == 0 && c&0x40 == 0 || v == -1 && c&0x40 != 0
+		if !done {
+			c |= 0x80
+		}
+		b.WriteByte(c)
+		if done {
+			return
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package wasm
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Page size of linear memory
+const PageSize = 65536
+
+// maxPages is the largest memory a 32-bit module can address
+const maxPages = 65536
+
+// Validate checks that m is well formed: indices are in range, export
+// names are unique, data segments fit in memory and every function body
+// type-checks against its signature.
+func (m *Module) Validate() error {
+	for i, t := range m.Types {
+		for _, v := range append(append([]ValType(nil), t.Params...), t.Results...) {
+			if !v.valid() {
+				return fmt.Errorf("type %d: bad value type %v", i, v)
+			}
+		}
+	}
+	for i, im := range m.Imports {
+		if im.Type >= uint32(len(m.Types)) {
+			return fmt.Errorf("import %d (%s.%s): type index %d out of range", i, im.Module, im.Name, im.Type)
+		}
+	}
+	for i, f := range m.Funcs {
+		if f.Type >= uint32(len(m.Types)) {
+			return fmt.Errorf("func %d: type index %d out of range", len(m.Imports)+i, f.Type)
+		}
+		for _, v := range f.Locals {
+			if !v.valid() {
+				return fmt.Errorf("func %d: bad local type %v", len(m.Imports)+i, v)
+			}
+		}
+	}
+	if len(m.Memories) > 1 {
+		return fmt.Errorf("%d memories, at most one is allowed", len(m.Memories))
+	}
+	for _, mem := range m.Memories {
+		if mem.Min > maxPages || mem.HasMax && (mem.Max > maxPages || mem.Max < mem.Min) {
+			return fmt.Errorf("memory: bad limits %d..%d", mem.Min, mem.Max)
+		}
+	}
+	for i, g := range m.Globals {
+		if !g.Type.valid() {
+			return fmt.Errorf("global %d: bad value type %v", i, g.Type)
+		}
+		if t, ok := constType(g.Init.Op); !ok || t != g.Type {
+			return fmt.Errorf("global %d: initializer %s does not match type %v", i, g.Init, g.Type)
+		}
+	}
+	names := make(map[string]bool)
+	for _, e := range m.Exports {
+		if names[e.Name] {
+			return fmt.Errorf("duplicate export %q", e.Name)
+		}
+		names[e.Name] = true
+		var n int
+		switch e.Kind {
+		case ExternFunc:
+			n = len(m.Imports) + len(m.Funcs)
+		case ExternMemory:
+			n = len(m.Memories)
+		case ExternGlobal:
+			n = len(m.Globals)
+		default:
+			return fmt.Errorf("export %q: bad kind %d", e.Name, e.Kind)
+		}
+		if e.Index >= uint32(n) {
+			return fmt.Errorf("export %q: index %d out of range", e.Name, e.Index)
+		}
+	}
+	for i, d := range m.Data {
+		if len(m.Memories) == 0 {
+			return fmt.Errorf("data %d: no memory", i)
+		}
+		if uint64(d.Offset)+uint64(len(d.Bytes)) > uint64(m.Memories[0].Min)*PageSize {
+			return fmt.Errorf("data %d: %d bytes at %d do not fit in the initial memory", i, len(d.Bytes), d.Offset)
+		}
+	}
+	for i, f := range m.Funcs {
+		if err := m.checkFunc(f); err != nil {
+			return fmt.Errorf("func %d: %v", len(m.Imports)+i, err)
+		}
+	}
+	return nil
+}
+
+func (t ValType) valid() bool {
+	switch t {
+	case I32, I64, F32, F64:
+		return true
+	}
+	return false
+}
+
+func constType(op Opcode) (ValType, bool) {
+	switch op {
+	case OpI32Const:
+		return I32, true
+	case OpI64Const:
+		return I64, true
+	case OpF64Const:
+		return F64, true
+	}
+	return 0, false
+}
+
+// unknown is the type of a value popped from the polymorphic stack after
+// an unconditional branch; it matches every type
+const unknown ValType = 0
+
+// frame is an open block, loop, if or the function body
+type frame struct {
+	op          Opcode
+	params      []ValType
+	results     []ValType
+	height      int  // Operand stack height at entry
+	unreachable bool // The rest of the block cannot be reached
+}
+
+// labelTypes are the types a branch to the frame carries
+func (f *frame) labelTypes() []ValType {
+	if f.op == OpLoop {
+		return f.params
+	}
+	return f.results
+}
+
+// checker type-checks one function body, following the validation
+// algorithm in the appendix of the WebAssembly specification
+type checker struct {
+	m      *Module
+	locals []ValType
+	vals   []ValType
+	ctrls  []frame
+}
+
+func (m *Module) checkFunc(f Func) error {
+	sig := m.Types[f.Type]
+	c := &checker{m: m, locals: append(append([]ValType(nil), sig.Params...), f.Locals...)}
+	c.pushCtrl(OpBlock, nil, sig.Results)
+	for i, in := range f.Body {
+		if len(c.ctrls) == 0 {
+			return fmt.Errorf("instruction %d (%s): after the end of the function", i, in)
+		}
+		if err := c.check(in, sig); err != nil {
+			return fmt.Errorf("instruction %d (%s): %v", i, in, err)
+		}
+	}
+	if len(c.ctrls) != 1 {
+		return fmt.Errorf("%d blocks not closed", len(c.ctrls)-1)
+	}
+	if _, err := c.popCtrl(); err != nil {
+		return fmt.Errorf("end of function: %v", err)
+	}
+	return nil
+}
+
+func (c *checker) push(types ...ValType) {
+	c.vals = append(c.vals, types...)
+}
+
+func (c *checker) pop(want ValType) (ValType, error) {
+	top := &c.ctrls[len(c.ctrls)-1]
+	if len(c.vals) == top.height {
+		if top.unreachable {
+			return want, nil
+		}
+		if want == unknown {
+			return 0, fmt.Errorf("stack underflow")
+		}
+		return 0, fmt.Errorf("stack underflow, want %v", want)
+	}
+	got := c.vals[len(c.vals)-1]
+	c.vals = c.vals[:len(c.vals)-1]
+	if got != want && got != unknown && want != unknown {
+		return 0, fmt.Errorf("got %v, want %v", got, want)
+	}
+	if got == unknown {
+		return want, nil
+	}
+	return got, nil
+}
+
+func (c *checker) popAll(types []ValType) error {
+	for i := len(types) - 1; i >= 0; i-- {
+		if _, err := c.pop(types[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *checker) pushCtrl(op Opcode, params, results []ValType) {
+	c.ctrls = append(c.ctrls, frame{op: op, params: params, results: results, height: len(c.vals)})
+	c.push(params...)
+}
+
+func (c *checker) popCtrl() (frame, error) {
+	top := c.ctrls[len(c.ctrls)-1]
+	if err := c.popAll(top.results); err != nil {
+		return top, err
+	}
+	if len(c.vals) != top.height {
+		return top, fmt.Errorf("%d values left on the stack", len(c.vals)-top.height)
+	}
+	c.ctrls = c.ctrls[:len(c.ctrls)-1]
+	return top, nil
+}
+
+func (c *checker) setUnreachable() {
+	top := &c.ctrls[len(c.ctrls)-1]
+	c.vals = c.vals[:top.height]
+	top.unreachable = true
+}
+
+func (c *checker) label(depth uint32) (*frame, error) {
+	if depth >= uint32(len(c.ctrls)) {
+		return nil, fmt.Errorf("label %d out of range", depth)
+	}
+	return &c.ctrls[len(c.ctrls)-1-int(depth)], nil
+}
+
+func (c *checker) blockType(bt BlockType) ([]ValType, []ValType, error) {
+	switch {
+	case bt == BlockEmpty:
+		return nil, nil, nil
+	case bt < 0:
+		t := ValType(bt + 0x80)
+		if !t.valid() {
+			return nil, nil, fmt.Errorf("bad block type %d", bt)
+		}
+		return nil, []ValType{t}, nil
+	case bt >= BlockType(len(c.m.Types)):
+		return nil, nil, fmt.Errorf("block type index %d out of range", bt)
+	}
+	t := c.m.Types[bt]
+	return t.Params, t.Results, nil
+}
+
+func (c *checker) local(idx uint32) (ValType, error) {
+	if idx >= uint32(len(c.locals)) {
+		return 0, fmt.Errorf("local %d out of range", idx)
+	}
+	return c.locals[idx], nil
+}
+
+func (c *checker) check(in Instr, sig FuncType) error {
+	info := opcodes[in.Op]
+	switch {
+	case info.imm == immMem:
+		if len(c.m.Memories) == 0 {
+			return fmt.Errorf("no memory")
+		}
+		if in.Align > uint32(bits.TrailingZeros(uint(info.access))) {
+			return fmt.Errorf("alignment 2**%d larger than natural", in.Align)
+		}
+	case info.imm == immMemory || info.imm == immMemory2:
+		if len(c.m.Memories) == 0 {
+			return fmt.Errorf("no memory")
+		}
+	}
+	if !info.special {
+		if info.name == "" {
+			return fmt.Errorf("unknown opcode")
+		}
+		if err := c.popAll(info.pops); err != nil {
+			return err
+		}
+		c.push(info.pushes...)
+		return nil
+	}
+
+	switch in.Op {
+	case OpUnreachable:
+		c.setUnreachable()
+	case OpBlock, OpLoop, OpIf:
+		params, results, err := c.blockType(in.Block)
+		if err != nil {
+			return err
+		}
+		if in.Op == OpIf {
+			if _, err := c.pop(I32); err != nil {
+				return err
+			}
+		}
+		if err := c.popAll(params); err != nil {
+			return err
+		}
+		c.pushCtrl(in.Op, params, results)
+	case OpElse:
+		if c.ctrls[len(c.ctrls)-1].op != OpIf {
+			return fmt.Errorf("else without if")
+		}
+		f, err := c.popCtrl()
+		if err != nil {
+			return err
+		}
+		c.pushCtrl(OpElse, f.params, f.results)
+	case OpEnd:
+		if len(c.ctrls) == 1 {
+			return fmt.Errorf("end closes the function body")
+		}
+		f, err := c.popCtrl()
+		if err != nil {
+			return err
+		}
+		if f.op == OpIf && !sameTypes(f.params, f.results) {
+			return fmt.Errorf("if without else must not change the stack")
+		}
+		c.push(f.results...)
+	case OpBr:
+		f, err := c.label(in.Index)
+		if err != nil {
+			return err
+		}
+		if err := c.popAll(f.labelTypes()); err != nil {
+			return err
+		}
+		c.setUnreachable()
+	case OpBrIf:
+		f, err := c.label(in.Index)
+		if err != nil {
+			return err
+		}
+		if _, err := c.pop(I32); err != nil {
+			return err
+		}
+		if err := c.popAll(f.labelTypes()); err != nil {
+			return err
+		}
+		c.push(f.labelTypes()...)
+	case OpBrTable:
+		if _, err := c.pop(I32); err != nil {
+			return err
+		}
+		def, err := c.label(in.Index)
+		if err != nil {
+			return err
+		}
+		arity := len(def.labelTypes())
+		for _, l := range in.Labels {
+			f, err := c.label(l)
+			if err != nil {
+				return err
+			}
+			if len(f.labelTypes()) != arity {
+				return fmt.Errorf("br_table targets have different arities")
+			}
+			if err := c.popAll(f.labelTypes()); err != nil {
+				return err
+			}
+			c.push(f.labelTypes()...)
+		}
+		if err := c.popAll(def.labelTypes()); err != nil {
+			return err
+		}
+		c.setUnreachable()
+	case OpReturn:
+		if err := c.popAll(sig.Results); err != nil {
+			return err
+		}
+		c.setUnreachable()
+	case OpCall:
+		t, ok := c.m.funcType(in.Index)
+		if !ok {
+			return fmt.Errorf("function %d out of range", in.Index)
+		}
+		if err := c.popAll(t.Params); err != nil {
+			return err
+		}
+		c.push(t.Results...)
+	case OpDrop:
+		if _, err := c.pop(unknown); err != nil {
+			return err
+		}
+	case OpSelect:
+		if _, err := c.pop(I32); err != nil {
+			return err
+		}
+		t1, err := c.pop(unknown)
+		if err != nil {
+			return err
+		}
+		t2, err := c.pop(t1)
+		if err != nil {
+			return err
+		}
+		c.push(t2)
+	case OpLocalGet:
+		t, err := c.local(in.Index)
+		if err != nil {
+			return err
+		}
+		c.push(t)
+	case OpLocalSet, OpLocalTee:
+		t, err := c.local(in.Index)
+		if err != nil {
+			return err
+		}
+		if _, err := c.pop(t); err != nil {
+			return err
+		}
+		if in.Op == OpLocalTee {
+			c.push(t)
+		}
+	case OpGlobalGet, OpGlobalSet:
+		if in.Index >= uint32(len(c.m.Globals)) {
+			return fmt.Errorf("global %d out of range", in.Index)
+		}
+		g := c.m.Globals[in.Index]
+		if in.Op == OpGlobalGet {
+			c.push(g.Type)
+			break
+		}
+		if !g.Mutable {
+			return fmt.Errorf("global %d is immutable", in.Index)
+		}
+		if _, err := c.pop(g.Type); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown opcode")
+	}
+	return nil
+}
@@ -0,0 +1,474 @@
+// Package wasm is a small WebAssembly binary toolkit: an in-memory module
+// model, an encoder for the binary format, a decoder for the same subset
+// and a validator that type-checks function bodies. It has no runtime;
+// modules are checked structurally, so the compiler's output can be tested
+// without a WebAssembly engine.
+//
+// The supported subset is what the BASIC backend emits: function imports,
+// one linear memory, mutable globals, active data segments, multi-value
+// functions and blocks, and the numeric, memory and control instructions
+// listed in the opcode table (plus memory.copy and memory.fill).
+package wasm
+
+import (
+	"fmt"
+	"math"
+)
+
+// ValType is a value type
+type ValType byte
+
+const (
+	I32 ValType = 0x7f
+	I64 ValType = 0x7e
+	F32 ValType = 0x7d
+	F64 ValType = 0x7c
+)
+
+func (t ValType) String() string {
+	switch t {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case F32:
+		return "f32"
+	case F64:
+		return "f64"
+	}
+	return fmt.Sprintf("type(0x%02x)", byte(t))
+}
+
+// FuncType is a function signature
+type FuncType struct {
+	Params  []ValType
+	Results []ValType
+}
+
+func (t FuncType) equal(u FuncType) bool {
+	return sameTypes(t.Params, u.Params) && sameTypes(t.Results, u.Results)
+}
+
+func sameTypes(a, b []ValType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// BlockType is the signature of a block, loop or if: BlockEmpty, a value
+// type as returned by BlockOf, or a non-negative type index. It is stored
+// as the signed 33-bit integer the binary format uses.
+type BlockType int64
+
+// BlockEmpty is a block with no parameters and no results
+const BlockEmpty BlockType = -0x40
+
+// BlockOf returns the block type with the single result t
+func BlockOf(t ValType) BlockType {
+	return BlockType(int64(t) - 0x80)
+}
+
+// ExternKind is the kind of an import or export
+type ExternKind byte
+
+const (
+	ExternFunc   ExternKind = 0
+	ExternMemory ExternKind = 2
+	ExternGlobal ExternKind = 3
+)
+
+// Import is an imported function
+type Import struct {
+	Module, Name string
+	Type         uint32 // Index in Module.Types
+}
+
+// Func is a function defined in the module. Its index comes after all
+// imported functions.
+type Func struct {
+	Type   uint32    // Index in Module.Types
+	Locals []ValType // Locals after the parameters
+	Body   []Instr   // Without the final end
+}
+
+// Memory is a linear memory, sized in 64 KiB pages
+type Memory struct {
+	Min    uint32
+	Max    uint32
+	HasMax bool
+}
+
+// Global is a global variable with a constant initial value
+type Global struct {
+	Type    ValType
+	Mutable bool
+	Init    Instr // A single i32.const, i64.const or f64.const
+}
+
+// Export makes a function, memory or global visible to the host
+type Export struct {
+	Name  string
+	Kind  ExternKind
+	Index uint32
+}
+
+// Data is an active data segment of memory 0
+type Data struct {
+	Offset uint32
+	Bytes  []byte
+}
+
+// Module is a WebAssembly module
+type Module struct {
+	Types    []FuncType
+	Imports  []Import
+	Funcs    []Func
+	Memories []Memory
+	Globals  []Global
+	Exports  []Export
+	Data     []Data
+}
+
+// AddType returns the index of t in m.Types, adding it if needed
+func (m *Module) AddType(t FuncType) uint32 {
+	for i, u := range m.Types {
+		if u.equal(t) {
+			return uint32(i)
+		}
+	}
+	m.Types = append(m.Types, t)
+	return uint32(len(m.Types) - 1)
+}
+
+// funcType returns the signature of function idx, counting imports first
+func (m *Module) funcType(idx uint32) (FuncType, bool) {
+	var typ uint32
+	switch {
+	case idx < uint32(len(m.Imports)):
+		typ = m.Imports[idx].Type
+	case idx-uint32(len(m.Imports)) < uint32(len(m.Funcs)):
+		typ = m.Funcs[idx-uint32(len(m.Imports))].Type
+	default:
+		return FuncType{}, false
+	}
+	if typ >= uint32(len(m.Types)) {
+		return FuncType{}, false
+	}
+	return m.Types[typ], true
+}
+
+// Opcode is an instruction opcode. Instructions with the 0xfc prefix are
+// 0xfc00 plus their sub-opcode.
+type Opcode uint16
+
+// Instr is one instruction with its immediates. Which fields are used
+// depends on the opcode's immediate kind.
+type Instr struct {
+	Op     Opcode
+	Block  BlockType // block, loop, if
+	Index  uint32    // Label, function, local or global index; br_table default
+	Labels []uint32  // br_table targets
+	Align  uint32    // Memory access alignment (log2)
+	Offset uint32    // Memory access offset
+	Int    int64     // i32.const, i64.const
+	Float  float64   // f64.const
+}
+
+func (in Instr) String() string {
+	info, ok := opcodes[in.Op]
+	if !ok {
+		return fmt.Sprintf("opcode(0x%x)", uint16(in.Op))
+	}
+	switch info.imm {
+	case immBlock:
+		return fmt.Sprintf("%s %d", info.name, in.Block)
+	case immIndex:
+		return fmt.Sprintf("%s %d", info.name, in.Index)
+	case immBrTable:
+		return fmt.Sprintf("%s %v %d", info.name, in.Labels, in.Index)
+	case immMem:
+		return fmt.Sprintf("%s offset=%d align=%d", info.name, in.Offset, in.Align)
+	case immI32, immI64:
+		return fmt.Sprintf("%s %d", info.name, in.Int)
+	case immF64:
+		return fmt.Sprintf("%s %v", info.name, in.Float)
+	}
+	return info.name
+}
+
+// equal compares instructions, treating f64 constants bit for bit
+func (in Instr) equal(o Instr) bool {
+	return in.Op == o.Op && in.Block == o.Block && in.Index == o.Index &&
+		sameLabels(in.Labels, o.Labels) && in.Align == o.Align && in.Offset == o.Offset &&
+		in.Int == o.Int && math.Float64bits(in.Float) == math.Float64bits(o.Float)
+}
+
+func sameLabels(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Kinds of immediates
+const (
+	immNone    = iota
+	immBlock   // Block type
+	immIndex   // One index
+	immBrTable // Label vector and default label
+	immMem     // Alignment and offset
+	immI32     // Signed 32-bit integer
+	immI64     // Signed 64-bit integer
+	immF64     // 64-bit float
+	immMemory  // Memory index 0 (memory.size, memory.grow, memory.fill)
+	immMemory2 // Two memory indices 0 (memory.copy)
+)
+
+// opInfo describes an opcode. Instructions with a fixed signature list
+// what they pop and push; control and variable instructions, whose types
+// depend on their immediates, are checked by the validator itself.
+type opInfo struct {
+	name    string
+	imm     int
+	pops    []ValType
+	pushes  []ValType
+	access  int // Bytes accessed by a load or store, for the alignment check
+	special bool
+}
+
+var (
+	none = []ValType{}
+	i32  = []ValType{I32}
+	i64  = []ValType{I64}
+	f64  = []ValType{F64}
+)
+
+func sig(name string, pops, pushes []ValType) opInfo {
+	return opInfo{name: name, pops: pops, pushes: pushes}
+}
+
+func load(name string, result ValType, bytes int) opInfo {
+	return opInfo{name: name, imm: immMem, pops: i32, pushes: []ValType{result}, access: bytes}
+}
+
+func store(name string, value ValType, bytes int) opInfo {
+	return opInfo{name: name, imm: immMem, pops: []ValType{I32, value}, pushes: none, access: bytes}
+}
+
+func special(name string, imm int) opInfo {
+	return opInfo{name: name, imm: imm, special: true}
+}
+
+// Opcodes of the supported instructions
+const (
+	OpUnreachable Opcode = 0x00
+	OpNop         Opcode = 0x01
+	OpBlock       Opcode = 0x02
+	OpLoop        Opcode = 0x03
+	OpIf          Opcode = 0x04
+	OpElse        Opcode = 0x05
+	OpEnd         Opcode = 0x0b
+	OpBr          Opcode = 0x0c
+	OpBrIf        Opcode = 0x0d
+	OpBrTable     Opcode = 0x0e
+	OpReturn      Opcode = 0x0f
+	OpCall        Opcode = 0x10
+	OpDrop        Opcode = 0x1a
+	OpSelect      Opcode = 0x1b
+	OpLocalGet    Opcode = 0x20
+	OpLocalSet    Opcode = 0x21
+	OpLocalTee    Opcode = 0x22
+	OpGlobalGet   Opcode = 0x23
+	OpGlobalSet   Opcode = 0x24
+
+	OpI32Load    Opcode = 0x28
+	OpI64Load    Opcode = 0x29
+	OpF64Load    Opcode = 0x2b
+	OpI32Load8U  Opcode = 0x2d
+	OpI32Store   Opcode = 0x36
+	OpI64Store   Opcode = 0x37
+	OpF64Store   Opcode = 0x39
+	OpI32Store8  Opcode = 0x3a
+	OpMemorySize Opcode = 0x3f
+	OpMemoryGrow Opcode = 0x40
+
+	OpI32Const Opcode = 0x41
+	OpI64Const Opcode = 0x42
+	OpF64Const Opcode = 0x44
+
+	OpI32Eqz Opcode = 0x45
+	OpI32Eq  Opcode = 0x46
+	OpI32Ne  Opcode = 0x47
+	OpI32LtS Opcode = 0x48
+	OpI32LtU Opcode = 0x49
+	OpI32GtS Opcode = 0x4a
+	OpI32GtU Opcode = 0x4b
+	OpI32LeS Opcode = 0x4c
+	OpI32LeU Opcode = 0x4d
+	OpI32GeS Opcode = 0x4e
+	OpI32GeU Opcode = 0x4f
+	OpI64Eqz Opcode = 0x50
+	OpI64Eq  Opcode = 0x51
+	OpI64Ne  Opcode = 0x52
+	OpI64LtS Opcode = 0x53
+	OpI64GtS Opcode = 0x55
+	OpI64GtU Opcode = 0x56
+	OpI64LeS Opcode = 0x57
+	OpI64GeS Opcode = 0x59
+	OpF64Eq  Opcode = 0x61
+	OpF64Ne  Opcode = 0x62
+	OpF64Lt  Opcode = 0x63
+	OpF64Gt  Opcode = 0x64
+	OpF64Le  Opcode = 0x65
+	OpF64Ge  Opcode = 0x66
+
+	OpI32Add  Opcode = 0x6a
+	OpI32Sub  Opcode = 0x6b
+	OpI32Mul  Opcode = 0x6c
+	OpI32DivU Opcode = 0x6e
+	OpI32RemU Opcode = 0x70
+	OpI32And  Opcode = 0x71
+	OpI32Or   Opcode = 0x72
+	OpI32Xor  Opcode = 0x73
+	OpI32Shl  Opcode = 0x74
+	OpI32ShrU Opcode = 0x76
+	OpI64Add  Opcode = 0x7c
+	OpI64Sub  Opcode = 0x7d
+	OpI64Mul  Opcode = 0x7e
+	OpI64DivS Opcode = 0x7f
+	OpI64DivU Opcode = 0x80
+	OpI64RemS Opcode = 0x81
+
+	OpF64Abs   Opcode = 0x99
+	OpF64Neg   Opcode = 0x9a
+	OpF64Floor Opcode = 0x9c
+	OpF64Trunc Opcode = 0x9d
+	OpF64Sqrt  Opcode = 0x9f
+	OpF64Add   Opcode = 0xa0
+	OpF64Sub   Opcode = 0xa1
+	OpF64Mul   Opcode = 0xa2
+	OpF64Div   Opcode = 0xa3
+
+	OpI32WrapI64      Opcode = 0xa7
+	OpI64ExtendI32S   Opcode = 0xac
+	OpI64ExtendI32U   Opcode = 0xad
+	OpI64TruncF64S    Opcode = 0xb0
+	OpF64ConvertI32S  Opcode = 0xb7
+	OpF64ConvertI32U  Opcode = 0xb8
+	OpF64ConvertI64S  Opcode = 0xb9
+	OpI32TruncSatF64U Opcode = 0xfc03
+	OpMemoryCopy      Opcode = 0xfc0a
+	OpMemoryFill      Opcode = 0xfc0b
+)
+
+var opcodes = map[Opcode]opInfo{
+	OpUnreachable: special("unreachable", immNone),
+	OpNop:         sig("nop", none, none),
+	OpBlock:       special("block", immBlock),
+	OpLoop:        special("loop", immBlock),
+	OpIf:          special("if", immBlock),
+	OpElse:        special("else", immNone),
+	OpEnd:         special("end", immNone),
+	OpBr:          special("br", immIndex),
+	OpBrIf:        special("br_if", immIndex),
+	OpBrTable:     special("br_table", immBrTable),
+	OpReturn:      special("return", immNone),
+	OpCall:        special("call", immIndex),
+	OpDrop:        special("drop", immNone),
+	OpSelect:      special("select", immNone),
+	OpLocalGet:    special("local.get", immIndex),
+	OpLocalSet:    special("local.set", immIndex),
+	OpLocalTee:    special("local.tee", immIndex),
+	OpGlobalGet:   special("global.get", immIndex),
+	OpGlobalSet:   special("global.set", immIndex),
+
+	OpI32Load:    load("i32.load", I32, 4),
+	OpI64Load:    load("i64.load", I64, 8),
+	OpF64Load:    load("f64.load", F64, 8),
+	OpI32Load8U:  load("i32.load8_u", I32, 1),
+	OpI32Store:   store("i32.store", I32, 4),
+	OpI64Store:   store("i64.store", I64, 8),
+	OpF64Store:   store("f64.store", F64, 8),
+	OpI32Store8:  store("i32.store8", I32, 1),
+	OpMemorySize: {name: "memory.size", imm: immMemory, pops: none, pushes: i32},
+	OpMemoryGrow: {name: "memory.grow", imm: immMemory, pops: i32, pushes: i32},
+
+	OpI32Const: {name: "i32.const", imm: immI32, pops: none, pushes: i32},
+	OpI64Const: {name: "i64.const", imm: immI64, pops: none, pushes: i64},
+	OpF64Const: {name: "f64.const", imm: immF64, pops: none, pushes: f64},
+
+	OpI32Eqz: sig("i32.eqz", i32, i32),
+	OpI32Eq:  sig("i32.eq", []ValType{I32, I32}, i32),
+	OpI32Ne:  sig("i32.ne", []ValType{I32, I32}, i32),
+	OpI32LtS: sig("i32.lt_s", []ValType{I32, I32}, i32),
+	OpI32LtU: sig("i32.lt_u", []ValType{I32, I32}, i32),
+	OpI32GtS: sig("i32.gt_s", []ValType{I32, I32}, i32),
+	OpI32GtU: sig("i32.gt_u", []ValType{I32, I32}, i32),
+	OpI32LeS: sig("i32.le_s", []ValType{I32, I32}, i32),
+	OpI32LeU: sig("i32.le_u", []ValType{I32, I32}, i32),
+	OpI32GeS: sig("i32.ge_s", []ValType{I32, I32}, i32),
+	OpI32GeU: sig("i32.ge_u", []ValType{I32, I32}, i32),
+	OpI64Eqz: sig("i64.eqz", i64, i32),
+	OpI64Eq:  sig("i64.eq", []ValType{I64, I64}, i32),
+	OpI64Ne:  sig("i64.ne", []ValType{I64, I64}, i32),
+	OpI64LtS: sig("i64.lt_s", []ValType{I64, I64}, i32),
+	OpI64GtS: sig("i64.gt_s", []ValType{I64, I64}, i32),
+	OpI64GtU: sig("i64.gt_u", []ValType{I64, I64}, i32),
+	OpI64LeS: sig("i64.le_s", []ValType{I64, I64}, i32),
+	OpI64GeS: sig("i64.ge_s", []ValType{I64, I64}, i32),
+	OpF64Eq:  sig("f64.eq", []ValType{F64, F64}, i32),
+	OpF64Ne:  sig("f64.ne", []ValType{F64, F64}, i32),
+	OpF64Lt:  sig("f64.lt", []ValType{F64, F64}, i32),
+	OpF64Gt:  sig("f64.gt", []ValType{F64, F64}, i32),
+	OpF64Le:  sig("f64.le", []ValType{F64, F64}, i32),
+	OpF64Ge:  sig("f64.ge", []ValType{F64, F64}, i32),
+
+	OpI32Add:  sig("i32.add", []ValType{I32, I32}, i32),
+	OpI32Sub:  sig("i32.sub", []ValType{I32, I32}, i32),
+	OpI32Mul:  sig("i32.mul", []ValType{I32, I32}, i32),
+	OpI32DivU: sig("i32.div_u", []ValType{I32, I32}, i32),
+	OpI32RemU: sig("i32.rem_u", []ValType{I32, I32}, i32),
+	OpI32And:  sig("i32.and", []ValType{I32, I32}, i32),
+	OpI32Or:   sig("i32.or", []ValType{I32, I32}, i32),
+	OpI32Xor:  sig("i32.xor", []ValType{I32, I32}, i32),
+	OpI32Shl:  sig("i32.shl", []ValType{I32, I32}, i32),
+	OpI32ShrU: sig("i32.shr_u", []ValType{I32, I32}, i32),
+	OpI64Add:  sig("i64.add", []ValType{I64, I64}, i64),
+	OpI64Sub:  sig("i64.sub", []ValType{I64, I64}, i64),
+	OpI64Mul:  sig("i64.mul", []ValType{I64, I64}, i64),
+	OpI64DivS: sig("i64.div_s", []ValType{I64, I64}, i64),
+	OpI64DivU: sig("i64.div_u", []ValType{I64, I64}, i64),
+	OpI64RemS: sig("i64.rem_s", []ValType{I64, I64}, i64),
+
+	OpF64Abs:   sig("f64.abs", f64, f64),
+	OpF64Neg:   sig("f64.neg", f64, f64),
+	OpF64Floor: sig("f64.floor", f64, f64),
+	OpF64Trunc: sig("f64.trunc", f64, f64),
+	OpF64Sqrt:  sig("f64.sqrt", f64, f64),
+	OpF64Add:   sig("f64.add", []ValType{F64, F64}, f64),
+	OpF64Sub:   sig("f64.sub", []ValType{F64, F64}, f64),
+	OpF64Mul:   sig("f64.mul", []ValType{F64, F64}, f64),
+	OpF64Div:   sig("f64.div", []ValType{F64, F64}, f64),
+
+	OpI32WrapI64:      sig("i32.wrap_i64", i64, i32),
+	OpI64ExtendI32S:   sig("i64.extend_i32_s", i32, i64),
+	OpI64ExtendI32U:   sig("i64.extend_i32_u", i32, i64),
+	OpI64TruncF64S:    sig("i64.trunc_f64_s", f64, i64),
+	OpF64ConvertI32S:  sig("f64.convert_i32_s", i32, f64),
+	OpF64ConvertI32U:  sig("f64.convert_i32_u", i32, f64),
+	OpF64ConvertI64S:  sig("f64.convert_i64_s", i64, f64),
+	OpI32TruncSatF64U: sig("i32.trunc_sat_f64_u", f64, i32),
+	OpMemoryCopy:      {name: "memory.copy", imm: immMemory2, pops: []ValType{I32, I32, I32}, pushes: none},
+	OpMemoryFill:      {name: "memory.fill", imm: immMemory, pops: []ValType{I32, I32, I32}, pushes: none},
+}
@@ -0,0 +1,236 @@
+package wasm_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"zork-basic/internal/wasm"
+)
+
+// sample builds a module that uses every section and most immediates
+func sample() *wasm.Module {
+	m := &wasm.Module{}
+	print := m.AddType(wasm.FuncType{Params: []wasm.ValType{wasm.I32}})
+	pair := m.AddType(wasm.FuncType{Params: []wasm.ValType{wasm.F64}, Results: []wasm.ValType{wasm.F64, wasm.I32}})
+	run := m.AddType(wasm.FuncType{})
+	m.Imports = []wasm.Import{{Module: "env", Name: "print", Type: print}}
+	m.Memories = []wasm.Memory{{Min: 1}}
+	m.Globals = []wasm.Global{
+		{Type: wasm.I32, Mutable: true, Init: wasm.Instr{Op: wasm.OpI32Const, Int: 1024}},
+		{Type: wasm.F64, Init: wasm.Instr{Op: wasm.OpF64Const, Float: math.Inf(-1)}},
+	}
+	m.Funcs = []wasm.Func{
+		{Type: pair, Body: []wasm.Instr{
+			{Op: wasm.OpLocalGet, Index: 0},
+			{Op: wasm.OpI32Const, Int: -1},
+		}},
+		{Type: run, Locals: []wasm.ValType{wasm.I32, wasm.I32, wasm.F64, wasm.I32}, Body: []wasm.Instr{
+			{Op: wasm.OpF64Const, Float: math.Copysign(0, -1)},
+			{Op: wasm.OpCall, Index: 1},
+			{Op: wasm.OpLocalSet, Index: 0},
+			{Op: wasm.OpLocalSet, Index: 2},
+			{Op: wasm.OpLoop, Block: wasm.BlockEmpty},
+			{Op: wasm.OpBlock, Block: wasm.BlockEmpty},
+			{Op: wasm.OpBlock, Block: wasm.BlockEmpty},
+			{Op: wasm.OpLocalGet, Index: 1},
+			{Op: wasm.OpBrTable, Labels: []uint32{0, 1}, Index: 2},
+			{Op: wasm.OpEnd},
+			{Op: wasm.OpI32Const, Int: 1},
+			{Op: wasm.OpLocalSet, Index: 1},
+			{Op: wasm.OpBr, Index: 1},
+			{Op: wasm.OpEnd},
+			{Op: wasm.OpEnd},
+			{Op: wasm.OpLocalGet, Index: 0},
+			{Op: wasm.OpIf, Block: wasm.BlockOf(wasm.I32)},
+			{Op: wasm.OpGlobalGet, Index: 0},
+			{Op: wasm.OpI32Load, Align: 2, Offset: 8},
+			{Op: wasm.OpElse},
+			{Op: wasm.OpI32Const, Int: math.MinInt32},
+			{Op: wasm.OpEnd},
+			{Op: wasm.OpCall, Index: 0},
+			{Op: wasm.OpI32Const, Int: 16},
+			{Op: wasm.OpI32Const, Int: 0},
+			{Op: wasm.OpI32Const, Int: 8},
+			{Op: wasm.OpMemoryCopy},
+			{Op: wasm.OpI32Const, Int: 1},
+			{Op: wasm.OpMemoryGrow},
+			{Op: wasm.OpDrop},
+			{Op: wasm.OpI64Const, Int: math.MinInt64},
+			{Op: wasm.OpI64Eqz},
+			{Op: wasm.OpDrop},
+		}},
+	}
+	m.Exports = []wasm.Export{
+		{Name: "memory", Kind: wasm.ExternMemory},
+		{Name: "run", Kind: wasm.ExternFunc, Index: 2},
+		{Name: "heap", Kind: wasm.ExternGlobal},
+	}
+	m.Data = []wasm.Data{{Offset: 16, Bytes: []byte("hello\x00\xff")}}
+	return m
+}
+
+func TestRoundTrip(t *testing.T) {
+	m := sample()
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	bin, err := m.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := wasm.Decode(bin)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("Decode(Encode(m)) differs from m:\n got %+v\nwant %+v", got, m)
+	}
+	again, err := got.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !bytes.Equal(again, bin) {
+		t.Errorf("re-encoding changed the bytes")
+	}
+}
+
+func TestEncodeBytes(t *testing.T) {
+	// (module (func (export "f") (result i32) i32.const -64))
+	m := &wasm.Module{}
+	typ := m.AddType(wasm.FuncType{Results: []wasm.ValType{wasm.I32}})
+	m.Funcs = []wasm.Func{{Type: typ, Body: []wasm.Instr{{Op: wasm.OpI32Const, Int: -64}}}}
+	m.Exports = []wasm.Export{{Name: "f", Kind: wasm.ExternFunc}}
+	bin, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f, // type
+		0x03, 0x02, 0x01, 0x00, // function
+		0x07, 0x05, 0x01, 0x01, 'f', 0x00, 0x00, // export
+		0x0a, 0x06, 0x01, 0x04, 0x00, 0x41, 0x40, 0x0b, // code
+	}
+	if !bytes.Equal(bin, want) {
+		t.Errorf("Encode = % x\nwant     % x", bin, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	bin, err := sample().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(bin); i++ {
+		// Cutting between sections leaves a smaller valid module
+		m, err := wasm.Decode(bin[:i])
+		if err != nil {
+			continue
+		}
+		if again, _ := m.Encode(); !bytes.Equal(again, bin[:i]) {
+			t.Errorf("Decode of %d of %d bytes succeeded", i, len(bin))
+		}
+	}
+	custom := append(append([]byte(nil), bin...), 0x00, 0x03, 0x01, 'x', 0xaa)
+	if _, err := wasm.Decode(custom); err != nil {
+		t.Errorf("custom section: %v", err)
+	}
+	if _, err := wasm.Decode([]byte("\x00asm\x02\x00\x00\x00")); err == nil {
+		t.Errorf("version 2 accepted")
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *wasm.Module)
+		want   string
+	}{
+		{"type mismatch", func(m *wasm.Module) {
+			m.Funcs[0].Body[1] = wasm.Instr{Op: wasm.OpF64Const}
+		}, "got f64, want i32"},
+		{"missing result", func(m *wasm.Module) {
+			m.Funcs[0].Body = m.Funcs[0].Body[:0]
+		}, "stack underflow"},
+		{"extra value", func(m *wasm.Module) {
+			m.Funcs[1].Body = append(m.Funcs[1].Body, wasm.Instr{Op: wasm.OpI32Const})
+		}, "values left on the stack"},
+		{"unclosed block", func(m *wasm.Module) {
+			m.Funcs[1].Body = append(m.Funcs[1].Body, wasm.Instr{Op: wasm.OpBlock, Block: wasm.BlockEmpty})
+		}, "not closed"},
+		{"stray end", func(m *wasm.Module) {
+			m.Funcs[1].Body = append(m.Funcs[1].Body, wasm.Instr{Op: wasm.OpEnd})
+		}, "closes the function"},
+		{"bad label", func(m *wasm.Module) {
+			m.Funcs[1].Body[12].Index = 9
+		}, "label 9 out of range"},
+		{"bad local", func(m *wasm.Module) {
+			m.Funcs[1].Body[2].Index = 9
+		}, "local 9 out of range"},
+		{"bad call", func(m *wasm.Module) {
+			m.Funcs[1].Body[1].Index = 7
+		}, "function 7 out of range"},
+		{"immutable global", func(m *wasm.Module) {
+			m.Funcs[0].Body = append(m.Funcs[0].Body, wasm.Instr{Op: wasm.OpF64Const}, wasm.Instr{Op: wasm.OpGlobalSet, Index: 1})
+		}, "immutable"},
+		{"if without else", func(m *wasm.Module) {
+			m.Funcs[1].Body = append(m.Funcs[1].Body[:19], m.Funcs[1].Body[21:]...)
+		}, "without else"},
+		{"alignment", func(m *wasm.Module) {
+			m.Funcs[1].Body[18].Align = 3
+		}, "alignment"},
+		{"no memory", func(m *wasm.Module) {
+			m.Memories = nil
+			m.Exports = m.Exports[1:]
+			m.Data = nil
+		}, "no memory"},
+		{"data overflow", func(m *wasm.Module) {
+			m.Data[0].Offset = wasm.PageSize - 2
+		}, "do not fit"},
+		{"duplicate export", func(m *wasm.Module) {
+			m.Exports[1].Name = "memory"
+		}, "duplicate export"},
+		{"global initializer", func(m *wasm.Module) {
+			m.Globals[0].Init = wasm.Instr{Op: wasm.OpF64Const}
+		}, "does not match"},
+		{"br_table arity", func(m *wasm.Module) {
+			m.Funcs[1].Body[5].Block = wasm.BlockOf(wasm.I32)
+		}, "different arities"},
+		{"code after branch", func(m *wasm.Module) {
+			m.Funcs[1].Body = append(m.Funcs[1].Body, wasm.Instr{Op: wasm.OpReturn}, wasm.Instr{Op: wasm.OpI32Add}, wasm.Instr{Op: wasm.OpI32Add})
+		}, "values left on the stack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := sample()
+			tt.modify(m)
+			err := m.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnreachableIsPolymorphic(t *testing.T) {
+	m := &wasm.Module{}
+	typ := m.AddType(wasm.FuncType{Params: []wasm.ValType{wasm.I32}, Results: []wasm.ValType{wasm.F64, wasm.I32}})
+	m.Funcs = []wasm.Func{{Type: typ, Body: []wasm.Instr{
+		{Op: wasm.OpLocalGet, Index: 0},
+		{Op: wasm.OpIf, Block: wasm.BlockEmpty},
+		{Op: wasm.OpUnreachable},
+		{Op: wasm.OpI32Add},
+		{Op: wasm.OpSelect},
+		{Op: wasm.OpDrop},
+		{Op: wasm.OpEnd},
+		{Op: wasm.OpF64Const, Float: 1},
+		{Op: wasm.OpLocalGet, Index: 0},
+		{Op: wasm.OpReturn},
+	}}}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
@@ -35,7 +35,7 @@
 350 REM ============================================================
 360 PRINT "3. 多变量输入"
 370 PRINT
-380 PRINT "请输入三个数字（没输入一次需回车）:"
+380 PRINT "请输入三个数字，用逗号分隔（如 1, 2, 3）:"
 390 INPUT X, Y, Z
 400 PRINT "您输入的数字:"
 410 PRINT "  X = "; X
@@ -53,6 +53,10 @@
 530 PRINT "姓名: "; NAME$
 540 PRINT "年龄: "; AGE
 550 PRINT
+552 REM 提示后用分号会再显示 "? "；LINE INPUT 读入整行，逗号和引号原样保留
+554 LINE INPUT "请输入一句话: "; S$
+556 PRINT "您输入的是: "; S$
+558 PRINT
 560
 570 REM ============================================================
 580 REM 实际应用：计算器
@@ -84,7 +88,7 @@
 840 REM ============================================================
 850 PRINT "7. 成绩统计"
 860 PRINT
-870 PRINT "请输入五门课程的成绩:"
+870 PRINT "请输入五门课程的成绩，用逗号分隔:"
 880 INPUT S1, S2, S3, S4, S5
 890 TOTAL = S1 + S2 + S3 + S4 + S5
 900 AVG = TOTAL / 5